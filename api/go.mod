@@ -15,6 +15,7 @@ require (
 	k8s.io/client-go v0.32.2
 	k8s.io/utils v0.0.0-20241210054802-24370beab758
 	sigs.k8s.io/controller-runtime v0.20.2
+	sigs.k8s.io/gateway-api v1.2.1
 )
 
 require (
@@ -6,6 +6,7 @@ import (
 	"reflect"
 	"strings"
 
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
 	v1 "k8s.io/api/core/v1"
 )
 
@@ -233,6 +234,14 @@ type EndpointScrapeParams struct {
 	// FollowRedirects controls redirects for scraping.
 	// +optional
 	FollowRedirects *bool `json:"follow_redirects,omitempty"`
+	// EnableHTTP2 controls HTTP/2 usage for scraping.
+	// vmagent scrapes targets only over HTTP/1.1, so only false value is supported.
+	// +optional
+	EnableHTTP2 *bool `json:"enableHTTP2,omitempty"`
+	// Headers defines HTTP headers sent to the scrape targets.
+	// Header value could be loaded from the secret in the namespace of the scrape object.
+	// +optional
+	Headers []ScrapeHeader `json:"headers,omitempty"`
 	// SampleLimit defines per-scrape limit on number of scraped samples that will be accepted.
 	// +optional
 	SampleLimit uint64 `json:"sampleLimit,omitempty"`
@@ -260,13 +269,88 @@ type EndpointScrapeParams struct {
 	// +optional
 	HonorTimestamps *bool `json:"honorTimestamps,omitempty"`
 	// MaxScrapeSize defines a maximum size of scraped data for a job
+	// It supports optional KB, MB, GB, TB, KiB, MiB, GiB, TiB suffixes.
+	// It's ignored for vmagent versions older than v1.106.0
 	// +optional
 	MaxScrapeSize string `json:"max_scrape_size,omitempty"`
+	// ScrapeProtocols defines protocols to negotiate during a scrape in order of preference.
+	// It allows to scrape native histograms with PrometheusProto protocol.
+	// It's ignored for vmagent versions older than v1.117.0
+	// +optional
+	// +kubebuilder:validation:items:Enum=PrometheusProto;OpenMetricsText0.0.1;OpenMetricsText1.0.0;PrometheusText0.0.4;PrometheusText1.0.0
+	ScrapeProtocols []string `json:"scrape_protocols,omitempty"`
 	// VMScrapeParams defines VictoriaMetrics specific scrape parameters
 	// +optional
 	VMScrapeParams *VMScrapeParams `json:"vm_scrape_params,omitempty"`
 }
 
+var supportedScrapeProtocols = map[string]struct{}{
+	"PrometheusProto":      {},
+	"OpenMetricsText0.0.1": {},
+	"OpenMetricsText1.0.0": {},
+	"PrometheusText0.0.4":  {},
+	"PrometheusText1.0.0":  {},
+}
+
+// ValidateScrapeProtocols checks if the given scrape_protocols values are supported by vmagent
+func ValidateScrapeProtocols(protocols []string) error {
+	for _, p := range protocols {
+		if _, ok := supportedScrapeProtocols[p]; !ok {
+			return fmt.Errorf("unsupported scrape_protocols value=%q", p)
+		}
+	}
+	return nil
+}
+
+// Validate checks scrape params values
+func (cs *EndpointScrapeParams) Validate() error {
+	if cs.MaxScrapeSize != "" {
+		if _, err := flagutil.ParseBytes(cs.MaxScrapeSize); err != nil {
+			return fmt.Errorf("bad max_scrape_size=%q, it must be size in bytes with optional KB, MB, GB, TB, KiB, MiB, GiB, TiB suffix: %w", cs.MaxScrapeSize, err)
+		}
+	}
+	if err := ValidateScrapeProtocols(cs.ScrapeProtocols); err != nil {
+		return err
+	}
+	if cs.EnableHTTP2 != nil && *cs.EnableHTTP2 {
+		return fmt.Errorf("enableHTTP2=true is not supported, vmagent scrapes targets over HTTP/1.1 only")
+	}
+	for i, h := range cs.Headers {
+		if err := h.validate(); err != nil {
+			return fmt.Errorf("incorrect headers[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// ScrapeHeader defines HTTP header sent to the scrape target
+type ScrapeHeader struct {
+	// Name of the header
+	Name string `json:"name"`
+	// Value of the header
+	// +optional
+	Value string `json:"value,omitempty"`
+	// ValueFrom defines secret key with the header value
+	// +optional
+	ValueFrom *v1.SecretKeySelector `json:"valueFrom,omitempty"`
+}
+
+func (h *ScrapeHeader) validate() error {
+	if h.Name == "" {
+		return fmt.Errorf("name cannot be empty")
+	}
+	if strings.ContainsAny(h.Name, ": \t\r\n") {
+		return fmt.Errorf("name=%q must not contain colon or whitespace characters", h.Name)
+	}
+	if h.ValueFrom != nil && h.Value != "" {
+		return fmt.Errorf("value and valueFrom cannot be set at the same time for header=%q", h.Name)
+	}
+	if h.ValueFrom == nil && h.Value == "" {
+		return fmt.Errorf("value or valueFrom must be set for header=%q", h.Name)
+	}
+	return nil
+}
+
 // EndpointAuth defines target endpoint authorization options for scrapping
 type EndpointAuth struct {
 	// OAuth2 defines auth configuration
@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net"
+	"net/url"
 	"strings"
 
 	appsv1 "k8s.io/api/apps/v1"
@@ -40,6 +42,161 @@ type VMAgentSecurityEnforcements struct {
 	ArbitraryFSAccessThroughSMs ArbitraryFSAccessThroughSMsConfig `json:"arbitraryFSAccessThroughSMs,omitempty"`
 }
 
+// VMAgentTenantRoutingLimitReachedCondition is set to True at VMAgent status
+// if some tenants were skipped due to tenantRouting.maxURLs limit
+const VMAgentTenantRoutingLimitReachedCondition = "TenantRoutingLimitReached"
+
+const (
+	// VMAgentConfigReconcileStrategyApply always applies generated scrape configuration
+	VMAgentConfigReconcileStrategyApply = "apply"
+	// VMAgentConfigReconcileStrategyHoldOnDegraded keeps last applied scrape configuration
+	// if the number of generated scrape jobs dropped significantly
+	VMAgentConfigReconcileStrategyHoldOnDegraded = "holdOnDegraded"
+)
+
+// VMAgentScrapeConfigDegradedCondition is set to True at VMAgent status
+// if generated scrape configuration wasn't applied due to holdOnDegraded configReconcileStrategy
+const VMAgentScrapeConfigDegradedCondition = "ScrapeConfigDegraded"
+
+// VMAgentForceApplyConfigAnnotation allows to apply degraded scrape configuration
+// for holdOnDegraded configReconcileStrategy
+const VMAgentForceApplyConfigAnnotation = "operator.victoriametrics.com/force-apply-config"
+
+// VMAgentScrapeTokenPath defines path of projected service account token
+// mounted to vmagent with serviceScrapeTokenProjection.
+// It could be used as authorization credentialsFile at scrape endpoints
+const VMAgentScrapeTokenPath = "/var/run/secrets/operator.victoriametrics.com/scrape-token/token"
+
+// VMAgentTokenProjection defines projected service account token used for targets scrape
+type VMAgentTokenProjection struct {
+	// Audience defines intended audience of the token.
+	// Defaults to the identifier of the apiserver
+	// +optional
+	Audience string `json:"audience,omitempty"`
+	// ExpirationSeconds defines requested duration of validity of the token.
+	// Kubelet rotates token after 80% of its duration. Defaults to 3600
+	// +kubebuilder:validation:Minimum=600
+	// +optional
+	ExpirationSeconds *int64 `json:"expirationSeconds,omitempty"`
+}
+
+// VMAgentTenantRouting defines routing of scraped metrics into per tenant remoteWrite urls.
+// Tenant is resolved from label or annotation of the namespace, metrics belong to the namespace by source label value.
+type VMAgentTenantRouting struct {
+	// URLTemplate defines remoteWrite url with {{tenant}} placeholder,
+	// e.g. http://vminsert-main.monitoring.svc:8480/insert/{{tenant}}/prometheus/api/v1/write
+	URLTemplate string `json:"urlTemplate"`
+	// NamespaceLabel defines name of the namespace label with tenant id in form accountID[:projectID]
+	// +optional
+	NamespaceLabel string `json:"namespaceLabel,omitempty"`
+	// NamespaceAnnotation defines name of the namespace annotation with tenant id in form accountID[:projectID]
+	// NamespaceLabel has priority over it
+	// +optional
+	NamespaceAnnotation string `json:"namespaceAnnotation,omitempty"`
+	// NamespaceSelector limits namespaces used for tenants discovery
+	// by default, all namespaces with tenant label or annotation are used
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	// SourceLabel defines name of the label with source namespace of metrics
+	// Defaults to namespace
+	// +optional
+	SourceLabel string `json:"sourceLabel,omitempty"`
+	// MaxURLs limits number of generated remoteWrite urls.
+	// Tenants above the limit are skipped and reported with TenantRoutingLimitReached condition
+	// Defaults to 50
+	// +optional
+	MaxURLs int `json:"maxURLs,omitempty"`
+}
+
+// VMAgentDebug defines debug options for VMAgent
+type VMAgentDebug struct {
+	// ExposeRenderedConfig writes generated scrape configuration into vmagent-<name>-rendered-config ConfigMap.
+	// Secret values are replaced with <secret:namespace/name/key> references.
+	// Scrape objects get generated job names at its status condition message
+	// +optional
+	ExposeRenderedConfig bool `json:"exposeRenderedConfig,omitempty"`
+}
+
+// VMAgentGlobalMetricRelabelConfigs defines metric relabel configs applied to all scrape jobs generated from scrape objects
+type VMAgentGlobalMetricRelabelConfigs struct {
+	// RelabelConfigs are added after metricRelabelConfigs of each scrape job.
+	// It's useful for dropping unwanted metrics from all targets
+	RelabelConfigs []*RelabelConfig `json:"relabelConfigs"`
+	// ExemptSelector selects scrape objects by labels, which are excluded from global metric relabeling
+	// +optional
+	ExemptSelector *metav1.LabelSelector `json:"exemptSelector,omitempty"`
+}
+
+// VMAgentGlobalScrapeLimits defines limits applied to all scrape jobs generated by VMAgent
+type VMAgentGlobalScrapeLimits struct {
+	// SampleLimit defines per-scrape limit on number of scraped samples
+	// for scrape objects without own sampleLimit
+	// +optional
+	SampleLimit uint64 `json:"sampleLimit,omitempty"`
+	// SeriesLimit defines per-scrape limit on number of unique time series a single target can expose during 24h
+	// for scrape objects without own seriesLimit
+	// +optional
+	SeriesLimit uint64 `json:"seriesLimit,omitempty"`
+	// LabelLimit defines the maximum number of labels per time series.
+	// Series with superfluous labels are ignored. It's applied to all ingested series with -maxLabelsPerTimeseries flag
+	// +optional
+	LabelLimit int `json:"labelLimit,omitempty"`
+	// LabelNameLengthLimit defines the maximum length of label names.
+	// Series with longer label names are ignored. It's applied to all ingested series with -maxLabelNameLen flag
+	// +optional
+	LabelNameLengthLimit int `json:"labelNameLengthLimit,omitempty"`
+	// LabelValueLengthLimit defines the maximum length of label values.
+	// Series with longer label values are ignored. It's applied to all ingested series with -maxLabelValueLen flag
+	// +optional
+	LabelValueLengthLimit int `json:"labelValueLengthLimit,omitempty"`
+	// Enforce reduces sampleLimit and seriesLimit of scrape objects, which are higher than global limits.
+	// Reduced limits are reported at scrape object status
+	// +optional
+	Enforce bool `json:"enforce,omitempty"`
+}
+
+// VMAgentManagedProber defines blackbox exporter deployed by operator for VMProbe objects
+type VMAgentManagedProber struct {
+	// Image of blackbox exporter
+	// +optional
+	Image Image `json:"image,omitempty"`
+	// ReplicaCount of blackbox exporter, defaults to 1
+	// +optional
+	ReplicaCount *int32 `json:"replicaCount,omitempty"`
+	// Resources of blackbox exporter container
+	// +optional
+	Resources v1.ResourceRequirements `json:"resources,omitempty"`
+}
+
+// ScrapeClass defines shared scrape settings, which could be referenced by scrape objects with scrapeClassName.
+// Settings of the scrape object have priority over class settings.
+type ScrapeClass struct {
+	// Name of the scrape class, referenced by scrapeClassName of scrape objects
+	Name string `json:"name"`
+	// Default marks class as default one, it's applied to scrape objects without scrapeClassName.
+	// Only one class could be marked as default
+	// +optional
+	Default bool `json:"default,omitempty"`
+	// TLSConfig is used by endpoints without own tlsConfig.
+	// Only file based settings are supported, since class could be used by objects from any namespace
+	// +optional
+	TLSConfig *TLSConfig `json:"tlsConfig,omitempty"`
+	// Authorization is used by endpoints without own authorization settings.
+	// Only credentialsFile is supported, since class could be used by objects from any namespace
+	// +optional
+	Authorization *Authorization `json:"authorization,omitempty"`
+	// RelabelConfigs are added before relabelConfigs of each endpoint
+	// +optional
+	RelabelConfigs []*RelabelConfig `json:"relabelConfigs,omitempty"`
+	// MetricRelabelConfigs are added before metricRelabelConfigs of each endpoint
+	// +optional
+	MetricRelabelConfigs []*RelabelConfig `json:"metricRelabelConfigs,omitempty"`
+	// AttachMetadata is used by scrape objects without own attach_metadata settings.
+	// It's ignored by VMProbe
+	// +optional
+	AttachMetadata *AttachMetadata `json:"attachMetadata,omitempty"`
+}
+
 // VMAgentSpec defines the desired state of VMAgent
 // +k8s:openapi-gen=true
 type VMAgentSpec struct {
@@ -103,6 +260,10 @@ type VMAgentSpec struct {
 	// RemoteWriteSettings defines global settings for all remoteWrite urls.
 	// +optional
 	RemoteWriteSettings *VMAgentRemoteWriteSettings `json:"remoteWriteSettings,omitempty"`
+	// TenantRouting generates remoteWrite url per tenant of discovered namespaces
+	// and routes metrics of each namespace into its tenant
+	// +optional
+	TenantRouting *VMAgentTenantRouting `json:"tenantRouting,omitempty"`
 	// RelabelConfig ConfigMap with global relabel config -remoteWrite.relabelConfig
 	// This relabeling is applied to all the collected metrics before sending them to remote storage.
 	// +optional
@@ -253,6 +414,11 @@ type VMAgentSpec struct {
 	// it's useful for adding specific labels to all targets
 	// +optional
 	ServiceScrapeRelabelTemplate []*RelabelConfig `json:"serviceScrapeRelabelTemplate,omitempty"`
+	// UseEndpointSlices changes default kubernetes_sd role for VMServiceScrape objects from endpoints to endpointslices.
+	// Relabel configs of VMServiceScrape, which reference __meta_kubernetes_endpoint labels, are translated into endpointslice labels.
+	// VMServiceScrape discoveryRole has priority over this setting.
+	// +optional
+	UseEndpointSlices bool `json:"useEndpointSlices,omitempty"`
 	// PodScrapeRelabelTemplate defines relabel config, that will be added to each VMPodScrape.
 	// it's useful for adding specific labels to all targets
 	// +optional
@@ -279,6 +445,59 @@ type VMAgentSpec struct {
 	// MaxScrapeInterval allows limiting maximum scrape interval for VMServiceScrape, VMPodScrape and other scrapes
 	// If interval is higher than defined limit, `maxScrapeInterval` will be used.
 	MaxScrapeInterval *string `json:"maxScrapeInterval,omitempty"`
+	// GlobalScrapeLimits defines limits applied to all scrape jobs, which don't set its own limits
+	// +optional
+	GlobalScrapeLimits *VMAgentGlobalScrapeLimits `json:"globalScrapeLimits,omitempty"`
+	// ScrapeClasses defines shared scrape settings for VMServiceScrape, VMPodScrape and VMProbe objects.
+	// Objects reference class by scrapeClassName, the default class is used for objects without it
+	// +optional
+	ScrapeClasses []ScrapeClass `json:"scrapeClasses,omitempty"`
+	// ManagedProber defines blackbox exporter, which is deployed by operator
+	// for selected VMProbe objects with vmProberSpec.managed
+	// +optional
+	ManagedProber *VMAgentManagedProber `json:"managedProber,omitempty"`
+	// GlobalMetricRelabelConfigs defines metric relabel configs, which are added to each scrape job generated from scrape objects.
+	// They are applied after metricRelabelConfigs of scrape objects
+	// +optional
+	GlobalMetricRelabelConfigs *VMAgentGlobalMetricRelabelConfigs `json:"globalMetricRelabelConfigs,omitempty"`
+	// ConfigReconcileStrategy defines how generated scrape configuration is applied.
+	// apply - configuration is always applied, it's default behaviour.
+	// holdOnDegraded - configuration isn't applied, if the number of scrape jobs dropped
+	// by more than configDegradedThresholdPercent compared to the last applied configuration.
+	// +kubebuilder:validation:Enum=apply;holdOnDegraded
+	// +optional
+	ConfigReconcileStrategy string `json:"configReconcileStrategy,omitempty"`
+	// ConfigDegradedThresholdPercent defines allowed drop of scrape jobs count in percents
+	// for holdOnDegraded configReconcileStrategy
+	// Defaults to 50
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	ConfigDegradedThresholdPercent int32 `json:"configDegradedThresholdPercent,omitempty"`
+	// Debug defines debug options for generated configuration
+	// +optional
+	Debug *VMAgentDebug `json:"debug,omitempty"`
+	// ConfigCheckInterval defines interval for checking changes of scrape configuration
+	// and files referred by it. It's passed to vmagent with -promscrape.configCheckInterval flag
+	// +optional
+	// +kubebuilder:validation:Pattern:="^([0-9]+(ms|s|m|h))+$"
+	ConfigCheckInterval string `json:"configCheckInterval,omitempty"`
+	// ServiceScrapeTokenProjection mounts projected service account token to vmagent at VMAgentScrapeTokenPath.
+	// Token is rotated by kubelet and could be used for scrape targets authorization
+	// with authorization.credentialsFile=/var/run/secrets/operator.victoriametrics.com/scrape-token/token
+	// +optional
+	ServiceScrapeTokenProjection *VMAgentTokenProjection `json:"serviceScrapeTokenProjection,omitempty"`
+	// JobLabelTemplate defines template for `job` label of targets discovered by scrape objects.
+	// Supported placeholders: {{kind}}, {{namespace}}, {{name}} and {{endpoint}} - index of endpoint at scrape object.
+	// By default, `job` label matches unique job name: kind/namespace/name/endpoint.
+	// jobName and jobLabel defined at scrape objects take precedence over it
+	// +optional
+	JobLabelTemplate string `json:"jobLabelTemplate,omitempty"`
+	// LegacyJobNames keeps `job` label scheme of previous releases:
+	// service name for VMServiceScrape and namespace/name for VMPodScrape and VMNodeScrape.
+	// Such labels may collide for scrape objects with the same name at different namespaces
+	// +optional
+	LegacyJobNames bool `json:"legacyJobNames,omitempty"`
 	// StatefulMode enables StatefulSet for `VMAgent` instead of Deployment
 	// it allows using persistent storage for vmagent's persistentQueue
 	// +optional
@@ -293,6 +512,11 @@ type VMAgentSpec struct {
 
 	// ClaimTemplates allows adding additional VolumeClaimTemplates for VMAgent in StatefulMode
 	ClaimTemplates []v1.PersistentVolumeClaim `json:"claimTemplates,omitempty"`
+	// DaemonSetMode enables DaemonSet deployment mode instead of Deployment
+	// each vmagent pod scrapes only targets located at the same node with it.
+	// It cannot be used together with statefulMode, shardCount and replicaCount
+	// +optional
+	DaemonSetMode bool `json:"daemonSetMode,omitempty"`
 	// IngestOnlyMode switches vmagent into unmanaged mode
 	// it disables any config generation for scraping
 	// Currently it prevents vmagent from managing tls and auth options for remote write
@@ -304,6 +528,12 @@ type VMAgentSpec struct {
 	// See [here](https://docs.victoriametrics.com/enterprise)
 	// +optional
 	License *License `json:"license,omitempty"`
+	// AcceptEULA confirms usage of enterprise version of vmagent for enterprise-only features,
+	// if image tag doesn't have enterprise suffix and license isn't provided.
+	// If license isn't provided, it adds -eula flag to vmagent.
+	// See [here](https://victoriametrics.com/legal/esa/)
+	// +optional
+	AcceptEULA bool `json:"acceptEULA,omitempty"`
 
 	// ServiceAccountName is the name of the ServiceAccount to use to run the pods
 	// +optional
@@ -381,7 +611,15 @@ type VMAgentRemoteWriteSettings struct {
 // +k8s:openapi-gen=true
 type VMAgentRemoteWriteSpec struct {
 	// URL of the endpoint to send samples to.
+	// It must be empty if kafka or pubsub is set
+	// +optional
 	URL string `json:"url"`
+	// Kafka defines Kafka topic to send samples to, it requires enterprise version of vmagent
+	// +optional
+	Kafka *VMAgentRemoteWriteKafka `json:"kafka,omitempty"`
+	// PubSub defines Google PubSub topic to send samples to, it requires enterprise version of vmagent
+	// +optional
+	PubSub *VMAgentRemoteWritePubSub `json:"pubsub,omitempty"`
 	// BasicAuth allow an endpoint to authenticate over basic authentication
 	// +optional
 	BasicAuth *BasicAuth `json:"basicAuth,omitempty"`
@@ -402,6 +640,15 @@ type VMAgentRemoteWriteSpec struct {
 	// TLSConfig describes tls configuration for remote write target
 	// +optional
 	TLSConfig *TLSConfig `json:"tlsConfig,omitempty"`
+	// ProxyURL defines http, https or socks5 proxy for -remoteWrite.url
+	// e.g. http://proxy:3128
+	// +optional
+	ProxyURL *string `json:"proxyURL,omitempty"`
+	// ProxyBasicAuth allows to authenticate at ProxyURL over basic authentication
+	// Credentials are passed to vmagent with environment variables and
+	// changes of secret content are applied only after pods restart
+	// +optional
+	ProxyBasicAuth *BasicAuth `json:"proxyBasicAuth,omitempty"`
 	// Timeout for sending a single block of data to -remoteWrite.url (default 1m0s)
 	// +optional
 	// +kubebuilder:validation:Pattern:="[0-9]+(ms|s|m|h)"
@@ -417,6 +664,8 @@ type VMAgentRemoteWriteSpec struct {
 	// +optional
 	StreamAggrConfig *StreamAggrConfig `json:"streamAggrConfig,omitempty"`
 	// MaxDiskUsage defines the maximum file-based buffer size in bytes for -remoteWrite.url
+	// It supports optional KB, MB, GB, TB, KiB, MiB, GiB, TiB suffixes.
+	// If not set, remoteWriteSettings.maxDiskUsagePerURL is used
 	// +optional
 	MaxDiskUsage *string `json:"maxDiskUsage,omitempty"`
 	// ForceVMProto forces using VictoriaMetrics protocol for sending data to -remoteWrite.url
@@ -424,11 +673,98 @@ type VMAgentRemoteWriteSpec struct {
 	ForceVMProto bool `json:"forceVMProto,omitempty"`
 }
 
+// VMAgentRemoteWriteKafka defines Kafka remote write target
+// See [here](https://docs.victoriametrics.com/vmagent/#writing-metrics-to-kafka)
+type VMAgentRemoteWriteKafka struct {
+	// Brokers defines list of Kafka brokers in form host:port
+	// +kubebuilder:validation:MinItems=1
+	Brokers []string `json:"brokers"`
+	// Topic defines Kafka topic name
+	Topic string `json:"topic"`
+	// SecurityProtocol defines protocol used to communicate with brokers.
+	// By default, it's detected from saslAuth and remoteWrite tlsConfig
+	// +kubebuilder:validation:Enum=PLAINTEXT;SSL;SASL_PLAINTEXT;SASL_SSL
+	// +optional
+	SecurityProtocol string `json:"securityProtocol,omitempty"`
+	// SASLMechanism defines SASL mechanism for authentication with saslAuth
+	// +kubebuilder:validation:Enum=PLAIN;SCRAM-SHA-256;SCRAM-SHA-512
+	// +optional
+	SASLMechanism string `json:"saslMechanism,omitempty"`
+	// SASLAuth defines references to secrets with SASL username and password
+	// +optional
+	SASLAuth *BasicAuth `json:"saslAuth,omitempty"`
+	// Params defines additional Kafka client options passed at url query
+	// +optional
+	Params map[string]string `json:"params,omitempty"`
+}
+
+// BuildURL returns vmagent remote write url for Kafka target
+func (k *VMAgentRemoteWriteKafka) BuildURL(hasTLS bool) string {
+	params := url.Values{}
+	for key, value := range k.Params {
+		params.Set(key, value)
+	}
+	params.Set("topic", k.Topic)
+	if len(k.Brokers) > 1 {
+		params.Set("bootstrap.servers", strings.Join(k.Brokers, ","))
+	}
+	protocol := k.SecurityProtocol
+	if protocol == "" {
+		switch {
+		case k.SASLAuth != nil && hasTLS:
+			protocol = "SASL_SSL"
+		case k.SASLAuth != nil:
+			protocol = "SASL_PLAINTEXT"
+		case hasTLS:
+			protocol = "SSL"
+		}
+	}
+	if protocol != "" {
+		params.Set("security.protocol", protocol)
+	}
+	if k.SASLMechanism != "" {
+		params.Set("sasl.mechanisms", k.SASLMechanism)
+	}
+	var broker string
+	if len(k.Brokers) > 0 {
+		broker = k.Brokers[0]
+	}
+	return fmt.Sprintf("kafka://%s/?%s", broker, params.Encode())
+}
+
+// VMAgentRemoteWritePubSub defines Google PubSub remote write target
+// See [here](https://docs.victoriametrics.com/vmagent/#writing-metrics-to-pubsub)
+type VMAgentRemoteWritePubSub struct {
+	// Project defines GCP project id
+	Project string `json:"project"`
+	// Topic defines PubSub topic id
+	Topic string `json:"topic"`
+	// CredentialsSecret defines reference to secret with GCP credentials json.
+	// If not set, default GCP credentials are used
+	// +optional
+	CredentialsSecret *v1.SecretKeySelector `json:"credentialsSecret,omitempty"`
+}
+
+// BuildURL returns vmagent remote write url for PubSub target
+func (p *VMAgentRemoteWritePubSub) BuildURL() string {
+	return fmt.Sprintf("pubsub:projects/%s/topics/%s", p.Project, p.Topic)
+}
+
+// AsPubSubKey key for internal cache map of pubsub credentials
+func (rw *VMAgentRemoteWriteSpec) AsPubSubKey() string {
+	return fmt.Sprintf("remoteWritePubSub-%s", rw.URL)
+}
+
 // AsMapKey key for internal cache map
 func (rw *VMAgentRemoteWriteSpec) AsMapKey() string {
 	return fmt.Sprintf("remoteWrite-%s", rw.URL)
 }
 
+// AsProxyKey key for internal cache map of proxy credentials
+func (rw *VMAgentRemoteWriteSpec) AsProxyKey() string {
+	return fmt.Sprintf("remoteWriteProxy-%s", rw.URL)
+}
+
 // AsSecretKey key for kubernetes secret data
 func (rw *VMAgentRemoteWriteSpec) AsSecretKey(idx int, suffix string) string {
 	return fmt.Sprintf("RWS_%d-SECRET-%s", idx, strings.ToUpper(suffix))
@@ -447,8 +783,27 @@ type VMAgentStatus struct {
 	// Selector string form of label value set for autoscaling
 	Selector string `json:"selector,omitempty"`
 	// ReplicaCount Total number of pods targeted by this VMAgent
-	Replicas       int32 `json:"replicas,omitempty"`
-	StatusMetadata `json:",inline"`
+	Replicas int32 `json:"replicas,omitempty"`
+	// ShardTransition reports progress of shardCount change
+	// it's removed after all shards were updated
+	// +optional
+	ShardTransition *VMAgentShardTransition `json:"shardTransition,omitempty"`
+	// ConfigSecretParts defines number of Secrets with scrape jobs,
+	// which are created if generated configuration exceeds Secret size limit.
+	// Zero means that all scrape jobs are stored at the main config Secret
+	// +optional
+	ConfigSecretParts int32 `json:"configSecretParts,omitempty"`
+	StatusMetadata    `json:",inline"`
+}
+
+// VMAgentShardTransition describes progress of VMAgent shards count change
+type VMAgentShardTransition struct {
+	// From defines shards count before change
+	From int32 `json:"from"`
+	// To defines desired shards count
+	To int32 `json:"to"`
+	// CompletedShards defines number of shards updated to the desired shards count
+	CompletedShards int32 `json:"completedShards"`
 }
 
 // GetStatusMetadata returns metadata for object status
@@ -580,6 +935,31 @@ func (cr *VMAgent) RelabelingAssetName() string {
 	return fmt.Sprintf("relabelings-assets-vmagent-%s", cr.Name)
 }
 
+// ConfigSecretPartName returns name of the Secret with the given part of scrape jobs
+func (cr *VMAgent) ConfigSecretPartName(idx int) string {
+	return fmt.Sprintf("%s-config-%d", cr.PrefixedName(), idx)
+}
+
+// RenderedConfigName returns name of the ConfigMap with redacted scrape configuration
+func (cr *VMAgent) RenderedConfigName() string {
+	return fmt.Sprintf("%s-rendered-config", cr.PrefixedName())
+}
+
+// ProberName returns name of managed prober resources
+func (cr *VMAgent) ProberName() string {
+	return fmt.Sprintf("%s-prober", cr.PrefixedName())
+}
+
+// ProberSelectorLabels returns selector labels of managed prober pods
+func (cr *VMAgent) ProberSelectorLabels() map[string]string {
+	return map[string]string{
+		"app.kubernetes.io/name":      "vmagent-prober",
+		"app.kubernetes.io/instance":  cr.Name,
+		"app.kubernetes.io/component": "monitoring",
+		"managed-by":                  "vm-operator",
+	}
+}
+
 func (cr *VMAgent) StreamAggrConfigName() string {
 	return fmt.Sprintf("stream-aggr-vmagent-%s", cr.Name)
 }
@@ -641,6 +1021,15 @@ func (cr *VMAgent) AsURL() string {
 	return fmt.Sprintf("%s://%s.%s.svc:%s", protoFromFlags(cr.Spec.ExtraArgs), cr.PrefixedName(), cr.Namespace, port)
 }
 
+// PodURL returns url for http access to the vmagent pod with given IP address
+func (cr *VMAgent) PodURL(podIP, path string) string {
+	port := cr.Spec.Port
+	if port == "" {
+		port = "8429"
+	}
+	return fmt.Sprintf("%s://%s%s", protoFromFlags(cr.Spec.ExtraArgs), net.JoinHostPort(podIP, port), buildPathWithPrefixFlag(cr.Spec.ExtraArgs, path))
+}
+
 // AsCRDOwner implements interface
 func (cr *VMAgent) AsCRDOwner() []metav1.OwnerReference {
 	return GetCRDAsOwner(Agent)
@@ -803,6 +1192,9 @@ func (cr *VMAgent) SetUpdateStatusTo(ctx context.Context, r client.Client, statu
 			vs.Shards = shardCnt
 			vs.Selector = labels.SelectorFromSet(cr.SelectorLabels()).String()
 		},
+		statusOf: func(pcr *VMAgent) *VMAgentStatus {
+			return &pcr.Status
+		},
 	})
 }
 
@@ -20,8 +20,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/url"
+	"strings"
 
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
 	"gopkg.in/yaml.v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
@@ -63,17 +67,168 @@ func (r *VMAgent) sanityCheck() error {
 			return err
 		}
 	}
+	if r.Spec.DaemonSetMode {
+		if r.Spec.StatefulMode {
+			return fmt.Errorf("spec.statefulMode cannot be used with spec.daemonSetMode")
+		}
+		if r.Spec.StatefulStorage != nil || len(r.Spec.ClaimTemplates) > 0 {
+			return fmt.Errorf("spec.statefulStorage and spec.claimTemplates cannot be used with spec.daemonSetMode")
+		}
+		if r.Spec.ShardCount != nil {
+			return fmt.Errorf("spec.shardCount cannot be used with spec.daemonSetMode")
+		}
+		if r.Spec.ReplicaCount != nil && *r.Spec.ReplicaCount > 1 {
+			return fmt.Errorf("spec.replicaCount cannot be greater than 1 with spec.daemonSetMode")
+		}
+		if r.Spec.UpdateStrategy != nil || r.Spec.RollingUpdate != nil {
+			return fmt.Errorf("spec.updateStrategy and spec.rollingUpdate cannot be used with spec.daemonSetMode")
+		}
+		if r.Spec.PodDisruptionBudget != nil {
+			return fmt.Errorf("spec.podDisruptionBudget cannot be used with spec.daemonSetMode")
+		}
+	}
+	if err := r.Spec.StreamAggrConfig.Validate(); err != nil {
+		return fmt.Errorf("bad spec.streamAggrConfig: %w", err)
+	}
+	var hasEnterpriseRemoteWrite bool
 	for idx, rw := range r.Spec.RemoteWrite {
-		if rw.URL == "" {
+		switch {
+		case rw.Kafka != nil && rw.PubSub != nil:
+			return fmt.Errorf("remoteWrite.kafka and remoteWrite.pubsub are mutually exclusive at idx: %d", idx)
+		case rw.Kafka != nil || rw.PubSub != nil:
+			if rw.URL != "" {
+				return fmt.Errorf("remoteWrite.url must be empty at idx: %d, if kafka or pubsub is set", idx)
+			}
+			if err := rw.checkStructuredTarget(); err != nil {
+				return fmt.Errorf("bad remoteWrite at idx: %d: %w", idx, err)
+			}
+			hasEnterpriseRemoteWrite = true
+		case rw.URL == "":
 			return fmt.Errorf("remoteWrite.url cannot be empty at idx: %d", idx)
 		}
+		if err := rw.StreamAggrConfig.Validate(); err != nil {
+			return fmt.Errorf("bad streamAggrConfig at remoteWrite idx: %d, err: %w", idx, err)
+		}
+		if rw.MaxDiskUsage != nil {
+			if _, err := flagutil.ParseBytes(*rw.MaxDiskUsage); err != nil {
+				return fmt.Errorf("bad maxDiskUsage=%q at remoteWrite idx: %d, it must be size in bytes with optional KB, MB, GB, TB, KiB, MiB, GiB, TiB suffix: %w", *rw.MaxDiskUsage, idx, err)
+			}
+		}
+		if rw.ProxyURL != nil {
+			pu, err := url.Parse(*rw.ProxyURL)
+			if err != nil {
+				return fmt.Errorf("bad proxyURL=%q at remoteWrite idx: %d: %w", *rw.ProxyURL, idx, err)
+			}
+			switch pu.Scheme {
+			case "http", "https", "socks5":
+			default:
+				return fmt.Errorf("unsupported scheme=%q of proxyURL at remoteWrite idx: %d, supported values: http, https, socks5", pu.Scheme, idx)
+			}
+			if rw.ProxyBasicAuth != nil && pu.User != nil {
+				return fmt.Errorf("proxyURL at remoteWrite idx: %d cannot contain user info if proxyBasicAuth is set", idx)
+			}
+		} else if rw.ProxyBasicAuth != nil {
+			return fmt.Errorf("proxyBasicAuth at remoteWrite idx: %d requires proxyURL", idx)
+		}
 		if len(rw.InlineUrlRelabelConfig) > 0 {
 			if err := checkRelabelConfigs(rw.InlineUrlRelabelConfig); err != nil {
 				return fmt.Errorf("bad urlRelabelingConfig at idx: %d, err: %w", idx, err)
 			}
 		}
 	}
+	if hasEnterpriseRemoteWrite && !r.isEnterprise() {
+		return fmt.Errorf("remoteWrite kafka and pubsub targets require enterprise version of vmagent, set image tag with enterprise suffix, license or acceptEULA")
+	}
+	if r.Spec.LegacyJobNames && r.Spec.JobLabelTemplate != "" {
+		return fmt.Errorf("spec.legacyJobNames and spec.jobLabelTemplate cannot be used together")
+	}
+	if tr := r.Spec.TenantRouting; tr != nil {
+		if !strings.Contains(tr.URLTemplate, "{{tenant}}") {
+			return fmt.Errorf("spec.tenantRouting.urlTemplate=%q must contain {{tenant}} placeholder", tr.URLTemplate)
+		}
+		if tr.NamespaceLabel == "" && tr.NamespaceAnnotation == "" {
+			return fmt.Errorf("spec.tenantRouting requires namespaceLabel or namespaceAnnotation")
+		}
+		if tr.MaxURLs < 0 {
+			return fmt.Errorf("spec.tenantRouting.maxURLs cannot be negative")
+		}
+		if tr.NamespaceSelector != nil {
+			if _, err := metav1.LabelSelectorAsSelector(tr.NamespaceSelector); err != nil {
+				return fmt.Errorf("bad spec.tenantRouting.namespaceSelector: %w", err)
+			}
+		}
+	}
+	if err := checkScrapeClasses(r.Spec.ScrapeClasses); err != nil {
+		return err
+	}
+	if gmr := r.Spec.GlobalMetricRelabelConfigs; gmr != nil && gmr.ExemptSelector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(gmr.ExemptSelector); err != nil {
+			return fmt.Errorf("bad spec.globalMetricRelabelConfigs.exemptSelector: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// checkScrapeClasses validates scrape classes.
+// Classes could be used by scrape objects from any namespace, so secret references are not allowed
+func checkScrapeClasses(classes []ScrapeClass) error {
+	names := make(map[string]struct{}, len(classes))
+	var defaultClass string
+	for idx, sc := range classes {
+		if sc.Name == "" {
+			return fmt.Errorf("spec.scrapeClasses name cannot be empty at idx: %d", idx)
+		}
+		if _, ok := names[sc.Name]; ok {
+			return fmt.Errorf("spec.scrapeClasses name=%q is duplicated", sc.Name)
+		}
+		names[sc.Name] = struct{}{}
+		if sc.Default {
+			if defaultClass != "" {
+				return fmt.Errorf("spec.scrapeClasses name=%q cannot be default, class name=%q is already marked as default", sc.Name, defaultClass)
+			}
+			defaultClass = sc.Name
+		}
+		if tc := sc.TLSConfig; tc != nil {
+			if tc.CA.PrefixedName() != "" || tc.Cert.PrefixedName() != "" || tc.KeySecret != nil {
+				return fmt.Errorf("spec.scrapeClasses name=%q tlsConfig supports only caFile, certFile and keyFile", sc.Name)
+			}
+		}
+		if ac := sc.Authorization; ac != nil {
+			if ac.Credentials != nil {
+				return fmt.Errorf("spec.scrapeClasses name=%q authorization supports only credentialsFile", sc.Name)
+			}
+			if err := ac.validate(); err != nil {
+				return fmt.Errorf("bad spec.scrapeClasses name=%q authorization: %w", sc.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// isEnterprise checks if enterprise version of vmagent is used or explicitly accepted
+func (r *VMAgent) isEnterprise() bool {
+	return r.Spec.AcceptEULA || r.Spec.License.IsProvided() || strings.Contains(r.Spec.Image.Tag, "enterprise")
+}
 
+// checkStructuredTarget validates kafka and pubsub remote write targets
+func (rw *VMAgentRemoteWriteSpec) checkStructuredTarget() error {
+	if k := rw.Kafka; k != nil {
+		if len(k.Brokers) == 0 {
+			return fmt.Errorf("kafka.brokers cannot be empty")
+		}
+		if k.Topic == "" {
+			return fmt.Errorf("kafka.topic cannot be empty")
+		}
+		if k.SASLAuth != nil && rw.BasicAuth != nil {
+			return fmt.Errorf("kafka.saslAuth and basicAuth are mutually exclusive")
+		}
+	}
+	if p := rw.PubSub; p != nil {
+		if p.Project == "" || p.Topic == "" {
+			return fmt.Errorf("pubsub.project and pubsub.topic cannot be empty")
+		}
+	}
 	return nil
 }
 
@@ -2,6 +2,10 @@ package v1beta1
 
 import (
 	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
 )
 
 func TestVMAgent_sanityCheck(t *testing.T) {
@@ -65,6 +69,281 @@ func TestVMAgent_sanityCheck(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "valid stream aggregation",
+			spec: VMAgentSpec{
+				RemoteWrite: []VMAgentRemoteWriteSpec{{
+					URL: "http://some-rw",
+					StreamAggrConfig: &StreamAggrConfig{
+						Rules: []StreamAggrRule{{Interval: "1m", Outputs: []string{"total"}}},
+					},
+				}},
+				StreamAggrConfig: &StreamAggrConfig{
+					Rules: []StreamAggrRule{{Interval: "5m", Outputs: []string{"count_samples"}}},
+				},
+			},
+		},
+		{
+			name: "stream aggregation without outputs",
+			spec: VMAgentSpec{
+				RemoteWrite: []VMAgentRemoteWriteSpec{{URL: "http://some-rw"}},
+				StreamAggrConfig: &StreamAggrConfig{
+					Rules: []StreamAggrRule{{Interval: "5m"}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "remoteWrite stream aggregation without interval",
+			spec: VMAgentSpec{
+				RemoteWrite: []VMAgentRemoteWriteSpec{{
+					URL: "http://some-rw",
+					StreamAggrConfig: &StreamAggrConfig{
+						Rules: []StreamAggrRule{{Outputs: []string{"total"}}},
+					},
+				}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid maxDiskUsage",
+			spec: VMAgentSpec{
+				RemoteWrite: []VMAgentRemoteWriteSpec{
+					{URL: "http://some-rw", MaxDiskUsage: ptr.To("1073741824")},
+					{URL: "http://some-rw-2", MaxDiskUsage: ptr.To("5GiB")},
+					{URL: "http://some-rw-3", MaxDiskUsage: ptr.To("500MB")},
+				},
+			},
+		},
+		{
+			name: "bad maxDiskUsage",
+			spec: VMAgentSpec{
+				RemoteWrite: []VMAgentRemoteWriteSpec{
+					{URL: "http://some-rw"},
+					{URL: "http://some-rw-2", MaxDiskUsage: ptr.To("5Gi")},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid proxyURL",
+			spec: VMAgentSpec{
+				RemoteWrite: []VMAgentRemoteWriteSpec{
+					{URL: "http://some-rw", ProxyURL: ptr.To("http://proxy:3128")},
+					{URL: "http://some-rw-2", ProxyURL: ptr.To("socks5://proxy:1080"), ProxyBasicAuth: &BasicAuth{}},
+				},
+			},
+		},
+		{
+			name: "unsupported proxyURL scheme",
+			spec: VMAgentSpec{
+				RemoteWrite: []VMAgentRemoteWriteSpec{
+					{URL: "http://some-rw", ProxyURL: ptr.To("ftp://proxy:3128")},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "proxyBasicAuth without proxyURL",
+			spec: VMAgentSpec{
+				RemoteWrite: []VMAgentRemoteWriteSpec{
+					{URL: "http://some-rw", ProxyBasicAuth: &BasicAuth{}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid daemonSetMode",
+			spec: VMAgentSpec{
+				RemoteWrite:   []VMAgentRemoteWriteSpec{{URL: "http://some-rw"}},
+				DaemonSetMode: true,
+			},
+		},
+		{
+			name: "daemonSetMode with statefulMode",
+			spec: VMAgentSpec{
+				RemoteWrite:   []VMAgentRemoteWriteSpec{{URL: "http://some-rw"}},
+				DaemonSetMode: true,
+				StatefulMode:  true,
+			},
+			wantErr: true,
+		},
+		{
+			name: "daemonSetMode with shardCount",
+			spec: VMAgentSpec{
+				RemoteWrite:   []VMAgentRemoteWriteSpec{{URL: "http://some-rw"}},
+				DaemonSetMode: true,
+				ShardCount:    ptr.To(2),
+			},
+			wantErr: true,
+		},
+		{
+			name: "daemonSetMode with replicaCount",
+			spec: VMAgentSpec{
+				RemoteWrite:   []VMAgentRemoteWriteSpec{{URL: "http://some-rw"}},
+				DaemonSetMode: true,
+				CommonApplicationDeploymentParams: CommonApplicationDeploymentParams{
+					ReplicaCount: ptr.To[int32](2),
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "tenantRouting without placeholder",
+			spec: VMAgentSpec{
+				RemoteWrite: []VMAgentRemoteWriteSpec{{URL: "http://some-rw"}},
+				TenantRouting: &VMAgentTenantRouting{
+					URLTemplate:    "http://vminsert:8480/insert/0/prometheus/api/v1/write",
+					NamespaceLabel: "tenant",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "tenantRouting without namespace source",
+			spec: VMAgentSpec{
+				RemoteWrite: []VMAgentRemoteWriteSpec{{URL: "http://some-rw"}},
+				TenantRouting: &VMAgentTenantRouting{
+					URLTemplate: "http://vminsert:8480/insert/{{tenant}}/prometheus/api/v1/write",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "tenantRouting ok",
+			spec: VMAgentSpec{
+				RemoteWrite: []VMAgentRemoteWriteSpec{{URL: "http://some-rw"}},
+				TenantRouting: &VMAgentTenantRouting{
+					URLTemplate:         "http://vminsert:8480/insert/{{tenant}}/prometheus/api/v1/write",
+					NamespaceAnnotation: "victoriametrics.com/tenant",
+				},
+			},
+		},
+		{
+			name: "kafka without enterprise",
+			spec: VMAgentSpec{
+				RemoteWrite: []VMAgentRemoteWriteSpec{{
+					Kafka: &VMAgentRemoteWriteKafka{Brokers: []string{"kafka:9092"}, Topic: "metrics"},
+				}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "kafka with url",
+			spec: VMAgentSpec{
+				AcceptEULA: true,
+				RemoteWrite: []VMAgentRemoteWriteSpec{{
+					URL:   "http://some-rw",
+					Kafka: &VMAgentRemoteWriteKafka{Brokers: []string{"kafka:9092"}, Topic: "metrics"},
+				}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "kafka and pubsub",
+			spec: VMAgentSpec{
+				AcceptEULA: true,
+				RemoteWrite: []VMAgentRemoteWriteSpec{{
+					Kafka:  &VMAgentRemoteWriteKafka{Brokers: []string{"kafka:9092"}, Topic: "metrics"},
+					PubSub: &VMAgentRemoteWritePubSub{Project: "project", Topic: "metrics"},
+				}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "kafka ok",
+			spec: VMAgentSpec{
+				AcceptEULA: true,
+				RemoteWrite: []VMAgentRemoteWriteSpec{{
+					Kafka: &VMAgentRemoteWriteKafka{Brokers: []string{"kafka:9092"}, Topic: "metrics"},
+				}},
+			},
+		},
+		{
+			name: "pubsub without topic",
+			spec: VMAgentSpec{
+				AcceptEULA: true,
+				RemoteWrite: []VMAgentRemoteWriteSpec{{
+					PubSub: &VMAgentRemoteWritePubSub{Project: "project"},
+				}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "pubsub with enterprise image",
+			spec: VMAgentSpec{
+				CommonDefaultableParams: CommonDefaultableParams{
+					Image: Image{Tag: "v1.110.0-enterprise"},
+				},
+				RemoteWrite: []VMAgentRemoteWriteSpec{{
+					PubSub: &VMAgentRemoteWritePubSub{Project: "project", Topic: "metrics"},
+				}},
+			},
+		},
+		{
+			name: "legacyJobNames with jobLabelTemplate",
+			spec: VMAgentSpec{
+				RemoteWrite:      []VMAgentRemoteWriteSpec{{URL: "http://some-rw"}},
+				LegacyJobNames:   true,
+				JobLabelTemplate: "{{namespace}}/{{name}}",
+			},
+			wantErr: true,
+		},
+		{
+			name: "scrapeClasses ok",
+			spec: VMAgentSpec{
+				RemoteWrite: []VMAgentRemoteWriteSpec{{URL: "http://some-rw"}},
+				ScrapeClasses: []ScrapeClass{
+					{
+						Name:          "istio",
+						Default:       true,
+						TLSConfig:     &TLSConfig{CAFile: "/etc/istio-certs/root-cert.pem"},
+						Authorization: &Authorization{CredentialsFile: "/var/run/secrets/token"},
+					},
+					{Name: "plain"},
+				},
+			},
+		},
+		{
+			name: "scrapeClasses duplicated name",
+			spec: VMAgentSpec{
+				RemoteWrite:   []VMAgentRemoteWriteSpec{{URL: "http://some-rw"}},
+				ScrapeClasses: []ScrapeClass{{Name: "istio"}, {Name: "istio"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "globalMetricRelabelConfigs bad exemptSelector",
+			spec: VMAgentSpec{
+				RemoteWrite: []VMAgentRemoteWriteSpec{{URL: "http://some-rw"}},
+				GlobalMetricRelabelConfigs: &VMAgentGlobalMetricRelabelConfigs{
+					RelabelConfigs: []*RelabelConfig{{Action: "drop", SourceLabels: []string{"__name__"}, Regex: StringOrArray{"go_.*"}}},
+					ExemptSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "team", Operator: "bad"}}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "scrapeClasses multiple defaults",
+			spec: VMAgentSpec{
+				RemoteWrite:   []VMAgentRemoteWriteSpec{{URL: "http://some-rw"}},
+				ScrapeClasses: []ScrapeClass{{Name: "istio", Default: true}, {Name: "plain", Default: true}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "scrapeClasses with secret reference",
+			spec: VMAgentSpec{
+				RemoteWrite: []VMAgentRemoteWriteSpec{{URL: "http://some-rw"}},
+				ScrapeClasses: []ScrapeClass{{
+					Name: "istio",
+					TLSConfig: &TLSConfig{
+						KeySecret: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "tls"}, Key: "key"},
+					},
+				}},
+			},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
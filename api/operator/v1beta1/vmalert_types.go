@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net"
+	"net/url"
 	"strings"
 
 	appsv1 "k8s.io/api/apps/v1"
@@ -18,6 +20,28 @@ const (
 	// MetaVMAlertDeduplicateRulesKey - controls behavior for vmalert rules deduplication
 	// its useful for migration from prometheus.
 	MetaVMAlertDeduplicateRulesKey = "operator.victoriametrics.com/vmalert-deduplicate-rules"
+	// VMAlertIgnoreRuleAnnotation excludes VMRule with "true" value from all VMAlerts
+	VMAlertIgnoreRuleAnnotation = "operator.victoriametrics.com/vmalert-ignore"
+	// VMAlertRulesChecksumAnnotation holds checksum of rule files stored at generated ConfigMap or Secret
+	VMAlertRulesChecksumAnnotation = "operator.victoriametrics.com/rules-checksum"
+	// VMAlertRulesStorageSecret stores rule files at Secrets
+	VMAlertRulesStorageSecret = "secret"
+	// VMAlertRuleConfigMapLayoutPerNamespace stores rule files of each namespace at dedicated ConfigMaps
+	VMAlertRuleConfigMapLayoutPerNamespace = "perNamespace"
+	// VMAlertRuleShardingByGroup distributes VMRule groups across vmalert shards
+	VMAlertRuleShardingByGroup = "byGroup"
+	// VMAlertNoRulesSelectedCondition is set to True at VMAlert status if no VMRules were selected
+	VMAlertNoRulesSelectedCondition = "NoRulesSelected"
+	// VMAlertRulesLimitReachedCondition is set to True at VMAlert status if some VMRules were skipped due to maxTotalRules limit
+	VMAlertRulesLimitReachedCondition = "RulesLimitReached"
+	// VMAlertRulesReloadDegradedCondition is set to True at VMAlert status if vmalert pods didn't load new rules in time
+	VMAlertRulesReloadDegradedCondition = "RulesReloadDegraded"
+	// VMAlertExternalRuleSourcesMissingCondition is set to True at VMAlert status if some of spec.externalRuleSources are missing
+	VMAlertExternalRuleSourcesMissingCondition = "ExternalRuleSourcesMissing"
+	// VMAlertExternalRulesChecksumAnnotation holds checksum of rule files from spec.externalRuleSources
+	VMAlertExternalRulesChecksumAnnotation = "operator.victoriametrics.com/external-rules-checksum"
+	// VMAlertRemoteSecretsChecksumAnnotation holds checksum of auth secrets and tls assets of datasource, remoteRead, remoteWrite and notifiers
+	VMAlertRemoteSecretsChecksumAnnotation = "operator.victoriametrics.com/remote-secrets-checksum"
 )
 
 // VMAlertSpec defines the desired state of VMAlert
@@ -50,6 +74,17 @@ type VMAlertSpec struct {
 	// being created.
 	// +optional
 	EnforcedNamespaceLabel string `json:"enforcedNamespaceLabel,omitempty"`
+	// TenantLabelFromNamespaceAnnotation defines name of the VMRule namespace annotation
+	// with tenant id in form accountID[:projectID], e.g. operator.victoriametrics.com/tenant-id.
+	// Operator adds vm_account_id and vm_project_id extra_label params to each rule group
+	// and labels to each rule. It allows to use multitenant VMCluster endpoints for datasource and remoteWrite.
+	// VMRule is rejected, if its namespace doesn't have such annotation.
+	// +optional
+	TenantLabelFromNamespaceAnnotation string `json:"tenantLabelFromNamespaceAnnotation,omitempty"`
+	// RuleGroupDefaults defines settings, which are added to each selected VMRule group
+	// if group doesn't set them explicitly
+	// +optional
+	RuleGroupDefaults *VMAlertRuleGroupDefaults `json:"ruleGroupDefaults,omitempty"`
 	// SelectAllByDefault changes default behavior for empty CRD selectors, such RuleSelector.
 	// with selectAllByDefault: true and empty serviceScrapeSelector and RuleNamespaceSelector
 	// Operator selects all exist serviceScrapes
@@ -69,6 +104,94 @@ type VMAlertSpec struct {
 	// NamespaceSelector nil - only objects at VMAlert namespace.
 	// +optional
 	RuleNamespaceSelector *metav1.LabelSelector `json:"ruleNamespaceSelector,omitempty"`
+	// RuleDenySelector excludes VMRules matching it from the VMRules selected by RuleSelector and RuleNamespaceSelector.
+	// VMRule could be also excluded with annotation operator.victoriametrics.com/vmalert-ignore: "true"
+	// +optional
+	RuleDenySelector *metav1.LabelSelector `json:"ruleDenySelector,omitempty"`
+	// PlaceholderRulesRef references ConfigMap key with rule file content, which is used if no VMRules were selected.
+	// By default, rule file with empty groups list is used.
+	// +optional
+	PlaceholderRulesRef *v1.ConfigMapKeySelector `json:"placeholderRulesRef,omitempty"`
+	// RulesStorage defines kind of objects for generated rule files storage.
+	// Supported values are configmap and secret, by default configmap is used.
+	// Secret could be used, if rules contain sensitive data, e.g. bearer tokens at group params.
+	// Objects from previous storage kind are removed after vmalert deployment update
+	// +kubebuilder:validation:Enum=configmap;secret
+	// +optional
+	RulesStorage string `json:"rulesStorage,omitempty"`
+	// RuleConfigMapLayout defines how rule files are distributed across generated ConfigMaps.
+	// Supported values are flat and perNamespace, by default flat is used.
+	// With perNamespace each ConfigMap contains rule files of VMRules from a single namespace
+	// and is named vm-<name>-rulefiles-<namespace>-<num>
+	// +kubebuilder:validation:Enum=flat;perNamespace
+	// +optional
+	RuleConfigMapLayout string `json:"ruleConfigMapLayout,omitempty"`
+	// CompressRuleConfigMaps stores rule files gzip-compressed at ConfigMaps binaryData.
+	// It reduces the number of generated ConfigMaps for large amount of VMRules.
+	// Compressed rule files are unpacked by config-reloader into emptyDir volume before vmalert start
+	// Requires useVMConfigReloader: true
+	// +optional
+	CompressRuleConfigMaps *bool `json:"compressRuleConfigMaps,omitempty"`
+	// UseNamespaceRuleDirs stores rule files at namespace directories inside mounted rule volumes, e.g. team-a/rule.yaml,
+	// instead of flat file names, e.g. team-a-rule.yaml. It prevents file name collisions between VMRules
+	// and allows to use per namespace glob patterns. Since the mapping is defined at deployment volumes,
+	// any change of rule files set triggers VMAlert reconcile and vmalert rolling update.
+	// Cannot be used with compressRuleConfigMaps
+	// +optional
+	UseNamespaceRuleDirs *bool `json:"useNamespaceRuleDirs,omitempty"`
+	// RolloutOnRuleChange sets checksum of generated rule files as vmalert pod template annotation.
+	// Any change of rules content triggers rolling restart of vmalert pods
+	// instead of in-place config reload.
+	// +optional
+	RolloutOnRuleChange *bool `json:"rolloutOnRuleChange,omitempty"`
+	// RulesServerSideApply enables server-side apply for rule ConfigMaps with vm-operator field manager.
+	// Fields added to ConfigMaps by third-party controllers are preserved.
+	// Overrides operator -controller.vmalert.rulesServerSideApply flag
+	// +optional
+	RulesServerSideApply *bool `json:"rulesServerSideApply,omitempty"`
+	// RulesReloadCheck enables verification of rules reload at vmalert pods.
+	// After rule files update operator polls vmalert pods until new rules are loaded.
+	// It's ignored if rolloutOnRuleChange is set
+	// +optional
+	RulesReloadCheck *VMAlertRulesReloadCheck `json:"rulesReloadCheck,omitempty"`
+	// DisableRuleExprValidation disables validation of VMRule expressions with MetricsQL parser.
+	// It could be useful for vmalert-only query extensions, which cannot be parsed by MetricsQL.
+	// By default, groups with unparsable expressions are excluded from rule files.
+	// +optional
+	DisableRuleExprValidation *bool `json:"disableRuleExprValidation,omitempty"`
+	// MaxRulesPerGroup defines max number of rules at VMRule group.
+	// VMRule with group exceeding this limit is rejected. Zero value means no limit
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxRulesPerGroup int `json:"maxRulesPerGroup,omitempty"`
+	// MaxGroupsPerRule defines max number of groups at VMRule.
+	// VMRule exceeding this limit is rejected. Zero value means no limit
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxGroupsPerRule int `json:"maxGroupsPerRule,omitempty"`
+	// MaxTotalRules defines max number of rules selected by VMAlert.
+	// VMRules are selected in namespace/name order, once limit is reached the rest of VMRules are skipped
+	// and RulesLimitReached condition is set at VMAlert status. Zero value means no limit
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxTotalRules int `json:"maxTotalRules,omitempty"`
+	// RulePolicies defines label and annotation policies for selected VMRules.
+	// VMRule with rules violating policies is rejected
+	// +optional
+	RulePolicies *VMAlertRulePolicies `json:"rulePolicies,omitempty"`
+	// RuleShardingStrategy defines how rules are distributed across vmalert shards.
+	// Supported value is byGroup - each VMRule group is assigned to a single shard with consistent hashing.
+	// Operator creates dedicated ConfigMaps and deployment with -shard-<num> name suffix per shard.
+	// Requires shardCount to be greater than 1
+	// +kubebuilder:validation:Enum=byGroup
+	// +optional
+	RuleShardingStrategy string `json:"ruleShardingStrategy,omitempty"`
+	// ShardCount - numbers of shards of VMAlert
+	// in this case operator will use 1 deployment per shard with
+	// replicas count according to spec.replicas
+	// Requires ruleShardingStrategy to be set
+	// +optional
+	ShardCount *int `json:"shardCount,omitempty"`
 
 	// Notifier prometheus alertmanager endpoint spec. Required at least one of notifier or notifiers when there are alerting rules. e.g. http://127.0.0.1:9093
 	// If specified both notifier and notifiers, notifier will be added as last element to notifiers.
@@ -87,6 +210,19 @@ type VMAlertSpec struct {
 	// +optional
 	NotifierConfigRef *v1.SecretKeySelector `json:"notifierConfigRef,omitempty"`
 
+	// NotifierSelector defines VMAlertmanager objects to be discovered as notifiers.
+	// Works in combination with NotifierNamespaceSelector.
+	// NotifierNamespaceSelector nil - only objects at VMAlert namespace.
+	// Discovered alertmanagers are written into notifier config file, which is reloaded without pod restart.
+	// Cannot be used with notifier, notifiers and notifierConfigRef
+	// +optional
+	NotifierSelector *metav1.LabelSelector `json:"notifierSelector,omitempty"`
+	// NotifierNamespaceSelector defines namespaces for VMAlertmanager objects discovery.
+	// Works in combination with NotifierSelector.
+	// NotifierSelector nil - all VMAlertmanager objects at selected namespaces
+	// +optional
+	NotifierNamespaceSelector *metav1.LabelSelector `json:"notifierNamespaceSelector,omitempty"`
+
 	// RemoteWrite Optional URL to remote-write compatible storage to persist
 	// vmalert state and rule results to.
 	// Rule results will be persisted according to each rule.
@@ -113,6 +249,11 @@ type VMAlertSpec struct {
 	// by default operator adds /etc/vmalert/configs/base/vmalert.yaml
 	// +optional
 	RulePath []string `json:"rulePath,omitempty"`
+	// ExternalRuleSources defines ConfigMaps and Secrets with rule files managed outside of operator.
+	// E.g. rules rendered from git repository by third-party tool.
+	// Rule files are mounted into rules directory and added to -rule flag
+	// +optional
+	ExternalRuleSources []VMAlertExternalRuleSource `json:"externalRuleSources,omitempty"`
 	// Datasource Victoria Metrics or VMSelect url. Required parameter. e.g. http://127.0.0.1:8428
 	Datasource VMAlertDatasourceSpec `json:"datasource"`
 
@@ -120,6 +261,15 @@ type VMAlertSpec struct {
 	// +optional
 	ExternalLabels map[string]string `json:"externalLabels,omitempty"`
 
+	// HAMode enables deduplication of alert notifications for vmalert replicas,
+	// which evaluate the same rules.
+	// Operator adds vmalert_replica external label with pod name to rule results and alerts
+	// and drops it from alerts sent to notifiers, so alertmanager receives identical alerts from each replica.
+	// It requires spec.notifierSelector or spec.notifierConfigRef,
+	// notifierConfigRef must drop vmalert_replica label with alert_relabel_configs.
+	// +optional
+	HAMode bool `json:"haMode,omitempty"`
+
 	// ServiceSpec that will be added to vmalert service spec
 	// +optional
 	ServiceSpec *AdditionalServiceSpec `json:"serviceSpec,omitempty"`
@@ -134,6 +284,17 @@ type VMAlertSpec struct {
 	// RollingUpdate - overrides deployment update params.
 	// +optional
 	RollingUpdate *appsv1.RollingUpdateDeployment `json:"rollingUpdate,omitempty"`
+	// StatefulMode enables StatefulSet for `VMAlert` instead of Deployment
+	// it provides stable pod names and allows using persistent volumes defined at claimTemplates
+	// +optional
+	StatefulMode bool `json:"statefulMode,omitempty"`
+	// StatefulRollingUpdateStrategy allows configuration for strategyType
+	// set it to RollingUpdate for disabling operator statefulSet rollingUpdate
+	// +optional
+	StatefulRollingUpdateStrategy appsv1.StatefulSetUpdateStrategyType `json:"statefulRollingUpdateStrategy,omitempty"`
+	// ClaimTemplates allows adding additional VolumeClaimTemplates for VMAlert in StatefulMode
+	// +optional
+	ClaimTemplates []v1.PersistentVolumeClaim `json:"claimTemplates,omitempty"`
 	// PodDisruptionBudget created by operator
 	// +optional
 	PodDisruptionBudget *EmbeddedPodDisruptionBudgetSpec `json:"podDisruptionBudget,omitempty"`
@@ -180,6 +341,64 @@ func (cr *VMAlertSpec) UnmarshalJSON(src []byte) error {
 	return nil
 }
 
+// VMAlertRuleGroupDefaults defines default settings for rule groups selected by VMAlert
+// +k8s:openapi-gen=true
+type VMAlertRuleGroupDefaults struct {
+	// Params optional HTTP URL parameters added to each rule request
+	// group params take precedence over default params with the same name
+	// +optional
+	Params url.Values `json:"params,omitempty"`
+	// Headers optional HTTP headers in form `header-name: value` added to each rule request
+	// group headers take precedence over default headers with the same name
+	// +optional
+	Headers []string `json:"headers,omitempty"`
+	// EvalInterval defines evaluation interval for groups without interval
+	// +optional
+	EvalInterval string `json:"evalInterval,omitempty"`
+	// Concurrency defines how many rules execute at once for groups without concurrency
+	// +optional
+	Concurrency int `json:"concurrency,omitempty"`
+}
+
+// VMAlertRulePolicies defines label and annotation policies for rules selected by VMAlert.
+// Group labels are considered as rule labels.
+// +k8s:openapi-gen=true
+type VMAlertRulePolicies struct {
+	// RequiredLabels defines label keys, which must be set for each rule
+	// +optional
+	RequiredLabels []string `json:"requiredLabels,omitempty"`
+	// ForbiddenLabels defines label keys, which must not be set for any rule
+	// +optional
+	ForbiddenLabels []string `json:"forbiddenLabels,omitempty"`
+	// RequiredAnnotations defines annotation keys, which must be set for each rule
+	// +optional
+	RequiredAnnotations []string `json:"requiredAnnotations,omitempty"`
+	// MaxAnnotationValueLength defines max length of rule annotation value. Zero value means no limit
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxAnnotationValueLength int `json:"maxAnnotationValueLength,omitempty"`
+	// ApplyToRecordingRules enables policies for recording rules.
+	// By default, policies are applied to alerting rules only
+	// +optional
+	ApplyToRecordingRules *bool `json:"applyToRecordingRules,omitempty"`
+}
+
+// VMAlertExternalRuleSource defines ConfigMap or Secret with vmalert rule files.
+// Only one of configMap or secret must be set
+// +k8s:openapi-gen=true
+type VMAlertExternalRuleSource struct {
+	// ConfigMap defines name of ConfigMap with rule files at VMAlert namespace
+	// +optional
+	ConfigMap *v1.LocalObjectReference `json:"configMap,omitempty"`
+	// Secret defines name of Secret with rule files at VMAlert namespace
+	// +optional
+	Secret *v1.LocalObjectReference `json:"secret,omitempty"`
+	// Keys defines object keys with rule files.
+	// By default, all keys with .yaml suffix are used
+	// +optional
+	Keys []string `json:"keys,omitempty"`
+}
+
 // VMAlertDatasourceSpec defines the remote storage configuration for VmAlert to read alerts from
 // +k8s:openapi-gen=true
 type VMAlertDatasourceSpec struct {
@@ -248,6 +467,44 @@ type VMAlertRemoteWriteSpec struct {
 // +k8s:openapi-gen=true
 type VMAlertStatus struct {
 	StatusMetadata `json:",inline"`
+	// RuleSync describes rule files generated for VMAlert at the last reconcile
+	// +optional
+	RuleSync *VMAlertRuleSyncStatus `json:"ruleSync,omitempty"`
+}
+
+// VMAlertRulesReloadCheck defines verification of rules reload at vmalert pods
+type VMAlertRulesReloadCheck struct {
+	// Timeout defines deadline for vmalert pods to load new rules
+	// If pods didn't load rules in time, RulesReloadDegraded condition is set at VMAlert status
+	// +kubebuilder:validation:Pattern:="^([0-9]+(ms|s|m|h))+$"
+	// +kubebuilder:default:="60s"
+	// +optional
+	Timeout string `json:"timeout,omitempty"`
+	// TLSConfig for requests to vmalert pods, if vmalert listens on https
+	// +optional
+	TLSConfig *TLSConfig `json:"tlsConfig,omitempty"`
+}
+
+// VMAlertRuleSyncStatus describes rule files generated from selected VMRules
+type VMAlertRuleSyncStatus struct {
+	// ConfigMaps contains names of generated ConfigMaps or Secrets with rule files
+	// +optional
+	ConfigMaps []string `json:"configMaps,omitempty"`
+	// RuleFiles is a total number of rule files generated from VMRules
+	RuleFiles int `json:"ruleFiles"`
+	// RuleFilesHash is a hash of rule file paths mounted with useNamespaceRuleDirs.
+	// Its change triggers VMAlert reconcile, which mounts new rule files
+	// +optional
+	RuleFilesHash string `json:"ruleFilesHash,omitempty"`
+	// Groups is a total number of rule groups at rule files
+	Groups int `json:"groups"`
+	// Rules is a total number of rules at rule files
+	Rules int `json:"rules"`
+	// RejectedRules is a number of VMRules rejected fully or partially
+	RejectedRules int `json:"rejectedRules"`
+	// LastSyncTime is the time of the last rules sync, which changed this status
+	// +optional
+	LastSyncTime metav1.Time `json:"lastSyncTime,omitempty"`
 }
 
 // GetStatusMetadata returns metadata for object status
@@ -404,6 +661,47 @@ func (cr *VMAlert) GetServiceScrape() *VMServiceScrapeSpec {
 	return cr.Spec.ServiceScrapeSpec
 }
 
+// IsNotifierDiscoveryEnabled checks if VMAlertmanager objects must be discovered as notifiers
+func (cr *VMAlert) IsNotifierDiscoveryEnabled() bool {
+	return cr.Spec.NotifierSelector != nil || cr.Spec.NotifierNamespaceSelector != nil
+}
+
+// VMAlertReplicaLabel is an external label added to rule results and alerts of each vmalert replica at haMode
+const VMAlertReplicaLabel = "vmalert_replica"
+
+// NotifiersConfigName returns name of Secret with discovered notifiers config
+func (cr *VMAlert) NotifiersConfigName() string {
+	return fmt.Sprintf("%s-notifiers", cr.PrefixedName())
+}
+
+// PodRulesURL returns url of rules API for the given vmalert pod address
+func (cr *VMAlert) PodRulesURL(podIP string) string {
+	port := cr.Spec.Port
+	if port == "" {
+		port = "8080"
+	}
+	return fmt.Sprintf("%s://%s%s", protoFromFlags(cr.Spec.ExtraArgs), net.JoinHostPort(podIP, port), buildPathWithPrefixFlag(cr.Spec.ExtraArgs, "/api/v1/rules"))
+}
+
+// RuleShardsCount returns number of vmalert shards
+// it returns 1 if rule sharding is not enabled
+func (cr *VMAlert) RuleShardsCount() int {
+	if cr.Spec.RuleShardingStrategy == "" || cr.Spec.ShardCount == nil || *cr.Spec.ShardCount < 1 {
+		return 1
+	}
+	return *cr.Spec.ShardCount
+}
+
+// IsRuleConfigMapPerNamespace checks if rule files must be stored at per namespace ConfigMaps
+func (cr *VMAlert) IsRuleConfigMapPerNamespace() bool {
+	return cr.Spec.RuleConfigMapLayout == VMAlertRuleConfigMapLayoutPerNamespace
+}
+
+// IsRulesStorageSecret checks if rule files must be stored at Secrets
+func (cr *VMAlert) IsRulesStorageSecret() bool {
+	return cr.Spec.RulesStorage == VMAlertRulesStorageSecret
+}
+
 func (cr *VMAlert) NeedDedupRules() bool {
 	return cr.ObjectMeta.Annotations[MetaVMAlertDeduplicateRulesKey] != ""
 }
@@ -492,6 +790,9 @@ func (cr *VMAlert) SetUpdateStatusTo(ctx context.Context, r client.Client, statu
 		cr:           cr,
 		crStatus:     &cr.Status,
 		maybeErr:     maybeErr,
+		statusOf: func(pcr *VMAlert) *VMAlertStatus {
+			return &pcr.Status
+		},
 	})
 }
 
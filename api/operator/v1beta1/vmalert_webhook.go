@@ -20,8 +20,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
@@ -56,8 +59,106 @@ func (r *VMAlert) sanityCheck() error {
 			return fmt.Errorf("notifier.url is empty and selector is not set, provide at least once for spec.notifiers at idx: %d", idx)
 		}
 	}
+	if ptr.Deref(r.Spec.CompressRuleConfigMaps, false) && !ptr.Deref(r.Spec.UseVMConfigReloader, false) {
+		return fmt.Errorf("spec.compressRuleConfigMaps requires spec.useVMConfigReloader to be enabled")
+	}
+	if ptr.Deref(r.Spec.CompressRuleConfigMaps, false) && ptr.Deref(r.Spec.UseNamespaceRuleDirs, false) {
+		return fmt.Errorf("spec.compressRuleConfigMaps cannot be used with spec.useNamespaceRuleDirs")
+	}
+	if rc := r.Spec.RulesReloadCheck; rc != nil {
+		if rc.Timeout != "" {
+			if _, err := time.ParseDuration(rc.Timeout); err != nil {
+				return fmt.Errorf("cannot parse spec.rulesReloadCheck.timeout: %w", err)
+			}
+		}
+		if rc.TLSConfig != nil {
+			if err := rc.TLSConfig.Validate(); err != nil {
+				return fmt.Errorf("incorrect spec.rulesReloadCheck.tlsConfig: %w", err)
+			}
+			if rc.TLSConfig.CAFile != "" || rc.TLSConfig.CertFile != "" || rc.TLSConfig.KeyFile != "" {
+				return fmt.Errorf("spec.rulesReloadCheck.tlsConfig supports only secret and configmap references, file paths are not allowed")
+			}
+		}
+	}
+	if rp := r.Spec.RulePolicies; rp != nil {
+		forbidden := make(map[string]struct{}, len(rp.ForbiddenLabels))
+		for _, key := range rp.ForbiddenLabels {
+			if key == "" {
+				return fmt.Errorf("spec.rulePolicies.forbiddenLabels cannot contain empty key")
+			}
+			forbidden[key] = struct{}{}
+		}
+		for _, key := range rp.RequiredLabels {
+			if key == "" {
+				return fmt.Errorf("spec.rulePolicies.requiredLabels cannot contain empty key")
+			}
+			if _, ok := forbidden[key]; ok {
+				return fmt.Errorf("label=%q cannot be both required and forbidden at spec.rulePolicies", key)
+			}
+		}
+		for _, key := range rp.RequiredAnnotations {
+			if key == "" {
+				return fmt.Errorf("spec.rulePolicies.requiredAnnotations cannot contain empty key")
+			}
+		}
+	}
+	externalSources := make(map[string]struct{}, len(r.Spec.ExternalRuleSources))
+	for idx, src := range r.Spec.ExternalRuleSources {
+		var key string
+		switch {
+		case src.ConfigMap != nil && src.Secret != nil:
+			return fmt.Errorf("spec.externalRuleSources at idx=%d must have only one of configMap or secret", idx)
+		case src.ConfigMap != nil && src.ConfigMap.Name != "":
+			key = "configmap/" + src.ConfigMap.Name
+		case src.Secret != nil && src.Secret.Name != "":
+			key = "secret/" + src.Secret.Name
+		default:
+			return fmt.Errorf("spec.externalRuleSources at idx=%d must have configMap or secret name", idx)
+		}
+		if _, ok := externalSources[key]; ok {
+			return fmt.Errorf("spec.externalRuleSources has duplicate source=%s", key)
+		}
+		externalSources[key] = struct{}{}
+		for _, k := range src.Keys {
+			if k == "" {
+				return fmt.Errorf("spec.externalRuleSources at idx=%d cannot contain empty key", idx)
+			}
+		}
+	}
+	if r.Spec.RuleDenySelector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(r.Spec.RuleDenySelector); err != nil {
+			return fmt.Errorf("cannot parse spec.ruleDenySelector: %w", err)
+		}
+	}
+	if r.Spec.RuleShardingStrategy != "" && ptr.Deref(r.Spec.ShardCount, 0) < 2 {
+		return fmt.Errorf("spec.ruleShardingStrategy requires spec.shardCount to be greater than 1")
+	}
+	if r.Spec.RuleShardingStrategy == "" && ptr.Deref(r.Spec.ShardCount, 0) > 1 {
+		return fmt.Errorf("spec.shardCount requires spec.ruleShardingStrategy to be set")
+	}
+	if r.IsNotifierDiscoveryEnabled() {
+		if r.Spec.Notifier != nil || len(r.Spec.Notifiers) > 0 || r.Spec.NotifierConfigRef != nil {
+			return fmt.Errorf("spec.notifierSelector and spec.notifierNamespaceSelector cannot be used with spec.notifier, spec.notifiers and spec.notifierConfigRef")
+		}
+		for name, sel := range map[string]*metav1.LabelSelector{"notifierSelector": r.Spec.NotifierSelector, "notifierNamespaceSelector": r.Spec.NotifierNamespaceSelector} {
+			if _, err := metav1.LabelSelectorAsSelector(sel); err != nil {
+				return fmt.Errorf("cannot parse spec.%s: %w", name, err)
+			}
+		}
+	}
+	if len(r.Spec.ClaimTemplates) > 0 && !r.Spec.StatefulMode {
+		return fmt.Errorf("spec.claimTemplates requires spec.statefulMode to be enabled")
+	}
+	if r.Spec.HAMode {
+		if r.Spec.Notifier != nil || len(r.Spec.Notifiers) > 0 {
+			return fmt.Errorf("spec.haMode cannot be used with spec.notifier and spec.notifiers, replica label cannot be dropped for static notifiers, use spec.notifierSelector or spec.notifierConfigRef instead")
+		}
+		if _, ok := r.Spec.ExternalLabels[VMAlertReplicaLabel]; ok {
+			return fmt.Errorf("spec.externalLabels cannot contain %s label with spec.haMode", VMAlertReplicaLabel)
+		}
+	}
 	if _, ok := r.Spec.ExtraArgs["notifier.blackhole"]; !ok {
-		if r.Spec.Notifier == nil && len(r.Spec.Notifiers) == 0 && r.Spec.NotifierConfigRef == nil {
+		if r.Spec.Notifier == nil && len(r.Spec.Notifiers) == 0 && r.Spec.NotifierConfigRef == nil && !r.IsNotifierDiscoveryEnabled() {
 			return fmt.Errorf("vmalert should have at least one notifier.url or enable `-notifier.blackhole`")
 		}
 	}
@@ -2,6 +2,10 @@ package v1beta1
 
 import (
 	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
 )
 
 func TestVMAlert_sanityCheck(t *testing.T) {
@@ -29,6 +33,210 @@ func TestVMAlert_sanityCheck(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "compressed rules wo vm config-reloader",
+			spec: VMAlertSpec{
+				Datasource:             VMAlertDatasourceSpec{URL: "http://some-url"},
+				Notifier:               &VMAlertNotifierSpec{URL: "http://some-url"},
+				CompressRuleConfigMaps: ptr.To(true),
+			},
+			wantErr: true,
+		},
+		{
+			name: "compressed rules with vm config-reloader",
+			spec: VMAlertSpec{
+				Datasource:             VMAlertDatasourceSpec{URL: "http://some-url"},
+				Notifier:               &VMAlertNotifierSpec{URL: "http://some-url"},
+				CompressRuleConfigMaps: ptr.To(true),
+				CommonConfigReloaderParams: CommonConfigReloaderParams{
+					UseVMConfigReloader: ptr.To(true),
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "compressed rules with namespace rule dirs",
+			spec: VMAlertSpec{
+				Datasource:             VMAlertDatasourceSpec{URL: "http://some-url"},
+				Notifier:               &VMAlertNotifierSpec{URL: "http://some-url"},
+				CompressRuleConfigMaps: ptr.To(true),
+				UseNamespaceRuleDirs:   ptr.To(true),
+				CommonConfigReloaderParams: CommonConfigReloaderParams{
+					UseVMConfigReloader: ptr.To(true),
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid rule deny selector",
+			spec: VMAlertSpec{
+				Datasource: VMAlertDatasourceSpec{URL: "http://some-url"},
+				Notifier:   &VMAlertNotifierSpec{URL: "http://some-url"},
+				RuleDenySelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "team", Operator: "Unknown"},
+				}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "rule sharding without shard count",
+			spec: VMAlertSpec{
+				Datasource:           VMAlertDatasourceSpec{URL: "http://some-url"},
+				Notifier:             &VMAlertNotifierSpec{URL: "http://some-url"},
+				RuleShardingStrategy: VMAlertRuleShardingByGroup,
+			},
+			wantErr: true,
+		},
+		{
+			name: "shard count without rule sharding",
+			spec: VMAlertSpec{
+				Datasource: VMAlertDatasourceSpec{URL: "http://some-url"},
+				Notifier:   &VMAlertNotifierSpec{URL: "http://some-url"},
+				ShardCount: ptr.To(2),
+			},
+			wantErr: true,
+		},
+		{
+			name: "rule sharding by group",
+			spec: VMAlertSpec{
+				Datasource:           VMAlertDatasourceSpec{URL: "http://some-url"},
+				Notifier:             &VMAlertNotifierSpec{URL: "http://some-url"},
+				RuleShardingStrategy: VMAlertRuleShardingByGroup,
+				ShardCount:           ptr.To(2),
+			},
+			wantErr: false,
+		},
+		{
+			name: "notifier selector",
+			spec: VMAlertSpec{
+				Datasource:       VMAlertDatasourceSpec{URL: "http://some-url"},
+				NotifierSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "a"}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "claim templates without stateful mode",
+			spec: VMAlertSpec{
+				Datasource:     VMAlertDatasourceSpec{URL: "http://some-url"},
+				Notifier:       &VMAlertNotifierSpec{URL: "http://some-url"},
+				ClaimTemplates: []corev1.PersistentVolumeClaim{{ObjectMeta: metav1.ObjectMeta{Name: "data"}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "ha mode with notifier selector",
+			spec: VMAlertSpec{
+				Datasource:       VMAlertDatasourceSpec{URL: "http://some-url"},
+				NotifierSelector: &metav1.LabelSelector{},
+				HAMode:           true,
+			},
+			wantErr: false,
+		},
+		{
+			name: "ha mode with static notifier",
+			spec: VMAlertSpec{
+				Datasource: VMAlertDatasourceSpec{URL: "http://some-url"},
+				Notifier:   &VMAlertNotifierSpec{URL: "http://some-url"},
+				HAMode:     true,
+			},
+			wantErr: true,
+		},
+		{
+			name: "notifier selector with notifier",
+			spec: VMAlertSpec{
+				Datasource:                VMAlertDatasourceSpec{URL: "http://some-url"},
+				Notifier:                  &VMAlertNotifierSpec{URL: "http://some-url"},
+				NotifierNamespaceSelector: &metav1.LabelSelector{},
+			},
+			wantErr: true,
+		},
+		{
+			name: "rules reload check with invalid timeout",
+			spec: VMAlertSpec{
+				Datasource:       VMAlertDatasourceSpec{URL: "http://some-url"},
+				Notifier:         &VMAlertNotifierSpec{URL: "http://some-url"},
+				RulesReloadCheck: &VMAlertRulesReloadCheck{Timeout: "1 minute"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "rules reload check",
+			spec: VMAlertSpec{
+				Datasource:       VMAlertDatasourceSpec{URL: "http://some-url"},
+				Notifier:         &VMAlertNotifierSpec{URL: "http://some-url"},
+				RulesReloadCheck: &VMAlertRulesReloadCheck{Timeout: "30s"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "rules reload check with tls files",
+			spec: VMAlertSpec{
+				Datasource:       VMAlertDatasourceSpec{URL: "http://some-url"},
+				Notifier:         &VMAlertNotifierSpec{URL: "http://some-url"},
+				RulesReloadCheck: &VMAlertRulesReloadCheck{TLSConfig: &TLSConfig{CAFile: "/etc/ssl/ca.crt"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "rule policies",
+			spec: VMAlertSpec{
+				Datasource: VMAlertDatasourceSpec{URL: "http://some-url"},
+				Notifier:   &VMAlertNotifierSpec{URL: "http://some-url"},
+				RulePolicies: &VMAlertRulePolicies{
+					RequiredLabels:           []string{"severity", "team"},
+					ForbiddenLabels:          []string{"instance"},
+					MaxAnnotationValueLength: 512,
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "rule policies with required and forbidden label",
+			spec: VMAlertSpec{
+				Datasource: VMAlertDatasourceSpec{URL: "http://some-url"},
+				Notifier:   &VMAlertNotifierSpec{URL: "http://some-url"},
+				RulePolicies: &VMAlertRulePolicies{
+					RequiredLabels:  []string{"severity"},
+					ForbiddenLabels: []string{"severity"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "external rule sources",
+			spec: VMAlertSpec{
+				Datasource: VMAlertDatasourceSpec{URL: "http://some-url"},
+				Notifier:   &VMAlertNotifierSpec{URL: "http://some-url"},
+				ExternalRuleSources: []VMAlertExternalRuleSource{
+					{ConfigMap: &corev1.LocalObjectReference{Name: "git-rules"}, Keys: []string{"alerts.yaml"}},
+					{Secret: &corev1.LocalObjectReference{Name: "git-rules"}},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "external rule source with configmap and secret",
+			spec: VMAlertSpec{
+				Datasource: VMAlertDatasourceSpec{URL: "http://some-url"},
+				Notifier:   &VMAlertNotifierSpec{URL: "http://some-url"},
+				ExternalRuleSources: []VMAlertExternalRuleSource{
+					{ConfigMap: &corev1.LocalObjectReference{Name: "git-rules"}, Secret: &corev1.LocalObjectReference{Name: "git-rules"}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "duplicate external rule sources",
+			spec: VMAlertSpec{
+				Datasource: VMAlertDatasourceSpec{URL: "http://some-url"},
+				Notifier:   &VMAlertNotifierSpec{URL: "http://some-url"},
+				ExternalRuleSources: []VMAlertExternalRuleSource{
+					{ConfigMap: &corev1.LocalObjectReference{Name: "git-rules"}},
+					{ConfigMap: &corev1.LocalObjectReference{Name: "git-rules"}, Keys: []string{"alerts.yaml"}},
+				},
+			},
+			wantErr: true,
+		},
 		{
 			name: "wo notifier url",
 			spec: VMAlertSpec{
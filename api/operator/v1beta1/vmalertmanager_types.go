@@ -4,7 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net"
 	"path"
+	"strings"
 
 	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
@@ -62,6 +64,12 @@ type VMAlertmanagerSpec struct {
 	// The Templates are mounted into /etc/vm/templates/<configmap-name>/<configmap-key>.
 	// +optional
 	Templates []ConfigMapKeyReference `json:"templates,omitempty"`
+	// TemplateSecrets is a list of Secret key references for Secrets in the same namespace as the VMAlertmanager
+	// object, which shall be mounted into the VMAlertmanager Pods.
+	// The TemplateSecrets are mounted into /etc/vm/templates/<secret-name>/<secret-key>.
+	// Secret names must not overlap with ConfigMap names defined at Templates.
+	// +optional
+	TemplateSecrets []v1.SecretKeySelector `json:"templateSecrets,omitempty"`
 
 	// ConfigRawYaml - raw configuration for alertmanager,
 	// it helps it to start without secret.
@@ -77,6 +85,18 @@ type VMAlertmanagerSpec struct {
 	// +optional
 	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Secret with alertmanager config",xDescriptors="urn:alm:descriptor:io.kubernetes:Secret"
 	ConfigSecret string `json:"configSecret,omitempty"`
+	// ConfigSecretMergeStrategy defines how selected VMAlertmanagerConfigs are merged with configuration from ConfigSecret.
+	// base - ConfigSecret is used as base configuration, routes of VMAlertmanagerConfigs are added after its routes, it's default behaviour.
+	// override - routes of VMAlertmanagerConfigs are added before routes of ConfigSecret, so they take precedence.
+	// disabled - ConfigSecret is used as is, VMAlertmanagerConfigs are not merged.
+	// +kubebuilder:validation:Enum=base;override;disabled
+	// +optional
+	ConfigSecretMergeStrategy string `json:"configSecretMergeStrategy,omitempty"`
+	// GlobalConfig defines global section of alertmanager configuration.
+	// Selected VMAlertmanagerConfigs are merged beneath it.
+	// It cannot be used together with ConfigSecret or with global section defined at ConfigRawYaml.
+	// +optional
+	GlobalConfig *AlertmanagerGlobalConfig `json:"globalConfig,omitempty"`
 	// Log level for VMAlertmanager to be configured with.
 	// +optional
 	// +kubebuilder:validation:Enum=debug;info;warn;error;DEBUG;INFO;WARN;ERROR
@@ -107,6 +127,12 @@ type VMAlertmanagerSpec struct {
 	// under a different route prefix. For example for use with `kubectl proxy`.
 	// +optional
 	RoutePrefix string `json:"routePrefix,omitempty"`
+	// ExposeViaVMAuth configures managed VMUser, which exposes VMAlertmanager web UI and API via VMAuth.
+	// Path prefix is stripped by VMAuth, so routePrefix must not be set.
+	// If ExternalURL is empty, it defaults to the alertmanager pod address with the same path.
+	// It should point to the VMAuth address in order to generate correct links at notifications
+	// +optional
+	ExposeViaVMAuth *AlertmanagerVMAuthExposure `json:"exposeViaVMAuth,omitempty"`
 
 	// ClusterDomainName defines domain name suffix for in-cluster dns addresses
 	// aka .cluster.local
@@ -160,7 +186,7 @@ type VMAlertmanagerSpec struct {
 	// +optional
 	ConfigNamespaceSelector *metav1.LabelSelector `json:"configNamespaceSelector,omitempty"`
 
-	// DisableNamespaceMatcher disables top route namespace label matcher for VMAlertmanagerConfig
+	// DisableNamespaceMatcher disables namespace label matcher for VMAlertmanagerConfig top route and inhibit rules
 	// It may be useful if alert doesn't have namespace label for some reason
 	// +optional
 	DisableNamespaceMatcher bool `json:"disableNamespaceMatcher,omitempty"`
@@ -191,6 +217,11 @@ type VMAlertmanagerSpec struct {
 	// GossipConfig defines gossip TLS configuration for Alertmanager cluster
 	// +optional
 	GossipConfig *AlertmanagerGossipConfig `json:"gossipConfig,omitempty"`
+	// ClusterTLS enables mTLS for gossip communication between Alertmanager replicas.
+	// Operator generates gossip TLS configuration with certificate from the given Secret or cert-manager issuer.
+	// Mutually exclusive with gossipConfig
+	// +optional
+	ClusterTLS *AlertmanagerClusterTLS `json:"clusterTLS,omitempty"`
 	// ServiceAccountName is the name of the ServiceAccount to use to run the pods
 	// +optional
 	ServiceAccountName string `json:"serviceAccountName,omitempty"`
@@ -243,6 +274,25 @@ type VMAlertmanagerList struct {
 // Operator API itself. More info:
 type VMAlertmanagerStatus struct {
 	StatusMetadata `json:",inline"`
+	// ConfigSummary contains counters of the last generated alertmanager configuration
+	// +optional
+	ConfigSummary *VMAlertmanagerConfigSummary `json:"configSummary,omitempty"`
+}
+
+// VMAlertmanagerConfigSummary defines counters of the generated alertmanager configuration.
+// Routing tree of the configuration is published into ConfigMap vmalertmanager-<name>-route-summary
+type VMAlertmanagerConfigSummary struct {
+	// Receivers is a number of receivers at the generated configuration
+	Receivers int32 `json:"receivers"`
+	// Routes is a number of routes at the generated configuration, excluding the top-level route
+	Routes int32 `json:"routes"`
+	// InhibitRules is a number of inhibit rules at the generated configuration
+	InhibitRules int32 `json:"inhibitRules"`
+	// ConfigsMerged is a number of VMAlertmanagerConfigs merged into the configuration
+	ConfigsMerged int32 `json:"configsMerged"`
+	// ConfigsSkipped is a number of selected VMAlertmanagerConfigs, which were excluded from configuration because of errors.
+	// Error is reported at status of VMAlertmanagerConfig
+	ConfigsSkipped int32 `json:"configsSkipped"`
 }
 
 // GetStatusMetadata returns metadata for object status
@@ -331,6 +381,34 @@ func (cr *VMAlertmanager) PrefixedName() string {
 	return fmt.Sprintf("vmalertmanager-%s", cr.Name)
 }
 
+// RouteSummaryConfigMapName returns name of the ConfigMap with routing tree summary of the generated configuration
+func (cr *VMAlertmanager) RouteSummaryConfigMapName() string {
+	return fmt.Sprintf("%s-route-summary", cr.PrefixedName())
+}
+
+// VMAuthExposurePath returns normalized request path prefix, which is routed from VMAuth to alertmanager
+func (cr *VMAlertmanager) VMAuthExposurePath() string {
+	if cr.Spec.ExposeViaVMAuth == nil {
+		return ""
+	}
+	p := strings.Trim(cr.Spec.ExposeViaVMAuth.Path, "/")
+	if p == "" {
+		p = "alertmanager"
+	}
+	return "/" + p + "/"
+}
+
+// GetClusterTLSSecretName returns name of the Secret with gossip TLS certificate
+func (cr *VMAlertmanager) GetClusterTLSSecretName() string {
+	if cr.Spec.ClusterTLS == nil {
+		return ""
+	}
+	if cr.Spec.ClusterTLS.SecretName != "" {
+		return cr.Spec.ClusterTLS.SecretName
+	}
+	return fmt.Sprintf("%s-cluster-tls", cr.PrefixedName())
+}
+
 func (cr *VMAlertmanager) GetServiceAccountName() string {
 	if cr.Spec.ServiceAccountName == "" {
 		return cr.PrefixedName()
@@ -373,12 +451,34 @@ func (cr *VMAlertmanager) AsURL() string {
 			}
 		}
 	}
-	return fmt.Sprintf("%s://%s.%s.svc:%s", cr.accessScheme(), cr.PrefixedName(), cr.Namespace, port)
+	return fmt.Sprintf("%s://%s.%s.svc:%s", cr.AccessScheme(), cr.PrefixedName(), cr.Namespace, port)
+}
+
+// PodAddresses returns host:port addresses of alertmanager pods
+func (cr *VMAlertmanager) PodAddresses() []string {
+	replicaCount := 1
+	if cr.Spec.ReplicaCount != nil {
+		replicaCount = int(*cr.Spec.ReplicaCount)
+	}
+	addrs := make([]string, 0, replicaCount)
+	for i := 0; i < replicaCount; i++ {
+		addrs = append(addrs, cr.podAddress(i))
+	}
+	return addrs
+}
+
+func (cr *VMAlertmanager) podAddress(idx int) string {
+	return fmt.Sprintf("%s-%d.%s.%s.svc:%s", cr.PrefixedName(), idx, cr.PrefixedName(), cr.Namespace, cr.Port())
 }
 
 // returns fqdn for direct pod access
 func (cr *VMAlertmanager) asPodFQDN(idx int) string {
-	return fmt.Sprintf("%s://%s-%d.%s.%s.svc:%s", cr.accessScheme(), cr.PrefixedName(), idx, cr.PrefixedName(), cr.Namespace, cr.Port())
+	return fmt.Sprintf("%s://%s", cr.AccessScheme(), cr.podAddress(idx))
+}
+
+// PodAlertsURL returns url of alerts API for the alertmanager pod with given IP address
+func (cr *VMAlertmanager) PodAlertsURL(podIP string) string {
+	return fmt.Sprintf("%s://%s%s", cr.AccessScheme(), net.JoinHostPort(podIP, cr.Port()), path.Join("/", cr.Spec.RoutePrefix, "/api/v2/alerts"))
 }
 
 // GetMetricPath returns prefixed path for metric requests
@@ -443,7 +543,8 @@ func (cr *VMAlertmanager) ProbePort() string {
 	return cr.Spec.PortName
 }
 
-func (cr *VMAlertmanager) accessScheme() string {
+// AccessScheme returns scheme for alertmanager access
+func (cr *VMAlertmanager) AccessScheme() string {
 	if cr.Spec.WebConfig != nil && cr.Spec.WebConfig.TLSServerConfig != nil {
 		// special case for mTLS
 		return "https"
@@ -489,9 +590,62 @@ func (cr *VMAlertmanager) SetUpdateStatusTo(ctx context.Context, r client.Client
 		cr:           cr,
 		crStatus:     &cr.Status,
 		maybeErr:     maybeErr,
+		statusOf: func(pcr *VMAlertmanager) *VMAlertmanagerStatus {
+			return &pcr.Status
+		},
 	})
 }
 
+// VMAlertmanagerTemplatesDegradedCondition is set to True at VMAlertmanager status
+// if some ConfigMaps or Secrets referenced by templates or templateSecrets are missing
+const VMAlertmanagerTemplatesDegradedCondition = "TemplatesDegraded"
+
+const (
+	// VMAlertmanagerConfigSecretMergeStrategyBase adds routes of VMAlertmanagerConfigs after routes of configSecret
+	VMAlertmanagerConfigSecretMergeStrategyBase = "base"
+	// VMAlertmanagerConfigSecretMergeStrategyOverride adds routes of VMAlertmanagerConfigs before routes of configSecret
+	VMAlertmanagerConfigSecretMergeStrategyOverride = "override"
+	// VMAlertmanagerConfigSecretMergeStrategyDisabled disables merging of VMAlertmanagerConfigs with configSecret
+	VMAlertmanagerConfigSecretMergeStrategyDisabled = "disabled"
+)
+
+// VMAlertmanagerClusterTLSChecksumAnnotation holds checksum of gossip TLS certificate mounted into alertmanager pods
+const VMAlertmanagerClusterTLSChecksumAnnotation = "operator.victoriametrics.com/cluster-tls-checksum"
+
+// AlertmanagerClusterTLS defines source of certificates for mTLS gossip traffic between alertmanager replicas.
+// Secret must contain ca.crt, tls.crt and tls.key keys
+type AlertmanagerClusterTLS struct {
+	// IssuerRef references cert-manager Issuer or ClusterIssuer.
+	// Operator creates cert-manager Certificate valid for all alertmanager pods.
+	// Mutually exclusive with secretName
+	// +optional
+	IssuerRef *VMClusterCertManagerIssuerRef `json:"issuerRef,omitempty"`
+	// SecretName defines name of pre-provisioned Secret with certificate for alertmanager pods.
+	// Certificate must be valid for *.<prefixed-name>.<namespace>.svc names.
+	// Mutually exclusive with issuerRef
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+	// Duration of generated certificate, for example 2160h
+	// +kubebuilder:validation:Pattern:="^([0-9]+(ms|s|m|h))+$"
+	// +optional
+	Duration string `json:"duration,omitempty"`
+	// RenewBefore defines how long before expiration certificate must be renewed, for example 360h
+	// +optional
+	RenewBefore string `json:"renewBefore,omitempty"`
+}
+
+// AlertmanagerVMAuthExposure defines managed VMUser, which routes requests from VMAuth to alertmanager
+type AlertmanagerVMAuthExposure struct {
+	// VMAuthSelector defines labels of managed VMUser.
+	// They must match spec.userSelector of VMAuth, which serves alertmanager
+	// +kubebuilder:validation:MinProperties=1
+	VMAuthSelector map[string]string `json:"vmAuthSelector"`
+	// Path defines request path prefix at VMAuth, which is routed to alertmanager.
+	// Defaults to /alertmanager/
+	// +optional
+	Path string `json:"path,omitempty"`
+}
+
 // AlertmanagerGossipConfig defines Gossip TLS configuration for alertmanager
 type AlertmanagerGossipConfig struct {
 	// TLSServerConfig defines server TLS configuration for alertmanager
@@ -512,6 +666,11 @@ type AlertmanagerWebConfig struct {
 	// Passwords must be hashed with bcrypt
 	// +optional
 	BasicAuthUsers map[string]string `json:"basic_auth_users,omitempty"`
+	// ClientBasicAuth defines plain credentials of one of basic_auth_users.
+	// They are used by VMAlert notifiers discovered with notifierSelector.
+	// Secrets must be at VMAlertmanager namespace, password_file isn't supported
+	// +optional
+	ClientBasicAuth *BasicAuth `json:"client_basic_auth,omitempty"`
 }
 
 // AlertmanagerHTTPConfig defines http server configuration for alertmanager
@@ -525,6 +684,49 @@ type AlertmanagerHTTPConfig struct {
 	Headers map[string]string `json:"headers,omitempty"`
 }
 
+// AlertmanagerGlobalConfig defines global parameters of alertmanager configuration
+// https://prometheus.io/docs/alerting/latest/configuration/#configuration-file
+type AlertmanagerGlobalConfig struct {
+	// ResolveTimeout is the default value used by alertmanager if the alert does
+	// not include EndsAt, after this time passes it can declare the alert as resolved if it has not been updated.
+	// +kubebuilder:validation:Pattern:="^([0-9]+(ms|s|m|h))+$"
+	// +optional
+	ResolveTimeout string `json:"resolve_timeout,omitempty"`
+	// HTTPConfig defines default HTTP client configuration for receivers
+	// +optional
+	HTTPConfig *HTTPConfig `json:"http_config,omitempty"`
+	// SMTPFrom defines default sender address for email notifications
+	// +optional
+	SMTPFrom string `json:"smtp_from,omitempty"`
+	// SMTPHello defines default hostname to identify to the SMTP server
+	// +optional
+	SMTPHello string `json:"smtp_hello,omitempty"`
+	// SMTPSmarthost defines default SMTP host through which emails are sent, must be in host:port format
+	// +optional
+	SMTPSmarthost string `json:"smtp_smarthost,omitempty"`
+	// SMTPAuthUsername defines SMTP Auth using CRAM-MD5, LOGIN and PLAIN
+	// +optional
+	SMTPAuthUsername string `json:"smtp_auth_username,omitempty"`
+	// SMTPAuthPassword defines secret name and key at VMAlertmanager namespace.
+	// It must contain password for SMTP Auth using LOGIN and PLAIN
+	// +optional
+	SMTPAuthPassword *v1.SecretKeySelector `json:"smtp_auth_password,omitempty"`
+	// SMTPAuthSecret defines secret name and key at VMAlertmanager namespace.
+	// It must contain secret for SMTP Auth using CRAM-MD5
+	// +optional
+	SMTPAuthSecret *v1.SecretKeySelector `json:"smtp_auth_secret,omitempty"`
+	// SMTPAuthIdentity defines SMTP Auth using PLAIN
+	// +optional
+	SMTPAuthIdentity string `json:"smtp_auth_identity,omitempty"`
+	// SMTPRequireTLS defines the default SMTP TLS requirement
+	// +optional
+	SMTPRequireTLS *bool `json:"smtp_require_tls,omitempty"`
+	// SlackAPIURL defines secret name and key at VMAlertmanager namespace.
+	// It must contain default Slack API URL for slack receivers
+	// +optional
+	SlackAPIURL *v1.SecretKeySelector `json:"slack_api_url,omitempty"`
+}
+
 // GetAdditionalService returns AdditionalServiceSpec settings
 func (cr *VMAlertmanager) GetAdditionalService() *AdditionalServiceSpec {
 	return cr.Spec.ServiceSpec
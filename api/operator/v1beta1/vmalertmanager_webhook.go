@@ -20,8 +20,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
 
 	"github.com/prometheus/alertmanager/pkg/labels"
+	"gopkg.in/yaml.v2"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
@@ -43,6 +48,33 @@ func (r *VMAlertmanager) SetupWebhookWithManager(mgr ctrl.Manager) error {
 
 // +kubebuilder:webhook:path=/validate-operator-victoriametrics-com-v1beta1-vmalertmanager,mutating=false,failurePolicy=fail,sideEffects=None,groups=operator.victoriametrics.com,resources=vmalertmanagers,verbs=create;update,versions=v1beta1,name=vvmalertmanager.kb.io,admissionReviewVersions=v1
 
+func (r *VMAlertmanager) validateGlobalConfig() error {
+	if len(r.Spec.ConfigSecret) > 0 {
+		return fmt.Errorf("it cannot be used together with spec.configSecret, global section must be defined at configSecret")
+	}
+	if len(r.Spec.ConfigRawYaml) > 0 {
+		var rawCfg yaml.MapSlice
+		if err := yaml.Unmarshal([]byte(r.Spec.ConfigRawYaml), &rawCfg); err != nil {
+			return fmt.Errorf("cannot parse spec.configRawYaml: %w", err)
+		}
+		for _, item := range rawCfg {
+			if item.Key == "global" {
+				return fmt.Errorf("global section is already defined at spec.configRawYaml")
+			}
+		}
+	}
+	gc := r.Spec.GlobalConfig
+	if len(gc.SMTPSmarthost) > 0 {
+		if _, _, err := net.SplitHostPort(gc.SMTPSmarthost); err != nil {
+			return fmt.Errorf("incorrect smtp_smarthost=%q, must be in host:port format: %w", gc.SMTPSmarthost, err)
+		}
+	}
+	if err := gc.HTTPConfig.validate(); err != nil {
+		return fmt.Errorf("incorrect http_config: %w", err)
+	}
+	return nil
+}
+
 func (r *VMAlertmanager) sanityCheck() error {
 	if r.Spec.ServiceSpec != nil && r.Spec.ServiceSpec.Name == r.PrefixedName() {
 		return fmt.Errorf("spec.serviceSpec.Name cannot be equal to prefixed name=%q", r.PrefixedName())
@@ -62,6 +94,28 @@ func (r *VMAlertmanager) sanityCheck() error {
 	if r.Spec.ConfigSecret == r.ConfigSecretName() {
 		return fmt.Errorf("spec.configSecret uses the same name as built-in config secret used by operator. Please change it's name")
 	}
+	if r.Spec.ConfigSecretMergeStrategy != "" && r.Spec.ConfigSecret == "" {
+		return fmt.Errorf("spec.configSecretMergeStrategy=%q requires spec.configSecret to be set", r.Spec.ConfigSecretMergeStrategy)
+	}
+	if len(r.Spec.TemplateSecrets) > 0 {
+		cmNames := make(map[string]struct{}, len(r.Spec.Templates))
+		for _, t := range r.Spec.Templates {
+			cmNames[t.Name] = struct{}{}
+		}
+		for idx, t := range r.Spec.TemplateSecrets {
+			if t.Name == "" || t.Key == "" {
+				return fmt.Errorf("spec.templateSecrets at idx=%d must have name and key", idx)
+			}
+			if _, ok := cmNames[t.Name]; ok {
+				return fmt.Errorf("spec.templateSecrets at idx=%d has the same name=%q as ConfigMap at spec.templates, it must be unique", idx, t.Name)
+			}
+		}
+	}
+	if r.Spec.GlobalConfig != nil {
+		if err := r.validateGlobalConfig(); err != nil {
+			return fmt.Errorf("incorrect spec.globalConfig: %w", err)
+		}
+	}
 	if r.Spec.WebConfig != nil {
 		if r.Spec.WebConfig.HTTPServerConfig != nil {
 			if r.Spec.WebConfig.HTTPServerConfig.HTTP2 && r.Spec.WebConfig.TLSServerConfig == nil {
@@ -108,6 +162,67 @@ func (r *VMAlertmanager) sanityCheck() error {
 			}
 		}
 	}
+	if r.Spec.ClusterTLS != nil {
+		if err := r.checkClusterTLS(); err != nil {
+			return err
+		}
+	}
+	if r.Spec.ExposeViaVMAuth != nil {
+		if err := r.checkVMAuthExposure(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkVMAuthExposure validates exposeViaVMAuth configuration
+// path prefix is stripped by VMAuth, so alertmanager must serve requests from the root path
+func (r *VMAlertmanager) checkVMAuthExposure() error {
+	if len(r.Spec.ExposeViaVMAuth.VMAuthSelector) == 0 {
+		return fmt.Errorf("exposeViaVMAuth.vmAuthSelector cannot be empty")
+	}
+	if r.Spec.RoutePrefix != "" && r.Spec.RoutePrefix != "/" {
+		return fmt.Errorf("routePrefix=%q cannot be used with exposeViaVMAuth, path prefix is stripped by VMAuth", r.Spec.RoutePrefix)
+	}
+	if r.Spec.ExternalURL != "" {
+		u, err := url.Parse(r.Spec.ExternalURL)
+		if err != nil {
+			return fmt.Errorf("cannot parse externalURL=%q: %w", r.Spec.ExternalURL, err)
+		}
+		exposurePath := r.VMAuthExposurePath()
+		if strings.TrimRight(u.Path, "/")+"/" != exposurePath {
+			return fmt.Errorf("externalURL=%q path must match exposeViaVMAuth path=%q", r.Spec.ExternalURL, exposurePath)
+		}
+	}
+	return nil
+}
+
+// checkClusterTLS validates clusterTLS configuration
+// gossip is disabled for a single replica, so TLS cannot be enabled for it
+func (r *VMAlertmanager) checkClusterTLS() error {
+	tls := r.Spec.ClusterTLS
+	switch {
+	case r.Spec.GossipConfig != nil:
+		return fmt.Errorf("clusterTLS and gossipConfig are mutually exclusive")
+	case r.Spec.ReplicaCount != nil && *r.Spec.ReplicaCount == 1:
+		return fmt.Errorf("clusterTLS cannot be enabled for replicaCount=1, cluster mode is disabled for a single replica")
+	case tls.IssuerRef == nil && tls.SecretName == "":
+		return fmt.Errorf("clusterTLS requires either issuerRef or secretName")
+	case tls.IssuerRef != nil && tls.SecretName != "":
+		return fmt.Errorf("clusterTLS.issuerRef and clusterTLS.secretName are mutually exclusive")
+	case tls.IssuerRef != nil && tls.IssuerRef.Name == "":
+		return fmt.Errorf("clusterTLS.issuerRef.name cannot be empty")
+	}
+	if tls.Duration != "" {
+		if _, err := time.ParseDuration(tls.Duration); err != nil {
+			return fmt.Errorf("cannot parse clusterTLS.duration=%q: %w", tls.Duration, err)
+		}
+	}
+	if tls.RenewBefore != "" {
+		if _, err := time.ParseDuration(tls.RenewBefore); err != nil {
+			return fmt.Errorf("cannot parse clusterTLS.renewBefore=%q: %w", tls.RenewBefore, err)
+		}
+	}
 	return nil
 }
 
@@ -19,7 +19,9 @@ package v1beta1
 import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
 )
 
 var _ = Describe("VMAlertmanager Webhook", func() {
@@ -59,6 +61,91 @@ global:
           `
 			Expect(am.sanityCheck()).To(Succeed())
 		})
+
+		It("Should allow globalConfig", func() {
+			am.Spec.ConfigRawYaml = `
+route:
+  receiver: blackhole
+receivers:
+- name: blackhole
+`
+			am.Spec.GlobalConfig = &AlertmanagerGlobalConfig{
+				ResolveTimeout: "10m",
+				SMTPFrom:       "alertmanager@example.com",
+				SMTPSmarthost:  "smtp.example.com:587",
+			}
+			Expect(am.sanityCheck()).To(Succeed())
+		})
+
+		It("Should deny globalConfig with configSecret", func() {
+			am.Spec.ConfigSecret = "user-config"
+			am.Spec.GlobalConfig = &AlertmanagerGlobalConfig{ResolveTimeout: "10m"}
+			Expect(am.sanityCheck()).NotTo(Succeed())
+		})
+
+		It("Should deny globalConfig with global section at configRawYaml", func() {
+			am.Spec.ConfigRawYaml = `
+global:
+  resolve_timeout: 5m
+route:
+  receiver: blackhole
+receivers:
+- name: blackhole
+`
+			am.Spec.GlobalConfig = &AlertmanagerGlobalConfig{ResolveTimeout: "10m"}
+			Expect(am.sanityCheck()).NotTo(Succeed())
+		})
+
+		It("Should deny configSecretMergeStrategy without configSecret", func() {
+			am.Spec.ConfigSecretMergeStrategy = VMAlertmanagerConfigSecretMergeStrategyOverride
+			Expect(am.sanityCheck()).NotTo(Succeed())
+			am.Spec.ConfigSecret = "user-config"
+			Expect(am.sanityCheck()).To(Succeed())
+		})
+
+		It("Should deny templateSecrets with the same name as templates", func() {
+			am.Spec.Templates = []ConfigMapKeyReference{{LocalObjectReference: v1.LocalObjectReference{Name: "templates"}, Key: "email.tmpl"}}
+			am.Spec.TemplateSecrets = []v1.SecretKeySelector{{LocalObjectReference: v1.LocalObjectReference{Name: "templates"}, Key: "slack.tmpl"}}
+			Expect(am.sanityCheck()).NotTo(Succeed())
+			am.Spec.TemplateSecrets[0].Name = "secret-templates"
+			Expect(am.sanityCheck()).To(Succeed())
+		})
+
+		It("Should deny globalConfig with incorrect smtp_smarthost", func() {
+			am.Spec.GlobalConfig = &AlertmanagerGlobalConfig{SMTPSmarthost: "smtp.example.com"}
+			Expect(am.sanityCheck()).NotTo(Succeed())
+		})
+
+		It("Should validate exposeViaVMAuth", func() {
+			am.Spec.ExposeViaVMAuth = &AlertmanagerVMAuthExposure{}
+			Expect(am.sanityCheck()).NotTo(Succeed())
+			am.Spec.ExposeViaVMAuth.VMAuthSelector = map[string]string{"app": "vmauth"}
+			Expect(am.sanityCheck()).To(Succeed())
+			am.Spec.RoutePrefix = "/alertmanager"
+			Expect(am.sanityCheck()).NotTo(Succeed())
+			am.Spec.RoutePrefix = ""
+			am.Spec.ExternalURL = "https://vmauth.example.com/am"
+			Expect(am.sanityCheck()).NotTo(Succeed())
+			am.Spec.ExternalURL = "https://vmauth.example.com/alertmanager"
+			Expect(am.sanityCheck()).To(Succeed())
+		})
+
+		It("Should validate clusterTLS", func() {
+			am.Spec.ClusterTLS = &AlertmanagerClusterTLS{SecretName: "am-tls"}
+			Expect(am.sanityCheck()).To(Succeed())
+			am.Spec.ClusterTLS.IssuerRef = &VMClusterCertManagerIssuerRef{Name: "ca-issuer"}
+			Expect(am.sanityCheck()).NotTo(Succeed())
+			am.Spec.ClusterTLS.SecretName = ""
+			am.Spec.ClusterTLS.Duration = "90d"
+			Expect(am.sanityCheck()).NotTo(Succeed())
+			am.Spec.ClusterTLS.Duration = "2160h"
+			Expect(am.sanityCheck()).To(Succeed())
+			am.Spec.ReplicaCount = ptr.To[int32](1)
+			Expect(am.sanityCheck()).NotTo(Succeed())
+			am.Spec.ReplicaCount = ptr.To[int32](3)
+			am.Spec.GossipConfig = &AlertmanagerGossipConfig{}
+			Expect(am.sanityCheck()).NotTo(Succeed())
+		})
 	})
 
 	Context("When creating VMAlertmanager under Conversion Webhook", func() {
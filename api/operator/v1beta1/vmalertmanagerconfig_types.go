@@ -104,6 +104,47 @@ type VMAlertmanagerConfigStatus struct {
 	// reconcile
 	StatusMetadata                  `json:",inline"`
 	LastErrorParentAlertmanagerName string `json:"lastErrorParentAlertmanagerName,omitempty"`
+	// ReceiverTest contains result of the last receiver test
+	// triggered by operator.victoriametrics.com/test-receiver annotation
+	// +optional
+	ReceiverTest *VMAlertmanagerConfigReceiverTest `json:"receiverTest,omitempty"`
+}
+
+// VMAlertmanagerConfigTestReceiverAnnotation triggers delivery test of the receiver with the given name.
+// Operator posts synthetic alert routed to the receiver into parent VMAlertmanagers,
+// records result at status.receiverTest and removes annotation
+const VMAlertmanagerConfigTestReceiverAnnotation = "operator.victoriametrics.com/test-receiver"
+
+// VMAlertmanagerConfigReceiverTest defines result of receiver delivery test
+type VMAlertmanagerConfigReceiverTest struct {
+	// Receiver is a name of tested receiver
+	Receiver string `json:"receiver"`
+	// LastTestTime is the time of the receiver test
+	LastTestTime metav1.Time `json:"lastTestTime"`
+	// Error defines reason why synthetic alert wasn't sent
+	// +optional
+	Error string `json:"error,omitempty"`
+	// Results contains responses of alertmanager API for each pod of parent VMAlertmanagers
+	// +optional
+	Results []VMAlertmanagerReceiverTestResult `json:"results,omitempty"`
+}
+
+// VMAlertmanagerReceiverTestResult defines alertmanager API response for synthetic test alert
+type VMAlertmanagerReceiverTestResult struct {
+	// Alertmanager is a namespaced name of VMAlertmanager
+	Alertmanager string `json:"alertmanager"`
+	// Pod is a name of VMAlertmanager pod, which received synthetic alert
+	// +optional
+	Pod string `json:"pod,omitempty"`
+	// Labels of the synthetic alert
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+	// StatusCode of alertmanager API response
+	// +optional
+	StatusCode int32 `json:"statusCode,omitempty"`
+	// Response contains body of alertmanager API response or request error
+	// +optional
+	Response string `json:"response,omitempty"`
 }
 
 // VMAlertmanagerConfig is the Schema for the vmalertmanagerconfigs API
@@ -731,6 +772,9 @@ type PagerDutyConfig struct {
 	// The part or component of the affected system that is broken.
 	// +optional
 	Component string `json:"component,omitempty"`
+	// Unique location of the affected system.
+	// +optional
+	Source string `json:"source,omitempty"`
 	// Arbitrary key/value pairs that provide further detail about the incident.
 	// +optional
 	Details map[string]string `json:"details,omitempty"`
@@ -774,6 +818,9 @@ type MSTeamsConfig struct {
 	// The title of the teams notification.
 	// +optional
 	Title string `json:"title,omitempty"`
+	// The summary of the teams notification.
+	// +optional
+	Summary string `json:"summary,omitempty"`
 	// The text body of the teams notification.
 	// +optional
 	Text string `json:"text,omitempty"`
@@ -801,6 +848,15 @@ type DiscordConfig struct {
 	// The message body template
 	// +optional
 	Message string `json:"message,omitempty"`
+	// The message content template
+	// +optional
+	Content string `json:"content,omitempty"`
+	// The username of the message sender
+	// +optional
+	Username string `json:"username,omitempty"`
+	// The avatar url of the message sender
+	// +optional
+	AvatarURL string `json:"avatar_url,omitempty" yaml:"avatar_url,omitempty"`
 	// HTTP client configuration.
 	// +optional
 	HTTPConfig *HTTPConfig `json:"http_config,omitempty" yaml:"http_config,omitempty"`
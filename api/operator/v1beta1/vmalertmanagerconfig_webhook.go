@@ -26,6 +26,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -367,6 +368,11 @@ func validateReceiver(recv Receiver) error {
 				return fmt.Errorf("at idx=%d for discord_configs has invalid webhook_url=%q", idx, *cfg.URL)
 			}
 		}
+		if cfg.AvatarURL != "" {
+			if _, err := url.Parse(cfg.AvatarURL); err != nil {
+				return fmt.Errorf("at idx=%d for discord_configs has invalid avatar_url=%q", idx, cfg.AvatarURL)
+			}
+		}
 		if err := cfg.HTTPConfig.validate(); err != nil {
 			return fmt.Errorf("at idx=%d for discord_configs incorrect http_config: %w", idx, err)
 		}
@@ -407,14 +413,18 @@ func validateReceiver(recv Receiver) error {
 func checkRouteReceiver(r *SubRoute, receivers map[string]struct{}, tiNames map[string]struct{}) error {
 	for _, ti := range r.ActiveTimeIntervals {
 		if _, ok := tiNames[ti]; !ok {
-			return fmt.Errorf("undefined time interval %q used in route", ti)
+			return fmt.Errorf("undefined active time interval %q used in route", ti)
 		}
 	}
-	if r.Receiver == "" {
-		return nil
+	for _, ti := range r.MuteTimeIntervals {
+		if _, ok := tiNames[ti]; !ok {
+			return fmt.Errorf("undefined mute time interval %q used in route", ti)
+		}
 	}
-	if _, ok := receivers[r.Receiver]; !ok {
-		return fmt.Errorf("undefined receiver %q used in route", r.Receiver)
+	if r.Receiver != "" {
+		if _, ok := receivers[r.Receiver]; !ok {
+			return fmt.Errorf("undefined receiver %q used in route", r.Receiver)
+		}
 	}
 	for idx, sr := range r.Routes {
 		if err := checkRouteReceiver(sr, receivers, tiNames); err != nil {
@@ -471,6 +481,11 @@ func validateTimeIntervalsEntry(ti *TimeIntervals) error {
 				return fmt.Errorf("year range=%q at idx=%d is invalid: %w", year, i, err)
 			}
 		}
+		if ti.Location != "" {
+			if _, err := time.LoadLocation(ti.Location); err != nil {
+				return fmt.Errorf("location=%q at idx=%d is invalid: %w", ti.Location, i, err)
+			}
+		}
 	}
 	return nil
 }
@@ -120,6 +120,49 @@ var _ = Describe("VMAlertmanagerConfig Webhook", func() {
               routes:
               - matcher: [nested=env]
         `, `undefined mute time interval "months" used in root route`),
+			Entry("missing mute time interval at nested route without receiver", `
+        apiVersion: v1
+        kind: VMAlertmanagerConfig
+        metadata:
+          name: test-fail
+        spec:
+          receivers:
+          - name: blackhole
+          time_intervals:
+          - name: business-hours
+            time_intervals:
+            - weekdays: [monday:friday]
+              times:
+              - start_time: "09:00"
+                end_time: "18:00"
+          route:
+            receiver: blackhole
+            routes:
+            - matchers: [team=ops]
+              routes:
+              - matchers: [env=dev]
+                receiver: blackhole
+                active_time_intervals:
+                - business-hours
+                mute_time_intervals:
+                - weekends
+        `, `subRoute=0 is not valid: nested route=0: undefined mute time interval "weekends" used in route`),
+			Entry("incorrect time interval location", `
+        apiVersion: v1
+        kind: VMAlertmanagerConfig
+        metadata:
+          name: test-fail
+        spec:
+          receivers:
+          - name: blackhole
+          time_intervals:
+          - name: business-hours
+            time_intervals:
+            - weekdays: [monday:friday]
+              location: Mars/Olympus
+          route:
+            receiver: blackhole
+        `, `time interval at idx=0 is invalid: location="Mars/Olympus" at idx=0 is invalid: unknown time zone Mars/Olympus`),
 			Entry("incorrect matchers syntax", `
         apiVersion: v1
         kind: VMAlertmanagerConfig
@@ -4,7 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net"
 	"net/url"
+	"slices"
 	"strings"
 
 	v12 "k8s.io/api/networking/v1"
@@ -12,6 +14,7 @@ import (
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/utils/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	gwapiv1 "sigs.k8s.io/gateway-api/apis/v1"
 )
 
 // VMAuthSpec defines the desired state of VMAuth
@@ -63,6 +66,10 @@ type VMAuthSpec struct {
 	PodDisruptionBudget *EmbeddedPodDisruptionBudgetSpec `json:"podDisruptionBudget,omitempty" yaml:"podDisruptionBudget,omitempty"`
 	// Ingress enables ingress configuration for VMAuth.
 	Ingress *EmbeddedIngress `json:"ingress,omitempty"`
+	// HTTPRoute enables Gateway API HTTPRoute configuration for VMAuth.
+	// It requires Gateway API CRDs to be installed at the cluster.
+	// +optional
+	HTTPRoute *EmbeddedHTTPRoute `json:"httpRoute,omitempty"`
 	// LivenessProbe that will be added to VMAuth pod
 	*EmbeddedProbes `json:",inline"`
 	// UnauthorizedAccessConfig configures access for un authorized users
@@ -76,6 +83,10 @@ type VMAuthSpec struct {
 	// UnauthorizedUserAccessSpec defines unauthorized_user config section of vmauth config
 	// +optional
 	UnauthorizedUserAccessSpec *VMAuthUnauthorizedUserAccessSpec `json:"unauthorizedUserAccessSpec,omitempty" yaml:"unauthorizedUserAccessSpec,omitempty"`
+	// DefaultUserPolicies defines access policies, which are applied to all users selected by VMAuth.
+	// Options defined at VMUser take precedence over default policies.
+	// +optional
+	DefaultUserPolicies *VMAuthDefaultUserPolicies `json:"defaultUserPolicies,omitempty" yaml:"defaultUserPolicies,omitempty"`
 	// IPFilters global access ip filters
 	// supported only with enterprise version of [vmauth](https://docs.victoriametrics.com/vmauth/#ip-filters)
 	// +optional
@@ -159,6 +170,94 @@ func (vmuua *VMAuthUnauthorizedUserAccessSpec) Validate() error {
 	return nil
 }
 
+// VMAuthDefaultUserPolicies defines default access policies for all users of VMAuth
+type VMAuthDefaultUserPolicies struct {
+	// IPFilters defines default src ip filters for users
+	// supported only with enterprise version of [vmauth](https://docs.victoriametrics.com/vmauth/#ip-filters)
+	// +optional
+	IPFilters VMUserIPFilters `json:"ip_filters,omitempty" yaml:"ip_filters,omitempty"`
+	// MaxConcurrentRequests defines default max concurrent requests per user
+	// +optional
+	MaxConcurrentRequests *int `json:"max_concurrent_requests,omitempty" yaml:"max_concurrent_requests,omitempty"`
+	// DiscoverBackendIPs instructs discovering URLPrefix backend IPs via DNS.
+	// +optional
+	DiscoverBackendIPs *bool `json:"discover_backend_ips,omitempty" yaml:"discover_backend_ips,omitempty"`
+	// RetryStatusCodes defines http status codes in numeric format for request retries
+	// e.g. [429,503]
+	// +optional
+	RetryStatusCodes []int `json:"retry_status_codes,omitempty" yaml:"retry_status_codes,omitempty"`
+	// Enforce forbids VMUsers to allow access from addresses outside of ip_filters.allow_list.
+	// VMUser with wider ip_filters.allow_list is excluded from config and error is reported at its status
+	// +optional
+	Enforce bool `json:"enforce,omitempty" yaml:"enforce,omitempty"`
+}
+
+// Validate performs semantic syntax validation
+func (dp *VMAuthDefaultUserPolicies) Validate() error {
+	if err := dp.IPFilters.Validate(); err != nil {
+		return err
+	}
+	if dp.Enforce && len(dp.IPFilters.AllowList) == 0 {
+		return fmt.Errorf("ip_filters.allow_list cannot be empty with enforce: true")
+	}
+	if dp.MaxConcurrentRequests != nil && *dp.MaxConcurrentRequests <= 0 {
+		return fmt.Errorf("max_concurrent_requests=%d must be positive", *dp.MaxConcurrentRequests)
+	}
+	return validateRoutingOptions(dp.RetryStatusCodes, nil, nil)
+}
+
+// ValidateUserIPFilters checks that allow_list of the given user filters
+// doesn't permit addresses outside of default allow_list.
+// Empty user allow_list is valid, since default allow_list is applied to it
+func (dp *VMAuthDefaultUserPolicies) ValidateUserIPFilters(ipf VMUserIPFilters) error {
+	allowed := make([]*net.IPNet, 0, len(dp.IPFilters.AllowList))
+	for _, v := range dp.IPFilters.AllowList {
+		ipNet, err := parseIPNet(v)
+		if err != nil {
+			return fmt.Errorf("incorrect default ip_filters.allow_list: %w", err)
+		}
+		allowed = append(allowed, ipNet)
+	}
+	for _, v := range ipf.AllowList {
+		ipNet, err := parseIPNet(v)
+		if err != nil {
+			return fmt.Errorf("incorrect ip_filters.allow_list: %w", err)
+		}
+		if !slices.ContainsFunc(allowed, func(parent *net.IPNet) bool {
+			return containsIPNet(parent, ipNet)
+		}) {
+			return fmt.Errorf("ip_filters.allow_list entry=%q is outside of VMAuth defaultUserPolicies.ip_filters.allow_list=%q", v, dp.IPFilters.AllowList)
+		}
+	}
+	return nil
+}
+
+// parseIPNet parses IP address or CIDR into network
+func parseIPNet(v string) (*net.IPNet, error) {
+	if strings.Contains(v, "/") {
+		_, ipNet, err := net.ParseCIDR(v)
+		if err != nil {
+			return nil, fmt.Errorf("incorrect CIDR=%q: %w", v, err)
+		}
+		return ipNet, nil
+	}
+	ip := net.ParseIP(v)
+	if ip == nil {
+		return nil, fmt.Errorf("incorrect IP address=%q", v)
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}, nil
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+}
+
+// containsIPNet checks if child network is a subnet of parent network
+func containsIPNet(parent, child *net.IPNet) bool {
+	parentOnes, parentBits := parent.Mask.Size()
+	childOnes, childBits := child.Mask.Size()
+	return parentBits == childBits && parentOnes <= childOnes && parent.Contains(child.IP)
+}
+
 // UnauthorizedAccessConfigURLMap defines element of url_map routing configuration
 // For UnauthorizedAccessConfig and VMAuthUnauthorizedUserAccessSpec.URLMap
 type UnauthorizedAccessConfigURLMap struct {
@@ -190,7 +289,7 @@ func (uac *UnauthorizedAccessConfigURLMap) Validate() error {
 			return fmt.Errorf("incorrect url_prefix=%q at idx: %d: %w", urlPrefix, idx, err)
 		}
 	}
-	return nil
+	return uac.URLMapCommon.Validate()
 }
 
 func validateURLPrefix(urlPrefixStr string) error {
@@ -253,6 +352,17 @@ type URLMapCommon struct {
 	DropSrcPathPrefixParts *int `json:"drop_src_path_prefix_parts,omitempty" yaml:"drop_src_path_prefix_parts,omitempty"`
 }
 
+// Validate performs syntax validation of url_map options
+func (umc *URLMapCommon) Validate() error {
+	if err := validateHTTPHeaders(umc.RequestHeaders); err != nil {
+		return fmt.Errorf("incorrect 'headers' syntax: %w", err)
+	}
+	if err := validateHTTPHeaders(umc.ResponseHeaders); err != nil {
+		return fmt.Errorf("incorrect 'response_headers' syntax: %w", err)
+	}
+	return validateRoutingOptions(umc.RetryStatusCodes, umc.LoadBalancingPolicy, umc.DropSrcPathPrefixParts)
+}
+
 // VMUserConfigOptions defines configuration options for VMUser object
 type VMUserConfigOptions struct {
 	// DefaultURLs backend url for non-matching paths filter
@@ -329,12 +439,34 @@ func (vuopts *VMUserConfigOptions) Validate() error {
 			return err
 		}
 	}
+	if err := vuopts.IPFilters.Validate(); err != nil {
+		return err
+	}
 	if err := validateHTTPHeaders(vuopts.Headers); err != nil {
 		return fmt.Errorf("incorrect 'headers' syntax: %w", err)
 	}
 	if err := validateHTTPHeaders(vuopts.ResponseHeaders); err != nil {
 		return fmt.Errorf("incorrect 'response_headers' syntax: %w", err)
 	}
+	return validateRoutingOptions(vuopts.RetryStatusCodes, vuopts.LoadBalancingPolicy, vuopts.DropSrcPathPrefixParts)
+}
+
+func validateRoutingOptions(retryStatusCodes []int, loadBalancingPolicy *string, dropSrcPathPrefixParts *int) error {
+	for _, code := range retryStatusCodes {
+		if code < 100 || code > 599 {
+			return fmt.Errorf("incorrect retry_status_codes value=%d, must be valid http status code", code)
+		}
+	}
+	if loadBalancingPolicy != nil {
+		switch *loadBalancingPolicy {
+		case "least_loaded", "first_available":
+		default:
+			return fmt.Errorf("unsupported load_balancing_policy=%q, must be one of least_loaded, first_available", *loadBalancingPolicy)
+		}
+	}
+	if dropSrcPathPrefixParts != nil && *dropSrcPathPrefixParts < 0 {
+		return fmt.Errorf("drop_src_path_prefix_parts=%d cannot be negative", *dropSrcPathPrefixParts)
+	}
 	return nil
 }
 
@@ -401,10 +533,82 @@ type EmbeddedIngress struct {
 	// It will be used, only if TlsHosts is empty
 	// +optional
 	Host string `json:"host,omitempty"`
+	// PathPrefix defines path prefix for default rule
+	// Defaults to /
+	// +optional
+	PathPrefix string `json:"pathPrefix,omitempty"`
+}
+
+// PathPrefixOrDefault returns path prefix for default ingress rule
+func (ei *EmbeddedIngress) PathPrefixOrDefault() string {
+	if ei.PathPrefix == "" {
+		return "/"
+	}
+	return ei.PathPrefix
+}
+
+// EmbeddedHTTPRoute describes Gateway API HTTPRoute configuration
+type EmbeddedHTTPRoute struct {
+	//  EmbeddedObjectMetadata adds labels and annotations for object.
+	EmbeddedObjectMetadata `json:",inline"`
+	// ParentRefs defines Gateways, which HTTPRoute must be attached to
+	// https://gateway-api.sigs.k8s.io/reference/spec/#gateway.networking.k8s.io/v1.ParentReference
+	// +kubebuilder:validation:MinItems=1
+	ParentRefs []gwapiv1.ParentReference `json:"parentRefs"`
+	// Hostnames defines hostnames, which must be matched by Host header of request
+	// +optional
+	Hostnames []gwapiv1.Hostname `json:"hostnames,omitempty"`
+	// PathPrefix defines path prefix for route matching
+	// Defaults to /
+	// +optional
+	PathPrefix string `json:"pathPrefix,omitempty"`
+}
+
+// PathPrefixOrDefault returns path prefix for HTTPRoute rule
+func (er *EmbeddedHTTPRoute) PathPrefixOrDefault() string {
+	if er.PathPrefix == "" {
+		return "/"
+	}
+	return er.PathPrefix
+}
+
+// Validate performs syntax logic validation
+func (er *EmbeddedHTTPRoute) Validate() error {
+	if len(er.ParentRefs) == 0 {
+		return fmt.Errorf("parentRefs cannot be empty")
+	}
+	for idx, pr := range er.ParentRefs {
+		if pr.Name == "" {
+			return fmt.Errorf("parentRefs[%d].name cannot be empty", idx)
+		}
+	}
+	if !strings.HasPrefix(er.PathPrefixOrDefault(), "/") {
+		return fmt.Errorf("pathPrefix=%q must start with /", er.PathPrefix)
+	}
+	return nil
 }
 
 // VMAuthStatus defines the observed state of VMAuth
 type VMAuthStatus struct {
+	// ConfigSecretParts defines number of additional Secrets with users configuration,
+	// which are created if generated configuration exceeds Secret size limit.
+	// Zero means that configuration is stored at the main config Secret
+	// +optional
+	ConfigSecretParts int32 `json:"configSecretParts,omitempty"`
+	// ConfigSecretPartsChecksum defines checksum of the split configuration,
+	// vmauth pods merge config parts of the same checksum only
+	// +optional
+	ConfigSecretPartsChecksum string `json:"configSecretPartsChecksum,omitempty"`
+	// UsersTotal is a number of VMUsers selected by VMAuth at the last config generation
+	// +optional
+	UsersTotal int32 `json:"usersTotal,omitempty"`
+	// UsersFailed is a number of selected VMUsers, which were excluded from config because of errors.
+	// Error is reported at status of VMUser
+	// +optional
+	UsersFailed int32 `json:"usersFailed,omitempty"`
+	// LastConfigHash is a sha256 hash of the last generated vmauth configuration
+	// +optional
+	LastConfigHash string `json:"lastConfigHash,omitempty"`
 	StatusMetadata `json:",inline"`
 }
 
@@ -542,6 +746,11 @@ func (cr *VMAuth) ConfigSecretName() string {
 	return fmt.Sprintf("vmauth-config-%s", cr.Name)
 }
 
+// ConfigSecretPartName returns name of the Secret with the given part of users configuration
+func (cr *VMAuth) ConfigSecretPartName(idx int) string {
+	return fmt.Sprintf("%s-%d", cr.ConfigSecretName(), idx)
+}
+
 // GetMetricPath returns prefixed path for metric requests
 func (cr *VMAuth) GetMetricPath() string {
 	return buildPathWithPrefixFlag(cr.Spec.ExtraArgs, metricPath)
@@ -606,6 +815,9 @@ func (cr *VMAuth) SetUpdateStatusTo(ctx context.Context, r client.Client, status
 		cr:           cr,
 		crStatus:     &cr.Status,
 		maybeErr:     maybeErr,
+		statusOf: func(pcr *VMAuth) *VMAuthStatus {
+			return &pcr.Status
+		},
 	})
 }
 
@@ -0,0 +1,63 @@
+package v1beta1
+
+import (
+	"testing"
+)
+
+func TestVMAuthDefaultUserPolicies_ValidateUserIPFilters(t *testing.T) {
+	f := func(defaultAllowList, userAllowList []string, wantErr bool) {
+		t.Helper()
+		dp := &VMAuthDefaultUserPolicies{
+			IPFilters: VMUserIPFilters{AllowList: defaultAllowList},
+			Enforce:   true,
+		}
+		err := dp.ValidateUserIPFilters(VMUserIPFilters{AllowList: userAllowList})
+		if (err != nil) != wantErr {
+			t.Fatalf("unexpected error: %v, wantErr: %v", err, wantErr)
+		}
+	}
+
+	// empty user allow_list inherits defaults
+	f([]string{"10.0.0.0/16"}, nil, false)
+
+	// subnets and addresses inside of default allow_list
+	f([]string{"10.0.0.0/16", "192.168.1.1"}, []string{"10.0.0.0/16", "10.0.5.0/24", "10.0.1.1", "192.168.1.1/32"}, false)
+
+	// wider subnet
+	f([]string{"10.0.0.0/16"}, []string{"10.0.0.0/8"}, true)
+
+	// address outside of default allow_list
+	f([]string{"10.0.0.0/16", "192.168.1.1"}, []string{"192.168.1.2"}, true)
+
+	// ipv6
+	f([]string{"2001:db8::/32"}, []string{"2001:db8:1::/48", "2001:db8::1"}, false)
+	f([]string{"2001:db8::/32"}, []string{"2001:db9::1"}, true)
+
+	// ipv4 address doesn't match ipv6 subnet
+	f([]string{"::/0"}, []string{"10.0.0.1"}, true)
+
+	// incorrect user allow_list
+	f([]string{"10.0.0.0/16"}, []string{"10.0.0.0/33"}, true)
+}
+
+func TestVMUserIPFilters_Validate(t *testing.T) {
+	f := func(ipf VMUserIPFilters, wantErr string) {
+		t.Helper()
+		err := ipf.Validate()
+		if wantErr == "" {
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			return
+		}
+		if err == nil || err.Error() != wantErr {
+			t.Fatalf("unexpected error, want: %q, got: %v", wantErr, err)
+		}
+	}
+	f(VMUserIPFilters{AllowList: []string{"10.0.0.0/24", "10.0.1.1"}, DenyList: []string{"10.0.0.1"}}, "")
+	f(VMUserIPFilters{DenyList: []string{"10.0.0.0/33"}}, `incorrect CIDR="10.0.0.0/33" at ip_filters.deny_list: invalid CIDR address: 10.0.0.0/33`)
+	// errors are reported in stable order
+	for range 10 {
+		f(VMUserIPFilters{AllowList: []string{"bad-allow"}, DenyList: []string{"bad-deny"}}, `incorrect IP address="bad-allow" at ip_filters.allow_list`)
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"strings"
 
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -53,6 +54,14 @@ func (r *VMAuth) sanityCheck() error {
 		if ing.TlsSecretName != "" && len(ing.TlsHosts) == 0 {
 			return fmt.Errorf("spec.ingress.tlsHosts cannot be empty with non-empty spec.ingress.tlsSecretName")
 		}
+		if !strings.HasPrefix(ing.PathPrefixOrDefault(), "/") {
+			return fmt.Errorf("spec.ingress.pathPrefix=%q must start with /", ing.PathPrefix)
+		}
+	}
+	if r.Spec.HTTPRoute != nil {
+		if err := r.Spec.HTTPRoute.Validate(); err != nil {
+			return fmt.Errorf("incorrect spec.httpRoute: %w", err)
+		}
 	}
 	if r.Spec.ConfigSecret != "" && r.Spec.ExternalConfig.SecretRef != nil {
 		return fmt.Errorf("spec.configSecret and spec.externalConfig.secretRef cannot be used at the same time")
@@ -88,6 +97,11 @@ func (r *VMAuth) sanityCheck() error {
 			return fmt.Errorf("incorrect r.spec.UnauthorizedUserAccess syntax: %w", err)
 		}
 	}
+	if r.Spec.DefaultUserPolicies != nil {
+		if err := r.Spec.DefaultUserPolicies.Validate(); err != nil {
+			return fmt.Errorf("incorrect spec.defaultUserPolicies: %w", err)
+		}
+	}
 
 	return nil
 }
@@ -30,6 +30,27 @@ var _ = Describe("VMAuth Webhook", func() {
             - host-1
             - host-2
         `, `spec.ingress.tlsSecretName cannot be empty with non-empty spec.ingress.tlsHosts`),
+			Entry("httpRoute without parentRefs", `
+        apiVersion: v1
+        kind: VMAuth
+        metadata:
+          name: must-fail
+        spec:
+          httpRoute:
+            hostnames:
+            - vmauth.example.com
+        `, `incorrect spec.httpRoute: parentRefs cannot be empty`),
+			Entry("httpRoute with incorrect pathPrefix", `
+        apiVersion: v1
+        kind: VMAuth
+        metadata:
+          name: must-fail
+        spec:
+          httpRoute:
+            parentRefs:
+            - name: gateway
+            pathPrefix: api
+        `, `incorrect spec.httpRoute: pathPrefix="api" must start with /`),
 			Entry("both configSecret and external config is defined at the same time", `
         apiVersion: v1 
         kind: VMAuth
@@ -103,6 +124,34 @@ var _ = Describe("VMAuth Webhook", func() {
             - http://url-1
         `, "at most one option can be used `spec.unauthorizedAccessConfig` or `spec.unauthorizedUserAccessSpec`, got both",
 			),
+			Entry("incorrect unauthorized access config ip_filters", `
+        apiVersion: v1
+        kind: VMAuth
+        metadata:
+          name: must-fail
+        spec:
+         unauthorizedUserAccessSpec:
+            url_prefix: http://some-dst
+            ip_filters:
+              allow_list:
+              - 10.0.0.0/24
+              deny_list:
+              - 10.0.0.300/32
+        `, `incorrect r.spec.UnauthorizedUserAccess syntax: incorrect UnauthorizedUserAccess options: incorrect CIDR="10.0.0.300/32" at ip_filters.deny_list: invalid CIDR address: 10.0.0.300/32`,
+			),
+			Entry("enforced default user policies without allow_list", `
+        apiVersion: v1
+        kind: VMAuth
+        metadata:
+          name: must-fail
+        spec:
+         defaultUserPolicies:
+            enforce: true
+            ip_filters:
+              deny_list:
+              - 10.0.0.1
+        `, `incorrect spec.defaultUserPolicies: ip_filters.allow_list cannot be empty with enforce: true`,
+			),
 		)
 	})
 })
@@ -4,7 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net"
 	"path"
+	"slices"
 	"strings"
 
 	appsv1 "k8s.io/api/apps/v1"
@@ -74,6 +76,11 @@ type VMClusterSpec struct {
 	// drops not needed security permissions
 	// +optional
 	UseStrictSecurity *bool `json:"useStrictSecurity,omitempty"`
+	// ExtraArgsOverride allows extraArgs of cluster components to override flags
+	// defined by the structured spec fields and flags managed by operator.
+	// By default, such extraArgs are rejected.
+	// +optional
+	ExtraArgsOverride bool `json:"extraArgsOverride,omitempty"`
 
 	// RequestsLoadBalancer configures load-balancing for vminsert and vmselect requests
 	// it helps to evenly spread load across pods
@@ -82,6 +89,178 @@ type VMClusterSpec struct {
 	// ManagedMetadata defines metadata that will be added to the all objects
 	// created by operator for the given CustomResource
 	ManagedMetadata *ManagedObjectsMetadata `json:"managedMetadata,omitempty"`
+	// UpdateStrategy defines order of cluster components update
+	// +optional
+	UpdateStrategy *VMClusterUpdateStrategy `json:"updateStrategy,omitempty"`
+	// RetentionFilters defines per series retention for vmstorage
+	// rendered into -retentionFilter flag. Enterprise only feature.
+	// See [here](https://docs.victoriametrics.com/Cluster-VictoriaMetrics#retention-filters)
+	// +optional
+	RetentionFilters []VMClusterRetentionFilter `json:"retentionFilters,omitempty"`
+	// Downsampling defines downsampling periods for vmstorage and vmselect
+	// rendered into -downsampling.period flag. Enterprise only feature.
+	// See [here](https://docs.victoriametrics.com/Cluster-VictoriaMetrics#downsampling)
+	// +optional
+	Downsampling []VMClusterDownsamplingPeriod `json:"downsampling,omitempty"`
+	// InternalTLS enables mTLS protection for vminsert-vmstorage and vmselect-vmstorage connections.
+	// Enterprise only feature.
+	// See [here](https://docs.victoriametrics.com/Cluster-VictoriaMetrics#mtls-protection)
+	// +optional
+	InternalTLS *VMClusterInternalTLS `json:"internalTLS,omitempty"`
+}
+
+// VMClusterRetentionFilter defines retention for series matching filter
+type VMClusterRetentionFilter struct {
+	// Filter is a series selector, for example {vm_account_id="5",env="dev"}
+	Filter string `json:"filter"`
+	// Retention for matched series, for example 5d
+	Retention string `json:"retention"`
+}
+
+func (rf *VMClusterRetentionFilter) String() string {
+	return fmt.Sprintf("%s:%s", rf.Filter, rf.Retention)
+}
+
+// VMClusterDownsamplingPeriod defines downsampling interval for samples older than offset
+type VMClusterDownsamplingPeriod struct {
+	// Filter is an optional series selector, downsampling is applied to all series if omitted
+	// +optional
+	Filter string `json:"filter,omitempty"`
+	// Offset defines age of samples to downsample, for example 30d
+	Offset string `json:"offset"`
+	// Interval defines interval between samples after downsampling, for example 5m
+	Interval string `json:"interval"`
+}
+
+func (dp *VMClusterDownsamplingPeriod) String() string {
+	if dp.Filter != "" {
+		return fmt.Sprintf("%s:%s:%s", dp.Filter, dp.Offset, dp.Interval)
+	}
+	return fmt.Sprintf("%s:%s", dp.Offset, dp.Interval)
+}
+
+// VMClusterInternalTLS defines source of certificates for mTLS between cluster components.
+// Secrets must contain ca.crt, tls.crt and tls.key keys
+type VMClusterInternalTLS struct {
+	// IssuerRef references cert-manager Issuer or ClusterIssuer.
+	// Operator creates cert-manager Certificate for each cluster component.
+	// Mutually exclusive with secretName
+	// +optional
+	IssuerRef *VMClusterCertManagerIssuerRef `json:"issuerRef,omitempty"`
+	// SecretName defines name of pre-provisioned Secret with certificate for all cluster components.
+	// Mutually exclusive with issuerRef
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+	// Duration of generated certificates, for example 2160h
+	// +kubebuilder:validation:Pattern:="^([0-9]+(ms|s|m|h))+$"
+	// +optional
+	Duration string `json:"duration,omitempty"`
+	// RenewBefore defines how long before expiration certificate must be renewed, for example 360h
+	// +optional
+	RenewBefore string `json:"renewBefore,omitempty"`
+}
+
+// VMClusterCertManagerIssuerRef references cert-manager issuer
+type VMClusterCertManagerIssuerRef struct {
+	// Name of the issuer
+	Name string `json:"name"`
+	// Kind of the issuer, Issuer or ClusterIssuer
+	// +kubebuilder:validation:Enum=Issuer;ClusterIssuer
+	// +optional
+	Kind string `json:"kind,omitempty"`
+	// Group of the issuer, defaults to cert-manager.io
+	// +optional
+	Group string `json:"group,omitempty"`
+}
+
+// VMClusterUpdateStrategy defines update strategy for cluster components
+type VMClusterUpdateStrategy struct {
+	// Order defines order of cluster components update.
+	// Operator waits until all pods of the component are ready before update of the next component.
+	// vmstorage pods must also pass /health check.
+	// Components not listed at order are updated after listed components.
+	// Defaults to vmstorage, vmselect, vminsert
+	// +optional
+	// +kubebuilder:validation:items:Enum=vmstorage;vmselect;vminsert
+	Order []string `json:"order,omitempty"`
+}
+
+const (
+	// VMClusterComponentVMStorage defines name of vmstorage component
+	VMClusterComponentVMStorage = "vmstorage"
+	// VMClusterComponentVMSelect defines name of vmselect component
+	VMClusterComponentVMSelect = "vmselect"
+	// VMClusterComponentVMInsert defines name of vminsert component
+	VMClusterComponentVMInsert = "vminsert"
+)
+
+// defaultVMClusterUpdateOrder defines default order of cluster components update
+var defaultVMClusterUpdateOrder = []string{VMClusterComponentVMStorage, VMClusterComponentVMSelect, VMClusterComponentVMInsert}
+
+// UpdateOrder returns order of cluster components update.
+// Components missing at spec.updateStrategy.order are appended in default order
+func (cr *VMCluster) UpdateOrder() []string {
+	if cr.Spec.UpdateStrategy == nil || len(cr.Spec.UpdateStrategy.Order) == 0 {
+		return append([]string{}, defaultVMClusterUpdateOrder...)
+	}
+	order := append([]string{}, cr.Spec.UpdateStrategy.Order...)
+	for _, component := range defaultVMClusterUpdateOrder {
+		if !slices.Contains(order, component) {
+			order = append(order, component)
+		}
+	}
+	return order
+}
+
+// VMClusterSkipReconcileAnnotation contains comma-separated list of VMCluster components, which objects must not be reconciled
+const VMClusterSkipReconcileAnnotation = "operator.victoriametrics.com/skip-reconcile"
+
+// IsComponentPaused checks if reconcile of the given cluster component is paused
+// with spec.<component>.paused or skip-reconcile annotation
+func (cr *VMCluster) IsComponentPaused(component string) bool {
+	var paused bool
+	switch component {
+	case VMClusterComponentVMStorage:
+		if cr.Spec.VMStorage == nil {
+			return false
+		}
+		paused = cr.Spec.VMStorage.Paused
+	case VMClusterComponentVMSelect:
+		if cr.Spec.VMSelect == nil {
+			return false
+		}
+		paused = cr.Spec.VMSelect.Paused
+	case VMClusterComponentVMInsert:
+		if cr.Spec.VMInsert == nil {
+			return false
+		}
+		paused = cr.Spec.VMInsert.Paused
+	default:
+		return false
+	}
+	if paused {
+		return true
+	}
+	for _, skipped := range strings.Split(cr.Annotations[VMClusterSkipReconcileAnnotation], ",") {
+		if strings.TrimSpace(skipped) == component {
+			return true
+		}
+	}
+	return false
+}
+
+// VMClusterInternalTLSChecksumAnnotation holds checksum of internal TLS certificate mounted into cluster component pods
+const VMClusterInternalTLSChecksumAnnotation = "operator.victoriametrics.com/internal-tls-checksum"
+
+// GetInternalTLSSecretName returns name of the Secret with internal TLS certificate for the given cluster component
+func (cr *VMCluster) GetInternalTLSSecretName(component string) string {
+	if cr.Spec.InternalTLS == nil {
+		return ""
+	}
+	if cr.Spec.InternalTLS.SecretName != "" {
+		return cr.Spec.InternalTLS.SecretName
+	}
+	return fmt.Sprintf("%s-internal-tls", prefixedName(cr.Name, component))
 }
 
 // VMAuthLBSelectorLabels defines selector labels for vmauth balancer
@@ -168,11 +347,66 @@ func (c *VMCluster) AsOwner() []metav1.OwnerReference {
 	}
 }
 
+// VMClusterUpdateStalledCondition is set to True at VMCluster status if update of cluster component failed
+// and update of the next components was halted
+const VMClusterUpdateStalledCondition = "ClusterUpdateStalled"
+
+// VMClusterStorageShrinkRejectedCondition is set to True at VMCluster status if vmstorage storage size was decreased
+const VMClusterStorageShrinkRejectedCondition = "StorageShrinkRejected"
+
+// VMClusterStorageScaleDownRejectedCondition is set to True at VMCluster status if vmstorage replicas were decreased
+// without allowDataLossOnScaleDown and replicationFactor less than 2
+const VMClusterStorageScaleDownRejectedCondition = "StorageScaleDownRejected"
+
+// vmstorage scale down phases reported at VMCluster status
+const (
+	VMClusterStorageScaleDownExcludingNodes  = "ExcludingNodes"
+	VMClusterStorageScaleDownScalingDown     = "ScalingDown"
+	VMClusterStorageScaleDownDeletingVolumes = "DeletingVolumes"
+)
+
+// vmstorage PVCs reclaim policies applied on scale down
+const (
+	VMStorageReclaimPolicyRetain = "Retain"
+	VMStorageReclaimPolicyDelete = "Delete"
+)
+
 // VMClusterStatus defines the observed state of VMCluster
 type VMClusterStatus struct {
 	StatusMetadata `json:",inline"`
 	// LegacyStatus is deprecated and will be removed at v0.52.0 version
 	LegacyStatus UpdateStatus `json:"clusterStatus,omitempty"`
+	// StorageExpansionInProgress is set to true until all vmstorage PVCs report requested capacity
+	// +optional
+	StorageExpansionInProgress bool `json:"storageExpansionInProgress,omitempty"`
+	// StorageScaleDownPhase shows current phase of vmstorage scale down, empty if scale down is not in progress
+	// +optional
+	StorageScaleDownPhase string `json:"storageScaleDownPhase,omitempty"`
+	// PausedComponents contains cluster components, which reconcile is paused
+	// +optional
+	PausedComponents []string `json:"pausedComponents,omitempty"`
+	// Components contains state of cluster components keyed by component name
+	// +optional
+	Components map[string]*VMClusterComponentStatus `json:"components,omitempty"`
+}
+
+// VMClusterComponentStatus defines observed state of cluster component
+type VMClusterComponentStatus struct {
+	// Replicas is the number of desired replicas
+	Replicas int32 `json:"replicas"`
+	// ReadyReplicas is the number of ready replicas
+	ReadyReplicas int32 `json:"readyReplicas"`
+	// UpdatedReplicas is the number of replicas updated to the latest revision
+	UpdatedReplicas int32 `json:"updatedReplicas"`
+	// ObservedGeneration is the generation of component StatefulSet or Deployment observed by its controller
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// LastRolloutTime is the time of the last successful rollout of component
+	// +optional
+	LastRolloutTime *metav1.Time `json:"lastRolloutTime,omitempty"`
+	// Reason describes why component update is stalled
+	// +optional
+	Reason string `json:"reason,omitempty"`
 }
 
 // GetStatusMetadata returns metadata for object status
@@ -228,6 +462,10 @@ type VMSelect struct {
 	// ServiceSpec that will be added to vmselect service spec
 	// +optional
 	ServiceSpec *AdditionalServiceSpec `json:"serviceSpec,omitempty"`
+	// PerReplicaService creates dedicated service for each vmselect pod in addition to the common vmselect service.
+	// It allows to route queries to the specific vmselect replica and benefit from its rollup cache.
+	// +optional
+	PerReplicaService bool `json:"perReplicaService,omitempty"`
 	// ServiceScrapeSpec that will be added to vmselect VMServiceScrape spec
 	// +optional
 	ServiceScrapeSpec *VMServiceScrapeSpec `json:"serviceScrapeSpec,omitempty"`
@@ -246,11 +484,38 @@ type VMSelect struct {
 	RollingUpdateStrategy appsv1.StatefulSetUpdateStrategyType `json:"rollingUpdateStrategy,omitempty"`
 	// ClaimTemplates allows adding additional VolumeClaimTemplates for StatefulSet
 	ClaimTemplates []v1.PersistentVolumeClaim `json:"claimTemplates,omitempty"`
+	// AdditionalPools defines additional vmselect pools connected to the same vmstorage nodes.
+	// Each pool is deployed as a dedicated StatefulSet and Service with vmselect-<cluster>-<pool> name
+	// and inherits vmselect configuration. It allows to separate query workloads, e.g. alerting and dashboards.
+	// +optional
+	AdditionalPools []VMSelectPool `json:"additionalPools,omitempty"`
 
 	CommonDefaultableParams           `json:",inline"`
 	CommonApplicationDeploymentParams `json:",inline"`
 }
 
+// VMSelectPool defines additional pool of vmselect nodes
+// pool inherits vmselect configuration and overrides it with the defined fields
+type VMSelectPool struct {
+	// Name of the pool, it's used as suffix for the pool StatefulSet and Service names
+	Name string `json:"name"`
+	// ReplicaCount defines number of vmselect nodes at the pool
+	// +optional
+	ReplicaCount *int32 `json:"replicaCount,omitempty"`
+	// Resources overrides vmselect resources for the pool pods
+	// +optional
+	Resources *v1.ResourceRequirements `json:"resources,omitempty"`
+	// ExtraArgs are merged with vmselect extraArgs for the pool pods
+	// +optional
+	ExtraArgs map[string]string `json:"extraArgs,omitempty"`
+	// CacheMountPath overrides vmselect cacheMountPath for the pool pods
+	// +optional
+	CacheMountPath string `json:"cacheMountPath,omitempty"`
+	// RetainCaches keeps PersistentVolumeClaims of the pool caches after pool removal
+	// +optional
+	RetainCaches bool `json:"retainCaches,omitempty"`
+}
+
 // GetVMSelectLBName returns headless proxy service name for select component
 func (cr *VMCluster) GetVMSelectLBName() string {
 	return prefixedName(cr.Name, "vmselectinternal")
@@ -356,10 +621,58 @@ func (cr *VMCluster) GetVMSelectName() string {
 	return prefixedName(cr.Name, "vmselect")
 }
 
+// GetVMSelectReplicaServiceName returns name of the dedicated service for vmselect pod with the given index
+func (cr *VMCluster) GetVMSelectReplicaServiceName(idx int32) string {
+	return fmt.Sprintf("%s-%d", cr.GetVMSelectName(), idx)
+}
+
 func (cr *VMCluster) GetVMStorageName() string {
 	return prefixedName(cr.Name, "vmstorage")
 }
 
+// GetVMStorageGroupName returns name of the vmstorage StatefulSet for the given storage node group
+func (cr *VMCluster) GetVMStorageGroupName(group string) string {
+	return fmt.Sprintf("%s-%s", cr.GetVMStorageName(), group)
+}
+
+// VMStorageStatefulSetNames returns names of all vmstorage StatefulSets
+func (cr *VMCluster) VMStorageStatefulSetNames() []string {
+	if cr.Spec.VMStorage == nil {
+		return nil
+	}
+	if len(cr.Spec.VMStorage.StorageNodeGroups) == 0 {
+		return []string{cr.GetVMStorageName()}
+	}
+	names := make([]string, 0, len(cr.Spec.VMStorage.StorageNodeGroups))
+	for _, g := range cr.Spec.VMStorage.StorageNodeGroups {
+		names = append(names, cr.GetVMStorageGroupName(g.Name))
+	}
+	return names
+}
+
+// VMStorageReplicas returns desired number of replicas for each vmstorage StatefulSet
+func (cr *VMCluster) VMStorageReplicas() map[string]int32 {
+	if cr.Spec.VMStorage == nil {
+		return nil
+	}
+	replicas := make(map[string]int32, len(cr.Spec.VMStorage.StorageNodeGroups)+1)
+	if len(cr.Spec.VMStorage.StorageNodeGroups) == 0 {
+		replicas[cr.GetVMStorageName()] = ptr.Deref(cr.Spec.VMStorage.ReplicaCount, 0)
+	}
+	for _, g := range cr.Spec.VMStorage.StorageNodeGroups {
+		replicas[cr.GetVMStorageGroupName(g.Name)] = ptr.Deref(g.ReplicaCount, 0)
+	}
+	return replicas
+}
+
+// IsStorageScaleDownAllowed checks if number of vmstorage nodes could be decreased
+func (cr *VMCluster) IsStorageScaleDownAllowed() bool {
+	if cr.Spec.VMStorage == nil {
+		return false
+	}
+	return cr.Spec.VMStorage.AllowDataLossOnScaleDown || ptr.Deref(cr.Spec.ReplicationFactor, 1) >= 2
+}
+
 type VMStorage struct {
 	// PodMetadata configures Labels and Annotations which are propagated to the VMStorage pods.
 	PodMetadata *EmbeddedObjectMetadata `json:"podMetadata,omitempty"`
@@ -414,14 +727,75 @@ type VMStorage struct {
 	// Can be changed for RollingUpdate
 	// +optional
 	RollingUpdateStrategy appsv1.StatefulSetUpdateStrategyType `json:"rollingUpdateStrategy,omitempty"`
+	// RollingUpdateStrategyBehavior defines additional checks of vmstorage pods performed during rolling update
+	// +optional
+	RollingUpdateStrategyBehavior *VMStorageRollingUpdateStrategyBehavior `json:"rollingUpdateStrategyBehavior,omitempty"`
 
 	// ClaimTemplates allows adding additional VolumeClaimTemplates for StatefulSet
 	ClaimTemplates []v1.PersistentVolumeClaim `json:"claimTemplates,omitempty"`
 
+	// StorageNodeGroups splits vmstorage into multiple StatefulSets, one per group.
+	// Each group can be pinned to the dedicated availability zone with own nodeSelector, affinity and tolerations.
+	// vminsert and vmselect use nodes of all groups in the order of definition.
+	// replicaCount is ignored if groups are defined.
+	// +optional
+	StorageNodeGroups []VMStorageNodeGroup `json:"storageNodeGroups,omitempty"`
+
+	// AllowDataLossOnScaleDown allows decreasing number of vmstorage nodes if replicationFactor is less than 2.
+	// Data stored at removed nodes is no longer available for querying.
+	// +optional
+	AllowDataLossOnScaleDown bool `json:"allowDataLossOnScaleDown,omitempty"`
+	// ReclaimPolicy defines what happens with PVCs of vmstorage nodes removed on scale down.
+	// Retain keeps PVCs, Delete removes them after StatefulSet scale down.
+	// Default is Retain.
+	// +kubebuilder:validation:Enum=Retain;Delete
+	// +optional
+	ReclaimPolicy string `json:"reclaimPolicy,omitempty"`
+
 	CommonDefaultableParams           `json:",inline"`
 	CommonApplicationDeploymentParams `json:",inline"`
 }
 
+// VMStorageRollingUpdateStrategyBehavior defines additional checks of vmstorage pods during rolling update
+type VMStorageRollingUpdateStrategyBehavior struct {
+	// WaitForVMStorageReadyMetrics makes operator wait until updated vmstorage pod serves vm_indexdb_items_added_total metric
+	// and responds to /-/healthy requests before update of the next pod.
+	// Pod may become Ready before it finishes index caches loading. It's supported only for OnDelete rollingUpdateStrategy.
+	// +optional
+	WaitForVMStorageReadyMetrics bool `json:"waitForVMStorageReadyMetrics,omitempty"`
+	// ReadyChecksCount defines number of consecutive successful checks required to consider vmstorage pod updated
+	// Default is 3
+	// +optional
+	ReadyChecksCount *int32 `json:"readyChecksCount,omitempty"`
+	// ReadyCheckInterval defines interval between checks
+	// Default is 5s
+	// +kubebuilder:validation:Pattern:="^([0-9]+(ms|s|m|h))+$"
+	// +optional
+	ReadyCheckInterval string `json:"readyCheckInterval,omitempty"`
+	// ReadyCheckTimeout defines how long operator waits for successful checks of a single vmstorage pod
+	// Default is 5m
+	// +kubebuilder:validation:Pattern:="^([0-9]+(ms|s|m|h))+$"
+	// +optional
+	ReadyCheckTimeout string `json:"readyCheckTimeout,omitempty"`
+}
+
+// VMStorageNodeGroup defines group of vmstorage nodes
+type VMStorageNodeGroup struct {
+	// Name of the group, it's used as suffix for the group StatefulSet name
+	Name string `json:"name"`
+	// ReplicaCount defines number of vmstorage nodes at the group
+	ReplicaCount *int32 `json:"replicaCount"`
+	// NodeSelector overrides vmstorage nodeSelector for the group pods
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// Affinity overrides vmstorage affinity for the group pods
+	// +optional
+	Affinity *v1.Affinity `json:"affinity,omitempty"`
+	// Tolerations overrides vmstorage tolerations for the group pods
+	// +optional
+	Tolerations []v1.Toleration `json:"tolerations,omitempty"`
+}
+
 type VMBackup struct {
 	// AcceptEULA accepts enterprise feature usage, must be set to true.
 	// otherwise backupmanager cannot be added to single/cluster version.
@@ -565,6 +939,52 @@ func (cr *VMCluster) VMSelectPodLabels() map[string]string {
 	return labels.Merge(cr.Spec.VMSelect.PodMetadata.Labels, selectorLabels)
 }
 
+// VMSelectPoolLabel contains name of the additional vmselect pool
+const VMSelectPoolLabel = "operator.victoriametrics.com/vmselect-pool"
+
+// GetVMSelectPoolName returns name of the vmselect StatefulSet and Service for the given pool
+// empty pool refers to the default vmselect pool
+func (cr *VMCluster) GetVMSelectPoolName(pool string) string {
+	if pool == "" {
+		return cr.GetVMSelectName()
+	}
+	return fmt.Sprintf("%s-%s", cr.GetVMSelectName(), pool)
+}
+
+// VMSelectStatefulSetNames returns names of all vmselect StatefulSets
+func (cr *VMCluster) VMSelectStatefulSetNames() []string {
+	if cr.Spec.VMSelect == nil {
+		return nil
+	}
+	names := make([]string, 0, len(cr.Spec.VMSelect.AdditionalPools)+1)
+	names = append(names, cr.GetVMSelectName())
+	for _, p := range cr.Spec.VMSelect.AdditionalPools {
+		names = append(names, cr.GetVMSelectPoolName(p.Name))
+	}
+	return names
+}
+
+// VMSelectPoolSelectorLabels returns selector labels for the given vmselect pool
+// additional pools have dedicated name label in order to not overlap with the default pool selector
+func (cr *VMCluster) VMSelectPoolSelectorLabels(pool string) map[string]string {
+	selectorLabels := cr.VMSelectSelectorLabels()
+	if pool == "" {
+		return selectorLabels
+	}
+	selectorLabels["app.kubernetes.io/name"] = "vmselect-pool"
+	selectorLabels[VMSelectPoolLabel] = pool
+	return selectorLabels
+}
+
+// VMSelectPoolPodLabels returns pod labels for the given vmselect pool
+func (cr *VMCluster) VMSelectPoolPodLabels(pool string) map[string]string {
+	selectorLabels := cr.VMSelectPoolSelectorLabels(pool)
+	if cr.Spec.VMSelect == nil || cr.Spec.VMSelect.PodMetadata == nil {
+		return selectorLabels
+	}
+	return labels.Merge(cr.Spec.VMSelect.PodMetadata.Labels, selectorLabels)
+}
+
 // VMInsertSelectorLabels returns selector labels for vminsert cluster component
 func (cr *VMCluster) VMInsertSelectorLabels() map[string]string {
 	return map[string]string{
@@ -603,12 +1023,51 @@ func (cr *VMCluster) VMStoragePodLabels() map[string]string {
 	return labels.Merge(cr.Spec.VMStorage.PodMetadata.Labels, selectorLabels)
 }
 
+// VMStorageGroupLabel contains name of the vmstorage node group
+const VMStorageGroupLabel = "operator.victoriametrics.com/vmstorage-group"
+
+// VMStorageGroupSelectorLabels returns selector labels for the given vmstorage node group
+func (cr VMCluster) VMStorageGroupSelectorLabels(group string) map[string]string {
+	selectorLabels := cr.VMStorageSelectorLabels()
+	selectorLabels[VMStorageGroupLabel] = group
+	return selectorLabels
+}
+
+// AvailableStorageNodePods returns pod names of the storage nodes for the provided component
+// nodes of storage groups are listed in the order of groups definition
+// maintenance node ids refer to the position of the pod at the list of all nodes
+func (cr *VMCluster) AvailableStorageNodePods(requestsType string) []string {
+	if cr.Spec.VMStorage == nil {
+		return nil
+	}
+	var pods []string
+	if len(cr.Spec.VMStorage.StorageNodeGroups) == 0 {
+		for i := int32(0); i < ptr.Deref(cr.Spec.VMStorage.ReplicaCount, 0); i++ {
+			pods = append(pods, fmt.Sprintf("%s-%d", cr.GetVMStorageName(), i))
+		}
+	}
+	for _, g := range cr.Spec.VMStorage.StorageNodeGroups {
+		for i := int32(0); i < ptr.Deref(g.ReplicaCount, 0); i++ {
+			pods = append(pods, fmt.Sprintf("%s-%d", cr.GetVMStorageGroupName(g.Name), i))
+		}
+	}
+	var result []string
+	for _, id := range cr.availableStorageNodeIDs(requestsType, int32(len(pods))) {
+		result = append(result, pods[id])
+	}
+	return result
+}
+
 // AvailableStorageNodeIDs returns ids of the storage nodes for the provided component
 func (cr *VMCluster) AvailableStorageNodeIDs(requestsType string) []int32 {
-	var result []int32
 	if cr.Spec.VMStorage == nil || cr.Spec.VMStorage.ReplicaCount == nil {
-		return result
+		return nil
 	}
+	return cr.availableStorageNodeIDs(requestsType, *cr.Spec.VMStorage.ReplicaCount)
+}
+
+func (cr *VMCluster) availableStorageNodeIDs(requestsType string, replicaCount int32) []int32 {
+	var result []int32
 	maintenanceNodes := make(map[int32]struct{})
 	switch requestsType {
 	case "select":
@@ -622,7 +1081,7 @@ func (cr *VMCluster) AvailableStorageNodeIDs(requestsType string) []int32 {
 	default:
 		panic("BUG unsupported requestsType: " + requestsType)
 	}
-	for i := int32(0); i < *cr.Spec.VMStorage.ReplicaCount; i++ {
+	for i := int32(0); i < replicaCount; i++ {
 		if _, ok := maintenanceNodes[i]; ok {
 			continue
 		}
@@ -850,6 +1309,20 @@ func (cr *VMCluster) VMInsertURL() string {
 	return fmt.Sprintf("%s://%s.%s.svc:%s", protoFromFlags(cr.Spec.VMInsert.ExtraArgs), cr.GetVMInsertName(), cr.Namespace, port)
 }
 
+// VMStoragePodHealthURL returns url of health endpoint for the given vmstorage pod address
+func (cr *VMCluster) VMStoragePodHealthURL(podIP string) string {
+	return cr.VMStoragePodURL(podIP, healthPath)
+}
+
+// VMStoragePodURL returns url of the given http path for the given vmstorage pod address
+func (cr *VMCluster) VMStoragePodURL(podIP, path string) string {
+	port := cr.Spec.VMStorage.Port
+	if port == "" {
+		port = "8482"
+	}
+	return fmt.Sprintf("%s://%s%s", protoFromFlags(cr.Spec.VMStorage.ExtraArgs), net.JoinHostPort(podIP, port), buildPathWithPrefixFlag(cr.Spec.VMStorage.ExtraArgs, path))
+}
+
 func (cr *VMCluster) VMStorageURL() string {
 	if cr.Spec.VMStorage == nil {
 		return ""
@@ -924,6 +1397,9 @@ func (cr *VMCluster) SetUpdateStatusTo(ctx context.Context, r client.Client, sta
 		mutateCurrentBeforeCompare: func(vs *VMClusterStatus) {
 			vs.LegacyStatus = vs.UpdateStatus
 		},
+		statusOf: func(pcr *VMCluster) *VMClusterStatus {
+			return &pcr.Status
+		},
 	})
 }
 
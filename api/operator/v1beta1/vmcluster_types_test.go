@@ -1,9 +1,14 @@
 package v1beta1
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
 )
 
 func TestVMBackup_SnapshotDeletePathWithFlags(t *testing.T) {
@@ -94,3 +99,278 @@ func TestVMBackup_SnapshotCreatePathWithFlags(t *testing.T) {
 		})
 	}
 }
+
+func TestVMCluster_UpdateOrder(t *testing.T) {
+	f := func(strategy *VMClusterUpdateStrategy, want []string) {
+		t.Helper()
+		cr := &VMCluster{Spec: VMClusterSpec{UpdateStrategy: strategy}}
+		assert.Equal(t, want, cr.UpdateOrder())
+	}
+	f(nil, []string{"vmstorage", "vmselect", "vminsert"})
+	f(&VMClusterUpdateStrategy{}, []string{"vmstorage", "vmselect", "vminsert"})
+	f(&VMClusterUpdateStrategy{Order: []string{"vmselect", "vmstorage", "vminsert"}}, []string{"vmselect", "vmstorage", "vminsert"})
+
+	// missing components are appended in default order
+	f(&VMClusterUpdateStrategy{Order: []string{"vminsert"}}, []string{"vminsert", "vmstorage", "vmselect"})
+}
+
+func TestVMCluster_sanityCheckUpdateStrategy(t *testing.T) {
+	f := func(order []string, wantErr bool) {
+		t.Helper()
+		cr := &VMCluster{Spec: VMClusterSpec{UpdateStrategy: &VMClusterUpdateStrategy{Order: order}}}
+		if err := cr.sanityCheck(); (err != nil) != wantErr {
+			t.Fatalf("sanityCheck() error = %v, wantErr %v", err, wantErr)
+		}
+	}
+	f([]string{"vmselect", "vmstorage", "vminsert"}, false)
+	f([]string{"vmauth"}, true)
+	f([]string{"vmselect", "vmselect"}, true)
+}
+
+func TestVMCluster_sanityCheckHPA(t *testing.T) {
+	f := func(spec VMClusterSpec, wantErr bool) {
+		t.Helper()
+		cr := &VMCluster{Spec: spec}
+		if err := cr.sanityCheck(); (err != nil) != wantErr {
+			t.Fatalf("sanityCheck() error = %v, wantErr %v", err, wantErr)
+		}
+	}
+	hpa := &EmbeddedHPA{MinReplicas: ptr.To[int32](1), MaxReplicas: 3, Behaviour: &autoscalingv2.HorizontalPodAutoscalerBehavior{}}
+	f(VMClusterSpec{VMInsert: &VMInsert{HPA: hpa}, VMSelect: &VMSelect{HPA: hpa}}, false)
+	f(VMClusterSpec{VMSelect: &VMSelect{HPA: &EmbeddedHPA{MinReplicas: ptr.To[int32](3), MaxReplicas: 2}}}, true)
+}
+
+func TestVMCluster_sanityCheckRetentionFiltersAndDownsampling(t *testing.T) {
+	f := func(spec VMClusterSpec, wantErr bool) {
+		t.Helper()
+		cr := &VMCluster{Spec: spec}
+		if err := cr.sanityCheck(); (err != nil) != wantErr {
+			t.Fatalf("sanityCheck() error = %v, wantErr %v", err, wantErr)
+		}
+	}
+	f(VMClusterSpec{RetentionFilters: []VMClusterRetentionFilter{{Filter: `{env="dev"}`, Retention: "5d"}}}, false)
+	f(VMClusterSpec{RetentionFilters: []VMClusterRetentionFilter{{Filter: `{env="dev"}`, Retention: "5days"}}}, true)
+	f(VMClusterSpec{RetentionFilters: []VMClusterRetentionFilter{{Retention: "5d"}}}, true)
+	f(VMClusterSpec{Downsampling: []VMClusterDownsamplingPeriod{{Offset: "30d", Interval: "5m"}, {Filter: `{env="dev"}`, Offset: "1y", Interval: "1h"}}}, false)
+	f(VMClusterSpec{Downsampling: []VMClusterDownsamplingPeriod{{Offset: "30d"}}}, true)
+	f(VMClusterSpec{Downsampling: []VMClusterDownsamplingPeriod{{Offset: "a", Interval: "5m"}}}, true)
+}
+
+func TestVMCluster_AvailableStorageNodePods(t *testing.T) {
+	f := func(vmstorage *VMStorage, requestsType string, want []string) {
+		t.Helper()
+		cr := &VMCluster{ObjectMeta: metav1.ObjectMeta{Name: "cluster"}, Spec: VMClusterSpec{VMStorage: vmstorage}}
+		assert.Equal(t, want, cr.AvailableStorageNodePods(requestsType))
+	}
+	f(nil, "insert", nil)
+	f(&VMStorage{CommonApplicationDeploymentParams: CommonApplicationDeploymentParams{ReplicaCount: ptr.To[int32](2)}}, "insert",
+		[]string{"vmstorage-cluster-0", "vmstorage-cluster-1"})
+	f(&VMStorage{
+		MaintenanceSelectNodeIDs: []int32{1},
+		StorageNodeGroups: []VMStorageNodeGroup{
+			{Name: "a", ReplicaCount: ptr.To[int32](2)},
+			{Name: "b", ReplicaCount: ptr.To[int32](2)},
+		},
+		CommonApplicationDeploymentParams: CommonApplicationDeploymentParams{ReplicaCount: ptr.To[int32](1)},
+	}, "select", []string{"vmstorage-cluster-a-0", "vmstorage-cluster-b-0", "vmstorage-cluster-b-1"})
+}
+
+func TestVMCluster_sanityCheckStorageNodeGroups(t *testing.T) {
+	f := func(groups []VMStorageNodeGroup, wantErr bool) {
+		t.Helper()
+		cr := &VMCluster{Spec: VMClusterSpec{VMStorage: &VMStorage{StorageNodeGroups: groups}}}
+		if err := cr.sanityCheck(); (err != nil) != wantErr {
+			t.Fatalf("sanityCheck() error = %v, wantErr %v", err, wantErr)
+		}
+	}
+	f([]VMStorageNodeGroup{{Name: "zone-a", ReplicaCount: ptr.To[int32](1)}, {Name: "zone-b", ReplicaCount: ptr.To[int32](1)}}, false)
+	f([]VMStorageNodeGroup{{Name: "zone-a", ReplicaCount: ptr.To[int32](1)}, {Name: "zone-a", ReplicaCount: ptr.To[int32](1)}}, true)
+	f([]VMStorageNodeGroup{{Name: "Zone_A", ReplicaCount: ptr.To[int32](1)}}, true)
+	f([]VMStorageNodeGroup{{Name: "zone-a"}}, true)
+}
+
+func TestVMCluster_sanityCheckVMSelectAdditionalPools(t *testing.T) {
+	f := func(pools []VMSelectPool, wantErr bool) {
+		t.Helper()
+		cr := &VMCluster{Spec: VMClusterSpec{VMSelect: &VMSelect{AdditionalPools: pools}}}
+		if err := cr.sanityCheck(); (err != nil) != wantErr {
+			t.Fatalf("sanityCheck() error = %v, wantErr %v", err, wantErr)
+		}
+	}
+	f([]VMSelectPool{{Name: "alerting"}, {Name: "dashboards", ReplicaCount: ptr.To[int32](3)}}, false)
+	f([]VMSelectPool{{Name: "alerting"}, {Name: "alerting"}}, true)
+	f([]VMSelectPool{{Name: "Alerting_Pool"}}, true)
+	f([]VMSelectPool{{Name: ""}}, true)
+	f([]VMSelectPool{{Name: "1"}}, true)
+}
+
+func TestVMCluster_checkStorageScaleDown(t *testing.T) {
+	f := func(prev, cr *VMCluster, wantErr bool) {
+		t.Helper()
+		if err := cr.checkStorageScaleDown(prev); (err != nil) != wantErr {
+			t.Fatalf("checkStorageScaleDown() error = %v, wantErr %v", err, wantErr)
+		}
+	}
+	newCR := func(replicationFactor int32, storage *VMStorage) *VMCluster {
+		return &VMCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "test"},
+			Spec:       VMClusterSpec{ReplicationFactor: ptr.To(replicationFactor), VMStorage: storage},
+		}
+	}
+	// scale up
+	f(newCR(1, &VMStorage{CommonApplicationDeploymentParams: CommonApplicationDeploymentParams{ReplicaCount: ptr.To[int32](2)}}),
+		newCR(1, &VMStorage{CommonApplicationDeploymentParams: CommonApplicationDeploymentParams{ReplicaCount: ptr.To[int32](3)}}), false)
+	// scale down without replication
+	f(newCR(1, &VMStorage{CommonApplicationDeploymentParams: CommonApplicationDeploymentParams{ReplicaCount: ptr.To[int32](3)}}),
+		newCR(1, &VMStorage{CommonApplicationDeploymentParams: CommonApplicationDeploymentParams{ReplicaCount: ptr.To[int32](2)}}), true)
+	// scale down with replication
+	f(newCR(2, &VMStorage{CommonApplicationDeploymentParams: CommonApplicationDeploymentParams{ReplicaCount: ptr.To[int32](3)}}),
+		newCR(2, &VMStorage{CommonApplicationDeploymentParams: CommonApplicationDeploymentParams{ReplicaCount: ptr.To[int32](2)}}), false)
+	// scale down with explicit data loss permission
+	f(newCR(1, &VMStorage{CommonApplicationDeploymentParams: CommonApplicationDeploymentParams{ReplicaCount: ptr.To[int32](3)}}),
+		newCR(1, &VMStorage{AllowDataLossOnScaleDown: true, CommonApplicationDeploymentParams: CommonApplicationDeploymentParams{ReplicaCount: ptr.To[int32](2)}}), false)
+	// removed storage node group
+	f(newCR(1, &VMStorage{StorageNodeGroups: []VMStorageNodeGroup{{Name: "a", ReplicaCount: ptr.To[int32](1)}, {Name: "b", ReplicaCount: ptr.To[int32](1)}}}),
+		newCR(1, &VMStorage{StorageNodeGroups: []VMStorageNodeGroup{{Name: "a", ReplicaCount: ptr.To[int32](2)}}}), true)
+	// migration to storage node groups with replication
+	f(newCR(2, &VMStorage{CommonApplicationDeploymentParams: CommonApplicationDeploymentParams{ReplicaCount: ptr.To[int32](2)}}),
+		newCR(2, &VMStorage{StorageNodeGroups: []VMStorageNodeGroup{{Name: "a", ReplicaCount: ptr.To[int32](2)}}}), true)
+	// migration to storage node groups with explicit data loss permission
+	f(newCR(1, &VMStorage{CommonApplicationDeploymentParams: CommonApplicationDeploymentParams{ReplicaCount: ptr.To[int32](2)}}),
+		newCR(1, &VMStorage{AllowDataLossOnScaleDown: true, StorageNodeGroups: []VMStorageNodeGroup{{Name: "a", ReplicaCount: ptr.To[int32](2)}}}), false)
+}
+
+func TestVMCluster_sanityCheckRollingUpdateStrategyBehavior(t *testing.T) {
+	f := func(strategy appsv1.StatefulSetUpdateStrategyType, behavior *VMStorageRollingUpdateStrategyBehavior, wantErr bool) {
+		t.Helper()
+		cr := &VMCluster{Spec: VMClusterSpec{VMStorage: &VMStorage{RollingUpdateStrategy: strategy, RollingUpdateStrategyBehavior: behavior}}}
+		if err := cr.sanityCheck(); (err != nil) != wantErr {
+			t.Fatalf("sanityCheck() error = %v, wantErr %v", err, wantErr)
+		}
+	}
+	f("", &VMStorageRollingUpdateStrategyBehavior{WaitForVMStorageReadyMetrics: true, ReadyCheckInterval: "5s", ReadyCheckTimeout: "10m"}, false)
+	f(appsv1.RollingUpdateStatefulSetStrategyType, &VMStorageRollingUpdateStrategyBehavior{WaitForVMStorageReadyMetrics: true}, true)
+	f("", &VMStorageRollingUpdateStrategyBehavior{WaitForVMStorageReadyMetrics: true, ReadyChecksCount: ptr.To[int32](0)}, true)
+	f("", &VMStorageRollingUpdateStrategyBehavior{WaitForVMStorageReadyMetrics: true, ReadyCheckInterval: "5"}, true)
+	f("", &VMStorageRollingUpdateStrategyBehavior{WaitForVMStorageReadyMetrics: true, ReadyCheckTimeout: "x"}, true)
+}
+
+func TestVMCluster_IsComponentPaused(t *testing.T) {
+	f := func(annotation string, spec VMClusterSpec, component string, want bool) {
+		t.Helper()
+		cr := &VMCluster{Spec: spec}
+		if annotation != "" {
+			cr.Annotations = map[string]string{VMClusterSkipReconcileAnnotation: annotation}
+		}
+		assert.Equal(t, want, cr.IsComponentPaused(component))
+	}
+	spec := VMClusterSpec{VMStorage: &VMStorage{}, VMSelect: &VMSelect{}, VMInsert: &VMInsert{}}
+	f("", spec, VMClusterComponentVMStorage, false)
+	f("vmstorage, vmselect", spec, VMClusterComponentVMStorage, true)
+	f("vmstorage, vmselect", spec, VMClusterComponentVMSelect, true)
+	f("vmstorage, vmselect", spec, VMClusterComponentVMInsert, false)
+	f("vminsert", VMClusterSpec{}, VMClusterComponentVMInsert, false)
+	f("", VMClusterSpec{VMInsert: &VMInsert{CommonApplicationDeploymentParams: CommonApplicationDeploymentParams{Paused: true}}}, VMClusterComponentVMInsert, true)
+}
+
+func TestVMCluster_sanityCheckInternalTLS(t *testing.T) {
+	f := func(clusterVersion string, storageImage Image, tls *VMClusterInternalTLS, wantErr bool) {
+		t.Helper()
+		cr := &VMCluster{Spec: VMClusterSpec{
+			ClusterVersion: clusterVersion,
+			VMStorage:      &VMStorage{CommonDefaultableParams: CommonDefaultableParams{Image: storageImage}},
+			InternalTLS:    tls,
+		}}
+		if err := cr.sanityCheck(); (err != nil) != wantErr {
+			t.Fatalf("sanityCheck() error = %v, wantErr %v", err, wantErr)
+		}
+	}
+	issuer := &VMClusterCertManagerIssuerRef{Name: "ca-issuer", Kind: "Issuer"}
+	f("v1.110.0-enterprise-cluster", Image{}, &VMClusterInternalTLS{IssuerRef: issuer, Duration: "2160h", RenewBefore: "360h"}, false)
+	f("", Image{Tag: "v1.110.0-enterprise-cluster"}, &VMClusterInternalTLS{SecretName: "mtls"}, false)
+	f("v1.110.0-cluster", Image{}, &VMClusterInternalTLS{SecretName: "mtls"}, true)
+	f("", Image{}, &VMClusterInternalTLS{SecretName: "mtls"}, true)
+	f("v1.110.0-enterprise-cluster", Image{Tag: "v1.110.0-cluster"}, &VMClusterInternalTLS{SecretName: "mtls"}, true)
+	f("v1.110.0-enterprise-cluster", Image{}, &VMClusterInternalTLS{}, true)
+	f("v1.110.0-enterprise-cluster", Image{}, &VMClusterInternalTLS{IssuerRef: issuer, SecretName: "mtls"}, true)
+	f("v1.110.0-enterprise-cluster", Image{}, &VMClusterInternalTLS{IssuerRef: issuer, Duration: "90d"}, true)
+}
+
+func TestVMCluster_checkExtraArgs(t *testing.T) {
+	f := func(spec VMClusterSpec, wantErr string) {
+		t.Helper()
+		cr := &VMCluster{Spec: spec}
+		err := cr.checkExtraArgs()
+		if wantErr == "" {
+			assert.NoError(t, err)
+			return
+		}
+		assert.EqualError(t, err, wantErr)
+	}
+	withExtraArgs := func(extraArgs map[string]string) CommonApplicationDeploymentParams {
+		return CommonApplicationDeploymentParams{ExtraArgs: extraArgs}
+	}
+
+	// flag defined by spec
+	f(VMClusterSpec{
+		RetentionPeriod: "1",
+		VMStorage:       &VMStorage{CommonApplicationDeploymentParams: withExtraArgs(map[string]string{"-retentionPeriod": "2"})},
+	}, "incorrect vmstorage extraArgs: flag -retentionPeriod is already defined by spec.retentionPeriod, set spec.extraArgsOverride=true in order to override it")
+
+	// extraArgs override is allowed
+	f(VMClusterSpec{
+		RetentionPeriod:   "1",
+		ExtraArgsOverride: true,
+		VMStorage:         &VMStorage{CommonApplicationDeploymentParams: withExtraArgs(map[string]string{"retentionPeriod": "2"})},
+	}, "")
+
+	// managed flag
+	f(VMClusterSpec{
+		VMInsert: &VMInsert{CommonApplicationDeploymentParams: withExtraArgs(map[string]string{"httpListenAddr": ":8480"})},
+	}, "incorrect vminsert extraArgs: flag -httpListenAddr is managed by operator and cannot be set at extraArgs, set spec.extraArgsOverride=true in order to override it")
+
+	// storage nodes are not managed by the cluster
+	f(VMClusterSpec{
+		VMSelect: &VMSelect{CommonApplicationDeploymentParams: withExtraArgs(map[string]string{"storageNode": "vmstorage:8401"})},
+	}, "")
+
+	// storage nodes are managed by the cluster
+	f(VMClusterSpec{
+		VMStorage: &VMStorage{},
+		VMSelect:  &VMSelect{CommonApplicationDeploymentParams: withExtraArgs(map[string]string{"storageNode": "vmstorage:8401"})},
+	}, "incorrect vmselect extraArgs: flag -storageNode is managed by operator and cannot be set at extraArgs, set spec.extraArgsOverride=true in order to override it")
+
+	// logger flag defined by component spec
+	f(VMClusterSpec{
+		VMSelect: &VMSelect{
+			LogLevel:                          "WARN",
+			CommonApplicationDeploymentParams: withExtraArgs(map[string]string{"loggerLevel": "ERROR"}),
+		},
+	}, "incorrect vmselect extraArgs: flag -loggerLevel is already defined by spec.vmselect.logLevel, set spec.extraArgsOverride=true in order to override it")
+}
+
+func TestVMCluster_ValidateUpdateExtraArgs(t *testing.T) {
+	f := func(prevExtraArgs, extraArgs map[string]string, wantErr bool, wantWarnings int) {
+		t.Helper()
+		newCR := func(extraArgs map[string]string) *VMCluster {
+			return &VMCluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "test"},
+				Spec: VMClusterSpec{
+					RetentionPeriod: "1",
+					VMStorage:       &VMStorage{CommonApplicationDeploymentParams: CommonApplicationDeploymentParams{ExtraArgs: extraArgs}},
+				},
+			}
+		}
+		warnings, err := newCR(nil).ValidateUpdate(context.Background(), newCR(prevExtraArgs), newCR(extraArgs))
+		if (err != nil) != wantErr {
+			t.Fatalf("ValidateUpdate() error = %v, wantErr %v", err, wantErr)
+		}
+		assert.Len(t, warnings, wantWarnings)
+	}
+	// new conflict
+	f(nil, map[string]string{"retentionPeriod": "2"}, true, 0)
+	// conflict accepted by previous operator versions
+	f(map[string]string{"retentionPeriod": "2"}, map[string]string{"retentionPeriod": "3"}, false, 1)
+	// conflict is resolved
+	f(map[string]string{"retentionPeriod": "2"}, nil, false, 0)
+}
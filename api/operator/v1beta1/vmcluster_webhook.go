@@ -20,8 +20,16 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutils"
+	appsv1 "k8s.io/api/apps/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
 	ctrl "sigs.k8s.io/controller-runtime"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
@@ -56,6 +64,20 @@ func (r *VMCluster) sanityCheck() error {
 		if vms.StorageSpec != nil {
 			vmclusterlog.Info("deprecated property is defined `vmcluster.spec.vmselect.persistentVolume`, use `storage` instead.")
 		}
+		pools := make(map[string]struct{}, len(vms.AdditionalPools))
+		for idx, p := range vms.AdditionalPools {
+			if errs := validation.IsDNS1123Label(p.Name); len(errs) > 0 {
+				return fmt.Errorf("incorrect additionalPools[%d].name=%q: %s", idx, p.Name, strings.Join(errs, ","))
+			}
+			// numeric names clash with names of per replica services
+			if _, err := strconv.Atoi(p.Name); err == nil {
+				return fmt.Errorf("additionalPools[%d].name=%q cannot be a number", idx, p.Name)
+			}
+			if _, ok := pools[p.Name]; ok {
+				return fmt.Errorf("duplicate additionalPools[%d].name=%q", idx, p.Name)
+			}
+			pools[p.Name] = struct{}{}
+		}
 	}
 	if r.Spec.VMInsert != nil {
 		vmi := r.Spec.VMInsert
@@ -73,6 +95,37 @@ func (r *VMCluster) sanityCheck() error {
 		if vms.ServiceSpec != nil && vms.ServiceSpec.Name == r.GetVMInsertName() {
 			return fmt.Errorf(".serviceSpec.Name cannot be equal to prefixed name=%q", r.GetVMStorageName())
 		}
+		groups := make(map[string]struct{}, len(vms.StorageNodeGroups))
+		for idx, g := range vms.StorageNodeGroups {
+			if errs := validation.IsDNS1123Label(g.Name); len(errs) > 0 {
+				return fmt.Errorf("incorrect storageNodeGroups[%d].name=%q: %s", idx, g.Name, strings.Join(errs, ","))
+			}
+			if _, ok := groups[g.Name]; ok {
+				return fmt.Errorf("duplicate storageNodeGroups[%d].name=%q", idx, g.Name)
+			}
+			groups[g.Name] = struct{}{}
+			if g.ReplicaCount == nil {
+				return fmt.Errorf("storageNodeGroups[%d].replicaCount must be defined", idx)
+			}
+		}
+		if b := vms.RollingUpdateStrategyBehavior; b != nil {
+			if b.WaitForVMStorageReadyMetrics && vms.RollingUpdateStrategy != "" && vms.RollingUpdateStrategy != appsv1.OnDeleteStatefulSetStrategyType {
+				return fmt.Errorf("rollingUpdateStrategyBehavior.waitForVMStorageReadyMetrics is supported only for rollingUpdateStrategy=%s", appsv1.OnDeleteStatefulSetStrategyType)
+			}
+			if b.ReadyChecksCount != nil && *b.ReadyChecksCount < 1 {
+				return fmt.Errorf("rollingUpdateStrategyBehavior.readyChecksCount=%d must be greater than 0", *b.ReadyChecksCount)
+			}
+			if b.ReadyCheckInterval != "" {
+				if _, err := time.ParseDuration(b.ReadyCheckInterval); err != nil {
+					return fmt.Errorf("cannot parse rollingUpdateStrategyBehavior.readyCheckInterval=%q: %w", b.ReadyCheckInterval, err)
+				}
+			}
+			if b.ReadyCheckTimeout != "" {
+				if _, err := time.ParseDuration(b.ReadyCheckTimeout); err != nil {
+					return fmt.Errorf("cannot parse rollingUpdateStrategyBehavior.readyCheckTimeout=%q: %w", b.ReadyCheckTimeout, err)
+				}
+			}
+		}
 		if r.Spec.VMStorage.VMBackup != nil {
 			if err := r.Spec.VMStorage.VMBackup.sanityCheck(r.Spec.License); err != nil {
 				return err
@@ -85,7 +138,94 @@ func (r *VMCluster) sanityCheck() error {
 			return fmt.Errorf(".serviceSpec.Name cannot be equal to prefixed name=%q", r.GetVMAuthLBName())
 		}
 	}
+	if r.Spec.UpdateStrategy != nil {
+		seen := make(map[string]struct{}, len(r.Spec.UpdateStrategy.Order))
+		for _, component := range r.Spec.UpdateStrategy.Order {
+			if !slices.Contains(defaultVMClusterUpdateOrder, component) {
+				return fmt.Errorf("unsupported component=%q at updateStrategy.order, supported values: %s", component, strings.Join(defaultVMClusterUpdateOrder, ","))
+			}
+			if _, ok := seen[component]; ok {
+				return fmt.Errorf("duplicate component=%q at updateStrategy.order", component)
+			}
+			seen[component] = struct{}{}
+		}
+	}
+	for idx, rf := range r.Spec.RetentionFilters {
+		if rf.Filter == "" {
+			return fmt.Errorf("retentionFilters[%d].filter cannot be empty", idx)
+		}
+		if _, err := promutils.ParseDuration(rf.Retention); err != nil {
+			return fmt.Errorf("cannot parse retentionFilters[%d].retention=%q: %w", idx, rf.Retention, err)
+		}
+	}
+	for idx, dp := range r.Spec.Downsampling {
+		if _, err := promutils.ParseDuration(dp.Offset); err != nil {
+			return fmt.Errorf("cannot parse downsampling[%d].offset=%q: %w", idx, dp.Offset, err)
+		}
+		if _, err := promutils.ParseDuration(dp.Interval); err != nil {
+			return fmt.Errorf("cannot parse downsampling[%d].interval=%q: %w", idx, dp.Interval, err)
+		}
+	}
+	if r.Spec.InternalTLS != nil {
+		if err := r.checkInternalTLS(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
 
+// checkInternalTLS validates internalTLS configuration
+// cluster TLS flags are supported only by enterprise images of cluster components
+func (r *VMCluster) checkInternalTLS() error {
+	tls := r.Spec.InternalTLS
+	switch {
+	case tls.IssuerRef == nil && tls.SecretName == "":
+		return fmt.Errorf("internalTLS requires either issuerRef or secretName")
+	case tls.IssuerRef != nil && tls.SecretName != "":
+		return fmt.Errorf("internalTLS.issuerRef and internalTLS.secretName are mutually exclusive")
+	case tls.IssuerRef != nil && tls.IssuerRef.Name == "":
+		return fmt.Errorf("internalTLS.issuerRef.name cannot be empty")
+	}
+	if tls.Duration != "" {
+		if _, err := time.ParseDuration(tls.Duration); err != nil {
+			return fmt.Errorf("cannot parse internalTLS.duration=%q: %w", tls.Duration, err)
+		}
+	}
+	if tls.RenewBefore != "" {
+		if _, err := time.ParseDuration(tls.RenewBefore); err != nil {
+			return fmt.Errorf("cannot parse internalTLS.renewBefore=%q: %w", tls.RenewBefore, err)
+		}
+	}
+	checkImage := func(component string, image Image) error {
+		tag := image.Tag
+		if tag == "" {
+			tag = r.Spec.ClusterVersion
+		}
+		// operator uses community image by default
+		if tag == "" {
+			return fmt.Errorf("internalTLS requires enterprise version of %s, image.tag or clusterVersion must be defined", component)
+		}
+		if !strings.Contains(tag, "enterprise") {
+			return fmt.Errorf("internalTLS requires enterprise version of %s, image tag=%q doesn't support -cluster.tls flags", component, tag)
+		}
+		return nil
+	}
+	if r.Spec.VMStorage != nil {
+		if err := checkImage(VMClusterComponentVMStorage, r.Spec.VMStorage.Image); err != nil {
+			return err
+		}
+	}
+	if r.Spec.VMSelect != nil {
+		if err := checkImage(VMClusterComponentVMSelect, r.Spec.VMSelect.Image); err != nil {
+			return err
+		}
+	}
+	if r.Spec.VMInsert != nil {
+		if err := checkImage(VMClusterComponentVMInsert, r.Spec.VMInsert.Image); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -105,6 +245,9 @@ func (*VMCluster) ValidateCreate(_ context.Context, obj runtime.Object) (admissi
 	if err := r.sanityCheck(); err != nil {
 		return nil, err
 	}
+	if err := r.checkExtraArgs(); err != nil {
+		return nil, err
+	}
 	return nil, nil
 }
 
@@ -123,7 +266,169 @@ func (*VMCluster) ValidateUpdate(_ context.Context, oldObj, newObj runtime.Objec
 	if err := r.sanityCheck(); err != nil {
 		return nil, err
 	}
-	return nil, nil
+	prev, ok := oldObj.(*VMCluster)
+	if !ok {
+		return nil, fmt.Errorf("BUG: unexpected type: %T", oldObj)
+	}
+	if err := r.checkStorageScaleDown(prev); err != nil {
+		return nil, err
+	}
+	var warnings admission.Warnings
+	if err := r.checkExtraArgs(); err != nil {
+		// conflicting extraArgs were accepted by previous operator versions
+		// do not block updates of such objects
+		if prev.checkExtraArgs() == nil {
+			return nil, err
+		}
+		warnings = append(warnings, err.Error())
+	}
+	return warnings, nil
+}
+
+// checkStorageScaleDown rejects decrease of vmstorage nodes, if it may lead to data loss
+func (r *VMCluster) checkStorageScaleDown(prev *VMCluster) error {
+	if r.Spec.VMStorage == nil || prev.Spec.VMStorage == nil {
+		return nil
+	}
+	// data isn't moved between StatefulSets, all nodes of the previous StatefulSet are removed at once
+	// and replicationFactor doesn't protect from data loss
+	if (len(prev.Spec.VMStorage.StorageNodeGroups) == 0) != (len(r.Spec.VMStorage.StorageNodeGroups) == 0) && !r.Spec.VMStorage.AllowDataLossOnScaleDown {
+		return fmt.Errorf("migration between vmstorage storageNodeGroups and single StatefulSet removes all previous vmstorage nodes and their data. " +
+			"Set vmstorage.allowDataLossOnScaleDown to allow it")
+	}
+	if r.IsStorageScaleDownAllowed() {
+		return nil
+	}
+	replicas := r.VMStorageReplicas()
+	for name, prevReplicas := range prev.VMStorageReplicas() {
+		if replicas[name] < prevReplicas {
+			return fmt.Errorf("cannot decrease replicas of vmstorage=%s from=%d to=%d, it may lead to data loss. "+
+				"Set vmstorage.allowDataLossOnScaleDown or replicationFactor>=2 to allow it", name, prevReplicas, replicas[name])
+		}
+	}
+	return nil
+}
+
+// checkExtraArgs checks that extraArgs of cluster components don't conflict
+// with flags defined by operator, unless spec.extraArgsOverride is set
+func (r *VMCluster) checkExtraArgs() error {
+	if r.Spec.ExtraArgsOverride {
+		return nil
+	}
+	if r.Spec.VMStorage != nil {
+		if err := r.checkComponentExtraArgs(VMClusterComponentVMStorage, r.Spec.VMStorage.ExtraArgs, r.vmStorageSpecFlags()); err != nil {
+			return err
+		}
+	}
+	if r.Spec.VMSelect != nil {
+		if err := r.checkComponentExtraArgs(VMClusterComponentVMSelect, r.Spec.VMSelect.ExtraArgs, r.vmSelectSpecFlags()); err != nil {
+			return err
+		}
+	}
+	if r.Spec.VMInsert != nil {
+		if err := r.checkComponentExtraArgs(VMClusterComponentVMInsert, r.Spec.VMInsert.ExtraArgs, r.vmInsertSpecFlags()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkComponentExtraArgs checks that extraArgs don't define flags managed by operator
+// and flags already defined by the structured spec fields.
+// specFlags maps flag name to the spec field, which defines it
+func (r *VMCluster) checkComponentExtraArgs(component string, extraArgs map[string]string, specFlags map[string]string) error {
+	managedFlags := []string{"httpListenAddr"}
+	// -storageNode could be set by extraArgs for vmstorage nodes not managed by the given cluster
+	if component != VMClusterComponentVMStorage && r.Spec.VMStorage != nil {
+		managedFlags = append(managedFlags, "storageNode")
+	}
+	argKeys := make([]string, 0, len(extraArgs))
+	for argKey := range extraArgs {
+		argKeys = append(argKeys, argKey)
+	}
+	sort.Strings(argKeys)
+	for _, argKey := range argKeys {
+		flag := strings.TrimLeft(argKey, "-")
+		if slices.Contains(managedFlags, flag) {
+			return fmt.Errorf("incorrect %s extraArgs: flag -%s is managed by operator and cannot be set at extraArgs, set spec.extraArgsOverride=true in order to override it", component, flag)
+		}
+		if field, ok := specFlags[flag]; ok {
+			return fmt.Errorf("incorrect %s extraArgs: flag -%s is already defined by %s, set spec.extraArgsOverride=true in order to override it", component, flag, field)
+		}
+	}
+	return nil
+}
+
+// addLoggerSpecFlags adds logger flags defined by the component spec
+func addLoggerSpecFlags(specFlags map[string]string, component, logLevel, logFormat string) {
+	if logLevel != "" {
+		specFlags["loggerLevel"] = fmt.Sprintf("spec.%s.logLevel", component)
+	}
+	if logFormat != "" {
+		specFlags["loggerFormat"] = fmt.Sprintf("spec.%s.logFormat", component)
+	}
+}
+
+// vmStorageSpecFlags returns vmstorage flags defined by the structured spec fields
+func (r *VMCluster) vmStorageSpecFlags() map[string]string {
+	specFlags := map[string]string{
+		"retentionPeriod": "spec.retentionPeriod",
+		"storageDataPath": "spec.vmstorage.storageDataPath",
+		"vminsertAddr":    "spec.vmstorage.vmInsertPort",
+		"vmselectAddr":    "spec.vmstorage.vmSelectPort",
+	}
+	if len(r.Spec.RetentionFilters) > 0 {
+		specFlags["retentionFilter"] = "spec.retentionFilters"
+	}
+	if len(r.Spec.Downsampling) > 0 {
+		specFlags["downsampling.period"] = "spec.downsampling"
+	}
+	addLoggerSpecFlags(specFlags, VMClusterComponentVMStorage, r.Spec.VMStorage.LogLevel, r.Spec.VMStorage.LogFormat)
+	return specFlags
+}
+
+// vmSelectSpecFlags returns vmselect flags defined by the structured spec fields
+// -replicationFactor and -dedup.minScrapeInterval are allowed to be overridden by extraArgs
+func (r *VMCluster) vmSelectSpecFlags() map[string]string {
+	specFlags := make(map[string]string)
+	if r.Spec.VMSelect.ClusterNativePort != "" {
+		specFlags["clusternativeListenAddr"] = "spec.vmselect.clusterNativeListenPort"
+	}
+	if r.Spec.VMSelect.CacheMountPath != "" {
+		specFlags["cacheDataPath"] = "spec.vmselect.cacheMountPath"
+	}
+	if len(r.Spec.Downsampling) > 0 {
+		specFlags["downsampling.period"] = "spec.downsampling"
+	}
+	addLoggerSpecFlags(specFlags, VMClusterComponentVMSelect, r.Spec.VMSelect.LogLevel, r.Spec.VMSelect.LogFormat)
+	return specFlags
+}
+
+// vmInsertSpecFlags returns vminsert flags defined by the structured spec fields
+func (r *VMCluster) vmInsertSpecFlags() map[string]string {
+	specFlags := make(map[string]string)
+	if r.Spec.VMInsert.ClusterNativePort != "" {
+		specFlags["clusternativeListenAddr"] = "spec.vminsert.clusterNativeListenPort"
+	}
+	if r.Spec.ReplicationFactor != nil {
+		specFlags["replicationFactor"] = "spec.replicationFactor"
+	}
+	if ip := r.Spec.VMInsert.InsertPorts; ip != nil {
+		if ip.GraphitePort != "" {
+			specFlags["graphiteListenAddr"] = "spec.vminsert.insertPorts.graphitePort"
+		}
+		if ip.InfluxPort != "" {
+			specFlags["influxListenAddr"] = "spec.vminsert.insertPorts.influxPort"
+		}
+		if ip.OpenTSDBPort != "" {
+			specFlags["opentsdbListenAddr"] = "spec.vminsert.insertPorts.openTSDBPort"
+		}
+		if ip.OpenTSDBHTTPPort != "" {
+			specFlags["opentsdbHTTPListenAddr"] = "spec.vminsert.insertPorts.openTSDBHTTPPort"
+		}
+	}
+	addLoggerSpecFlags(specFlags, VMClusterComponentVMInsert, r.Spec.VMInsert.LogLevel, r.Spec.VMInsert.LogFormat)
+	return specFlags
 }
 
 // ValidateDelete implements webhook.Validator so a webhook will be registered for the type
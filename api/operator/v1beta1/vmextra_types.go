@@ -652,6 +652,27 @@ func (config *StreamAggrConfig) HasAnyRule() bool {
 	return false
 }
 
+// Validate checks inline stream aggregation rules
+func (config *StreamAggrConfig) Validate() error {
+	if config == nil {
+		return nil
+	}
+	return ValidateStreamAggrRules(config.Rules)
+}
+
+// ValidateStreamAggrRules checks that stream aggregation rules have required fields
+func ValidateStreamAggrRules(rules []StreamAggrRule) error {
+	for idx, rule := range rules {
+		if rule.Interval == "" {
+			return fmt.Errorf("missing interval for stream aggregation rule at idx=%d", idx)
+		}
+		if len(rule.Outputs) == 0 {
+			return fmt.Errorf("missing outputs for stream aggregation rule at idx=%d", idx)
+		}
+	}
+	return nil
+}
+
 // KeyValue defines a (key, value) tuple.
 // +kubebuilder:object:generate=false
 // +k8s:openapi-gen=false
@@ -1073,6 +1094,24 @@ type TLSClientConfig struct {
 // ScrapeObjectStatus defines the observed state of ScrapeObjects
 type ScrapeObjectStatus struct {
 	StatusMetadata `json:",inline"`
+	// SelectedBy contains VMAgents, which selected the scrape object.
+	// It's populated only if operator is started with -controller.vmagent.scrapeObjectSelectedByStatus flag
+	// +optional
+	// +listType=map
+	// +listMapKey=vmagent
+	SelectedBy []ScrapeObjectSelectedBy `json:"selectedBy,omitempty"`
+}
+
+// ScrapeObjectSelectedBy defines VMAgent, which selected the scrape object
+type ScrapeObjectSelectedBy struct {
+	// VMAgent defines namespace/name of VMAgent
+	VMAgent string `json:"vmagent"`
+	// Jobs contains names of scrape jobs generated for the scrape object
+	// +optional
+	Jobs []string `json:"jobs,omitempty"`
+	// ObservedGeneration defines generation of the scrape object used for config generation
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
 }
 
 type objectWithLastAppliedState[T, ST any] interface {
@@ -1358,11 +1397,21 @@ type patchStatusOpts[T client.Object, ST any] struct {
 	crStatus                   objectStatusWithDeepCopy[ST]
 	maybeErr                   error
 	mutateCurrentBeforeCompare func(ST)
+	// statusOf returns status of the given object
+	// if set, current status is compared with the persisted object status,
+	// since status fields could be modified by reconcile before update
+	statusOf func(T) ST
 }
 
 func updateObjectStatus[T client.Object, ST any](ctx context.Context, rclient client.Client, opts *patchStatusOpts[T, ST]) error {
 	currentStatus := opts.crStatus
 	prevStatus := opts.crStatus.DeepCopy()
+	if opts.statusOf != nil {
+		persisted := opts.cr.DeepCopy()
+		if err := rclient.Get(ctx, types.NamespacedName{Namespace: opts.cr.GetNamespace(), Name: opts.cr.GetName()}, persisted); err == nil {
+			prevStatus = opts.statusOf(persisted)
+		}
+	}
 	currMeta := currentStatus.GetStatusMetadata()
 	newUpdateStatus := opts.actualStatus
 	switch opts.actualStatus {
@@ -1452,6 +1501,10 @@ type StatusMetadata struct {
 	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
 	// CurrentSyncError holds an error occured during reconcile loop
 	CurrentSyncError string `json:"-"`
+	// CurrentSyncWarning holds a non-fatal issue occurred during reconcile loop
+	CurrentSyncWarning string `json:"-"`
+	// CurrentSyncInfo holds additional details about applied object, e.g. generated scrape job names
+	CurrentSyncInfo string `json:"-"`
 	// Known .status.conditions.type are: "Available", "Progressing", and "Degraded"
 	// +patchMergeKey=type
 	// +patchStrategy=merge
@@ -1,6 +1,7 @@
 package v1beta1
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"reflect"
@@ -12,6 +13,10 @@ import (
 	"k8s.io/utils/ptr"
 
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
 func Test_buildPathWithPrefixFlag(t *testing.T) {
@@ -224,3 +229,34 @@ func TestStringOrArrayUnMarshal(t *testing.T) {
 `, yaml.Unmarshal, StringOrArray{""})
 
 }
+
+func TestSetUpdateStatusToPersistsSubStatus(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := AddToScheme(scheme); err != nil {
+		t.Fatalf("cannot build scheme: %s", err)
+	}
+	persisted := &VMAlertmanager{
+		ObjectMeta: metav1.ObjectMeta{Name: "main", Namespace: "default"},
+		Status: VMAlertmanagerStatus{
+			StatusMetadata: StatusMetadata{UpdateStatus: UpdateStatusOperational},
+		},
+	}
+	rclient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(persisted).WithStatusSubresource(persisted).Build()
+	ctx := context.Background()
+
+	// reconcile may modify sub-status of the object before update status call
+	cr := persisted.DeepCopy()
+	cr.Status.Conditions = append(cr.Status.Conditions, Condition{
+		Type:   VMAlertmanagerTemplatesDegradedCondition,
+		Status: metav1.ConditionTrue,
+	})
+	if err := cr.SetUpdateStatusTo(ctx, rclient, UpdateStatusOperational, nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var got VMAlertmanager
+	if err := rclient.Get(ctx, types.NamespacedName{Namespace: "default", Name: "main"}, &got); err != nil {
+		t.Fatalf("cannot get object: %s", err)
+	}
+	assert.Len(t, got.Status.Conditions, 1)
+	assert.Equal(t, VMAlertmanagerTemplatesDegradedCondition, got.Status.Conditions[0].Type)
+}
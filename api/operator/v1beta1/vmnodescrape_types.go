@@ -16,7 +16,12 @@ type VMNodeScrapeSpec struct {
 	TargetLabels []string `json:"targetLabels,omitempty"`
 	// Name of the port exposed at Node.
 	// +optional
-	Port                 string `json:"port,omitempty"`
+	Port string `json:"port,omitempty"`
+	// UseNodeAddressType defines priority list of Node address types used as target address.
+	// The first address type present at Node is used, port of the target is preserved.
+	// By default, vmagent uses InternalIP address.
+	// +optional
+	UseNodeAddressType   []NodeAddressType `json:"useNodeAddressType,omitempty"`
 	EndpointRelabelings  `json:",inline"`
 	EndpointAuth         `json:",inline"`
 	EndpointScrapeParams `json:",inline"`
@@ -29,6 +34,17 @@ type VMNodeScrapeSpec struct {
 	Selector metav1.LabelSelector `json:"selector,omitempty"`
 }
 
+// NodeAddressType defines type of kubernetes Node address
+// +kubebuilder:validation:Enum=InternalIP;Hostname;ExternalIP
+type NodeAddressType string
+
+// Supported node address types
+const (
+	NodeAddressInternalIP NodeAddressType = "InternalIP"
+	NodeAddressHostname   NodeAddressType = "Hostname"
+	NodeAddressExternalIP NodeAddressType = "ExternalIP"
+)
+
 // VMNodeScrape defines discovery for targets placed on kubernetes nodes,
 // usually its node-exporters and other host services.
 // InternalIP is used as __address__ for scraping.
@@ -71,6 +87,11 @@ func (cr *VMNodeScrape) GetStatusMetadata() *StatusMetadata {
 	return &cr.Status.StatusMetadata
 }
 
+// GetScrapeObjectStatus returns status of the scrape object
+func (cr *VMNodeScrape) GetScrapeObjectStatus() *ScrapeObjectStatus {
+	return &cr.Status
+}
+
 func init() {
 	SchemeBuilder.Register(&VMNodeScrape{}, &VMNodeScrapeList{})
 }
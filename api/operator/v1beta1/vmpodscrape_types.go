@@ -3,6 +3,7 @@ package v1beta1
 import (
 	"fmt"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 )
@@ -36,6 +37,15 @@ type VMPodScrapeSpec struct {
 	// AttachMetadata configures metadata attaching from service discovery
 	// +optional
 	AttachMetadata AttachMetadata `json:"attach_metadata,omitempty"`
+	// RelabelConfigRefs defines ConfigMap keys with shared list of relabel configs in yaml format.
+	// Relabel configs from the referenced keys are added to each endpoint before its relabelConfigs.
+	// ConfigMap must be located at the same namespace as the scrape object.
+	// +optional
+	RelabelConfigRefs []corev1.ConfigMapKeySelector `json:"relabelConfigRefs,omitempty"`
+	// ScrapeClassName references scrape class defined at VMAgent spec.scrapeClasses.
+	// If omitted, the default scrape class is used, if any
+	// +optional
+	ScrapeClassName string `json:"scrapeClassName,omitempty"`
 }
 
 // VMPodScrape is scrape configuration for pods,
@@ -123,6 +133,11 @@ func (cr *VMPodScrape) GetStatusMetadata() *StatusMetadata {
 	return &cr.Status.StatusMetadata
 }
 
+// GetScrapeObjectStatus returns status of the scrape object
+func (cr *VMPodScrape) GetScrapeObjectStatus() *ScrapeObjectStatus {
+	return &cr.Status
+}
+
 func init() {
 	SchemeBuilder.Register(&VMPodScrape{}, &VMPodScrapeList{})
 }
@@ -28,7 +28,7 @@ type VMProbeSpec struct {
 	// The job name assigned to scraped metrics by default.
 	JobName string `json:"jobName,omitempty"`
 	// Specification for the prober to use for probing targets.
-	// The prober.URL parameter is required. Targets cannot be probed if left empty.
+	// The prober.URL parameter is required, if prober isn't managed by operator.
 	VMProberSpec VMProberSpec `json:"vmProberSpec"`
 	// The module to use for probing specifying how to probe the target.
 	// Example module configuring in the blackbox exporter:
@@ -39,6 +39,10 @@ type VMProbeSpec struct {
 	// MetricRelabelConfigs to apply to samples after scrapping.
 	// +optional
 	MetricRelabelConfigs []*RelabelConfig `json:"metricRelabelConfigs,omitempty"`
+	// ScrapeClassName references scrape class defined at VMAgent spec.scrapeClasses.
+	// If omitted, the default scrape class is used, if any
+	// +optional
+	ScrapeClassName string `json:"scrapeClassName,omitempty"`
 
 	EndpointAuth         `json:",inline"`
 	EndpointScrapeParams `json:",inline"`
@@ -72,14 +76,23 @@ type ProbeTargetIngress struct {
 	// Select Ingress objects by namespace.
 	NamespaceSelector NamespaceSelector `json:"namespaceSelector,omitempty"`
 	// RelabelConfigs to apply to samples during service discovery.
+	// They are applied after generated target, namespace, ingress and instance labels.
 	RelabelConfigs []*RelabelConfig `json:"relabelingConfigs,omitempty"`
+	// IncludePaths defines whether paths of Ingress rules are added to probe targets.
+	// Each host and path combination of Ingress rules is probed as a separate target.
+	// Target scheme is https for hosts defined at Ingress tls section.
+	// Defaults to true
+	// +optional
+	IncludePaths *bool `json:"includePaths,omitempty"`
 }
 
 // VMProberSpec contains specification parameters for the Prober used for probing.
 // +k8s:openapi-gen=true
 type VMProberSpec struct {
-	// Mandatory URL of the prober.
-	URL string `json:"url"`
+	// URL of the prober.
+	// It's required if prober isn't managed by operator
+	// +optional
+	URL string `json:"url,omitempty"`
 	// HTTP scheme to use for scraping.
 	// Defaults to `http`.
 	// +optional
@@ -88,6 +101,88 @@ type VMProberSpec struct {
 	// Path to collect metrics from.
 	// Defaults to `/probe`.
 	Path string `json:"path,omitempty"`
+	// Managed defines that blackbox exporter is deployed by operator for the VMAgent, which selects VMProbe.
+	// VMAgent must have spec.managedProber defined. url, scheme and path are ignored for managed prober
+	// +optional
+	Managed bool `json:"managed,omitempty"`
+	// ModuleConfig defines module of managed prober.
+	// Module is generated with name namespace_name of VMProbe, spec.module is ignored.
+	// Defaults to http prober
+	// +optional
+	ModuleConfig *VMProberModule `json:"moduleConfig,omitempty"`
+}
+
+// VMProberModule defines blackbox exporter module
+type VMProberModule struct {
+	// Prober defines type of the probe
+	// +kubebuilder:validation:Enum=http;tcp;dns;icmp
+	Prober string `json:"prober"`
+	// Timeout of the probe
+	// +kubebuilder:validation:Pattern:="^([0-9]+(ms|s|m|h))+$"
+	// +optional
+	Timeout string `json:"timeout,omitempty"`
+	// PreferredIPProtocol defines IP protocol used for the probe
+	// +kubebuilder:validation:Enum=ip4;ip6
+	// +optional
+	PreferredIPProtocol string `json:"preferredIPProtocol,omitempty"`
+	// HTTP defines params of http prober
+	// +optional
+	HTTP *VMProberHTTPModule `json:"http,omitempty"`
+	// TCP defines params of tcp prober
+	// +optional
+	TCP *VMProberTCPModule `json:"tcp,omitempty"`
+	// DNS defines params of dns prober, it's required for dns prober
+	// +optional
+	DNS *VMProberDNSModule `json:"dns,omitempty"`
+	// ICMP defines params of icmp prober
+	// +optional
+	ICMP *VMProberICMPModule `json:"icmp,omitempty"`
+}
+
+// VMProberHTTPModule defines params of http prober
+type VMProberHTTPModule struct {
+	// Method of http request, defaults to GET
+	// +optional
+	Method string `json:"method,omitempty"`
+	// ValidStatusCodes defines accepted status codes, defaults to 2xx
+	// +optional
+	ValidStatusCodes []int `json:"validStatusCodes,omitempty"`
+	// InsecureSkipVerify disables target certificate validation
+	// +optional
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+}
+
+// VMProberTCPModule defines params of tcp prober
+type VMProberTCPModule struct {
+	// TLS enables TLS for connection
+	// +optional
+	TLS bool `json:"tls,omitempty"`
+	// InsecureSkipVerify disables target certificate validation
+	// +optional
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+}
+
+// VMProberDNSModule defines params of dns prober
+type VMProberDNSModule struct {
+	// QueryName defines name to resolve with the target DNS server
+	QueryName string `json:"queryName"`
+	// QueryType defines type of DNS query, defaults to ANY
+	// +optional
+	QueryType string `json:"queryType,omitempty"`
+	// TransportProtocol defines protocol of DNS query, defaults to udp
+	// +kubebuilder:validation:Enum=udp;tcp
+	// +optional
+	TransportProtocol string `json:"transportProtocol,omitempty"`
+}
+
+// VMProberICMPModule defines params of icmp prober
+type VMProberICMPModule struct {
+	// PayloadSize defines size of ICMP packet payload in bytes
+	// +optional
+	PayloadSize int `json:"payloadSize,omitempty"`
+	// DontFragment sets DF bit at IP header
+	// +optional
+	DontFragment bool `json:"dontFragment,omitempty"`
 }
 
 // VMProbe defines a probe for targets, that will be executed with prober,
@@ -132,6 +227,11 @@ func (cr *VMProbe) GetStatusMetadata() *StatusMetadata {
 	return &cr.Status.StatusMetadata
 }
 
+// GetScrapeObjectStatus returns status of the scrape object
+func (cr *VMProbe) GetScrapeObjectStatus() *ScrapeObjectStatus {
+	return &cr.Status
+}
+
 func init() {
 	SchemeBuilder.Register(&VMProbe{}, &VMProbeList{})
 }
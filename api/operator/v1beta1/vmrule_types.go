@@ -1,7 +1,9 @@
 package v1beta1
 
 import (
+	"fmt"
 	"net/url"
+	"strconv"
 
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -11,6 +13,14 @@ import (
 // Limit it to the half size of constant value, since it may be different for kubernetes versions.
 var MaxConfigMapDataSize = int(float64(v1.MaxSecretSize) * 0.5)
 
+const (
+	// VMRuleOrderAnnotation defines order of VMRule rule file at vmalert rules directory.
+	// Rule files with lower order are loaded first
+	VMRuleOrderAnnotation = "operator.victoriametrics.com/rule-order"
+	// MaxVMRuleOrder is a maximum value of VMRuleOrderAnnotation
+	MaxVMRuleOrder = 9999
+)
+
 // VMRuleSpec defines the desired state of VMRule
 type VMRuleSpec struct {
 	// Groups list of group rules
@@ -22,6 +32,12 @@ type VMRuleSpec struct {
 type RuleGroup struct {
 	// Name of group
 	Name string `json:"name"`
+	// Order defines position of the group at generated rule file.
+	// If order is set for any group of VMRule, groups are sorted by order and name.
+	// It's not passed to vmalert
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Order int `json:"order,omitempty" yaml:"order,omitempty"`
 	// evaluation interval for group
 	// +optional
 	Interval string `json:"interval,omitempty" yaml:"interval,omitempty"`
@@ -67,6 +83,7 @@ type RuleGroup struct {
 	Params url.Values `json:"params,omitempty" yaml:"params,omitempty"`
 	// Type defines datasource type for enterprise version of vmalert
 	// possible values - prometheus,graphite,vlogs
+	// +kubebuilder:validation:Enum=prometheus;graphite;vlogs
 	// +optional
 	Type string `json:"type,omitempty" yaml:"type,omitempty"`
 	// Headers contains optional HTTP headers added to each rule request
@@ -134,6 +151,23 @@ func (cr *VMRule) GetStatusMetadata() *StatusMetadata {
 	return &cr.Status.StatusMetadata
 }
 
+// RuleOrder returns order of VMRule rule file defined by VMRuleOrderAnnotation.
+// It returns nil if order is not set
+func (cr *VMRule) RuleOrder() (*int, error) {
+	value, ok := cr.Annotations[VMRuleOrderAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	order, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse %s=%q annotation: %w", VMRuleOrderAnnotation, value, err)
+	}
+	if order < 0 || order > MaxVMRuleOrder {
+		return nil, fmt.Errorf("%s=%d annotation must be in range [0...%d]", VMRuleOrderAnnotation, order, MaxVMRuleOrder)
+	}
+	return &order, nil
+}
+
 // VMRule defines rule records for vmalert application
 // +operator-sdk:gen-csv:customresourcedefinitions.displayName="VMRule"
 // +kubebuilder:object:root=true
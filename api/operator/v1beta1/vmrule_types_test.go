@@ -0,0 +1,78 @@
+package v1beta1
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestVMRule_ValidateCreate(t *testing.T) {
+	f := func(vmr *VMRule, wantErr string) {
+		t.Helper()
+		_, err := vmr.ValidateCreate(context.Background(), vmr)
+		if wantErr == "" {
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			return
+		}
+		if err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Fatalf("expected error containing %q, got: %v", wantErr, err)
+		}
+	}
+	rule := func(expr string) Rule {
+		return Rule{Alert: "up", Expr: expr}
+	}
+
+	// valid rule
+	f(&VMRule{Spec: VMRuleSpec{Groups: []RuleGroup{{Name: "group", Rules: []Rule{rule("up == 0")}}}}}, "")
+
+	// invalid expression
+	f(&VMRule{Spec: VMRuleSpec{Groups: []RuleGroup{{Name: "group", Rules: []Rule{rule("up ==")}}}}}, `bad prometheus expr: "up =="`)
+
+	// validation is skipped with annotation
+	f(&VMRule{
+		ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{SkipValidationAnnotation: SkipValidationValue}},
+		Spec:       VMRuleSpec{Groups: []RuleGroup{{Name: "group", Rules: []Rule{rule("up ==")}}}},
+	}, "")
+
+	// large object is rejected at the first group exceeding size limit,
+	// the following invalid groups are not parsed
+	largeRule := rule("up == 0")
+	largeRule.Annotations = map[string]string{"description": strings.Repeat("a", MaxConfigMapDataSize)}
+	large := &VMRule{Spec: VMRuleSpec{Groups: []RuleGroup{{Name: "group-0", Rules: []Rule{largeRule}}}}}
+	for i := 1; i < 500; i++ {
+		large.Spec.Groups = append(large.Spec.Groups, RuleGroup{Name: fmt.Sprintf("group-%d", i), Rules: []Rule{rule("up ==")}})
+	}
+	f(large, "exceed single rule limit")
+
+	// group order isn't passed to vmalert validation
+	f(&VMRule{Spec: VMRuleSpec{Groups: []RuleGroup{{Name: "group", Order: 1, Rules: []Rule{rule("up == 0")}}}}}, "")
+
+	// group datasource type with group params
+	f(&VMRule{Spec: VMRuleSpec{Groups: []RuleGroup{{
+		Name:            "graphite",
+		Interval:        "1m",
+		Type:            "graphite",
+		EvalOffset:      "10s",
+		EvalDelay:       "30s",
+		Headers:         []string{"X-Org: 1"},
+		NotifierHeaders: []string{"X-Team: a"},
+		Rules:           []Rule{{Record: "carbon:up", Expr: "sumSeries(carbon.agents.*.up)"}},
+	}}}}, "")
+	f(&VMRule{Spec: VMRuleSpec{Groups: []RuleGroup{{Name: "group", Type: "influx", Rules: []Rule{rule("up == 0")}}}}}, `unsupported group type="influx"`)
+
+	// rule order annotation
+	withOrder := func(order string) *VMRule {
+		return &VMRule{
+			ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{VMRuleOrderAnnotation: order}},
+			Spec:       VMRuleSpec{Groups: []RuleGroup{{Name: "group", Rules: []Rule{rule("up == 0")}}}},
+		}
+	}
+	f(withOrder("10"), "")
+	f(withOrder("first"), "cannot parse")
+	f(withOrder("10000"), "must be in range")
+}
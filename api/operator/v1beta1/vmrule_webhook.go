@@ -62,6 +62,9 @@ func (r *VMRule) Validate() error {
 			panic(fmt.Sprintf("cannot init vmalert templates for validation: %s", err))
 		}
 	})
+	if _, err := r.RuleOrder(); err != nil {
+		return err
+	}
 	uniqNames := make(map[string]struct{})
 	var totalSize int
 	for i := range r.Spec.Groups {
@@ -75,6 +78,13 @@ func (r *VMRule) Validate() error {
 			}
 			group.Tenant = ""
 		}
+		switch group.Type {
+		case "", "prometheus", "graphite", "vlogs":
+		default:
+			return fmt.Errorf("at idx=%d unsupported group type=%q, want one of: prometheus, graphite, vlogs", i, group.Type)
+		}
+		// order is used by operator only and must not be passed to vmalert
+		group.Order = 0
 		errContext := fmt.Sprintf("VMRule: %s/%s group: %s", r.Namespace, r.Name, group.Name)
 		if _, ok := uniqNames[group.Name]; ok {
 			return fmt.Errorf("duplicate group name: %s", errContext)
@@ -84,8 +94,13 @@ func (r *VMRule) Validate() error {
 		if err != nil {
 			return fmt.Errorf("cannot marshal %s, err: %w", errContext, err)
 		}
-		var vmalertGroup config.Group
+		// check size before group validation in order to reject large objects
+		// without parsing all of its groups
 		totalSize += len(groupBytes)
+		if totalSize > MaxConfigMapDataSize {
+			return fmt.Errorf("VMRule's content size: %d exceed single rule limit: %d", totalSize, MaxConfigMapDataSize)
+		}
+		var vmalertGroup config.Group
 		if err := yaml.Unmarshal(groupBytes, &vmalertGroup); err != nil {
 			return fmt.Errorf("cannot parse vmalert group %s, err: %w, r: \n%s", errContext, err, string(groupBytes))
 		}
@@ -93,9 +108,6 @@ func (r *VMRule) Validate() error {
 			return fmt.Errorf("validation failed for %s err: %w", errContext, err)
 		}
 	}
-	if totalSize > MaxConfigMapDataSize {
-		return fmt.Errorf("VMRule's content size: %d exceed single rule limit: %d", totalSize, MaxConfigMapDataSize)
-	}
 	return nil
 }
 
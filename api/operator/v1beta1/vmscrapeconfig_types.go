@@ -519,6 +519,44 @@ func (cr *VMScrapeConfig) GetStatusMetadata() *StatusMetadata {
 	return &cr.Status.StatusMetadata
 }
 
+// GetScrapeObjectStatus returns status of the scrape object
+func (cr *VMScrapeConfig) GetScrapeObjectStatus() *ScrapeObjectStatus {
+	return &cr.Status
+}
+
+// ValidateSDConfigs checks service discovery configs, which cannot be validated by vmagent config parser
+func (spec *VMScrapeConfigSpec) ValidateSDConfigs() error {
+	for i, fc := range spec.FileSDConfigs {
+		if len(fc.Files) == 0 {
+			return fmt.Errorf("fileSDConfigs[%d]: files cannot be empty", i)
+		}
+		for _, f := range fc.Files {
+			if f == "" {
+				return fmt.Errorf("fileSDConfigs[%d]: file path cannot be empty", i)
+			}
+		}
+	}
+	for i, cc := range spec.ConsulSDConfigs {
+		if cc.TokenRef != nil && cc.Authorization != nil {
+			return fmt.Errorf("consulSDConfigs[%d]: tokenRef and authorization cannot be set together", i)
+		}
+	}
+	for i, dc := range spec.DNSSDConfigs {
+		if len(dc.Names) == 0 {
+			return fmt.Errorf("dnsSDConfigs[%d]: names cannot be empty", i)
+		}
+		if dc.Type != nil && (*dc.Type == "A" || *dc.Type == "AAAA") && dc.Port == nil {
+			return fmt.Errorf("dnsSDConfigs[%d]: port must be set for type=%s", i, *dc.Type)
+		}
+	}
+	for i, ec := range spec.EC2SDConfigs {
+		if (ec.AccessKey == nil) != (ec.SecretKey == nil) {
+			return fmt.Errorf("ec2SDConfigs[%d]: accessKey and secretKey must be set together", i)
+		}
+	}
+	return nil
+}
+
 func init() {
 	SchemeBuilder.Register(&VMScrapeConfig{}, &VMScrapeConfigList{})
 }
@@ -3,6 +3,7 @@ package v1beta1
 import (
 	"fmt"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 )
@@ -10,7 +11,7 @@ import (
 // VMServiceScrapeSpec defines the desired state of VMServiceScrape
 type VMServiceScrapeSpec struct {
 	// DiscoveryRole - defines kubernetes_sd role for objects discovery.
-	// by default, its endpoints.
+	// by default, its endpoints or endpointslices if VMAgent useEndpointSlices is set.
 	// can be changed to service or endpointslices.
 	// note, that with service setting, you have to use port: "name"
 	// and cannot use targetPort for endpoints.
@@ -47,6 +48,15 @@ type VMServiceScrapeSpec struct {
 	// AttachMetadata configures metadata attaching from service discovery
 	// +optional
 	AttachMetadata AttachMetadata `json:"attach_metadata,omitempty"`
+	// RelabelConfigRefs defines ConfigMap keys with shared list of relabel configs in yaml format.
+	// Relabel configs from the referenced keys are added to each endpoint before its relabelConfigs.
+	// ConfigMap must be located at the same namespace as the scrape object.
+	// +optional
+	RelabelConfigRefs []corev1.ConfigMapKeySelector `json:"relabelConfigRefs,omitempty"`
+	// ScrapeClassName references scrape class defined at VMAgent spec.scrapeClasses.
+	// If omitted, the default scrape class is used, if any
+	// +optional
+	ScrapeClassName string `json:"scrapeClassName,omitempty"`
 }
 
 // VMServiceScrape is scrape configuration for endpoints associated with
@@ -145,6 +155,11 @@ func (cr *VMServiceScrape) GetStatusMetadata() *StatusMetadata {
 	return &cr.Status.StatusMetadata
 }
 
+// GetScrapeObjectStatus returns status of the scrape object
+func (cr *VMServiceScrape) GetScrapeObjectStatus() *ScrapeObjectStatus {
+	return &cr.Status
+}
+
 func init() {
 	SchemeBuilder.Register(&VMServiceScrape{}, &VMServiceScrapeList{})
 }
@@ -22,10 +22,17 @@ type VMStaticScrapeSpec struct {
 }
 
 // TargetEndpoint defines single static target endpoint.
+// +kubebuilder:validation:XValidation:rule="(has(self.targets) && size(self.targets) > 0) || (has(self.targetsWithLabels) && size(self.targetsWithLabels) > 0)",message="either targets or targetsWithLabels must be defined"
 type TargetEndpoint struct {
 	// Targets static targets addresses in form of ["192.122.55.55:9100","some-name:9100"].
-	// +kubebuilder:validation:MinItems=1
-	Targets []string `json:"targets"`
+	// Either targets or targetsWithLabels must be defined.
+	// +optional
+	Targets []string `json:"targets,omitempty"`
+	// TargetsWithLabels defines static targets with own labels and params.
+	// Labels of the endpoint are applied to each target, target labels have priority over them.
+	// Target params have priority over params of the endpoint.
+	// +optional
+	TargetsWithLabels []TargetWithLabels `json:"targetsWithLabels,omitempty"`
 	// Labels static labels for targets.
 	// +optional
 	Labels               map[string]string `json:"labels,omitempty"`
@@ -34,6 +41,32 @@ type TargetEndpoint struct {
 	EndpointScrapeParams `json:",inline"`
 }
 
+// TargetWithLabels defines single static target with its own labels and params.
+type TargetWithLabels struct {
+	// Target address in form of "192.122.55.55:9100".
+	// +kubebuilder:validation:MinLength=1
+	Target string `json:"target"`
+	// Labels static labels for the target.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+	// Params defines HTTP URL parameters for the target.
+	// +optional
+	Params map[string]string `json:"params,omitempty"`
+}
+
+// Validate checks if the endpoint has at least one non-empty target
+func (te *TargetEndpoint) Validate() error {
+	if len(te.Targets) == 0 && len(te.TargetsWithLabels) == 0 {
+		return fmt.Errorf("either targets or targetsWithLabels must be defined")
+	}
+	for _, t := range te.TargetsWithLabels {
+		if t.Target == "" {
+			return fmt.Errorf("targetsWithLabels.target cannot be empty")
+		}
+	}
+	return nil
+}
+
 // VMStaticScrape  defines static targets configuration for scraping.
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
@@ -72,6 +105,11 @@ func (cr *VMStaticScrape) GetStatusMetadata() *StatusMetadata {
 	return &cr.Status.StatusMetadata
 }
 
+// GetScrapeObjectStatus returns status of the scrape object
+func (cr *VMStaticScrape) GetScrapeObjectStatus() *ScrapeObjectStatus {
+	return &cr.Status
+}
+
 func init() {
 	SchemeBuilder.Register(&VMStaticScrape{}, &VMStaticScrapeList{})
 }
@@ -2,7 +2,12 @@ package v1beta1
 
 import (
 	"fmt"
+	"maps"
+	"net"
+	"slices"
 	"strings"
+	"text/template"
+	"time"
 
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -32,6 +37,10 @@ type VMUserSpec struct {
 	// if spec.password if empty.
 	// +optional
 	GeneratePassword bool `json:"generatePassword,omitempty"`
+	// PasswordRotation configures periodic rotation of the password generated by operator.
+	// It requires generatePassword to be set.
+	// +optional
+	PasswordRotation *VMUserPasswordRotation `json:"passwordRotation,omitempty"`
 	// BearerToken Authorization header value for accessing protected endpoint.
 	// +optional
 	BearerToken *string `json:"bearerToken,omitempty"`
@@ -50,13 +59,18 @@ type VMUserSpec struct {
 
 // TargetRef describes target for user traffic forwarding.
 // one of target types can be chosen:
-// crd or static per targetRef.
+// crd, crdSelector or static per targetRef.
 // user can define multiple targetRefs with different ref Types.
 type TargetRef struct {
 	// CRD describes exist operator's CRD object,
 	// operator generates access url based on CRD params.
 	// +optional
 	CRD *CRDRef `json:"crd,omitempty"`
+	// CRDSelector selects operator's CRD objects by labels,
+	// operator generates access url for each matched object.
+	// Matched objects are load-balanced at the single route.
+	// +optional
+	CRDSelector *CRDSelectorRef `json:"crdSelector,omitempty"`
 	// Static - user defined url for traffic forward,
 	// for instance http://vmsingle:8429
 	// +optional
@@ -71,6 +85,8 @@ type TargetRef struct {
 	// TargetPathSuffix allows to add some suffix to the target path
 	// It allows to hide tenant configuration from user with crd as ref.
 	// it also may contain any url encoded params.
+	// For crdSelector it's a template executed for each matched object,
+	// with .Name, .Namespace and .Labels of the object available.
 	// +optional
 	TargetPathSuffix string `json:"target_path_suffix,omitempty"`
 	// TargetRefBasicAuth allow an target endpoint to authenticate over basic authentication
@@ -85,6 +101,26 @@ type VMUserIPFilters struct {
 	AllowList []string `json:"allow_list,omitempty"`
 }
 
+// Validate checks that filters contain only IP addresses or CIDRs
+func (ipf *VMUserIPFilters) Validate() error {
+	lists := map[string][]string{"deny_list": ipf.DenyList, "allow_list": ipf.AllowList}
+	// keys are sorted in order to report errors in stable order
+	for _, name := range slices.Sorted(maps.Keys(lists)) {
+		for _, v := range lists[name] {
+			if strings.Contains(v, "/") {
+				if _, _, err := net.ParseCIDR(v); err != nil {
+					return fmt.Errorf("incorrect CIDR=%q at ip_filters.%s: %w", v, name, err)
+				}
+				continue
+			}
+			if net.ParseIP(v) == nil {
+				return fmt.Errorf("incorrect IP address=%q at ip_filters.%s", v, name)
+			}
+		}
+	}
+	return nil
+}
+
 // CRDRef describe CRD target reference.
 type CRDRef struct {
 	// Kind one of:
@@ -108,6 +144,37 @@ func (cr *CRDRef) AsKey() string {
 	return fmt.Sprintf("%s/%s/%s", cr.Kind, cr.Namespace, cr.Name)
 }
 
+// CRDSelectorRef selects CRD targets by labels.
+type CRDSelectorRef struct {
+	// Kind one of:
+	// VMAgent,VMAlert, VMSingle, VMCluster/vmselect, VMCluster/vmstorage,VMCluster/vminsert  or VMAlertManager
+	// +kubebuilder:validation:Enum=VMAgent;VMAlert;VMSingle;VLogs;VMAlertManager;VMAlertmanager;VMCluster/vmselect;VMCluster/vmstorage;VMCluster/vminsert
+	Kind string `json:"kind"`
+	// Selector defines labels of target CRD objects,
+	// all objects of the given kind are selected if it's not set.
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+	// NamespaceSelector defines namespaces for target CRD objects selection,
+	// VMUser namespace is used if it's not set.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+}
+
+func (cr *CRDSelectorRef) validate() error {
+	if _, err := metav1.LabelSelectorAsSelector(cr.Selector); err != nil {
+		return fmt.Errorf("incorrect selector: %w", err)
+	}
+	if _, err := metav1.LabelSelectorAsSelector(cr.NamespaceSelector); err != nil {
+		return fmt.Errorf("incorrect namespaceSelector: %w", err)
+	}
+	return nil
+}
+
+// ParseTargetPathSuffixTemplate parses target_path_suffix of crdSelector ref as a template
+func (tr *TargetRef) ParseTargetPathSuffixTemplate() (*template.Template, error) {
+	return template.New("target_path_suffix").Option("missingkey=error").Parse(tr.TargetPathSuffix)
+}
+
 // StaticRef - user-defined routing host address.
 type StaticRef struct {
 	// URL http url for given staticRef.
@@ -129,9 +196,74 @@ type TargetRefBasicAuth struct {
 	Password v1.SecretKeySelector `json:"password"`
 }
 
+// VMUserPasswordRotation defines rotation of the password generated by operator
+type VMUserPasswordRotation struct {
+	// Interval defines how often generated password must be rotated
+	// +kubebuilder:validation:Pattern:="^([0-9]+(ms|s|m|h))+$"
+	// +optional
+	Interval string `json:"interval,omitempty"`
+	// RotateNow triggers one-shot password rotation on each change of its value.
+	// For example, it could be set to the current timestamp.
+	// +optional
+	RotateNow string `json:"rotateNow,omitempty"`
+	// OverlapWindow defines for how long the previous password remains valid after rotation.
+	// Defaults to 5m
+	// +kubebuilder:validation:Pattern:="^([0-9]+(ms|s|m|h))+$"
+	// +optional
+	OverlapWindow string `json:"overlapWindow,omitempty"`
+}
+
+// DefaultVMUserPasswordOverlapWindow defines default overlap window for the rotated password
+const DefaultVMUserPasswordOverlapWindow = 5 * time.Minute
+
+// GetInterval returns parsed rotation interval or 0 if it's not set
+func (pr *VMUserPasswordRotation) GetInterval() time.Duration {
+	if pr == nil || pr.Interval == "" {
+		return 0
+	}
+	d, _ := time.ParseDuration(pr.Interval)
+	return d
+}
+
+// GetOverlapWindow returns parsed overlap window or default value if it's not set
+func (pr *VMUserPasswordRotation) GetOverlapWindow() time.Duration {
+	if pr == nil || pr.OverlapWindow == "" {
+		return DefaultVMUserPasswordOverlapWindow
+	}
+	d, err := time.ParseDuration(pr.OverlapWindow)
+	if err != nil {
+		return DefaultVMUserPasswordOverlapWindow
+	}
+	return d
+}
+
+func (pr *VMUserPasswordRotation) validate() error {
+	if pr.Interval == "" && pr.RotateNow == "" {
+		return fmt.Errorf("one of interval or rotateNow must be set")
+	}
+	if pr.Interval != "" {
+		d, err := time.ParseDuration(pr.Interval)
+		if err != nil {
+			return fmt.Errorf("cannot parse interval=%q: %w", pr.Interval, err)
+		}
+		if d <= 0 {
+			return fmt.Errorf("interval=%q must be positive", pr.Interval)
+		}
+	}
+	if pr.OverlapWindow != "" {
+		if _, err := time.ParseDuration(pr.OverlapWindow); err != nil {
+			return fmt.Errorf("cannot parse overlapWindow=%q: %w", pr.OverlapWindow, err)
+		}
+	}
+	return nil
+}
+
 // VMUserStatus defines the observed state of VMUser
 type VMUserStatus struct {
 	StatusMetadata `json:",inline"`
+	// LastPasswordRotationTime defines time of the last generated password rotation
+	// +optional
+	LastPasswordRotationTime *metav1.Time `json:"lastPasswordRotationTime,omitempty"`
 }
 
 // VMUser is the Schema for the vmusers API
@@ -44,17 +44,31 @@ func (r *VMUser) sanityCheck() error {
 	if r.Spec.PasswordRef != nil && r.Spec.Password != nil {
 		return fmt.Errorf("one of spec.password or spec.passwordRef must be used for user, got both")
 	}
+	if r.Spec.PasswordRotation != nil {
+		if !r.Spec.GeneratePassword || r.Spec.Password != nil || r.Spec.PasswordRef != nil || r.Spec.BearerToken != nil || r.Spec.TokenRef != nil {
+			return fmt.Errorf("spec.passwordRotation could be used only with spec.generatePassword and without spec.password, spec.passwordRef, spec.bearerToken and spec.tokenRef")
+		}
+		if err := r.Spec.PasswordRotation.validate(); err != nil {
+			return fmt.Errorf("incorrect spec.passwordRotation: %w", err)
+		}
+	}
 	if len(r.Spec.TargetRefs) == 0 {
 		return fmt.Errorf("at least 1 TargetRef must be provided for spec.targetRefs")
 	}
 	isRetryCodesSet := len(r.Spec.RetryStatusCodes) > 0
 	for i := range r.Spec.TargetRefs {
 		targetRef := r.Spec.TargetRefs[i]
-		if targetRef.CRD != nil && targetRef.Static != nil {
-			return fmt.Errorf("targetRef validation failed, one of `crd` or `static` must be configured, got both")
+		var refTypes int
+		for _, isSet := range []bool{targetRef.CRD != nil, targetRef.CRDSelector != nil, targetRef.Static != nil} {
+			if isSet {
+				refTypes++
+			}
+		}
+		if refTypes > 1 {
+			return fmt.Errorf("targetRef validation failed, one of `crd`, `crdSelector` or `static` must be configured, got multiple")
 		}
-		if targetRef.CRD == nil && targetRef.Static == nil {
-			return fmt.Errorf("targetRef validation failed, one of `crd` or `static` must be configured, got none")
+		if refTypes == 0 {
+			return fmt.Errorf("targetRef validation failed, one of `crd`, `crdSelector` or `static` must be configured, got none")
 		}
 		if targetRef.Static != nil {
 			if targetRef.Static.URL == "" && len(targetRef.Static.URLs) == 0 {
@@ -76,11 +90,16 @@ func (r *VMUser) sanityCheck() error {
 				return fmt.Errorf("crd.name and crd.namespace cannot be empty")
 			}
 		}
-		if err := validateHTTPHeaders(targetRef.ResponseHeaders); err != nil {
-			return fmt.Errorf("failed to parse targetRef response headers :%w", err)
+		if targetRef.CRDSelector != nil {
+			if err := targetRef.CRDSelector.validate(); err != nil {
+				return fmt.Errorf("incorrect crdSelector at idx=%d: %w", i, err)
+			}
+			if _, err := targetRef.ParseTargetPathSuffixTemplate(); err != nil {
+				return fmt.Errorf("incorrect target_path_suffix at idx=%d: %w", i, err)
+			}
 		}
-		if err := validateHTTPHeaders(targetRef.RequestHeaders); err != nil {
-			return fmt.Errorf("failed to parse targetRef headers :%w", err)
+		if err := targetRef.URLMapCommon.Validate(); err != nil {
+			return fmt.Errorf("incorrect targetRef at idx=%d: %w", i, err)
 		}
 		if isRetryCodesSet && len(targetRef.RetryStatusCodes) > 0 {
 			return fmt.Errorf("retry_status_codes already set at VMUser.spec level")
@@ -91,12 +110,18 @@ func (r *VMUser) sanityCheck() error {
 			return fmt.Errorf("incorrect metricLabels key=%q, must match pattern=%q", k, labelNameRegexp)
 		}
 	}
+	if err := r.Spec.IPFilters.Validate(); err != nil {
+		return err
+	}
 	if err := validateHTTPHeaders(r.Spec.Headers); err != nil {
 		return fmt.Errorf("failed to parse vmuser headers: %w", err)
 	}
 	if err := validateHTTPHeaders(r.Spec.ResponseHeaders); err != nil {
 		return fmt.Errorf("failed to parse vmuser response headers: %w", err)
 	}
+	if err := validateRoutingOptions(r.Spec.RetryStatusCodes, r.Spec.LoadBalancingPolicy, r.Spec.DropSrcPathPrefixParts); err != nil {
+		return fmt.Errorf("incorrect vmuser options: %w", err)
+	}
 	return nil
 }
 
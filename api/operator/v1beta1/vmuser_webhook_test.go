@@ -59,6 +59,152 @@ func TestVMUser_sanityCheck(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "crd and crdSelector ref",
+			fields: fields{
+				Spec: VMUserSpec{
+					UserName: ptr.To("some-user"),
+					TargetRefs: []TargetRef{
+						{
+							CRD:         &CRDRef{Name: "sm", Namespace: "default", Kind: "VMSingle"},
+							CRDSelector: &CRDSelectorRef{Kind: "VMSingle"},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "crdSelector ref with templated suffix",
+			fields: fields{
+				Spec: VMUserSpec{
+					UserName: ptr.To("some-user"),
+					TargetRefs: []TargetRef{
+						{
+							CRDSelector: &CRDSelectorRef{
+								Kind:     "VMCluster/vmselect",
+								Selector: &v1.LabelSelector{MatchLabels: map[string]string{"team": "infra"}},
+							},
+							TargetPathSuffix: "/select/{{ .Labels.tenant }}/prometheus",
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "crdSelector ref with incorrect suffix template",
+			fields: fields{
+				Spec: VMUserSpec{
+					UserName: ptr.To("some-user"),
+					TargetRefs: []TargetRef{
+						{
+							CRDSelector:      &CRDSelectorRef{Kind: "VMSingle"},
+							TargetPathSuffix: "/{{ .Name }",
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "crdSelector ref with incorrect selector",
+			fields: fields{
+				Spec: VMUserSpec{
+					UserName: ptr.To("some-user"),
+					TargetRefs: []TargetRef{
+						{
+							CRDSelector: &CRDSelectorRef{
+								Kind:     "VMSingle",
+								Selector: &v1.LabelSelector{MatchExpressions: []v1.LabelSelectorRequirement{{Key: "team", Operator: "Unknown"}}},
+							},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid ip filters",
+			fields: fields{
+				Spec: VMUserSpec{
+					UserName: ptr.To("some-user"),
+					TargetRefs: []TargetRef{
+						{
+							Static: &StaticRef{URL: "http://some-url"},
+						},
+					},
+					VMUserConfigOptions: VMUserConfigOptions{
+						IPFilters: VMUserIPFilters{AllowList: []string{"10.0.0.0/24", "not-an-ip"}},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid targetRef options",
+			fields: fields{
+				Spec: VMUserSpec{
+					UserName: ptr.To("some-user"),
+					TargetRefs: []TargetRef{
+						{
+							Static: &StaticRef{URL: "http://some-url"},
+							URLMapCommon: URLMapCommon{
+								RetryStatusCodes:       []int{503},
+								DropSrcPathPrefixParts: ptr.To(-1),
+							},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "password rotation without generated password",
+			fields: fields{
+				Spec: VMUserSpec{
+					UserName:         ptr.To("some-user"),
+					Password:         ptr.To("some-password"),
+					PasswordRotation: &VMUserPasswordRotation{Interval: "24h"},
+					TargetRefs: []TargetRef{
+						{
+							Static: &StaticRef{URL: "http://some-url"},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "password rotation without interval",
+			fields: fields{
+				Spec: VMUserSpec{
+					UserName:         ptr.To("some-user"),
+					GeneratePassword: true,
+					PasswordRotation: &VMUserPasswordRotation{OverlapWindow: "10m"},
+					TargetRefs: []TargetRef{
+						{
+							Static: &StaticRef{URL: "http://some-url"},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "password rotation ok",
+			fields: fields{
+				Spec: VMUserSpec{
+					UserName:         ptr.To("some-user"),
+					GeneratePassword: true,
+					PasswordRotation: &VMUserPasswordRotation{Interval: "720h", OverlapWindow: "10m"},
+					TargetRefs: []TargetRef{
+						{
+							Static: &StaticRef{URL: "http://some-url"},
+						},
+					},
+				},
+			},
+		},
 		{
 			name: "invalid ref crd, bad empty ns",
 			fields: fields{
@@ -30,6 +30,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"net/url"
+	apisv1 "sigs.k8s.io/gateway-api/apis/v1"
 )
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
@@ -79,6 +80,66 @@ func (in *AdditionalServiceSpec) DeepCopy() *AdditionalServiceSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AlertmanagerClusterTLS) DeepCopyInto(out *AlertmanagerClusterTLS) {
+	*out = *in
+	if in.IssuerRef != nil {
+		in, out := &in.IssuerRef, &out.IssuerRef
+		*out = new(VMClusterCertManagerIssuerRef)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AlertmanagerClusterTLS.
+func (in *AlertmanagerClusterTLS) DeepCopy() *AlertmanagerClusterTLS {
+	if in == nil {
+		return nil
+	}
+	out := new(AlertmanagerClusterTLS)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AlertmanagerGlobalConfig) DeepCopyInto(out *AlertmanagerGlobalConfig) {
+	*out = *in
+	if in.HTTPConfig != nil {
+		in, out := &in.HTTPConfig, &out.HTTPConfig
+		*out = new(HTTPConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SMTPAuthPassword != nil {
+		in, out := &in.SMTPAuthPassword, &out.SMTPAuthPassword
+		*out = new(v1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SMTPAuthSecret != nil {
+		in, out := &in.SMTPAuthSecret, &out.SMTPAuthSecret
+		*out = new(v1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SMTPRequireTLS != nil {
+		in, out := &in.SMTPRequireTLS, &out.SMTPRequireTLS
+		*out = new(bool)
+		**out = **in
+	}
+	if in.SlackAPIURL != nil {
+		in, out := &in.SlackAPIURL, &out.SlackAPIURL
+		*out = new(v1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AlertmanagerGlobalConfig.
+func (in *AlertmanagerGlobalConfig) DeepCopy() *AlertmanagerGlobalConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(AlertmanagerGlobalConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AlertmanagerGossipConfig) DeepCopyInto(out *AlertmanagerGossipConfig) {
 	*out = *in
@@ -126,6 +187,28 @@ func (in *AlertmanagerHTTPConfig) DeepCopy() *AlertmanagerHTTPConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AlertmanagerVMAuthExposure) DeepCopyInto(out *AlertmanagerVMAuthExposure) {
+	*out = *in
+	if in.VMAuthSelector != nil {
+		in, out := &in.VMAuthSelector, &out.VMAuthSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AlertmanagerVMAuthExposure.
+func (in *AlertmanagerVMAuthExposure) DeepCopy() *AlertmanagerVMAuthExposure {
+	if in == nil {
+		return nil
+	}
+	out := new(AlertmanagerVMAuthExposure)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AlertmanagerWebConfig) DeepCopyInto(out *AlertmanagerWebConfig) {
 	*out = *in
@@ -146,6 +229,11 @@ func (in *AlertmanagerWebConfig) DeepCopyInto(out *AlertmanagerWebConfig) {
 			(*out)[key] = val
 		}
 	}
+	if in.ClientBasicAuth != nil {
+		in, out := &in.ClientBasicAuth, &out.ClientBasicAuth
+		*out = new(BasicAuth)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AlertmanagerWebConfig.
@@ -315,6 +403,31 @@ func (in *CRDRef) DeepCopy() *CRDRef {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CRDSelectorRef) DeepCopyInto(out *CRDSelectorRef) {
+	*out = *in
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CRDSelectorRef.
+func (in *CRDSelectorRef) DeepCopy() *CRDSelectorRef {
+	if in == nil {
+		return nil
+	}
+	out := new(CRDSelectorRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Certs) DeepCopyInto(out *Certs) {
 	*out = *in
@@ -1012,6 +1125,34 @@ func (in *EmbeddedHPA) DeepCopy() *EmbeddedHPA {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EmbeddedHTTPRoute) DeepCopyInto(out *EmbeddedHTTPRoute) {
+	*out = *in
+	in.EmbeddedObjectMetadata.DeepCopyInto(&out.EmbeddedObjectMetadata)
+	if in.ParentRefs != nil {
+		in, out := &in.ParentRefs, &out.ParentRefs
+		*out = make([]apisv1.ParentReference, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Hostnames != nil {
+		in, out := &in.Hostnames, &out.Hostnames
+		*out = make([]apisv1.Hostname, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EmbeddedHTTPRoute.
+func (in *EmbeddedHTTPRoute) DeepCopy() *EmbeddedHTTPRoute {
+	if in == nil {
+		return nil
+	}
+	out := new(EmbeddedHTTPRoute)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *EmbeddedIngress) DeepCopyInto(out *EmbeddedIngress) {
 	*out = *in
@@ -1287,6 +1428,18 @@ func (in *EndpointScrapeParams) DeepCopyInto(out *EndpointScrapeParams) {
 		*out = new(bool)
 		**out = **in
 	}
+	if in.EnableHTTP2 != nil {
+		in, out := &in.EnableHTTP2, &out.EnableHTTP2
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Headers != nil {
+		in, out := &in.Headers, &out.Headers
+		*out = make([]ScrapeHeader, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.ProxyURL != nil {
 		in, out := &in.ProxyURL, &out.ProxyURL
 		*out = new(string)
@@ -1297,6 +1450,11 @@ func (in *EndpointScrapeParams) DeepCopyInto(out *EndpointScrapeParams) {
 		*out = new(bool)
 		**out = **in
 	}
+	if in.ScrapeProtocols != nil {
+		in, out := &in.ScrapeProtocols, &out.ScrapeProtocols
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.VMScrapeParams != nil {
 		in, out := &in.VMScrapeParams, &out.VMScrapeParams
 		*out = new(VMScrapeParams)
@@ -2111,6 +2269,11 @@ func (in *ProbeTargetIngress) DeepCopyInto(out *ProbeTargetIngress) {
 			}
 		}
 	}
+	if in.IncludePaths != nil {
+		in, out := &in.IncludePaths, &out.IncludePaths
+		*out = new(bool)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProbeTargetIngress.
@@ -2505,10 +2668,109 @@ func (in *RuleGroup) DeepCopy() *RuleGroup {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScrapeClass) DeepCopyInto(out *ScrapeClass) {
+	*out = *in
+	if in.TLSConfig != nil {
+		in, out := &in.TLSConfig, &out.TLSConfig
+		*out = new(TLSConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Authorization != nil {
+		in, out := &in.Authorization, &out.Authorization
+		*out = new(Authorization)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RelabelConfigs != nil {
+		in, out := &in.RelabelConfigs, &out.RelabelConfigs
+		*out = make([]*RelabelConfig, len(*in))
+		for i := range *in {
+			if (*in)[i] != nil {
+				in, out := &(*in)[i], &(*out)[i]
+				*out = new(RelabelConfig)
+				(*in).DeepCopyInto(*out)
+			}
+		}
+	}
+	if in.MetricRelabelConfigs != nil {
+		in, out := &in.MetricRelabelConfigs, &out.MetricRelabelConfigs
+		*out = make([]*RelabelConfig, len(*in))
+		for i := range *in {
+			if (*in)[i] != nil {
+				in, out := &(*in)[i], &(*out)[i]
+				*out = new(RelabelConfig)
+				(*in).DeepCopyInto(*out)
+			}
+		}
+	}
+	if in.AttachMetadata != nil {
+		in, out := &in.AttachMetadata, &out.AttachMetadata
+		*out = new(AttachMetadata)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScrapeClass.
+func (in *ScrapeClass) DeepCopy() *ScrapeClass {
+	if in == nil {
+		return nil
+	}
+	out := new(ScrapeClass)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScrapeHeader) DeepCopyInto(out *ScrapeHeader) {
+	*out = *in
+	if in.ValueFrom != nil {
+		in, out := &in.ValueFrom, &out.ValueFrom
+		*out = new(v1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScrapeHeader.
+func (in *ScrapeHeader) DeepCopy() *ScrapeHeader {
+	if in == nil {
+		return nil
+	}
+	out := new(ScrapeHeader)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScrapeObjectSelectedBy) DeepCopyInto(out *ScrapeObjectSelectedBy) {
+	*out = *in
+	if in.Jobs != nil {
+		in, out := &in.Jobs, &out.Jobs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScrapeObjectSelectedBy.
+func (in *ScrapeObjectSelectedBy) DeepCopy() *ScrapeObjectSelectedBy {
+	if in == nil {
+		return nil
+	}
+	out := new(ScrapeObjectSelectedBy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ScrapeObjectStatus) DeepCopyInto(out *ScrapeObjectStatus) {
 	*out = *in
 	in.StatusMetadata.DeepCopyInto(&out.StatusMetadata)
+	if in.SelectedBy != nil {
+		in, out := &in.SelectedBy, &out.SelectedBy
+		*out = make([]ScrapeObjectSelectedBy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScrapeObjectStatus.
@@ -3121,6 +3383,13 @@ func (in *TargetEndpoint) DeepCopyInto(out *TargetEndpoint) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.TargetsWithLabels != nil {
+		in, out := &in.TargetsWithLabels, &out.TargetsWithLabels
+		*out = make([]TargetWithLabels, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.Labels != nil {
 		in, out := &in.Labels, &out.Labels
 		*out = make(map[string]string, len(*in))
@@ -3151,6 +3420,11 @@ func (in *TargetRef) DeepCopyInto(out *TargetRef) {
 		*out = new(CRDRef)
 		**out = **in
 	}
+	if in.CRDSelector != nil {
+		in, out := &in.CRDSelector, &out.CRDSelector
+		*out = new(CRDSelectorRef)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Static != nil {
 		in, out := &in.Static, &out.Static
 		*out = new(StaticRef)
@@ -3201,6 +3475,35 @@ func (in *TargetRefBasicAuth) DeepCopy() *TargetRefBasicAuth {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TargetWithLabels) DeepCopyInto(out *TargetWithLabels) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Params != nil {
+		in, out := &in.Params, &out.Params
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TargetWithLabels.
+func (in *TargetWithLabels) DeepCopy() *TargetWithLabels {
+	if in == nil {
+		return nil
+	}
+	out := new(TargetWithLabels)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TelegramConfig) DeepCopyInto(out *TelegramConfig) {
 	*out = *in
@@ -3560,44 +3863,179 @@ func (in *VMAgent) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *VMAgentList) DeepCopyInto(out *VMAgentList) {
+func (in *VMAgentDebug) DeepCopyInto(out *VMAgentDebug) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ListMeta.DeepCopyInto(&out.ListMeta)
-	if in.Items != nil {
-		in, out := &in.Items, &out.Items
-		*out = make([]VMAgent, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMAgentList.
-func (in *VMAgentList) DeepCopy() *VMAgentList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMAgentDebug.
+func (in *VMAgentDebug) DeepCopy() *VMAgentDebug {
 	if in == nil {
 		return nil
 	}
-	out := new(VMAgentList)
+	out := new(VMAgentDebug)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *VMAgentList) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
-	}
-	return nil
-}
-
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *VMAgentRemoteWriteSettings) DeepCopyInto(out *VMAgentRemoteWriteSettings) {
+func (in *VMAgentGlobalMetricRelabelConfigs) DeepCopyInto(out *VMAgentGlobalMetricRelabelConfigs) {
 	*out = *in
-	if in.MaxBlockSize != nil {
-		in, out := &in.MaxBlockSize, &out.MaxBlockSize
-		*out = new(int32)
-		**out = **in
+	if in.RelabelConfigs != nil {
+		in, out := &in.RelabelConfigs, &out.RelabelConfigs
+		*out = make([]*RelabelConfig, len(*in))
+		for i := range *in {
+			if (*in)[i] != nil {
+				in, out := &(*in)[i], &(*out)[i]
+				*out = new(RelabelConfig)
+				(*in).DeepCopyInto(*out)
+			}
+		}
+	}
+	if in.ExemptSelector != nil {
+		in, out := &in.ExemptSelector, &out.ExemptSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMAgentGlobalMetricRelabelConfigs.
+func (in *VMAgentGlobalMetricRelabelConfigs) DeepCopy() *VMAgentGlobalMetricRelabelConfigs {
+	if in == nil {
+		return nil
+	}
+	out := new(VMAgentGlobalMetricRelabelConfigs)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VMAgentGlobalScrapeLimits) DeepCopyInto(out *VMAgentGlobalScrapeLimits) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMAgentGlobalScrapeLimits.
+func (in *VMAgentGlobalScrapeLimits) DeepCopy() *VMAgentGlobalScrapeLimits {
+	if in == nil {
+		return nil
+	}
+	out := new(VMAgentGlobalScrapeLimits)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VMAgentList) DeepCopyInto(out *VMAgentList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]VMAgent, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMAgentList.
+func (in *VMAgentList) DeepCopy() *VMAgentList {
+	if in == nil {
+		return nil
+	}
+	out := new(VMAgentList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *VMAgentList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VMAgentManagedProber) DeepCopyInto(out *VMAgentManagedProber) {
+	*out = *in
+	out.Image = in.Image
+	if in.ReplicaCount != nil {
+		in, out := &in.ReplicaCount, &out.ReplicaCount
+		*out = new(int32)
+		**out = **in
+	}
+	in.Resources.DeepCopyInto(&out.Resources)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMAgentManagedProber.
+func (in *VMAgentManagedProber) DeepCopy() *VMAgentManagedProber {
+	if in == nil {
+		return nil
+	}
+	out := new(VMAgentManagedProber)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VMAgentRemoteWriteKafka) DeepCopyInto(out *VMAgentRemoteWriteKafka) {
+	*out = *in
+	if in.Brokers != nil {
+		in, out := &in.Brokers, &out.Brokers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.SASLAuth != nil {
+		in, out := &in.SASLAuth, &out.SASLAuth
+		*out = new(BasicAuth)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Params != nil {
+		in, out := &in.Params, &out.Params
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMAgentRemoteWriteKafka.
+func (in *VMAgentRemoteWriteKafka) DeepCopy() *VMAgentRemoteWriteKafka {
+	if in == nil {
+		return nil
+	}
+	out := new(VMAgentRemoteWriteKafka)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VMAgentRemoteWritePubSub) DeepCopyInto(out *VMAgentRemoteWritePubSub) {
+	*out = *in
+	if in.CredentialsSecret != nil {
+		in, out := &in.CredentialsSecret, &out.CredentialsSecret
+		*out = new(v1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMAgentRemoteWritePubSub.
+func (in *VMAgentRemoteWritePubSub) DeepCopy() *VMAgentRemoteWritePubSub {
+	if in == nil {
+		return nil
+	}
+	out := new(VMAgentRemoteWritePubSub)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VMAgentRemoteWriteSettings) DeepCopyInto(out *VMAgentRemoteWriteSettings) {
+	*out = *in
+	if in.MaxBlockSize != nil {
+		in, out := &in.MaxBlockSize, &out.MaxBlockSize
+		*out = new(int32)
+		**out = **in
 	}
 	if in.MaxDiskUsagePerURL != nil {
 		in, out := &in.MaxDiskUsagePerURL, &out.MaxDiskUsagePerURL
@@ -3646,6 +4084,16 @@ func (in *VMAgentRemoteWriteSettings) DeepCopy() *VMAgentRemoteWriteSettings {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VMAgentRemoteWriteSpec) DeepCopyInto(out *VMAgentRemoteWriteSpec) {
 	*out = *in
+	if in.Kafka != nil {
+		in, out := &in.Kafka, &out.Kafka
+		*out = new(VMAgentRemoteWriteKafka)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PubSub != nil {
+		in, out := &in.PubSub, &out.PubSub
+		*out = new(VMAgentRemoteWritePubSub)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.BasicAuth != nil {
 		in, out := &in.BasicAuth, &out.BasicAuth
 		*out = new(BasicAuth)
@@ -3678,6 +4126,16 @@ func (in *VMAgentRemoteWriteSpec) DeepCopyInto(out *VMAgentRemoteWriteSpec) {
 		*out = new(TLSConfig)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.ProxyURL != nil {
+		in, out := &in.ProxyURL, &out.ProxyURL
+		*out = new(string)
+		**out = **in
+	}
+	if in.ProxyBasicAuth != nil {
+		in, out := &in.ProxyBasicAuth, &out.ProxyBasicAuth
+		*out = new(BasicAuth)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.SendTimeout != nil {
 		in, out := &in.SendTimeout, &out.SendTimeout
 		*out = new(string)
@@ -3726,6 +4184,21 @@ func (in *VMAgentSecurityEnforcements) DeepCopy() *VMAgentSecurityEnforcements {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VMAgentShardTransition) DeepCopyInto(out *VMAgentShardTransition) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMAgentShardTransition.
+func (in *VMAgentShardTransition) DeepCopy() *VMAgentShardTransition {
+	if in == nil {
+		return nil
+	}
+	out := new(VMAgentShardTransition)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VMAgentSpec) DeepCopyInto(out *VMAgentSpec) {
 	*out = *in
@@ -3773,6 +4246,11 @@ func (in *VMAgentSpec) DeepCopyInto(out *VMAgentSpec) {
 		*out = new(VMAgentRemoteWriteSettings)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.TenantRouting != nil {
+		in, out := &in.TenantRouting, &out.TenantRouting
+		*out = new(VMAgentTenantRouting)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.RelabelConfig != nil {
 		in, out := &in.RelabelConfig, &out.RelabelConfig
 		*out = new(v1.ConfigMapKeySelector)
@@ -3971,6 +4449,38 @@ func (in *VMAgentSpec) DeepCopyInto(out *VMAgentSpec) {
 		*out = new(string)
 		**out = **in
 	}
+	if in.GlobalScrapeLimits != nil {
+		in, out := &in.GlobalScrapeLimits, &out.GlobalScrapeLimits
+		*out = new(VMAgentGlobalScrapeLimits)
+		**out = **in
+	}
+	if in.ScrapeClasses != nil {
+		in, out := &in.ScrapeClasses, &out.ScrapeClasses
+		*out = make([]ScrapeClass, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ManagedProber != nil {
+		in, out := &in.ManagedProber, &out.ManagedProber
+		*out = new(VMAgentManagedProber)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.GlobalMetricRelabelConfigs != nil {
+		in, out := &in.GlobalMetricRelabelConfigs, &out.GlobalMetricRelabelConfigs
+		*out = new(VMAgentGlobalMetricRelabelConfigs)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Debug != nil {
+		in, out := &in.Debug, &out.Debug
+		*out = new(VMAgentDebug)
+		**out = **in
+	}
+	if in.ServiceScrapeTokenProjection != nil {
+		in, out := &in.ServiceScrapeTokenProjection, &out.ServiceScrapeTokenProjection
+		*out = new(VMAgentTokenProjection)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.StatefulStorage != nil {
 		in, out := &in.StatefulStorage, &out.StatefulStorage
 		*out = new(StorageSpec)
@@ -4007,6 +4517,11 @@ func (in *VMAgentSpec) DeepCopy() *VMAgentSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VMAgentStatus) DeepCopyInto(out *VMAgentStatus) {
 	*out = *in
+	if in.ShardTransition != nil {
+		in, out := &in.ShardTransition, &out.ShardTransition
+		*out = new(VMAgentShardTransition)
+		**out = **in
+	}
 	in.StatusMetadata.DeepCopyInto(&out.StatusMetadata)
 }
 
@@ -4020,6 +4535,46 @@ func (in *VMAgentStatus) DeepCopy() *VMAgentStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VMAgentTenantRouting) DeepCopyInto(out *VMAgentTenantRouting) {
+	*out = *in
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMAgentTenantRouting.
+func (in *VMAgentTenantRouting) DeepCopy() *VMAgentTenantRouting {
+	if in == nil {
+		return nil
+	}
+	out := new(VMAgentTenantRouting)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VMAgentTokenProjection) DeepCopyInto(out *VMAgentTokenProjection) {
+	*out = *in
+	if in.ExpirationSeconds != nil {
+		in, out := &in.ExpirationSeconds, &out.ExpirationSeconds
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMAgentTokenProjection.
+func (in *VMAgentTokenProjection) DeepCopy() *VMAgentTokenProjection {
+	if in == nil {
+		return nil
+	}
+	out := new(VMAgentTokenProjection)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VMAlert) DeepCopyInto(out *VMAlert) {
 	*out = *in
@@ -4068,6 +4623,36 @@ func (in *VMAlertDatasourceSpec) DeepCopy() *VMAlertDatasourceSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VMAlertExternalRuleSource) DeepCopyInto(out *VMAlertExternalRuleSource) {
+	*out = *in
+	if in.ConfigMap != nil {
+		in, out := &in.ConfigMap, &out.ConfigMap
+		*out = new(v1.LocalObjectReference)
+		**out = **in
+	}
+	if in.Secret != nil {
+		in, out := &in.Secret, &out.Secret
+		*out = new(v1.LocalObjectReference)
+		**out = **in
+	}
+	if in.Keys != nil {
+		in, out := &in.Keys, &out.Keys
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMAlertExternalRuleSource.
+func (in *VMAlertExternalRuleSource) DeepCopy() *VMAlertExternalRuleSource {
+	if in == nil {
+		return nil
+	}
+	out := new(VMAlertExternalRuleSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VMAlertList) DeepCopyInto(out *VMAlertList) {
 	*out = *in
@@ -4178,6 +4763,118 @@ func (in *VMAlertRemoteWriteSpec) DeepCopy() *VMAlertRemoteWriteSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VMAlertRuleGroupDefaults) DeepCopyInto(out *VMAlertRuleGroupDefaults) {
+	*out = *in
+	if in.Params != nil {
+		in, out := &in.Params, &out.Params
+		*out = make(url.Values, len(*in))
+		for key, val := range *in {
+			var outVal []string
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				inVal := (*in)[key]
+				in, out := &inVal, &outVal
+				*out = make([]string, len(*in))
+				copy(*out, *in)
+			}
+			(*out)[key] = outVal
+		}
+	}
+	if in.Headers != nil {
+		in, out := &in.Headers, &out.Headers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMAlertRuleGroupDefaults.
+func (in *VMAlertRuleGroupDefaults) DeepCopy() *VMAlertRuleGroupDefaults {
+	if in == nil {
+		return nil
+	}
+	out := new(VMAlertRuleGroupDefaults)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VMAlertRulePolicies) DeepCopyInto(out *VMAlertRulePolicies) {
+	*out = *in
+	if in.RequiredLabels != nil {
+		in, out := &in.RequiredLabels, &out.RequiredLabels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ForbiddenLabels != nil {
+		in, out := &in.ForbiddenLabels, &out.ForbiddenLabels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RequiredAnnotations != nil {
+		in, out := &in.RequiredAnnotations, &out.RequiredAnnotations
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ApplyToRecordingRules != nil {
+		in, out := &in.ApplyToRecordingRules, &out.ApplyToRecordingRules
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMAlertRulePolicies.
+func (in *VMAlertRulePolicies) DeepCopy() *VMAlertRulePolicies {
+	if in == nil {
+		return nil
+	}
+	out := new(VMAlertRulePolicies)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VMAlertRulesReloadCheck) DeepCopyInto(out *VMAlertRulesReloadCheck) {
+	*out = *in
+	if in.TLSConfig != nil {
+		in, out := &in.TLSConfig, &out.TLSConfig
+		*out = new(TLSConfig)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMAlertRulesReloadCheck.
+func (in *VMAlertRulesReloadCheck) DeepCopy() *VMAlertRulesReloadCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(VMAlertRulesReloadCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VMAlertRuleSyncStatus) DeepCopyInto(out *VMAlertRuleSyncStatus) {
+	*out = *in
+	if in.ConfigMaps != nil {
+		in, out := &in.ConfigMaps, &out.ConfigMaps
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.LastSyncTime.DeepCopyInto(&out.LastSyncTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMAlertRuleSyncStatus.
+func (in *VMAlertRuleSyncStatus) DeepCopy() *VMAlertRuleSyncStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(VMAlertRuleSyncStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VMAlertSpec) DeepCopyInto(out *VMAlertSpec) {
 	*out = *in
@@ -4191,6 +4888,11 @@ func (in *VMAlertSpec) DeepCopyInto(out *VMAlertSpec) {
 		*out = new(ManagedObjectsMetadata)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.RuleGroupDefaults != nil {
+		in, out := &in.RuleGroupDefaults, &out.RuleGroupDefaults
+		*out = new(VMAlertRuleGroupDefaults)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.RuleSelector != nil {
 		in, out := &in.RuleSelector, &out.RuleSelector
 		*out = new(metav1.LabelSelector)
@@ -4201,6 +4903,56 @@ func (in *VMAlertSpec) DeepCopyInto(out *VMAlertSpec) {
 		*out = new(metav1.LabelSelector)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.RuleDenySelector != nil {
+		in, out := &in.RuleDenySelector, &out.RuleDenySelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PlaceholderRulesRef != nil {
+		in, out := &in.PlaceholderRulesRef, &out.PlaceholderRulesRef
+		*out = new(v1.ConfigMapKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CompressRuleConfigMaps != nil {
+		in, out := &in.CompressRuleConfigMaps, &out.CompressRuleConfigMaps
+		*out = new(bool)
+		**out = **in
+	}
+	if in.UseNamespaceRuleDirs != nil {
+		in, out := &in.UseNamespaceRuleDirs, &out.UseNamespaceRuleDirs
+		*out = new(bool)
+		**out = **in
+	}
+	if in.RolloutOnRuleChange != nil {
+		in, out := &in.RolloutOnRuleChange, &out.RolloutOnRuleChange
+		*out = new(bool)
+		**out = **in
+	}
+	if in.RulesServerSideApply != nil {
+		in, out := &in.RulesServerSideApply, &out.RulesServerSideApply
+		*out = new(bool)
+		**out = **in
+	}
+	if in.RulesReloadCheck != nil {
+		in, out := &in.RulesReloadCheck, &out.RulesReloadCheck
+		*out = new(VMAlertRulesReloadCheck)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DisableRuleExprValidation != nil {
+		in, out := &in.DisableRuleExprValidation, &out.DisableRuleExprValidation
+		*out = new(bool)
+		**out = **in
+	}
+	if in.RulePolicies != nil {
+		in, out := &in.RulePolicies, &out.RulePolicies
+		*out = new(VMAlertRulePolicies)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ShardCount != nil {
+		in, out := &in.ShardCount, &out.ShardCount
+		*out = new(int)
+		**out = **in
+	}
 	if in.Notifier != nil {
 		in, out := &in.Notifier, &out.Notifier
 		*out = new(VMAlertNotifierSpec)
@@ -4218,6 +4970,16 @@ func (in *VMAlertSpec) DeepCopyInto(out *VMAlertSpec) {
 		*out = new(v1.SecretKeySelector)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.NotifierSelector != nil {
+		in, out := &in.NotifierSelector, &out.NotifierSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NotifierNamespaceSelector != nil {
+		in, out := &in.NotifierNamespaceSelector, &out.NotifierNamespaceSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.RemoteWrite != nil {
 		in, out := &in.RemoteWrite, &out.RemoteWrite
 		*out = new(VMAlertRemoteWriteSpec)
@@ -4233,6 +4995,13 @@ func (in *VMAlertSpec) DeepCopyInto(out *VMAlertSpec) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.ExternalRuleSources != nil {
+		in, out := &in.ExternalRuleSources, &out.ExternalRuleSources
+		*out = make([]VMAlertExternalRuleSource, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	in.Datasource.DeepCopyInto(&out.Datasource)
 	if in.ExternalLabels != nil {
 		in, out := &in.ExternalLabels, &out.ExternalLabels
@@ -4261,6 +5030,13 @@ func (in *VMAlertSpec) DeepCopyInto(out *VMAlertSpec) {
 		*out = new(appsv1.RollingUpdateDeployment)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.ClaimTemplates != nil {
+		in, out := &in.ClaimTemplates, &out.ClaimTemplates
+		*out = make([]v1.PersistentVolumeClaim, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.PodDisruptionBudget != nil {
 		in, out := &in.PodDisruptionBudget, &out.PodDisruptionBudget
 		*out = new(EmbeddedPodDisruptionBudgetSpec)
@@ -4295,6 +5071,11 @@ func (in *VMAlertSpec) DeepCopy() *VMAlertSpec {
 func (in *VMAlertStatus) DeepCopyInto(out *VMAlertStatus) {
 	*out = *in
 	in.StatusMetadata.DeepCopyInto(&out.StatusMetadata)
+	if in.RuleSync != nil {
+		in, out := &in.RuleSync, &out.RuleSync
+		*out = new(VMAlertRuleSyncStatus)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMAlertStatus.
@@ -4398,6 +5179,29 @@ func (in *VMAlertmanagerConfigList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VMAlertmanagerConfigReceiverTest) DeepCopyInto(out *VMAlertmanagerConfigReceiverTest) {
+	*out = *in
+	in.LastTestTime.DeepCopyInto(&out.LastTestTime)
+	if in.Results != nil {
+		in, out := &in.Results, &out.Results
+		*out = make([]VMAlertmanagerReceiverTestResult, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMAlertmanagerConfigReceiverTest.
+func (in *VMAlertmanagerConfigReceiverTest) DeepCopy() *VMAlertmanagerConfigReceiverTest {
+	if in == nil {
+		return nil
+	}
+	out := new(VMAlertmanagerConfigReceiverTest)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VMAlertmanagerConfigSpec) DeepCopyInto(out *VMAlertmanagerConfigSpec) {
 	*out = *in
@@ -4443,6 +5247,11 @@ func (in *VMAlertmanagerConfigSpec) DeepCopy() *VMAlertmanagerConfigSpec {
 func (in *VMAlertmanagerConfigStatus) DeepCopyInto(out *VMAlertmanagerConfigStatus) {
 	*out = *in
 	in.StatusMetadata.DeepCopyInto(&out.StatusMetadata)
+	if in.ReceiverTest != nil {
+		in, out := &in.ReceiverTest, &out.ReceiverTest
+		*out = new(VMAlertmanagerConfigReceiverTest)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMAlertmanagerConfigStatus.
@@ -4455,6 +5264,21 @@ func (in *VMAlertmanagerConfigStatus) DeepCopy() *VMAlertmanagerConfigStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VMAlertmanagerConfigSummary) DeepCopyInto(out *VMAlertmanagerConfigSummary) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMAlertmanagerConfigSummary.
+func (in *VMAlertmanagerConfigSummary) DeepCopy() *VMAlertmanagerConfigSummary {
+	if in == nil {
+		return nil
+	}
+	out := new(VMAlertmanagerConfigSummary)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VMAlertmanagerList) DeepCopyInto(out *VMAlertmanagerList) {
 	*out = *in
@@ -4487,6 +5311,28 @@ func (in *VMAlertmanagerList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VMAlertmanagerReceiverTestResult) DeepCopyInto(out *VMAlertmanagerReceiverTestResult) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMAlertmanagerReceiverTestResult.
+func (in *VMAlertmanagerReceiverTestResult) DeepCopy() *VMAlertmanagerReceiverTestResult {
+	if in == nil {
+		return nil
+	}
+	out := new(VMAlertmanagerReceiverTestResult)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VMAlertmanagerSpec) DeepCopyInto(out *VMAlertmanagerSpec) {
 	*out = *in
@@ -4505,11 +5351,28 @@ func (in *VMAlertmanagerSpec) DeepCopyInto(out *VMAlertmanagerSpec) {
 		*out = make([]ConfigMapKeyReference, len(*in))
 		copy(*out, *in)
 	}
+	if in.TemplateSecrets != nil {
+		in, out := &in.TemplateSecrets, &out.TemplateSecrets
+		*out = make([]v1.SecretKeySelector, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.GlobalConfig != nil {
+		in, out := &in.GlobalConfig, &out.GlobalConfig
+		*out = new(AlertmanagerGlobalConfig)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Storage != nil {
 		in, out := &in.Storage, &out.Storage
 		*out = new(StorageSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.ExposeViaVMAuth != nil {
+		in, out := &in.ExposeViaVMAuth, &out.ExposeViaVMAuth
+		*out = new(AlertmanagerVMAuthExposure)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.AdditionalPeers != nil {
 		in, out := &in.AdditionalPeers, &out.AdditionalPeers
 		*out = make([]string, len(*in))
@@ -4567,6 +5430,11 @@ func (in *VMAlertmanagerSpec) DeepCopyInto(out *VMAlertmanagerSpec) {
 		*out = new(AlertmanagerGossipConfig)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.ClusterTLS != nil {
+		in, out := &in.ClusterTLS, &out.ClusterTLS
+		*out = new(AlertmanagerClusterTLS)
+		(*in).DeepCopyInto(*out)
+	}
 	in.CommonDefaultableParams.DeepCopyInto(&out.CommonDefaultableParams)
 	in.CommonConfigReloaderParams.DeepCopyInto(&out.CommonConfigReloaderParams)
 	in.CommonApplicationDeploymentParams.DeepCopyInto(&out.CommonApplicationDeploymentParams)
@@ -4586,6 +5454,11 @@ func (in *VMAlertmanagerSpec) DeepCopy() *VMAlertmanagerSpec {
 func (in *VMAlertmanagerStatus) DeepCopyInto(out *VMAlertmanagerStatus) {
 	*out = *in
 	in.StatusMetadata.DeepCopyInto(&out.StatusMetadata)
+	if in.ConfigSummary != nil {
+		in, out := &in.ConfigSummary, &out.ConfigSummary
+		*out = new(VMAlertmanagerConfigSummary)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMAlertmanagerStatus.
@@ -4630,6 +5503,37 @@ func (in *VMAuth) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VMAuthDefaultUserPolicies) DeepCopyInto(out *VMAuthDefaultUserPolicies) {
+	*out = *in
+	in.IPFilters.DeepCopyInto(&out.IPFilters)
+	if in.MaxConcurrentRequests != nil {
+		in, out := &in.MaxConcurrentRequests, &out.MaxConcurrentRequests
+		*out = new(int)
+		**out = **in
+	}
+	if in.DiscoverBackendIPs != nil {
+		in, out := &in.DiscoverBackendIPs, &out.DiscoverBackendIPs
+		*out = new(bool)
+		**out = **in
+	}
+	if in.RetryStatusCodes != nil {
+		in, out := &in.RetryStatusCodes, &out.RetryStatusCodes
+		*out = make([]int, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMAuthDefaultUserPolicies.
+func (in *VMAuthDefaultUserPolicies) DeepCopy() *VMAuthDefaultUserPolicies {
+	if in == nil {
+		return nil
+	}
+	out := new(VMAuthDefaultUserPolicies)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VMAuthList) DeepCopyInto(out *VMAuthList) {
 	*out = *in
@@ -4763,6 +5667,11 @@ func (in *VMAuthSpec) DeepCopyInto(out *VMAuthSpec) {
 		*out = new(EmbeddedIngress)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.HTTPRoute != nil {
+		in, out := &in.HTTPRoute, &out.HTTPRoute
+		*out = new(EmbeddedHTTPRoute)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.EmbeddedProbes != nil {
 		in, out := &in.EmbeddedProbes, &out.EmbeddedProbes
 		*out = new(EmbeddedProbes)
@@ -4780,6 +5689,11 @@ func (in *VMAuthSpec) DeepCopyInto(out *VMAuthSpec) {
 		*out = new(VMAuthUnauthorizedUserAccessSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.DefaultUserPolicies != nil {
+		in, out := &in.DefaultUserPolicies, &out.DefaultUserPolicies
+		*out = new(VMAuthDefaultUserPolicies)
+		(*in).DeepCopyInto(*out)
+	}
 	in.VMUserConfigOptions.DeepCopyInto(&out.VMUserConfigOptions)
 	if in.License != nil {
 		in, out := &in.License, &out.License
@@ -4931,46 +5845,115 @@ func (in *VMBackup) DeepCopyInto(out *VMBackup) {
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMBackup.
-func (in *VMBackup) DeepCopy() *VMBackup {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMBackup.
+func (in *VMBackup) DeepCopy() *VMBackup {
+	if in == nil {
+		return nil
+	}
+	out := new(VMBackup)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VMCluster) DeepCopyInto(out *VMCluster) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	if in.ParsedLastAppliedSpec != nil {
+		in, out := &in.ParsedLastAppliedSpec, &out.ParsedLastAppliedSpec
+		*out = new(VMClusterSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMCluster.
+func (in *VMCluster) DeepCopy() *VMCluster {
+	if in == nil {
+		return nil
+	}
+	out := new(VMCluster)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *VMCluster) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VMClusterCertManagerIssuerRef) DeepCopyInto(out *VMClusterCertManagerIssuerRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMClusterCertManagerIssuerRef.
+func (in *VMClusterCertManagerIssuerRef) DeepCopy() *VMClusterCertManagerIssuerRef {
+	if in == nil {
+		return nil
+	}
+	out := new(VMClusterCertManagerIssuerRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VMClusterComponentStatus) DeepCopyInto(out *VMClusterComponentStatus) {
+	*out = *in
+	if in.LastRolloutTime != nil {
+		in, out := &in.LastRolloutTime, &out.LastRolloutTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMClusterComponentStatus.
+func (in *VMClusterComponentStatus) DeepCopy() *VMClusterComponentStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(VMBackup)
+	out := new(VMClusterComponentStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *VMCluster) DeepCopyInto(out *VMCluster) {
+func (in *VMClusterDownsamplingPeriod) DeepCopyInto(out *VMClusterDownsamplingPeriod) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	in.Spec.DeepCopyInto(&out.Spec)
-	if in.ParsedLastAppliedSpec != nil {
-		in, out := &in.ParsedLastAppliedSpec, &out.ParsedLastAppliedSpec
-		*out = new(VMClusterSpec)
-		(*in).DeepCopyInto(*out)
-	}
-	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMCluster.
-func (in *VMCluster) DeepCopy() *VMCluster {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMClusterDownsamplingPeriod.
+func (in *VMClusterDownsamplingPeriod) DeepCopy() *VMClusterDownsamplingPeriod {
 	if in == nil {
 		return nil
 	}
-	out := new(VMCluster)
+	out := new(VMClusterDownsamplingPeriod)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *VMCluster) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VMClusterInternalTLS) DeepCopyInto(out *VMClusterInternalTLS) {
+	*out = *in
+	if in.IssuerRef != nil {
+		in, out := &in.IssuerRef, &out.IssuerRef
+		*out = new(VMClusterCertManagerIssuerRef)
+		**out = **in
 	}
-	return nil
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMClusterInternalTLS.
+func (in *VMClusterInternalTLS) DeepCopy() *VMClusterInternalTLS {
+	if in == nil {
+		return nil
+	}
+	out := new(VMClusterInternalTLS)
+	in.DeepCopyInto(out)
+	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
@@ -5005,6 +5988,21 @@ func (in *VMClusterList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VMClusterRetentionFilter) DeepCopyInto(out *VMClusterRetentionFilter) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMClusterRetentionFilter.
+func (in *VMClusterRetentionFilter) DeepCopy() *VMClusterRetentionFilter {
+	if in == nil {
+		return nil
+	}
+	out := new(VMClusterRetentionFilter)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VMClusterSpec) DeepCopyInto(out *VMClusterSpec) {
 	*out = *in
@@ -5049,6 +6047,26 @@ func (in *VMClusterSpec) DeepCopyInto(out *VMClusterSpec) {
 		*out = new(ManagedObjectsMetadata)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.UpdateStrategy != nil {
+		in, out := &in.UpdateStrategy, &out.UpdateStrategy
+		*out = new(VMClusterUpdateStrategy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RetentionFilters != nil {
+		in, out := &in.RetentionFilters, &out.RetentionFilters
+		*out = make([]VMClusterRetentionFilter, len(*in))
+		copy(*out, *in)
+	}
+	if in.Downsampling != nil {
+		in, out := &in.Downsampling, &out.Downsampling
+		*out = make([]VMClusterDownsamplingPeriod, len(*in))
+		copy(*out, *in)
+	}
+	if in.InternalTLS != nil {
+		in, out := &in.InternalTLS, &out.InternalTLS
+		*out = new(VMClusterInternalTLS)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMClusterSpec.
@@ -5065,6 +6083,27 @@ func (in *VMClusterSpec) DeepCopy() *VMClusterSpec {
 func (in *VMClusterStatus) DeepCopyInto(out *VMClusterStatus) {
 	*out = *in
 	in.StatusMetadata.DeepCopyInto(&out.StatusMetadata)
+	if in.PausedComponents != nil {
+		in, out := &in.PausedComponents, &out.PausedComponents
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Components != nil {
+		in, out := &in.Components, &out.Components
+		*out = make(map[string]*VMClusterComponentStatus, len(*in))
+		for key, val := range *in {
+			var outVal *VMClusterComponentStatus
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				inVal := (*in)[key]
+				in, out := &inVal, &outVal
+				*out = new(VMClusterComponentStatus)
+				(*in).DeepCopyInto(*out)
+			}
+			(*out)[key] = outVal
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMClusterStatus.
@@ -5077,6 +6116,26 @@ func (in *VMClusterStatus) DeepCopy() *VMClusterStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VMClusterUpdateStrategy) DeepCopyInto(out *VMClusterUpdateStrategy) {
+	*out = *in
+	if in.Order != nil {
+		in, out := &in.Order, &out.Order
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMClusterUpdateStrategy.
+func (in *VMClusterUpdateStrategy) DeepCopy() *VMClusterUpdateStrategy {
+	if in == nil {
+		return nil
+	}
+	out := new(VMClusterUpdateStrategy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VMInsert) DeepCopyInto(out *VMInsert) {
 	*out = *in
@@ -5206,6 +6265,11 @@ func (in *VMNodeScrapeSpec) DeepCopyInto(out *VMNodeScrapeSpec) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.UseNodeAddressType != nil {
+		in, out := &in.UseNodeAddressType, &out.UseNodeAddressType
+		*out = make([]NodeAddressType, len(*in))
+		copy(*out, *in)
+	}
 	in.EndpointRelabelings.DeepCopyInto(&out.EndpointRelabelings)
 	in.EndpointAuth.DeepCopyInto(&out.EndpointAuth)
 	in.EndpointScrapeParams.DeepCopyInto(&out.EndpointScrapeParams)
@@ -5299,6 +6363,13 @@ func (in *VMPodScrapeSpec) DeepCopyInto(out *VMPodScrapeSpec) {
 	in.Selector.DeepCopyInto(&out.Selector)
 	in.NamespaceSelector.DeepCopyInto(&out.NamespaceSelector)
 	in.AttachMetadata.DeepCopyInto(&out.AttachMetadata)
+	if in.RelabelConfigRefs != nil {
+		in, out := &in.RelabelConfigRefs, &out.RelabelConfigRefs
+		*out = make([]v1.ConfigMapKeySelector, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMPodScrapeSpec.
@@ -5373,7 +6444,7 @@ func (in *VMProbeList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VMProbeSpec) DeepCopyInto(out *VMProbeSpec) {
 	*out = *in
-	out.VMProberSpec = in.VMProberSpec
+	in.VMProberSpec.DeepCopyInto(&out.VMProberSpec)
 	in.Targets.DeepCopyInto(&out.Targets)
 	if in.MetricRelabelConfigs != nil {
 		in, out := &in.MetricRelabelConfigs, &out.MetricRelabelConfigs
@@ -5463,9 +6534,99 @@ func (in *VMProbeTargets) DeepCopy() *VMProbeTargets {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VMProberDNSModule) DeepCopyInto(out *VMProberDNSModule) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMProberDNSModule.
+func (in *VMProberDNSModule) DeepCopy() *VMProberDNSModule {
+	if in == nil {
+		return nil
+	}
+	out := new(VMProberDNSModule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VMProberHTTPModule) DeepCopyInto(out *VMProberHTTPModule) {
+	*out = *in
+	if in.ValidStatusCodes != nil {
+		in, out := &in.ValidStatusCodes, &out.ValidStatusCodes
+		*out = make([]int, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMProberHTTPModule.
+func (in *VMProberHTTPModule) DeepCopy() *VMProberHTTPModule {
+	if in == nil {
+		return nil
+	}
+	out := new(VMProberHTTPModule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VMProberICMPModule) DeepCopyInto(out *VMProberICMPModule) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMProberICMPModule.
+func (in *VMProberICMPModule) DeepCopy() *VMProberICMPModule {
+	if in == nil {
+		return nil
+	}
+	out := new(VMProberICMPModule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VMProberModule) DeepCopyInto(out *VMProberModule) {
+	*out = *in
+	if in.HTTP != nil {
+		in, out := &in.HTTP, &out.HTTP
+		*out = new(VMProberHTTPModule)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TCP != nil {
+		in, out := &in.TCP, &out.TCP
+		*out = new(VMProberTCPModule)
+		**out = **in
+	}
+	if in.DNS != nil {
+		in, out := &in.DNS, &out.DNS
+		*out = new(VMProberDNSModule)
+		**out = **in
+	}
+	if in.ICMP != nil {
+		in, out := &in.ICMP, &out.ICMP
+		*out = new(VMProberICMPModule)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMProberModule.
+func (in *VMProberModule) DeepCopy() *VMProberModule {
+	if in == nil {
+		return nil
+	}
+	out := new(VMProberModule)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VMProberSpec) DeepCopyInto(out *VMProberSpec) {
 	*out = *in
+	if in.ModuleConfig != nil {
+		in, out := &in.ModuleConfig, &out.ModuleConfig
+		*out = new(VMProberModule)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMProberSpec.
@@ -5478,6 +6639,21 @@ func (in *VMProberSpec) DeepCopy() *VMProberSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VMProberTCPModule) DeepCopyInto(out *VMProberTCPModule) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMProberTCPModule.
+func (in *VMProberTCPModule) DeepCopy() *VMProberTCPModule {
+	if in == nil {
+		return nil
+	}
+	out := new(VMProberTCPModule)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VMRestore) DeepCopyInto(out *VMRestore) {
 	*out = *in
@@ -5873,6 +7049,13 @@ func (in *VMSelect) DeepCopyInto(out *VMSelect) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.AdditionalPools != nil {
+		in, out := &in.AdditionalPools, &out.AdditionalPools
+		*out = make([]VMSelectPool, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	in.CommonDefaultableParams.DeepCopyInto(&out.CommonDefaultableParams)
 	in.CommonApplicationDeploymentParams.DeepCopyInto(&out.CommonApplicationDeploymentParams)
 }
@@ -5887,6 +7070,38 @@ func (in *VMSelect) DeepCopy() *VMSelect {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VMSelectPool) DeepCopyInto(out *VMSelectPool) {
+	*out = *in
+	if in.ReplicaCount != nil {
+		in, out := &in.ReplicaCount, &out.ReplicaCount
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = new(v1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ExtraArgs != nil {
+		in, out := &in.ExtraArgs, &out.ExtraArgs
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMSelectPool.
+func (in *VMSelectPool) DeepCopy() *VMSelectPool {
+	if in == nil {
+		return nil
+	}
+	out := new(VMSelectPool)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VMServiceScrape) DeepCopyInto(out *VMServiceScrape) {
 	*out = *in
@@ -5969,6 +7184,13 @@ func (in *VMServiceScrapeSpec) DeepCopyInto(out *VMServiceScrapeSpec) {
 	in.Selector.DeepCopyInto(&out.Selector)
 	in.NamespaceSelector.DeepCopyInto(&out.NamespaceSelector)
 	in.AttachMetadata.DeepCopyInto(&out.AttachMetadata)
+	if in.RelabelConfigRefs != nil {
+		in, out := &in.RelabelConfigRefs, &out.RelabelConfigRefs
+		*out = make([]v1.ConfigMapKeySelector, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMServiceScrapeSpec.
@@ -6262,6 +7484,11 @@ func (in *VMStorage) DeepCopyInto(out *VMStorage) {
 		*out = make([]int32, len(*in))
 		copy(*out, *in)
 	}
+	if in.RollingUpdateStrategyBehavior != nil {
+		in, out := &in.RollingUpdateStrategyBehavior, &out.RollingUpdateStrategyBehavior
+		*out = new(VMStorageRollingUpdateStrategyBehavior)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.ClaimTemplates != nil {
 		in, out := &in.ClaimTemplates, &out.ClaimTemplates
 		*out = make([]v1.PersistentVolumeClaim, len(*in))
@@ -6269,6 +7496,13 @@ func (in *VMStorage) DeepCopyInto(out *VMStorage) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.StorageNodeGroups != nil {
+		in, out := &in.StorageNodeGroups, &out.StorageNodeGroups
+		*out = make([]VMStorageNodeGroup, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	in.CommonDefaultableParams.DeepCopyInto(&out.CommonDefaultableParams)
 	in.CommonApplicationDeploymentParams.DeepCopyInto(&out.CommonApplicationDeploymentParams)
 }
@@ -6283,6 +7517,65 @@ func (in *VMStorage) DeepCopy() *VMStorage {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VMStorageNodeGroup) DeepCopyInto(out *VMStorageNodeGroup) {
+	*out = *in
+	if in.ReplicaCount != nil {
+		in, out := &in.ReplicaCount, &out.ReplicaCount
+		*out = new(int32)
+		**out = **in
+	}
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Affinity != nil {
+		in, out := &in.Affinity, &out.Affinity
+		*out = new(v1.Affinity)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]v1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMStorageNodeGroup.
+func (in *VMStorageNodeGroup) DeepCopy() *VMStorageNodeGroup {
+	if in == nil {
+		return nil
+	}
+	out := new(VMStorageNodeGroup)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VMStorageRollingUpdateStrategyBehavior) DeepCopyInto(out *VMStorageRollingUpdateStrategyBehavior) {
+	*out = *in
+	if in.ReadyChecksCount != nil {
+		in, out := &in.ReadyChecksCount, &out.ReadyChecksCount
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMStorageRollingUpdateStrategyBehavior.
+func (in *VMStorageRollingUpdateStrategyBehavior) DeepCopy() *VMStorageRollingUpdateStrategyBehavior {
+	if in == nil {
+		return nil
+	}
+	out := new(VMStorageRollingUpdateStrategyBehavior)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VMUser) DeepCopyInto(out *VMUser) {
 	*out = *in
@@ -6433,6 +7726,21 @@ func (in *VMUserList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VMUserPasswordRotation) DeepCopyInto(out *VMUserPasswordRotation) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMUserPasswordRotation.
+func (in *VMUserPasswordRotation) DeepCopy() *VMUserPasswordRotation {
+	if in == nil {
+		return nil
+	}
+	out := new(VMUserPasswordRotation)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VMUserSpec) DeepCopyInto(out *VMUserSpec) {
 	*out = *in
@@ -6461,6 +7769,11 @@ func (in *VMUserSpec) DeepCopyInto(out *VMUserSpec) {
 		*out = new(v1.SecretKeySelector)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.PasswordRotation != nil {
+		in, out := &in.PasswordRotation, &out.PasswordRotation
+		*out = new(VMUserPasswordRotation)
+		**out = **in
+	}
 	if in.BearerToken != nil {
 		in, out := &in.BearerToken, &out.BearerToken
 		*out = new(string)
@@ -6497,6 +7810,10 @@ func (in *VMUserSpec) DeepCopy() *VMUserSpec {
 func (in *VMUserStatus) DeepCopyInto(out *VMUserStatus) {
 	*out = *in
 	in.StatusMetadata.DeepCopyInto(&out.StatusMetadata)
+	if in.LastPasswordRotationTime != nil {
+		in, out := &in.LastPasswordRotationTime, &out.LastPasswordRotationTime
+		*out = (*in).DeepCopy()
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMUserStatus.
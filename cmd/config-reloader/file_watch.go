@@ -2,9 +2,11 @@ package main
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
 	"crypto/sha256"
 	"fmt"
+	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
@@ -192,6 +194,11 @@ func (dw *dirWatcher) startWatch(ctx context.Context, updates chan struct{}) {
 		if bytes.Equal(prevHash, newHash) {
 			return false, nil
 		}
+		if *unpackDir != "" {
+			if err := unpackGzippedDir(eventPath, *unpackDir); err != nil {
+				return false, fmt.Errorf("cannot unpack dir: %s, err: %w", eventPath, err)
+			}
+		}
 		filesContentHashPath[eventPath] = newHash
 		logger.Infof("base dir: %s hash not the same, update needed", eventPath)
 		return true, nil
@@ -235,3 +242,71 @@ func (dw *dirWatcher) startWatch(ctx context.Context, updates chan struct{}) {
 func (dw *dirWatcher) close() {
 	dw.wg.Wait()
 }
+
+const gzippedFileSuffix = ".gz"
+
+// unpackGzippedDir decompresses gzipped files from srcDir into dstDir/base(srcDir)
+// files without .gz suffix are ignored
+// files, which are not present at srcDir anymore, are removed from destination
+func unpackGzippedDir(srcDir, dstDir string) error {
+	dst := filepath.Join(dstDir, filepath.Base(srcDir))
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return fmt.Errorf("cannot create dir for unpacked files: %w", err)
+	}
+	entries, err := os.ReadDir(srcDir)
+	if err != nil {
+		return fmt.Errorf("cannot read dir: %w", err)
+	}
+	unpackedFiles := make(map[string]struct{}, len(entries))
+	for _, e := range entries {
+		name := e.Name()
+		// hack for kubernetes configmaps.
+		if strings.HasPrefix(name, "..") || !strings.HasSuffix(name, gzippedFileSuffix) {
+			continue
+		}
+		// configmap files are symlinks, os.Stat follows it
+		f, err := os.Stat(filepath.Join(srcDir, name))
+		if err != nil {
+			return fmt.Errorf("cannot check file stat for path: %s, err: %w", name, err)
+		}
+		if f.IsDir() {
+			continue
+		}
+		data, err := readFileContent(filepath.Join(srcDir, name))
+		if err != nil {
+			return fmt.Errorf("cannot read file content: %w", err)
+		}
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return fmt.Errorf("cannot create gzip reader for file: %s, err: %w", name, err)
+		}
+		data, err = io.ReadAll(gz)
+		gz.Close()
+		if err != nil {
+			return fmt.Errorf("cannot ungzip file: %s, err: %w", name, err)
+		}
+		unpackedName := strings.TrimSuffix(name, gzippedFileSuffix)
+		dstPath := filepath.Join(dst, unpackedName)
+		tmpDst := dstPath + ".tmp"
+		if err := os.WriteFile(tmpDst, data, 0644); err != nil {
+			return fmt.Errorf("cannot write file: %s to the disk: %w", dstPath, err)
+		}
+		if err := os.Rename(tmpDst, dstPath); err != nil {
+			return fmt.Errorf("cannot rename tmp file: %w", err)
+		}
+		unpackedFiles[unpackedName] = struct{}{}
+	}
+	dstEntries, err := os.ReadDir(dst)
+	if err != nil {
+		return fmt.Errorf("cannot read dir with unpacked files: %w", err)
+	}
+	for _, e := range dstEntries {
+		if _, ok := unpackedFiles[e.Name()]; ok {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dst, e.Name())); err != nil {
+			return fmt.Errorf("cannot remove stale unpacked file: %w", err)
+		}
+	}
+	return nil
+}
@@ -45,6 +45,9 @@ var (
 		"watched-dir", "directory to watch non-recursively")
 	rulesDir = flagutil.NewArrayString(
 		"rules-dir", "the same as watched-dir, legacy")
+	unpackDir = flag.String(
+		"unpack-dir", "", "optional directory for unpacking gzip compressed files from watched-dir. "+
+			"Content of each watched-dir is written into sub-directory with the same base name. Compressed files must have .gz suffix")
 	reloadURL = flag.String(
 		"reload-url", "http://127.0.0.1:8429/-/reload", "reload URL to trigger config reload")
 	listenAddr = flag.String(
@@ -92,11 +95,25 @@ func main() {
 		logger.Fatalf("cannot create configWatcher: %s", err)
 	}
 
+	var dws []string
+	if len(*watchedDir) > 0 {
+		dws = *watchedDir
+	} else if len(*rulesDir) > 0 {
+		dws = *rulesDir
+	}
+
 	err = configWatcher.startWatch(ctx, updatesChan)
 	if *onlyInitConfig {
 		if err != nil {
 			logger.Fatalf("failed to init config: %v", err)
 		}
+		if *unpackDir != "" {
+			for _, dw := range dws {
+				if err := unpackGzippedDir(dw, *unpackDir); err != nil {
+					logger.Fatalf("failed to unpack dir: %s, err: %v", dw, err)
+				}
+			}
+		}
 		logger.Infof("config initiation succeed, exit now")
 		cancel()
 		configWatcher.close()
@@ -107,12 +124,6 @@ func main() {
 		reloader: r.reload,
 	}
 	watcher.start(ctx)
-	var dws []string
-	if len(*watchedDir) > 0 {
-		dws = *watchedDir
-	} else if len(*rulesDir) > 0 {
-		dws = *rulesDir
-	}
 
 	dw, err := newDirWatchers(dws)
 	if err != nil {
@@ -148,6 +148,12 @@ type BaseOperatorConf struct {
 		ConfigReloaderMemory string `default:"25Mi"`
 	}
 
+	VMProberDefault struct {
+		Image   string `default:"prom/blackbox-exporter"`
+		Version string `default:"v0.25.0"`
+		Port    string `default:"9115"`
+	}
+
 	VMSingleDefault struct {
 		Image   string `default:"victoriametrics/victoria-metrics"`
 		Version string `default:"v1.113.0"`
@@ -312,6 +318,12 @@ type BaseOperatorConf struct {
 	PodWaitReadyIntervalCheck time.Duration `default:"5s"`
 	// configures force resync interval for VMAgent, VMAlert, VMAlertmanager and VMAuth.
 	ForceResyncInterval time.Duration `default:"60s"`
+	// Defines number of concurrent workers for VMRules validation and rule files generation at VMAlert reconcile.
+	// GOMAXPROCS is used if set to 0
+	VMAlertRulesProcessingWorkers int `default:"0"`
+	// Disables VMAlertmanagerConfig receiver delivery test
+	// triggered by operator.victoriametrics.com/test-receiver annotation.
+	DisableAlertmanagerReceiverTest bool `default:"false"`
 	// EnableStrictSecurity will add default `securityContext` to pods and containers created by operator
 	// Default PodSecurityContext include:
 	// 1. RunAsNonRoot: true
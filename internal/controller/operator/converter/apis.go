@@ -36,16 +36,27 @@ func ConvertPromRule(prom *promv1.PrometheusRule, conf *config.BaseOperatorConf)
 			if promRuleItem.For != nil {
 				trule.For = string(*promRuleItem.For)
 			}
+			if promRuleItem.KeepFiringFor != nil {
+				trule.KeepFiringFor = string(*promRuleItem.KeepFiringFor)
+			}
 			ruleItems = append(ruleItems, trule)
 		}
 
 		tgroup := vmv1beta1.RuleGroup{
-			Name:  promGroup.Name,
-			Rules: ruleItems,
+			Name:   promGroup.Name,
+			Labels: promGroup.Labels,
+			Rules:  ruleItems,
 		}
 		if promGroup.Interval != nil {
 			tgroup.Interval = string(*promGroup.Interval)
 		}
+		// query_offset has the same meaning as vmalert eval_delay
+		if promGroup.QueryOffset != nil {
+			tgroup.EvalDelay = string(*promGroup.QueryOffset)
+		}
+		if promGroup.Limit != nil {
+			tgroup.Limit = *promGroup.Limit
+		}
 		ruleGroups = append(ruleGroups, tgroup)
 	}
 	cr := &vmv1beta1.VMRule{
@@ -9,6 +9,7 @@ import (
 	promv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/utils/ptr"
 )
 
@@ -519,3 +520,44 @@ func TestConvertProbe(t *testing.T) {
 		})
 	}
 }
+
+func TestConvertPromRule(t *testing.T) {
+	dur := func(d string) *promv1.Duration {
+		v := promv1.Duration(d)
+		return &v
+	}
+	keepFiringFor := promv1.NonEmptyDuration("5m")
+	prom := &promv1.PrometheusRule{
+		ObjectMeta: metav1.ObjectMeta{Name: "rule", Namespace: "default"},
+		Spec: promv1.PrometheusRuleSpec{Groups: []promv1.RuleGroup{{
+			Name:        "group",
+			Labels:      map[string]string{"team": "a"},
+			Interval:    dur("1m"),
+			QueryOffset: dur("30s"),
+			Limit:       ptr.To(10),
+			Rules: []promv1.Rule{{
+				Alert:         "down",
+				Expr:          intstr.FromString("up == 0"),
+				For:           dur("2m"),
+				KeepFiringFor: &keepFiringFor,
+			}},
+		}}},
+	}
+	got := ConvertPromRule(prom, &config.BaseOperatorConf{})
+	want := []vmv1beta1.RuleGroup{{
+		Name:      "group",
+		Labels:    map[string]string{"team": "a"},
+		Interval:  "1m",
+		EvalDelay: "30s",
+		Limit:     10,
+		Rules: []vmv1beta1.Rule{{
+			Alert:         "down",
+			Expr:          "up == 0",
+			For:           "2m",
+			KeepFiringFor: "5m",
+		}},
+	}}
+	if !reflect.DeepEqual(got.Spec.Groups, want) {
+		t.Errorf("ConvertPromRule() got = \n%v, \nwant \n%v", got.Spec.Groups, want)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	policyv1 "k8s.io/api/policy/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/utils/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -16,6 +17,7 @@ import (
 	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
 	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/build"
 	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/finalize"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/logger"
 	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/reconcile"
 )
 
@@ -69,6 +71,10 @@ func CreateOrUpdateAlertManager(ctx context.Context, cr *vmv1beta1.VMAlertmanage
 		}
 	}
 
+	if err := createOrUpdateVMUser(ctx, rclient, cr); err != nil {
+		return err
+	}
+
 	if cr.Spec.PodDisruptionBudget != nil {
 		var prevPDB *policyv1.PodDisruptionBudget
 		if prevCR != nil && prevCR.Spec.PodDisruptionBudget != nil {
@@ -78,6 +84,9 @@ func CreateOrUpdateAlertManager(ctx context.Context, cr *vmv1beta1.VMAlertmanage
 			return err
 		}
 	}
+	if err := createOrUpdateClusterTLSCertificate(ctx, rclient, cr, prevCR); err != nil {
+		return err
+	}
 	var prevSts *appsv1.StatefulSet
 	if prevCR != nil {
 		var err error
@@ -90,6 +99,17 @@ func CreateOrUpdateAlertManager(ctx context.Context, cr *vmv1beta1.VMAlertmanage
 	if err != nil {
 		return fmt.Errorf("cannot generate alertmanager sts, name: %s,err: %w", cr.Name, err)
 	}
+	checksum, issued, err := clusterTLSChecksum(ctx, rclient, cr)
+	if err != nil {
+		return err
+	}
+	if !issued {
+		// pods cannot start without gossip TLS certificate
+		// statefulset is updated on the Secret creation, which triggers VMAlertmanager reconcile
+		logger.WithContext(ctx).Info(fmt.Sprintf("cluster TLS Secret=%s isn't issued yet, postponing statefulset update", cr.GetClusterTLSSecretName()))
+		return nil
+	}
+	setClusterTLSChecksum(&newSts.Spec.Template, checksum)
 
 	stsOpts := reconcile.STSOptions{
 		HasClaim:       len(newSts.Spec.VolumeClaimTemplates) > 0,
@@ -118,6 +138,12 @@ func deletePrevStateResources(ctx context.Context, cr *vmv1beta1.VMAlertmanager,
 			return fmt.Errorf("cannot remove serviceScrape: %w", err)
 		}
 	}
+	if cr.Spec.ExposeViaVMAuth == nil && cr.ParsedLastAppliedSpec.ExposeViaVMAuth != nil {
+		// VMUser finalizer is removed by its own controller, which also cleans up VMAuth configuration
+		if err := rclient.Delete(ctx, &vmv1beta1.VMUser{ObjectMeta: objMeta}); err != nil && !k8serrors.IsNotFound(err) {
+			return fmt.Errorf("cannot remove VMUser for vmauth exposure: %w", err)
+		}
+	}
 
 	return nil
 }
@@ -349,8 +349,8 @@ func Test_createDefaultAMConfig(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			fclient := k8stools.GetTestClientWithObjects(tt.predefinedObjects)
-			if err := CreateOrUpdateConfig(tt.args.ctx, fclient, tt.args.cr, nil); (err != nil) != tt.wantErr {
+			fclient := k8stools.GetTestClientWithObjects(append(tt.predefinedObjects, tt.args.cr))
+			if err := CreateOrUpdateConfig(tt.args.ctx, fclient, tt.args.cr, nil, nil); (err != nil) != tt.wantErr {
 				t.Fatalf("createDefaultAMConfig() error = %v, wantErr %v", err, tt.wantErr)
 			}
 			var createdSecret corev1.Secret
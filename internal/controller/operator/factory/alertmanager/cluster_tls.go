@@ -0,0 +1,207 @@
+package alertmanager
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"path"
+	"strconv"
+
+	"gopkg.in/yaml.v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/reconcile"
+)
+
+const clusterTLSVolumeName = "cluster-tls"
+
+// clusterTLSSecretKeys defines keys of the Secret with gossip TLS certificate
+// it matches keys of the Secret generated by cert-manager
+var clusterTLSSecretKeys = []string{"ca.crt", "tls.crt", "tls.key"}
+
+func clusterTLSMountPath(cr *vmv1beta1.VMAlertmanager) string {
+	return path.Join(vmv1beta1.SecretsDir, cr.GetClusterTLSSecretName())
+}
+
+// clusterTLSServerName returns name of alertmanager service used for peers certificate verification
+// peers are dialed by ip addresses, so server_name must be set explicitly
+func clusterTLSServerName(cr *vmv1beta1.VMAlertmanager) string {
+	return fmt.Sprintf("%s.%s.svc", cr.PrefixedName(), cr.Namespace)
+}
+
+// addClusterTLSToPodSpec mounts gossip TLS certificate into alertmanager container
+func addClusterTLSToPodSpec(cr *vmv1beta1.VMAlertmanager, volumes []corev1.Volume, mounts []corev1.VolumeMount) ([]corev1.Volume, []corev1.VolumeMount) {
+	if cr.Spec.ClusterTLS == nil {
+		return volumes, mounts
+	}
+	volumes = append(volumes, corev1.Volume{
+		Name: clusterTLSVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{
+				SecretName: cr.GetClusterTLSSecretName(),
+			},
+		},
+	})
+	mounts = append(mounts, corev1.VolumeMount{
+		Name:      clusterTLSVolumeName,
+		ReadOnly:  true,
+		MountPath: clusterTLSMountPath(cr),
+	})
+	return volumes, mounts
+}
+
+// buildClusterTLSGossipConfig builds gossip mTLS configuration with mounted clusterTLS certificate
+// see https://prometheus.io/docs/alerting/latest/https/#gossip-traffic
+func buildClusterTLSGossipConfig(cr *vmv1beta1.VMAlertmanager) ([]byte, error) {
+	mountPath := clusterTLSMountPath(cr)
+	cfg := yaml.MapSlice{
+		{
+			Key: "tls_server_config",
+			Value: yaml.MapSlice{
+				{Key: "client_ca_file", Value: path.Join(mountPath, "ca.crt")},
+				{Key: "cert_file", Value: path.Join(mountPath, "tls.crt")},
+				{Key: "key_file", Value: path.Join(mountPath, "tls.key")},
+				{Key: "client_auth_type", Value: "RequireAndVerifyClientCert"},
+			},
+		},
+		{
+			Key: "tls_client_config",
+			Value: yaml.MapSlice{
+				{Key: "ca_file", Value: path.Join(mountPath, "ca.crt")},
+				{Key: "cert_file", Value: path.Join(mountPath, "tls.crt")},
+				{Key: "key_file", Value: path.Join(mountPath, "tls.key")},
+				{Key: "server_name", Value: clusterTLSServerName(cr)},
+			},
+		},
+	}
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("cannot serialize alertmanager gossip config as yaml: %w", err)
+	}
+	return data, nil
+}
+
+// clusterTLSChecksum returns checksum of the gossip TLS certificate
+// it's set at pod template annotations in order to trigger rolling restart on certificate rotation,
+// since alertmanager reads gossip TLS configuration only at start.
+// It returns false, if certificate wasn't issued yet
+func clusterTLSChecksum(ctx context.Context, rclient client.Client, cr *vmv1beta1.VMAlertmanager) (string, bool, error) {
+	if cr.Spec.ClusterTLS == nil {
+		return "", true, nil
+	}
+	var secret corev1.Secret
+	nsn := types.NamespacedName{Namespace: cr.Namespace, Name: cr.GetClusterTLSSecretName()}
+	if err := rclient.Get(ctx, nsn, &secret); err != nil {
+		if errors.IsNotFound(err) {
+			return "", false, nil
+		}
+		return "", false, fmt.Errorf("cannot get cluster TLS Secret=%s: %w", nsn.Name, err)
+	}
+	h := fnv.New64a()
+	for _, k := range clusterTLSSecretKeys {
+		v, ok := secret.Data[k]
+		if !ok {
+			return "", false, nil
+		}
+		h.Write([]byte(k)) //nolint:errcheck
+		h.Write(v)         //nolint:errcheck
+	}
+	return strconv.FormatUint(h.Sum64(), 16), true, nil
+}
+
+// setClusterTLSChecksum sets gossip TLS certificate checksum at the given pod template
+func setClusterTLSChecksum(tmpl *corev1.PodTemplateSpec, checksum string) {
+	if checksum == "" {
+		return
+	}
+	if tmpl.Annotations == nil {
+		tmpl.Annotations = make(map[string]string)
+	}
+	tmpl.Annotations[vmv1beta1.VMAlertmanagerClusterTLSChecksumAnnotation] = checksum
+}
+
+// clusterTLSDNSNames returns dns names of alertmanager pods
+func clusterTLSDNSNames(cr *vmv1beta1.VMAlertmanager) []any {
+	serviceName := cr.PrefixedName()
+	names := []any{
+		serviceName,
+		fmt.Sprintf("%s.%s", serviceName, cr.Namespace),
+		fmt.Sprintf("%s.%s.svc", serviceName, cr.Namespace),
+		fmt.Sprintf("*.%s", serviceName),
+		fmt.Sprintf("*.%s.%s", serviceName, cr.Namespace),
+		fmt.Sprintf("*.%s.%s.svc", serviceName, cr.Namespace),
+	}
+	if cr.Spec.ClusterDomainName != "" {
+		names = append(names,
+			fmt.Sprintf("%s.%s.svc.%s", serviceName, cr.Namespace, cr.Spec.ClusterDomainName),
+			fmt.Sprintf("*.%s.%s.svc.%s", serviceName, cr.Namespace, cr.Spec.ClusterDomainName),
+		)
+	}
+	return names
+}
+
+// buildClusterTLSCertificate builds cert-manager Certificate for alertmanager pods
+func buildClusterTLSCertificate(cr *vmv1beta1.VMAlertmanager) *unstructured.Unstructured {
+	if cr.Spec.ClusterTLS == nil || cr.Spec.ClusterTLS.IssuerRef == nil {
+		return nil
+	}
+	tls := cr.Spec.ClusterTLS
+	issuerRef := map[string]any{
+		"name": tls.IssuerRef.Name,
+	}
+	if tls.IssuerRef.Kind != "" {
+		issuerRef["kind"] = tls.IssuerRef.Kind
+	}
+	if tls.IssuerRef.Group != "" {
+		issuerRef["group"] = tls.IssuerRef.Group
+	}
+	spec := map[string]any{
+		"secretName": cr.GetClusterTLSSecretName(),
+		"issuerRef":  issuerRef,
+		"commonName": cr.PrefixedName(),
+		"dnsNames":   clusterTLSDNSNames(cr),
+		"usages":     []any{"digital signature", "key encipherment", "server auth", "client auth"},
+	}
+	if tls.Duration != "" {
+		spec["duration"] = tls.Duration
+	}
+	if tls.RenewBefore != "" {
+		spec["renewBefore"] = tls.RenewBefore
+	}
+	cert := &unstructured.Unstructured{Object: map[string]any{"spec": spec}}
+	cert.SetGroupVersionKind(reconcile.CertificateGVK)
+	cert.SetName(cr.GetClusterTLSSecretName())
+	cert.SetNamespace(cr.Namespace)
+	cert.SetLabels(cr.AllLabels())
+	cert.SetOwnerReferences(cr.AsOwner())
+	return cert
+}
+
+// createOrUpdateClusterTLSCertificate reconciles cert-manager Certificate for gossip TLS
+// and removes Certificate, which is no longer needed
+func createOrUpdateClusterTLSCertificate(ctx context.Context, rclient client.Client, cr, prevCR *vmv1beta1.VMAlertmanager) error {
+	newCert := buildClusterTLSCertificate(cr)
+	if newCert != nil {
+		if err := reconcile.Certificate(ctx, rclient, newCert); err != nil {
+			return err
+		}
+	}
+	if prevCR == nil {
+		return nil
+	}
+	prevCert := buildClusterTLSCertificate(prevCR)
+	if prevCert == nil || (newCert != nil && newCert.GetName() == prevCert.GetName()) {
+		return nil
+	}
+	return reconcile.RemoveCertificate(ctx, rclient, prevCert.GetNamespace(), prevCert.GetName())
+}
+
+// IsClusterTLSSecret checks if the given Secret holds gossip TLS certificate of the alertmanager
+func IsClusterTLSSecret(cr *vmv1beta1.VMAlertmanager, secretName string) bool {
+	return cr.Spec.ClusterTLS != nil && cr.GetClusterTLSSecretName() == secretName
+}
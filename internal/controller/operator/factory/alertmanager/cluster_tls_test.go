@@ -0,0 +1,150 @@
+package alertmanager
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+
+	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/k8stools"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/reconcile"
+)
+
+func TestClusterTLSStatefulSet(t *testing.T) {
+	cr := &vmv1beta1.VMAlertmanager{
+		ObjectMeta: metav1.ObjectMeta{Name: "am", Namespace: "default"},
+		Spec: vmv1beta1.VMAlertmanagerSpec{
+			CommonApplicationDeploymentParams: vmv1beta1.CommonApplicationDeploymentParams{
+				ReplicaCount: ptr.To(int32(2)),
+			},
+			ClusterTLS: &vmv1beta1.AlertmanagerClusterTLS{SecretName: "am-tls"},
+		},
+	}
+	spec, err := makeStatefulSetSpec(cr)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	podSpec := spec.Template.Spec
+	assert.Contains(t, podSpec.Containers[0].Args, "--cluster.tls-config=/etc/alertmanager/tls_assets/gossip_config.yaml")
+	assert.Contains(t, podSpec.Containers[0].Args, "--cluster.peer=vmalertmanager-am-1.vmalertmanager-am:9094")
+	assert.Contains(t, podSpec.Containers[0].VolumeMounts, corev1.VolumeMount{
+		Name:      "cluster-tls",
+		ReadOnly:  true,
+		MountPath: "/etc/vm/secrets/am-tls",
+	})
+	assert.Contains(t, podSpec.Volumes, corev1.Volume{
+		Name: "cluster-tls",
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{SecretName: "am-tls"},
+		},
+	})
+
+	cfg, err := buildGossipConfigYAML(context.TODO(), k8stools.GetTestClientWithObjects(nil), cr, map[string]string{})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.Equal(t, `tls_server_config:
+  client_ca_file: /etc/vm/secrets/am-tls/ca.crt
+  cert_file: /etc/vm/secrets/am-tls/tls.crt
+  key_file: /etc/vm/secrets/am-tls/tls.key
+  client_auth_type: RequireAndVerifyClientCert
+tls_client_config:
+  ca_file: /etc/vm/secrets/am-tls/ca.crt
+  cert_file: /etc/vm/secrets/am-tls/tls.crt
+  key_file: /etc/vm/secrets/am-tls/tls.key
+  server_name: vmalertmanager-am.default.svc
+`, string(cfg))
+}
+
+func TestClusterTLSChecksum(t *testing.T) {
+	cr := &vmv1beta1.VMAlertmanager{
+		ObjectMeta: metav1.ObjectMeta{Name: "am", Namespace: "default"},
+		Spec: vmv1beta1.VMAlertmanagerSpec{
+			ClusterTLS: &vmv1beta1.AlertmanagerClusterTLS{SecretName: "am-tls"},
+		},
+	}
+	ctx := context.TODO()
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "am-tls", Namespace: "default"},
+		Data:       map[string][]byte{"ca.crt": []byte("ca"), "tls.crt": []byte("cert"), "tls.key": []byte("key")},
+	}
+	fclient := k8stools.GetTestClientWithObjects(nil)
+	// certificate isn't issued yet
+	_, issued, err := clusterTLSChecksum(ctx, fclient, cr)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.False(t, issued)
+	if err := fclient.Create(ctx, secret); err != nil {
+		t.Fatalf("cannot create secret: %s", err)
+	}
+	checksum, issued, err := clusterTLSChecksum(ctx, fclient, cr)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.True(t, issued)
+	assert.NotEmpty(t, checksum)
+
+	// rotated certificate must change checksum
+	secret.Data["tls.crt"] = []byte("rotated-cert")
+	if err := fclient.Update(ctx, secret); err != nil {
+		t.Fatalf("cannot update secret: %s", err)
+	}
+	rotatedChecksum, _, err := clusterTLSChecksum(ctx, fclient, cr)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.NotEqual(t, checksum, rotatedChecksum)
+
+	assert.True(t, IsClusterTLSSecret(cr, "am-tls"))
+	assert.False(t, IsClusterTLSSecret(cr, "other"))
+}
+
+func TestCreateOrUpdateClusterTLSCertificate(t *testing.T) {
+	cr := &vmv1beta1.VMAlertmanager{
+		ObjectMeta: metav1.ObjectMeta{Name: "am", Namespace: "default"},
+		Spec: vmv1beta1.VMAlertmanagerSpec{
+			ClusterTLS: &vmv1beta1.AlertmanagerClusterTLS{
+				IssuerRef: &vmv1beta1.VMClusterCertManagerIssuerRef{Name: "ca-issuer", Kind: "ClusterIssuer"},
+				Duration:  "2160h",
+			},
+		},
+	}
+	ctx := context.TODO()
+	fclient := k8stools.GetTestClientWithObjects([]runtime.Object{})
+	getCert := func(name string) (*unstructured.Unstructured, error) {
+		cert := &unstructured.Unstructured{}
+		cert.SetGroupVersionKind(reconcile.CertificateGVK)
+		err := fclient.Get(ctx, types.NamespacedName{Namespace: "default", Name: name}, cert)
+		return cert, err
+	}
+	if err := createOrUpdateClusterTLSCertificate(ctx, fclient, cr, nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	cert, err := getCert("vmalertmanager-am-cluster-tls")
+	if err != nil {
+		t.Fatalf("cannot get certificate: %s", err)
+	}
+	dnsNames, _, _ := unstructured.NestedStringSlice(cert.Object, "spec", "dnsNames")
+	assert.Contains(t, dnsNames, "vmalertmanager-am.default.svc")
+	assert.Contains(t, dnsNames, "*.vmalertmanager-am")
+	issuerKind, _, _ := unstructured.NestedString(cert.Object, "spec", "issuerRef", "kind")
+	assert.Equal(t, "ClusterIssuer", issuerKind)
+
+	// certificate must be removed on switch to pre-provisioned secret
+	prevCR := cr.DeepCopy()
+	cr.Spec.ClusterTLS = &vmv1beta1.AlertmanagerClusterTLS{SecretName: "am-tls"}
+	if err := createOrUpdateClusterTLSCertificate(ctx, fclient, cr, prevCR); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := getCert("vmalertmanager-am-cluster-tls"); err == nil {
+		t.Fatalf("certificate must be removed")
+	}
+}
@@ -5,12 +5,14 @@ import (
 	"fmt"
 	"net/url"
 	"path"
+	"slices"
 	"sort"
 	"strings"
 
 	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
 	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/build"
 	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/k8stools"
+	amconfig "github.com/prometheus/alertmanager/config"
 	"gopkg.in/yaml.v2"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
@@ -37,31 +39,22 @@ func buildConfig(ctx context.Context, rclient client.Client, alertmanagerCR *vmv
 		return nil, fmt.Errorf("cannot parse base cfg :%w", err)
 	}
 
+	baseReceivers := make(map[string]struct{}, len(baseYAMlCfg.Receivers))
+	for _, recv := range baseYAMlCfg.Receivers {
+		recvName, err := receiverName(recv)
+		if err != nil {
+			return nil, fmt.Errorf("incorrect base configuration=%q: %w", string(baseCfg), err)
+		}
+		baseReceivers[recvName] = struct{}{}
+	}
+
 	if baseYAMlCfg.Route == nil {
 		baseYAMlCfg.Route = &route{
 			Receiver: "blackhole",
 		}
-		var isBlackholeDefined bool
-		for _, recv := range baseYAMlCfg.Receivers {
-			var recvName string
-			for _, entry := range recv {
-				if entry.Key == "name" {
-					s, ok := entry.Value.(string)
-					if !ok {
-						return nil, fmt.Errorf("incorrect base configuration=%q, expected receiver name=%v to be a string", string(baseCfg), entry.Value)
-					}
-					recvName = s
-					break
-				}
-			}
-			if recvName == "blackhole" {
-				isBlackholeDefined = true
-				break
-			}
-		}
 		// conditionally add blackhole as default route path
 		// alertmanager config must have some default route
-		if !isBlackholeDefined {
+		if _, ok := baseReceivers["blackhole"]; !ok {
 			baseYAMlCfg.Receivers = append(baseYAMlCfg.Receivers, yaml.MapSlice{
 				{
 					Key:   "name",
@@ -97,6 +90,13 @@ OUTER:
 				continue OUTER
 			}
 			if len(receiverCfg) > 0 {
+				// generated receiver must not silently shadow receiver of base configuration
+				recvName, _ := receiverName(receiverCfg)
+				if _, ok := baseReceivers[recvName]; ok {
+					result.brokenAMCfgs = append(result.brokenAMCfgs, amcKey)
+					amcKey.Status.CurrentSyncError = fmt.Sprintf("generated receiver name=%q is already defined at base configuration", recvName)
+					continue OUTER
+				}
 				receiverCfgs = append(receiverCfgs, receiverCfg)
 			}
 		}
@@ -116,10 +116,20 @@ OUTER:
 			continue
 		}
 
-		baseYAMlCfg.Receivers = append(baseYAMlCfg.Receivers, receiverCfgs...)
+		var inhibitRules []yaml.MapSlice
 		for _, rule := range amcKey.Spec.InhibitRules {
-			baseYAMlCfg.InhibitRules = append(baseYAMlCfg.InhibitRules, buildInhibitRule(amcKey.Namespace, rule, !alertmanagerCR.Spec.DisableNamespaceMatcher))
+			inhibitRules = append(inhibitRules, buildInhibitRule(amcKey.Namespace, rule, !alertmanagerCR.Spec.DisableNamespaceMatcher))
 		}
+		if !build.MustSkipRuntimeValidation {
+			if err := validateConfigFragment(&globalConfigOpts, route, receiverCfgs, inhibitRules, mtis); err != nil {
+				result.brokenAMCfgs = append(result.brokenAMCfgs, amcKey)
+				amcKey.Status.CurrentSyncError = err.Error()
+				continue
+			}
+		}
+
+		baseYAMlCfg.Receivers = append(baseYAMlCfg.Receivers, receiverCfgs...)
+		baseYAMlCfg.InhibitRules = append(baseYAMlCfg.InhibitRules, inhibitRules...)
 		if len(mtis) > 0 {
 			timeIntervals = append(timeIntervals, mtis...)
 		}
@@ -130,7 +140,12 @@ OUTER:
 	amcfgs = amcfgs[:cnt]
 
 	if len(subRoutes) > 0 {
-		baseYAMlCfg.Route.Routes = append(baseYAMlCfg.Route.Routes, subRoutes...)
+		if alertmanagerCR.Spec.ConfigSecret != "" && alertmanagerCR.Spec.ConfigSecretMergeStrategy == vmv1beta1.VMAlertmanagerConfigSecretMergeStrategyOverride {
+			// routes of VMAlertmanagerConfigs must take precedence over routes of configSecret
+			baseYAMlCfg.Route.Routes = append(subRoutes, baseYAMlCfg.Route.Routes...)
+		} else {
+			baseYAMlCfg.Route.Routes = append(baseYAMlCfg.Route.Routes, subRoutes...)
+		}
 	}
 	if len(timeIntervals) > 0 {
 		baseYAMlCfg.TimeIntervals = append(baseYAMlCfg.TimeIntervals, timeIntervals...)
@@ -145,6 +160,120 @@ OUTER:
 	return &result, nil
 }
 
+// validateConfigFragment renders configuration generated for a single VMAlertmanagerConfig into isolated
+// alertmanager configuration and checks it with upstream config parser.
+// It allows to exclude broken object from the final configuration instead of breaking it for all objects
+func validateConfigFragment(globalCfg *globalAlertmanagerConfig, subRoute yaml.MapSlice, receivers, inhibitRules, timeIntervals []yaml.MapSlice) error {
+	fragment := alertmanagerConfig{
+		Global: globalCfg.Global,
+		Route: &route{
+			Receiver: "blackhole",
+			Routes:   []yaml.MapSlice{subRoute},
+		},
+		Receivers:     append(receivers[:len(receivers):len(receivers)], yaml.MapSlice{{Key: "name", Value: "blackhole"}}),
+		InhibitRules:  inhibitRules,
+		TimeIntervals: timeIntervals,
+	}
+	data, err := yaml.Marshal(fragment)
+	if err != nil {
+		return fmt.Errorf("cannot marshal alertmanager config fragment: %w", err)
+	}
+	if _, err := amconfig.Load(string(data)); err != nil {
+		return fmt.Errorf("alertmanager config validation failed: %w", err)
+	}
+	return nil
+}
+
+// addGlobalConfig builds global section from VMAlertmanager spec.globalConfig and adds it to the given base config.
+// Default route and receiver are used, if base config is empty
+func addGlobalConfig(ctx context.Context, rclient client.Client, cr *vmv1beta1.VMAlertmanager, baseCfg []byte, tlsAssets map[string]string) ([]byte, error) {
+	gc := cr.Spec.GlobalConfig
+	if gc == nil {
+		return baseCfg, nil
+	}
+	if len(cr.Spec.ConfigSecret) > 0 {
+		return nil, fmt.Errorf("spec.globalConfig cannot be used together with spec.configSecret=%q, global section must be defined at configSecret", cr.Spec.ConfigSecret)
+	}
+	var cfg yaml.MapSlice
+	if len(baseCfg) > 0 {
+		if err := yaml.Unmarshal(baseCfg, &cfg); err != nil {
+			return nil, fmt.Errorf("cannot parse base cfg: %w", err)
+		}
+		for _, item := range cfg {
+			if item.Key == "global" {
+				return nil, fmt.Errorf("spec.globalConfig cannot be used together with global section defined at spec.configRawYaml")
+			}
+		}
+	}
+	if len(cfg) == 0 {
+		cfg = yaml.MapSlice{
+			{Key: "route", Value: yaml.MapSlice{{Key: "receiver", Value: "blackhole"}}},
+			{Key: "receivers", Value: []yaml.MapSlice{{{Key: "name", Value: "blackhole"}}}},
+		}
+	}
+	cb := &configBuilder{
+		TLSConfigBuilder: build.TLSConfigBuilder{
+			Ctx:                ctx,
+			Client:             rclient,
+			CurrentCRName:      cr.Name,
+			CurrentCRNamespace: cr.Namespace,
+			SecretCache:        make(map[string]*corev1.Secret),
+			ConfigmapCache:     make(map[string]*corev1.ConfigMap),
+			TLSAssets:          tlsAssets,
+		},
+	}
+	var global yaml.MapSlice
+	toYaml := func(key string, src string) {
+		if len(src) > 0 {
+			global = append(global, yaml.MapItem{Key: key, Value: src})
+		}
+	}
+	toYaml("resolve_timeout", gc.ResolveTimeout)
+	if gc.HTTPConfig != nil {
+		c, err := cb.buildHTTPConfig(gc.HTTPConfig)
+		if err != nil {
+			return nil, fmt.Errorf("cannot build global http_config: %w", err)
+		}
+		global = append(global, yaml.MapItem{Key: "http_config", Value: c})
+	}
+	toYaml("smtp_from", gc.SMTPFrom)
+	toYaml("smtp_hello", gc.SMTPHello)
+	toYaml("smtp_smarthost", gc.SMTPSmarthost)
+	toYaml("smtp_auth_username", gc.SMTPAuthUsername)
+	if gc.SMTPAuthPassword != nil {
+		s, err := cb.fetchSecretValue(gc.SMTPAuthPassword)
+		if err != nil {
+			return nil, fmt.Errorf("cannot fetch global smtp_auth_password: %w", err)
+		}
+		toYaml("smtp_auth_password", s)
+	}
+	if gc.SMTPAuthSecret != nil {
+		s, err := cb.fetchSecretValue(gc.SMTPAuthSecret)
+		if err != nil {
+			return nil, fmt.Errorf("cannot fetch global smtp_auth_secret: %w", err)
+		}
+		toYaml("smtp_auth_secret", s)
+	}
+	toYaml("smtp_auth_identity", gc.SMTPAuthIdentity)
+	if gc.SMTPRequireTLS != nil {
+		global = append(global, yaml.MapItem{Key: "smtp_require_tls", Value: *gc.SMTPRequireTLS})
+	}
+	if gc.SlackAPIURL != nil {
+		s, err := cb.fetchSecretValue(gc.SlackAPIURL)
+		if err != nil {
+			return nil, fmt.Errorf("cannot fetch global slack_api_url: %w", err)
+		}
+		if err := parseURL(s); err != nil {
+			return nil, fmt.Errorf("invalid global slack_api_url in key %s from secret %s: %w", gc.SlackAPIURL.Key, gc.SlackAPIURL.Name, err)
+		}
+		toYaml("slack_api_url", s)
+	}
+	if len(global) > 0 {
+		cfg = append(yaml.MapSlice{{Key: "global", Value: global}}, cfg...)
+	}
+	return yaml.Marshal(cfg)
+}
+
 // addConfigTemplates adds external templates to the given based configuration
 func addConfigTemplates(baseCfg []byte, templates []string) ([]byte, error) {
 	if len(templates) == 0 {
@@ -185,10 +314,8 @@ func buildGlobalTimeIntervals(cr *vmv1beta1.VMAlertmanagerConfig) ([]yaml.MapSli
 			return r, fmt.Errorf("got duplicate timeInterval name %s", mti.Name)
 		}
 		timeIntervalNameList[mti.Name] = struct{}{}
-		if len(mti.TimeIntervals) == 0 {
-			continue
-		}
-		var temp []yaml.MapSlice
+		// interval without entries must be defined too, since it could be referenced by routes
+		temp := []yaml.MapSlice{}
 		var tiItem yaml.MapSlice
 		toYaml := func(key string, src []string) {
 			if len(src) > 0 {
@@ -218,9 +345,7 @@ func buildGlobalTimeIntervals(cr *vmv1beta1.VMAlertmanagerConfig) ([]yaml.MapSli
 				temp = append(temp, tiItem)
 			}
 		}
-		if len(temp) > 0 {
-			r = append(r, yaml.MapSlice{{Key: "name", Value: buildCRPrefixedName(cr, mti.Name)}, {Key: "time_intervals", Value: temp}})
-		}
+		r = append(r, yaml.MapSlice{{Key: "name", Value: buildCRPrefixedName(cr, mti.Name)}, {Key: "time_intervals", Value: temp}})
 	}
 	return r, nil
 }
@@ -236,7 +361,7 @@ func buildRoute(cr *vmv1beta1.VMAlertmanagerConfig, cfgRoute *vmv1beta1.Route, t
 			continueSetting = true
 		}
 		if !alertmanagerCR.Spec.DisableNamespaceMatcher {
-			matchers = append(matchers, fmt.Sprintf("namespace = %q", cr.Namespace))
+			matchers = append(matchers, buildNamespaceMatcher(cr.Namespace))
 		}
 		if len(alertmanagerCR.Spec.EnforcedTopRouteMatchers) > 0 {
 			matchers = append(matchers, alertmanagerCR.Spec.EnforcedTopRouteMatchers...)
@@ -291,12 +416,22 @@ func buildRoute(cr *vmv1beta1.VMAlertmanagerConfig, cfgRoute *vmv1beta1.Route, t
 	return r, nil
 }
 
+// buildNamespaceMatcher returns matcher, which limits routes and inhibit rules of VMAlertmanagerConfig
+// to alerts from its namespace
+func buildNamespaceMatcher(namespace string) string {
+	return fmt.Sprintf("namespace = %q", namespace)
+}
+
+// buildInhibitRule converts VMAlertmanagerConfig inhibit rule into alertmanager configuration
+// namespace matcher is added to both source and target matchers,
+// so inhibit rule cannot mute alerts from other namespaces
 func buildInhibitRule(namespace string, rule vmv1beta1.InhibitRule, mustAddNamespaceMatcher bool) yaml.MapSlice {
 	var r yaml.MapSlice
 	if mustAddNamespaceMatcher {
-		namespaceMatch := fmt.Sprintf("namespace = %q", namespace)
-		rule.SourceMatchers = append(rule.SourceMatchers, namespaceMatch)
-		rule.TargetMatchers = append(rule.TargetMatchers, namespaceMatch)
+		namespaceMatch := buildNamespaceMatcher(namespace)
+		// clone matchers in order to not modify VMAlertmanagerConfig spec
+		rule.SourceMatchers = append(slices.Clone(rule.SourceMatchers), namespaceMatch)
+		rule.TargetMatchers = append(slices.Clone(rule.TargetMatchers), namespaceMatch)
 	}
 	toYaml := func(key string, src []string) {
 		if len(src) > 0 {
@@ -312,6 +447,20 @@ func buildInhibitRule(namespace string, rule vmv1beta1.InhibitRule, mustAddNames
 	return r
 }
 
+// receiverName returns name of the given receiver configuration
+func receiverName(recv yaml.MapSlice) (string, error) {
+	for _, entry := range recv {
+		if entry.Key == "name" {
+			s, ok := entry.Value.(string)
+			if !ok {
+				return "", fmt.Errorf("expected receiver name=%v to be a string", entry.Value)
+			}
+			return s, nil
+		}
+	}
+	return "", nil
+}
+
 func buildCRPrefixedName(cr *vmv1beta1.VMAlertmanagerConfig, name string) string {
 	return fmt.Sprintf("%s-%s-%s", cr.Namespace, cr.Name, name)
 }
@@ -547,6 +696,7 @@ func (cb *configBuilder) buildTeams(ms vmv1beta1.MSTeamsConfig) error {
 		}
 	}
 	toYaml("title", ms.Title)
+	toYaml("summary", ms.Summary)
 	toYaml("text", ms.Text)
 
 	cb.currentYaml = append(cb.currentYaml, temp)
@@ -585,6 +735,9 @@ func (cb *configBuilder) buildDiscord(dc vmv1beta1.DiscordConfig) error {
 	}
 	toYaml("title", dc.Title)
 	toYaml("message", dc.Message)
+	toYaml("content", dc.Content)
+	toYaml("username", dc.Username)
+	toYaml("avatar_url", dc.AvatarURL)
 
 	cb.currentYaml = append(cb.currentYaml, temp)
 	return nil
@@ -1058,6 +1211,7 @@ func (cb *configBuilder) buildPagerDuty(pd vmv1beta1.PagerDutyConfig) error {
 	toYaml("component", pd.Component)
 	toYaml("group", pd.Group)
 	toYaml("severity", pd.Severity)
+	toYaml("source", pd.Source)
 	var images []yaml.MapSlice
 	for _, image := range pd.Images {
 		var imageYAML yaml.MapSlice
@@ -1065,7 +1219,7 @@ func (cb *configBuilder) buildPagerDuty(pd vmv1beta1.PagerDutyConfig) error {
 			imageYAML = append(imageYAML, yaml.MapItem{Key: "href", Value: image.Href})
 		}
 		if len(image.Source) > 0 {
-			imageYAML = append(imageYAML, yaml.MapItem{Key: "source", Value: image.Source})
+			imageYAML = append(imageYAML, yaml.MapItem{Key: "src", Value: image.Source})
 		}
 		if len(image.Alt) > 0 {
 			imageYAML = append(imageYAML, yaml.MapItem{Key: "alt", Value: image.Alt})
@@ -1198,7 +1352,7 @@ func (cb *configBuilder) buildOpsGenie(og vmv1beta1.OpsGenieConfig) error {
 	toYamlString("note", og.Note)
 	toYamlString("api_url", og.APIURL)
 	toYamlString("entity", og.Entity)
-	toYamlString("Actions", og.Actions)
+	toYamlString("actions", og.Actions)
 
 	if og.APIURL != "" {
 		err := parseURL(og.APIURL)
@@ -1249,7 +1403,7 @@ func (cb *configBuilder) buildOpsGenie(og vmv1beta1.OpsGenieConfig) error {
 
 func (cb *configBuilder) fetchSecretValue(selector *corev1.SecretKeySelector) (string, error) {
 	tcb := cb.TLSConfigBuilder
-	return k8stools.GetCredFromSecret(tcb.Ctx, tcb.Client, tcb.CurrentCRNamespace, selector, selector.Name, tcb.SecretCache)
+	return k8stools.GetCredFromSecret(tcb.Ctx, tcb.Client, tcb.CurrentCRNamespace, selector, tcb.CacheKey(selector.Name), tcb.SecretCache)
 }
 
 func (cb *configBuilder) buildHTTPConfig(httpCfg *vmv1beta1.HTTPConfig) (yaml.MapSlice, error) {
@@ -1461,6 +1615,9 @@ func secretSelectorToAssetKey(selector *corev1.SecretKeySelector) string {
 
 // builds configuration according to https://prometheus.io/docs/alerting/latest/https/#gossip-traffic
 func buildGossipConfigYAML(ctx context.Context, rclient client.Client, vmaCR *vmv1beta1.VMAlertmanager, tlsAssets map[string]string) ([]byte, error) {
+	if vmaCR.Spec.ClusterTLS != nil {
+		return buildClusterTLSGossipConfig(vmaCR)
+	}
 	if vmaCR.Spec.GossipConfig == nil {
 		return nil, nil
 	}
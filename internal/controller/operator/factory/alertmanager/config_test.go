@@ -5,6 +5,7 @@ import (
 	"os"
 	"testing"
 
+	amconfig "github.com/prometheus/alertmanager/config"
 	"github.com/stretchr/testify/assert"
 	"gopkg.in/yaml.v2"
 	corev1 "k8s.io/api/core/v1"
@@ -41,7 +42,7 @@ func TestBuildConfig(t *testing.T) {
 				amCR: &vmv1beta1.VMAlertmanager{
 					Spec: vmv1beta1.VMAlertmanagerSpec{
 						EnforcedTopRouteMatchers: []string{
-							`env=~{"dev|prod"}`,
+							`env=~"dev|prod"`,
 							`pod!=""`,
 						},
 					},
@@ -69,6 +70,7 @@ func TestBuildConfig(t *testing.T) {
 											Smarthost:    "some:443",
 											TLSConfig: &vmv1beta1.TLSConfig{
 												CertFile: "some_cert_path",
+												KeyFile:  "some_key_path",
 											},
 										},
 									},
@@ -84,6 +86,7 @@ func TestBuildConfig(t *testing.T) {
 											Smarthost:    "some:443",
 											TLSConfig: &vmv1beta1.TLSConfig{
 												CertFile: "some_cert_path",
+												KeyFile:  "some_key_path",
 											},
 										},
 									},
@@ -91,16 +94,16 @@ func TestBuildConfig(t *testing.T) {
 							},
 							Route: &vmv1beta1.Route{
 								Receiver:  "email",
-								GroupWait: "1min",
+								GroupWait: "1m",
 								Routes: []*vmv1beta1.SubRoute{
 									{
 										Receiver:  "email-sub-1",
-										GroupWait: "5min",
+										GroupWait: "5m",
 										Matchers:  []string{"team=prod"},
 										Routes: []*vmv1beta1.SubRoute{
 											{
 												Receiver:  "email",
-												GroupWait: "10min",
+												GroupWait: "10m",
 												Matchers:  []string{"pod=dev-env"},
 											},
 										},
@@ -121,19 +124,19 @@ route:
     - routes:
       - matchers:
         - pod=dev-env
-        group_wait: 10min
+        group_wait: 10m
         receiver: default-base-email
         continue: false
       matchers:
       - team=prod
-      group_wait: 5min
+      group_wait: 5m
       receiver: default-base-email-sub-1
       continue: false
     matchers:
     - namespace = "default"
-    - env=~{"dev|prod"}
+    - env=~"dev|prod"
     - pod!=""
-    group_wait: 1min
+    group_wait: 1m
     receiver: default-base-email
     continue: true
 receivers:
@@ -142,6 +145,7 @@ receivers:
   email_configs:
   - tls_config:
       cert_file: some_cert_path
+      key_file: some_key_path
     from: some-sender
     text: some-text
     to: some-dst-1
@@ -151,6 +155,7 @@ receivers:
   email_configs:
   - tls_config:
       cert_file: some_cert_path
+      key_file: some_key_path
     from: some-sender
     text: some-text
     to: some-dst-1
@@ -187,6 +192,7 @@ templates: []
 											Smarthost:    "some:443",
 											TLSConfig: &vmv1beta1.TLSConfig{
 												CertFile: "some_cert_path",
+												KeyFile:  "some_key_path",
 											},
 										},
 										{
@@ -198,6 +204,7 @@ templates: []
 											RequireTLS:   ptr.To(false),
 											TLSConfig: &vmv1beta1.TLSConfig{
 												CertFile: "some_cert_path",
+												KeyFile:  "some_key_path",
 											},
 										},
 										{
@@ -209,6 +216,7 @@ templates: []
 											RequireTLS:   ptr.To(true),
 											TLSConfig: &vmv1beta1.TLSConfig{
 												CertFile: "some_cert_path",
+												KeyFile:  "some_key_path",
 											},
 										},
 
@@ -224,7 +232,7 @@ templates: []
 							},
 							Route: &vmv1beta1.Route{
 								Receiver:  "email",
-								GroupWait: "1min",
+								GroupWait: "1m",
 							},
 						},
 					},
@@ -238,7 +246,7 @@ route:
   routes:
   - matchers:
     - namespace = "default"
-    group_wait: 1min
+    group_wait: 1m
     receiver: default-base-email
     continue: true
 receivers:
@@ -247,6 +255,7 @@ receivers:
   email_configs:
   - tls_config:
       cert_file: some_cert_path
+      key_file: some_key_path
     from: some-sender
     text: some-text
     to: some-dst-1
@@ -255,6 +264,7 @@ receivers:
   - require_tls: false
     tls_config:
       cert_file: some_cert_path
+      key_file: some_key_path
     from: some-sender
     text: some-text
     to: some-dst-2
@@ -263,6 +273,7 @@ receivers:
   - require_tls: true
     tls_config:
       cert_file: some_cert_path
+      key_file: some_key_path
     from: some-sender
     text: some-text
     to: some-dst-3
@@ -323,7 +334,7 @@ templates: []
 							},
 							Route: &vmv1beta1.Route{
 								Receiver:  "email",
-								GroupWait: "1min",
+								GroupWait: "1m",
 								Routes: []*vmv1beta1.SubRoute{
 									{
 										Receiver: "webhook",
@@ -355,7 +366,7 @@ templates: []
 												{
 													Name:     "n",
 													Username: "f",
-													Type:     "some-type",
+													Type:     "team",
 												},
 											},
 										},
@@ -380,7 +391,7 @@ route:
       continue: false
     matchers:
     - namespace = "default"
-    group_wait: 1min
+    group_wait: 1m
     receiver: default-base-email
     continue: true
   - matchers:
@@ -423,7 +434,7 @@ receivers:
     responders:
     - name: "n"
       username: f
-      type: some-type
+      type: team
 templates: []
 `,
 		},
@@ -459,7 +470,7 @@ templates: []
 							},
 							Route: &vmv1beta1.Route{
 								Receiver:  "webhook",
-								GroupWait: "1min",
+								GroupWait: "1m",
 							},
 						},
 					},
@@ -483,7 +494,7 @@ route:
   routes:
   - matchers:
     - namespace = "default"
-    group_wait: 1min
+    group_wait: 1m
     receiver: default-base-webhook
     continue: true
 receivers:
@@ -529,6 +540,7 @@ templates: []
 											Username:     "some-user",
 											Actions: []vmv1beta1.SlackAction{
 												{
+													Type: "button",
 													Name: "deny",
 													Text: "text-5",
 													URL:  "some-url",
@@ -541,6 +553,7 @@ templates: []
 												{
 													Short: ptr.To(true),
 													Title: "fields",
+													Value: "value",
 												},
 											},
 										},
@@ -549,7 +562,7 @@ templates: []
 							},
 							Route: &vmv1beta1.Route{
 								Receiver:  "slack",
-								GroupWait: "1min",
+								GroupWait: "1m",
 							},
 						},
 					},
@@ -573,7 +586,7 @@ route:
   routes:
   - matchers:
     - namespace = "default"
-    group_wait: 1min
+    group_wait: 1m
     receiver: default-base-slack
     continue: true
 receivers:
@@ -591,10 +604,12 @@ receivers:
     - name: deny
       text: text-5
       url: some-url
+      type: button
       confirm:
         text: confirmed
     fields:
-    - title: fields
+    - value: value
+      title: fields
       short: true
 templates: []
 `,
@@ -657,7 +672,7 @@ templates: []
 							},
 							Route: &vmv1beta1.Route{
 								Receiver:  "pagerduty",
-								GroupWait: "1min",
+								GroupWait: "1m",
 							},
 						},
 					},
@@ -676,7 +691,7 @@ route:
   routes:
   - matchers:
     - namespace = "default"
-    group_wait: 1min
+    group_wait: 1m
     receiver: default-base-pagerduty
     continue: true
 receivers:
@@ -689,7 +704,7 @@ receivers:
     severity: warning
     images:
     - href: http://some-href
-      source: http://some-source
+      src: http://some-source
       alt: some-alt-text
     links:
     - href: http://some-href
@@ -752,7 +767,7 @@ templates: []
 							},
 							Route: &vmv1beta1.Route{
 								Receiver:  "telegram",
-								GroupWait: "1min",
+								GroupWait: "1m",
 							},
 						},
 					},
@@ -765,7 +780,7 @@ route:
   routes:
   - matchers:
     - namespace = "default"
-    group_wait: 1min
+    group_wait: 1m
     receiver: default-tg-telegram
     continue: true
 receivers:
@@ -811,7 +826,7 @@ templates: []
 							},
 							Route: &vmv1beta1.Route{
 								Receiver:  "slack",
-								GroupWait: "1min",
+								GroupWait: "1m",
 							},
 						},
 					},
@@ -839,7 +854,7 @@ templates: []
 `,
 		},
 		{
-			name: "telegram bad, not strict parse",
+			name: "telegram bad, missing bot token",
 			args: args{
 				ctx: context.Background(),
 				baseCfg: []byte(`global:
@@ -872,7 +887,7 @@ templates: []
 							},
 							Route: &vmv1beta1.Route{
 								Receiver:  "telegram",
-								GroupWait: "1min",
+								GroupWait: "1m",
 							},
 						},
 					},
@@ -896,30 +911,19 @@ templates: []
 							},
 							Route: &vmv1beta1.Route{
 								Receiver:  "telegram",
-								GroupWait: "1min",
+								GroupWait: "1m",
 							},
 						},
 					},
 				},
 			},
-			parseError: `unable to fetch key from secret: "tg-secret" for object: "tg-secret" : secrets "tg-secret" not found`,
+			parseError: `unable to fetch key from secret: "tg-secret" for object: "default/tg-secret" : secrets "tg-secret" not found`,
 			want: `global:
   time_out: 1min
 route:
   receiver: blackhole
-  routes:
-  - matchers:
-    - namespace = "default"
-    group_wait: 1min
-    receiver: default-tg-telegram
-    continue: true
 receivers:
 - name: blackhole
-- name: default-tg-telegram
-  telegram_configs:
-  - send_resolved: true
-    chat_id: 125
-    message: some-templated message
 templates: []
 `,
 		},
@@ -1175,7 +1179,7 @@ func Test_configBuilder_buildHTTPConfig(t *testing.T) {
 				},
 			},
 			fields: fields{secretCache: map[string]*corev1.Secret{
-				"secret-store": {
+				"default/secret-store": {
 					Data: map[string][]byte{
 						"username": []byte("user-1"),
 					},
@@ -1214,7 +1218,7 @@ func Test_configBuilder_buildHTTPConfig(t *testing.T) {
 				},
 			},
 			fields: fields{secretCache: map[string]*corev1.Secret{
-				"secret-store": {
+				"default/secret-store": {
 					Data: map[string][]byte{
 						"cert": []byte("---PEM---"),
 						"ca":   []byte("---PEM-CA"),
@@ -1269,20 +1273,20 @@ authorization:
 			},
 			fields: fields{
 				secretCache: map[string]*corev1.Secret{
-					"secret-store": {
+					"default/secret-store": {
 						Data: map[string][]byte{
 							"cert": []byte("---PEM---"),
 							"key":  []byte("--KEY-PEM--"),
 						},
 					},
-					"secret-bearer": {
+					"default/secret-bearer": {
 						Data: map[string][]byte{
 							"token": []byte("secret-token"),
 						},
 					},
 				},
 				configmapCache: map[string]*corev1.ConfigMap{
-					"cm-store": {
+					"default/cm-store": {
 						Data: map[string]string{
 							"ca": "--CA-PEM--",
 						},
@@ -1339,7 +1343,7 @@ authorization:
 			},
 			fields: fields{
 				secretCache: map[string]*corev1.Secret{
-					"secret-store": {
+					"default/secret-store": {
 						Data: map[string][]byte{
 							"client-secret": []byte("value"),
 						},
@@ -1388,7 +1392,7 @@ authorization:
 			},
 			fields: fields{
 				secretCache: map[string]*corev1.Secret{
-					"secret-store": {
+					"default/secret-store": {
 						Data: map[string][]byte{
 							"client-secret": []byte("value"),
 							"client-id":     []byte("client-value"),
@@ -1515,10 +1519,10 @@ func Test_UpdateDefaultAMConfig(t *testing.T) {
 	assert.Nil(t, os.Setenv("WATCH_NAMESPACE", "default"))
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			fclient := k8stools.GetTestClientWithObjects(tt.predefinedObjects)
+			fclient := k8stools.GetTestClientWithObjects(append(tt.predefinedObjects, tt.args.cr))
 
 			// Create secret with alert manager config
-			if err := CreateOrUpdateConfig(tt.args.ctx, fclient, tt.args.cr, nil); (err != nil) != tt.wantErr {
+			if err := CreateOrUpdateConfig(tt.args.ctx, fclient, tt.args.cr, nil, nil); (err != nil) != tt.wantErr {
 				t.Fatalf("createDefaultAMConfig() error = %v, wantErr %v", err, tt.wantErr)
 			}
 			var amCfgs []*vmv1beta1.VMAlertmanagerConfig
@@ -1581,7 +1585,7 @@ func Test_UpdateDefaultAMConfig(t *testing.T) {
 			}
 
 			// Update secret with alert manager config
-			if err = CreateOrUpdateConfig(tt.args.ctx, fclient, tt.args.cr, nil); (err != nil) != tt.wantErr {
+			if err = CreateOrUpdateConfig(tt.args.ctx, fclient, tt.args.cr, nil, nil); (err != nil) != tt.wantErr {
 				t.Fatalf("createDefaultAMConfig() error = %v, wantErr %v", err, tt.wantErr)
 			}
 
@@ -1855,3 +1859,757 @@ tls_client_config:
 		})
 	}
 }
+
+func TestBuildConfigReceiversValidation(t *testing.T) {
+	secretKey := func(key string) *corev1.SecretKeySelector {
+		return &corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "receiver-secrets"},
+			Key:                  key,
+		}
+	}
+	f := func(receiver vmv1beta1.Receiver, wantErr string) {
+		t.Helper()
+		receiver.Name = "receiver"
+		amcfg := &vmv1beta1.VMAlertmanagerConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "amc", Namespace: "default"},
+			Spec: vmv1beta1.VMAlertmanagerConfigSpec{
+				Receivers: []vmv1beta1.Receiver{receiver},
+				Route:     &vmv1beta1.Route{Receiver: "receiver"},
+			},
+		}
+		fclient := k8stools.GetTestClientWithObjects([]runtime.Object{
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "receiver-secrets", Namespace: "default"},
+				Data: map[string][]byte{
+					"url":   []byte("https://receiver.example.com/hook"),
+					"token": []byte("secret-token"),
+					"key":   []byte("secret-key"),
+				},
+			},
+		})
+		got, err := buildConfig(context.Background(), fclient, &vmv1beta1.VMAlertmanager{}, nil, []*vmv1beta1.VMAlertmanagerConfig{amcfg}, map[string]string{})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if len(wantErr) == 0 {
+			if len(got.brokenAMCfgs) > 0 {
+				t.Fatalf("unexpected broken config: %s", got.brokenAMCfgs[0].Status.CurrentSyncError)
+			}
+			assert.Len(t, got.amcfgs, 1)
+			return
+		}
+		if assert.Len(t, got.brokenAMCfgs, 1) {
+			assert.Contains(t, got.brokenAMCfgs[0].Status.CurrentSyncError, wantErr)
+		}
+		assert.NotContains(t, string(got.data), "default-amc-receiver")
+	}
+
+	// email
+	f(vmv1beta1.Receiver{EmailConfigs: []vmv1beta1.EmailConfig{{To: "ops@example.com", From: "am@example.com", Smarthost: "smtp.example.com:587"}}}, "")
+	f(vmv1beta1.Receiver{EmailConfigs: []vmv1beta1.EmailConfig{{From: "am@example.com", Smarthost: "smtp.example.com:587"}}}, "missing to address in email config")
+
+	// slack
+	f(vmv1beta1.Receiver{SlackConfigs: []vmv1beta1.SlackConfig{{APIURL: secretKey("url"), Channel: "#alerts"}}}, "")
+	f(vmv1beta1.Receiver{SlackConfigs: []vmv1beta1.SlackConfig{{APIURL: secretKey("url"), Fields: []vmv1beta1.SlackField{{Title: "title"}}}}}, "missing value in Slack field configuration")
+
+	// pagerduty
+	f(vmv1beta1.Receiver{PagerDutyConfigs: []vmv1beta1.PagerDutyConfig{{RoutingKey: secretKey("key")}}}, "")
+	f(vmv1beta1.Receiver{PagerDutyConfigs: []vmv1beta1.PagerDutyConfig{{RoutingKey: secretKey("key"), Images: []vmv1beta1.ImageConfig{{Source: "https://img.example.com", Href: "https://example.com"}}}}}, "")
+	f(vmv1beta1.Receiver{PagerDutyConfigs: []vmv1beta1.PagerDutyConfig{{}}}, "missing service or routing key in PagerDuty config")
+
+	// opsgenie
+	f(vmv1beta1.Receiver{OpsGenieConfigs: []vmv1beta1.OpsGenieConfig{{APIKey: secretKey("key"), Responders: []vmv1beta1.OpsGenieConfigResponder{{Name: "ops", Type: "team"}}}}}, "")
+	f(vmv1beta1.Receiver{OpsGenieConfigs: []vmv1beta1.OpsGenieConfig{{APIKey: secretKey("key"), Responders: []vmv1beta1.OpsGenieConfigResponder{{Name: "ops", Type: "unknown"}}}}}, "type does not match valid options")
+
+	// pushover
+	f(vmv1beta1.Receiver{PushoverConfigs: []vmv1beta1.PushoverConfig{{UserKey: secretKey("key"), Token: secretKey("token")}}}, "")
+	f(vmv1beta1.Receiver{PushoverConfigs: []vmv1beta1.PushoverConfig{{UserKey: secretKey("key")}}}, "one of token or token_file must be configured")
+
+	// victorops
+	f(vmv1beta1.Receiver{VictorOpsConfigs: []vmv1beta1.VictorOpsConfig{{APIKey: secretKey("key"), RoutingKey: "ops"}}}, "")
+	f(vmv1beta1.Receiver{VictorOpsConfigs: []vmv1beta1.VictorOpsConfig{{APIKey: secretKey("key"), RoutingKey: "ops", CustomFields: map[string]string{"entity_id": "id"}}}}, "victorOps config contains custom field entity_id which cannot be used as it conflicts with the fixed/static fields")
+
+	// wechat
+	f(vmv1beta1.Receiver{WeChatConfigs: []vmv1beta1.WeChatConfig{{APISecret: secretKey("key"), CorpID: "corp"}}}, "")
+	f(vmv1beta1.Receiver{WeChatConfigs: []vmv1beta1.WeChatConfig{{APISecret: secretKey("key"), CorpID: "corp", MessageType: "html"}}}, "weChat message type \"html\" does not match valid options")
+
+	// webhook
+	f(vmv1beta1.Receiver{WebhookConfigs: []vmv1beta1.WebhookConfig{{URL: ptr.To("http://webhook.example.com")}}}, "")
+	f(vmv1beta1.Receiver{WebhookConfigs: []vmv1beta1.WebhookConfig{{URL: ptr.To("ftp://webhook.example.com")}}}, "unsupported scheme \"ftp\" for URL")
+
+	// telegram
+	f(vmv1beta1.Receiver{TelegramConfigs: []vmv1beta1.TelegramConfig{{BotToken: secretKey("token"), ChatID: 125}}}, "")
+	f(vmv1beta1.Receiver{TelegramConfigs: []vmv1beta1.TelegramConfig{{BotToken: secretKey("token"), ChatID: 125, ParseMode: "Text"}}}, "unknown parse_mode on telegram_config")
+
+	// msteams
+	f(vmv1beta1.Receiver{MSTeamsConfigs: []vmv1beta1.MSTeamsConfig{{URL: ptr.To("https://teams.example.com/hook")}}}, "")
+	f(vmv1beta1.Receiver{MSTeamsConfigs: []vmv1beta1.MSTeamsConfig{{URLSecret: secretKey("url"), Title: "title", Summary: "summary", Text: "text"}}}, "")
+	f(vmv1beta1.Receiver{MSTeamsConfigs: []vmv1beta1.MSTeamsConfig{{URL: ptr.To("teams.example.com/hook")}}}, "unsupported scheme \"\" for URL")
+
+	// discord
+	f(vmv1beta1.Receiver{DiscordConfigs: []vmv1beta1.DiscordConfig{{URLSecret: secretKey("url")}}}, "")
+	f(vmv1beta1.Receiver{DiscordConfigs: []vmv1beta1.DiscordConfig{{URLSecret: secretKey("url"), Content: "{{ .CommonLabels.alertname }}", Username: "alertmanager", AvatarURL: "https://avatar.example.com/am.png"}}}, "")
+	f(vmv1beta1.Receiver{DiscordConfigs: []vmv1beta1.DiscordConfig{{URL: ptr.To("discord.example.com/hook")}}}, "unsupported scheme \"\" for URL")
+
+	// sns
+	f(vmv1beta1.Receiver{SNSConfigs: []vmv1beta1.SnsConfig{{TopicArn: "arn:aws:sns:us-east-1:123456789012:alerts"}}}, "")
+	f(vmv1beta1.Receiver{SNSConfigs: []vmv1beta1.SnsConfig{{Subject: "alerts"}}}, "must provide either a Target ARN, Topic ARN, or Phone Number for SNS config")
+
+	// webex
+	f(vmv1beta1.Receiver{WebexConfigs: []vmv1beta1.WebexConfig{{RoomId: "room", HTTPConfig: &vmv1beta1.HTTPConfig{BearerTokenSecret: secretKey("token")}}}}, "")
+	f(vmv1beta1.Receiver{WebexConfigs: []vmv1beta1.WebexConfig{{RoomId: "room"}}}, "missing webex_configs.http_config.authorization")
+}
+
+func TestBuildConfigChatReceivers(t *testing.T) {
+	amcfg := &vmv1beta1.VMAlertmanagerConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "chat", Namespace: "default"},
+		Spec: vmv1beta1.VMAlertmanagerConfigSpec{
+			Route: &vmv1beta1.Route{Receiver: "chat"},
+			Receivers: []vmv1beta1.Receiver{{
+				Name: "chat",
+				MSTeamsConfigs: []vmv1beta1.MSTeamsConfig{{
+					URLSecret: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "chat-secrets"}, Key: "teams"},
+					Title:     "title",
+					Summary:   "summary",
+					Text:      "text",
+				}},
+				TelegramConfigs: []vmv1beta1.TelegramConfig{{
+					BotToken:  &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "chat-secrets"}, Key: "telegram"},
+					ChatID:    125,
+					Message:   "message",
+					ParseMode: "HTML",
+				}},
+				WebexConfigs: []vmv1beta1.WebexConfig{{
+					RoomId:  "room",
+					Message: "message",
+					HTTPConfig: &vmv1beta1.HTTPConfig{
+						Authorization: &vmv1beta1.Authorization{Credentials: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "chat-secrets"}, Key: "webex"}},
+					},
+				}},
+				DiscordConfigs: []vmv1beta1.DiscordConfig{{
+					URLSecret: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "chat-secrets"}, Key: "discord"},
+					Title:     "title",
+					Message:   "message",
+					Content:   "content",
+					Username:  "alertmanager",
+					AvatarURL: "https://avatar.example.com/am.png",
+				}},
+			}},
+		},
+	}
+	fclient := k8stools.GetTestClientWithObjects([]runtime.Object{
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "chat-secrets", Namespace: "default"},
+			Data: map[string][]byte{
+				"teams":    []byte("https://teams.example.com/hook"),
+				"telegram": []byte("telegram-token"),
+				"webex":    []byte("webex-token"),
+				"discord":  []byte("https://discord.example.com/hook"),
+			},
+		},
+	})
+	got, err := buildConfig(context.Background(), fclient, &vmv1beta1.VMAlertmanager{}, nil, []*vmv1beta1.VMAlertmanagerConfig{amcfg}, map[string]string{})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(got.brokenAMCfgs) > 0 {
+		t.Fatalf("unexpected broken config: %s", got.brokenAMCfgs[0].Status.CurrentSyncError)
+	}
+	assert.Equal(t, `route:
+  receiver: blackhole
+  routes:
+  - matchers:
+    - namespace = "default"
+    receiver: default-chat-chat
+    continue: true
+receivers:
+- name: blackhole
+- name: default-chat-chat
+  telegram_configs:
+  - bot_token: telegram-token
+    chat_id: 125
+    message: message
+    parse_mode: HTML
+  msteams_configs:
+  - webhook_url: https://teams.example.com/hook
+    title: title
+    summary: summary
+    text: text
+  discord_configs:
+  - webhook_url: https://discord.example.com/hook
+    title: title
+    message: message
+    content: content
+    username: alertmanager
+    avatar_url: https://avatar.example.com/am.png
+  webex_configs:
+  - http_config:
+      authorization:
+        credentials: webex-token
+    room_id: room
+    message: message
+templates: []
+`, string(got.data))
+}
+
+func TestAddGlobalConfig(t *testing.T) {
+	f := func(spec vmv1beta1.VMAlertmanagerSpec, baseCfg, want, wantErr string) {
+		t.Helper()
+		cr := &vmv1beta1.VMAlertmanager{
+			ObjectMeta: metav1.ObjectMeta{Name: "base", Namespace: "default"},
+			Spec:       spec,
+		}
+		fclient := k8stools.GetTestClientWithObjects([]runtime.Object{
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "global-secrets", Namespace: "default"},
+				Data: map[string][]byte{
+					"password": []byte("smtp-password"),
+					"slack":    []byte("https://hooks.slack.com/services/abc"),
+				},
+			},
+		})
+		got, err := addGlobalConfig(context.Background(), fclient, cr, []byte(baseCfg), map[string]string{})
+		if len(wantErr) > 0 {
+			if err == nil {
+				t.Fatalf("expected error: %q", wantErr)
+			}
+			assert.Contains(t, err.Error(), wantErr)
+			return
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		assert.Equal(t, want, string(got))
+	}
+	secretKey := func(key string) *corev1.SecretKeySelector {
+		return &corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "global-secrets"},
+			Key:                  key,
+		}
+	}
+
+	// global config is not defined
+	f(vmv1beta1.VMAlertmanagerSpec{}, "route:\n  receiver: blackhole\n", "route:\n  receiver: blackhole\n", "")
+
+	// empty base config
+	f(vmv1beta1.VMAlertmanagerSpec{
+		GlobalConfig: &vmv1beta1.AlertmanagerGlobalConfig{
+			ResolveTimeout:   "10m",
+			SMTPFrom:         "alertmanager@example.com",
+			SMTPSmarthost:    "smtp.example.com:587",
+			SMTPAuthUsername: "alertmanager",
+			SMTPAuthPassword: secretKey("password"),
+			SMTPRequireTLS:   ptr.To(true),
+			SlackAPIURL:      secretKey("slack"),
+			HTTPConfig: &vmv1beta1.HTTPConfig{
+				ProxyURL: "http://proxy.example.com:8080",
+			},
+		},
+	}, "", `global:
+  resolve_timeout: 10m
+  http_config:
+    proxy_url: http://proxy.example.com:8080
+  smtp_from: alertmanager@example.com
+  smtp_smarthost: smtp.example.com:587
+  smtp_auth_username: alertmanager
+  smtp_auth_password: smtp-password
+  smtp_require_tls: true
+  slack_api_url: https://hooks.slack.com/services/abc
+route:
+  receiver: blackhole
+receivers:
+- name: blackhole
+`, "")
+
+	// raw config without global section
+	f(vmv1beta1.VMAlertmanagerSpec{
+		ConfigRawYaml: "route:\n  receiver: webhook\nreceivers:\n- name: webhook\n  webhook_configs:\n  - url: http://webhook\n",
+		GlobalConfig:  &vmv1beta1.AlertmanagerGlobalConfig{ResolveTimeout: "1m"},
+	}, "route:\n  receiver: webhook\nreceivers:\n- name: webhook\n  webhook_configs:\n  - url: http://webhook\n", `global:
+  resolve_timeout: 1m
+route:
+  receiver: webhook
+receivers:
+- name: webhook
+  webhook_configs:
+  - url: http://webhook
+`, "")
+
+	// conflict with global section at raw config
+	f(vmv1beta1.VMAlertmanagerSpec{
+		GlobalConfig: &vmv1beta1.AlertmanagerGlobalConfig{ResolveTimeout: "1m"},
+	}, "global:\n  resolve_timeout: 5m\n", "", "cannot be used together with global section defined at spec.configRawYaml")
+
+	// conflict with config secret
+	f(vmv1beta1.VMAlertmanagerSpec{
+		ConfigSecret: "user-config",
+		GlobalConfig: &vmv1beta1.AlertmanagerGlobalConfig{ResolveTimeout: "1m"},
+	}, "", "", "cannot be used together with spec.configSecret")
+
+	// missing secret
+	f(vmv1beta1.VMAlertmanagerSpec{
+		GlobalConfig: &vmv1beta1.AlertmanagerGlobalConfig{SMTPAuthPassword: &corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "missing"},
+			Key:                  "password",
+		}},
+	}, "", "", "cannot fetch global smtp_auth_password")
+}
+
+func TestBuildConfigTimeIntervals(t *testing.T) {
+	f := func(amcfgs []*vmv1beta1.VMAlertmanagerConfig, want string, wantBroken []string) {
+		t.Helper()
+		fclient := k8stools.GetTestClientWithObjects(nil)
+		got, err := buildConfig(context.Background(), fclient, &vmv1beta1.VMAlertmanager{}, nil, amcfgs, map[string]string{})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		var gotBroken []string
+		for _, amc := range got.brokenAMCfgs {
+			gotBroken = append(gotBroken, amc.Status.CurrentSyncError)
+		}
+		assert.Equal(t, wantBroken, gotBroken)
+		assert.Equal(t, want, string(got.data))
+	}
+	businessHours := func(namespace string) *vmv1beta1.VMAlertmanagerConfig {
+		return &vmv1beta1.VMAlertmanagerConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "ops", Namespace: namespace},
+			Spec: vmv1beta1.VMAlertmanagerConfigSpec{
+				Receivers: []vmv1beta1.Receiver{{Name: "blackhole"}},
+				TimeIntervals: []vmv1beta1.TimeIntervals{
+					{
+						Name: "business-hours",
+						TimeIntervals: []vmv1beta1.TimeInterval{{
+							Times:    []vmv1beta1.TimeRange{{StartTime: "09:00", EndTime: "18:00"}},
+							Weekdays: []string{"monday:friday"},
+							Location: "Europe/Berlin",
+						}},
+					},
+					{
+						Name: "holidays",
+						TimeIntervals: []vmv1beta1.TimeInterval{{
+							DaysOfMonth: []string{"1"},
+							Months:      []string{"january"},
+							Years:       []string{"2025:2030"},
+						}},
+					},
+					{Name: "never"},
+				},
+				Route: &vmv1beta1.Route{
+					Receiver:            "blackhole",
+					ActiveTimeIntervals: []string{"business-hours"},
+					Routes: []*vmv1beta1.SubRoute{{
+						Receiver:          "blackhole",
+						MuteTimeIntervals: []string{"holidays", "never"},
+					}},
+				},
+			},
+		}
+	}
+
+	// intervals with the same names at different namespaces
+	f([]*vmv1beta1.VMAlertmanagerConfig{businessHours("default"), businessHours("monitoring")}, `route:
+  receiver: blackhole
+  routes:
+  - routes:
+    - mute_time_intervals:
+      - default-ops-holidays
+      - default-ops-never
+      receiver: default-ops-blackhole
+      continue: false
+    matchers:
+    - namespace = "default"
+    active_time_intervals:
+    - default-ops-business-hours
+    receiver: default-ops-blackhole
+    continue: true
+  - routes:
+    - mute_time_intervals:
+      - monitoring-ops-holidays
+      - monitoring-ops-never
+      receiver: monitoring-ops-blackhole
+      continue: false
+    matchers:
+    - namespace = "monitoring"
+    active_time_intervals:
+    - monitoring-ops-business-hours
+    receiver: monitoring-ops-blackhole
+    continue: true
+receivers:
+- name: blackhole
+- name: default-ops-blackhole
+- name: monitoring-ops-blackhole
+time_intervals:
+- name: default-ops-business-hours
+  time_intervals:
+  - weekdays:
+    - monday:friday
+    location: Europe/Berlin
+    times:
+    - start_time: "09:00"
+      end_time: "18:00"
+- name: default-ops-holidays
+  time_intervals:
+  - days_of_month:
+    - "1"
+    months:
+    - january
+    years:
+    - 2025:2030
+- name: default-ops-never
+  time_intervals: []
+- name: monitoring-ops-business-hours
+  time_intervals:
+  - weekdays:
+    - monday:friday
+    location: Europe/Berlin
+    times:
+    - start_time: "09:00"
+      end_time: "18:00"
+- name: monitoring-ops-holidays
+  time_intervals:
+  - days_of_month:
+    - "1"
+    months:
+    - january
+    years:
+    - 2025:2030
+- name: monitoring-ops-never
+  time_intervals: []
+templates: []
+`, nil)
+
+	// reference to interval defined at another object
+	crossRef := businessHours("default")
+	crossRef.Name = "cross-ref"
+	crossRef.Spec.TimeIntervals = nil
+	f([]*vmv1beta1.VMAlertmanagerConfig{crossRef}, `route:
+  receiver: blackhole
+receivers:
+- name: blackhole
+templates: []
+`, []string{`alertmanager config validation failed: undefined time interval "default-cross-ref-holidays" used in route`})
+}
+
+func TestBuildConfigInhibitRulesNamespaceScoping(t *testing.T) {
+	// matches reports whether alert with given labels satisfies all matchers
+	matches := func(ms amconfig.Matchers, alert map[string]string) bool {
+		for _, m := range ms {
+			if !m.Matches(alert[m.Name]) {
+				return false
+			}
+		}
+		return true
+	}
+	type opts struct {
+		disableNamespaceMatcher bool
+		targetAlert             map[string]string
+		wantInhibited           bool
+	}
+	f := func(o opts) {
+		t.Helper()
+		amc := &vmv1beta1.VMAlertmanagerConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "team", Namespace: "team-a"},
+			Spec: vmv1beta1.VMAlertmanagerConfigSpec{
+				Receivers: []vmv1beta1.Receiver{{Name: "blackhole"}},
+				Route:     &vmv1beta1.Route{Receiver: "blackhole"},
+				InhibitRules: []vmv1beta1.InhibitRule{{
+					SourceMatchers: []string{`severity = "critical"`},
+					TargetMatchers: []string{`severity = "warning"`},
+					Equal:          []string{"alertname"},
+				}},
+			},
+		}
+		cr := &vmv1beta1.VMAlertmanager{
+			Spec: vmv1beta1.VMAlertmanagerSpec{DisableNamespaceMatcher: o.disableNamespaceMatcher},
+		}
+		fclient := k8stools.GetTestClientWithObjects(nil)
+		got, err := buildConfig(context.Background(), fclient, cr, nil, []*vmv1beta1.VMAlertmanagerConfig{amc}, map[string]string{})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		assert.Empty(t, got.brokenAMCfgs)
+		// spec of VMAlertmanagerConfig must be kept as is
+		assert.Equal(t, []string{`severity = "critical"`}, amc.Spec.InhibitRules[0].SourceMatchers)
+		assert.Equal(t, []string{`severity = "warning"`}, amc.Spec.InhibitRules[0].TargetMatchers)
+
+		parsed, err := amconfig.Load(string(got.data))
+		if err != nil {
+			t.Fatalf("cannot parse generated config: %s", err)
+		}
+		if len(parsed.InhibitRules) != 1 {
+			t.Fatalf("unexpected number of inhibit rules, want 1, got %d", len(parsed.InhibitRules))
+		}
+		rule := parsed.InhibitRules[0]
+		sourceAlert := map[string]string{"alertname": "HighLatency", "severity": "critical", "namespace": "team-a"}
+		if !matches(rule.SourceMatchers, sourceAlert) {
+			t.Fatalf("source alert from VMAlertmanagerConfig namespace must match source matchers")
+		}
+		assert.Equal(t, o.wantInhibited, matches(rule.TargetMatchers, o.targetAlert))
+	}
+
+	// alert from the same namespace
+	f(opts{
+		targetAlert:   map[string]string{"alertname": "HighLatency", "severity": "warning", "namespace": "team-a"},
+		wantInhibited: true,
+	})
+
+	// alert from another namespace
+	f(opts{
+		targetAlert:   map[string]string{"alertname": "HighLatency", "severity": "warning", "namespace": "team-b"},
+		wantInhibited: false,
+	})
+
+	// alert without namespace label
+	f(opts{
+		targetAlert:   map[string]string{"alertname": "HighLatency", "severity": "warning"},
+		wantInhibited: false,
+	})
+
+	// namespace matcher is disabled
+	f(opts{
+		disableNamespaceMatcher: true,
+		targetAlert:             map[string]string{"alertname": "HighLatency", "severity": "warning", "namespace": "team-b"},
+		wantInhibited:           true,
+	})
+}
+
+func TestBuildConfigSecretMergeStrategy(t *testing.T) {
+	baseCfg := []byte(`route:
+  receiver: default
+  routes:
+  - receiver: default
+    matchers:
+    - severity = "info"
+receivers:
+- name: default
+- name: team-a-conflict-email
+`)
+	f := func(strategy string, amcfgs []*vmv1beta1.VMAlertmanagerConfig, want string, wantBroken []string) {
+		t.Helper()
+		cr := &vmv1beta1.VMAlertmanager{
+			ObjectMeta: metav1.ObjectMeta{Name: "am", Namespace: "default"},
+			Spec: vmv1beta1.VMAlertmanagerSpec{
+				ConfigSecret:              "user-config",
+				ConfigSecretMergeStrategy: strategy,
+				SelectAllByDefault:        true,
+			},
+		}
+		var predefinedObjects []runtime.Object
+		for _, amcfg := range amcfgs {
+			predefinedObjects = append(predefinedObjects, amcfg)
+		}
+		fclient := k8stools.GetTestClientWithObjects(predefinedObjects)
+		got, err := buildAlertmanagerConfigWithCRDs(context.Background(), fclient, cr, baseCfg, map[string]string{})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		var gotBroken []string
+		for _, amc := range got.brokenAMCfgs {
+			gotBroken = append(gotBroken, amc.Status.CurrentSyncError)
+		}
+		assert.Equal(t, wantBroken, gotBroken)
+		assert.Equal(t, want, string(got.data))
+	}
+	amcfg := func(name string) *vmv1beta1.VMAlertmanagerConfig {
+		return &vmv1beta1.VMAlertmanagerConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "team-a"},
+			Spec: vmv1beta1.VMAlertmanagerConfigSpec{
+				Route:     &vmv1beta1.Route{Receiver: "email"},
+				Receivers: []vmv1beta1.Receiver{{Name: "email"}},
+			},
+		}
+	}
+
+	// base strategy, routes of VMAlertmanagerConfigs are added after base routes
+	f("", []*vmv1beta1.VMAlertmanagerConfig{amcfg("ops")}, `route:
+  receiver: default
+  routes:
+  - receiver: default
+    matchers:
+    - severity = "info"
+  - matchers:
+    - namespace = "team-a"
+    receiver: team-a-ops-email
+    continue: true
+receivers:
+- name: default
+- name: team-a-conflict-email
+- name: team-a-ops-email
+templates: []
+`, nil)
+
+	// override strategy, routes of VMAlertmanagerConfigs take precedence
+	f("override", []*vmv1beta1.VMAlertmanagerConfig{amcfg("ops")}, `route:
+  receiver: default
+  routes:
+  - matchers:
+    - namespace = "team-a"
+    receiver: team-a-ops-email
+    continue: true
+  - receiver: default
+    matchers:
+    - severity = "info"
+receivers:
+- name: default
+- name: team-a-conflict-email
+- name: team-a-ops-email
+templates: []
+`, nil)
+
+	// disabled strategy, base config is used as is
+	f("disabled", []*vmv1beta1.VMAlertmanagerConfig{amcfg("ops")}, string(baseCfg), nil)
+
+	// receiver name conflicts with base configuration
+	f("base", []*vmv1beta1.VMAlertmanagerConfig{amcfg("conflict")}, `route:
+  receiver: default
+  routes:
+  - receiver: default
+    matchers:
+    - severity = "info"
+receivers:
+- name: default
+- name: team-a-conflict-email
+templates: []
+`, []string{`generated receiver name="team-a-conflict-email" is already defined at base configuration`})
+}
+
+func TestBuildConfigPagerDutyOpsGenie(t *testing.T) {
+	secretKey := func(key string) *corev1.SecretKeySelector {
+		return &corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "team-keys"},
+			Key:                  key,
+		}
+	}
+	teamSecret := func(namespace string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "team-keys", Namespace: namespace},
+			Data: map[string][]byte{
+				"routing-key": []byte(namespace + "-routing-key"),
+				"api-key":     []byte(namespace + "-api-key"),
+			},
+		}
+	}
+	amcfg := func(namespace string) *vmv1beta1.VMAlertmanagerConfig {
+		return &vmv1beta1.VMAlertmanagerConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "oncall", Namespace: namespace},
+			Spec: vmv1beta1.VMAlertmanagerConfigSpec{
+				Route: &vmv1beta1.Route{Receiver: "oncall"},
+				Receivers: []vmv1beta1.Receiver{{
+					Name: "oncall",
+					PagerDutyConfigs: []vmv1beta1.PagerDutyConfig{{
+						RoutingKey:  secretKey("routing-key"),
+						Description: "{{ .CommonAnnotations.summary }}",
+						Severity:    "critical",
+						Class:       "database",
+						Component:   "postgres",
+						Group:       "storage",
+						Source:      "vmalertmanager",
+						Details:     map[string]string{"team": namespace, "runbook": "https://runbooks.example.com"},
+						Images:      []vmv1beta1.ImageConfig{{Source: "https://grafana.example.com/panel.png", Href: "https://grafana.example.com", Alt: "panel"}},
+						Links:       []vmv1beta1.LinkConfig{{Href: "https://grafana.example.com/d/db", Text: "dashboard"}},
+					}},
+					OpsGenieConfigs: []vmv1beta1.OpsGenieConfig{{
+						APIKey:       secretKey("api-key"),
+						Entity:       "database",
+						Actions:      "restart,failover",
+						UpdateAlerts: true,
+						Responders: []vmv1beta1.OpsGenieConfigResponder{
+							{Name: namespace, Type: "team"},
+							{Username: "oncall@example.com", Type: "user"},
+							{ID: "4513b7ea-3b91-438f-b7e4-e3e54af9147c", Type: "escalation"},
+						},
+					}},
+				}},
+			},
+		}
+	}
+	fclient := k8stools.GetTestClientWithObjects([]runtime.Object{teamSecret("team-a"), teamSecret("team-b")})
+	got, err := buildConfig(context.Background(), fclient, &vmv1beta1.VMAlertmanager{}, nil, []*vmv1beta1.VMAlertmanagerConfig{amcfg("team-a"), amcfg("team-b")}, map[string]string{})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for _, amc := range got.brokenAMCfgs {
+		t.Fatalf("unexpected broken config=%s: %s", amc.Name, amc.Status.CurrentSyncError)
+	}
+	// generated configuration must be accepted by alertmanager
+	if _, err := amconfig.Load(string(got.data)); err != nil {
+		t.Fatalf("cannot load generated config: %s", err)
+	}
+	assert.Equal(t, `route:
+  receiver: blackhole
+  routes:
+  - matchers:
+    - namespace = "team-a"
+    receiver: team-a-oncall-oncall
+    continue: true
+  - matchers:
+    - namespace = "team-b"
+    receiver: team-b-oncall-oncall
+    continue: true
+receivers:
+- name: blackhole
+- name: team-a-oncall-oncall
+  opsgenie_configs:
+  - api_key: team-a-api-key
+    entity: database
+    actions: restart,failover
+    update_alerts: true
+    responders:
+    - name: team-a
+      type: team
+    - username: oncall@example.com
+      type: user
+    - id: 4513b7ea-3b91-438f-b7e4-e3e54af9147c
+      type: escalation
+  pagerduty_configs:
+  - routing_key: team-a-routing-key
+    description: '{{ .CommonAnnotations.summary }}'
+    class: database
+    component: postgres
+    group: storage
+    severity: critical
+    source: vmalertmanager
+    images:
+    - href: https://grafana.example.com
+      src: https://grafana.example.com/panel.png
+      alt: panel
+    links:
+    - href: https://grafana.example.com/d/db
+      text: dashboard
+    details:
+      runbook: https://runbooks.example.com
+      team: team-a
+- name: team-b-oncall-oncall
+  opsgenie_configs:
+  - api_key: team-b-api-key
+    entity: database
+    actions: restart,failover
+    update_alerts: true
+    responders:
+    - name: team-b
+      type: team
+    - username: oncall@example.com
+      type: user
+    - id: 4513b7ea-3b91-438f-b7e4-e3e54af9147c
+      type: escalation
+  pagerduty_configs:
+  - routing_key: team-b-routing-key
+    description: '{{ .CommonAnnotations.summary }}'
+    class: database
+    component: postgres
+    group: storage
+    severity: critical
+    source: vmalertmanager
+    images:
+    - href: https://grafana.example.com
+      src: https://grafana.example.com/panel.png
+      alt: panel
+    links:
+    - href: https://grafana.example.com/d/db
+      text: dashboard
+    details:
+      runbook: https://runbooks.example.com
+      team: team-b
+templates: []
+`, string(got.data))
+}
@@ -0,0 +1,164 @@
+package alertmanager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"gopkg.in/yaml.v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/logger"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/reconcile"
+)
+
+const routeSummaryKey = "route-summary.yaml"
+
+// routeSummary defines route of alertmanager configuration
+// only fields required for routing tree overview are parsed,
+// so receivers configuration and its secrets are never exposed
+type routeSummary struct {
+	Receiver string            `yaml:"receiver,omitempty"`
+	Match    map[string]string `yaml:"match,omitempty"`
+	MatchRE  map[string]string `yaml:"match_re,omitempty"`
+	Matchers []string          `yaml:"matchers,omitempty"`
+	GroupBy  []string          `yaml:"group_by,omitempty"`
+	Continue bool              `yaml:"continue,omitempty"`
+	Routes   []*routeSummary   `yaml:"routes,omitempty"`
+}
+
+type configSummary struct {
+	Receivers    []string      `yaml:"receivers"`
+	Route        *routeSummary `yaml:"route,omitempty"`
+	inhibitRules int
+}
+
+// normalize converts deprecated match and match_re fields into matchers
+// and returns number of nested routes
+func (r *routeSummary) normalize() int {
+	keys := make([]string, 0, len(r.Match))
+	for k := range r.Match {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		r.Matchers = append(r.Matchers, fmt.Sprintf("%s=%q", k, r.Match[k]))
+	}
+	keys = keys[:0]
+	for k := range r.MatchRE {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		r.Matchers = append(r.Matchers, fmt.Sprintf("%s=~%q", k, r.MatchRE[k]))
+	}
+	r.Match = nil
+	r.MatchRE = nil
+	cnt := len(r.Routes)
+	for _, nested := range r.Routes {
+		cnt += nested.normalize()
+	}
+	return cnt
+}
+
+// buildConfigSummary parses the given alertmanager configuration
+// and returns its routing tree summary with configuration counters
+func buildConfigSummary(data []byte, mergedCfg *parsedConfig) ([]byte, *vmv1beta1.VMAlertmanagerConfigSummary, error) {
+	var cfg struct {
+		Receivers []struct {
+			Name string `yaml:"name"`
+		} `yaml:"receivers"`
+		Route        *routeSummary `yaml:"route"`
+		InhibitRules []any         `yaml:"inhibit_rules"`
+	}
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, nil, fmt.Errorf("cannot parse alertmanager config: %w", err)
+	}
+	summary := configSummary{
+		Route:        cfg.Route,
+		inhibitRules: len(cfg.InhibitRules),
+	}
+	for _, recv := range cfg.Receivers {
+		summary.Receivers = append(summary.Receivers, recv.Name)
+	}
+	var routesCnt int
+	if summary.Route != nil {
+		routesCnt = summary.Route.normalize()
+	}
+	out, err := yaml.Marshal(summary)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot serialize route summary: %w", err)
+	}
+	counters := &vmv1beta1.VMAlertmanagerConfigSummary{
+		Receivers:      int32(len(summary.Receivers)),
+		Routes:         int32(routesCnt),
+		InhibitRules:   int32(summary.inhibitRules),
+		ConfigsMerged:  int32(len(mergedCfg.amcfgs)),
+		ConfigsSkipped: int32(len(mergedCfg.brokenAMCfgs)),
+	}
+	return out, counters, nil
+}
+
+func buildRouteSummaryConfigMapMeta(cr *vmv1beta1.VMAlertmanager) *metav1.ObjectMeta {
+	return &metav1.ObjectMeta{
+		Name:            cr.RouteSummaryConfigMapName(),
+		Namespace:       cr.Namespace,
+		Labels:          cr.AllLabels(),
+		Annotations:     cr.AnnotationsFiltered(),
+		OwnerReferences: cr.AsOwner(),
+		Finalizers:      []string{vmv1beta1.FinalizerName},
+	}
+}
+
+// createOrUpdateRouteSummary publishes routing tree summary of the given configuration into ConfigMap
+// and sets configuration counters at VMAlertmanager status
+func createOrUpdateRouteSummary(ctx context.Context, rclient client.Client, cr, prevCR *vmv1beta1.VMAlertmanager, data []byte, mergedCfg *parsedConfig) error {
+	summary, counters, err := buildConfigSummary(data, mergedCfg)
+	if err != nil {
+		// configuration could be defined with raw yaml and must not block reconcile
+		logger.WithContext(ctx).Error(err, "cannot build route summary")
+		return nil
+	}
+	cm := &corev1.ConfigMap{
+		ObjectMeta: *buildRouteSummaryConfigMapMeta(cr),
+		Data: map[string]string{
+			routeSummaryKey: string(summary),
+		},
+	}
+	var prevMeta *metav1.ObjectMeta
+	if prevCR != nil {
+		prevMeta = buildRouteSummaryConfigMapMeta(prevCR)
+	}
+	if err := reconcile.ConfigMap(ctx, rclient, cm, prevMeta); err != nil {
+		return fmt.Errorf("cannot reconcile route summary configmap: %w", err)
+	}
+	cr.Status.ConfigSummary = counters
+	return nil
+}
+
+// updateConfigSummaryStatus persists status.configSummary of the given VMAlertmanager.
+// It must be used, since configuration could be updated outside of VMAlertmanager reconcile
+func updateConfigSummaryStatus(ctx context.Context, rclient client.Client, cr *vmv1beta1.VMAlertmanager) error {
+	patch := map[string]any{
+		"status": map[string]any{
+			"configSummary": cr.Status.ConfigSummary,
+		},
+	}
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return fmt.Errorf("cannot marshal config summary status patch: %w", err)
+	}
+	// patch a copy, since Patch reloads object state from API server
+	// and drops status changes, which are not persisted yet
+	objToUpdate := cr.DeepCopy()
+	if err := rclient.Status().Patch(ctx, objToUpdate, client.RawPatch(types.MergePatchType, data)); err != nil {
+		return fmt.Errorf("cannot update config summary status: %w", err)
+	}
+	cr.SetResourceVersion(objToUpdate.GetResourceVersion())
+	return nil
+}
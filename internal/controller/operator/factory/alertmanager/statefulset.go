@@ -11,10 +11,12 @@ import (
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/utils/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -155,7 +157,7 @@ func makeStatefulSetSpec(cr *vmv1beta1.VMAlertmanager) (*appsv1.StatefulSetSpec,
 	if cr.Spec.WebConfig != nil {
 		amArgs = append(amArgs, fmt.Sprintf("--web.config.file=%s/%s", tlsAssetsDir, webserverConfigKey))
 	}
-	if cr.Spec.GossipConfig != nil {
+	if cr.Spec.GossipConfig != nil || cr.Spec.ClusterTLS != nil {
 		amArgs = append(amArgs, fmt.Sprintf("--cluster.tls-config=%s/%s", tlsAssetsDir, gossipConfigKey))
 	}
 
@@ -176,8 +178,14 @@ func makeStatefulSetSpec(cr *vmv1beta1.VMAlertmanager) (*appsv1.StatefulSetSpec,
 	}
 	amArgs = append(amArgs, fmt.Sprintf("--web.listen-address=%s:%d", listenHost, port))
 
-	if cr.Spec.ExternalURL != "" {
-		amArgs = append(amArgs, "--web.external-url="+cr.Spec.ExternalURL)
+	externalURL := cr.Spec.ExternalURL
+	if externalURL == "" && cr.Spec.ExposeViaVMAuth != nil {
+		// keep alertmanager default host, but serve links with exposure path prefix,
+		// since VMAuth strips it before proxying requests
+		externalURL = fmt.Sprintf("http://$(POD_NAME):%d%s", port, cr.VMAuthExposurePath())
+	}
+	if externalURL != "" {
+		amArgs = append(amArgs, "--web.external-url="+externalURL)
 	}
 
 	webRoutePrefix := "/"
@@ -332,29 +340,11 @@ func makeStatefulSetSpec(cr *vmv1beta1.VMAlertmanager) (*appsv1.StatefulSetSpec,
 		crVolumeMounts = append(crVolumeMounts, cmVolumeMount)
 	}
 
-	volumeByName := make(map[string]struct{})
-	for _, t := range cr.Spec.Templates {
-		// Deduplicate configmaps by name
-		if _, ok := volumeByName[t.Name]; ok {
-			continue
-		}
-		volumeByName[t.Name] = struct{}{}
-		volumes = append(volumes, corev1.Volume{
-			Name: k8stools.SanitizeVolumeName("templates-" + t.Name),
-			VolumeSource: corev1.VolumeSource{
-				ConfigMap: &corev1.ConfigMapVolumeSource{
-					LocalObjectReference: t.LocalObjectReference,
-				},
-			},
-		})
-		tmplVolumeMount := corev1.VolumeMount{
-			Name:      k8stools.SanitizeVolumeName("templates-" + t.Name),
-			MountPath: path.Join(templatesDir, t.Name),
-			ReadOnly:  true,
-		}
-		amVolumeMounts = append(amVolumeMounts, tmplVolumeMount)
-		crVolumeMounts = append(crVolumeMounts, tmplVolumeMount)
-	}
+	tmplVolumes, tmplVolumeMounts := buildTemplatesVolumes(cr)
+	volumes = append(volumes, tmplVolumes...)
+	amVolumeMounts = append(amVolumeMounts, tmplVolumeMounts...)
+	crVolumeMounts = append(crVolumeMounts, tmplVolumeMounts...)
+	volumes, amVolumeMounts = addClusterTLSToPodSpec(cr, volumes, amVolumeMounts)
 
 	amVolumeMounts = append(amVolumeMounts, cr.Spec.VolumeMounts...)
 
@@ -372,6 +362,17 @@ func makeStatefulSetSpec(cr *vmv1beta1.VMAlertmanager) (*appsv1.StatefulSetSpec,
 			},
 		},
 	}
+	if cr.Spec.ExternalURL == "" && cr.Spec.ExposeViaVMAuth != nil {
+		envs = append(envs, corev1.EnvVar{
+			// Necessary for default '--web.external-url' flag
+			Name: "POD_NAME",
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{
+					FieldPath: "metadata.name",
+				},
+			},
+		})
+	}
 	envs = append(envs, cr.Spec.ExtraEnvs...)
 
 	var initContainers []corev1.Container
@@ -443,7 +444,25 @@ func makeStatefulSetSpec(cr *vmv1beta1.VMAlertmanager) (*appsv1.StatefulSetSpec,
 
 // CreateOrUpdateConfig - check if secret with config exist,
 // if not create with predefined or user value.
-func CreateOrUpdateConfig(ctx context.Context, rclient client.Client, cr *vmv1beta1.VMAlertmanager, childCR *vmv1beta1.VMAlertmanagerConfig) error {
+// It persists status.configSummary and TemplatesDegraded condition changes, since it could be called outside of VMAlertmanager reconcile
+func CreateOrUpdateConfig(ctx context.Context, rclient client.Client, cr *vmv1beta1.VMAlertmanager, childCR *vmv1beta1.VMAlertmanagerConfig, recorder record.EventRecorder) error {
+	prevSummary := cr.Status.ConfigSummary.DeepCopy()
+	prevTemplatesCond := findAlertmanagerCondition(cr, vmv1beta1.VMAlertmanagerTemplatesDegradedCondition).DeepCopy()
+	if err := createOrUpdateConfig(ctx, rclient, cr, childCR, recorder); err != nil {
+		return err
+	}
+	if !equality.Semantic.DeepEqual(prevSummary, cr.Status.ConfigSummary) {
+		if err := updateConfigSummaryStatus(ctx, rclient, cr); err != nil {
+			return err
+		}
+	}
+	if !equality.Semantic.DeepEqual(prevTemplatesCond, findAlertmanagerCondition(cr, vmv1beta1.VMAlertmanagerTemplatesDegradedCondition)) {
+		return updateTemplatesConditionStatus(ctx, rclient, cr)
+	}
+	return nil
+}
+
+func createOrUpdateConfig(ctx context.Context, rclient client.Client, cr *vmv1beta1.VMAlertmanager, childCR *vmv1beta1.VMAlertmanagerConfig, recorder record.EventRecorder) error {
 	l := logger.WithContext(ctx)
 	var prevCR *vmv1beta1.VMAlertmanager
 	if cr.ParsedLastAppliedSpec != nil {
@@ -476,6 +495,10 @@ func CreateOrUpdateConfig(ctx context.Context, rclient client.Client, cr *vmv1be
 	case cr.Spec.ConfigRawYaml != "":
 		alertmananagerConfig = []byte(cr.Spec.ConfigRawYaml)
 	}
+	alertmananagerConfig, err := addGlobalConfig(ctx, rclient, cr, alertmananagerConfig, tlsAssets)
+	if err != nil {
+		return fmt.Errorf("cannot build alertmanager config with globalConfig: %w", err)
+	}
 	mergedCfg, err := buildAlertmanagerConfigWithCRDs(ctx, rclient, cr, alertmananagerConfig, tlsAssets)
 	if err != nil {
 		return fmt.Errorf("cannot build alertmanager config with configSelector, err: %w", err)
@@ -497,11 +520,10 @@ func CreateOrUpdateConfig(ctx context.Context, rclient client.Client, cr *vmv1be
 	}
 
 	// add templates from CR to alermanager config
-	if len(cr.Spec.Templates) > 0 {
-		templatePaths := make([]string, 0, len(cr.Spec.Templates))
-		for _, template := range cr.Spec.Templates {
-			templatePaths = append(templatePaths, path.Join(templatesDir, template.Name, template.Key))
-		}
+	if err := setTemplatesDegradedCondition(ctx, rclient, cr); err != nil {
+		return err
+	}
+	if templatePaths := buildTemplatesPaths(cr); len(templatePaths) > 0 {
 		mergedCfg, err := addConfigTemplates(alertmananagerConfig, templatePaths)
 		if err != nil {
 			return fmt.Errorf("cannot build alertmanager config with templates, err: %w", err)
@@ -517,7 +539,7 @@ func CreateOrUpdateConfig(ctx context.Context, rclient client.Client, cr *vmv1be
 	if cr.Spec.WebConfig != nil {
 		newAMSecretConfig.Data[webserverConfigKey] = webCfg
 	}
-	if cr.Spec.GossipConfig != nil {
+	if cr.Spec.GossipConfig != nil || cr.Spec.ClusterTLS != nil {
 		newAMSecretConfig.Data[gossipConfigKey] = gossipCfg
 	}
 
@@ -533,31 +555,51 @@ func CreateOrUpdateConfig(ctx context.Context, rclient client.Client, cr *vmv1be
 	if err := reconcile.Secret(ctx, rclient, newAMSecretConfig, prevSecretMeta); err != nil {
 		return err
 	}
+	if err := createOrUpdateRouteSummary(ctx, rclient, cr, prevCR, alertmananagerConfig, mergedCfg); err != nil {
+		return err
+	}
 
 	parent := fmt.Sprintf("%s.%s.vmalertmanager", cr.Name, cr.Namespace)
+	events := reconcile.ChildObjectEvents{
+		Object:         "config",
+		Parent:         fmt.Sprintf("vmalertmanager=%s/%s", cr.Namespace, cr.Name),
+		RejectedReason: amConfigRejectedEventReason,
+		AcceptedReason: amConfigAcceptedEventReason,
+	}
 
 	if childCR != nil {
 		// fast path update only single object
 		for _, amc := range mergedCfg.amcfgs {
 			if amc.Name == childCR.Name && amc.Namespace == childCR.Namespace {
-				return reconcile.StatusForChildObjects(ctx, rclient, parent, []*vmv1beta1.VMAlertmanagerConfig{amc})
+				childCfgs := []*vmv1beta1.VMAlertmanagerConfig{amc}
+				reconcile.ChildObjectsEvents(recorder, parent, childCfgs, events)
+				return reconcile.StatusForChildObjects(ctx, rclient, parent, childCfgs)
 			}
 		}
 		for _, amc := range mergedCfg.brokenAMCfgs {
 			if amc.Name == childCR.Name && amc.Namespace == childCR.Namespace {
-				return reconcile.StatusForChildObjects(ctx, rclient, parent, []*vmv1beta1.VMAlertmanagerConfig{amc})
+				childCfgs := []*vmv1beta1.VMAlertmanagerConfig{amc}
+				reconcile.ChildObjectsEvents(recorder, parent, childCfgs, events)
+				return reconcile.StatusForChildObjects(ctx, rclient, parent, childCfgs)
 			}
 		}
 	}
+	reconcile.ChildObjectsEvents(recorder, parent, mergedCfg.amcfgs, events)
 	if err := reconcile.StatusForChildObjects(ctx, rclient, parent, mergedCfg.amcfgs); err != nil {
 		return fmt.Errorf("failed to update vmalertmanagerConfigs statuses: %w", err)
 	}
+	reconcile.ChildObjectsEvents(recorder, parent, mergedCfg.brokenAMCfgs, events)
 	if err := reconcile.StatusForChildObjects(ctx, rclient, parent, mergedCfg.brokenAMCfgs); err != nil {
 		return fmt.Errorf("failed to update broken vmalertmanagerConfigs statuses: %w", err)
 	}
 	return nil
 }
 
+const (
+	amConfigRejectedEventReason = "VMAlertmanagerConfigRejected"
+	amConfigAcceptedEventReason = "VMAlertmanagerConfigAccepted"
+)
+
 func buildConfgSecretMeta(cr *vmv1beta1.VMAlertmanager) *metav1.ObjectMeta {
 	return &metav1.ObjectMeta{
 		Name:            cr.ConfigSecretName(),
@@ -676,6 +718,10 @@ func getSecretContentForAlertmanager(ctx context.Context, rclient client.Client,
 }
 
 func buildAlertmanagerConfigWithCRDs(ctx context.Context, rclient client.Client, cr *vmv1beta1.VMAlertmanager, originConfig []byte, tlsAssets map[string]string) (*parsedConfig, error) {
+	if cr.Spec.ConfigSecret != "" && cr.Spec.ConfigSecretMergeStrategy == vmv1beta1.VMAlertmanagerConfigSecretMergeStrategyDisabled {
+		logger.WithContext(ctx).Info("VMAlertmanagerConfigs are not merged, since configSecretMergeStrategy is disabled")
+		return &parsedConfig{data: originConfig}, nil
+	}
 	var amCfgs []*vmv1beta1.VMAlertmanagerConfig
 	var badCfgs []*vmv1beta1.VMAlertmanagerConfig
 	var namespacedNames []string
@@ -713,7 +759,7 @@ func buildAlertmanagerConfigWithCRDs(ctx context.Context, rclient client.Client,
 	}
 	parsedCfg.brokenAMCfgs = append(parsedCfg.brokenAMCfgs, badCfgs...)
 	logger.SelectedObjects(ctx, "VMAlertmanagerConfigs", len(parsedCfg.amcfgs), len(parsedCfg.brokenAMCfgs), namespacedNames)
-	badConfigsTotal.Add(float64(len(badCfgs)))
+	badConfigsTotal.Add(float64(len(parsedCfg.brokenAMCfgs)))
 	return parsedCfg, nil
 }
 
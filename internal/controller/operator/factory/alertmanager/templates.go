@@ -0,0 +1,215 @@
+package alertmanager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/k8stools"
+)
+
+// buildTemplatesPaths returns paths of templates files from spec.templates and spec.templateSecrets
+func buildTemplatesPaths(cr *vmv1beta1.VMAlertmanager) []string {
+	templatePaths := make([]string, 0, len(cr.Spec.Templates)+len(cr.Spec.TemplateSecrets))
+	for _, t := range cr.Spec.Templates {
+		templatePaths = append(templatePaths, path.Join(templatesDir, t.Name, t.Key))
+	}
+	for _, t := range cr.Spec.TemplateSecrets {
+		templatePaths = append(templatePaths, path.Join(templatesDir, t.Name, t.Key))
+	}
+	return templatePaths
+}
+
+// buildTemplatesVolumes returns volumes and mounts for templates ConfigMaps and Secrets.
+// Volumes are optional, missing templates sources must not block alertmanager pods start.
+// Config-reloader watches mounted directories, so templates change triggers alertmanager reload
+func buildTemplatesVolumes(cr *vmv1beta1.VMAlertmanager) ([]corev1.Volume, []corev1.VolumeMount) {
+	var volumes []corev1.Volume
+	var mounts []corev1.VolumeMount
+	addVolume := func(volumeName, name string, src corev1.VolumeSource) {
+		volumes = append(volumes, corev1.Volume{
+			Name:         volumeName,
+			VolumeSource: src,
+		})
+		mounts = append(mounts, corev1.VolumeMount{
+			Name:      volumeName,
+			MountPath: path.Join(templatesDir, name),
+			ReadOnly:  true,
+		})
+	}
+	volumeByName := make(map[string]struct{})
+	for _, t := range cr.Spec.Templates {
+		// Deduplicate configmaps by name
+		if _, ok := volumeByName[t.Name]; ok {
+			continue
+		}
+		volumeByName[t.Name] = struct{}{}
+		addVolume(k8stools.SanitizeVolumeName("templates-"+t.Name), t.Name, corev1.VolumeSource{
+			ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: t.LocalObjectReference,
+				Optional:             ptr.To(true),
+			},
+		})
+	}
+	for _, t := range cr.Spec.TemplateSecrets {
+		if _, ok := volumeByName[t.Name]; ok {
+			continue
+		}
+		volumeByName[t.Name] = struct{}{}
+		addVolume(k8stools.SanitizeVolumeName("templates-secret-"+t.Name), t.Name, corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{
+				SecretName: t.Name,
+				Optional:   ptr.To(true),
+			},
+		})
+	}
+	return volumes, mounts
+}
+
+// setTemplatesDegradedCondition checks that ConfigMaps and Secrets referenced by templates exist
+// and contain referenced keys. Missing templates don't break alertmanager configuration,
+// they're reported with TemplatesDegraded condition at VMAlertmanager status
+func setTemplatesDegradedCondition(ctx context.Context, rclient client.Client, cr *vmv1beta1.VMAlertmanager) error {
+	if len(cr.Spec.Templates) == 0 && len(cr.Spec.TemplateSecrets) == 0 {
+		removeAlertmanagerCondition(cr, vmv1beta1.VMAlertmanagerTemplatesDegradedCondition)
+		return nil
+	}
+	var missing []string
+	cms := make(map[string]*corev1.ConfigMap)
+	for _, t := range cr.Spec.Templates {
+		cm, ok := cms[t.Name]
+		if !ok {
+			cm = &corev1.ConfigMap{}
+			if err := rclient.Get(ctx, types.NamespacedName{Namespace: cr.Namespace, Name: t.Name}, cm); err != nil {
+				if !k8serrors.IsNotFound(err) {
+					return fmt.Errorf("cannot get templates configmap=%q: %w", t.Name, err)
+				}
+				cm = nil
+			}
+			cms[t.Name] = cm
+		}
+		if cm == nil {
+			missing = append(missing, fmt.Sprintf("configmap=%s", t.Name))
+			continue
+		}
+		if _, ok := cm.Data[t.Key]; !ok {
+			missing = append(missing, fmt.Sprintf("configmap=%s key=%s", t.Name, t.Key))
+		}
+	}
+	secrets := make(map[string]*corev1.Secret)
+	for _, t := range cr.Spec.TemplateSecrets {
+		s, ok := secrets[t.Name]
+		if !ok {
+			s = &corev1.Secret{}
+			if err := rclient.Get(ctx, types.NamespacedName{Namespace: cr.Namespace, Name: t.Name}, s); err != nil {
+				if !k8serrors.IsNotFound(err) {
+					return fmt.Errorf("cannot get templates secret=%q: %w", t.Name, err)
+				}
+				s = nil
+			}
+			secrets[t.Name] = s
+		}
+		if s == nil {
+			missing = append(missing, fmt.Sprintf("secret=%s", t.Name))
+			continue
+		}
+		if _, ok := s.Data[t.Key]; !ok {
+			missing = append(missing, fmt.Sprintf("secret=%s key=%s", t.Name, t.Key))
+		}
+	}
+	cond := vmv1beta1.Condition{
+		Type:   vmv1beta1.VMAlertmanagerTemplatesDegradedCondition,
+		Status: metav1.ConditionFalse,
+		Reason: "TemplatesFound",
+	}
+	if len(missing) > 0 {
+		cond.Status = metav1.ConditionTrue
+		cond.Reason = "TemplatesMissing"
+		cond.Message = fmt.Sprintf("missing templates sources: %s", strings.Join(missing, ", "))
+	}
+	setAlertmanagerCondition(cr, cond)
+	return nil
+}
+
+// updateTemplatesConditionStatus persists status.conditions of the given VMAlertmanager with TemplatesDegraded condition
+func updateTemplatesConditionStatus(ctx context.Context, rclient client.Client, cr *vmv1beta1.VMAlertmanager) error {
+	patch := map[string]any{
+		"status": map[string]any{
+			"conditions": cr.Status.Conditions,
+		},
+	}
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return fmt.Errorf("cannot marshal templates condition status patch: %w", err)
+	}
+	// patch a copy, since Patch reloads object state from API server
+	// and drops status changes, which are not persisted yet
+	objToUpdate := cr.DeepCopy()
+	if err := rclient.Status().Patch(ctx, objToUpdate, client.RawPatch(types.MergePatchType, data)); err != nil {
+		return fmt.Errorf("cannot update templates condition status: %w", err)
+	}
+	cr.SetResourceVersion(objToUpdate.GetResourceVersion())
+	return nil
+}
+
+// findAlertmanagerCondition returns condition with the given type from VMAlertmanager status
+func findAlertmanagerCondition(cr *vmv1beta1.VMAlertmanager, condType string) *vmv1beta1.Condition {
+	for idx := range cr.Status.Conditions {
+		if cr.Status.Conditions[idx].Type == condType {
+			return &cr.Status.Conditions[idx]
+		}
+	}
+	return nil
+}
+
+// IsTemplatesConfigMap checks if the given ConfigMap is referenced by spec.templates of the alertmanager
+func IsTemplatesConfigMap(cr *vmv1beta1.VMAlertmanager, name string) bool {
+	return slices.ContainsFunc(cr.Spec.Templates, func(t vmv1beta1.ConfigMapKeyReference) bool { return t.Name == name })
+}
+
+// IsTemplatesSecret checks if the given Secret is referenced by spec.templateSecrets of the alertmanager
+func IsTemplatesSecret(cr *vmv1beta1.VMAlertmanager, name string) bool {
+	return slices.ContainsFunc(cr.Spec.TemplateSecrets, func(t corev1.SecretKeySelector) bool { return t.Name == name })
+}
+
+// setAlertmanagerCondition adds or updates condition with the same type at VMAlertmanager status
+func setAlertmanagerCondition(cr *vmv1beta1.VMAlertmanager, cond vmv1beta1.Condition) {
+	ctm := metav1.Now()
+	cond.ObservedGeneration = cr.Generation
+	cond.LastTransitionTime = ctm
+	cond.LastUpdateTime = ctm
+	for idx, c := range cr.Status.Conditions {
+		if c.Type != cond.Type {
+			continue
+		}
+		if c.Status == cond.Status {
+			cond.LastTransitionTime = c.LastTransitionTime
+			cond.LastUpdateTime = c.LastUpdateTime
+		}
+		cr.Status.Conditions[idx] = cond
+		return
+	}
+	cr.Status.Conditions = append(cr.Status.Conditions, cond)
+}
+
+// removeAlertmanagerCondition removes condition with the given type from VMAlertmanager status
+func removeAlertmanagerCondition(cr *vmv1beta1.VMAlertmanager, condType string) {
+	var conds []vmv1beta1.Condition
+	for _, c := range cr.Status.Conditions {
+		if c.Type != condType {
+			conds = append(conds, c)
+		}
+	}
+	cr.Status.Conditions = conds
+}
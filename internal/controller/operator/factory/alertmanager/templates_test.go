@@ -0,0 +1,188 @@
+package alertmanager
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+
+	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/k8stools"
+)
+
+func TestBuildTemplatesVolumes(t *testing.T) {
+	cr := &vmv1beta1.VMAlertmanager{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+		Spec: vmv1beta1.VMAlertmanagerSpec{
+			Templates: []vmv1beta1.ConfigMapKeyReference{
+				{LocalObjectReference: corev1.LocalObjectReference{Name: "tmpl-cm"}, Key: "a.tmpl"},
+				{LocalObjectReference: corev1.LocalObjectReference{Name: "tmpl-cm"}, Key: "b.tmpl"},
+			},
+			TemplateSecrets: []corev1.SecretKeySelector{
+				{LocalObjectReference: corev1.LocalObjectReference{Name: "tmpl-secret"}, Key: "c.tmpl"},
+			},
+		},
+	}
+	volumes, mounts := buildTemplatesVolumes(cr)
+	assert.Equal(t, []corev1.Volume{
+		{
+			Name: "templates-tmpl-cm",
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "tmpl-cm"},
+					Optional:             ptr.To(true),
+				},
+			},
+		},
+		{
+			Name: "templates-secret-tmpl-secret",
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: "tmpl-secret",
+					Optional:   ptr.To(true),
+				},
+			},
+		},
+	}, volumes)
+	assert.Equal(t, []corev1.VolumeMount{
+		{Name: "templates-tmpl-cm", MountPath: "/etc/vm/templates/tmpl-cm", ReadOnly: true},
+		{Name: "templates-secret-tmpl-secret", MountPath: "/etc/vm/templates/tmpl-secret", ReadOnly: true},
+	}, mounts)
+	assert.Equal(t, []string{
+		"/etc/vm/templates/tmpl-cm/a.tmpl",
+		"/etc/vm/templates/tmpl-cm/b.tmpl",
+		"/etc/vm/templates/tmpl-secret/c.tmpl",
+	}, buildTemplatesPaths(cr))
+}
+
+func TestSetTemplatesDegradedCondition(t *testing.T) {
+	type opts struct {
+		spec              vmv1beta1.VMAlertmanagerSpec
+		predefinedObjects []runtime.Object
+		wantStatus        metav1.ConditionStatus
+		wantMessage       string
+	}
+	f := func(o opts) {
+		t.Helper()
+		cr := &vmv1beta1.VMAlertmanager{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+			Spec:       o.spec,
+			Status: vmv1beta1.VMAlertmanagerStatus{
+				StatusMetadata: vmv1beta1.StatusMetadata{
+					Conditions: []vmv1beta1.Condition{{Type: vmv1beta1.VMAlertmanagerTemplatesDegradedCondition, Status: metav1.ConditionTrue}},
+				},
+			},
+		}
+		fclient := k8stools.GetTestClientWithObjects(o.predefinedObjects)
+		if err := setTemplatesDegradedCondition(context.TODO(), fclient, cr); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if o.wantStatus == "" {
+			assert.Empty(t, cr.Status.Conditions)
+			return
+		}
+		if assert.Len(t, cr.Status.Conditions, 1) {
+			cond := cr.Status.Conditions[0]
+			assert.Equal(t, o.wantStatus, cond.Status)
+			assert.Equal(t, o.wantMessage, cond.Message)
+		}
+	}
+	cmRef := func(name, key string) vmv1beta1.ConfigMapKeyReference {
+		return vmv1beta1.ConfigMapKeyReference{LocalObjectReference: corev1.LocalObjectReference{Name: name}, Key: key}
+	}
+	secretRef := func(name, key string) corev1.SecretKeySelector {
+		return corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: name}, Key: key}
+	}
+
+	// templates are not defined
+	f(opts{})
+
+	// all templates found
+	f(opts{
+		spec: vmv1beta1.VMAlertmanagerSpec{
+			Templates:       []vmv1beta1.ConfigMapKeyReference{cmRef("tmpl-cm", "a.tmpl")},
+			TemplateSecrets: []corev1.SecretKeySelector{secretRef("tmpl-secret", "b.tmpl")},
+		},
+		predefinedObjects: []runtime.Object{
+			&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "tmpl-cm", Namespace: "default"},
+				Data:       map[string]string{"a.tmpl": `{{ define "a" }}a{{ end }}`},
+			},
+			&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "tmpl-secret", Namespace: "default"},
+				Data:       map[string][]byte{"b.tmpl": []byte(`{{ define "b" }}b{{ end }}`)},
+			},
+		},
+		wantStatus: metav1.ConditionFalse,
+	})
+
+	// missing objects and keys
+	f(opts{
+		spec: vmv1beta1.VMAlertmanagerSpec{
+			Templates: []vmv1beta1.ConfigMapKeyReference{
+				cmRef("tmpl-cm", "a.tmpl"),
+				cmRef("tmpl-cm", "missing.tmpl"),
+				cmRef("missing-cm", "a.tmpl"),
+			},
+			TemplateSecrets: []corev1.SecretKeySelector{secretRef("missing-secret", "b.tmpl")},
+		},
+		predefinedObjects: []runtime.Object{
+			&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "tmpl-cm", Namespace: "default"},
+				Data:       map[string]string{"a.tmpl": `{{ define "a" }}a{{ end }}`},
+			},
+		},
+		wantStatus:  metav1.ConditionTrue,
+		wantMessage: "missing templates sources: configmap=tmpl-cm key=missing.tmpl, configmap=missing-cm, secret=missing-secret",
+	})
+}
+
+func TestCreateOrUpdateConfigTemplatesCondition(t *testing.T) {
+	cr := &vmv1beta1.VMAlertmanager{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+		Spec: vmv1beta1.VMAlertmanagerSpec{
+			Templates: []vmv1beta1.ConfigMapKeyReference{
+				{LocalObjectReference: corev1.LocalObjectReference{Name: "tmpl-cm"}, Key: "a.tmpl"},
+			},
+		},
+	}
+	ctx := context.TODO()
+	fclient := k8stools.GetTestClientWithObjects([]runtime.Object{cr.DeepCopy()})
+	getCondition := func() *vmv1beta1.Condition {
+		t.Helper()
+		var got vmv1beta1.VMAlertmanager
+		if err := fclient.Get(ctx, types.NamespacedName{Namespace: cr.Namespace, Name: cr.Name}, &got); err != nil {
+			t.Fatalf("cannot get vmalertmanager: %s", err)
+		}
+		return findAlertmanagerCondition(&got, vmv1beta1.VMAlertmanagerTemplatesDegradedCondition)
+	}
+
+	// condition must be persisted without VMAlertmanager status update
+	if err := CreateOrUpdateConfig(ctx, fclient, cr, nil, nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if cond := getCondition(); assert.NotNil(t, cond) {
+		assert.Equal(t, metav1.ConditionTrue, cond.Status)
+	}
+
+	// templates configmap was created
+	if err := fclient.Create(ctx, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "tmpl-cm", Namespace: "default"},
+		Data:       map[string]string{"a.tmpl": `{{ define "a" }}a{{ end }}`},
+	}); err != nil {
+		t.Fatalf("cannot create configmap: %s", err)
+	}
+	if err := CreateOrUpdateConfig(ctx, fclient, cr, nil, nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if cond := getCondition(); assert.NotNil(t, cond) {
+		assert.Equal(t, metav1.ConditionFalse, cond.Status)
+	}
+	assert.True(t, IsTemplatesConfigMap(cr, "tmpl-cm"))
+	assert.False(t, IsTemplatesSecret(cr, "tmpl-cm"))
+}
@@ -0,0 +1,276 @@
+package alertmanager
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	amlabels "github.com/prometheus/alertmanager/pkg/labels"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/k8stools"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/logger"
+)
+
+const (
+	testAlertName = "VMAlertmanagerConfigReceiverTest"
+	// testAlertDuration defines interval after which synthetic alert is resolved by alertmanager
+	testAlertDuration = 5 * time.Minute
+	// maxTestResponseSize limits size of alertmanager response stored at status
+	maxTestResponseSize = 512
+	testRequestTimeout  = 5 * time.Second
+)
+
+// testAlert defines alert in format of alertmanager API v2
+type testAlert struct {
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	StartsAt    time.Time         `json:"startsAt"`
+	EndsAt      time.Time         `json:"endsAt"`
+}
+
+// newTestReceiverHTTPClient returns client for requests to the given alertmanager pod.
+// Alertmanager web server certificate is used as trusted CA and verified for pod dns name,
+// the same way as for vmalert notifiers discovered with notifierSelector
+func newTestReceiverHTTPClient(ctx context.Context, rclient client.Client, am *vmv1beta1.VMAlertmanager, pod *corev1.Pod) (*http.Client, error) {
+	var tc *vmv1beta1.TLSConfig
+	if wc := am.Spec.WebConfig; wc != nil && wc.TLSServerConfig != nil {
+		tc = &vmv1beta1.TLSConfig{
+			ServerName: fmt.Sprintf("%s.%s.%s.svc", pod.Name, am.PrefixedName(), am.Namespace),
+		}
+		if wc.TLSServerConfig.CertSecretRef != nil {
+			tc.CA = vmv1beta1.SecretOrConfigMap{Secret: wc.TLSServerConfig.CertSecretRef}
+		}
+	}
+	return k8stools.NewHTTPClient(ctx, rclient, am.Namespace, tc, testRequestTimeout)
+}
+
+// findRoutePath returns path from the top route to the first route with the given receiver
+func findRoutePath(r *vmv1beta1.Route, receiver string) []*vmv1beta1.Route {
+	if r.Receiver == receiver {
+		return []*vmv1beta1.Route{r}
+	}
+	for _, nested := range r.Routes {
+		if path := findRoutePath((*vmv1beta1.Route)(nested), receiver); path != nil {
+			return append([]*vmv1beta1.Route{r}, path...)
+		}
+	}
+	return nil
+}
+
+// buildTestAlertLabels returns labels of synthetic alert, which is routed to the given receiver.
+// Only equality matchers and regex matchers with literal value could be satisfied,
+// other matchers of the route are ignored
+func buildTestAlertLabels(amcfg *vmv1beta1.VMAlertmanagerConfig, am *vmv1beta1.VMAlertmanager, receiver string) (map[string]string, error) {
+	var found bool
+	for _, r := range amcfg.Spec.Receivers {
+		if r.Name == receiver {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("receiver=%q is not defined at VMAlertmanagerConfig", receiver)
+	}
+	if amcfg.Spec.Route == nil {
+		return nil, fmt.Errorf("route is not defined at VMAlertmanagerConfig")
+	}
+	routePath := findRoutePath(amcfg.Spec.Route, receiver)
+	if routePath == nil {
+		return nil, fmt.Errorf("receiver=%q is not referenced by any route", receiver)
+	}
+	matchers := append([]string{}, am.Spec.EnforcedTopRouteMatchers...)
+	for _, r := range routePath {
+		matchers = append(matchers, r.Matchers...)
+	}
+	lbls := map[string]string{
+		"alertname": testAlertName,
+	}
+	for _, m := range matchers {
+		parsed, err := amlabels.ParseMatchers(m)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse route matcher=%q: %w", m, err)
+		}
+		for _, pm := range parsed {
+			switch pm.Type {
+			case amlabels.MatchEqual:
+				lbls[pm.Name] = pm.Value
+			case amlabels.MatchRegexp:
+				if regexp.QuoteMeta(pm.Value) == pm.Value {
+					lbls[pm.Name] = pm.Value
+				}
+			}
+		}
+	}
+	// scope synthetic alert to the VMAlertmanagerConfig namespace
+	lbls["namespace"] = amcfg.Namespace
+	lbls["test"] = "true"
+	return lbls, nil
+}
+
+// postTestAlert posts synthetic alert into alerts API of alertmanager
+func postTestAlert(ctx context.Context, hc *http.Client, alertsURL string, creds *k8stools.BasicAuthCredentials, alert testAlert) (int, string, error) {
+	data, err := json.Marshal([]testAlert{alert})
+	if err != nil {
+		return 0, "", fmt.Errorf("cannot marshal test alert: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, alertsURL, bytes.NewReader(data))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if creds != nil {
+		req.SetBasicAuth(creds.Username, creds.Password)
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxTestResponseSize))
+	if err != nil {
+		return resp.StatusCode, "", fmt.Errorf("cannot read response body: %w", err)
+	}
+	return resp.StatusCode, string(body), nil
+}
+
+// sendTestAlerts posts synthetic alert for the given receiver into each running pod of the VMAlertmanager
+func sendTestAlerts(ctx context.Context, rclient client.Client, amcfg *vmv1beta1.VMAlertmanagerConfig, am *vmv1beta1.VMAlertmanager, receiver string, now time.Time) ([]vmv1beta1.VMAlertmanagerReceiverTestResult, error) {
+	lbls, err := buildTestAlertLabels(amcfg, am, receiver)
+	if err != nil {
+		return nil, err
+	}
+	alert := testAlert{
+		Labels: lbls,
+		Annotations: map[string]string{
+			"summary": fmt.Sprintf("Test alert for receiver=%s of VMAlertmanagerConfig=%s/%s", receiver, amcfg.Namespace, amcfg.Name),
+		},
+		StartsAt: now,
+		EndsAt:   now.Add(testAlertDuration),
+	}
+	var creds *k8stools.BasicAuthCredentials
+	if am.Spec.WebConfig != nil && am.Spec.WebConfig.ClientBasicAuth != nil {
+		bac, err := k8stools.LoadBasicAuthSecret(ctx, rclient, am.Namespace, am.Spec.WebConfig.ClientBasicAuth, make(map[string]*corev1.Secret))
+		if err != nil {
+			return nil, fmt.Errorf("cannot load client basic auth of VMAlertmanager=%s/%s: %w", am.Namespace, am.Name, err)
+		}
+		creds = &bac
+	}
+	var pods corev1.PodList
+	if err := rclient.List(ctx, &pods, &client.ListOptions{Namespace: am.Namespace, LabelSelector: labels.SelectorFromSet(am.SelectorLabels())}); err != nil {
+		return nil, fmt.Errorf("cannot list vmalertmanager pods: %w", err)
+	}
+	amName := fmt.Sprintf("%s/%s", am.Namespace, am.Name)
+	var results []vmv1beta1.VMAlertmanagerReceiverTestResult
+	for _, pod := range pods.Items {
+		if !pod.DeletionTimestamp.IsZero() || pod.Status.Phase != corev1.PodRunning || pod.Status.PodIP == "" {
+			continue
+		}
+		result := vmv1beta1.VMAlertmanagerReceiverTestResult{
+			Alertmanager: amName,
+			Pod:          pod.Name,
+			Labels:       lbls,
+		}
+		hc, err := newTestReceiverHTTPClient(ctx, rclient, am, &pod)
+		if err != nil {
+			result.Response = fmt.Sprintf("cannot build http client: %s", err)
+			results = append(results, result)
+			continue
+		}
+		statusCode, response, err := postTestAlert(ctx, hc, am.PodAlertsURL(pod.Status.PodIP), creds, alert)
+		result.StatusCode = int32(statusCode)
+		result.Response = response
+		if err != nil {
+			result.Response = err.Error()
+		}
+		results = append(results, result)
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("VMAlertmanager=%s doesn't have running pods", amName)
+	}
+	return results, nil
+}
+
+// TestReceiver posts synthetic alert for the receiver defined with test-receiver annotation of VMAlertmanagerConfig
+// into the given VMAlertmanagers and records results at VMAlertmanagerConfig status.
+// Annotation is removed before alerts are sent, so the test is performed at most once per annotation
+func TestReceiver(ctx context.Context, rclient client.Client, amcfg *vmv1beta1.VMAlertmanagerConfig, ams []*vmv1beta1.VMAlertmanager) error {
+	receiver := amcfg.Annotations[vmv1beta1.VMAlertmanagerConfigTestReceiverAnnotation]
+	if receiver == "" {
+		return nil
+	}
+	// resourceVersion precondition prevents duplicate test, if annotation was already removed by concurrent reconcile
+	metaPatch, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{
+			"resourceVersion": amcfg.ResourceVersion,
+			"annotations": map[string]any{
+				vmv1beta1.VMAlertmanagerConfigTestReceiverAnnotation: nil,
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("cannot marshal annotation patch: %w", err)
+	}
+	if err := rclient.Patch(ctx, amcfg, client.RawPatch(types.MergePatchType, metaPatch)); err != nil {
+		return fmt.Errorf("cannot remove %s annotation: %w", vmv1beta1.VMAlertmanagerConfigTestReceiverAnnotation, err)
+	}
+
+	now := time.Now()
+	status := &vmv1beta1.VMAlertmanagerConfigReceiverTest{
+		Receiver:     receiver,
+		LastTestTime: metav1.NewTime(now),
+	}
+	var errs []string
+	for _, am := range ams {
+		results, err := sendTestAlerts(ctx, rclient, amcfg, am, receiver, now)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		status.Results = append(status.Results, results...)
+	}
+	if len(ams) == 0 {
+		errs = append(errs, "VMAlertmanagerConfig isn't selected by any VMAlertmanager")
+	}
+	if len(errs) > 0 {
+		status.Error = strings.Join(errs, ", ")
+	}
+	logger.WithContext(ctx).Info(fmt.Sprintf("sent test alerts for receiver=%s, results=%d", receiver, len(status.Results)))
+
+	// merge patch must explicitly reset fields of the previous test result
+	receiverTest := map[string]any{
+		"receiver":     status.Receiver,
+		"lastTestTime": status.LastTestTime,
+		"error":        nil,
+		"results":      nil,
+	}
+	if status.Error != "" {
+		receiverTest["error"] = status.Error
+	}
+	if len(status.Results) > 0 {
+		receiverTest["results"] = status.Results
+	}
+	statusPatch, err := json.Marshal(map[string]any{
+		"status": map[string]any{
+			"receiverTest": receiverTest,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("cannot marshal receiver test status patch: %w", err)
+	}
+	if err := rclient.Status().Patch(ctx, amcfg, client.RawPatch(types.MergePatchType, statusPatch)); err != nil {
+		return fmt.Errorf("cannot update receiver test status: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package alertmanager
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+
+	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/k8stools"
+)
+
+func TestBuildTestAlertLabels(t *testing.T) {
+	f := func(route *vmv1beta1.Route, enforcedMatchers []string, receiver string, want map[string]string, wantErr string) {
+		t.Helper()
+		amcfg := &vmv1beta1.VMAlertmanagerConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "team", Namespace: "team-a"},
+			Spec: vmv1beta1.VMAlertmanagerConfigSpec{
+				Route:     route,
+				Receivers: []vmv1beta1.Receiver{{Name: "default"}, {Name: "pagerduty"}, {Name: "unused"}},
+			},
+		}
+		am := &vmv1beta1.VMAlertmanager{
+			Spec: vmv1beta1.VMAlertmanagerSpec{EnforcedTopRouteMatchers: enforcedMatchers},
+		}
+		got, err := buildTestAlertLabels(amcfg, am, receiver)
+		if wantErr != "" {
+			assert.EqualError(t, err, wantErr)
+			return
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		assert.Equal(t, want, got)
+	}
+	route := &vmv1beta1.Route{
+		Receiver: "default",
+		Matchers: []string{`team = "infra"`},
+		Routes: []*vmv1beta1.SubRoute{
+			{
+				Receiver: "pagerduty",
+				Matchers: []string{`{severity =~ "critical", service =~ "db.*", env != "dev"}`},
+			},
+		},
+	}
+
+	// top route receiver
+	f(route, nil, "default", map[string]string{
+		"alertname": "VMAlertmanagerConfigReceiverTest",
+		"namespace": "team-a",
+		"team":      "infra",
+		"test":      "true",
+	}, "")
+
+	// nested route receiver with enforced matchers
+	f(route, []string{`cluster = "prod"`}, "pagerduty", map[string]string{
+		"alertname": "VMAlertmanagerConfigReceiverTest",
+		"cluster":   "prod",
+		"namespace": "team-a",
+		"severity":  "critical",
+		"team":      "infra",
+		"test":      "true",
+	}, "")
+
+	// missing receiver
+	f(route, nil, "missing", nil, `receiver="missing" is not defined at VMAlertmanagerConfig`)
+
+	// receiver without route
+	f(route, nil, "unused", nil, `receiver="unused" is not referenced by any route`)
+}
+
+func TestTestReceiver(t *testing.T) {
+	var gotAlerts []testAlert
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v2/alerts" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if user, pass, ok := r.BasicAuth(); !ok || user != "vmalert" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&gotAlerts); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+	host, port, err := net.SplitHostPort(srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("cannot parse server address: %s", err)
+	}
+	am := &vmv1beta1.VMAlertmanager{
+		ObjectMeta: metav1.ObjectMeta{Name: "am", Namespace: "monitoring"},
+		Spec: vmv1beta1.VMAlertmanagerSpec{
+			CommonDefaultableParams: vmv1beta1.CommonDefaultableParams{Port: port},
+			WebConfig: &vmv1beta1.AlertmanagerWebConfig{
+				ClientBasicAuth: &vmv1beta1.BasicAuth{
+					Username: corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "am-auth"}, Key: "username"},
+					Password: corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "am-auth"}, Key: "password"},
+				},
+			},
+		},
+	}
+	amcfg := &vmv1beta1.VMAlertmanagerConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "team",
+			Namespace:   "team-a",
+			Annotations: map[string]string{vmv1beta1.VMAlertmanagerConfigTestReceiverAnnotation: "pagerduty"},
+		},
+		Spec: vmv1beta1.VMAlertmanagerConfigSpec{
+			Route:     &vmv1beta1.Route{Receiver: "pagerduty"},
+			Receivers: []vmv1beta1.Receiver{{Name: "pagerduty"}},
+		},
+	}
+	ctx := context.TODO()
+	fclient := k8stools.GetTestClientWithObjects([]runtime.Object{
+		amcfg,
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "am-auth", Namespace: "monitoring"},
+			Data:       map[string][]byte{"username": []byte("vmalert"), "password": []byte("secret")},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "vmalertmanager-am-0", Namespace: "monitoring", Labels: am.SelectorLabels()},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning, PodIP: host},
+		},
+		// not running pod must be ignored
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "vmalertmanager-am-1", Namespace: "monitoring", Labels: am.SelectorLabels()},
+			Status:     corev1.PodStatus{Phase: corev1.PodPending},
+		},
+	})
+	if err := TestReceiver(ctx, fclient, amcfg, []*vmv1beta1.VMAlertmanager{am}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	wantLabels := map[string]string{
+		"alertname": "VMAlertmanagerConfigReceiverTest",
+		"namespace": "team-a",
+		"test":      "true",
+	}
+	if assert.Len(t, gotAlerts, 1) {
+		assert.Equal(t, wantLabels, gotAlerts[0].Labels)
+		assert.Equal(t, testAlertDuration, gotAlerts[0].EndsAt.Sub(gotAlerts[0].StartsAt))
+	}
+
+	var got vmv1beta1.VMAlertmanagerConfig
+	if err := fclient.Get(ctx, types.NamespacedName{Namespace: "team-a", Name: "team"}, &got); err != nil {
+		t.Fatalf("cannot get VMAlertmanagerConfig: %s", err)
+	}
+	assert.NotContains(t, got.Annotations, vmv1beta1.VMAlertmanagerConfigTestReceiverAnnotation)
+	if assert.NotNil(t, got.Status.ReceiverTest) {
+		assert.Equal(t, "pagerduty", got.Status.ReceiverTest.Receiver)
+		assert.Empty(t, got.Status.ReceiverTest.Error)
+		assert.Equal(t, []vmv1beta1.VMAlertmanagerReceiverTestResult{{
+			Alertmanager: "monitoring/am",
+			Pod:          "vmalertmanager-am-0",
+			Labels:       wantLabels,
+			StatusCode:   http.StatusOK,
+		}}, got.Status.ReceiverTest.Results)
+	}
+
+	// annotation was already removed by concurrent reconcile
+	gotAlerts = nil
+	stale := amcfg.DeepCopy()
+	stale.ResourceVersion = "1"
+	stale.Annotations = map[string]string{vmv1beta1.VMAlertmanagerConfigTestReceiverAnnotation: "pagerduty"}
+	assert.Error(t, TestReceiver(ctx, fclient, stale, []*vmv1beta1.VMAlertmanager{am}))
+	assert.Empty(t, gotAlerts)
+
+	// config without parent alertmanagers
+	amcfg.Annotations = map[string]string{vmv1beta1.VMAlertmanagerConfigTestReceiverAnnotation: "pagerduty"}
+	if err := TestReceiver(ctx, fclient, amcfg, nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := fclient.Get(ctx, types.NamespacedName{Namespace: "team-a", Name: "team"}, &got); err != nil {
+		t.Fatalf("cannot get VMAlertmanagerConfig: %s", err)
+	}
+	assert.Equal(t, "VMAlertmanagerConfig isn't selected by any VMAlertmanager", got.Status.ReceiverTest.Error)
+	assert.Empty(t, got.Status.ReceiverTest.Results)
+}
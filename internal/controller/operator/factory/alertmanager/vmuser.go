@@ -0,0 +1,59 @@
+package alertmanager
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/reconcile"
+)
+
+// buildVMUser returns VMUser, which routes requests with exposure path prefix from VMAuth to alertmanager service.
+// Path prefix is dropped by VMAuth, since alertmanager serves requests from the root path
+func buildVMUser(cr *vmv1beta1.VMAlertmanager) *vmv1beta1.VMUser {
+	exposurePath := cr.VMAuthExposurePath()
+	prefixParts := strings.Count(strings.Trim(exposurePath, "/"), "/") + 1
+	return &vmv1beta1.VMUser{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            cr.PrefixedName(),
+			Namespace:       cr.Namespace,
+			Labels:          labels.Merge(cr.AllLabels(), cr.Spec.ExposeViaVMAuth.VMAuthSelector),
+			Annotations:     cr.AnnotationsFiltered(),
+			OwnerReferences: cr.AsOwner(),
+		},
+		Spec: vmv1beta1.VMUserSpec{
+			UserName:         ptr.To(cr.PrefixedName()),
+			GeneratePassword: true,
+			TargetRefs: []vmv1beta1.TargetRef{
+				{
+					CRD: &vmv1beta1.CRDRef{
+						Kind:      "VMAlertmanager",
+						Name:      cr.Name,
+						Namespace: cr.Namespace,
+					},
+					Paths: []string{exposurePath + ".*"},
+					URLMapCommon: vmv1beta1.URLMapCommon{
+						DropSrcPathPrefixParts: ptr.To(prefixParts),
+					},
+				},
+			},
+		},
+	}
+}
+
+// createOrUpdateVMUser reconciles VMUser, which exposes alertmanager via VMAuth
+func createOrUpdateVMUser(ctx context.Context, rclient client.Client, cr *vmv1beta1.VMAlertmanager) error {
+	if cr.Spec.ExposeViaVMAuth == nil {
+		return nil
+	}
+	if err := reconcile.VMUserForCRD(ctx, rclient, buildVMUser(cr)); err != nil {
+		return fmt.Errorf("cannot reconcile VMUser for vmauth exposure: %w", err)
+	}
+	return nil
+}
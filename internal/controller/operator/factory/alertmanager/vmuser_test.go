@@ -0,0 +1,79 @@
+package alertmanager
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+
+	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/k8stools"
+)
+
+func TestCreateOrUpdateVMUser(t *testing.T) {
+	cr := &vmv1beta1.VMAlertmanager{
+		ObjectMeta: metav1.ObjectMeta{Name: "am", Namespace: "monitoring"},
+		Spec: vmv1beta1.VMAlertmanagerSpec{
+			ExposeViaVMAuth: &vmv1beta1.AlertmanagerVMAuthExposure{
+				VMAuthSelector: map[string]string{"vmauth": "main"},
+				Path:           "/ui/alertmanager",
+			},
+		},
+	}
+	ctx := context.TODO()
+	fclient := k8stools.GetTestClientWithObjects(nil)
+	if err := createOrUpdateVMUser(ctx, fclient, cr); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	nsn := types.NamespacedName{Namespace: "monitoring", Name: "vmalertmanager-am"}
+	var got vmv1beta1.VMUser
+	if err := fclient.Get(ctx, nsn, &got); err != nil {
+		t.Fatalf("cannot get VMUser: %s", err)
+	}
+	assert.Equal(t, "main", got.Labels["vmauth"])
+	assert.Equal(t, []vmv1beta1.TargetRef{{
+		CRD:   &vmv1beta1.CRDRef{Kind: "VMAlertmanager", Name: "am", Namespace: "monitoring"},
+		Paths: []string{"/ui/alertmanager/.*"},
+		URLMapCommon: vmv1beta1.URLMapCommon{
+			DropSrcPathPrefixParts: ptr.To(2),
+		},
+	}}, got.Spec.TargetRefs)
+
+	// default path
+	cr.Spec.ExposeViaVMAuth.Path = ""
+	if err := createOrUpdateVMUser(ctx, fclient, cr); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := fclient.Get(ctx, nsn, &got); err != nil {
+		t.Fatalf("cannot get VMUser: %s", err)
+	}
+	assert.Equal(t, []string{"/alertmanager/.*"}, got.Spec.TargetRefs[0].Paths)
+	assert.Equal(t, ptr.To(1), got.Spec.TargetRefs[0].DropSrcPathPrefixParts)
+
+	// external url is derived from exposure path
+	sts, err := newStsForAlertManager(cr)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.Contains(t, sts.Spec.Template.Spec.Containers[0].Args, "--web.external-url=http://$(POD_NAME):9093/alertmanager/")
+	cr.Spec.ExternalURL = "https://vmauth.example.com/alertmanager/"
+	sts, err = newStsForAlertManager(cr)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.Contains(t, sts.Spec.Template.Spec.Containers[0].Args, "--web.external-url=https://vmauth.example.com/alertmanager/")
+
+	// VMUser must be removed on disable
+	prevSpec := cr.Spec.DeepCopy()
+	cr.Spec.ExposeViaVMAuth = nil
+	cr.ParsedLastAppliedSpec = prevSpec
+	if err := deletePrevStateResources(ctx, cr, fclient); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	err = fclient.Get(ctx, nsn, &got)
+	assert.True(t, k8serrors.IsNotFound(err), "VMUser must be removed, got err: %v", err)
+}
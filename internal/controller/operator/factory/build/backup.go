@@ -171,7 +171,11 @@ func VMBackupManager(
 func VMRestore(
 	cr *vmv1beta1.VMBackup,
 	storagePath, dataVolumeName string,
+	license *vmv1beta1.License,
 ) (*corev1.Container, error) {
+	if !cr.AcceptEULA && !license.IsProvided() {
+		return nil, nil
+	}
 
 	args := []string{
 		fmt.Sprintf("-storageDataPath=%s", storagePath),
@@ -214,6 +218,10 @@ func VMRestore(
 		})
 		args = append(args, fmt.Sprintf("-credsFilePath=%s/%s", vmBackuperCreds, cr.CredentialsSecret.Key))
 	}
+
+	_, mounts = license.MaybeAddToVolumes(nil, mounts, vmv1beta1.SecretsDir)
+	args = license.MaybeAddToArgs(args, vmv1beta1.SecretsDir)
+
 	extraEnvs := cr.ExtraEnvs
 	if len(cr.ExtraEnvs) > 0 {
 		args = append(args, "-envflag.enable=true")
@@ -30,9 +30,14 @@ type builderOpts interface {
 
 // PodDNSAddress formats pod dns address with optional domain name
 func PodDNSAddress(baseName string, podIndex int32, namespace string, portName string, domain string) string {
+	return ServicePodDNSAddress(fmt.Sprintf("%s-%d", baseName, podIndex), baseName, namespace, portName, domain)
+}
+
+// ServicePodDNSAddress formats dns address for the pod governed by the given headless service with optional domain name
+func ServicePodDNSAddress(podName, serviceName string, namespace string, portName string, domain string) string {
 	// The default DNS search path is .svc.<cluster domain>
 	if domain == "" {
-		return fmt.Sprintf("%s-%d.%s.%s:%s,", baseName, podIndex, baseName, namespace, portName)
+		return fmt.Sprintf("%s.%s.%s:%s,", podName, serviceName, namespace, portName)
 	}
-	return fmt.Sprintf("%s-%d.%s.%s.svc.%s:%s,", baseName, podIndex, baseName, namespace, domain, portName)
+	return fmt.Sprintf("%s.%s.%s.svc.%s:%s,", podName, serviceName, namespace, domain, portName)
 }
@@ -22,6 +22,12 @@ type TLSConfigBuilder struct {
 	TLSAssets          map[string]string
 }
 
+// CacheKey returns key for SecretCache and ConfigmapCache of the object from the current CR namespace.
+// Caches could be shared between CRs from different namespaces, so object name cannot be used as a key
+func (cb *TLSConfigBuilder) CacheKey(name string) string {
+	return fmt.Sprintf("%s/%s", cb.CurrentCRNamespace, name)
+}
+
 // BuildTLSConfig return map with paths to tls config keys
 // let caller to use their own json tag
 func (cb *TLSConfigBuilder) BuildTLSConfig(tlsCfg *vmv1beta1.TLSConfig, tlsAssetsDir string) (map[string]any, error) {
@@ -75,19 +81,19 @@ func (cb *TLSConfigBuilder) fetchSecretWithAssets(ss *corev1.SecretKeySelector,
 	var value string
 	if ss != nil {
 		var s corev1.Secret
-		if v, ok := cb.SecretCache[ss.Name]; ok {
+		if v, ok := cb.SecretCache[cb.CacheKey(ss.Name)]; ok {
 			s = *v
 		} else {
 			if err := cb.Client.Get(cb.Ctx, types.NamespacedName{Namespace: cb.CurrentCRNamespace, Name: ss.Name}, &s); err != nil {
 				return fmt.Errorf("cannot fetch secret=%q for tlsAsset, err=%w", ss.Name, err)
 			}
-			cb.SecretCache[ss.Name] = &s
+			cb.SecretCache[cb.CacheKey(ss.Name)] = &s
 		}
 		value = string(s.Data[ss.Key])
 	}
 	if cs != nil {
 		var c corev1.ConfigMap
-		if v, ok := cb.ConfigmapCache[cs.Name]; ok {
+		if v, ok := cb.ConfigmapCache[cb.CacheKey(cs.Name)]; ok {
 			c = *v
 		} else {
 			if err := cb.Client.Get(cb.Ctx, types.NamespacedName{Namespace: cb.CurrentCRNamespace, Name: cs.Name}, &c); err != nil {
@@ -105,6 +105,41 @@ func Probe(container corev1.Container, cr probeCRD) corev1.Container {
 	return container
 }
 
+const (
+	defaultStartupProbeFailureThreshold int32 = 10
+	// each config object may take up to 30s to load for large configurations
+	startupProbePeriodsPerObject int32 = 6
+)
+
+// StartupProbeFailureThreshold returns failureThreshold of default startupProbe
+// for application, which loads the given count of config objects at start
+func StartupProbeFailureThreshold(objectsCount int) int32 {
+	return defaultStartupProbeFailureThreshold + int32(objectsCount)*startupProbePeriodsPerObject
+}
+
+// AddDefaultStartupProbe adds startupProbe with given failureThreshold to the container,
+// if it isn't defined at CRD. Liveness and readiness probes are started only after startupProbe success,
+// it allows application with large configuration to start without restarts by liveness probe
+func AddDefaultStartupProbe(container corev1.Container, cr probeCRD, failureThreshold int32) corev1.Container {
+	if container.StartupProbe != nil {
+		return container
+	}
+	container.StartupProbe = &corev1.Probe{
+		ProbeHandler: corev1.ProbeHandler{
+			HTTPGet: &corev1.HTTPGetAction{
+				Port:   intstr.Parse(cr.ProbePort()),
+				Scheme: corev1.URIScheme(cr.ProbeScheme()),
+				Path:   cr.ProbePath(),
+			},
+		},
+		TimeoutSeconds:   probeTimeoutSeconds,
+		PeriodSeconds:    5,
+		FailureThreshold: failureThreshold,
+		SuccessThreshold: 1,
+	}
+	return container
+}
+
 // Resources creates containter resources with conditional defaults values
 func Resources(crdResources corev1.ResourceRequirements, defaultResources config.Resource, useDefault bool) corev1.ResourceRequirements {
 	if crdResources.Requests == nil {
@@ -159,19 +194,22 @@ func AddExtraArgsOverrideDefaults(args []string, extraArgs map[string]string, da
 		return args
 	}
 	cleanArg := func(arg string) string {
-		if idx := strings.Index(arg, dashes); idx == 0 {
-			arg = arg[len(dashes):]
-		}
+		arg = strings.TrimLeft(arg, "-")
 		idx := strings.IndexByte(arg, '=')
 		if idx > 0 {
 			arg = arg[:idx]
 		}
 		return arg
 	}
+	// extraArgs keys could be defined with leading dashes
+	normalizedArgs := make(map[string]string, len(extraArgs))
+	for argKey, argValue := range extraArgs {
+		normalizedArgs[strings.TrimLeft(argKey, "-")] = argValue
+	}
 	var cnt int
 	for _, arg := range args {
 		argKey := cleanArg(arg)
-		if _, ok := extraArgs[argKey]; ok {
+		if _, ok := normalizedArgs[argKey]; ok {
 			continue
 		}
 		args[cnt] = arg
@@ -180,12 +218,7 @@ func AddExtraArgsOverrideDefaults(args []string, extraArgs map[string]string, da
 	// trim in-place
 	args = args[:cnt]
 	// add extraArgs
-	for argKey, argValue := range extraArgs {
-		// hack for alertmanager migration
-		// TODO remove it at the 28.0 release
-		if len(dashes) == 2 && strings.HasPrefix(argKey, "-") {
-			argKey = strings.TrimPrefix(argKey, "-")
-		}
+	for argKey, argValue := range normalizedArgs {
 		// special hack for https://github.com/VictoriaMetrics/VictoriaMetrics/issues/1145
 		if argKey == "rule" {
 			args = append(args, fmt.Sprintf("%s%s=%q", dashes, argKey, argValue))
@@ -196,6 +229,11 @@ func AddExtraArgsOverrideDefaults(args []string, extraArgs map[string]string, da
 	return args
 }
 
+// FormatContainerImage returns container image with global container registry prefix if needed.
+func FormatContainerImage(containerImage string) string {
+	return formatContainerImage(getCfg().ContainerRegistry, containerImage)
+}
+
 // formatContainerImage returns container image with registry prefix if needed.
 func formatContainerImage(globalRepo string, containerImage string) string {
 	if globalRepo == "" {
@@ -178,6 +178,33 @@ func Test_buildProbe(t *testing.T) {
 	}
 }
 
+func TestAddDefaultStartupProbe(t *testing.T) {
+	f := func(ep *vmv1beta1.EmbeddedProbes, objectsCount int, wantFailureThreshold int32) {
+		t.Helper()
+		cr := testBuildProbeCR{
+			ep:              ep,
+			probePath:       func() string { return "/health" },
+			port:            "8080",
+			scheme:          "HTTP",
+			needAddLiveness: true,
+		}
+		got := AddDefaultStartupProbe(Probe(corev1.Container{}, cr), cr, StartupProbeFailureThreshold(objectsCount))
+		if assert.NotNil(t, got.StartupProbe) {
+			assert.Equal(t, wantFailureThreshold, got.StartupProbe.FailureThreshold)
+			assert.Equal(t, "/health", got.StartupProbe.HTTPGet.Path)
+		}
+	}
+
+	// no config objects
+	f(nil, 0, 10)
+
+	// threshold grows with config objects count
+	f(nil, 5, 40)
+
+	// custom startup probe
+	f(&vmv1beta1.EmbeddedProbes{StartupProbe: &corev1.Probe{FailureThreshold: 100}}, 5, 100)
+}
+
 func Test_addExtraArgsOverrideDefaults(t *testing.T) {
 	type args struct {
 		args      []string
@@ -242,6 +269,24 @@ func Test_addExtraArgsOverrideDefaults(t *testing.T) {
 			},
 			want: []string{"--log.level=info", "--web.externalURL=http://domain.example"},
 		},
+		{
+			name: "dashed key, override default",
+			args: args{
+				args:      []string{"-retentionPeriod=1", "--graphiteListenAddr=:2003"},
+				extraArgs: map[string]string{"-retentionPeriod": "2"},
+				dashes:    "-",
+			},
+			want: []string{"--graphiteListenAddr=:2003", "-retentionPeriod=2"},
+		},
+		{
+			name: "double dashed default, override",
+			args: args{
+				args:      []string{"--graphiteListenAddr=:2003"},
+				extraArgs: map[string]string{"graphiteListenAddr": ":2004"},
+				dashes:    "-",
+			},
+			want: []string{"-graphiteListenAddr=:2004"},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -206,6 +206,18 @@ func addVMAgentDefaults(objI any) {
 	cv := config.ApplicationDefaults(c.VMAgentDefault)
 	addDefaultsToCommonParams(&cr.Spec.CommonDefaultableParams, &cv)
 	addDefaluesToConfigReloader(&cr.Spec.CommonConfigReloaderParams, ptr.Deref(cr.Spec.UseDefaultResources, false), &cv)
+	if mp := cr.Spec.ManagedProber; mp != nil {
+		if mp.Image.Repository == "" {
+			mp.Image.Repository = c.VMProberDefault.Image
+		}
+		mp.Image.Repository = formatContainerImage(c.ContainerRegistry, mp.Image.Repository)
+		if mp.Image.Tag == "" {
+			mp.Image.Tag = c.VMProberDefault.Version
+		}
+		if mp.Image.PullPolicy == "" {
+			mp.Image.PullPolicy = corev1.PullIfNotPresent
+		}
+	}
 }
 
 func addVMSingleDefaults(objI any) {
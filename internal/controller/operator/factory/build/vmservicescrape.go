@@ -120,7 +120,7 @@ func vmServiceScrapeForServiceWithSpec(service *v1.Service, serviceScrapeSpec *v
 			Labels:          service.Labels,
 			Annotations:     service.Annotations,
 		},
-		Spec: *serviceScrapeSpec,
+		Spec: *serviceScrapeSpec.DeepCopy(),
 	}
 	// merge generated endpoints into user defined values by Port name
 	// assume, that it must be unique.
@@ -133,6 +133,17 @@ func vmServiceScrapeForServiceWithSpec(service *v1.Service, serviceScrapeSpec *v
 				if eps.Path == "" {
 					eps.Path = generatedEP.Path
 				}
+				// user defined relabeling and intervals must be preserved
+				// but scheme and tls must match component listen params
+				if eps.Scheme == "" {
+					eps.Scheme = generatedEP.Scheme
+				}
+				if eps.TLSConfig == nil {
+					eps.TLSConfig = generatedEP.TLSConfig
+				}
+				if eps.Params == nil {
+					eps.Params = generatedEP.Params
+				}
 			}
 		}
 		if !found {
@@ -289,6 +289,61 @@ func TestVMServiceScrapeForServiceWithSpec(t *testing.T) {
 				Selector: metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: vmv1beta1.AdditionalServiceLabel, Operator: metav1.LabelSelectorOpDoesNotExist}}},
 			},
 		},
+		{
+			name: "with tls and user defined endpoint",
+			args: testVMServiceScrapeForServiceWithSpecArgs{
+				metricPath: "/metrics",
+				service: &corev1.Service{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "vmselect-svc",
+					},
+					Spec: corev1.ServiceSpec{
+						Ports: []corev1.ServicePort{
+							{
+								Name: "http",
+							},
+						},
+					},
+				},
+				extraArgs: map[string]string{
+					"tls": "true",
+				},
+				serviceScrapeSpecTemplate: &vmv1beta1.VMServiceScrapeSpec{
+					Endpoints: []vmv1beta1.Endpoint{
+						{
+							Port: "http",
+							EndpointScrapeParams: vmv1beta1.EndpointScrapeParams{
+								Interval: "10s",
+							},
+							EndpointRelabelings: vmv1beta1.EndpointRelabelings{
+								MetricRelabelConfigs: []*vmv1beta1.RelabelConfig{
+									{Action: "drop", SourceLabels: []string{"__name__"}, Regex: vmv1beta1.StringOrArray{"go_.*"}},
+								},
+							},
+						},
+					},
+				},
+			},
+			wantServiceScrapeSpec: vmv1beta1.VMServiceScrapeSpec{
+				Endpoints: []vmv1beta1.Endpoint{
+					{
+						EndpointScrapeParams: vmv1beta1.EndpointScrapeParams{
+							Path:     "/metrics",
+							Scheme:   "https",
+							Interval: "10s",
+						},
+						EndpointRelabelings: vmv1beta1.EndpointRelabelings{
+							MetricRelabelConfigs: []*vmv1beta1.RelabelConfig{
+								{Action: "drop", SourceLabels: []string{"__name__"}, Regex: vmv1beta1.StringOrArray{"go_.*"}},
+							},
+						},
+						EndpointAuth: vmv1beta1.EndpointAuth{TLSConfig: &vmv1beta1.TLSConfig{InsecureSkipVerify: true}},
+						Port:         "http",
+					},
+				},
+				Selector: metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: vmv1beta1.AdditionalServiceLabel, Operator: metav1.LabelSelectorOpDoesNotExist}}},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	appsv1 "k8s.io/api/apps/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -95,3 +96,27 @@ func discoverSTSsByLabels(ctx context.Context, rclient client.Client, ns string,
 	}
 	return resp, nil
 }
+
+// RemoveOrphanedPDBs removes pod disruption budgets detached from given object
+func RemoveOrphanedPDBs(ctx context.Context, rclient client.Client, cr orphanedCRD, keepPDBNames map[string]struct{}) error {
+	var pdbs policyv1.PodDisruptionBudgetList
+	opts := client.ListOptions{
+		Namespace:     cr.GetNSName(),
+		LabelSelector: labels.SelectorFromSet(cr.SelectorLabels()),
+	}
+	if err := rclient.List(ctx, &pdbs, &opts); err != nil {
+		return err
+	}
+	for i := range pdbs.Items {
+		pdb := &pdbs.Items[i]
+		if _, ok := keepPDBNames[pdb.Name]; !ok {
+			if err := RemoveFinalizer(ctx, rclient, pdb); err != nil {
+				return err
+			}
+			if err := SafeDelete(ctx, rclient, pdb); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -2,6 +2,8 @@ package finalize
 
 import (
 	"context"
+	"fmt"
+	"strings"
 
 	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
 	"github.com/VictoriaMetrics/operator/internal/config"
@@ -21,6 +23,9 @@ func OnVMAgentDelete(ctx context.Context, rclient client.Client, crd *vmv1beta1.
 	if err := removeFinalizeObjByName(ctx, rclient, &appsv1.StatefulSet{}, crd.PrefixedName(), crd.Namespace); err != nil {
 		return err
 	}
+	if err := removeFinalizeObjByName(ctx, rclient, &appsv1.DaemonSet{}, crd.PrefixedName(), crd.Namespace); err != nil {
+		return err
+	}
 
 	if err := RemoveOrphanedDeployments(ctx, rclient, crd, nil); err != nil {
 		return err
@@ -41,6 +46,15 @@ func OnVMAgentDelete(ctx context.Context, rclient client.Client, crd *vmv1beta1.
 	if err := removeFinalizeObjByName(ctx, rclient, &corev1.Secret{}, crd.PrefixedName(), crd.Namespace); err != nil {
 		return err
 	}
+	for idx := range int(crd.Status.ConfigSecretParts) {
+		if err := removeFinalizeObjByName(ctx, rclient, &corev1.Secret{}, crd.ConfigSecretPartName(idx), crd.Namespace); err != nil {
+			return err
+		}
+	}
+	// stale config parts are kept until vmagent pods update and could be missing at status
+	if err := removeConfigSecretParts(ctx, rclient, crd); err != nil {
+		return err
+	}
 
 	// check secret for tls assests
 	if err := removeFinalizeObjByName(ctx, rclient, &corev1.Secret{}, crd.TLSAssetName(), crd.Namespace); err != nil {
@@ -54,6 +68,19 @@ func OnVMAgentDelete(ctx context.Context, rclient client.Client, crd *vmv1beta1.
 	if err := removeFinalizeObjByName(ctx, rclient, &corev1.ConfigMap{}, crd.StreamAggrConfigName(), crd.Namespace); err != nil {
 		return err
 	}
+	if err := removeFinalizeObjByName(ctx, rclient, &corev1.ConfigMap{}, crd.RenderedConfigName(), crd.Namespace); err != nil {
+		return err
+	}
+	// managed prober
+	if err := removeFinalizeObjByName(ctx, rclient, &appsv1.Deployment{}, crd.ProberName(), crd.Namespace); err != nil {
+		return err
+	}
+	if err := removeFinalizeObjByName(ctx, rclient, &corev1.Service{}, crd.ProberName(), crd.Namespace); err != nil {
+		return err
+	}
+	if err := removeFinalizeObjByName(ctx, rclient, &corev1.ConfigMap{}, crd.ProberName(), crd.Namespace); err != nil {
+		return err
+	}
 
 	// check PDB
 	if crd.Spec.PodDisruptionBudget != nil {
@@ -113,3 +140,22 @@ func OnVMAgentDelete(ctx context.Context, rclient client.Client, crd *vmv1beta1.
 	}
 	return nil
 }
+
+// removeConfigSecretParts removes finalizers from all config part Secrets of the given VMAgent
+func removeConfigSecretParts(ctx context.Context, rclient client.Client, crd *vmv1beta1.VMAgent) error {
+	var secretList corev1.SecretList
+	if err := rclient.List(ctx, &secretList, client.InNamespace(crd.Namespace), client.MatchingLabels(crd.SelectorLabels())); err != nil {
+		return fmt.Errorf("cannot list vmagent config secret parts: %w", err)
+	}
+	prefix := crd.PrefixedName() + "-config-"
+	for i := range secretList.Items {
+		s := &secretList.Items[i]
+		if !strings.HasPrefix(s.Name, prefix) {
+			continue
+		}
+		if err := RemoveFinalizer(ctx, rclient, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
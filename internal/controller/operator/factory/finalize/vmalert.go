@@ -17,6 +17,10 @@ func OnVMAlertDelete(ctx context.Context, rclient client.Client, crd *vmv1beta1.
 	if err := removeFinalizeObjByName(ctx, rclient, &appsv1.Deployment{}, crd.PrefixedName(), crd.Namespace); err != nil {
 		return err
 	}
+	// check sharded deployments
+	if err := RemoveOrphanedDeployments(ctx, rclient, crd, nil); err != nil {
+		return err
+	}
 	// check service
 	if err := removeFinalizeObjByName(ctx, rclient, &corev1.Service{}, crd.PrefixedName(), crd.Namespace); err != nil {
 		return err
@@ -32,16 +36,35 @@ func OnVMAlertDelete(ctx context.Context, rclient client.Client, crd *vmv1beta1.
 			return fmt.Errorf("failed to remove finalizer from vmalert cm=%q: %w", cm.Name, err)
 		}
 	}
+	var secretList corev1.SecretList
+	if err := rclient.List(ctx, &secretList, crd.RulesConfigMapSelector()); err != nil {
+		return err
+	}
+	for _, s := range secretList.Items {
+		if err := vmv1beta1.RemoveFinalizer(&s, func(o client.Object) error {
+			return patchReplaceFinalizers(ctx, rclient, o)
+		}); err != nil {
+			return fmt.Errorf("failed to remove finalizer from vmalert rules secret=%q: %w", s.Name, err)
+		}
+	}
 	// check secret
 	if err := removeFinalizeObjByName(ctx, rclient, &corev1.Secret{}, crd.TLSAssetName(), crd.Namespace); err != nil {
 		return err
 	}
+	// check notifiers config
+	if err := removeFinalizeObjByName(ctx, rclient, &corev1.Secret{}, crd.NotifiersConfigName(), crd.Namespace); err != nil {
+		return err
+	}
 
 	// check PDB
 	if crd.Spec.PodDisruptionBudget != nil {
 		if err := finalizePBD(ctx, rclient, crd); err != nil {
 			return err
 		}
+		// check sharded PDBs
+		if err := RemoveOrphanedPDBs(ctx, rclient, crd, nil); err != nil {
+			return err
+		}
 	}
 	if err := deleteSA(ctx, rclient, crd); err != nil {
 		return err
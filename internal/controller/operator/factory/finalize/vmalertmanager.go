@@ -35,6 +35,9 @@ func OnVMAlertManagerDelete(ctx context.Context, rclient client.Client, crd *vmv
 			return err
 		}
 	}
+	if err := removeFinalizeObjByName(ctx, rclient, &v1.ConfigMap{}, crd.RouteSummaryConfigMapName(), crd.Namespace); err != nil {
+		return err
+	}
 
 	// check PDB
 	if crd.Spec.PodDisruptionBudget != nil {
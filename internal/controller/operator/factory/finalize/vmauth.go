@@ -9,6 +9,7 @@ import (
 	networkingv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	gwapiv1 "sigs.k8s.io/gateway-api/apis/v1"
 )
 
 // OnVMAuthDelete deletes all vmauth related resources
@@ -58,6 +59,11 @@ func OnVMAuthDelete(ctx context.Context, rclient client.Client, crd *vmv1beta1.V
 	if err := removeFinalizeObjByName(ctx, rclient, &networkingv1.Ingress{}, crd.PrefixedName(), crd.Namespace); err != nil {
 		return err
 	}
+	if crd.Spec.HTTPRoute != nil {
+		if err := removeFinalizeObjByName(ctx, rclient, &gwapiv1.HTTPRoute{}, crd.PrefixedName(), crd.Namespace); err != nil {
+			return err
+		}
+	}
 
 	if err := deleteSA(ctx, rclient, crd); err != nil {
 		return err
@@ -73,6 +73,18 @@ func OnVMSelectDelete(ctx context.Context, rclient client.Client, crd *vmv1beta1
 			},
 		})
 	}
+	if obj.PerReplicaService {
+		for i := int32(0); i < ptr.Deref(obj.ReplicaCount, 1); i++ {
+			objsToRemove = append(objsToRemove, &v1.Service{ObjectMeta: metav1.ObjectMeta{Name: crd.GetVMSelectReplicaServiceName(i), Namespace: crd.Namespace}})
+		}
+	}
+	for _, p := range obj.AdditionalPools {
+		poolMeta := metav1.ObjectMeta{Namespace: crd.Namespace, Name: crd.GetVMSelectPoolName(p.Name)}
+		objsToRemove = append(objsToRemove, &appsv1.StatefulSet{ObjectMeta: poolMeta}, &v1.Service{ObjectMeta: poolMeta})
+		if !ptr.Deref(obj.DisableSelfServiceScrape, false) {
+			objsToRemove = append(objsToRemove, &vmv1beta1.VMServiceScrape{ObjectMeta: poolMeta})
+		}
+	}
 	if obj.PodDisruptionBudget != nil {
 		objsToRemove = append(objsToRemove, &policyv1.PodDisruptionBudget{ObjectMeta: objMeta})
 	}
@@ -104,6 +116,12 @@ func OnVMStorageDelete(ctx context.Context, rclient client.Client, crd *vmv1beta
 		&appsv1.StatefulSet{ObjectMeta: objMeta},
 		&v1.Service{ObjectMeta: objMeta},
 	}
+	for _, g := range obj.StorageNodeGroups {
+		objsToRemove = append(objsToRemove, &appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{
+			Namespace: crd.Namespace,
+			Name:      crd.GetVMStorageGroupName(g.Name),
+		}})
+	}
 	if obj.ServiceSpec != nil && !obj.ServiceSpec.UseAsDefault {
 		objsToRemove = append(objsToRemove, &v1.Service{
 			ObjectMeta: metav1.ObjectMeta{
@@ -0,0 +1,73 @@
+package k8stools
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"time"
+
+	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// NewHTTPClient returns client for requests from operator to pods of managed application
+// TLS assets are loaded from secret and configmap references at the given namespace
+// File paths are rejected, since they're resolved at operator filesystem
+func NewHTTPClient(ctx context.Context, rclient client.Client, ns string, tc *vmv1beta1.TLSConfig, timeout time.Duration) (*http.Client, error) {
+	if tc == nil {
+		return &http.Client{Timeout: timeout}, nil
+	}
+	if tc.CAFile != "" || tc.CertFile != "" || tc.KeyFile != "" {
+		return nil, fmt.Errorf("file paths are not supported at tlsConfig, use secret or configmap references")
+	}
+	tlsCfg := &tls.Config{
+		ServerName:         tc.ServerName,
+		InsecureSkipVerify: tc.InsecureSkipVerify, // #nosec G402
+	}
+	ca, err := fetchTLSContent(ctx, rclient, ns, tc.CA)
+	if err != nil {
+		return nil, fmt.Errorf("cannot fetch CA: %w", err)
+	}
+	if len(ca) > 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("cannot parse CA")
+		}
+		tlsCfg.RootCAs = pool
+	}
+	cert, err := fetchTLSContent(ctx, rclient, ns, tc.Cert)
+	if err != nil {
+		return nil, fmt.Errorf("cannot fetch cert: %w", err)
+	}
+	key, err := fetchTLSContent(ctx, rclient, ns, vmv1beta1.SecretOrConfigMap{Secret: tc.KeySecret})
+	if err != nil {
+		return nil, fmt.Errorf("cannot fetch key: %w", err)
+	}
+	if len(cert) > 0 || len(key) > 0 {
+		keyPair, err := tls.X509KeyPair(cert, key)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load client certificate: %w", err)
+		}
+		tlsCfg.Certificates = []tls.Certificate{keyPair}
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: &http.Transport{TLSClientConfig: tlsCfg},
+	}, nil
+}
+
+// fetchTLSContent returns content of the given secret or configmap key
+func fetchTLSContent(ctx context.Context, rclient client.Client, ns string, src vmv1beta1.SecretOrConfigMap) ([]byte, error) {
+	switch {
+	case src.Secret != nil:
+		data, err := GetCredFromSecret(ctx, rclient, ns, src.Secret, buildCacheKey(ns, src.Secret.Name), map[string]*corev1.Secret{})
+		return []byte(data), err
+	case src.ConfigMap != nil:
+		data, err := GetCredFromConfigMap(ctx, rclient, ns, *src.ConfigMap, buildCacheKey(ns, src.ConfigMap.Name), map[string]*corev1.ConfigMap{})
+		return []byte(data), err
+	}
+	return nil, nil
+}
@@ -155,7 +155,7 @@ func GetCredFromConfigMap(
 		s = &corev1.ConfigMap{}
 		err := rclient.Get(ctx, types.NamespacedName{Namespace: ns, Name: sel.Name}, s)
 		if err != nil {
-			return "", fmt.Errorf("cannot get configmap: %s at namespace %s, err: %s", sel.Name, ns, err)
+			return "", fmt.Errorf("cannot get configmap: %s at namespace %s, err: %w", sel.Name, ns, err)
 		}
 		cache[cacheKey] = s
 	}
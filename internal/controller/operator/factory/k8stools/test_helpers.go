@@ -16,6 +16,7 @@ import (
 	"k8s.io/client-go/kubernetes/scheme"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	gwapiv1 "sigs.k8s.io/gateway-api/apis/v1"
 )
 
 func testGetScheme() *runtime.Scheme {
@@ -57,6 +58,10 @@ func testGetScheme() *runtime.Scheme {
 		&vmv1beta1.VMCluster{},
 		&vmv1beta1.VLogs{},
 	)
+	s.AddKnownTypes(gwapiv1.SchemeGroupVersion,
+		&gwapiv1.HTTPRoute{},
+		&gwapiv1.HTTPRouteList{},
+	)
 	return s
 }
 
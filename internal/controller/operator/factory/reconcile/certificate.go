@@ -0,0 +1,60 @@
+package reconcile
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/logger"
+)
+
+// CertificateGVK defines kind of cert-manager Certificate
+var CertificateGVK = schema.GroupVersionKind{Group: "cert-manager.io", Version: "v1", Kind: "Certificate"}
+
+// Certificate creates or updates cert-manager Certificate
+// it's managed as unstructured object, since operator doesn't depend on cert-manager api
+func Certificate(ctx context.Context, rclient client.Client, newCert *unstructured.Unstructured) error {
+	existCert := &unstructured.Unstructured{}
+	existCert.SetGroupVersionKind(CertificateGVK)
+	if err := rclient.Get(ctx, types.NamespacedName{Namespace: newCert.GetNamespace(), Name: newCert.GetName()}, existCert); err != nil {
+		if errors.IsNotFound(err) {
+			logger.WithContext(ctx).Info(fmt.Sprintf("creating new Certificate=%s", newCert.GetName()))
+			if err := rclient.Create(ctx, newCert); err != nil {
+				return fmt.Errorf("cannot create Certificate=%s: %w", newCert.GetName(), err)
+			}
+			return nil
+		}
+		return fmt.Errorf("cannot get Certificate=%s: %w", newCert.GetName(), err)
+	}
+	if equality.Semantic.DeepEqual(existCert.Object["spec"], newCert.Object["spec"]) &&
+		equality.Semantic.DeepEqual(existCert.GetLabels(), newCert.GetLabels()) {
+		return nil
+	}
+	existCert.Object["spec"] = newCert.Object["spec"]
+	existCert.SetLabels(newCert.GetLabels())
+	existCert.SetOwnerReferences(newCert.GetOwnerReferences())
+	logger.WithContext(ctx).Info(fmt.Sprintf("updating Certificate=%s", newCert.GetName()))
+	if err := rclient.Update(ctx, existCert); err != nil {
+		return fmt.Errorf("cannot update Certificate=%s: %w", newCert.GetName(), err)
+	}
+	return nil
+}
+
+// RemoveCertificate removes cert-manager Certificate with the given name if it exists
+func RemoveCertificate(ctx context.Context, rclient client.Client, namespace, name string) error {
+	toDelete := &unstructured.Unstructured{}
+	toDelete.SetGroupVersionKind(CertificateGVK)
+	toDelete.SetName(name)
+	toDelete.SetNamespace(namespace)
+	logger.WithContext(ctx).Info(fmt.Sprintf("removing Certificate=%s", name))
+	if err := rclient.Delete(ctx, toDelete); err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("cannot delete Certificate=%s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/finalize"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/logger"
+)
+
+// DaemonSet performs an update or create operator for daemonset and waits until it's pods is ready
+func DaemonSet(ctx context.Context, rclient client.Client, newDS, prevDS *appsv1.DaemonSet) error {
+
+	var isPrevEqual bool
+	if prevDS != nil {
+		isPrevEqual = equality.Semantic.DeepDerivative(prevDS.Spec, newDS.Spec)
+	}
+	rclient.Scheme().Default(newDS)
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var currentDS appsv1.DaemonSet
+		err := rclient.Get(ctx, types.NamespacedName{Name: newDS.Name, Namespace: newDS.Namespace}, &currentDS)
+		if err != nil {
+			if errors.IsNotFound(err) {
+				logger.WithContext(ctx).Info(fmt.Sprintf("creating new DaemonSet %s", newDS.Name))
+				if err := rclient.Create(ctx, newDS); err != nil {
+					return fmt.Errorf("cannot create new daemonset for app: %s, err: %w", newDS.Name, err)
+				}
+				return waitDaemonSetReady(ctx, rclient, newDS, appWaitReadyDeadline)
+			}
+			return fmt.Errorf("cannot get daemonset for app: %s err: %w", newDS.Name, err)
+		}
+		if err := finalize.FreeIfNeeded(ctx, rclient, &currentDS); err != nil {
+			return err
+		}
+		newDS.Status = currentDS.Status
+		var prevAnnotations, prevTemplateAnnotations map[string]string
+		if prevDS != nil {
+			prevAnnotations = prevDS.Annotations
+			prevTemplateAnnotations = prevDS.Spec.Template.Annotations
+		}
+		isEqual := equality.Semantic.DeepDerivative(newDS.Spec, currentDS.Spec)
+		if isEqual &&
+			isPrevEqual &&
+			equality.Semantic.DeepEqual(newDS.Labels, currentDS.Labels) &&
+			isAnnotationsEqual(currentDS.Annotations, newDS.Annotations, prevAnnotations) {
+			return waitDaemonSetReady(ctx, rclient, newDS, appWaitReadyDeadline)
+		}
+
+		vmv1beta1.AddFinalizer(newDS, &currentDS)
+		newDS.Annotations = mergeAnnotations(currentDS.Annotations, newDS.Annotations, prevAnnotations)
+		newDS.Spec.Template.Annotations = mergeAnnotations(currentDS.Spec.Template.Annotations, newDS.Spec.Template.Annotations, prevTemplateAnnotations)
+		cloneSignificantMetadata(newDS, &currentDS)
+
+		logger.WithContext(ctx).Info(fmt.Sprintf("updating DaemonSet %s configuration"+
+			"is_prev_equal=%v,is_current_equal=%v,is_prev_nil=%v",
+			newDS.Name, isPrevEqual, isEqual, prevDS == nil))
+
+		if err := rclient.Update(ctx, newDS); err != nil {
+			return fmt.Errorf("cannot update daemonset for app: %s, err: %w", newDS.Name, err)
+		}
+
+		return waitDaemonSetReady(ctx, rclient, newDS, appWaitReadyDeadline)
+	})
+}
+
+// waitDaemonSetReady waits until daemonset rollouts and all scheduled pods is ready
+func waitDaemonSetReady(ctx context.Context, rclient client.Client, ds *appsv1.DaemonSet, deadline time.Duration) error {
+	err := wait.PollUntilContextTimeout(ctx, time.Second, deadline, false, func(ctx context.Context) (done bool, err error) {
+		var actualDS appsv1.DaemonSet
+		if err := rclient.Get(ctx, types.NamespacedName{Namespace: ds.Namespace, Name: ds.Name}, &actualDS); err != nil {
+			return false, fmt.Errorf("cannot fetch actual daemonset state: %w", err)
+		}
+		// the same algorithm as `kubectl rollout status` command uses
+		// (https://github.com/kubernetes/kubectl/blob/6e4fe32a45fdcbf61e5c30ebdc511d75e7242432/pkg/polymorphichelpers/rollout_status.go#L95)
+		if actualDS.Generation > actualDS.Status.ObservedGeneration {
+			// Waiting for daemonset spec update to be observed by controller...
+			return false, nil
+		}
+		if actualDS.Status.UpdatedNumberScheduled < actualDS.Status.DesiredNumberScheduled {
+			// Waiting for daemonset rollout to finish: part of new pods have been updated...
+			return false, nil
+		}
+		if actualDS.Status.NumberAvailable < actualDS.Status.DesiredNumberScheduled {
+			// Waiting for daemonset rollout to finish: part of updated pods are available
+			return false, nil
+		}
+		return true, nil
+	})
+	if err != nil {
+		return reportFirstNotReadyPodOnError(ctx, rclient, fmt.Errorf("cannot wait for daemonset to become ready: %w", err), ds.Namespace, labels.SelectorFromSet(ds.Spec.Selector.MatchLabels), ds.Spec.MinReadySeconds)
+	}
+	return nil
+}
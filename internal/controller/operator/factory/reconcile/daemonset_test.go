@@ -0,0 +1,72 @@
+package reconcile
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/k8stools"
+)
+
+func TestDaemonSetOk(t *testing.T) {
+	f := func(ds *appsv1.DaemonSet) {
+		t.Helper()
+		ctx := context.Background()
+		rclient := k8stools.GetTestClientWithObjects(nil)
+		clientStats := rclient.(*k8stools.TestClientWithStatsTrack)
+
+		prevDS := ds.DeepCopy()
+		// expect 1 create
+		if err := DaemonSet(ctx, rclient, ds, nil); err != nil {
+			t.Fatalf("failed to create daemonset: %s", err)
+		}
+		assert.Equal(t, int64(1), clientStats.CreateCalls.Load())
+
+		// expect 0 update
+		if err := DaemonSet(ctx, rclient, ds.DeepCopy(), prevDS); err != nil {
+			t.Fatalf("failed to update created daemonset: %s", err)
+		}
+		assert.Equal(t, int64(1), clientStats.CreateCalls.Load())
+		assert.Equal(t, int64(0), clientStats.UpdateCalls.Load())
+
+		// expect 1 update
+		updated := ds.DeepCopy()
+		updated.Spec.Template.Annotations = map[string]string{"new-annotation": "value"}
+		if err := DaemonSet(ctx, rclient, updated, prevDS); err != nil {
+			t.Fatalf("failed to update daemonset: %s", err)
+		}
+		assert.Equal(t, int64(1), clientStats.CreateCalls.Load())
+		assert.Equal(t, int64(1), clientStats.UpdateCalls.Load())
+	}
+
+	f(&appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-1",
+			Namespace: "default",
+		},
+		Spec: appsv1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"label": "value",
+				},
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{"label": "value"},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:  "vmagent",
+							Image: "some-image:tag",
+						},
+					},
+				},
+			},
+		},
+	})
+}
@@ -0,0 +1,53 @@
+package reconcile
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
+)
+
+// ChildObjectEvents defines events emitted by ChildObjectsEvents
+type ChildObjectEvents struct {
+	// Object is a child object name used at event message, e.g. rule
+	Object string
+	// Parent is a parent object name used at event message, e.g. vmalert=monitoring/main
+	Parent string
+	// RejectedReason is a reason of Warning event for rejected child object
+	RejectedReason string
+	// AcceptedReason is a reason of Normal event for previously rejected child object, which became valid.
+	// Event is not emitted if it's empty
+	AcceptedReason string
+}
+
+// ChildObjectsEvents emits Warning event for rejected child objects and Normal event for previously rejected child objects, which became valid.
+// Previous state is taken from the child object status condition of the given parentObjectName,
+// so event is emitted only if error text changes and not on each reconcile loop.
+// It must be called before StatusForChildObjects.
+func ChildObjectsEvents[T objectWithStatus](recorder record.EventRecorder, parentObjectName string, childObjects []T, ev ChildObjectEvents) {
+	if recorder == nil {
+		return
+	}
+	typeName := parentObjectName + vmv1beta1.ConditionDomainTypeAppliedSuffix
+	for _, o := range childObjects {
+		st := o.GetStatusMetadata()
+		var prevCond *vmv1beta1.Condition
+		for idx := range st.Conditions {
+			if st.Conditions[idx].Type == typeName {
+				prevCond = &st.Conditions[idx]
+				break
+			}
+		}
+		wasRejected := prevCond != nil && prevCond.Status == metav1.ConditionFalse
+		switch {
+		case st.CurrentSyncError != "":
+			if wasRejected && prevCond.Message == st.CurrentSyncError {
+				continue
+			}
+			recorder.Eventf(o, corev1.EventTypeWarning, ev.RejectedReason, "%s was rejected by %s: %s", ev.Object, ev.Parent, st.CurrentSyncError)
+		case wasRejected && ev.AcceptedReason != "":
+			recorder.Eventf(o, corev1.EventTypeNormal, ev.AcceptedReason, "%s was accepted by %s", ev.Object, ev.Parent)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package reconcile
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
+)
+
+func TestChildObjectsEvents(t *testing.T) {
+	parentObject := "base.default.vmalert"
+	f := func(ev ChildObjectEvents, prevCond *vmv1beta1.Condition, syncErr string, wantEvents []string) {
+		t.Helper()
+		rule := &vmv1beta1.VMRule{ObjectMeta: metav1.ObjectMeta{Name: "rule", Namespace: "default"}}
+		if prevCond != nil {
+			prevCond.Type = parentObject + vmv1beta1.ConditionDomainTypeAppliedSuffix
+			rule.Status.Conditions = append(rule.Status.Conditions, *prevCond)
+		}
+		rule.Status.CurrentSyncError = syncErr
+		recorder := record.NewFakeRecorder(10)
+		ChildObjectsEvents(recorder, parentObject, []*vmv1beta1.VMRule{rule}, ev)
+		close(recorder.Events)
+		var gotEvents []string
+		for e := range recorder.Events {
+			gotEvents = append(gotEvents, e)
+		}
+		assert.Equal(t, wantEvents, gotEvents)
+	}
+	ev := ChildObjectEvents{
+		Object:         "rule",
+		Parent:         "vmalert=default/base",
+		RejectedReason: "RuleRejected",
+		AcceptedReason: "RuleAccepted",
+	}
+	// new valid object
+	f(ev, nil, "", nil)
+	// valid object
+	f(ev, &vmv1beta1.Condition{Status: metav1.ConditionTrue}, "", nil)
+	// new invalid object
+	f(ev, nil, "bad expr", []string{"Warning RuleRejected rule was rejected by vmalert=default/base: bad expr"})
+	// previously valid object became invalid
+	f(ev, &vmv1beta1.Condition{Status: metav1.ConditionTrue}, "bad expr", []string{"Warning RuleRejected rule was rejected by vmalert=default/base: bad expr"})
+	// same error must not be reported twice
+	f(ev, &vmv1beta1.Condition{Status: metav1.ConditionFalse, Message: "bad expr"}, "bad expr", nil)
+	// error text changed
+	f(ev, &vmv1beta1.Condition{Status: metav1.ConditionFalse, Message: "bad expr"}, "bad group", []string{"Warning RuleRejected rule was rejected by vmalert=default/base: bad group"})
+	// previously invalid object became valid
+	f(ev, &vmv1beta1.Condition{Status: metav1.ConditionFalse, Message: "bad expr"}, "", []string{"Normal RuleAccepted rule was accepted by vmalert=default/base"})
+
+	// accepted event is not emitted without reason
+	ev.AcceptedReason = ""
+	f(ev, &vmv1beta1.Condition{Status: metav1.ConditionFalse, Message: "bad expr"}, "", nil)
+}
@@ -32,6 +32,9 @@ type STSOptions struct {
 	SelectorLabels     func() map[string]string
 	HPA                *vmv1beta1.EmbeddedHPA
 	UpdateReplicaCount func(count *int32)
+	// PodReadyCheck optionally performs additional application specific check
+	// of updated pod after it becomes ready. Supported only for OnDelete update strategy
+	PodReadyCheck func(ctx context.Context, pod *corev1.Pod) error
 }
 
 func waitForStatefulSetReady(ctx context.Context, rclient client.Client, newSts *appsv1.StatefulSet) error {
@@ -132,7 +135,7 @@ func HandleSTSUpdate(ctx context.Context, rclient client.Client, cr STSOptions,
 
 		// perform manual update only with OnDelete policy, which is default.
 		if newSts.Spec.UpdateStrategy.Type == appsv1.OnDeleteStatefulSetStrategyType {
-			if err := performRollingUpdateOnSts(ctx, podMustRecreate, rclient, newSts.Name, newSts.Namespace, cr.SelectorLabels()); err != nil {
+			if err := performRollingUpdateOnSts(ctx, podMustRecreate, rclient, newSts.Name, newSts.Namespace, cr.SelectorLabels(), cr.PodReadyCheck); err != nil {
 				return fmt.Errorf("cannot handle rolling-update on sts: %s, err: %w", newSts.Name, err)
 			}
 		} else {
@@ -179,7 +182,7 @@ func getLatestStsState(ctx context.Context, rclient client.Client, targetSTS typ
 //
 // we always check if sts.Status.CurrentRevision needs update, to keep it equal to UpdateRevision
 // see https://github.com/kubernetes/kube-state-metrics/issues/1324#issuecomment-1779751992
-func performRollingUpdateOnSts(ctx context.Context, podMustRecreate bool, rclient client.Client, stsName string, ns string, podLabels map[string]string) error {
+func performRollingUpdateOnSts(ctx context.Context, podMustRecreate bool, rclient client.Client, stsName string, ns string, podLabels map[string]string, podReadyCheck func(context.Context, *corev1.Pod) error) error {
 	time.Sleep(podWaitReadyIntervalCheck)
 	sts, err := getLatestStsState(ctx, rclient, types.NamespacedName{Name: stsName, Namespace: ns})
 	if err != nil {
@@ -271,6 +274,9 @@ func performRollingUpdateOnSts(ctx context.Context, podMustRecreate bool, rclien
 		if err != nil {
 			return fmt.Errorf("cannot wait for pod ready state for already updated pod: %w", err)
 		}
+		if err := checkPodReady(ctx, rclient, podNsn, podReadyCheck); err != nil {
+			return err
+		}
 	}
 
 	// perform update for not updated pods
@@ -285,6 +291,9 @@ func performRollingUpdateOnSts(ctx context.Context, podMustRecreate bool, rclien
 		if err = waitForPodReady(ctx, rclient, podNsn, stsVersion, sts.Spec.MinReadySeconds); err != nil {
 			return fmt.Errorf("cannot wait for pod ready state during re-creation: %w", err)
 		}
+		if err := checkPodReady(ctx, rclient, podNsn, podReadyCheck); err != nil {
+			return err
+		}
 		l.Info(fmt.Sprintf("pod %s was updated successfully", pod.Name))
 	}
 
@@ -293,6 +302,21 @@ func performRollingUpdateOnSts(ctx context.Context, podMustRecreate bool, rclien
 	return nil
 }
 
+// checkPodReady performs optional additional readiness check for the given pod
+func checkPodReady(ctx context.Context, rclient client.Client, nsn types.NamespacedName, podReadyCheck func(context.Context, *corev1.Pod) error) error {
+	if podReadyCheck == nil {
+		return nil
+	}
+	var pod corev1.Pod
+	if err := rclient.Get(ctx, nsn, &pod); err != nil {
+		return fmt.Errorf("cannot get pod: %q: %w", nsn, err)
+	}
+	if err := podReadyCheck(ctx, &pod); err != nil {
+		return fmt.Errorf("pod=%s didn't pass readiness check: %w", pod.Name, err)
+	}
+	return nil
+}
+
 // PodIsReady check is pod is ready
 func PodIsReady(pod *corev1.Pod, minReadySeconds int32) bool {
 	if pod.ObjectMeta.DeletionTimestamp != nil {
@@ -309,3 +309,57 @@ func mayGrow(ctx context.Context, pvc *corev1.PersistentVolumeClaim, newSize, ex
 		return true
 	}
 }
+
+// CheckSTSPVCShrink returns error if any VolumeClaimTemplate of the given statefulSet
+// requests less storage than VolumeClaimTemplate of the existing statefulSet.
+// Kubernetes doesn't support PVC shrinking, so such change cannot be applied
+func CheckSTSPVCShrink(ctx context.Context, rclient client.Client, newSts *appsv1.StatefulSet) error {
+	var existingSts appsv1.StatefulSet
+	if err := rclient.Get(ctx, types.NamespacedName{Namespace: newSts.Namespace, Name: newSts.Name}, &existingSts); err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("cannot get sts=%s: %w", newSts.Name, err)
+	}
+	for _, newClaim := range newSts.Spec.VolumeClaimTemplates {
+		existingClaim := getPVCFromSTS(newClaim.Name, &existingSts)
+		if existingClaim == nil {
+			continue
+		}
+		newSize := newClaim.Spec.Resources.Requests.Storage()
+		existingSize := existingClaim.Spec.Resources.Requests.Storage()
+		if newSize.Cmp(*existingSize) < 0 {
+			return fmt.Errorf("cannot decrease size of VolumeClaimTemplate=%s for sts=%s from=%s to=%s, PVC shrinking is not supported",
+				newClaim.Name, newSts.Name, existingSize.String(), newSize.String())
+		}
+	}
+	return nil
+}
+
+// IsSTSPVCExpansionInProgress checks if any PVC of the given statefulSet
+// has capacity less than requested storage size
+func IsSTSPVCExpansionInProgress(ctx context.Context, rclient client.Client, sts *appsv1.StatefulSet) (bool, error) {
+	if len(sts.Spec.VolumeClaimTemplates) == 0 {
+		return false, nil
+	}
+	var pvcs corev1.PersistentVolumeClaimList
+	opts := &client.ListOptions{
+		Namespace:     sts.Namespace,
+		LabelSelector: labels.SelectorFromSet(sts.Spec.Selector.MatchLabels),
+	}
+	if err := rclient.List(ctx, &pvcs, opts); err != nil {
+		return false, fmt.Errorf("cannot list pvcs for sts=%s: %w", sts.Name, err)
+	}
+	for _, pvc := range pvcs.Items {
+		requested := pvc.Spec.Resources.Requests.Storage()
+		capacity, ok := pvc.Status.Capacity[corev1.ResourceStorage]
+		if !ok || requested.IsZero() {
+			continue
+		}
+		if capacity.Cmp(*requested) < 0 {
+			logger.WithContext(ctx).Info(fmt.Sprintf("PVC=%s expansion is in progress, capacity=%s, requested=%s", pvc.Name, capacity.String(), requested.String()))
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -529,3 +529,88 @@ func Test_growSTSPVC(t *testing.T) {
 		})
 	}
 }
+
+func TestCheckSTSPVCShrink(t *testing.T) {
+	f := func(existingSize, newSize string, wantErr bool) {
+		t.Helper()
+		sts := func(size string) *appsv1.StatefulSet {
+			return &appsv1.StatefulSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "vmstorage", Namespace: "default"},
+				Spec: appsv1.StatefulSetSpec{
+					VolumeClaimTemplates: []corev1.PersistentVolumeClaim{{
+						ObjectMeta: metav1.ObjectMeta{Name: "vmstorage-db"},
+						Spec: corev1.PersistentVolumeClaimSpec{
+							Resources: corev1.VolumeResourceRequirements{Requests: corev1.ResourceList{
+								corev1.ResourceStorage: resource.MustParse(size),
+							}},
+						},
+					}},
+				},
+			}
+		}
+		var predefinedObjects []runtime.Object
+		if existingSize != "" {
+			predefinedObjects = append(predefinedObjects, sts(existingSize))
+		}
+		fclient := k8stools.GetTestClientWithObjects(predefinedObjects)
+		err := CheckSTSPVCShrink(context.TODO(), fclient, sts(newSize))
+		if (err != nil) != wantErr {
+			t.Fatalf("CheckSTSPVCShrink() error = %v, wantErr %v", err, wantErr)
+		}
+	}
+
+	// new statefulset
+	f("", "10Gi", false)
+
+	// same size
+	f("10Gi", "10Gi", false)
+
+	// expand
+	f("10Gi", "15Gi", false)
+
+	// shrink
+	f("15Gi", "10Gi", true)
+}
+
+func TestIsSTSPVCExpansionInProgress(t *testing.T) {
+	f := func(requested, capacity string, want bool) {
+		t.Helper()
+		sts := &appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "vmstorage", Namespace: "default"},
+			Spec: appsv1.StatefulSetSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "vmstorage"}},
+				VolumeClaimTemplates: []corev1.PersistentVolumeClaim{{
+					ObjectMeta: metav1.ObjectMeta{Name: "vmstorage-db"},
+				}},
+			},
+		}
+		pvc := &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: "vmstorage-db-vmstorage-0", Namespace: "default", Labels: map[string]string{"app": "vmstorage"}},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				Resources: corev1.VolumeResourceRequirements{Requests: corev1.ResourceList{
+					corev1.ResourceStorage: resource.MustParse(requested),
+				}},
+			},
+		}
+		if capacity != "" {
+			pvc.Status.Capacity = corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(capacity)}
+		}
+		fclient := k8stools.GetTestClientWithObjects([]runtime.Object{pvc})
+		got, err := IsSTSPVCExpansionInProgress(context.TODO(), fclient, sts)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if got != want {
+			t.Fatalf("IsSTSPVCExpansionInProgress() = %v, want %v", got, want)
+		}
+	}
+
+	// pvc is not bound yet
+	f("10Gi", "", false)
+
+	// capacity matches request
+	f("10Gi", "10Gi", false)
+
+	// volume resize is pending
+	f("15Gi", "10Gi", true)
+}
@@ -7,6 +7,7 @@ import (
 	"reflect"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -45,6 +46,16 @@ func StatusForChildObjects[T any, PT interface {
 	*T
 	objectWithStatus
 }](ctx context.Context, rclient client.Client, parentObjectName string, childObjects []PT) error {
+	return StatusForChildObjectsWithWorkers[T](ctx, rclient, parentObjectName, childObjects, 1)
+}
+
+// StatusForChildObjectsWithWorkers reconciles status sub-resources the same way as StatusForChildObjects,
+// but issues status update requests with the given number of concurrent workers.
+// It's useful for parent objects with large number of child objects
+func StatusForChildObjectsWithWorkers[T any, PT interface {
+	*T
+	objectWithStatus
+}](ctx context.Context, rclient client.Client, parentObjectName string, childObjects []PT, workers int) error {
 	var errors []string
 
 	n := strings.Split(parentObjectName, ".")
@@ -52,26 +63,55 @@ func StatusForChildObjects[T any, PT interface {
 		panic(fmt.Sprintf("BUG: unexpected format for parentObjectName=%q, want name.namespace.resource", parentObjectName))
 	}
 	typeName := parentObjectName + vmv1beta1.ConditionDomainTypeAppliedSuffix
-	for _, childObject := range childObjects {
-		// update current time on each cycle
-		// due to possible throttling at API server
-		ctm := metav1.Now()
+	conds := make([]vmv1beta1.Condition, len(childObjects))
+	for idx, childObject := range childObjects {
 		st := childObject.GetStatusMetadata()
 		currCound := vmv1beta1.Condition{
 			Type:               typeName,
 			Reason:             vmv1beta1.ConditionParsingReason,
-			LastTransitionTime: ctm,
-			LastUpdateTime:     ctm,
 			ObservedGeneration: childObject.GetGeneration(),
 		}
 		if st.CurrentSyncError == "" {
 			currCound.Status = "True"
+			currCound.Message = st.CurrentSyncWarning
+			if st.CurrentSyncInfo != "" {
+				if currCound.Message != "" {
+					currCound.Message += "; "
+				}
+				currCound.Message += st.CurrentSyncInfo
+			}
 		} else {
 			currCound.Status = "False"
 			currCound.Message = st.CurrentSyncError
 			errors = append(errors, fmt.Sprintf("parent=%s config=namespace/name=%s/%s error text: %s", parentObjectName, childObject.GetNamespace(), childObject.GetName(), st.CurrentSyncError))
 		}
-		if err := updateChildStatusConditions[T](ctx, rclient, childObject, currCound); err != nil {
+		conds[idx] = currCound
+	}
+	updateErrs := make([]error, len(childObjects))
+	workCh := make(chan int)
+	var wg sync.WaitGroup
+	for range max(min(workers, len(childObjects)), 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range workCh {
+				// update current time on each request
+				// due to possible throttling at API server
+				ctm := metav1.Now()
+				cond := conds[idx]
+				cond.LastTransitionTime = ctm
+				cond.LastUpdateTime = ctm
+				updateErrs[idx] = updateChildStatusConditions[T](ctx, rclient, childObjects[idx], cond)
+			}
+		}()
+	}
+	for idx := range childObjects {
+		workCh <- idx
+	}
+	close(workCh)
+	wg.Wait()
+	for _, err := range updateErrs {
+		if err != nil {
 			return err
 		}
 	}
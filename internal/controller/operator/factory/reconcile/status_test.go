@@ -0,0 +1,94 @@
+package reconcile
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+
+	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/k8stools"
+)
+
+func TestStatusForChildObjects(t *testing.T) {
+	ctx := context.Background()
+	now := metav1.Now()
+	staleTime := metav1.NewTime(now.Add(-24 * time.Hour))
+	otherParentType := "other.default.vmalert" + vmv1beta1.ConditionDomainTypeAppliedSuffix
+	goneParentType := "gone.default.vmalert" + vmv1beta1.ConditionDomainTypeAppliedSuffix
+	mainParentType := "main.default.vmalert" + vmv1beta1.ConditionDomainTypeAppliedSuffix
+	rule := &vmv1beta1.VMRule{
+		ObjectMeta: metav1.ObjectMeta{Name: "rule", Namespace: "default", Generation: 2},
+		Status: vmv1beta1.VMRuleStatus{StatusMetadata: vmv1beta1.StatusMetadata{Conditions: []vmv1beta1.Condition{
+			{
+				Type:               otherParentType,
+				Status:             "False",
+				Reason:             vmv1beta1.ConditionParsingReason,
+				Message:            "rejected by other",
+				LastTransitionTime: now,
+				LastUpdateTime:     now,
+			},
+			{
+				Type:               goneParentType,
+				Status:             "True",
+				Reason:             vmv1beta1.ConditionParsingReason,
+				LastTransitionTime: staleTime,
+				LastUpdateTime:     staleTime,
+			},
+		}}},
+	}
+	rclient := k8stools.GetTestClientWithObjects([]runtime.Object{rule})
+	getConditions := func() map[string]vmv1beta1.Condition {
+		t.Helper()
+		var got vmv1beta1.VMRule
+		if err := rclient.Get(ctx, types.NamespacedName{Name: rule.Name, Namespace: rule.Namespace}, &got); err != nil {
+			t.Fatalf("cannot get rule: %s", err)
+		}
+		conds := make(map[string]vmv1beta1.Condition)
+		for _, c := range got.Status.Conditions {
+			conds[c.Type] = c
+		}
+		assert.Equal(t, vmv1beta1.UpdateStatusFailed, got.Status.UpdateStatus)
+		return conds
+	}
+
+	// parent adds own condition and keeps conditions of other parents
+	child := rule.DeepCopy()
+	child.Status.CurrentSyncError = "rejected by main"
+	if err := StatusForChildObjects(ctx, rclient, "main.default.vmalert", []*vmv1beta1.VMRule{child}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	conds := getConditions()
+	assert.Len(t, conds, 2)
+	assert.Equal(t, "rejected by other", conds[otherParentType].Message)
+	assert.Equal(t, metav1.ConditionFalse, conds[mainParentType].Status)
+	assert.Equal(t, "rejected by main", conds[mainParentType].Message)
+	assert.Equal(t, int64(2), conds[mainParentType].ObservedGeneration)
+	_, ok := conds[goneParentType]
+	assert.False(t, ok, "stale condition must be removed")
+
+	// parent updates only own condition
+	child = rule.DeepCopy()
+	if err := StatusForChildObjects(ctx, rclient, "main.default.vmalert", []*vmv1beta1.VMRule{child}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	conds = getConditions()
+	assert.Len(t, conds, 2)
+	assert.Equal(t, metav1.ConditionTrue, conds[mainParentType].Status)
+	assert.Empty(t, conds[mainParentType].Message)
+	assert.Equal(t, metav1.ConditionFalse, conds[otherParentType].Status)
+
+	// warning is reported at applied condition message
+	child = rule.DeepCopy()
+	child.Status.CurrentSyncWarning = "sampleLimit is reduced"
+	if err := StatusForChildObjects(ctx, rclient, "main.default.vmalert", []*vmv1beta1.VMRule{child}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	conds = getConditions()
+	assert.Equal(t, metav1.ConditionTrue, conds[mainParentType].Status)
+	assert.Equal(t, "sampleLimit is reduced", conds[mainParentType].Message)
+}
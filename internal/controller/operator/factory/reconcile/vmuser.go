@@ -0,0 +1,43 @@
+package reconcile
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/logger"
+)
+
+// VMUserForCRD creates or updates VMUser managed by the given CRD object
+func VMUserForCRD(ctx context.Context, rclient client.Client, vmu *vmv1beta1.VMUser) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var existVMU vmv1beta1.VMUser
+		err := rclient.Get(ctx, types.NamespacedName{Namespace: vmu.Namespace, Name: vmu.Name}, &existVMU)
+		if err != nil {
+			if errors.IsNotFound(err) {
+				logger.WithContext(ctx).Info(fmt.Sprintf("creating VMUser %s", vmu.Name))
+				return rclient.Create(ctx, vmu)
+			}
+			return err
+		}
+		if equality.Semantic.DeepEqual(vmu.Spec, existVMU.Spec) &&
+			equality.Semantic.DeepEqual(vmu.Labels, existVMU.Labels) &&
+			equality.Semantic.DeepEqual(vmu.Annotations, existVMU.Annotations) &&
+			equality.Semantic.DeepEqual(vmu.OwnerReferences, existVMU.OwnerReferences) {
+			return nil
+		}
+		existVMU.Annotations = vmu.Annotations
+		existVMU.Labels = vmu.Labels
+		existVMU.OwnerReferences = vmu.OwnerReferences
+		existVMU.Spec = vmu.Spec
+		logger.WithContext(ctx).Info(fmt.Sprintf("updating VMUser %s for CRD object", vmu.Name))
+
+		return rclient.Update(ctx, &existVMU)
+	})
+}
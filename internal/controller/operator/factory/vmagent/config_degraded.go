@@ -0,0 +1,128 @@
+package vmagent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"gopkg.in/yaml.v2"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/logger"
+)
+
+const (
+	// scrapeJobsCountAnnotation persists number of scrape jobs of the applied configuration at config secret
+	scrapeJobsCountAnnotation             = "operator.victoriametrics.com/scrape-jobs-count"
+	defaultConfigDegradedThresholdPercent = 50
+)
+
+func scrapeJobsCount(cfg yaml.MapSlice) int {
+	for _, item := range cfg {
+		if item.Key == "scrape_configs" {
+			return len(item.Value.([]yaml.MapSlice))
+		}
+	}
+	return 0
+}
+
+// isConfigDegraded checks if generated configuration must be held for holdOnDegraded configReconcileStrategy.
+// It compares jobs count of the generated configuration with count persisted at the applied config secret
+// and sets ScrapeConfigDegraded condition at VMAgent status.
+func isConfigDegraded(ctx context.Context, rclient client.Client, cr *vmv1beta1.VMAgent, jobsCount int) (bool, error) {
+	if cr.Spec.ConfigReconcileStrategy != vmv1beta1.VMAgentConfigReconcileStrategyHoldOnDegraded {
+		removeVMAgentCondition(cr, vmv1beta1.VMAgentScrapeConfigDegradedCondition)
+		return false, nil
+	}
+	cond := vmv1beta1.Condition{
+		Type:   vmv1beta1.VMAgentScrapeConfigDegradedCondition,
+		Status: metav1.ConditionFalse,
+		Reason: "ScrapeConfigApplied",
+	}
+	var prevSecret corev1.Secret
+	if err := rclient.Get(ctx, types.NamespacedName{Namespace: cr.Namespace, Name: cr.PrefixedName()}, &prevSecret); err != nil {
+		if !k8serrors.IsNotFound(err) {
+			return false, fmt.Errorf("cannot get vmagent config secret: %w", err)
+		}
+		setVMAgentCondition(cr, cond)
+		return false, nil
+	}
+	prevJobsCount, err := strconv.Atoi(prevSecret.Annotations[scrapeJobsCountAnnotation])
+	if err != nil || prevJobsCount <= jobsCount {
+		setVMAgentCondition(cr, cond)
+		return false, nil
+	}
+	threshold := int(cr.Spec.ConfigDegradedThresholdPercent)
+	if threshold == 0 {
+		threshold = defaultConfigDegradedThresholdPercent
+	}
+	dropPercent := (prevJobsCount - jobsCount) * 100 / prevJobsCount
+	if dropPercent <= threshold {
+		setVMAgentCondition(cr, cond)
+		return false, nil
+	}
+	msg := fmt.Sprintf("scrape jobs count dropped from %d to %d (%d%%), it exceeds threshold of %d%%", prevJobsCount, jobsCount, dropPercent, threshold)
+	if cr.Annotations[vmv1beta1.VMAgentForceApplyConfigAnnotation] == "true" {
+		logger.WithContext(ctx).Info(fmt.Sprintf("applying degraded scrape configuration due to %s annotation: %s", vmv1beta1.VMAgentForceApplyConfigAnnotation, msg))
+		setVMAgentCondition(cr, cond)
+		return false, nil
+	}
+	logger.WithContext(ctx).Info(fmt.Sprintf("keeping last applied scrape configuration: %s", msg))
+	cond.Status = metav1.ConditionTrue
+	cond.Reason = vmv1beta1.VMAgentScrapeConfigDegradedCondition
+	cond.Message = msg + ", last applied configuration is kept"
+	setVMAgentCondition(cr, cond)
+	return true, nil
+}
+
+// addMissingTLSAssets replaces the given assets with assets of the applied configuration.
+// Assets, which are missing at the applied configuration, are kept
+func addMissingTLSAssets(ctx context.Context, rclient client.Client, cr *vmv1beta1.VMAgent, assets map[string]string) error {
+	var prevSecret corev1.Secret
+	if err := rclient.Get(ctx, types.NamespacedName{Namespace: cr.Namespace, Name: cr.TLSAssetName()}, &prevSecret); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("cannot get vmagent tls assets secret: %w", err)
+	}
+	for key := range assets {
+		if v, ok := prevSecret.Data[key]; ok {
+			assets[key] = string(v)
+		}
+	}
+	for key, v := range prevSecret.Data {
+		if _, ok := assets[key]; !ok {
+			assets[key] = string(v)
+		}
+	}
+	return nil
+}
+
+// updateConfigSecretStatus persists status.configSecretParts and status.conditions of the given VMAgent.
+// It must be used, if configuration was updated outside of VMAgent reconcile,
+// status change triggers VMAgent reconcile, which mounts new parts to the pods
+func updateConfigSecretStatus(ctx context.Context, rclient client.Client, cr *vmv1beta1.VMAgent) error {
+	patch := map[string]any{
+		"status": map[string]any{
+			"configSecretParts": cr.Status.ConfigSecretParts,
+			"conditions":        cr.Status.Conditions,
+		},
+	}
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return fmt.Errorf("cannot marshal config secret status patch: %w", err)
+	}
+	// patch a copy, since response overwrites not yet persisted status fields
+	objToUpdate := cr.DeepCopy()
+	if err := rclient.Status().Patch(ctx, objToUpdate, client.RawPatch(types.MergePatchType, data)); err != nil {
+		return fmt.Errorf("cannot update config secret status: %w", err)
+	}
+	cr.SetResourceVersion(objToUpdate.GetResourceVersion())
+	return nil
+}
@@ -0,0 +1,117 @@
+package vmagent
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+
+	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/build"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/k8stools"
+)
+
+func TestCreateOrUpdateConfigurationSecretHoldOnDegraded(t *testing.T) {
+	cr := &vmv1beta1.VMAgent{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+		Spec: vmv1beta1.VMAgentSpec{
+			StaticScrapeSelector:    &metav1.LabelSelector{},
+			ConfigReconcileStrategy: vmv1beta1.VMAgentConfigReconcileStrategyHoldOnDegraded,
+		},
+	}
+	var objects []runtime.Object
+	for i := range 10 {
+		objects = append(objects, &vmv1beta1.VMStaticScrape{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("static-%d", i), Namespace: "default"},
+			Spec: vmv1beta1.VMStaticScrapeSpec{
+				TargetEndpoints: []*vmv1beta1.TargetEndpoint{{
+					Targets: []string{fmt.Sprintf("host-%d:9100", i)},
+				}},
+			},
+		})
+	}
+	fclient := k8stools.GetTestClientWithObjects(objects)
+	build.AddDefaults(fclient.Scheme())
+	ctx := context.TODO()
+
+	assertApplied := func(wantJobs string, wantDegraded metav1.ConditionStatus) {
+		t.Helper()
+		if _, _, err := createOrUpdateConfigurationSecret(ctx, fclient, cr, nil, nil, nil); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		var s corev1.Secret
+		if err := fclient.Get(ctx, types.NamespacedName{Namespace: cr.Namespace, Name: cr.PrefixedName()}, &s); err != nil {
+			t.Fatalf("cannot get vmagent config secret: %s", err)
+		}
+		assert.Equal(t, wantJobs, s.Annotations[scrapeJobsCountAnnotation])
+		if assert.Len(t, cr.Status.Conditions, 1) {
+			assert.Equal(t, vmv1beta1.VMAgentScrapeConfigDegradedCondition, cr.Status.Conditions[0].Type)
+			assert.Equal(t, wantDegraded, cr.Status.Conditions[0].Status)
+		}
+	}
+	removeStatics := func(names ...int) {
+		t.Helper()
+		for _, i := range names {
+			o := &vmv1beta1.VMStaticScrape{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("static-%d", i), Namespace: "default"}}
+			if err := fclient.Delete(ctx, o); err != nil {
+				t.Fatalf("cannot delete static scrape: %s", err)
+			}
+		}
+	}
+
+	assertApplied("10", metav1.ConditionFalse)
+
+	// drop within threshold
+	removeStatics(0, 1)
+	assertApplied("8", metav1.ConditionFalse)
+
+	// drop above threshold
+	removeStatics(2, 3, 4, 5, 6)
+	assertApplied("8", metav1.ConditionTrue)
+	assert.Contains(t, cr.Status.Conditions[0].Message, "scrape jobs count dropped from 8 to 3 (62%)")
+
+	// override annotation
+	cr.Annotations = map[string]string{vmv1beta1.VMAgentForceApplyConfigAnnotation: "true"}
+	assertApplied("3", metav1.ConditionFalse)
+
+	// strategy is disabled
+	cr.Annotations = nil
+	cr.Spec.ConfigReconcileStrategy = ""
+	removeStatics(7, 8)
+	if _, _, err := createOrUpdateConfigurationSecret(ctx, fclient, cr, nil, nil, nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.Empty(t, cr.Status.Conditions)
+}
+
+func Test_addMissingTLSAssets(t *testing.T) {
+	cr := &vmv1beta1.VMAgent{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+	}
+	ctx := context.TODO()
+
+	// assets weren't applied yet
+	assets := map[string]string{"scrape_ca": "new-ca"}
+	if err := addMissingTLSAssets(ctx, k8stools.GetTestClientWithObjects(nil), cr, assets); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.Equal(t, map[string]string{"scrape_ca": "new-ca"}, assets)
+
+	// assets of the applied configuration are kept
+	fclient := k8stools.GetTestClientWithObjects([]runtime.Object{
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: cr.TLSAssetName(), Namespace: cr.Namespace},
+			Data:       map[string][]byte{"scrape_ca": []byte("old-ca"), "removed_ca": []byte("removed")},
+		},
+	})
+	assets = map[string]string{"scrape_ca": "new-ca", "remote_write_ca": "rw-ca"}
+	if err := addMissingTLSAssets(ctx, fclient, cr, assets); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.Equal(t, map[string]string{"scrape_ca": "old-ca", "removed_ca": "removed", "remote_write_ca": "rw-ca"}, assets)
+}
@@ -0,0 +1,174 @@
+package vmagent
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/finalize"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/logger"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/reconcile"
+)
+
+const (
+	vmAgentConfigPartsDir    = "/etc/vmagent/config_parts"
+	configPartsVolumeName    = "config-parts"
+	configPartKey            = "scrape_configs.yaml"
+	configPartFilenameFormat = "part-%d.yaml"
+	// configPartsCountKey holds number of config parts referenced by the main config Secret
+	configPartsCountKey = "config_parts_count"
+)
+
+// maxConfigSecretSize defines size limit for compressed config at the main Secret
+// and for uncompressed scrape jobs at each config part Secret
+var maxConfigSecretSize = vmv1beta1.MaxConfigMapDataSize
+
+// splitScrapeConfigs moves scrape jobs of the given config into parts limited by maxConfigSecretSize.
+// Jobs are sorted by job name and packed sequentially, so the split is deterministic.
+// Returned config refers parts with scrape_config_files, parts contain plain yaml lists of scrape jobs,
+// it allows vmagent to read mounted parts directly.
+func splitScrapeConfigs(cfg yaml.MapSlice) (yaml.MapSlice, [][]byte, error) {
+	var scrapeConfigs []yaml.MapSlice
+	mainCfg := make(yaml.MapSlice, 0, len(cfg))
+	for _, item := range cfg {
+		if item.Key == "scrape_configs" {
+			scrapeConfigs = item.Value.([]yaml.MapSlice)
+			continue
+		}
+		mainCfg = append(mainCfg, item)
+	}
+	sort.SliceStable(scrapeConfigs, func(i, j int) bool {
+		return scrapeJobName(scrapeConfigs[i]) < scrapeJobName(scrapeConfigs[j])
+	})
+	var parts [][]byte
+	for _, sc := range scrapeConfigs {
+		data, err := yaml.Marshal([]yaml.MapSlice{sc})
+		if err != nil {
+			return nil, nil, fmt.Errorf("cannot marshal scrape job=%q: %w", scrapeJobName(sc), err)
+		}
+		// scrape job could be always placed into empty part, even if it exceeds size limit
+		if len(parts) == 0 || len(parts[len(parts)-1])+len(data) > maxConfigSecretSize {
+			parts = append(parts, nil)
+		}
+		parts[len(parts)-1] = append(parts[len(parts)-1], data...)
+	}
+	files := make([]string, 0, len(parts))
+	for i := range parts {
+		files = append(files, path.Join(vmAgentConfigPartsDir, fmt.Sprintf(configPartFilenameFormat, i)))
+	}
+	mainCfg = append(mainCfg, yaml.MapItem{Key: "scrape_config_files", Value: files})
+	return mainCfg, parts, nil
+}
+
+func scrapeJobName(sc yaml.MapSlice) string {
+	for _, item := range sc {
+		if item.Key == "job_name" {
+			return fmt.Sprintf("%v", item.Value)
+		}
+	}
+	return ""
+}
+
+func buildConfigPartMeta(cr *vmv1beta1.VMAgent, idx int) metav1.ObjectMeta {
+	meta := buildConfigMeta(cr)
+	meta.Name = cr.ConfigSecretPartName(idx)
+	return meta
+}
+
+// reconcileConfigSecretParts creates or updates Secrets with scrape jobs parts
+// stale parts must be removed with removeStaleConfigSecretParts after vmagent pods update
+func reconcileConfigSecretParts(ctx context.Context, rclient client.Client, cr, prevCR *vmv1beta1.VMAgent, parts [][]byte) error {
+	for idx, data := range parts {
+		s := &corev1.Secret{
+			ObjectMeta: buildConfigPartMeta(cr, idx),
+			Data: map[string][]byte{
+				configPartKey: data,
+			},
+		}
+		var prevMeta *metav1.ObjectMeta
+		if prevCR != nil {
+			prevMeta = ptr.To(buildConfigPartMeta(prevCR, idx))
+		}
+		if err := reconcile.Secret(ctx, rclient, s, prevMeta); err != nil {
+			return fmt.Errorf("cannot reconcile vmagent config secret part=%d: %w", idx, err)
+		}
+	}
+	return nil
+}
+
+// removeStaleConfigSecretParts removes config part Secrets, which are not referenced by status.configSecretParts
+// it must be called after vmagent pods update, since Secrets could be still mounted to the pods
+func removeStaleConfigSecretParts(ctx context.Context, rclient client.Client, cr *vmv1beta1.VMAgent) error {
+	var secretList corev1.SecretList
+	if err := rclient.List(ctx, &secretList, client.InNamespace(cr.Namespace), client.MatchingLabels(cr.SelectorLabels())); err != nil {
+		return fmt.Errorf("cannot list vmagent config secret parts: %w", err)
+	}
+	prefix := cr.PrefixedName() + "-config-"
+	for i := range secretList.Items {
+		s := &secretList.Items[i]
+		idx, err := strconv.Atoi(strings.TrimPrefix(s.Name, prefix))
+		if !strings.HasPrefix(s.Name, prefix) || err != nil || idx < int(cr.Status.ConfigSecretParts) {
+			continue
+		}
+		logger.WithContext(ctx).Info(fmt.Sprintf("removing stale vmagent config secret part=%s", s.Name))
+		if err := finalize.RemoveFinalizer(ctx, rclient, s); err != nil {
+			return err
+		}
+		if err := finalize.SafeDelete(ctx, rclient, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// canSwitchToConfigParts checks if the main config Secret could refer the given number of config parts before vmagent pods update.
+// It's safe only if Secret is missing or already refers at least the same number of parts,
+// since the main config Secret is switched to more parts only after pods mount them
+func canSwitchToConfigParts(ctx context.Context, rclient client.Client, cr *vmv1beta1.VMAgent, parts int) (bool, error) {
+	var s corev1.Secret
+	if err := rclient.Get(ctx, types.NamespacedName{Namespace: cr.Namespace, Name: cr.PrefixedName()}, &s); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, fmt.Errorf("cannot get vmagent config secret: %w", err)
+	}
+	mounted, _ := strconv.Atoi(string(s.Data[configPartsCountKey]))
+	return parts <= mounted, nil
+}
+
+// buildConfigPartsVolume returns projected volume with all config parts
+// or nil if scrape jobs are stored at the main config Secret
+func buildConfigPartsVolume(cr *vmv1beta1.VMAgent) *corev1.Volume {
+	if cr.Status.ConfigSecretParts == 0 {
+		return nil
+	}
+	sources := make([]corev1.VolumeProjection, 0, cr.Status.ConfigSecretParts)
+	for idx := range int(cr.Status.ConfigSecretParts) {
+		sources = append(sources, corev1.VolumeProjection{
+			Secret: &corev1.SecretProjection{
+				LocalObjectReference: corev1.LocalObjectReference{Name: cr.ConfigSecretPartName(idx)},
+				Items: []corev1.KeyToPath{
+					{Key: configPartKey, Path: fmt.Sprintf(configPartFilenameFormat, idx)},
+				},
+			},
+		})
+	}
+	return &corev1.Volume{
+		Name: configPartsVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			Projected: &corev1.ProjectedVolumeSource{Sources: sources},
+		},
+	}
+}
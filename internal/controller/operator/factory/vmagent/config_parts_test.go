@@ -0,0 +1,186 @@
+package vmagent
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+
+	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/build"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/k8stools"
+)
+
+func Test_splitScrapeConfigs(t *testing.T) {
+	defer func(v int) { maxConfigSecretSize = v }(maxConfigSecretSize)
+	maxConfigSecretSize = 80
+
+	job := func(name string) yaml.MapSlice {
+		return yaml.MapSlice{{Key: "job_name", Value: name}, {Key: "scrape_interval", Value: "30s"}}
+	}
+	cfg := yaml.MapSlice{
+		{Key: "global", Value: yaml.MapSlice{{Key: "scrape_interval", Value: "30s"}}},
+		{Key: "scrape_configs", Value: []yaml.MapSlice{job("c"), job("a"), job("b")}},
+	}
+	mainCfg, parts, err := splitScrapeConfigs(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	data, err := yaml.Marshal(mainCfg)
+	if err != nil {
+		t.Fatalf("cannot marshal config: %s", err)
+	}
+	assert.Equal(t, `global:
+  scrape_interval: 30s
+scrape_config_files:
+- /etc/vmagent/config_parts/part-0.yaml
+- /etc/vmagent/config_parts/part-1.yaml
+`, string(data))
+	if assert.Len(t, parts, 2) {
+		assert.Equal(t, `- job_name: a
+  scrape_interval: 30s
+- job_name: b
+  scrape_interval: 30s
+`, string(parts[0]))
+		assert.Equal(t, `- job_name: c
+  scrape_interval: 30s
+`, string(parts[1]))
+	}
+}
+
+func TestCreateOrUpdateConfigurationSecretParts(t *testing.T) {
+	defer func(v int) { maxConfigSecretSize = v }(maxConfigSecretSize)
+
+	cr := &vmv1beta1.VMAgent{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+		Spec: vmv1beta1.VMAgentSpec{
+			StaticScrapeSelector: &metav1.LabelSelector{},
+		},
+	}
+	var objects []runtime.Object
+	for i := range 20 {
+		objects = append(objects, &vmv1beta1.VMStaticScrape{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("static-%d", i), Namespace: "default"},
+			Spec: vmv1beta1.VMStaticScrapeSpec{
+				TargetEndpoints: []*vmv1beta1.TargetEndpoint{{
+					Targets: []string{fmt.Sprintf("host-%d:9100", i)},
+				}},
+			},
+		})
+	}
+	objects = append(objects, cr)
+	fclient := k8stools.GetTestClientWithObjects(objects)
+	build.AddDefaults(fclient.Scheme())
+	ctx := context.TODO()
+
+	getMainConfig := func() string {
+		t.Helper()
+		var s corev1.Secret
+		if err := fclient.Get(ctx, types.NamespacedName{Namespace: cr.Namespace, Name: cr.PrefixedName()}, &s); err != nil {
+			t.Fatalf("cannot get vmagent config secret: %s", err)
+		}
+		gr, err := gzip.NewReader(bytes.NewReader(s.Data[vmagentGzippedFilename]))
+		if err != nil {
+			t.Fatalf("cannot read gzipped config: %s", err)
+		}
+		data, err := io.ReadAll(gr)
+		if err != nil {
+			t.Fatalf("cannot read config: %s", err)
+		}
+		return string(data)
+	}
+
+	// config fits into the main secret
+	_, pending, err := createOrUpdateConfigurationSecret(ctx, fclient, cr, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.Nil(t, pending)
+	assert.Equal(t, int32(0), cr.Status.ConfigSecretParts)
+	assert.Contains(t, getMainConfig(), "staticScrape/default/static-0/0")
+
+	// config exceeds size limit
+	maxConfigSecretSize = 150
+	_, pending, err = createOrUpdateConfigurationSecret(ctx, fclient, cr, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	// parts count is persisted before the main config update
+	var persisted vmv1beta1.VMAgent
+	if err := fclient.Get(ctx, types.NamespacedName{Namespace: cr.Namespace, Name: cr.Name}, &persisted); err != nil {
+		t.Fatalf("cannot get vmagent: %s", err)
+	}
+	assert.Equal(t, cr.Status.ConfigSecretParts, persisted.Status.ConfigSecretParts)
+	// pods don't mount config parts yet, the main config must not be switched
+	assert.Contains(t, getMainConfig(), "staticScrape/default/static-0/0")
+	if assert.NotNil(t, pending) {
+		if err := reconcileConfigSecret(ctx, fclient, cr, nil, pending); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	// pods mount config parts, the same split is applied without pending
+	_, pending, err = createOrUpdateConfigurationSecret(ctx, fclient, cr, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.Nil(t, pending)
+	mainConfig := getMainConfig()
+	assert.NotContains(t, mainConfig, "staticScrape/default/static-0/0")
+	assert.Contains(t, mainConfig, "scrape_config_files")
+	if cr.Status.ConfigSecretParts < 2 {
+		t.Fatalf("expected config to be split, got parts: %d", cr.Status.ConfigSecretParts)
+	}
+	var jobs string
+	for idx := range int(cr.Status.ConfigSecretParts) {
+		var s corev1.Secret
+		if err := fclient.Get(ctx, types.NamespacedName{Namespace: cr.Namespace, Name: cr.ConfigSecretPartName(idx)}, &s); err != nil {
+			t.Fatalf("cannot get config secret part: %s", err)
+		}
+		assert.LessOrEqual(t, len(s.Data[configPartKey]), maxConfigSecretSize)
+		jobs += string(s.Data[configPartKey])
+	}
+	for i := range 20 {
+		assert.Contains(t, jobs, fmt.Sprintf("staticScrape/default/static-%d/0", i))
+	}
+
+	// stale parts are removed after switch back to the main secret
+	maxConfigSecretSize = vmv1beta1.MaxConfigMapDataSize
+	if _, _, err := createOrUpdateConfigurationSecret(ctx, fclient, cr, nil, nil, nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.Equal(t, int32(0), cr.Status.ConfigSecretParts)
+	if err := removeStaleConfigSecretParts(ctx, fclient, cr); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var secrets corev1.SecretList
+	if err := fclient.List(ctx, &secrets); err != nil {
+		t.Fatalf("cannot list secrets: %s", err)
+	}
+	for _, s := range secrets.Items {
+		assert.NotContains(t, s.Name, cr.PrefixedName()+"-config-")
+	}
+}
+
+func Test_buildConfigPartsVolume(t *testing.T) {
+	cr := &vmv1beta1.VMAgent{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+	}
+	assert.Nil(t, buildConfigPartsVolume(cr))
+	cr.Status.ConfigSecretParts = 2
+	v := buildConfigPartsVolume(cr)
+	if assert.NotNil(t, v) && assert.Len(t, v.Projected.Sources, 2) {
+		assert.Equal(t, "vmagent-test-config-1", v.Projected.Sources[1].Secret.Name)
+		assert.Equal(t, []corev1.KeyToPath{{Key: configPartKey, Path: "part-1.yaml"}}, v.Projected.Sources[1].Secret.Items)
+	}
+	args := buildConfigReloaderArgs(cr)
+	assert.Contains(t, args, "--watched-dir=/etc/vmagent/config_parts")
+}
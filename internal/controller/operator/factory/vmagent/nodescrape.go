@@ -18,22 +18,25 @@ func generateNodeScrapeConfig(
 	se vmv1beta1.VMAgentSecurityEnforcements,
 ) yaml.MapSlice {
 	nodeSpec := &cr.Spec
+	jobName := fmt.Sprintf("nodeScrape/%s/%s/%d", cr.Namespace, cr.Name, i)
 	cfg := yaml.MapSlice{
 		{
 			Key:   "job_name",
-			Value: fmt.Sprintf("nodeScrape/%s/%s/%d", cr.Namespace, cr.Name, i),
+			Value: jobName,
 		},
 	}
 
 	setScrapeIntervalToWithLimit(ctx, &nodeSpec.EndpointScrapeParams, vmagentCR)
+	setScrapeLimitsTo(&nodeSpec.EndpointScrapeParams, vmagentCR, cr.GetStatusMetadata(), jobName)
 
 	cfg = append(cfg, generateK8SSDConfig(nil, apiserverConfig, ssCache, kubernetesSDRoleNode, nil))
 
-	cfg = addCommonScrapeParamsTo(cfg, nodeSpec.EndpointScrapeParams, se)
+	cfg = addCommonScrapeParamsTo(cfg, nodeSpec.EndpointScrapeParams, se, vmagentCR)
 
 	var relabelings []yaml.MapSlice
 
 	relabelings = addSelectorToRelabelingFor(relabelings, "node", nodeSpec.Selector)
+	relabelings = addNodeFilterToRelabelingFor(relabelings, vmagentCR, "__meta_kubernetes_node_name")
 	// Add __address__ as internalIP  and pod and service labels into proper labels.
 	relabelings = append(relabelings, []yaml.MapSlice{
 		{
@@ -58,10 +61,14 @@ func generateNodeScrapeConfig(
 	// endpoints, therefore the endpoints labels is filled with the ports name or
 	// as a fallback the port number.
 
-	relabelings = append(relabelings, yaml.MapSlice{
-		{Key: "target_label", Value: "job"},
-		{Key: "replacement", Value: fmt.Sprintf("%s/%s", cr.GetNamespace(), cr.GetName())},
-	})
+	if vmagentCR.Spec.LegacyJobNames {
+		relabelings = append(relabelings, yaml.MapSlice{
+			{Key: "target_label", Value: "job"},
+			{Key: "replacement", Value: fmt.Sprintf("%s/%s", cr.GetNamespace(), cr.GetName())},
+		})
+	} else if jobRelabeling := generateJobLabelRelabeling(vmagentCR, "nodeScrape", cr.Namespace, cr.Name, i); jobRelabeling != nil {
+		relabelings = append(relabelings, jobRelabeling)
+	}
 	if cr.Spec.JobLabel != "" {
 		relabelings = append(relabelings, yaml.MapSlice{
 			{Key: "source_labels", Value: []string{"__meta_kubernetes_node_label_" + sanitizeLabelName(cr.Spec.JobLabel)}},
@@ -71,6 +78,16 @@ func generateNodeScrapeConfig(
 		})
 	}
 
+	// address types are applied in reverse order, so the first present address type has priority
+	for idx := len(nodeSpec.UseNodeAddressType) - 1; idx >= 0; idx-- {
+		relabelings = append(relabelings, yaml.MapSlice{
+			{Key: "source_labels", Value: []string{"__meta_kubernetes_node_address_" + string(nodeSpec.UseNodeAddressType[idx]), "__address__"}},
+			{Key: "target_label", Value: "__address__"},
+			{Key: "regex", Value: "(.+);.*:(.*)"},
+			{Key: "replacement", Value: "${1}:${2}"},
+		})
+	}
+
 	if nodeSpec.Port != "" {
 		relabelings = append(relabelings, yaml.MapSlice{
 			{Key: "source_labels", Value: []string{"__address__"}},
@@ -92,10 +109,29 @@ func generateNodeScrapeConfig(
 	relabelings = enforceNamespaceLabel(relabelings, cr.Namespace, se.EnforcedNamespaceLabel)
 
 	cfg = append(cfg, yaml.MapItem{Key: "relabel_configs", Value: relabelings})
-	cfg = addMetricRelabelingsTo(cfg, nodeSpec.MetricRelabelConfigs, se)
+	cfg = addMetricRelabelingsTo(cfg, withGlobalMetricRelabelConfigs(vmagentCR, cr, nodeSpec.MetricRelabelConfigs), se)
 	cfg = append(cfg, buildVMScrapeParams(cr.Namespace, cr.AsProxyKey(), cr.Spec.VMScrapeParams, ssCache)...)
+	cfg = addEndpointHeadersTo(cfg, cr.AsMapKey(), ssCache.headers)
 	cfg = addTLStoYaml(cfg, cr.Namespace, nodeSpec.TLSConfig, false)
 	cfg = addEndpointAuthTo(cfg, nodeSpec.EndpointAuth, cr.AsMapKey(), ssCache)
 
 	return cfg
 }
+
+// validateNodeAddressTypes checks that address types are supported and not duplicated
+func validateNodeAddressTypes(addressTypes []vmv1beta1.NodeAddressType) error {
+	seen := make(map[vmv1beta1.NodeAddressType]struct{}, len(addressTypes))
+	for _, at := range addressTypes {
+		switch at {
+		case vmv1beta1.NodeAddressInternalIP, vmv1beta1.NodeAddressHostname, vmv1beta1.NodeAddressExternalIP:
+		default:
+			return &invalidScrapeParamsError{err: fmt.Errorf("unsupported useNodeAddressType=%q, supported values: %s, %s, %s",
+				at, vmv1beta1.NodeAddressInternalIP, vmv1beta1.NodeAddressHostname, vmv1beta1.NodeAddressExternalIP)}
+		}
+		if _, ok := seen[at]; ok {
+			return &invalidScrapeParamsError{err: fmt.Errorf("duplicate useNodeAddressType=%q", at)}
+		}
+		seen[at] = struct{}{}
+	}
+	return nil
+}
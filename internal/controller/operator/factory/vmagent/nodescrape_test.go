@@ -58,8 +58,6 @@ relabel_configs:
 - source_labels:
   - __meta_kubernetes_node_name
   target_label: node
-- target_label: job
-  replacement: default/nodes-basic
 - source_labels:
   - __address__
   target_label: __address__
@@ -175,8 +173,6 @@ relabel_configs:
   target_label: env
   regex: (.+)
   replacement: ${1}
-- target_label: job
-  replacement: default/nodes-basic
 - source_labels:
   - __meta_kubernetes_node_label_env
   target_label: job
@@ -196,6 +192,71 @@ tls_config:
 bearer_token_file: /tmp/bearer
 basic_auth:
   username: username
+`,
+		},
+		{
+			name: "kubelet with address types and tls",
+			args: args{
+				ssCache: &scrapesSecretsCache{},
+				m: &vmv1beta1.VMNodeScrape{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "kubelet",
+						Namespace: "default",
+					},
+					Spec: vmv1beta1.VMNodeScrapeSpec{
+						Port:               "10250",
+						UseNodeAddressType: []vmv1beta1.NodeAddressType{"Hostname", "InternalIP"},
+						EndpointScrapeParams: vmv1beta1.EndpointScrapeParams{
+							Scheme: "https",
+						},
+						EndpointAuth: vmv1beta1.EndpointAuth{
+							TLSConfig: &vmv1beta1.TLSConfig{
+								CA: vmv1beta1.SecretOrConfigMap{
+									ConfigMap: &corev1.ConfigMapKeySelector{
+										LocalObjectReference: corev1.LocalObjectReference{Name: "kubelet-ca"},
+										Key:                  "ca.crt",
+									},
+								},
+							},
+							Authorization: &vmv1beta1.Authorization{
+								CredentialsFile: "/var/run/secrets/kubernetes.io/serviceaccount/token",
+							},
+						},
+					},
+				},
+			},
+			want: `job_name: nodeScrape/default/kubelet/0
+kubernetes_sd_configs:
+- role: node
+honor_labels: false
+scheme: https
+relabel_configs:
+- source_labels:
+  - __meta_kubernetes_node_name
+  target_label: node
+- source_labels:
+  - __meta_kubernetes_node_address_InternalIP
+  - __address__
+  target_label: __address__
+  regex: (.+);.*:(.*)
+  replacement: ${1}:${2}
+- source_labels:
+  - __meta_kubernetes_node_address_Hostname
+  - __address__
+  target_label: __address__
+  regex: (.+);.*:(.*)
+  replacement: ${1}:${2}
+- source_labels:
+  - __address__
+  target_label: __address__
+  regex: ^(.*):(.*)
+  replacement: ${1}:10250
+tls_config:
+  insecure_skip_verify: false
+  ca_file: /etc/vmagent-tls/certs/default_configmap_kubelet-ca_ca.crt
+authorization:
+  type: Bearer
+  credentials_file: /var/run/secrets/kubernetes.io/serviceaccount/token
 `,
 		},
 	}
@@ -211,3 +272,19 @@ basic_auth:
 		})
 	}
 }
+
+func TestValidateNodeAddressTypes(t *testing.T) {
+	f := func(addressTypes []vmv1beta1.NodeAddressType, wantErr string) {
+		t.Helper()
+		err := validateNodeAddressTypes(addressTypes)
+		if wantErr == "" {
+			assert.NoError(t, err)
+			return
+		}
+		assert.EqualError(t, err, wantErr)
+	}
+	f(nil, "")
+	f([]vmv1beta1.NodeAddressType{"Hostname", "InternalIP", "ExternalIP"}, "")
+	f([]vmv1beta1.NodeAddressType{"InternalDNS"}, `unsupported useNodeAddressType="InternalDNS", supported values: InternalIP, Hostname, ExternalIP`)
+	f([]vmv1beta1.NodeAddressType{"Hostname", "Hostname"}, `duplicate useNodeAddressType="Hostname"`)
+}
@@ -19,10 +19,11 @@ func generatePodScrapeConfig(
 	ssCache *scrapesSecretsCache,
 	se vmv1beta1.VMAgentSecurityEnforcements,
 ) yaml.MapSlice {
+	jobName := fmt.Sprintf("podScrape/%s/%s/%d", m.Namespace, m.Name, i)
 	cfg := yaml.MapSlice{
 		{
 			Key:   "job_name",
-			Value: fmt.Sprintf("podScrape/%s/%s/%d", m.Namespace, m.Name, i),
+			Value: jobName,
 		},
 	}
 
@@ -41,8 +42,9 @@ func generatePodScrapeConfig(
 	}
 
 	setScrapeIntervalToWithLimit(ctx, &ep.EndpointScrapeParams, vmagentCR)
+	setScrapeLimitsTo(&ep.EndpointScrapeParams, vmagentCR, m.GetStatusMetadata(), jobName)
 
-	cfg = addCommonScrapeParamsTo(cfg, ep.EndpointScrapeParams, se)
+	cfg = addCommonScrapeParamsTo(cfg, ep.EndpointScrapeParams, se, vmagentCR)
 
 	var relabelings []yaml.MapSlice
 
@@ -55,6 +57,7 @@ func generatePodScrapeConfig(
 	}
 
 	relabelings = addSelectorToRelabelingFor(relabelings, "pod", m.Spec.Selector)
+	relabelings = addNodeFilterToRelabelingFor(relabelings, vmagentCR, "__meta_kubernetes_pod_node_name")
 
 	// Filter targets based on correct port for the endpoint.
 	switch {
@@ -119,10 +122,14 @@ func generatePodScrapeConfig(
 	// endpoints, therefore the endpoints labels is filled with the ports name or
 	// as a fallback the port number.
 
-	relabelings = append(relabelings, yaml.MapSlice{
-		{Key: "target_label", Value: "job"},
-		{Key: "replacement", Value: fmt.Sprintf("%s/%s", m.GetNamespace(), m.GetName())},
-	})
+	if vmagentCR.Spec.LegacyJobNames {
+		relabelings = append(relabelings, yaml.MapSlice{
+			{Key: "target_label", Value: "job"},
+			{Key: "replacement", Value: fmt.Sprintf("%s/%s", m.GetNamespace(), m.GetName())},
+		})
+	} else if jobRelabeling := generateJobLabelRelabeling(vmagentCR, "podScrape", m.Namespace, m.Name, i); jobRelabeling != nil {
+		relabelings = append(relabelings, jobRelabeling)
+	}
 	if m.Spec.JobLabel != "" {
 		relabelings = append(relabelings, yaml.MapSlice{
 			{Key: "source_labels", Value: []string{"__meta_kubernetes_pod_label_" + sanitizeLabelName(m.Spec.JobLabel)}},
@@ -144,6 +151,9 @@ func generatePodScrapeConfig(
 		})
 	}
 
+	for _, c := range ssCache.relabelConfigs[relabelConfigsCacheKey("podScrape", m)] {
+		relabelings = append(relabelings, generateRelabelConfig(c))
+	}
 	for _, c := range ep.RelabelConfigs {
 		relabelings = append(relabelings, generateRelabelConfig(c))
 	}
@@ -155,8 +165,9 @@ func generatePodScrapeConfig(
 	relabelings = enforceNamespaceLabel(relabelings, m.Namespace, se.EnforcedNamespaceLabel)
 
 	cfg = append(cfg, yaml.MapItem{Key: "relabel_configs", Value: relabelings})
-	cfg = addMetricRelabelingsTo(cfg, ep.MetricRelabelConfigs, se)
+	cfg = addMetricRelabelingsTo(cfg, withGlobalMetricRelabelConfigs(vmagentCR, m, ep.MetricRelabelConfigs), se)
 	cfg = append(cfg, buildVMScrapeParams(m.Namespace, m.AsProxyKey(i), ep.VMScrapeParams, ssCache)...)
+	cfg = addEndpointHeadersTo(cfg, m.AsMapKey(i), ssCache.headers)
 	cfg = addTLStoYaml(cfg, m.Namespace, ep.TLSConfig, false)
 	cfg = addEndpointAuthTo(cfg, ep.EndpointAuth, m.AsMapKey(i), ssCache)
 
@@ -74,8 +74,62 @@ relabel_configs:
 - source_labels:
   - __meta_kubernetes_pod_name
   target_label: pod
-- target_label: job
-  replacement: default/test-1
+- target_label: endpoint
+  replacement: web
+`,
+		},
+		{
+			name: "daemonset mode",
+			args: args{
+				cr: vmv1beta1.VMAgent{
+					Spec: vmv1beta1.VMAgentSpec{
+						DaemonSetMode: true,
+					},
+				},
+				m: &vmv1beta1.VMPodScrape{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-1",
+						Namespace: "default",
+					},
+				},
+				ep: vmv1beta1.PodMetricsEndpoint{
+					EndpointScrapeParams: vmv1beta1.EndpointScrapeParams{
+						Path: "/metric",
+					},
+					Port: ptr.To("web"),
+				},
+				ssCache: &scrapesSecretsCache{},
+			},
+			want: `job_name: podScrape/default/test-1/0
+kubernetes_sd_configs:
+- role: pod
+  namespaces:
+    names:
+    - default
+honor_labels: false
+metrics_path: /metric
+relabel_configs:
+- action: drop
+  source_labels:
+  - __meta_kubernetes_pod_phase
+  regex: (Failed|Succeeded)
+- action: keep
+  source_labels:
+  - __meta_kubernetes_pod_node_name
+  regex: '%{NODE_NAME}'
+- action: keep
+  source_labels:
+  - __meta_kubernetes_pod_container_port_name
+  regex: web
+- source_labels:
+  - __meta_kubernetes_namespace
+  target_label: namespace
+- source_labels:
+  - __meta_kubernetes_pod_container_name
+  target_label: container
+- source_labels:
+  - __meta_kubernetes_pod_name
+  target_label: pod
 - target_label: endpoint
   replacement: web
 `,
@@ -125,8 +179,6 @@ relabel_configs:
 - source_labels:
   - __meta_kubernetes_pod_name
   target_label: pod
-- target_label: job
-  replacement: default/test-1
 - target_label: endpoint
   replacement: web
 `,
@@ -208,8 +260,6 @@ relabel_configs:
 - source_labels:
   - __meta_kubernetes_pod_name
   target_label: pod
-- target_label: job
-  replacement: default/test-1
 - target_label: endpoint
   replacement: web
 `,
@@ -281,8 +331,6 @@ relabel_configs:
 - source_labels:
   - __meta_kubernetes_pod_name
   target_label: pod
-- target_label: job
-  replacement: default/test-1
 `,
 		},
 	}
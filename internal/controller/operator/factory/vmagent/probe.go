@@ -6,6 +6,7 @@ import (
 
 	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
 	"gopkg.in/yaml.v2"
+	"k8s.io/utils/ptr"
 )
 
 func generateProbeConfig(
@@ -17,10 +18,11 @@ func generateProbeConfig(
 	ssCache *scrapesSecretsCache,
 	se vmv1beta1.VMAgentSecurityEnforcements,
 ) yaml.MapSlice {
+	jobName := fmt.Sprintf("probe/%s/%s/%d", cr.Namespace, cr.Name, i)
 	cfg := yaml.MapSlice{
 		{
 			Key:   "job_name",
-			Value: fmt.Sprintf("probe/%s/%s/%d", cr.Namespace, cr.Name, i),
+			Value: jobName,
 		},
 	}
 
@@ -30,16 +32,25 @@ func generateProbeConfig(
 	}
 	cr.Spec.EndpointScrapeParams.Path = cr.Spec.VMProberSpec.Path
 
-	if len(cr.Spec.Module) > 0 {
+	proberURL := cr.Spec.VMProberSpec.URL
+	module := cr.Spec.Module
+	if isManagedProbe(cr) {
+		proberURL = managedProberAddress(vmagentCR)
+		module = managedProberModuleName(cr)
+		cr.Spec.EndpointScrapeParams.Path = "/probe"
+		cr.Spec.EndpointScrapeParams.Scheme = "http"
+	}
+	if len(module) > 0 {
 		if cr.Spec.Params == nil {
 			cr.Spec.Params = make(map[string][]string)
 		}
-		cr.Spec.Params["module"] = []string{cr.Spec.Module}
+		cr.Spec.Params["module"] = []string{module}
 	}
 
 	setScrapeIntervalToWithLimit(ctx, &cr.Spec.EndpointScrapeParams, vmagentCR)
+	setScrapeLimitsTo(&cr.Spec.EndpointScrapeParams, vmagentCR, cr.GetStatusMetadata(), jobName)
 
-	cfg = addCommonScrapeParamsTo(cfg, cr.Spec.EndpointScrapeParams, se)
+	cfg = addCommonScrapeParamsTo(cfg, cr.Spec.EndpointScrapeParams, se, vmagentCR)
 
 	var relabelings []yaml.MapSlice
 
@@ -69,11 +80,31 @@ func generateProbeConfig(
 		}
 	}
 	if cr.Spec.Targets.Ingress != nil {
-
-		relabelings = addSelectorToRelabelingFor(relabelings, "ingress", cr.Spec.Targets.Ingress.Selector)
-		selectedNamespaces := getNamespacesFromNamespaceSelector(&cr.Spec.Targets.Ingress.NamespaceSelector, cr.Namespace, se.IgnoreNamespaceSelectors)
+		ing := cr.Spec.Targets.Ingress
+		relabelings = addSelectorToRelabelingFor(relabelings, "ingress", ing.Selector)
+		selectedNamespaces := getNamespacesFromNamespaceSelector(&ing.NamespaceSelector, cr.Namespace, se.IgnoreNamespaceSelectors)
 		cfg = append(cfg, generateK8SSDConfig(selectedNamespaces, apiserverConfig, ssCache, kubernetesSDRoleIngress, nil))
 
+		// ingress service discovery returns target per each host and path of ingress rules
+		// with https scheme for hosts defined at ingress tls section
+		targetRelabeling := yaml.MapSlice{
+			{Key: "source_labels", Value: []string{"__meta_kubernetes_ingress_scheme", "__address__", "__meta_kubernetes_ingress_path"}},
+			{Key: "separator", Value: ";"},
+			{Key: "regex", Value: "(.+);(.+);(.+)"},
+			{Key: "target_label", Value: "__param_target"},
+			{Key: "replacement", Value: "${1}://${2}${3}"},
+			{Key: "action", Value: "replace"},
+		}
+		if !ptr.Deref(ing.IncludePaths, true) {
+			targetRelabeling = yaml.MapSlice{
+				{Key: "source_labels", Value: []string{"__meta_kubernetes_ingress_scheme", "__address__"}},
+				{Key: "separator", Value: ";"},
+				{Key: "regex", Value: "(.+);(.+)"},
+				{Key: "target_label", Value: "__param_target"},
+				{Key: "replacement", Value: "${1}://${2}"},
+				{Key: "action", Value: "replace"},
+			}
+		}
 		// Relabelings for ingress SD.
 		relabelings = append(relabelings, []yaml.MapSlice{
 			{
@@ -84,14 +115,7 @@ func generateProbeConfig(
 				{Key: "replacement", Value: "$1"},
 				{Key: "action", Value: "replace"},
 			},
-			{
-				{Key: "source_labels", Value: []string{"__meta_kubernetes_ingress_scheme", "__address__", "__meta_kubernetes_ingress_path"}},
-				{Key: "separator", Value: ";"},
-				{Key: "regex", Value: "(.+);(.+);(.+)"},
-				{Key: "target_label", Value: "__param_target"},
-				{Key: "replacement", Value: "${1}://${2}${3}"},
-				{Key: "action", Value: "replace"},
-			},
+			targetRelabeling,
 			{
 				{Key: "source_labels", Value: []string{"__meta_kubernetes_namespace"}},
 				{Key: "target_label", Value: "namespace"},
@@ -100,15 +124,21 @@ func generateProbeConfig(
 				{Key: "source_labels", Value: []string{"__meta_kubernetes_ingress_name"}},
 				{Key: "target_label", Value: "ingress"},
 			},
+			{
+				{Key: "source_labels", Value: []string{"__param_target"}},
+				{Key: "target_label", Value: "instance"},
+			},
 		}...)
 
-		// Add configured relabelings.
-		for _, r := range cr.Spec.Targets.Ingress.RelabelConfigs {
+		// Add configured relabelings after generated ones, so they could override it.
+		for _, r := range ing.RelabelConfigs {
 			relabelings = append(relabelings, generateRelabelConfig(r))
 		}
-
 	}
 
+	if jobRelabeling := generateJobLabelRelabeling(vmagentCR, "probe", cr.Namespace, cr.Name, i); jobRelabeling != nil {
+		relabelings = append(relabelings, jobRelabeling)
+	}
 	if cr.Spec.JobName != "" {
 		relabelings = append(relabelings, yaml.MapSlice{
 			{Key: "target_label", Value: "job"},
@@ -117,16 +147,16 @@ func generateProbeConfig(
 	}
 
 	// Relabelings for prober.
-	relabelings = append(relabelings, []yaml.MapSlice{
-		{
+	if cr.Spec.Targets.StaticConfig != nil {
+		relabelings = append(relabelings, yaml.MapSlice{
 			{Key: "source_labels", Value: []string{"__param_target"}},
 			{Key: "target_label", Value: "instance"},
-		},
-		{
-			{Key: "target_label", Value: "__address__"},
-			{Key: "replacement", Value: cr.Spec.VMProberSpec.URL},
-		},
-	}...)
+		})
+	}
+	relabelings = append(relabelings, yaml.MapSlice{
+		{Key: "target_label", Value: "__address__"},
+		{Key: "replacement", Value: proberURL},
+	})
 
 	for _, trc := range vmagentCR.Spec.ProbeScrapeRelabelTemplate {
 		relabelings = append(relabelings, generateRelabelConfig(trc))
@@ -136,8 +166,9 @@ func generateProbeConfig(
 	relabelings = enforceNamespaceLabel(relabelings, cr.Namespace, se.EnforcedNamespaceLabel)
 
 	cfg = append(cfg, yaml.MapItem{Key: "relabel_configs", Value: relabelings})
-	cfg = addMetricRelabelingsTo(cfg, cr.Spec.MetricRelabelConfigs, se)
+	cfg = addMetricRelabelingsTo(cfg, withGlobalMetricRelabelConfigs(vmagentCR, cr, cr.Spec.MetricRelabelConfigs), se)
 	cfg = append(cfg, buildVMScrapeParams(cr.Namespace, cr.AsProxyKey(), cr.Spec.VMScrapeParams, ssCache)...)
+	cfg = addEndpointHeadersTo(cfg, cr.AsMapKey(), ssCache.headers)
 	cfg = addTLStoYaml(cfg, cr.Namespace, cr.Spec.TLSConfig, false)
 	cfg = addEndpointAuthTo(cfg, cr.Spec.EndpointAuth, cr.AsMapKey(), ssCache)
 
@@ -70,6 +70,57 @@ relabel_configs:
   target_label: instance
 - target_label: __address__
   replacement: blackbox-monitor:9115
+`,
+		},
+		{
+			name: "managed prober",
+			args: args{
+				ssCache: &scrapesSecretsCache{},
+				crAgent: vmv1beta1.VMAgent{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "monitoring", Name: "agent"},
+				},
+				cr: &vmv1beta1.VMProbe{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace: "default",
+						Name:      "dns-probe",
+					},
+					Spec: vmv1beta1.VMProbeSpec{
+						Module: "ignored",
+						VMProberSpec: vmv1beta1.VMProberSpec{
+							Managed: true,
+							ModuleConfig: &vmv1beta1.VMProberModule{
+								Prober: "dns",
+								DNS:    &vmv1beta1.VMProberDNSModule{QueryName: "example.com"},
+							},
+						},
+						Targets: vmv1beta1.VMProbeTargets{
+							StaticConfig: &vmv1beta1.VMProbeTargetStaticConfig{
+								Targets: []string{"8.8.8.8"},
+							},
+						},
+					},
+				},
+				i: 0,
+			},
+			want: `job_name: probe/default/dns-probe/0
+honor_labels: false
+metrics_path: /probe
+params:
+  module:
+  - default_dns-probe
+scheme: http
+static_configs:
+- targets:
+  - 8.8.8.8
+relabel_configs:
+- source_labels:
+  - __address__
+  target_label: __param_target
+- source_labels:
+  - __param_target
+  target_label: instance
+- target_label: __address__
+  replacement: vmagent-agent-prober.monitoring.svc:9115
 `,
 		},
 		{
@@ -133,13 +184,86 @@ relabel_configs:
 - source_labels:
   - __meta_kubernetes_ingress_name
   target_label: ingress
+- source_labels:
+  - __param_target
+  target_label: instance
 - source_labels:
   - label1
   target_label: api
   action: replacement
+- target_label: __address__
+  replacement: blackbox:9115
+`,
+		},
+		{
+			name: "with ingress discover without paths",
+			args: args{
+				ssCache: &scrapesSecretsCache{},
+				cr: &vmv1beta1.VMProbe{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "probe-ingress",
+						Namespace: "monitor",
+					},
+					Spec: vmv1beta1.VMProbeSpec{
+						Module:       "http200",
+						VMProberSpec: vmv1beta1.VMProberSpec{URL: "blackbox:9115"},
+						Targets: vmv1beta1.VMProbeTargets{
+							Ingress: &vmv1beta1.ProbeTargetIngress{
+								IncludePaths: ptr.To(false),
+								RelabelConfigs: []*vmv1beta1.RelabelConfig{
+									{
+										SourceLabels: []string{"__param_target"},
+										TargetLabel:  "instance",
+										Regex:        vmv1beta1.StringOrArray{"https?://(.+)"},
+										Replacement:  ptr.To("$1"),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			want: `job_name: probe/monitor/probe-ingress/0
+honor_labels: false
+metrics_path: /probe
+params:
+  module:
+  - http200
+kubernetes_sd_configs:
+- role: ingress
+  namespaces:
+    names:
+    - monitor
+relabel_configs:
+- source_labels:
+  - __address__
+  separator: ;
+  regex: (.*)
+  target_label: __tmp_ingress_address
+  replacement: $1
+  action: replace
+- source_labels:
+  - __meta_kubernetes_ingress_scheme
+  - __address__
+  separator: ;
+  regex: (.+);(.+)
+  target_label: __param_target
+  replacement: ${1}://${2}
+  action: replace
+- source_labels:
+  - __meta_kubernetes_namespace
+  target_label: namespace
+- source_labels:
+  - __meta_kubernetes_ingress_name
+  target_label: ingress
+- source_labels:
+  - __param_target
+  target_label: instance
 - source_labels:
   - __param_target
   target_label: instance
+  regex: https?://(.+)
+  replacement: $1
 - target_label: __address__
   replacement: blackbox:9115
 `,
@@ -0,0 +1,297 @@
+package vmagent
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"path"
+	"sort"
+
+	"gopkg.in/yaml.v2"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/config"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/finalize"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/reconcile"
+)
+
+const (
+	proberConfigKey          = "blackbox.yml"
+	proberConfigDir          = "/etc/blackbox-exporter"
+	proberConfigVolumeName   = "config"
+	proberConfigChecksumName = "operator.victoriametrics.com/prober-config-checksum"
+)
+
+// isManagedProbe checks if VMProbe uses blackbox exporter managed by operator
+func isManagedProbe(probe *vmv1beta1.VMProbe) bool {
+	return probe.Spec.VMProberSpec.Managed
+}
+
+// managedProberModuleName returns name of blackbox exporter module generated for the given VMProbe
+func managedProberModuleName(probe *vmv1beta1.VMProbe) string {
+	return fmt.Sprintf("%s_%s", probe.Namespace, probe.Name)
+}
+
+// managedProberAddress returns address of managed blackbox exporter service
+func managedProberAddress(cr *vmv1beta1.VMAgent) string {
+	return fmt.Sprintf("%s.%s.svc:%s", cr.ProberName(), cr.Namespace, config.MustGetBaseConfig().VMProberDefault.Port)
+}
+
+// validateManagedProbes excludes VMProbe objects with inconsistent prober params from configuration
+func validateManagedProbes(cr *vmv1beta1.VMAgent, sos *scrapeObjects) error {
+	var broken []*vmv1beta1.VMProbe
+	var err error
+	sos.prss, broken, err = forEachCollectSkipNotFound(sos.prss, func(probe *vmv1beta1.VMProbe) error {
+		ps := &probe.Spec.VMProberSpec
+		if !ps.Managed {
+			if ps.URL == "" {
+				return &invalidScrapeParamsError{err: fmt.Errorf("vmProberSpec.url must be set for not managed prober")}
+			}
+			return nil
+		}
+		if cr.Spec.ManagedProber == nil {
+			return &invalidScrapeParamsError{err: fmt.Errorf("vmProberSpec.managed requires spec.managedProber at VMAgent=%s/%s", cr.Namespace, cr.Name)}
+		}
+		if mc := ps.ModuleConfig; mc != nil && mc.Prober == "dns" && (mc.DNS == nil || mc.DNS.QueryName == "") {
+			return &invalidScrapeParamsError{err: fmt.Errorf("moduleConfig.dns.queryName must be set for dns prober")}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sos.prssBroken = append(sos.prssBroken, broken...)
+	return nil
+}
+
+// buildProberModule converts VMProbe module config into blackbox exporter module
+func buildProberModule(mc *vmv1beta1.VMProberModule) yaml.MapSlice {
+	if mc == nil {
+		mc = &vmv1beta1.VMProberModule{Prober: "http"}
+	}
+	module := yaml.MapSlice{{Key: "prober", Value: mc.Prober}}
+	if mc.Timeout != "" {
+		module = append(module, yaml.MapItem{Key: "timeout", Value: mc.Timeout})
+	}
+	var params yaml.MapSlice
+	if mc.PreferredIPProtocol != "" {
+		params = append(params, yaml.MapItem{Key: "preferred_ip_protocol", Value: mc.PreferredIPProtocol})
+	}
+	insecureTLS := func(insecure bool) {
+		if insecure {
+			params = append(params, yaml.MapItem{Key: "tls_config", Value: yaml.MapSlice{{Key: "insecure_skip_verify", Value: true}}})
+		}
+	}
+	switch mc.Prober {
+	case "http":
+		if h := mc.HTTP; h != nil {
+			if h.Method != "" {
+				params = append(params, yaml.MapItem{Key: "method", Value: h.Method})
+			}
+			if len(h.ValidStatusCodes) > 0 {
+				params = append(params, yaml.MapItem{Key: "valid_status_codes", Value: h.ValidStatusCodes})
+			}
+			insecureTLS(h.InsecureSkipVerify)
+		}
+	case "tcp":
+		if t := mc.TCP; t != nil {
+			if t.TLS {
+				params = append(params, yaml.MapItem{Key: "tls", Value: true})
+			}
+			insecureTLS(t.InsecureSkipVerify)
+		}
+	case "dns":
+		if d := mc.DNS; d != nil {
+			params = append(params, yaml.MapItem{Key: "query_name", Value: d.QueryName})
+			if d.QueryType != "" {
+				params = append(params, yaml.MapItem{Key: "query_type", Value: d.QueryType})
+			}
+			if d.TransportProtocol != "" {
+				params = append(params, yaml.MapItem{Key: "transport_protocol", Value: d.TransportProtocol})
+			}
+		}
+	case "icmp":
+		if i := mc.ICMP; i != nil {
+			if i.PayloadSize > 0 {
+				params = append(params, yaml.MapItem{Key: "payload_size", Value: i.PayloadSize})
+			}
+			if i.DontFragment {
+				params = append(params, yaml.MapItem{Key: "dont_fragment", Value: true})
+			}
+		}
+	}
+	if len(params) > 0 {
+		module = append(module, yaml.MapItem{Key: mc.Prober, Value: params})
+	}
+	return module
+}
+
+// buildManagedProberConfig returns blackbox exporter configuration with modules of managed VMProbes
+// and flag, which indicates that icmp prober is used
+func buildManagedProberConfig(probes []*vmv1beta1.VMProbe) ([]byte, bool, error) {
+	var modules yaml.MapSlice
+	var hasICMP bool
+	for _, probe := range probes {
+		if !isManagedProbe(probe) {
+			continue
+		}
+		mc := probe.Spec.VMProberSpec.ModuleConfig
+		if mc != nil && mc.Prober == "icmp" {
+			hasICMP = true
+		}
+		modules = append(modules, yaml.MapItem{Key: managedProberModuleName(probe), Value: buildProberModule(mc)})
+	}
+	if len(modules) == 0 {
+		return nil, false, nil
+	}
+	sort.Slice(modules, func(i, j int) bool {
+		return modules[i].Key.(string) < modules[j].Key.(string)
+	})
+	data, err := yaml.Marshal(yaml.MapSlice{{Key: "modules", Value: modules}})
+	if err != nil {
+		return nil, false, fmt.Errorf("cannot marshal prober config: %w", err)
+	}
+	return data, hasICMP, nil
+}
+
+func buildManagedProberMeta(cr *vmv1beta1.VMAgent) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:            cr.ProberName(),
+		Namespace:       cr.Namespace,
+		Labels:          cr.ProberSelectorLabels(),
+		OwnerReferences: cr.AsOwner(),
+		Finalizers:      []string{vmv1beta1.FinalizerName},
+	}
+}
+
+func buildManagedProberService(cr *vmv1beta1.VMAgent) *corev1.Service {
+	port := config.MustGetBaseConfig().VMProberDefault.Port
+	return &corev1.Service{
+		ObjectMeta: buildManagedProberMeta(cr),
+		Spec: corev1.ServiceSpec{
+			Type:     corev1.ServiceTypeClusterIP,
+			Selector: cr.ProberSelectorLabels(),
+			Ports: []corev1.ServicePort{
+				{
+					Name:       "http",
+					Protocol:   corev1.ProtocolTCP,
+					Port:       intstr.Parse(port).IntVal,
+					TargetPort: intstr.Parse(port),
+				},
+			},
+		},
+	}
+}
+
+func buildManagedProberDeployment(cr *vmv1beta1.VMAgent, data []byte, hasICMP bool) *appsv1.Deployment {
+	mp := cr.Spec.ManagedProber
+	port := config.MustGetBaseConfig().VMProberDefault.Port
+	h := fnv.New64a()
+	h.Write(data) //nolint:errcheck
+
+	container := corev1.Container{
+		Name:            "prober",
+		Image:           fmt.Sprintf("%s:%s", mp.Image.Repository, mp.Image.Tag),
+		ImagePullPolicy: mp.Image.PullPolicy,
+		Args: []string{
+			fmt.Sprintf("--config.file=%s", path.Join(proberConfigDir, proberConfigKey)),
+			fmt.Sprintf("--web.listen-address=:%s", port),
+		},
+		Ports: []corev1.ContainerPort{
+			{Name: "http", Protocol: corev1.ProtocolTCP, ContainerPort: intstr.Parse(port).IntVal},
+		},
+		Resources: mp.Resources,
+		ReadinessProbe: &corev1.Probe{
+			ProbeHandler: corev1.ProbeHandler{
+				HTTPGet: &corev1.HTTPGetAction{Path: "/-/healthy", Port: intstr.Parse(port)},
+			},
+		},
+		VolumeMounts: []corev1.VolumeMount{
+			{Name: proberConfigVolumeName, MountPath: proberConfigDir, ReadOnly: true},
+		},
+		TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
+	}
+	// icmp prober requires raw sockets
+	if hasICMP {
+		container.SecurityContext = &corev1.SecurityContext{
+			Capabilities: &corev1.Capabilities{Add: []corev1.Capability{"NET_RAW"}},
+		}
+	}
+	return &appsv1.Deployment{
+		ObjectMeta: buildManagedProberMeta(cr),
+		Spec: appsv1.DeploymentSpec{
+			Replicas: ptr.To(ptr.Deref(mp.ReplicaCount, 1)),
+			Selector: &metav1.LabelSelector{MatchLabels: cr.ProberSelectorLabels()},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: cr.ProberSelectorLabels(),
+					// restarts prober on configuration change
+					Annotations: map[string]string{proberConfigChecksumName: fmt.Sprintf("%x", h.Sum64())},
+				},
+				Spec: corev1.PodSpec{
+					ImagePullSecrets: cr.Spec.ImagePullSecrets,
+					Containers:       []corev1.Container{container},
+					Volumes: []corev1.Volume{
+						{
+							Name: proberConfigVolumeName,
+							VolumeSource: corev1.VolumeSource{
+								ConfigMap: &corev1.ConfigMapVolumeSource{
+									LocalObjectReference: corev1.LocalObjectReference{Name: cr.ProberName()},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// createOrUpdateManagedProber reconciles blackbox exporter for managed VMProbes selected by VMAgent
+// or removes it, if there are no such probes
+func createOrUpdateManagedProber(ctx context.Context, rclient client.Client, cr, prevCR *vmv1beta1.VMAgent, probes []*vmv1beta1.VMProbe) error {
+	var data []byte
+	var hasICMP bool
+	if cr.Spec.ManagedProber != nil {
+		var err error
+		data, hasICMP, err = buildManagedProberConfig(probes)
+		if err != nil {
+			return err
+		}
+	}
+	if len(data) == 0 {
+		meta := metav1.ObjectMeta{Name: cr.ProberName(), Namespace: cr.Namespace}
+		for _, o := range []client.Object{&appsv1.Deployment{ObjectMeta: meta}, &corev1.Service{ObjectMeta: meta}, &corev1.ConfigMap{ObjectMeta: meta}} {
+			if err := finalize.SafeDeleteWithFinalizer(ctx, rclient, o); err != nil {
+				return fmt.Errorf("cannot remove managed prober %T: %w", o, err)
+			}
+		}
+		return nil
+	}
+	var prevMeta *metav1.ObjectMeta
+	var prevSvc *corev1.Service
+	if prevCR != nil && prevCR.Spec.ManagedProber != nil {
+		prevMeta = ptr.To(buildManagedProberMeta(prevCR))
+		prevSvc = buildManagedProberService(prevCR)
+	}
+	cm := &corev1.ConfigMap{
+		ObjectMeta: buildManagedProberMeta(cr),
+		Data:       map[string]string{proberConfigKey: string(data)},
+	}
+	if err := reconcile.ConfigMap(ctx, rclient, cm, prevMeta); err != nil {
+		return fmt.Errorf("cannot reconcile managed prober config: %w", err)
+	}
+	if err := reconcile.Service(ctx, rclient, buildManagedProberService(cr), prevSvc); err != nil {
+		return fmt.Errorf("cannot reconcile managed prober service: %w", err)
+	}
+	if err := reconcile.Deployment(ctx, rclient, buildManagedProberDeployment(cr, data, hasICMP), nil, false); err != nil {
+		return fmt.Errorf("cannot reconcile managed prober deployment: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package vmagent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/k8stools"
+)
+
+func TestBuildManagedProberConfig(t *testing.T) {
+	f := func(probes []*vmv1beta1.VMProbe, want string, wantICMP bool) {
+		t.Helper()
+		got, hasICMP, err := buildManagedProberConfig(probes)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		assert.Equal(t, want, string(got))
+		assert.Equal(t, wantICMP, hasICMP)
+	}
+	newProbe := func(name string, managed bool, mc *vmv1beta1.VMProberModule) *vmv1beta1.VMProbe {
+		return &vmv1beta1.VMProbe{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: vmv1beta1.VMProbeSpec{
+				VMProberSpec: vmv1beta1.VMProberSpec{Managed: managed, ModuleConfig: mc},
+			},
+		}
+	}
+
+	// not managed probes
+	f([]*vmv1beta1.VMProbe{newProbe("external", false, nil)}, "", false)
+
+	// all probers
+	f([]*vmv1beta1.VMProbe{
+		newProbe("web", true, nil),
+		newProbe("tcp", true, &vmv1beta1.VMProberModule{
+			Prober:  "tcp",
+			Timeout: "5s",
+			TCP:     &vmv1beta1.VMProberTCPModule{TLS: true, InsecureSkipVerify: true},
+		}),
+		newProbe("dns", true, &vmv1beta1.VMProberModule{
+			Prober:              "dns",
+			PreferredIPProtocol: "ip4",
+			DNS:                 &vmv1beta1.VMProberDNSModule{QueryName: "example.com", QueryType: "A", TransportProtocol: "tcp"},
+		}),
+		newProbe("icmp", true, &vmv1beta1.VMProberModule{
+			Prober: "icmp",
+			ICMP:   &vmv1beta1.VMProberICMPModule{PayloadSize: 64, DontFragment: true},
+		}),
+		newProbe("api", true, &vmv1beta1.VMProberModule{
+			Prober: "http",
+			HTTP:   &vmv1beta1.VMProberHTTPModule{Method: "POST", ValidStatusCodes: []int{200, 401}},
+		}),
+		newProbe("external", false, nil),
+	}, `modules:
+  default_api:
+    prober: http
+    http:
+      method: POST
+      valid_status_codes:
+      - 200
+      - 401
+  default_dns:
+    prober: dns
+    dns:
+      preferred_ip_protocol: ip4
+      query_name: example.com
+      query_type: A
+      transport_protocol: tcp
+  default_icmp:
+    prober: icmp
+    icmp:
+      payload_size: 64
+      dont_fragment: true
+  default_tcp:
+    prober: tcp
+    timeout: 5s
+    tcp:
+      tls: true
+      tls_config:
+        insecure_skip_verify: true
+  default_web:
+    prober: http
+`, true)
+}
+
+func TestValidateManagedProbes(t *testing.T) {
+	f := func(managedProber *vmv1beta1.VMAgentManagedProber, ps vmv1beta1.VMProberSpec, wantErr string) {
+		t.Helper()
+		cr := &vmv1beta1.VMAgent{
+			ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "default"},
+			Spec:       vmv1beta1.VMAgentSpec{ManagedProber: managedProber},
+		}
+		sos := &scrapeObjects{
+			prss: []*vmv1beta1.VMProbe{{
+				ObjectMeta: metav1.ObjectMeta{Name: "probe", Namespace: "default"},
+				Spec:       vmv1beta1.VMProbeSpec{VMProberSpec: ps},
+			}},
+		}
+		if err := validateManagedProbes(cr, sos); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if wantErr == "" {
+			assert.Len(t, sos.prss, 1)
+			assert.Empty(t, sos.prssBroken)
+			return
+		}
+		assert.Empty(t, sos.prss)
+		if assert.Len(t, sos.prssBroken, 1) {
+			assert.Equal(t, wantErr, sos.prssBroken[0].Status.CurrentSyncError)
+		}
+	}
+
+	// external prober
+	f(nil, vmv1beta1.VMProberSpec{URL: "blackbox:9115"}, "")
+
+	// external prober without url
+	f(nil, vmv1beta1.VMProberSpec{}, "invalid scrape params: vmProberSpec.url must be set for not managed prober")
+
+	// managed prober
+	f(&vmv1beta1.VMAgentManagedProber{}, vmv1beta1.VMProberSpec{Managed: true}, "")
+
+	// managed prober isn't enabled at vmagent
+	f(nil, vmv1beta1.VMProberSpec{Managed: true}, "invalid scrape params: vmProberSpec.managed requires spec.managedProber at VMAgent=default/agent")
+
+	// dns prober without query name
+	f(&vmv1beta1.VMAgentManagedProber{}, vmv1beta1.VMProberSpec{
+		Managed:      true,
+		ModuleConfig: &vmv1beta1.VMProberModule{Prober: "dns"},
+	}, "invalid scrape params: moduleConfig.dns.queryName must be set for dns prober")
+}
+
+func TestBuildManagedProberDeployment(t *testing.T) {
+	cr := &vmv1beta1.VMAgent{
+		ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "default"},
+		Spec: vmv1beta1.VMAgentSpec{
+			ManagedProber: &vmv1beta1.VMAgentManagedProber{
+				Image: vmv1beta1.Image{Repository: "prom/blackbox-exporter", Tag: "v0.25.0"},
+			},
+		},
+	}
+	dep := buildManagedProberDeployment(cr, []byte(`modules: {}`), false)
+	assert.Equal(t, int32(1), *dep.Spec.Replicas)
+	c := dep.Spec.Template.Spec.Containers[0]
+	assert.Equal(t, "prom/blackbox-exporter:v0.25.0", c.Image)
+	assert.Equal(t, []string{"--config.file=/etc/blackbox-exporter/blackbox.yml", "--web.listen-address=:9115"}, c.Args)
+	assert.Nil(t, c.SecurityContext)
+	checksum := dep.Spec.Template.Annotations[proberConfigChecksumName]
+
+	dep = buildManagedProberDeployment(cr, []byte(`modules: {default_icmp: {prober: icmp}}`), true)
+	assert.NotEqual(t, checksum, dep.Spec.Template.Annotations[proberConfigChecksumName])
+	assert.Equal(t, []corev1.Capability{"NET_RAW"}, dep.Spec.Template.Spec.Containers[0].SecurityContext.Capabilities.Add)
+}
+
+func TestCreateOrUpdateManagedProberRemove(t *testing.T) {
+	cr := &vmv1beta1.VMAgent{
+		ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "default"},
+	}
+	meta := metav1.ObjectMeta{Name: cr.ProberName(), Namespace: cr.Namespace, Finalizers: []string{vmv1beta1.FinalizerName}}
+	ctx := context.TODO()
+	fclient := k8stools.GetTestClientWithObjects([]runtime.Object{
+		&appsv1.Deployment{ObjectMeta: meta},
+		&corev1.Service{ObjectMeta: meta},
+		&corev1.ConfigMap{ObjectMeta: meta},
+	})
+	// managed prober is removed from vmagent spec
+	if err := createOrUpdateManagedProber(ctx, fclient, cr, nil, nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	nsn := types.NamespacedName{Name: cr.ProberName(), Namespace: cr.Namespace}
+	for _, o := range []client.Object{&appsv1.Deployment{}, &corev1.Service{}, &corev1.ConfigMap{}} {
+		err := fclient.Get(ctx, nsn, o)
+		assert.True(t, errors.IsNotFound(err), "object %T must be removed, got err: %v", o, err)
+	}
+}
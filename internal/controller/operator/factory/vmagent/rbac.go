@@ -3,6 +3,8 @@ package vmagent
 import (
 	"context"
 	"fmt"
+	"slices"
+	"strings"
 
 	rbacv1 "k8s.io/api/rbac/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -75,9 +77,6 @@ var (
 				"watch",
 			},
 			Resources: []string{
-				"nodes",
-				"nodes/metrics",
-				"nodes/proxy",
 				"services",
 				"endpoints",
 				"pods",
@@ -111,6 +110,20 @@ var (
 			},
 		},
 	}
+	// nodesPolicyRule is added to cluster role only if selected scrape objects require nodes access
+	nodesPolicyRule = rbacv1.PolicyRule{
+		APIGroups: []string{""},
+		Verbs: []string{
+			"get",
+			"list",
+			"watch",
+		},
+		Resources: []string{
+			"nodes",
+			"nodes/metrics",
+			"nodes/proxy",
+		},
+	}
 )
 
 // createVMAgentK8sAPIAccess - creates RBAC access rules for vmagent
@@ -120,7 +133,10 @@ func createVMAgentK8sAPIAccess(ctx context.Context, rclient client.Client, cr, p
 		return fmt.Errorf("cannot perform RBAC migration: %w", err)
 	}
 	if clusterWide {
-		if err := ensureVMAgentCRExist(ctx, rclient, cr, prevCR); err != nil {
+		// cluster role depends on selected scrape objects
+		// and it's reconciled with scrape configuration
+		// create it in advance, since scrape configuration reconcile may fail before it
+		if err := ensureVMAgentCRCreated(ctx, rclient, cr); err != nil {
 			return fmt.Errorf("cannot ensure state of vmagent's cluster role: %w", err)
 		}
 		if err := ensureCRBExist(ctx, rclient, cr, prevCR); err != nil {
@@ -139,12 +155,65 @@ func createVMAgentK8sAPIAccess(ctx context.Context, rclient client.Client, cr, p
 	return nil
 }
 
-func ensureVMAgentCRExist(ctx context.Context, rclient client.Client, cr, prevCR *vmv1beta1.VMAgent) error {
+func ensureVMAgentCRExist(ctx context.Context, rclient client.Client, cr, prevCR *vmv1beta1.VMAgent, sos *scrapeObjects) error {
+	withNodes := requiresNodesAccess(cr, sos)
 	var prevClusterRole *rbacv1.ClusterRole
 	if prevCR != nil {
-		prevClusterRole = buildClusterRole(prevCR)
+		prevClusterRole = buildClusterRole(prevCR, withNodes)
+	}
+	return reconcile.ClusterRole(ctx, rclient, buildClusterRole(cr, withNodes), prevClusterRole)
+}
+
+// ensureVMAgentCRCreated creates vmagent cluster role, if it's missing.
+// Access to nodes is granted only by VMAgent spec, it's updated by ensureVMAgentCRExist with selected scrape objects
+func ensureVMAgentCRCreated(ctx context.Context, rclient client.Client, cr *vmv1beta1.VMAgent) error {
+	var clusterRole rbacv1.ClusterRole
+	err := rclient.Get(ctx, types.NamespacedName{Namespace: cr.Namespace, Name: cr.GetClusterRoleName()}, &clusterRole)
+	if err == nil || !errors.IsNotFound(err) {
+		return err
+	}
+	return reconcile.ClusterRole(ctx, rclient, buildClusterRole(cr, requiresNodesAccess(cr, &scrapeObjects{})), nil)
+}
+
+// requiresNodesAccess checks if vmagent must have access to nodes API.
+// It's required for nodes discovery and attaching nodes metadata to discovered targets.
+// Content of inline and additional scrape configs is unknown, so access is always granted for it.
+// DaemonSet mode requires access for node local targets discovery
+func requiresNodesAccess(cr *vmv1beta1.VMAgent, sos *scrapeObjects) bool {
+	if cr.Spec.DaemonSetMode || cr.Spec.InlineScrapeConfig != "" || cr.Spec.AdditionalScrapeConfigs != nil || len(sos.nss) > 0 {
+		return true
+	}
+	isNodeRequested := func(am *vmv1beta1.AttachMetadata) bool {
+		return am.Node != nil && *am.Node
+	}
+	for _, ss := range sos.sss {
+		if isNodeRequested(&ss.Spec.AttachMetadata) {
+			return true
+		}
+		for _, ep := range ss.Spec.Endpoints {
+			if isNodeRequested(&ep.AttachMetadata) {
+				return true
+			}
+		}
 	}
-	return reconcile.ClusterRole(ctx, rclient, buildClusterRole(cr), prevClusterRole)
+	for _, ps := range sos.pss {
+		if isNodeRequested(&ps.Spec.AttachMetadata) {
+			return true
+		}
+		for _, ep := range ps.Spec.PodMetricsEndpoints {
+			if isNodeRequested(&ep.AttachMetadata) {
+				return true
+			}
+		}
+	}
+	for _, sc := range sos.scss {
+		for _, sdc := range sc.Spec.KubernetesSDConfigs {
+			if strings.EqualFold(sdc.Role, "node") || isNodeRequested(&sdc.AttachMetadata) {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 func ensureCRBExist(ctx context.Context, rclient client.Client, cr, prevCR *vmv1beta1.VMAgent) error {
@@ -218,7 +287,11 @@ func buildClusterRoleBinding(cr *vmv1beta1.VMAgent) *rbacv1.ClusterRoleBinding {
 	}
 }
 
-func buildClusterRole(cr *vmv1beta1.VMAgent) *rbacv1.ClusterRole {
+func buildClusterRole(cr *vmv1beta1.VMAgent, withNodes bool) *rbacv1.ClusterRole {
+	rules := clusterWidePolicyRules
+	if withNodes {
+		rules = append(slices.Clone(rules), nodesPolicyRule)
+	}
 	return &rbacv1.ClusterRole{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:        cr.GetClusterRoleName(),
@@ -230,7 +303,7 @@ func buildClusterRole(cr *vmv1beta1.VMAgent) *rbacv1.ClusterRole {
 			// use crd instead
 			OwnerReferences: cr.AsCRDOwner(),
 		},
-		Rules: clusterWidePolicyRules,
+		Rules: rules,
 	}
 }
 
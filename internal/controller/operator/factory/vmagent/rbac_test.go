@@ -2,6 +2,7 @@ package vmagent
 
 import (
 	"context"
+	"slices"
 	"testing"
 
 	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
@@ -10,6 +11,8 @@ import (
 	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
 )
 
 func TestCreateVMAgentClusterAccess(t *testing.T) {
@@ -93,3 +96,123 @@ func TestCreateVMAgentClusterAccess(t *testing.T) {
 		})
 	}
 }
+
+func TestRequiresNodesAccess(t *testing.T) {
+	f := func(cr *vmv1beta1.VMAgent, sos *scrapeObjects, want bool) {
+		t.Helper()
+		if got := requiresNodesAccess(cr, sos); got != want {
+			t.Fatalf("unexpected result, got: %v, want: %v", got, want)
+		}
+	}
+	cr := &vmv1beta1.VMAgent{}
+
+	// no scrape objects
+	f(cr, &scrapeObjects{}, false)
+
+	// scrape objects without node metadata
+	f(cr, &scrapeObjects{
+		sss: []*vmv1beta1.VMServiceScrape{{Spec: vmv1beta1.VMServiceScrapeSpec{Endpoints: []vmv1beta1.Endpoint{{}}}}},
+		pss: []*vmv1beta1.VMPodScrape{{Spec: vmv1beta1.VMPodScrapeSpec{
+			AttachMetadata:      vmv1beta1.AttachMetadata{Node: ptr.To(false)},
+			PodMetricsEndpoints: []vmv1beta1.PodMetricsEndpoint{{}},
+		}}},
+		scss: []*vmv1beta1.VMScrapeConfig{{Spec: vmv1beta1.VMScrapeConfigSpec{
+			KubernetesSDConfigs: []vmv1beta1.KubernetesSDConfig{{Role: "pod"}},
+		}}},
+	}, false)
+
+	// pod scrape with node metadata
+	f(cr, &scrapeObjects{
+		pss: []*vmv1beta1.VMPodScrape{{Spec: vmv1beta1.VMPodScrapeSpec{
+			AttachMetadata: vmv1beta1.AttachMetadata{Node: ptr.To(true)},
+		}}},
+	}, true)
+
+	// service scrape endpoint with node metadata
+	f(cr, &scrapeObjects{
+		sss: []*vmv1beta1.VMServiceScrape{{Spec: vmv1beta1.VMServiceScrapeSpec{Endpoints: []vmv1beta1.Endpoint{
+			{},
+			{AttachMetadata: vmv1beta1.AttachMetadata{Node: ptr.To(true)}},
+		}}}},
+	}, true)
+
+	// node scrape
+	f(cr, &scrapeObjects{nss: []*vmv1beta1.VMNodeScrape{{}}}, true)
+
+	// scrape config with node discovery
+	f(cr, &scrapeObjects{
+		scss: []*vmv1beta1.VMScrapeConfig{{Spec: vmv1beta1.VMScrapeConfigSpec{
+			KubernetesSDConfigs: []vmv1beta1.KubernetesSDConfig{{Role: "node"}},
+		}}},
+	}, true)
+
+	// inline scrape config
+	f(&vmv1beta1.VMAgent{Spec: vmv1beta1.VMAgentSpec{InlineScrapeConfig: "- job_name: custom"}}, &scrapeObjects{}, true)
+
+	// daemonset mode
+	f(&vmv1beta1.VMAgent{Spec: vmv1beta1.VMAgentSpec{DaemonSetMode: true}}, &scrapeObjects{}, true)
+}
+
+func TestEnsureVMAgentCRExistNodesAccess(t *testing.T) {
+	cr := &vmv1beta1.VMAgent{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "rbac-test",
+		},
+	}
+	ctx := context.TODO()
+	fclient := k8stools.GetTestClientWithObjects(nil)
+	hasNodesRule := func() bool {
+		t.Helper()
+		var role rbacv1.ClusterRole
+		if err := fclient.Get(ctx, types.NamespacedName{Namespace: cr.Namespace, Name: cr.GetClusterRoleName()}, &role); err != nil {
+			t.Fatalf("cannot get cluster role: %s", err)
+		}
+		for _, rule := range role.Rules {
+			if slices.Contains(rule.Resources, "nodes") {
+				return true
+			}
+		}
+		return false
+	}
+	if err := ensureVMAgentCRExist(ctx, fclient, cr, nil, &scrapeObjects{}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if hasNodesRule() {
+		t.Fatalf("cluster role must not have access to nodes")
+	}
+	sos := &scrapeObjects{
+		pss: []*vmv1beta1.VMPodScrape{{Spec: vmv1beta1.VMPodScrapeSpec{
+			AttachMetadata: vmv1beta1.AttachMetadata{Node: ptr.To(true)},
+		}}},
+	}
+	if err := ensureVMAgentCRExist(ctx, fclient, cr, nil, sos); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !hasNodesRule() {
+		t.Fatalf("cluster role must have access to nodes")
+	}
+}
+
+func TestCreateVMAgentK8sAPIAccessClusterRole(t *testing.T) {
+	cr := &vmv1beta1.VMAgent{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "rbac-test",
+		},
+		Spec: vmv1beta1.VMAgentSpec{DaemonSetMode: true},
+	}
+	ctx := context.TODO()
+	fclient := k8stools.GetTestClientWithObjects(nil)
+	// cluster role binding must not refer missing cluster role
+	if err := createVMAgentK8sAPIAccess(ctx, fclient, cr, nil, true); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var role rbacv1.ClusterRole
+	if err := fclient.Get(ctx, types.NamespacedName{Namespace: cr.Namespace, Name: cr.GetClusterRoleName()}, &role); err != nil {
+		t.Fatalf("cannot get cluster role: %s", err)
+	}
+	if !slices.ContainsFunc(role.Rules, func(rule rbacv1.PolicyRule) bool { return slices.Contains(rule.Resources, "nodes") }) {
+		t.Fatalf("cluster role must have access to nodes in daemonset mode")
+	}
+}
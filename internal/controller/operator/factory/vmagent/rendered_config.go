@@ -0,0 +1,199 @@
+package vmagent
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/finalize"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/reconcile"
+)
+
+const renderedConfigKey = "config.yaml"
+
+// sensitiveConfigKeys contains keys of scrape configuration, which values must be redacted
+var sensitiveConfigKeys = map[string]struct{}{
+	"password":                      {},
+	"bearer_token":                  {},
+	"proxy_bearer_token":            {},
+	"credentials":                   {},
+	"client_secret":                 {},
+	"secret_key":                    {},
+	"token":                         {},
+	"consul_token":                  {},
+	"application_credential_secret": {},
+}
+
+func isExposeRenderedConfig(cr *vmv1beta1.VMAgent) bool {
+	return cr.Spec.Debug != nil && cr.Spec.Debug.ExposeRenderedConfig
+}
+
+// redactConfig returns a copy of the given scrape configuration with secret values replaced by references to its secrets.
+// Values, which cannot be resolved to the secret, are replaced with <secret>
+func redactConfig(data []byte, ssCache *scrapesSecretsCache) ([]byte, error) {
+	secretRefs := make(map[string]string)
+	if ssCache != nil {
+		cacheKeys := make([]string, 0, len(ssCache.nsSecretCache))
+		for k := range ssCache.nsSecretCache {
+			cacheKeys = append(cacheKeys, k)
+		}
+		sort.Strings(cacheKeys)
+		for _, k := range cacheKeys {
+			s := ssCache.nsSecretCache[k]
+			keys := make([]string, 0, len(s.Data))
+			for key := range s.Data {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				v := strings.TrimSpace(string(s.Data[key]))
+				if _, ok := secretRefs[v]; !ok && v != "" {
+					secretRefs[v] = fmt.Sprintf("<secret:%s/%s/%s>", s.Namespace, s.Name, key)
+				}
+			}
+		}
+	}
+	var cfg yaml.MapSlice
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("cannot parse config: %w", err)
+	}
+	redacted := redactConfigValue(cfg, "", secretRefs)
+	return yaml.Marshal(redacted)
+}
+
+func redactConfigValue(v any, parentKey string, secretRefs map[string]string) any {
+	switch t := v.(type) {
+	case yaml.MapSlice:
+		for i := range t {
+			key, _ := t[i].Key.(string)
+			_, sensitive := sensitiveConfigKeys[key]
+			// tls key material could be defined inline
+			if key == "key" && strings.HasSuffix(parentKey, "tls_config") {
+				sensitive = true
+			}
+			if sensitive {
+				if s, ok := t[i].Value.(string); ok {
+					ref, ok := secretRefs[strings.TrimSpace(s)]
+					if !ok {
+						ref = "<secret>"
+					}
+					t[i].Value = ref
+				}
+				continue
+			}
+			t[i].Value = redactConfigValue(t[i].Value, key, secretRefs)
+		}
+		return t
+	case []any:
+		for i := range t {
+			t[i] = redactConfigValue(t[i], parentKey, secretRefs)
+		}
+		return t
+	case string:
+		// headers are defined in the `Name: value` format and value could be loaded from secret
+		if parentKey == "headers" {
+			if name, value, ok := strings.Cut(t, ":"); ok {
+				if ref, ok := secretRefs[strings.TrimSpace(value)]; ok {
+					return fmt.Sprintf("%s: %s", name, ref)
+				}
+			}
+		}
+	}
+	return v
+}
+
+// reconcileRenderedConfig writes redacted scrape configuration into ConfigMap
+// or removes it, if rendered config isn't exposed
+func reconcileRenderedConfig(ctx context.Context, rclient client.Client, cr, prevCR *vmv1beta1.VMAgent, data []byte, ssCache *scrapesSecretsCache) error {
+	if !isExposeRenderedConfig(cr) {
+		cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: cr.RenderedConfigName(), Namespace: cr.Namespace}}
+		return finalize.SafeDeleteWithFinalizer(ctx, rclient, cm)
+	}
+	redacted, err := redactConfig(data, ssCache)
+	if err != nil {
+		return fmt.Errorf("cannot redact vmagent config: %w", err)
+	}
+	if len(redacted) > vmv1beta1.MaxConfigMapDataSize {
+		redacted = []byte(fmt.Sprintf("# rendered configuration size=%d exceeds ConfigMap size limit=%d\n", len(redacted), vmv1beta1.MaxConfigMapDataSize))
+	}
+	meta := buildConfigMeta(cr)
+	meta.Name = cr.RenderedConfigName()
+	cm := &corev1.ConfigMap{
+		ObjectMeta: meta,
+		Data: map[string]string{
+			renderedConfigKey: string(redacted),
+		},
+	}
+	var prevMeta *metav1.ObjectMeta
+	if prevCR != nil {
+		prevMeta = ptr.To(buildConfigMeta(prevCR))
+		prevMeta.Name = prevCR.RenderedConfigName()
+	}
+	if err := reconcile.ConfigMap(ctx, rclient, cm, prevMeta); err != nil {
+		return fmt.Errorf("cannot reconcile vmagent rendered config: %w", err)
+	}
+	return nil
+}
+
+// scrapeJobsByObject returns names of generated scrape jobs grouped by kind/namespace/name of scrape object
+func scrapeJobsByObject(cfg yaml.MapSlice) map[string][]string {
+	jobsByObject := make(map[string][]string)
+	for _, item := range cfg {
+		if item.Key != "scrape_configs" {
+			continue
+		}
+		for _, sc := range item.Value.([]yaml.MapSlice) {
+			jobName := scrapeJobName(sc)
+			// job name has form kind/namespace/name[/idx]
+			parts := strings.SplitN(jobName, "/", 4)
+			if len(parts) < 3 {
+				continue
+			}
+			key := strings.Join(parts[:3], "/")
+			jobsByObject[key] = append(jobsByObject[key], jobName)
+		}
+	}
+	return jobsByObject
+}
+
+// setGeneratedJobsInfo adds names of generated scrape jobs into status of scrape objects
+func setGeneratedJobsInfo(cfg yaml.MapSlice, sos *scrapeObjects) {
+	jobsByObject := scrapeJobsByObject(cfg)
+	setInfo := func(kind string, o interface {
+		GetNamespace() string
+		GetName() string
+		GetStatusMetadata() *vmv1beta1.StatusMetadata
+	}) {
+		jobs := jobsByObject[fmt.Sprintf("%s/%s/%s", kind, o.GetNamespace(), o.GetName())]
+		if len(jobs) == 0 {
+			return
+		}
+		o.GetStatusMetadata().CurrentSyncInfo = fmt.Sprintf("generated jobs: %s", strings.Join(jobs, ","))
+	}
+	for _, o := range sos.sss {
+		setInfo("serviceScrape", o)
+	}
+	for _, o := range sos.pss {
+		setInfo("podScrape", o)
+	}
+	for _, o := range sos.prss {
+		setInfo("probe", o)
+	}
+	for _, o := range sos.nss {
+		setInfo("nodeScrape", o)
+	}
+	for _, o := range sos.stss {
+		setInfo("staticScrape", o)
+	}
+	for _, o := range sos.scss {
+		setInfo("scrapeConfig", o)
+	}
+}
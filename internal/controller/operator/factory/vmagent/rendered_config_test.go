@@ -0,0 +1,125 @@
+package vmagent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+
+	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/build"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/k8stools"
+)
+
+func Test_redactConfig(t *testing.T) {
+	ssCache := &scrapesSecretsCache{
+		nsSecretCache: map[string]*corev1.Secret{
+			"default/ba-secret": {
+				ObjectMeta: metav1.ObjectMeta{Name: "ba-secret", Namespace: "default"},
+				Data:       map[string][]byte{"password": []byte("pass\n"), "user": []byte("admin"), "api-key": []byte("key-value")},
+			},
+		},
+	}
+	data := []byte(`scrape_configs:
+- job_name: staticScrape/default/test/0
+  basic_auth:
+    username: admin
+    password: pass
+  authorization:
+    credentials: inline-token
+  headers:
+  - 'X-Api-Key: key-value'
+  - 'X-Tenant: team-a'
+  tls_config:
+    key: inline-key
+    key_file: /etc/vmagent-tls/certs/key
+  static_configs:
+  - targets:
+    - host:9100
+`)
+	got, err := redactConfig(data, ssCache)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.Equal(t, `scrape_configs:
+- job_name: staticScrape/default/test/0
+  basic_auth:
+    username: admin
+    password: <secret:default/ba-secret/password>
+  authorization:
+    credentials: <secret>
+  headers:
+  - 'X-Api-Key: <secret:default/ba-secret/api-key>'
+  - 'X-Tenant: team-a'
+  tls_config:
+    key: <secret>
+    key_file: /etc/vmagent-tls/certs/key
+  static_configs:
+  - targets:
+    - host:9100
+`, string(got))
+}
+
+func TestCreateOrUpdateConfigurationSecretRenderedConfig(t *testing.T) {
+	cr := &vmv1beta1.VMAgent{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+		Spec: vmv1beta1.VMAgentSpec{
+			StaticScrapeSelector: &metav1.LabelSelector{},
+			Debug:                &vmv1beta1.VMAgentDebug{ExposeRenderedConfig: true},
+		},
+	}
+	static := &vmv1beta1.VMStaticScrape{
+		ObjectMeta: metav1.ObjectMeta{Name: "static", Namespace: "default"},
+		Spec: vmv1beta1.VMStaticScrapeSpec{
+			TargetEndpoints: []*vmv1beta1.TargetEndpoint{{
+				Targets: []string{"host:9100"},
+				EndpointAuth: vmv1beta1.EndpointAuth{
+					BearerTokenSecret: &corev1.SecretKeySelector{
+						Key:                  "token",
+						LocalObjectReference: corev1.LocalObjectReference{Name: "token-secret"},
+					},
+				},
+			}},
+		},
+	}
+	fclient := k8stools.GetTestClientWithObjects([]runtime.Object{
+		static,
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "token-secret", Namespace: "default"},
+			Data:       map[string][]byte{"token": []byte("secret-token")},
+		},
+	})
+	build.AddDefaults(fclient.Scheme())
+	ctx := context.TODO()
+	if _, _, err := createOrUpdateConfigurationSecret(ctx, fclient, cr, nil, nil, nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var cm corev1.ConfigMap
+	nsn := types.NamespacedName{Namespace: cr.Namespace, Name: cr.RenderedConfigName()}
+	if err := fclient.Get(ctx, nsn, &cm); err != nil {
+		t.Fatalf("cannot get rendered config: %s", err)
+	}
+	assert.Contains(t, cm.Data[renderedConfigKey], "bearer_token: <secret:default/token-secret/token>")
+	assert.NotContains(t, cm.Data[renderedConfigKey], "secret-token")
+
+	var gotStatic vmv1beta1.VMStaticScrape
+	if err := fclient.Get(ctx, types.NamespacedName{Namespace: static.Namespace, Name: static.Name}, &gotStatic); err != nil {
+		t.Fatalf("cannot get static scrape: %s", err)
+	}
+	if assert.Len(t, gotStatic.Status.Conditions, 1) {
+		assert.Equal(t, "generated jobs: staticScrape/default/static/0", gotStatic.Status.Conditions[0].Message)
+	}
+
+	// rendered config is removed
+	cr.Spec.Debug = nil
+	if _, _, err := createOrUpdateConfigurationSecret(ctx, fclient, cr, nil, nil, nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	err := fclient.Get(ctx, nsn, &cm)
+	assert.True(t, k8serrors.IsNotFound(err), "unexpected error: %v", err)
+}
@@ -0,0 +1,127 @@
+package vmagent
+
+import (
+	"fmt"
+
+	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
+)
+
+// findScrapeClass returns scrape class referenced by the given name or the default class if name is empty
+func findScrapeClass(cr *vmv1beta1.VMAgent, name string) (*vmv1beta1.ScrapeClass, error) {
+	for i := range cr.Spec.ScrapeClasses {
+		sc := &cr.Spec.ScrapeClasses[i]
+		if name == "" && sc.Default {
+			return sc, nil
+		}
+		if name != "" && sc.Name == name {
+			return sc, nil
+		}
+	}
+	if name != "" {
+		return nil, fmt.Errorf("scrapeClassName=%q is not defined at VMAgent spec.scrapeClasses", name)
+	}
+	return nil, nil
+}
+
+// mergeScrapeClassAuth sets class settings for endpoint without own settings
+func mergeScrapeClassAuth(sc *vmv1beta1.ScrapeClass, ea *vmv1beta1.EndpointAuth) {
+	if ea.TLSConfig == nil && sc.TLSConfig != nil {
+		ea.TLSConfig = sc.TLSConfig.DeepCopy()
+	}
+	hasAuth := ea.BasicAuth != nil || ea.BearerTokenSecret != nil || ea.BearerTokenFile != "" || ea.OAuth2 != nil || ea.Authorization != nil
+	if !hasAuth && sc.Authorization != nil {
+		ea.Authorization = sc.Authorization.DeepCopy()
+	}
+}
+
+// mergeScrapeClassRelabelings adds class relabel configs before relabel configs of endpoint
+func mergeScrapeClassRelabelings(sc *vmv1beta1.ScrapeClass, er *vmv1beta1.EndpointRelabelings) {
+	if len(sc.RelabelConfigs) > 0 {
+		er.RelabelConfigs = append(append([]*vmv1beta1.RelabelConfig{}, sc.RelabelConfigs...), er.RelabelConfigs...)
+	}
+	if len(sc.MetricRelabelConfigs) > 0 {
+		er.MetricRelabelConfigs = append(append([]*vmv1beta1.RelabelConfig{}, sc.MetricRelabelConfigs...), er.MetricRelabelConfigs...)
+	}
+}
+
+// mergeScrapeClassAttachMetadata sets class attach metadata for scrape object without own settings
+func mergeScrapeClassAttachMetadata(sc *vmv1beta1.ScrapeClass, dst *vmv1beta1.AttachMetadata) {
+	if dst.Node == nil && sc.AttachMetadata != nil {
+		dst.Node = sc.AttachMetadata.Node
+	}
+}
+
+// collectScrapeClassErrors applies scrape class to each object
+// and returns objects with unknown scrapeClassName separately
+func collectScrapeClassErrors[T scrapeObjectWithStatus](src []T, apply func(o T) error) ([]T, []T) {
+	var cnt int
+	var broken []T
+	for _, o := range src {
+		if err := apply(o); err != nil {
+			o.GetStatusMetadata().CurrentSyncError = err.Error()
+			broken = append(broken, o)
+			continue
+		}
+		src[cnt] = o
+		cnt++
+	}
+	return src[:cnt], broken
+}
+
+// applyScrapeClasses merges scrape classes defined at VMAgent into VMServiceScrape, VMPodScrape and VMProbe objects.
+// Settings of the scrape object have priority over class settings
+func applyScrapeClasses(cr *vmv1beta1.VMAgent, sos *scrapeObjects) {
+	var brokenSss []*vmv1beta1.VMServiceScrape
+	sos.sss, brokenSss = collectScrapeClassErrors(sos.sss, func(o *vmv1beta1.VMServiceScrape) error {
+		sc, err := findScrapeClass(cr, o.Spec.ScrapeClassName)
+		if err != nil || sc == nil {
+			return err
+		}
+		mergeScrapeClassAttachMetadata(sc, &o.Spec.AttachMetadata)
+		for i := range o.Spec.Endpoints {
+			ep := &o.Spec.Endpoints[i]
+			mergeScrapeClassAuth(sc, &ep.EndpointAuth)
+			mergeScrapeClassRelabelings(sc, &ep.EndpointRelabelings)
+		}
+		return nil
+	})
+	sos.sssBroken = append(sos.sssBroken, brokenSss...)
+
+	var brokenPss []*vmv1beta1.VMPodScrape
+	sos.pss, brokenPss = collectScrapeClassErrors(sos.pss, func(o *vmv1beta1.VMPodScrape) error {
+		sc, err := findScrapeClass(cr, o.Spec.ScrapeClassName)
+		if err != nil || sc == nil {
+			return err
+		}
+		mergeScrapeClassAttachMetadata(sc, &o.Spec.AttachMetadata)
+		for i := range o.Spec.PodMetricsEndpoints {
+			ep := &o.Spec.PodMetricsEndpoints[i]
+			mergeScrapeClassAuth(sc, &ep.EndpointAuth)
+			mergeScrapeClassRelabelings(sc, &ep.EndpointRelabelings)
+		}
+		return nil
+	})
+	sos.pssBroken = append(sos.pssBroken, brokenPss...)
+
+	var brokenPrss []*vmv1beta1.VMProbe
+	sos.prss, brokenPrss = collectScrapeClassErrors(sos.prss, func(o *vmv1beta1.VMProbe) error {
+		sc, err := findScrapeClass(cr, o.Spec.ScrapeClassName)
+		if err != nil || sc == nil {
+			return err
+		}
+		mergeScrapeClassAuth(sc, &o.Spec.EndpointAuth)
+		if len(sc.RelabelConfigs) > 0 {
+			if st := o.Spec.Targets.StaticConfig; st != nil {
+				st.RelabelConfigs = append(append([]*vmv1beta1.RelabelConfig{}, sc.RelabelConfigs...), st.RelabelConfigs...)
+			}
+			if ing := o.Spec.Targets.Ingress; ing != nil {
+				ing.RelabelConfigs = append(append([]*vmv1beta1.RelabelConfig{}, sc.RelabelConfigs...), ing.RelabelConfigs...)
+			}
+		}
+		if len(sc.MetricRelabelConfigs) > 0 {
+			o.Spec.MetricRelabelConfigs = append(append([]*vmv1beta1.RelabelConfig{}, sc.MetricRelabelConfigs...), o.Spec.MetricRelabelConfigs...)
+		}
+		return nil
+	})
+	sos.prssBroken = append(sos.prssBroken, brokenPrss...)
+}
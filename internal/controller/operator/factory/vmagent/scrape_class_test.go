@@ -0,0 +1,124 @@
+package vmagent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
+)
+
+func TestApplyScrapeClasses(t *testing.T) {
+	cr := &vmv1beta1.VMAgent{
+		Spec: vmv1beta1.VMAgentSpec{
+			ScrapeClasses: []vmv1beta1.ScrapeClass{
+				{
+					Name:          "istio",
+					Default:       true,
+					TLSConfig:     &vmv1beta1.TLSConfig{CAFile: "/etc/istio-certs/root-cert.pem"},
+					Authorization: &vmv1beta1.Authorization{CredentialsFile: "/var/run/secrets/token"},
+					RelabelConfigs: []*vmv1beta1.RelabelConfig{
+						{TargetLabel: "mesh", Replacement: ptr.To("istio")},
+					},
+					MetricRelabelConfigs: []*vmv1beta1.RelabelConfig{
+						{Action: "drop", SourceLabels: []string{"__name__"}, Regex: vmv1beta1.StringOrArray{"istio_.*"}},
+					},
+					AttachMetadata: &vmv1beta1.AttachMetadata{Node: ptr.To(true)},
+				},
+				{
+					Name:      "plain",
+					TLSConfig: &vmv1beta1.TLSConfig{InsecureSkipVerify: true},
+				},
+			},
+		},
+	}
+	sos := &scrapeObjects{
+		sss: []*vmv1beta1.VMServiceScrape{
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "default-class", Namespace: "default"},
+				Spec: vmv1beta1.VMServiceScrapeSpec{
+					Endpoints: []vmv1beta1.Endpoint{
+						{
+							EndpointRelabelings: vmv1beta1.EndpointRelabelings{
+								RelabelConfigs: []*vmv1beta1.RelabelConfig{{TargetLabel: "team", Replacement: ptr.To("infra")}},
+							},
+						},
+						{
+							EndpointAuth: vmv1beta1.EndpointAuth{
+								TLSConfig:         &vmv1beta1.TLSConfig{ServerName: "own"},
+								BearerTokenSecret: &corev1.SecretKeySelector{Key: "token"},
+							},
+						},
+					},
+				},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "unknown-class", Namespace: "default"},
+				Spec: vmv1beta1.VMServiceScrapeSpec{
+					ScrapeClassName: "missing",
+					Endpoints:       []vmv1beta1.Endpoint{{}},
+				},
+			},
+		},
+		pss: []*vmv1beta1.VMPodScrape{
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "plain-class", Namespace: "default"},
+				Spec: vmv1beta1.VMPodScrapeSpec{
+					ScrapeClassName:     "plain",
+					PodMetricsEndpoints: []vmv1beta1.PodMetricsEndpoint{{}},
+				},
+			},
+		},
+		prss: []*vmv1beta1.VMProbe{
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "probe", Namespace: "default"},
+				Spec: vmv1beta1.VMProbeSpec{
+					Targets: vmv1beta1.VMProbeTargets{
+						StaticConfig: &vmv1beta1.VMProbeTargetStaticConfig{Targets: []string{"example.com"}},
+					},
+				},
+			},
+		},
+	}
+	applyScrapeClasses(cr, sos)
+
+	// default class with own endpoint settings
+	if assert.Len(t, sos.sss, 1) {
+		sss := sos.sss[0]
+		assert.Equal(t, ptr.To(true), sss.Spec.AttachMetadata.Node)
+		ep := sss.Spec.Endpoints[0]
+		assert.Equal(t, "/etc/istio-certs/root-cert.pem", ep.TLSConfig.CAFile)
+		assert.Equal(t, "/var/run/secrets/token", ep.Authorization.CredentialsFile)
+		assert.Equal(t, []*vmv1beta1.RelabelConfig{
+			{TargetLabel: "mesh", Replacement: ptr.To("istio")},
+			{TargetLabel: "team", Replacement: ptr.To("infra")},
+		}, ep.RelabelConfigs)
+		assert.Len(t, ep.MetricRelabelConfigs, 1)
+		ep = sss.Spec.Endpoints[1]
+		assert.Equal(t, &vmv1beta1.TLSConfig{ServerName: "own"}, ep.TLSConfig)
+		assert.Nil(t, ep.Authorization)
+	}
+	// unknown class
+	if assert.Len(t, sos.sssBroken, 1) {
+		assert.Equal(t, "unknown-class", sos.sssBroken[0].Name)
+		assert.Equal(t, `scrapeClassName="missing" is not defined at VMAgent spec.scrapeClasses`, sos.sssBroken[0].Status.CurrentSyncError)
+	}
+	// named class
+	if assert.Len(t, sos.pss, 1) {
+		ep := sos.pss[0].Spec.PodMetricsEndpoints[0]
+		assert.Equal(t, &vmv1beta1.TLSConfig{InsecureSkipVerify: true}, ep.TLSConfig)
+		assert.Nil(t, ep.Authorization)
+		assert.Nil(t, ep.RelabelConfigs)
+		assert.Nil(t, sos.pss[0].Spec.AttachMetadata.Node)
+	}
+	// probe with default class
+	if assert.Len(t, sos.prss, 1) {
+		probe := sos.prss[0]
+		assert.Equal(t, "/etc/istio-certs/root-cert.pem", probe.Spec.TLSConfig.CAFile)
+		assert.Len(t, probe.Spec.Targets.StaticConfig.RelabelConfigs, 1)
+		assert.Len(t, probe.Spec.MetricRelabelConfigs, 1)
+	}
+}
@@ -0,0 +1,121 @@
+package vmagent
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promrelabel"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape"
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/yaml.v2"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
+)
+
+var invalidScrapeObjects = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "operator_vmagent_invalid_scrape_objects",
+	Help: "Number of selected scrape objects with errors, which are excluded from VMAgent scrape configuration",
+}, []string{"namespace", "name"})
+
+func init() {
+	metrics.Registry.MustRegister(invalidScrapeObjects)
+}
+
+// DeregisterScrapeMetrics removes scrape objects metrics of the given VMAgent
+// it must be called on VMAgent delete in order to not expose stale series
+func DeregisterScrapeMetrics(cr *vmv1beta1.VMAgent) {
+	invalidScrapeObjects.DeleteLabelValues(cr.Namespace, cr.Name)
+}
+
+const scrapeObjectRejectedEventReason = "ScrapeObjectRejected"
+
+// envTemplatePrefix marks environment variables placeholders, which are expanded by vmagent at runtime
+var envTemplatePrefix = []byte("%{")
+
+// validateScrapeConfig parses generated scrape config with vmagent config parser
+// and returns error if vmagent cannot load it
+func validateScrapeConfig(sc yaml.MapSlice) error {
+	// scrape_protocols is unknown to the bundled config parser,
+	// so its values are checked explicitly before parsing the rest of config
+	parserSC := make(yaml.MapSlice, 0, len(sc))
+	for _, item := range sc {
+		if item.Key == "scrape_protocols" {
+			if err := validateScrapeProtocols(item.Value); err != nil {
+				return err
+			}
+			continue
+		}
+		parserSC = append(parserSC, item)
+	}
+	data, err := yaml.Marshal(parserSC)
+	if err != nil {
+		return fmt.Errorf("cannot marshal scrape config: %w", err)
+	}
+	// placeholders cannot be expanded at operator side
+	if bytes.Contains(data, envTemplatePrefix) {
+		return nil
+	}
+	var parsed promscrape.ScrapeConfig
+	if err := yaml.UnmarshalStrict(data, &parsed); err != nil {
+		return fmt.Errorf("cannot parse scrape config: %w", err)
+	}
+	if parsed.JobName == "" {
+		return fmt.Errorf("missing `job_name` field in scrape config")
+	}
+	if _, err := parsed.HTTPClientConfig.NewConfig(""); err != nil {
+		return fmt.Errorf("cannot parse auth config for job_name=%q: %w", parsed.JobName, err)
+	}
+	if _, err := parsed.ProxyClientConfig.NewConfig(""); err != nil {
+		return fmt.Errorf("cannot parse proxy auth config for job_name=%q: %w", parsed.JobName, err)
+	}
+	if _, err := promrelabel.ParseRelabelConfigs(parsed.RelabelConfigs); err != nil {
+		return fmt.Errorf("cannot parse relabel_configs for job_name=%q: %w", parsed.JobName, err)
+	}
+	if _, err := promrelabel.ParseRelabelConfigs(parsed.MetricRelabelConfigs); err != nil {
+		return fmt.Errorf("cannot parse metric_relabel_configs for job_name=%q: %w", parsed.JobName, err)
+	}
+	return nil
+}
+
+// validateScrapeProtocols checks scrape_protocols value of generated or parsed scrape config
+func validateScrapeProtocols(v any) error {
+	var protocols []string
+	switch v := v.(type) {
+	case []string:
+		protocols = v
+	case []any:
+		for _, p := range v {
+			ps, ok := p.(string)
+			if !ok {
+				return fmt.Errorf("unexpected scrape_protocols item type=%T, want string", p)
+			}
+			protocols = append(protocols, ps)
+		}
+	default:
+		return fmt.Errorf("unexpected scrape_protocols type=%T, want list of strings", v)
+	}
+	return vmv1beta1.ValidateScrapeProtocols(protocols)
+}
+
+// appendValidScrapeConfigs generates scrape configs for the given objects and appends them to dst
+// objects with scrape configs rejected by validateScrapeConfig are excluded and returned as invalid with error at status
+func appendValidScrapeConfigs[T scrapeObjectWithStatus](dst []yaml.MapSlice, src []T, generate func(o T, idx int) []yaml.MapSlice) ([]yaml.MapSlice, []T, []T) {
+	var cnt int
+	var invalid []T
+OUTER:
+	for idx, o := range src {
+		scs := generate(o, idx)
+		for _, sc := range scs {
+			if err := validateScrapeConfig(sc); err != nil {
+				o.GetStatusMetadata().CurrentSyncError = fmt.Sprintf("generated scrape configuration is rejected by vmagent config parser: %s", err)
+				invalid = append(invalid, o)
+				continue OUTER
+			}
+		}
+		dst = append(dst, scs...)
+		src[cnt] = o
+		cnt++
+	}
+	return dst, src[:cnt], invalid
+}
@@ -0,0 +1,293 @@
+package vmagent
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+
+	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/build"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/k8stools"
+)
+
+func Test_validateScrapeConfig(t *testing.T) {
+	f := func(data string, wantErr bool) {
+		t.Helper()
+		var sc yaml.MapSlice
+		if err := yaml.Unmarshal([]byte(data), &sc); err != nil {
+			t.Fatalf("cannot unmarshal test config: %s", err)
+		}
+		err := validateScrapeConfig(sc)
+		if (err != nil) != wantErr {
+			t.Fatalf("unexpected error: %v, wantErr: %v", err, wantErr)
+		}
+	}
+	// valid config
+	f(`
+job_name: staticScrape/default/test/0
+static_configs:
+- targets: [host:9100]
+scrape_interval: 30s
+relabel_configs:
+- source_labels: [__address__]
+  target_label: instance
+`, false)
+	// unknown field
+	f(`
+job_name: staticScrape/default/test/0
+unknown_field: value
+`, true)
+	// bad duration
+	f(`
+job_name: staticScrape/default/test/0
+scrape_interval: 1x
+`, true)
+	// bad relabel action
+	f(`
+job_name: staticScrape/default/test/0
+metric_relabel_configs:
+- action: unknown
+`, true)
+	// bad relabel regex
+	f(`
+job_name: staticScrape/default/test/0
+relabel_configs:
+- source_labels: [job]
+  regex: "(a"
+`, true)
+	// env placeholders are expanded by vmagent
+	f(`
+job_name: staticScrape/default/test/0
+scrape_interval: "%{SCRAPE_INTERVAL}"
+`, false)
+	// scrape protocols
+	f(`
+job_name: staticScrape/default/test/0
+max_scrape_size: 64MiB
+scrape_protocols: [PrometheusProto]
+`, false)
+	// unsupported scrape protocol
+	f(`
+job_name: staticScrape/default/test/0
+scrape_protocols: [PrometheusProto, protobuf]
+`, true)
+	// bad scrape protocols type
+	f(`
+job_name: staticScrape/default/test/0
+scrape_protocols: PrometheusProto
+`, true)
+}
+
+func Test_validateScrapeProtocols(t *testing.T) {
+	assert.NoError(t, validateScrapeProtocols([]string{"PrometheusProto", "PrometheusText0.0.4"}))
+	assert.Error(t, validateScrapeProtocols([]string{"PrometheusProto", "protobuf"}))
+	assert.Error(t, validateScrapeProtocols([]any{"PrometheusProto", 1}))
+}
+
+func Test_validateScrapeParams(t *testing.T) {
+	f := func(cs vmv1beta1.EndpointScrapeParams, wantErr bool) {
+		t.Helper()
+		err := validateScrapeParams(&cs)
+		if (err != nil) != wantErr {
+			t.Fatalf("unexpected error: %v, wantErr: %v", err, wantErr)
+		}
+		var se *invalidScrapeParamsError
+		if wantErr && !errors.As(err, &se) {
+			t.Fatalf("unexpected error type: %T", err)
+		}
+	}
+	f(vmv1beta1.EndpointScrapeParams{}, false)
+	f(vmv1beta1.EndpointScrapeParams{MaxScrapeSize: "16MB", ScrapeProtocols: []string{"OpenMetricsText1.0.0", "PrometheusText1.0.0"}}, false)
+	f(vmv1beta1.EndpointScrapeParams{MaxScrapeSize: "16XB"}, true)
+	f(vmv1beta1.EndpointScrapeParams{ScrapeProtocols: []string{"PrometheusProto", "protobuf"}}, true)
+}
+
+func Test_validateStaticTargetEndpoint(t *testing.T) {
+	f := func(ep vmv1beta1.TargetEndpoint, wantErr bool) {
+		t.Helper()
+		err := ep.Validate()
+		if (err != nil) != wantErr {
+			t.Fatalf("unexpected error: %v, wantErr: %v", err, wantErr)
+		}
+	}
+	f(vmv1beta1.TargetEndpoint{Targets: []string{"host:9100"}}, false)
+	f(vmv1beta1.TargetEndpoint{TargetsWithLabels: []vmv1beta1.TargetWithLabels{{Target: "host:9100"}}}, false)
+	f(vmv1beta1.TargetEndpoint{}, true)
+	f(vmv1beta1.TargetEndpoint{Targets: []string{}, TargetsWithLabels: []vmv1beta1.TargetWithLabels{}}, true)
+	f(vmv1beta1.TargetEndpoint{TargetsWithLabels: []vmv1beta1.TargetWithLabels{{Target: ""}}}, true)
+}
+
+func TestCreateOrUpdateConfigurationSecretInvalidScrapeObjects(t *testing.T) {
+	cr := &vmv1beta1.VMAgent{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+		Spec: vmv1beta1.VMAgentSpec{
+			StaticScrapeSelector: &metav1.LabelSelector{},
+		},
+	}
+	staticScrape := func(name string, relabelConfigs []*vmv1beta1.RelabelConfig) *vmv1beta1.VMStaticScrape {
+		return &vmv1beta1.VMStaticScrape{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: vmv1beta1.VMStaticScrapeSpec{
+				TargetEndpoints: []*vmv1beta1.TargetEndpoint{{
+					Targets: []string{"host:9100"},
+					EndpointRelabelings: vmv1beta1.EndpointRelabelings{
+						MetricRelabelConfigs: relabelConfigs,
+					},
+				}},
+			},
+		}
+	}
+	fclient := k8stools.GetTestClientWithObjects([]runtime.Object{
+		staticScrape("good", []*vmv1beta1.RelabelConfig{{Action: "drop", SourceLabels: []string{"job"}}}),
+		staticScrape("bad", []*vmv1beta1.RelabelConfig{{Action: "unsupported"}}),
+	})
+	build.AddDefaults(fclient.Scheme())
+	recorder := record.NewFakeRecorder(10)
+	ctx := context.TODO()
+	if _, _, err := createOrUpdateConfigurationSecret(ctx, fclient, cr, nil, nil, recorder); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var configSecret corev1.Secret
+	if err := fclient.Get(ctx, types.NamespacedName{Namespace: cr.Namespace, Name: cr.PrefixedName()}, &configSecret); err != nil {
+		t.Fatalf("cannot get vmagent config secret: %s", err)
+	}
+	gr, err := gzip.NewReader(bytes.NewReader(configSecret.Data[vmagentGzippedFilename]))
+	if err != nil {
+		t.Fatalf("cannot read gzipped config: %s", err)
+	}
+	data, err := io.ReadAll(gr)
+	if err != nil {
+		t.Fatalf("cannot read config: %s", err)
+	}
+	assert.Contains(t, string(data), "staticScrape/default/good/0")
+	assert.NotContains(t, string(data), "staticScrape/default/bad/0")
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(invalidScrapeObjects.WithLabelValues(cr.Namespace, cr.Name)))
+	close(recorder.Events)
+	var gotEvents []string
+	for e := range recorder.Events {
+		gotEvents = append(gotEvents, e)
+	}
+	if assert.Len(t, gotEvents, 1) {
+		assert.Contains(t, gotEvents[0], "Warning ScrapeObjectRejected scrape object was rejected by vmagent=default/test")
+		assert.Contains(t, gotEvents[0], "unsupported")
+	}
+
+	var bad vmv1beta1.VMStaticScrape
+	if err := fclient.Get(ctx, types.NamespacedName{Namespace: "default", Name: "bad"}, &bad); err != nil {
+		t.Fatalf("cannot get static scrape: %s", err)
+	}
+	if assert.Len(t, bad.Status.Conditions, 1) {
+		assert.Equal(t, metav1.ConditionFalse, bad.Status.Conditions[0].Status)
+		assert.Contains(t, bad.Status.Conditions[0].Message, "generated scrape configuration is rejected by vmagent config parser")
+	}
+
+	DeregisterScrapeMetrics(cr)
+	assert.Equal(t, 0, testutil.CollectAndCount(invalidScrapeObjects))
+}
+
+func TestCreateOrUpdateConfigurationSecretScrapeConfigSDConfigs(t *testing.T) {
+	cr := &vmv1beta1.VMAgent{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+		Spec: vmv1beta1.VMAgentSpec{
+			ScrapeConfigSelector: &metav1.LabelSelector{},
+		},
+	}
+	fclient := k8stools.GetTestClientWithObjects([]runtime.Object{
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "sd-creds", Namespace: "default"},
+			Data: map[string][]byte{
+				"consul-token": []byte("consul-secret-token"),
+				"access-key":   []byte("aws-access"),
+				"secret-key":   []byte("aws-secret"),
+			},
+		},
+		&vmv1beta1.VMScrapeConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "good", Namespace: "default"},
+			Spec: vmv1beta1.VMScrapeConfigSpec{
+				ConsulSDConfigs: []vmv1beta1.ConsulSDConfig{{
+					Server: "consul:8500",
+					TokenRef: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "sd-creds"},
+						Key:                  "consul-token",
+					},
+				}},
+				DNSSDConfigs: []vmv1beta1.DNSSDConfig{{
+					Names: []string{"node.example.com"},
+					Type:  ptr.To("A"),
+					Port:  ptr.To(9100),
+				}},
+				EC2SDConfigs: []vmv1beta1.EC2SDConfig{{
+					Region: ptr.To("eu-west-1"),
+					AccessKey: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "sd-creds"},
+						Key:                  "access-key",
+					},
+					SecretKey: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "sd-creds"},
+						Key:                  "secret-key",
+					},
+				}},
+				FileSDConfigs: []vmv1beta1.FileSDConfig{{
+					Files: []string{"/etc/vmagent/sd/*.json"},
+				}},
+			},
+		},
+		&vmv1beta1.VMScrapeConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "bad", Namespace: "default"},
+			Spec: vmv1beta1.VMScrapeConfigSpec{
+				DNSSDConfigs: []vmv1beta1.DNSSDConfig{{
+					Names: []string{"node.example.com"},
+					Type:  ptr.To("A"),
+				}},
+			},
+		},
+	})
+	build.AddDefaults(fclient.Scheme())
+	ctx := context.TODO()
+	if _, _, err := createOrUpdateConfigurationSecret(ctx, fclient, cr, nil, nil, nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var configSecret corev1.Secret
+	if err := fclient.Get(ctx, types.NamespacedName{Namespace: cr.Namespace, Name: cr.PrefixedName()}, &configSecret); err != nil {
+		t.Fatalf("cannot get vmagent config secret: %s", err)
+	}
+	gr, err := gzip.NewReader(bytes.NewReader(configSecret.Data[vmagentGzippedFilename]))
+	if err != nil {
+		t.Fatalf("cannot read gzipped config: %s", err)
+	}
+	data, err := io.ReadAll(gr)
+	if err != nil {
+		t.Fatalf("cannot read config: %s", err)
+	}
+	assert.Contains(t, string(data), "job_name: scrapeConfig/default/good")
+	assert.Contains(t, string(data), "token: consul-secret-token")
+	assert.Contains(t, string(data), "secret_key: aws-secret")
+	assert.Contains(t, string(data), "- /etc/vmagent/sd/*.json")
+	assert.NotContains(t, string(data), "scrapeConfig/default/bad")
+
+	var bad vmv1beta1.VMScrapeConfig
+	if err := fclient.Get(ctx, types.NamespacedName{Namespace: "default", Name: "bad"}, &bad); err != nil {
+		t.Fatalf("cannot get scrape config: %s", err)
+	}
+	if assert.Len(t, bad.Status.Conditions, 1) {
+		assert.Equal(t, metav1.ConditionFalse, bad.Status.Conditions[0].Status)
+		assert.Contains(t, bad.Status.Conditions[0].Message, "dnsSDConfigs[0]: port must be set for type=A")
+	}
+	DeregisterScrapeMetrics(cr)
+}
@@ -25,10 +25,14 @@ func generateScrapeConfig(
 	}
 
 	setScrapeIntervalToWithLimit(ctx, &sc.Spec.EndpointScrapeParams, vmagentCR)
+	setScrapeLimitsTo(&sc.Spec.EndpointScrapeParams, vmagentCR, sc.GetStatusMetadata(), jobName)
 
-	cfg = addCommonScrapeParamsTo(cfg, sc.Spec.EndpointScrapeParams, se)
+	cfg = addCommonScrapeParamsTo(cfg, sc.Spec.EndpointScrapeParams, se, vmagentCR)
 
 	var relabelings []yaml.MapSlice
+	if jobRelabeling := generateJobLabelRelabeling(vmagentCR, "scrapeConfig", sc.Namespace, sc.Name, 0); jobRelabeling != nil {
+		relabelings = append(relabelings, jobRelabeling)
+	}
 	for _, c := range sc.Spec.RelabelConfigs {
 		relabelings = append(relabelings, generateRelabelConfig(c))
 	}
@@ -40,8 +44,9 @@ func generateScrapeConfig(
 	relabelings = enforceNamespaceLabel(relabelings, sc.Namespace, se.EnforcedNamespaceLabel)
 
 	cfg = append(cfg, yaml.MapItem{Key: "relabel_configs", Value: relabelings})
-	cfg = addMetricRelabelingsTo(cfg, sc.Spec.MetricRelabelConfigs, se)
+	cfg = addMetricRelabelingsTo(cfg, withGlobalMetricRelabelConfigs(vmagentCR, sc, sc.Spec.MetricRelabelConfigs), se)
 	cfg = append(cfg, buildVMScrapeParams(sc.Namespace, sc.AsProxyKey("", 0), sc.Spec.VMScrapeParams, ssCache)...)
+	cfg = addEndpointHeadersTo(cfg, sc.AsMapKey("", 0), ssCache.headers)
 	cfg = addTLStoYaml(cfg, sc.Namespace, sc.Spec.TLSConfig, false)
 	cfg = addEndpointAuthTo(cfg, sc.Spec.EndpointAuth, sc.AsMapKey("", 0), ssCache)
 
@@ -0,0 +1,167 @@
+package vmagent
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
+)
+
+var scrapeObjectSelectedByStatus bool
+
+// SetScrapeObjectSelectedByStatus enables selectedBy status of scrape objects.
+// It's disabled by default, since it adds status updates for each VMAgent selecting the scrape object
+func SetScrapeObjectSelectedByStatus(v bool) {
+	scrapeObjectSelectedByStatus = v
+}
+
+type scrapeObjectWithSelectedBy interface {
+	client.Object
+	GetScrapeObjectStatus() *vmv1beta1.ScrapeObjectStatus
+}
+
+// setSelectedBy returns a copy of src with entry of the given VMAgent replaced by the given entry.
+// Entry of the VMAgent is removed if the given entry is nil
+func setSelectedBy(src []vmv1beta1.ScrapeObjectSelectedBy, vmagentKey string, entry *vmv1beta1.ScrapeObjectSelectedBy) []vmv1beta1.ScrapeObjectSelectedBy {
+	var dst []vmv1beta1.ScrapeObjectSelectedBy
+	for _, sb := range src {
+		if sb.VMAgent != vmagentKey {
+			dst = append(dst, sb)
+		}
+	}
+	if entry != nil {
+		dst = append(dst, *entry)
+	}
+	slices.SortFunc(dst, func(a, b vmv1beta1.ScrapeObjectSelectedBy) int {
+		return strings.Compare(a.VMAgent, b.VMAgent)
+	})
+	return dst
+}
+
+// updateSelectedBy updates selectedBy status of the scrape object if it has changes
+func updateSelectedBy[T any, PT interface {
+	*T
+	scrapeObjectWithSelectedBy
+}](ctx context.Context, rclient client.Client, o PT, vmagentKey string, entry *vmv1beta1.ScrapeObjectSelectedBy) error {
+	st := o.GetScrapeObjectStatus()
+	if equality.Semantic.DeepEqual(st.SelectedBy, setSelectedBy(st.SelectedBy, vmagentKey, entry)) {
+		// fast path
+		return nil
+	}
+	nsn := client.ObjectKeyFromObject(o)
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		dst := PT(new(T))
+		if err := rclient.Get(ctx, nsn, dst); err != nil {
+			return err
+		}
+		st := dst.GetScrapeObjectStatus()
+		selectedBy := setSelectedBy(st.SelectedBy, vmagentKey, entry)
+		if equality.Semantic.DeepEqual(st.SelectedBy, selectedBy) {
+			return nil
+		}
+		st.SelectedBy = selectedBy
+		return rclient.Status().Update(ctx, dst)
+	})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return fmt.Errorf("cannot update selectedBy status of %s/%s: %w", nsn.Namespace, nsn.Name, err)
+	}
+	return nil
+}
+
+// updateSelectedByForKind sets selectedBy entry of the VMAgent for the selected scrape objects
+// and removes it from the existing objects, which are no longer selected
+func updateSelectedByForKind[T any, PT interface {
+	*T
+	scrapeObjectWithSelectedBy
+}](ctx context.Context, rclient client.Client, vmagentKey, kind string, selected []PT, existing []T, jobsByObject map[string][]string) error {
+	selectedKeys := make(map[string]struct{}, len(selected))
+	for _, o := range selected {
+		key := fmt.Sprintf("%s/%s/%s", kind, o.GetNamespace(), o.GetName())
+		selectedKeys[key] = struct{}{}
+		entry := &vmv1beta1.ScrapeObjectSelectedBy{
+			VMAgent:            vmagentKey,
+			Jobs:               jobsByObject[key],
+			ObservedGeneration: o.GetGeneration(),
+		}
+		if err := updateSelectedBy[T](ctx, rclient, o, vmagentKey, entry); err != nil {
+			return err
+		}
+	}
+	for i := range existing {
+		o := PT(&existing[i])
+		if _, ok := selectedKeys[fmt.Sprintf("%s/%s/%s", kind, o.GetNamespace(), o.GetName())]; ok {
+			continue
+		}
+		if err := updateSelectedBy[T](ctx, rclient, o, vmagentKey, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateScrapeObjectsSelectedBy records the given VMAgent at selectedBy status of selected scrape objects
+// and prunes it from scrape objects, which are no longer selected by the VMAgent
+func updateScrapeObjectsSelectedBy(ctx context.Context, rclient client.Client, cr *vmv1beta1.VMAgent, sos *scrapeObjects, cfg yaml.MapSlice) error {
+	if !scrapeObjectSelectedByStatus {
+		return nil
+	}
+	vmagentKey := fmt.Sprintf("%s/%s", cr.Namespace, cr.Name)
+	jobsByObject := scrapeJobsByObject(cfg)
+
+	var sssList vmv1beta1.VMServiceScrapeList
+	if err := rclient.List(ctx, &sssList); err != nil {
+		return fmt.Errorf("cannot list VMServiceScrapes: %w", err)
+	}
+	if err := updateSelectedByForKind(ctx, rclient, vmagentKey, "serviceScrape", slices.Concat(sos.sss, sos.sssBroken), sssList.Items, jobsByObject); err != nil {
+		return err
+	}
+	var pssList vmv1beta1.VMPodScrapeList
+	if err := rclient.List(ctx, &pssList); err != nil {
+		return fmt.Errorf("cannot list VMPodScrapes: %w", err)
+	}
+	if err := updateSelectedByForKind(ctx, rclient, vmagentKey, "podScrape", slices.Concat(sos.pss, sos.pssBroken), pssList.Items, jobsByObject); err != nil {
+		return err
+	}
+	var prssList vmv1beta1.VMProbeList
+	if err := rclient.List(ctx, &prssList); err != nil {
+		return fmt.Errorf("cannot list VMProbes: %w", err)
+	}
+	if err := updateSelectedByForKind(ctx, rclient, vmagentKey, "probe", slices.Concat(sos.prss, sos.prssBroken), prssList.Items, jobsByObject); err != nil {
+		return err
+	}
+	var nssList vmv1beta1.VMNodeScrapeList
+	if err := rclient.List(ctx, &nssList); err != nil {
+		return fmt.Errorf("cannot list VMNodeScrapes: %w", err)
+	}
+	if err := updateSelectedByForKind(ctx, rclient, vmagentKey, "nodeScrape", slices.Concat(sos.nss, sos.nssBroken), nssList.Items, jobsByObject); err != nil {
+		return err
+	}
+	var stssList vmv1beta1.VMStaticScrapeList
+	if err := rclient.List(ctx, &stssList); err != nil {
+		return fmt.Errorf("cannot list VMStaticScrapes: %w", err)
+	}
+	if err := updateSelectedByForKind(ctx, rclient, vmagentKey, "staticScrape", slices.Concat(sos.stss, sos.stssBroken), stssList.Items, jobsByObject); err != nil {
+		return err
+	}
+	var scssList vmv1beta1.VMScrapeConfigList
+	if err := rclient.List(ctx, &scssList); err != nil {
+		return fmt.Errorf("cannot list VMScrapeConfigs: %w", err)
+	}
+	if err := updateSelectedByForKind(ctx, rclient, vmagentKey, "scrapeConfig", slices.Concat(sos.scss, sos.scssBroken), scssList.Items, jobsByObject); err != nil {
+		return err
+	}
+	return nil
+}
+
+// RemoveScrapeObjectsSelectedBy removes the given VMAgent from selectedBy status of all scrape objects
+func RemoveScrapeObjectsSelectedBy(ctx context.Context, rclient client.Client, cr *vmv1beta1.VMAgent) error {
+	return updateScrapeObjectsSelectedBy(ctx, rclient, cr, &scrapeObjects{}, nil)
+}
@@ -0,0 +1,86 @@
+package vmagent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+
+	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/k8stools"
+)
+
+func TestUpdateScrapeObjectsSelectedBy(t *testing.T) {
+	SetScrapeObjectSelectedByStatus(true)
+	defer SetScrapeObjectSelectedByStatus(false)
+
+	cr := &vmv1beta1.VMAgent{ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "monitoring"}}
+	otherAgent := vmv1beta1.ScrapeObjectSelectedBy{VMAgent: "monitoring/other", Jobs: []string{"serviceScrape/default/selected/0"}}
+	selected := &vmv1beta1.VMServiceScrape{
+		ObjectMeta: metav1.ObjectMeta{Name: "selected", Namespace: "default", Generation: 2},
+		Status: vmv1beta1.ScrapeObjectStatus{
+			SelectedBy: []vmv1beta1.ScrapeObjectSelectedBy{otherAgent},
+		},
+	}
+	stale := &vmv1beta1.VMServiceScrape{
+		ObjectMeta: metav1.ObjectMeta{Name: "stale", Namespace: "default"},
+		Status: vmv1beta1.ScrapeObjectStatus{
+			SelectedBy: []vmv1beta1.ScrapeObjectSelectedBy{{VMAgent: "monitoring/agent"}, otherAgent},
+		},
+	}
+	broken := &vmv1beta1.VMPodScrape{
+		ObjectMeta: metav1.ObjectMeta{Name: "broken", Namespace: "default", Generation: 1},
+	}
+	fclient := k8stools.GetTestClientWithObjects([]runtime.Object{selected, stale, broken})
+	sos := &scrapeObjects{
+		sss:       []*vmv1beta1.VMServiceScrape{selected},
+		pssBroken: []*vmv1beta1.VMPodScrape{broken},
+	}
+	cfg := yaml.MapSlice{
+		{Key: "scrape_configs", Value: []yaml.MapSlice{
+			{{Key: "job_name", Value: "serviceScrape/default/selected/0"}},
+			{{Key: "job_name", Value: "serviceScrape/default/selected/1"}},
+		}},
+	}
+	ctx := context.TODO()
+	if err := updateScrapeObjectsSelectedBy(ctx, fclient, cr, sos, cfg); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var gotSss vmv1beta1.VMServiceScrape
+	if err := fclient.Get(ctx, types.NamespacedName{Namespace: "default", Name: "selected"}, &gotSss); err != nil {
+		t.Fatalf("cannot get VMServiceScrape: %s", err)
+	}
+	assert.Equal(t, []vmv1beta1.ScrapeObjectSelectedBy{
+		{VMAgent: "monitoring/agent", Jobs: []string{"serviceScrape/default/selected/0", "serviceScrape/default/selected/1"}, ObservedGeneration: 2},
+		otherAgent,
+	}, gotSss.Status.SelectedBy)
+
+	if err := fclient.Get(ctx, types.NamespacedName{Namespace: "default", Name: "stale"}, &gotSss); err != nil {
+		t.Fatalf("cannot get VMServiceScrape: %s", err)
+	}
+	assert.Equal(t, []vmv1beta1.ScrapeObjectSelectedBy{otherAgent}, gotSss.Status.SelectedBy)
+
+	var gotPss vmv1beta1.VMPodScrape
+	if err := fclient.Get(ctx, types.NamespacedName{Namespace: "default", Name: "broken"}, &gotPss); err != nil {
+		t.Fatalf("cannot get VMPodScrape: %s", err)
+	}
+	assert.Equal(t, []vmv1beta1.ScrapeObjectSelectedBy{{VMAgent: "monitoring/agent", ObservedGeneration: 1}}, gotPss.Status.SelectedBy)
+
+	// deleted vmagent
+	if err := RemoveScrapeObjectsSelectedBy(ctx, fclient, cr); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := fclient.Get(ctx, types.NamespacedName{Namespace: "default", Name: "selected"}, &gotSss); err != nil {
+		t.Fatalf("cannot get VMServiceScrape: %s", err)
+	}
+	assert.Equal(t, []vmv1beta1.ScrapeObjectSelectedBy{otherAgent}, gotSss.Status.SelectedBy)
+	if err := fclient.Get(ctx, types.NamespacedName{Namespace: "default", Name: "broken"}, &gotPss); err != nil {
+		t.Fatalf("cannot get VMPodScrape: %s", err)
+	}
+	assert.Empty(t, gotPss.Status.SelectedBy)
+}
@@ -3,6 +3,7 @@ package vmagent
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
 	"gopkg.in/yaml.v2"
@@ -18,15 +19,19 @@ func generateServiceScrapeConfig(
 	ssCache *scrapesSecretsCache,
 	se vmv1beta1.VMAgentSecurityEnforcements,
 ) yaml.MapSlice {
+	jobName := fmt.Sprintf("serviceScrape/%s/%s/%d", m.Namespace, m.Name, i)
 	cfg := yaml.MapSlice{
 		{
 			Key:   "job_name",
-			Value: fmt.Sprintf("serviceScrape/%s/%s/%d", m.Namespace, m.Name, i),
+			Value: jobName,
 		},
 	}
 	// service role.
 	if m.Spec.DiscoveryRole == "" {
 		m.Spec.DiscoveryRole = kubernetesSDRoleEndpoint
+		if vmagentCR.Spec.UseEndpointSlices {
+			m.Spec.DiscoveryRole = kubernetesSDRoleEndpointSlices
+		}
 	}
 
 	selectedNamespaces := getNamespacesFromNamespaceSelector(&m.Spec.NamespaceSelector, m.Namespace, se.IgnoreNamespaceSelectors)
@@ -43,8 +48,9 @@ func generateServiceScrapeConfig(
 	}
 
 	setScrapeIntervalToWithLimit(ctx, &ep.EndpointScrapeParams, vmagentCR)
+	setScrapeLimitsTo(&ep.EndpointScrapeParams, vmagentCR, m.GetStatusMetadata(), jobName)
 
-	cfg = addCommonScrapeParamsTo(cfg, ep.EndpointScrapeParams, se)
+	cfg = addCommonScrapeParamsTo(cfg, ep.EndpointScrapeParams, se, vmagentCR)
 
 	var relabelings []yaml.MapSlice
 
@@ -52,6 +58,10 @@ func generateServiceScrapeConfig(
 
 	// Exact label matches.
 	relabelings = addSelectorToRelabelingFor(relabelings, "service", m.Spec.Selector)
+	// service role targets have no pod metadata and cannot be filtered by node
+	if m.Spec.DiscoveryRole != kubernetesSDRoleService {
+		relabelings = addNodeFilterToRelabelingFor(relabelings, vmagentCR, "__meta_kubernetes_pod_node_name")
+	}
 
 	// Filter targets based on correct port for the endpoint.
 	if ep.Port != "" {
@@ -188,11 +198,15 @@ func generateServiceScrapeConfig(
 	// endpoints, therefore the endpoints labels is filled with the ports name or
 	// as a fallback the port number.
 
-	relabelings = append(relabelings, yaml.MapSlice{
-		{Key: "source_labels", Value: []string{"__meta_kubernetes_service_name"}},
-		{Key: "target_label", Value: "job"},
-		{Key: "replacement", Value: "${1}"},
-	})
+	if vmagentCR.Spec.LegacyJobNames {
+		relabelings = append(relabelings, yaml.MapSlice{
+			{Key: "source_labels", Value: []string{"__meta_kubernetes_service_name"}},
+			{Key: "target_label", Value: "job"},
+			{Key: "replacement", Value: "${1}"},
+		})
+	} else if jobRelabeling := generateJobLabelRelabeling(vmagentCR, "serviceScrape", m.Namespace, m.Name, i); jobRelabeling != nil {
+		relabelings = append(relabelings, jobRelabeling)
+	}
 	if m.Spec.JobLabel != "" {
 		relabelings = append(relabelings, yaml.MapSlice{
 			{Key: "source_labels", Value: []string{"__meta_kubernetes_service_label_" + sanitizeLabelName(m.Spec.JobLabel)}},
@@ -214,12 +228,15 @@ func generateServiceScrapeConfig(
 		})
 	}
 
+	for _, c := range ssCache.relabelConfigs[relabelConfigsCacheKey("serviceScrape", m)] {
+		relabelings = append(relabelings, generateRelabelConfig(toDiscoveryRoleRelabelConfig(c, m.Spec.DiscoveryRole)))
+	}
 	for _, c := range ep.RelabelConfigs {
-		relabelings = append(relabelings, generateRelabelConfig(c))
+		relabelings = append(relabelings, generateRelabelConfig(toDiscoveryRoleRelabelConfig(c, m.Spec.DiscoveryRole)))
 	}
 
 	for _, trc := range vmagentCR.Spec.ServiceScrapeRelabelTemplate {
-		relabelings = append(relabelings, generateRelabelConfig(trc))
+		relabelings = append(relabelings, generateRelabelConfig(toDiscoveryRoleRelabelConfig(trc, m.Spec.DiscoveryRole)))
 	}
 
 	// Because of security risks, whenever enforcedNamespaceLabel is set, we want to append it to the
@@ -227,10 +244,68 @@ func generateServiceScrapeConfig(
 	relabelings = enforceNamespaceLabel(relabelings, m.Namespace, se.EnforcedNamespaceLabel)
 
 	cfg = append(cfg, yaml.MapItem{Key: "relabel_configs", Value: relabelings})
-	cfg = addMetricRelabelingsTo(cfg, ep.MetricRelabelConfigs, se)
+	cfg = addMetricRelabelingsTo(cfg, withGlobalMetricRelabelConfigs(vmagentCR, m, ep.MetricRelabelConfigs), se)
 	cfg = append(cfg, buildVMScrapeParams(m.Namespace, m.AsProxyKey(i), ep.VMScrapeParams, ssCache)...)
+	cfg = addEndpointHeadersTo(cfg, m.AsMapKey(i), ssCache.headers)
 	cfg = addTLStoYaml(cfg, m.Namespace, ep.TLSConfig, false)
 	cfg = addEndpointAuthTo(cfg, ep.EndpointAuth, m.AsMapKey(i), ssCache)
 
 	return cfg
 }
+
+// endpointToEndpointSliceLabels maps endpoints role meta labels to the endpointslices role equivalents
+// endpointslices role has no endpoint node name label, pod node name is used instead,
+// it's set for endpoints backed by pods
+var endpointToEndpointSliceLabels = map[string]string{
+	"__meta_kubernetes_endpoints_name":               "__meta_kubernetes_endpointslice_name",
+	"__meta_kubernetes_endpoint_hostname":            "__meta_kubernetes_endpointslice_endpoint_hostname",
+	"__meta_kubernetes_endpoint_node_name":           "__meta_kubernetes_pod_node_name",
+	"__meta_kubernetes_endpoint_ready":               "__meta_kubernetes_endpointslice_endpoint_conditions_ready",
+	"__meta_kubernetes_endpoint_port_name":           "__meta_kubernetes_endpointslice_port_name",
+	"__meta_kubernetes_endpoint_port_protocol":       "__meta_kubernetes_endpointslice_port_protocol",
+	"__meta_kubernetes_endpoint_address_target_kind": "__meta_kubernetes_endpointslice_address_target_kind",
+	"__meta_kubernetes_endpoint_address_target_name": "__meta_kubernetes_endpointslice_address_target_name",
+}
+
+// endpointToEndpointSlicePrefixes maps endpoints object labels and annotations prefixes to the endpointslices role equivalents
+var endpointToEndpointSlicePrefixes = [][2]string{
+	{"__meta_kubernetes_endpoints_labelpresent_", "__meta_kubernetes_endpointslice_labelpresent_"},
+	{"__meta_kubernetes_endpoints_label_", "__meta_kubernetes_endpointslice_label_"},
+	{"__meta_kubernetes_endpoints_annotationpresent_", "__meta_kubernetes_endpointslice_annotationpresent_"},
+	{"__meta_kubernetes_endpoints_annotation_", "__meta_kubernetes_endpointslice_annotation_"},
+}
+
+// toDiscoveryRoleRelabelConfig translates endpoints role meta labels at relabel config source labels
+// into endpointslices role labels. It allows to switch discovery role without changes at relabel configs
+func toDiscoveryRoleRelabelConfig(rc *vmv1beta1.RelabelConfig, role string) *vmv1beta1.RelabelConfig {
+	if role != kubernetesSDRoleEndpointSlices || len(rc.SourceLabels) == 0 {
+		return rc
+	}
+	var rcCopy *vmv1beta1.RelabelConfig
+	for i, label := range rc.SourceLabels {
+		newLabel := toEndpointSliceLabel(label)
+		if newLabel == label {
+			continue
+		}
+		if rcCopy == nil {
+			rcCopy = rc.DeepCopy()
+		}
+		rcCopy.SourceLabels[i] = newLabel
+	}
+	if rcCopy == nil {
+		return rc
+	}
+	return rcCopy
+}
+
+func toEndpointSliceLabel(label string) string {
+	if newLabel, ok := endpointToEndpointSliceLabels[label]; ok {
+		return newLabel
+	}
+	for _, prefixes := range endpointToEndpointSlicePrefixes {
+		if name, ok := strings.CutPrefix(label, prefixes[0]); ok {
+			return prefixes[1] + name
+		}
+	}
+	return label
+}
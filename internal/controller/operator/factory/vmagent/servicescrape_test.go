@@ -128,10 +128,6 @@ relabel_configs:
 - source_labels:
   - __meta_kubernetes_service_name
   target_label: service
-- source_labels:
-  - __meta_kubernetes_service_name
-  target_label: job
-  replacement: ${1}
 - target_label: endpoint
   replacement: "8080"
 tls_config:
@@ -238,10 +234,6 @@ relabel_configs:
 - source_labels:
   - __meta_kubernetes_service_name
   target_label: service
-- source_labels:
-  - __meta_kubernetes_service_name
-  target_label: job
-  replacement: ${1}
 - target_label: endpoint
   replacement: "8080"
 tls_config:
@@ -348,10 +340,6 @@ relabel_configs:
 - source_labels:
   - __meta_kubernetes_service_name
   target_label: service
-- source_labels:
-  - __meta_kubernetes_service_name
-  target_label: job
-  replacement: ${1}
 - target_label: endpoint
   replacement: "8080"
 tls_config:
@@ -449,10 +437,6 @@ relabel_configs:
 - source_labels:
   - __meta_kubernetes_service_name
   target_label: service
-- source_labels:
-  - __meta_kubernetes_service_name
-  target_label: job
-  replacement: ${1}
 - target_label: endpoint
   replacement: "8080"
 tls_config:
@@ -533,10 +517,6 @@ relabel_configs:
 - source_labels:
   - __meta_kubernetes_service_name
   target_label: service
-- source_labels:
-  - __meta_kubernetes_service_name
-  target_label: job
-  replacement: ${1}
 - target_label: endpoint
   replacement: "8080"
 tls_config:
@@ -589,10 +569,6 @@ relabel_configs:
 - source_labels:
   - __meta_kubernetes_service_name
   target_label: service
-- source_labels:
-  - __meta_kubernetes_service_name
-  target_label: job
-  replacement: ${1}
 `,
 		},
 		{
@@ -648,10 +624,6 @@ relabel_configs:
 - source_labels:
   - __meta_kubernetes_service_name
   target_label: service
-- source_labels:
-  - __meta_kubernetes_service_name
-  target_label: job
-  replacement: ${1}
 - target_label: endpoint
   replacement: "8080"
 tls_config:
@@ -785,10 +757,6 @@ relabel_configs:
 - source_labels:
   - __meta_kubernetes_service_name
   target_label: service
-- source_labels:
-  - __meta_kubernetes_service_name
-  target_label: job
-  replacement: ${1}
 - target_label: endpoint
   replacement: "8080"
 stream_parse: true
@@ -910,10 +878,6 @@ relabel_configs:
 - source_labels:
   - __meta_kubernetes_service_name
   target_label: service
-- source_labels:
-  - __meta_kubernetes_service_name
-  target_label: job
-  replacement: ${1}
 - target_label: endpoint
   replacement: "8080"
 - source_labels:
@@ -924,6 +888,236 @@ tls_config:
   insecure_skip_verify: false
   ca_file: /etc/vmagent-tls/certs/default_tls-secret_ca
 bearer_token_file: /var/run/tolen
+`,
+		},
+		{
+			name: "config with global endpointslices discovery",
+			args: args{
+				cr: vmv1beta1.VMAgent{
+					Spec: vmv1beta1.VMAgentSpec{
+						UseEndpointSlices: true,
+						ServiceScrapeRelabelTemplate: []*vmv1beta1.RelabelConfig{
+							{
+								Action:       "drop",
+								SourceLabels: []string{"__meta_kubernetes_endpoint_ready"},
+								Regex:        vmv1beta1.StringOrArray{"false"},
+							},
+						},
+					},
+				},
+				m: &vmv1beta1.VMServiceScrape{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-scrape",
+						Namespace: "default",
+					},
+				},
+				ep: vmv1beta1.Endpoint{
+					Port: "web",
+					EndpointRelabelings: vmv1beta1.EndpointRelabelings{
+						RelabelConfigs: []*vmv1beta1.RelabelConfig{
+							{
+								SourceLabels: []string{"__meta_kubernetes_endpoint_node_name"},
+								TargetLabel:  "node_name",
+							},
+							{
+								SourceLabels: []string{"__meta_kubernetes_endpoint_port_name", "__meta_kubernetes_endpoints_label_app"},
+								TargetLabel:  "port_app",
+							},
+						},
+					},
+				},
+				i:               0,
+				apiserverConfig: nil,
+				ssCache:         &scrapesSecretsCache{},
+			},
+			want: `job_name: serviceScrape/default/test-scrape/0
+kubernetes_sd_configs:
+- role: endpointslices
+  namespaces:
+    names:
+    - default
+honor_labels: false
+relabel_configs:
+- action: keep
+  source_labels:
+  - __meta_kubernetes_endpointslice_port_name
+  regex: web
+- source_labels:
+  - __meta_kubernetes_endpointslice_address_target_kind
+  - __meta_kubernetes_endpointslice_address_target_name
+  separator: ;
+  regex: Node;(.*)
+  replacement: ${1}
+  target_label: node
+- source_labels:
+  - __meta_kubernetes_endpointslice_address_target_kind
+  - __meta_kubernetes_endpointslice_address_target_name
+  separator: ;
+  regex: Pod;(.*)
+  replacement: ${1}
+  target_label: pod
+- source_labels:
+  - __meta_kubernetes_pod_name
+  target_label: pod
+- source_labels:
+  - __meta_kubernetes_pod_container_name
+  target_label: container
+- source_labels:
+  - __meta_kubernetes_namespace
+  target_label: namespace
+- source_labels:
+  - __meta_kubernetes_service_name
+  target_label: service
+- target_label: endpoint
+  replacement: web
+- source_labels:
+  - __meta_kubernetes_pod_node_name
+  target_label: node_name
+- source_labels:
+  - __meta_kubernetes_endpointslice_port_name
+  - __meta_kubernetes_endpointslice_label_app
+  target_label: port_app
+- source_labels:
+  - __meta_kubernetes_endpointslice_endpoint_conditions_ready
+  regex: "false"
+  action: drop
+`,
+		},
+		{
+			name: "legacy job names",
+			args: args{
+				cr: vmv1beta1.VMAgent{
+					Spec: vmv1beta1.VMAgentSpec{
+						LegacyJobNames: true,
+					},
+				},
+				m: &vmv1beta1.VMServiceScrape{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-scrape",
+						Namespace: "default",
+					},
+				},
+				ep: vmv1beta1.Endpoint{
+					Port: "web",
+				},
+				ssCache: &scrapesSecretsCache{},
+			},
+			want: `job_name: serviceScrape/default/test-scrape/0
+kubernetes_sd_configs:
+- role: endpoints
+  namespaces:
+    names:
+    - default
+honor_labels: false
+relabel_configs:
+- action: keep
+  source_labels:
+  - __meta_kubernetes_endpoint_port_name
+  regex: web
+- source_labels:
+  - __meta_kubernetes_endpoint_address_target_kind
+  - __meta_kubernetes_endpoint_address_target_name
+  separator: ;
+  regex: Node;(.*)
+  replacement: ${1}
+  target_label: node
+- source_labels:
+  - __meta_kubernetes_endpoint_address_target_kind
+  - __meta_kubernetes_endpoint_address_target_name
+  separator: ;
+  regex: Pod;(.*)
+  replacement: ${1}
+  target_label: pod
+- source_labels:
+  - __meta_kubernetes_pod_name
+  target_label: pod
+- source_labels:
+  - __meta_kubernetes_pod_container_name
+  target_label: container
+- source_labels:
+  - __meta_kubernetes_namespace
+  target_label: namespace
+- source_labels:
+  - __meta_kubernetes_service_name
+  target_label: service
+- source_labels:
+  - __meta_kubernetes_service_name
+  target_label: job
+  replacement: ${1}
+- target_label: endpoint
+  replacement: web
+`,
+		},
+		{
+			name: "job label template",
+			args: args{
+				cr: vmv1beta1.VMAgent{
+					Spec: vmv1beta1.VMAgentSpec{
+						JobLabelTemplate: "{{namespace}}-{{name}}-{{endpoint}}",
+					},
+				},
+				m: &vmv1beta1.VMServiceScrape{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-scrape",
+						Namespace: "default",
+					},
+					Spec: vmv1beta1.VMServiceScrapeSpec{
+						JobLabel: "app",
+					},
+				},
+				ep: vmv1beta1.Endpoint{
+					Port: "web",
+				},
+				i:       1,
+				ssCache: &scrapesSecretsCache{},
+			},
+			want: `job_name: serviceScrape/default/test-scrape/1
+kubernetes_sd_configs:
+- role: endpoints
+  namespaces:
+    names:
+    - default
+honor_labels: false
+relabel_configs:
+- action: keep
+  source_labels:
+  - __meta_kubernetes_endpoint_port_name
+  regex: web
+- source_labels:
+  - __meta_kubernetes_endpoint_address_target_kind
+  - __meta_kubernetes_endpoint_address_target_name
+  separator: ;
+  regex: Node;(.*)
+  replacement: ${1}
+  target_label: node
+- source_labels:
+  - __meta_kubernetes_endpoint_address_target_kind
+  - __meta_kubernetes_endpoint_address_target_name
+  separator: ;
+  regex: Pod;(.*)
+  replacement: ${1}
+  target_label: pod
+- source_labels:
+  - __meta_kubernetes_pod_name
+  target_label: pod
+- source_labels:
+  - __meta_kubernetes_pod_container_name
+  target_label: container
+- source_labels:
+  - __meta_kubernetes_namespace
+  target_label: namespace
+- source_labels:
+  - __meta_kubernetes_service_name
+  target_label: service
+- target_label: job
+  replacement: default-test-scrape-1
+- source_labels:
+  - __meta_kubernetes_service_label_app
+  target_label: job
+  regex: (.+)
+  replacement: ${1}
+- target_label: endpoint
+  replacement: web
 `,
 		},
 	}
@@ -0,0 +1,192 @@
+package vmagent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/k8stools"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/logger"
+)
+
+const vmAgentTargetsMetric = "vm_promscrape_targets"
+
+const shardTargetsRequestTimeout = 5 * time.Second
+
+var (
+	shardTargetsCheckInterval = 5 * time.Second
+	shardTargetsCheckTimeout  = 5 * time.Minute
+)
+
+// newShardTransition returns state of shards count change or nil if shards count wasn't changed.
+// Transition in progress is continued from the persisted status,
+// if shards count was changed during transition, it's started from the previous desired count.
+// It allows to abort transition by reverting spec.
+func newShardTransition(cr, prevCR *vmv1beta1.VMAgent) *vmv1beta1.VMAgentShardTransition {
+	to := int32(ptr.Deref(cr.Spec.ShardCount, 0))
+	var from int32
+	var completed int32
+	switch {
+	case cr.Status.ShardTransition != nil:
+		from = cr.Status.ShardTransition.To
+		if from == to {
+			from = cr.Status.ShardTransition.From
+			completed = cr.Status.ShardTransition.CompletedShards
+		}
+	case prevCR != nil:
+		from = int32(ptr.Deref(prevCR.Spec.ShardCount, 0))
+	}
+	// transition from non-sharded mode changes names of deployments
+	// and cannot be performed without scrape gap
+	if from <= 1 || to <= 1 || from == to {
+		return nil
+	}
+	return &vmv1beta1.VMAgentShardTransition{From: from, To: to, CompletedShards: completed}
+}
+
+// ShardTransitionRequeueAfter returns interval for the next check of shards transition
+// or 0 if there is no transition in progress
+func ShardTransitionRequeueAfter(cr *vmv1beta1.VMAgent) time.Duration {
+	if cr.Status.ShardTransition == nil {
+		return 0
+	}
+	return shardTargetsCheckInterval
+}
+
+// updateShardTransitionStatus persists shards transition progress
+// it allows to continue transition from the last completed shard on the next reconcile
+func updateShardTransitionStatus(ctx context.Context, rclient client.Client, cr *vmv1beta1.VMAgent) error {
+	patch := map[string]any{
+		"status": map[string]any{
+			"shardTransition": cr.Status.ShardTransition,
+		},
+	}
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return fmt.Errorf("cannot marshal shard transition status patch: %w", err)
+	}
+	// make a deep copy, patch response must not override in-memory object
+	objToUpdate := cr.DeepCopy()
+	if err := rclient.Status().Patch(ctx, objToUpdate, client.RawPatch(types.MergePatchType, data)); err != nil {
+		return fmt.Errorf("cannot update shard transition status: %w", err)
+	}
+	cr.SetResourceVersion(objToUpdate.GetResourceVersion())
+	return nil
+}
+
+// isShardTargetsReady checks once if all pods of the given shard discovered scrape targets.
+// Pod, which cannot report targets longer than shardTargetsCheckTimeout after it became ready, is considered as ready.
+// It prevents transition from being stuck forever.
+func isShardTargetsReady(ctx context.Context, rclient client.Client, cr *vmv1beta1.VMAgent, shardNum int) (bool, error) {
+	if cr.Spec.IngestOnlyMode || ptr.Deref(cr.Spec.ReplicaCount, 1) == 0 {
+		return true, nil
+	}
+	selector := cr.SelectorLabels()
+	selector["shard-num"] = strconv.Itoa(shardNum)
+	var pods corev1.PodList
+	if err := rclient.List(ctx, &pods, &client.ListOptions{Namespace: cr.Namespace, LabelSelector: labels.SelectorFromSet(selector)}); err != nil {
+		return false, fmt.Errorf("cannot list pods of shard=%d: %w", shardNum, err)
+	}
+	hc, err := newVMAgentHTTPClient(ctx, rclient, cr)
+	if err != nil {
+		return false, fmt.Errorf("cannot build http client for vmagent shard=%d: %w", shardNum, err)
+	}
+	var checked int
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if !pod.DeletionTimestamp.IsZero() {
+			continue
+		}
+		checked++
+		if err := checkPodTargets(ctx, hc, cr, pod); err != nil {
+			if podReadyFor(pod) > shardTargetsCheckTimeout {
+				logger.WithContext(ctx).Error(err, fmt.Sprintf("cannot check scrape targets of vmagent shard=%d, considering it as ready after timeout=%s", shardNum, shardTargetsCheckTimeout))
+				continue
+			}
+			logger.WithContext(ctx).Info(fmt.Sprintf("vmagent shard=%d didn't discover scrape targets yet: %s", shardNum, err))
+			return false, nil
+		}
+	}
+	if checked == 0 {
+		logger.WithContext(ctx).Info(fmt.Sprintf("vmagent shard=%d doesn't have any pods yet", shardNum))
+		return false, nil
+	}
+	return true, nil
+}
+
+// podReadyFor returns duration since pod became ready
+func podReadyFor(pod *corev1.Pod) time.Duration {
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodReady && cond.Status == corev1.ConditionTrue {
+			return time.Since(cond.LastTransitionTime.Time)
+		}
+	}
+	return 0
+}
+
+// newVMAgentHTTPClient returns client for requests to vmagent pods
+// it uses tlsConfig of vmagent self-scrape endpoint defined at serviceScrapeSpec
+func newVMAgentHTTPClient(ctx context.Context, rclient client.Client, cr *vmv1beta1.VMAgent) (*http.Client, error) {
+	var tc *vmv1beta1.TLSConfig
+	if cr.Spec.ServiceScrapeSpec != nil {
+		for _, ep := range cr.Spec.ServiceScrapeSpec.Endpoints {
+			if ep.Port == "http" && ep.TLSConfig != nil {
+				tc = ep.TLSConfig
+				break
+			}
+		}
+	}
+	return k8stools.NewHTTPClient(ctx, rclient, cr.Namespace, tc, shardTargetsRequestTimeout)
+}
+
+// checkPodTargets checks that vmagent pod reports scrape targets metric
+// zero targets are valid, since shard may have nothing to scrape after targets redistribution
+func checkPodTargets(ctx context.Context, hc *http.Client, cr *vmv1beta1.VMAgent, pod *corev1.Pod) error {
+	if pod.Status.PodIP == "" {
+		return fmt.Errorf("pod=%s doesn't have IP address yet", pod.Name)
+	}
+	u := cr.PodURL(pod.Status.PodIP, cr.GetMetricPath())
+	if authKey := cr.Spec.ExtraArgs["metricsAuthKey"]; authKey != "" {
+		u += "?" + url.Values{"authKey": []string{authKey}}.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("cannot read metrics of pod=%s: %w", pod.Name, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code=%d for metrics of pod=%s", resp.StatusCode, pod.Name)
+	}
+	for _, line := range strings.Split(string(body), "\n") {
+		if !strings.HasPrefix(line, vmAgentTargetsMetric+"{") && !strings.HasPrefix(line, vmAgentTargetsMetric+" ") {
+			continue
+		}
+		idx := strings.LastIndexByte(line, ' ')
+		if _, err := strconv.ParseFloat(line[idx+1:], 64); err != nil {
+			return fmt.Errorf("cannot parse %s metric value of pod=%s: %w", vmAgentTargetsMetric, pod.Name, err)
+		}
+		return nil
+	}
+	return fmt.Errorf("pod=%s doesn't report %s metric yet", pod.Name, vmAgentTargetsMetric)
+}
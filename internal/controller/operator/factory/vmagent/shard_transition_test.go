@@ -0,0 +1,145 @@
+package vmagent
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+
+	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/k8stools"
+)
+
+func Test_newShardTransition(t *testing.T) {
+	f := func(shardCount, prevShardCount *int, status, want *vmv1beta1.VMAgentShardTransition) {
+		t.Helper()
+		cr := &vmv1beta1.VMAgent{
+			Spec:   vmv1beta1.VMAgentSpec{ShardCount: shardCount},
+			Status: vmv1beta1.VMAgentStatus{ShardTransition: status},
+		}
+		var prevCR *vmv1beta1.VMAgent
+		if prevShardCount != nil {
+			prevCR = &vmv1beta1.VMAgent{Spec: vmv1beta1.VMAgentSpec{ShardCount: prevShardCount}}
+		}
+		assert.Equal(t, want, newShardTransition(cr, prevCR))
+	}
+	// no changes
+	f(ptr.To(3), ptr.To(3), nil, nil)
+	// new object
+	f(ptr.To(3), nil, nil, nil)
+	// switch from non-sharded mode
+	f(ptr.To(3), ptr.To(1), nil, nil)
+	// upscale
+	f(ptr.To(5), ptr.To(3), nil, &vmv1beta1.VMAgentShardTransition{From: 3, To: 5})
+	// downscale
+	f(ptr.To(2), ptr.To(4), nil, &vmv1beta1.VMAgentShardTransition{From: 4, To: 2})
+	// continue transition in progress
+	f(ptr.To(5), ptr.To(5), &vmv1beta1.VMAgentShardTransition{From: 3, To: 5, CompletedShards: 1}, &vmv1beta1.VMAgentShardTransition{From: 3, To: 5, CompletedShards: 1})
+	// abort transition in progress
+	f(ptr.To(3), ptr.To(5), &vmv1beta1.VMAgentShardTransition{From: 3, To: 5, CompletedShards: 1}, &vmv1beta1.VMAgentShardTransition{From: 5, To: 3})
+}
+
+func Test_shardNumIter(t *testing.T) {
+	assert.Equal(t, []int{0, 1, 2}, slices.Collect(shardNumIter(false, 3)))
+	assert.Equal(t, []int{2, 1, 0}, slices.Collect(shardNumIter(true, 3)))
+}
+
+func TestIsShardTargetsReady(t *testing.T) {
+	f := func(metrics string, readySince time.Duration, want bool) {
+		t.Helper()
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/metrics" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			fmt.Fprint(w, metrics)
+		}))
+		defer srv.Close()
+		_, port, err := net.SplitHostPort(srv.Listener.Addr().String())
+		if err != nil {
+			t.Fatalf("cannot parse test server address: %s", err)
+		}
+		cr := &vmv1beta1.VMAgent{
+			ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "default"},
+			Spec: vmv1beta1.VMAgentSpec{
+				ShardCount: ptr.To(2),
+				CommonDefaultableParams: vmv1beta1.CommonDefaultableParams{
+					Port: port,
+				},
+			},
+		}
+		podLabels := cr.SelectorLabels()
+		podLabels["shard-num"] = "1"
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "agent-pod", Namespace: "default", Labels: podLabels},
+			Status: corev1.PodStatus{
+				PodIP: "127.0.0.1",
+				Conditions: []corev1.PodCondition{
+					{
+						Type:               corev1.PodReady,
+						Status:             corev1.ConditionTrue,
+						LastTransitionTime: metav1.NewTime(time.Now().Add(-readySince)),
+					},
+				},
+			},
+		}
+		fclient := k8stools.GetTestClientWithObjects([]runtime.Object{pod})
+		got, err := isShardTargetsReady(context.Background(), fclient, cr, 1)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		assert.Equal(t, want, got)
+	}
+	// targets discovered
+	f(`vm_promscrape_targets{type="kubernetes_sd_configs", status="up"} 3
+vm_promscrape_targets{type="kubernetes_sd_configs", status="down"} 0
+`, time.Minute, true)
+	// no targets after redistribution
+	f(`vm_promscrape_targets{type="kubernetes_sd_configs", status="up"} 0
+vm_promscrape_targets_total 5
+`, time.Minute, true)
+	// metric is missing
+	f(`vm_promscrape_config_reloads_total 1
+`, time.Minute, false)
+	// metric is missing after check timeout
+	f(`vm_promscrape_config_reloads_total 1
+`, shardTargetsCheckTimeout+time.Minute, true)
+}
+
+func TestNewVMAgentHTTPClient(t *testing.T) {
+	cr := &vmv1beta1.VMAgent{
+		ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "default"},
+		Spec: vmv1beta1.VMAgentSpec{
+			ServiceScrapeSpec: &vmv1beta1.VMServiceScrapeSpec{
+				Endpoints: []vmv1beta1.Endpoint{
+					{
+						Port: "http",
+						EndpointAuth: vmv1beta1.EndpointAuth{
+							TLSConfig: &vmv1beta1.TLSConfig{
+								CA: vmv1beta1.SecretOrConfigMap{
+									Secret: &corev1.SecretKeySelector{
+										LocalObjectReference: corev1.LocalObjectReference{Name: "agent-tls"},
+										Key:                  "ca.crt",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	// tls config of self-scrape endpoint must be used
+	_, err := newVMAgentHTTPClient(context.TODO(), k8stools.GetTestClientWithObjects(nil), cr)
+	assert.Error(t, err)
+}
@@ -17,19 +17,15 @@ func generateStaticScrapeConfig(
 	ssCache *scrapesSecretsCache,
 	se vmv1beta1.VMAgentSecurityEnforcements,
 ) yaml.MapSlice {
+	jobName := fmt.Sprintf("staticScrape/%s/%s/%d", m.Namespace, m.Name, i)
 	cfg := yaml.MapSlice{
 		{
 			Key:   "job_name",
-			Value: fmt.Sprintf("staticScrape/%s/%s/%d", m.Namespace, m.Name, i),
+			Value: jobName,
 		},
 	}
 
-	tgs := yaml.MapSlice{{Key: "targets", Value: ep.Targets}}
-	if ep.Labels != nil {
-		tgs = append(tgs, yaml.MapItem{Key: "labels", Value: ep.Labels})
-	}
-
-	cfg = append(cfg, yaml.MapItem{Key: "static_configs", Value: []yaml.MapSlice{tgs}})
+	cfg = append(cfg, yaml.MapItem{Key: "static_configs", Value: buildStaticConfigs(ep)})
 
 	// set defaults
 	if ep.SampleLimit == 0 {
@@ -42,11 +38,15 @@ func generateStaticScrapeConfig(
 		ep.ScrapeTimeout = vmagentCR.Spec.ScrapeTimeout
 	}
 	setScrapeIntervalToWithLimit(ctx, &ep.EndpointScrapeParams, vmagentCR)
+	setScrapeLimitsTo(&ep.EndpointScrapeParams, vmagentCR, m.GetStatusMetadata(), jobName)
 
-	cfg = addCommonScrapeParamsTo(cfg, ep.EndpointScrapeParams, se)
+	cfg = addCommonScrapeParamsTo(cfg, ep.EndpointScrapeParams, se, vmagentCR)
 
 	var relabelings []yaml.MapSlice
 
+	if jobRelabeling := generateJobLabelRelabeling(vmagentCR, "staticScrape", m.Namespace, m.Name, i); jobRelabeling != nil {
+		relabelings = append(relabelings, jobRelabeling)
+	}
 	if m.Spec.JobName != "" {
 		relabelings = append(relabelings, yaml.MapSlice{
 			{Key: "target_label", Value: "job"},
@@ -65,10 +65,44 @@ func generateStaticScrapeConfig(
 	relabelings = enforceNamespaceLabel(relabelings, m.Namespace, se.EnforcedNamespaceLabel)
 
 	cfg = append(cfg, yaml.MapItem{Key: "relabel_configs", Value: relabelings})
-	cfg = addMetricRelabelingsTo(cfg, ep.MetricRelabelConfigs, se)
+	cfg = addMetricRelabelingsTo(cfg, withGlobalMetricRelabelConfigs(vmagentCR, m, ep.MetricRelabelConfigs), se)
 	cfg = append(cfg, buildVMScrapeParams(m.Namespace, m.AsProxyKey(i), ep.VMScrapeParams, ssCache)...)
+	cfg = addEndpointHeadersTo(cfg, m.AsMapKey(i), ssCache.headers)
 	cfg = addTLStoYaml(cfg, m.Namespace, ep.TLSConfig, false)
 	cfg = addEndpointAuthTo(cfg, ep.EndpointAuth, m.AsMapKey(i), ssCache)
 
 	return cfg
 }
+
+// buildStaticConfigs returns static_configs for plain targets and targets with labels of the endpoint.
+// Each target with labels is rendered as a separate static config with endpoint labels merged with own labels,
+// target params are added as __param_<name> labels
+func buildStaticConfigs(ep *vmv1beta1.TargetEndpoint) []yaml.MapSlice {
+	var staticConfigs []yaml.MapSlice
+	// plain targets are kept for backward compatibility, even if it's empty
+	if len(ep.Targets) > 0 || len(ep.TargetsWithLabels) == 0 {
+		tgs := yaml.MapSlice{{Key: "targets", Value: ep.Targets}}
+		if ep.Labels != nil {
+			tgs = append(tgs, yaml.MapItem{Key: "labels", Value: ep.Labels})
+		}
+		staticConfigs = append(staticConfigs, tgs)
+	}
+	for _, twl := range ep.TargetsWithLabels {
+		tgs := yaml.MapSlice{{Key: "targets", Value: []string{twl.Target}}}
+		labels := make(map[string]string, len(ep.Labels)+len(twl.Labels)+len(twl.Params))
+		for k, v := range ep.Labels {
+			labels[k] = v
+		}
+		for k, v := range twl.Labels {
+			labels[k] = v
+		}
+		for k, v := range twl.Params {
+			labels["__param_"+k] = v
+		}
+		if len(labels) > 0 {
+			tgs = append(tgs, yaml.MapItem{Key: "labels", Value: labels})
+		}
+		staticConfigs = append(staticConfigs, tgs)
+	}
+	return staticConfigs
+}
@@ -57,6 +57,78 @@ honor_labels: false
 relabel_configs:
 - target_label: job
   replacement: static-job
+`,
+		},
+		{
+			name: "targets with labels",
+			args: args{
+				ssCache: &scrapesSecretsCache{},
+				m: &vmv1beta1.VMStaticScrape{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "static-1",
+						Namespace: "default",
+					},
+				},
+				ep: &vmv1beta1.TargetEndpoint{
+					Targets: []string{"192.168.11.1:9100"},
+					Labels:  map[string]string{"env": "dev", "group": "prod"},
+					TargetsWithLabels: []vmv1beta1.TargetWithLabels{
+						{
+							Target: "db-1:9187",
+							Labels: map[string]string{"env": "prod", "role": "primary"},
+							Params: map[string]string{"module": "pg"},
+						},
+						{
+							Target: "db-2:9187",
+						},
+					},
+				},
+			},
+			want: `job_name: staticScrape/default/static-1/0
+static_configs:
+- targets:
+  - 192.168.11.1:9100
+  labels:
+    env: dev
+    group: prod
+- targets:
+  - db-1:9187
+  labels:
+    __param_module: pg
+    env: prod
+    group: prod
+    role: primary
+- targets:
+  - db-2:9187
+  labels:
+    env: dev
+    group: prod
+honor_labels: false
+relabel_configs: []
+`,
+		},
+		{
+			name: "only targets with labels",
+			args: args{
+				ssCache: &scrapesSecretsCache{},
+				m: &vmv1beta1.VMStaticScrape{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "static-1",
+						Namespace: "default",
+					},
+				},
+				ep: &vmv1beta1.TargetEndpoint{
+					TargetsWithLabels: []vmv1beta1.TargetWithLabels{
+						{Target: "host-1:9100"},
+					},
+				},
+			},
+			want: `job_name: staticScrape/default/static-1/0
+static_configs:
+- targets:
+  - host-1:9100
+honor_labels: false
+relabel_configs: []
 `,
 		},
 		{
@@ -303,6 +375,184 @@ basic_auth:
 oauth2:
   client_id: some-id
   client_secret: some-secret
+`,
+		},
+		{
+			name: "max scrape size and scrape protocols",
+			args: args{
+				ssCache: &scrapesSecretsCache{},
+				cr: vmv1beta1.VMAgent{
+					Spec: vmv1beta1.VMAgentSpec{
+						CommonDefaultableParams: vmv1beta1.CommonDefaultableParams{
+							Image: vmv1beta1.Image{Tag: "v1.117.0-enterprise"},
+						},
+					},
+				},
+				m: &vmv1beta1.VMStaticScrape{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "static-1",
+						Namespace: "default",
+					},
+				},
+				ep: &vmv1beta1.TargetEndpoint{
+					Targets: []string{"some-host:9100"},
+					EndpointScrapeParams: vmv1beta1.EndpointScrapeParams{
+						MaxScrapeSize:   "64MiB",
+						ScrapeProtocols: []string{"PrometheusProto", "PrometheusText0.0.4"},
+					},
+				},
+			},
+			want: `job_name: staticScrape/default/static-1/0
+static_configs:
+- targets:
+  - some-host:9100
+honor_labels: false
+max_scrape_size: 64MiB
+scrape_protocols:
+- PrometheusProto
+- PrometheusText0.0.4
+relabel_configs: []
+`,
+		},
+		{
+			name: "authorization with projected token",
+			args: args{
+				ssCache: &scrapesSecretsCache{},
+				m: &vmv1beta1.VMStaticScrape{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "static-1",
+						Namespace: "default",
+					},
+				},
+				ep: &vmv1beta1.TargetEndpoint{
+					Targets: []string{"some-host:9100"},
+					EndpointAuth: vmv1beta1.EndpointAuth{
+						Authorization: &vmv1beta1.Authorization{CredentialsFile: vmv1beta1.VMAgentScrapeTokenPath},
+					},
+				},
+			},
+			want: `job_name: staticScrape/default/static-1/0
+static_configs:
+- targets:
+  - some-host:9100
+honor_labels: false
+relabel_configs: []
+authorization:
+  type: Bearer
+  credentials_file: /var/run/secrets/operator.victoriametrics.com/scrape-token/token
+`,
+		},
+		{
+			name: "max scrape size and scrape protocols at old vmagent",
+			args: args{
+				ssCache: &scrapesSecretsCache{},
+				cr: vmv1beta1.VMAgent{
+					Spec: vmv1beta1.VMAgentSpec{
+						CommonDefaultableParams: vmv1beta1.CommonDefaultableParams{
+							Image: vmv1beta1.Image{Tag: "v1.105.0"},
+						},
+					},
+				},
+				m: &vmv1beta1.VMStaticScrape{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "static-1",
+						Namespace: "default",
+					},
+				},
+				ep: &vmv1beta1.TargetEndpoint{
+					Targets: []string{"some-host:9100"},
+					EndpointScrapeParams: vmv1beta1.EndpointScrapeParams{
+						MaxScrapeSize:   "64MiB",
+						ScrapeProtocols: []string{"PrometheusProto"},
+					},
+				},
+			},
+			want: `job_name: staticScrape/default/static-1/0
+static_configs:
+- targets:
+  - some-host:9100
+honor_labels: false
+relabel_configs: []
+`,
+		},
+		{
+			name: "with global metric relabel configs",
+			args: args{
+				ssCache: &scrapesSecretsCache{},
+				cr: vmv1beta1.VMAgent{
+					Spec: vmv1beta1.VMAgentSpec{
+						GlobalMetricRelabelConfigs: &vmv1beta1.VMAgentGlobalMetricRelabelConfigs{
+							RelabelConfigs: []*vmv1beta1.RelabelConfig{
+								{Action: "drop", SourceLabels: []string{"__name__"}, Regex: vmv1beta1.StringOrArray{"go_.*"}},
+							},
+							ExemptSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "runtime"}},
+						},
+					},
+				},
+				m: &vmv1beta1.VMStaticScrape{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "static-1",
+						Namespace: "default",
+						Labels:    map[string]string{"team": "infra"},
+					},
+				},
+				ep: &vmv1beta1.TargetEndpoint{
+					Targets: []string{"192.168.11.1:9100"},
+					EndpointRelabelings: vmv1beta1.EndpointRelabelings{
+						MetricRelabelConfigs: []*vmv1beta1.RelabelConfig{
+							{Action: "keep", SourceLabels: []string{"__name__"}, Regex: vmv1beta1.StringOrArray{"go_.*|node_.*"}},
+						},
+					},
+				},
+			},
+			want: `job_name: staticScrape/default/static-1/0
+static_configs:
+- targets:
+  - 192.168.11.1:9100
+honor_labels: false
+relabel_configs: []
+metric_relabel_configs:
+- source_labels:
+  - __name__
+  regex: go_.*|node_.*
+  action: keep
+- source_labels:
+  - __name__
+  regex: go_.*
+  action: drop
+`,
+		},
+		{
+			name: "exempted from global metric relabel configs",
+			args: args{
+				ssCache: &scrapesSecretsCache{},
+				cr: vmv1beta1.VMAgent{
+					Spec: vmv1beta1.VMAgentSpec{
+						GlobalMetricRelabelConfigs: &vmv1beta1.VMAgentGlobalMetricRelabelConfigs{
+							RelabelConfigs: []*vmv1beta1.RelabelConfig{
+								{Action: "drop", SourceLabels: []string{"__name__"}, Regex: vmv1beta1.StringOrArray{"go_.*"}},
+							},
+							ExemptSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "runtime"}},
+						},
+					},
+				},
+				m: &vmv1beta1.VMStaticScrape{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "static-1",
+						Namespace: "default",
+						Labels:    map[string]string{"team": "runtime"},
+					},
+				},
+				ep: &vmv1beta1.TargetEndpoint{
+					Targets: []string{"192.168.11.1:9100"},
+				},
+			},
+			want: `job_name: staticScrape/default/static-1/0
+static_configs:
+- targets:
+  - 192.168.11.1:9100
+honor_labels: false
+relabel_configs: []
 `,
 		},
 	}
@@ -0,0 +1,153 @@
+package vmagent
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/config"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/logger"
+)
+
+const (
+	tenantPlaceholder            = "{{tenant}}"
+	defaultTenantRoutingMaxURLs  = 50
+	defaultTenantRoutingSrcLabel = "namespace"
+)
+
+var tenantIDRe = regexp.MustCompile(`^\d+(:\d+)?$`)
+
+// addTenantRemoteWrites appends remoteWrite url per tenant discovered from namespaces to the given VMAgent spec.
+// Each url keeps only metrics of the tenant namespaces with url relabeling.
+// Generated urls are not persisted and must be added on each reconcile before any remoteWrite processing.
+func addTenantRemoteWrites(ctx context.Context, rclient client.Client, cr *vmv1beta1.VMAgent) error {
+	tr := cr.Spec.TenantRouting
+	if tr == nil {
+		removeVMAgentCondition(cr, vmv1beta1.VMAgentTenantRoutingLimitReachedCondition)
+		return nil
+	}
+	if !config.IsClusterWideAccessAllowed() {
+		// namespaces are cluster scoped objects and cannot be listed with namespaced operator permissions
+		logger.WithContext(ctx).Info("skipping tenantRouting, it requires cluster wide access for operator, WATCH_NAMESPACE must be empty")
+		removeVMAgentCondition(cr, vmv1beta1.VMAgentTenantRoutingLimitReachedCondition)
+		return nil
+	}
+	opts := []client.ListOption{}
+	if tr.NamespaceSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(tr.NamespaceSelector)
+		if err != nil {
+			return fmt.Errorf("cannot parse tenantRouting.namespaceSelector: %w", err)
+		}
+		opts = append(opts, client.MatchingLabelsSelector{Selector: selector})
+	}
+	var nsList corev1.NamespaceList
+	if err := rclient.List(ctx, &nsList, opts...); err != nil {
+		return fmt.Errorf("cannot list namespaces for tenant routing: %w", err)
+	}
+	namespacesByTenant := make(map[string][]string)
+	for _, ns := range nsList.Items {
+		tenant := namespaceTenant(tr, &ns)
+		if tenant == "" {
+			continue
+		}
+		if !tenantIDRe.MatchString(tenant) {
+			logger.WithContext(ctx).Info(fmt.Sprintf("skipping namespace=%q with invalid tenant=%q, it must be in form accountID[:projectID]", ns.Name, tenant))
+			continue
+		}
+		namespacesByTenant[tenant] = append(namespacesByTenant[tenant], ns.Name)
+	}
+	tenants := make([]string, 0, len(namespacesByTenant))
+	for tenant := range namespacesByTenant {
+		tenants = append(tenants, tenant)
+	}
+	sort.Strings(tenants)
+
+	maxURLs := tr.MaxURLs
+	if maxURLs == 0 {
+		maxURLs = defaultTenantRoutingMaxURLs
+	}
+	cond := vmv1beta1.Condition{
+		Type:   vmv1beta1.VMAgentTenantRoutingLimitReachedCondition,
+		Status: metav1.ConditionFalse,
+		Reason: "TenantsWithinLimit",
+	}
+	if len(tenants) > maxURLs {
+		cond.Status = metav1.ConditionTrue
+		cond.Reason = vmv1beta1.VMAgentTenantRoutingLimitReachedCondition
+		cond.Message = fmt.Sprintf("maxURLs=%d limit is reached, skipped tenants: %s", maxURLs, strings.Join(tenants[maxURLs:], ","))
+		tenants = tenants[:maxURLs]
+	}
+	setVMAgentCondition(cr, cond)
+
+	srcLabel := tr.SourceLabel
+	if srcLabel == "" {
+		srcLabel = defaultTenantRoutingSrcLabel
+	}
+	for _, tenant := range tenants {
+		namespaces := namespacesByTenant[tenant]
+		sort.Strings(namespaces)
+		cr.Spec.RemoteWrite = append(cr.Spec.RemoteWrite, vmv1beta1.VMAgentRemoteWriteSpec{
+			URL: strings.ReplaceAll(tr.URLTemplate, tenantPlaceholder, tenant),
+			InlineUrlRelabelConfig: []vmv1beta1.RelabelConfig{
+				{
+					Action:       "keep",
+					SourceLabels: []string{srcLabel},
+					Regex:        vmv1beta1.StringOrArray(namespaces),
+				},
+			},
+		})
+	}
+	return nil
+}
+
+// namespaceTenant returns tenant id of the given namespace, label has priority over annotation
+func namespaceTenant(tr *vmv1beta1.VMAgentTenantRouting, ns *corev1.Namespace) string {
+	if tr.NamespaceLabel != "" {
+		if tenant := ns.Labels[tr.NamespaceLabel]; tenant != "" {
+			return tenant
+		}
+	}
+	if tr.NamespaceAnnotation != "" {
+		return ns.Annotations[tr.NamespaceAnnotation]
+	}
+	return ""
+}
+
+// setVMAgentCondition upserts the given condition at VMAgent status
+// transition times are preserved if condition status is not changed
+func setVMAgentCondition(cr *vmv1beta1.VMAgent, cond vmv1beta1.Condition) {
+	ctm := metav1.Now()
+	cond.ObservedGeneration = cr.Generation
+	cond.LastTransitionTime = ctm
+	cond.LastUpdateTime = ctm
+	for idx, c := range cr.Status.Conditions {
+		if c.Type != cond.Type {
+			continue
+		}
+		if c.Status == cond.Status {
+			cond.LastTransitionTime = c.LastTransitionTime
+			cond.LastUpdateTime = c.LastUpdateTime
+		}
+		cr.Status.Conditions[idx] = cond
+		return
+	}
+	cr.Status.Conditions = append(cr.Status.Conditions, cond)
+}
+
+// removeVMAgentCondition removes condition with the given type from VMAgent status
+func removeVMAgentCondition(cr *vmv1beta1.VMAgent, condType string) {
+	var conds []vmv1beta1.Condition
+	for _, c := range cr.Status.Conditions {
+		if c.Type != condType {
+			conds = append(conds, c)
+		}
+	}
+	cr.Status.Conditions = conds
+}
@@ -0,0 +1,130 @@
+package vmagent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/k8stools"
+)
+
+func Test_addTenantRemoteWrites(t *testing.T) {
+	type opts struct {
+		tr                *vmv1beta1.VMAgentTenantRouting
+		predefinedObjects []runtime.Object
+		wantRemoteWrites  []vmv1beta1.VMAgentRemoteWriteSpec
+		wantCondition     *vmv1beta1.Condition
+	}
+	newNamespace := func(name string, labels, annotations map[string]string) *corev1.Namespace {
+		return &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels, Annotations: annotations},
+		}
+	}
+	keepNamespaces := func(label string, namespaces ...string) []vmv1beta1.RelabelConfig {
+		return []vmv1beta1.RelabelConfig{{Action: "keep", SourceLabels: []string{label}, Regex: namespaces}}
+	}
+	f := func(o opts) {
+		t.Helper()
+		cr := &vmv1beta1.VMAgent{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+			Spec: vmv1beta1.VMAgentSpec{
+				RemoteWrite:   []vmv1beta1.VMAgentRemoteWriteSpec{{URL: "http://vminsert:8480/insert/0/prometheus/api/v1/write"}},
+				TenantRouting: o.tr,
+			},
+		}
+		fclient := k8stools.GetTestClientWithObjects(o.predefinedObjects)
+		if err := addTenantRemoteWrites(context.TODO(), fclient, cr); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if len(o.wantRemoteWrites) == 0 {
+			assert.Len(t, cr.Spec.RemoteWrite, 1)
+		} else {
+			assert.Equal(t, o.wantRemoteWrites, cr.Spec.RemoteWrite[1:])
+		}
+		if o.wantCondition == nil {
+			assert.Empty(t, cr.Status.Conditions)
+			return
+		}
+		if assert.Len(t, cr.Status.Conditions, 1) {
+			got := cr.Status.Conditions[0]
+			assert.Equal(t, o.wantCondition.Type, got.Type)
+			assert.Equal(t, o.wantCondition.Status, got.Status)
+			assert.Equal(t, o.wantCondition.Message, got.Message)
+		}
+	}
+
+	// routing is not enabled
+	f(opts{
+		predefinedObjects: []runtime.Object{
+			newNamespace("team-a", map[string]string{"tenant": "1"}, nil),
+		},
+	})
+
+	// tenants from label and annotation
+	f(opts{
+		tr: &vmv1beta1.VMAgentTenantRouting{
+			URLTemplate:         "http://vminsert:8480/insert/{{tenant}}/prometheus/api/v1/write",
+			NamespaceLabel:      "tenant",
+			NamespaceAnnotation: "vm/tenant",
+		},
+		predefinedObjects: []runtime.Object{
+			newNamespace("team-b", map[string]string{"tenant": "1"}, nil),
+			newNamespace("team-a", map[string]string{"tenant": "1"}, map[string]string{"vm/tenant": "5"}),
+			newNamespace("team-c", nil, map[string]string{"vm/tenant": "2:3"}),
+			newNamespace("team-d", map[string]string{"tenant": "invalid"}, nil),
+			newNamespace("default", nil, nil),
+		},
+		wantRemoteWrites: []vmv1beta1.VMAgentRemoteWriteSpec{
+			{
+				URL:                    "http://vminsert:8480/insert/1/prometheus/api/v1/write",
+				InlineUrlRelabelConfig: keepNamespaces("namespace", "team-a", "team-b"),
+			},
+			{
+				URL:                    "http://vminsert:8480/insert/2:3/prometheus/api/v1/write",
+				InlineUrlRelabelConfig: keepNamespaces("namespace", "team-c"),
+			},
+		},
+		wantCondition: &vmv1beta1.Condition{
+			Type:   vmv1beta1.VMAgentTenantRoutingLimitReachedCondition,
+			Status: metav1.ConditionFalse,
+		},
+	})
+
+	// namespace selector, custom source label and urls limit
+	f(opts{
+		tr: &vmv1beta1.VMAgentTenantRouting{
+			URLTemplate:       "http://vminsert:8480/insert/{{tenant}}/prometheus/api/v1/write",
+			NamespaceLabel:    "tenant",
+			SourceLabel:       "kubernetes_namespace",
+			NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"monitored": "true"}},
+			MaxURLs:           2,
+		},
+		predefinedObjects: []runtime.Object{
+			newNamespace("team-a", map[string]string{"tenant": "1", "monitored": "true"}, nil),
+			newNamespace("team-b", map[string]string{"tenant": "2", "monitored": "true"}, nil),
+			newNamespace("team-c", map[string]string{"tenant": "3", "monitored": "true"}, nil),
+			newNamespace("team-d", map[string]string{"tenant": "4", "monitored": "true"}, nil),
+			newNamespace("team-e", map[string]string{"tenant": "5"}, nil),
+		},
+		wantRemoteWrites: []vmv1beta1.VMAgentRemoteWriteSpec{
+			{
+				URL:                    "http://vminsert:8480/insert/1/prometheus/api/v1/write",
+				InlineUrlRelabelConfig: keepNamespaces("kubernetes_namespace", "team-a"),
+			},
+			{
+				URL:                    "http://vminsert:8480/insert/2/prometheus/api/v1/write",
+				InlineUrlRelabelConfig: keepNamespaces("kubernetes_namespace", "team-b"),
+			},
+		},
+		wantCondition: &vmv1beta1.Condition{
+			Type:    vmv1beta1.VMAgentTenantRoutingLimitReachedCondition,
+			Status:  metav1.ConditionTrue,
+			Message: "maxURLs=2 limit is reached, skipped tenants: 3,4",
+		},
+	})
+}
@@ -0,0 +1,34 @@
+package vmagent
+
+import (
+	"strings"
+
+	"github.com/hashicorp/go-version"
+
+	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
+)
+
+var (
+	// max_scrape_size scrape config option is added at v1.102.0-rc2 and its hot-reload is fixed at v1.105.0,
+	// see docs/changelog/CHANGELOG_2024.md of VictoriaMetrics
+	maxScrapeSizeMinVersion = version.Must(version.NewVersion("v1.106.0"))
+	// scrape_protocols scrape config option is unknown to lib/promscrape of v1.112.0, which is bundled with operator,
+	// and vmagent rejects unknown options with default -promscrape.config.strictParse.
+	// See Test_scrapeProtocolsMinVersion, the minimum must be revised together with bundled library version
+	scrapeProtocolsMinVersion = version.Must(version.NewVersion("v1.117.0"))
+)
+
+// isVMAgentVersionAtLeast checks if vmagent image version is equal or greater than minVersion.
+// Tags, which cannot be parsed as a version, e.g. latest or stable, are treated as the most recent version
+func isVMAgentVersionAtLeast(cr *vmv1beta1.VMAgent, minVersion *version.Version) bool {
+	tag := cr.Spec.Image.Tag
+	if idx := strings.Index(tag, "@"); idx >= 0 {
+		tag = tag[:idx]
+	}
+	v, err := version.NewVersion(tag)
+	if err != nil {
+		return true
+	}
+	// enterprise and scratch suffixes are parsed as pre-release
+	return v.Core().GreaterThanOrEqual(minVersion)
+}
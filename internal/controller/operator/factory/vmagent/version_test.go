@@ -0,0 +1,50 @@
+package vmagent
+
+import (
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape"
+	"github.com/hashicorp/go-version"
+	"gopkg.in/yaml.v2"
+
+	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
+)
+
+func Test_isVMAgentVersionAtLeast(t *testing.T) {
+	f := func(tag, minVersion string, want bool) {
+		t.Helper()
+		cr := &vmv1beta1.VMAgent{
+			Spec: vmv1beta1.VMAgentSpec{
+				CommonDefaultableParams: vmv1beta1.CommonDefaultableParams{
+					Image: vmv1beta1.Image{Tag: tag},
+				},
+			},
+		}
+		if got := isVMAgentVersionAtLeast(cr, version.Must(version.NewVersion(minVersion))); got != want {
+			t.Errorf("isVMAgentVersionAtLeast(%q, %q) = %v, want %v", tag, minVersion, got, want)
+		}
+	}
+	f("v1.106.0", "v1.106.0", true)
+	f("v1.105.1", "v1.106.0", false)
+	f("v1.106.0-enterprise", "v1.106.0", true)
+	f("v1.106.0-scratch@sha256:abc", "v1.106.0", true)
+	f("v1.90.0-enterprise", "v1.106.0", false)
+	f("latest", "v1.106.0", true)
+	f("", "v1.106.0", true)
+}
+
+func Test_scrapeProtocolsMinVersion(t *testing.T) {
+	// bundled config parser supports max_scrape_size
+	var sc promscrape.ScrapeConfig
+	if err := yaml.UnmarshalStrict([]byte("job_name: test\nmax_scrape_size: 16MiB\n"), &sc); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	// bundled config parser doesn't support scrape_protocols,
+	// version gate must be above its version
+	if err := yaml.UnmarshalStrict([]byte("job_name: test\nscrape_protocols: [PrometheusProto]\n"), &sc); err == nil {
+		t.Fatalf("scrape_protocols is supported by bundled config parser, scrapeProtocolsMinVersion must be revised")
+	}
+	if !scrapeProtocolsMinVersion.GreaterThan(version.Must(version.NewVersion("v1.112.0"))) {
+		t.Fatalf("scrapeProtocolsMinVersion=%s must be greater than bundled library version", scrapeProtocolsMinVersion)
+	}
+}
@@ -24,6 +24,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/utils/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -43,6 +44,8 @@ const (
 	vmagentGzippedFilename = "vmagent.yaml.gz"
 	configEnvsubstFilename = "vmagent.env.yaml"
 	defaultMaxDiskUsage    = "1073741824"
+	vmAgentNodeNameEnv     = "NODE_NAME"
+	scrapeTokenVolumeName  = "scrape-token"
 )
 
 // To save compatibility in the single-shard version still need to fill in %SHARD_NUM% placeholder
@@ -88,11 +91,16 @@ func createOrUpdateVMAgentService(ctx context.Context, rclient client.Client, cr
 
 // CreateOrUpdateVMAgent creates deployment for vmagent and configures it
 // waits for healthy state
-func CreateOrUpdateVMAgent(ctx context.Context, cr *vmv1beta1.VMAgent, rclient client.Client) error {
+// recorder is optional and used to emit events on rejected scrape objects
+func CreateOrUpdateVMAgent(ctx context.Context, cr *vmv1beta1.VMAgent, rclient client.Client, recorder record.EventRecorder) error {
 	var prevCR *vmv1beta1.VMAgent
 	if cr.ParsedLastAppliedSpec != nil {
 		prevCR = cr.DeepCopy()
-		prevCR.Spec = *cr.ParsedLastAppliedSpec
+		// remoteWrite entries are expanded in place and must not share memory with parsed spec
+		prevCR.Spec = *cr.ParsedLastAppliedSpec.DeepCopy()
+	}
+	if err := expandRemoteWrites(ctx, rclient, cr, prevCR); err != nil {
+		return err
 	}
 	if err := deletePrevStateResources(ctx, cr, rclient); err != nil {
 		return fmt.Errorf("cannot delete objects from prev state: %w", err)
@@ -119,13 +127,15 @@ func CreateOrUpdateVMAgent(ctx context.Context, cr *vmv1beta1.VMAgent, rclient c
 	}
 
 	if !ptr.Deref(cr.Spec.DisableSelfServiceScrape, false) {
-		err = reconcile.VMServiceScrapeForCRD(ctx, rclient, build.VMServiceScrapeForServiceWithSpec(svc, cr))
+		vss := build.VMServiceScrapeForServiceWithSpec(svc, cr)
+		addShardRelabelingsToServiceScrape(cr, vss)
+		err = reconcile.VMServiceScrapeForCRD(ctx, rclient, vss)
 		if err != nil {
 			return fmt.Errorf("cannot create serviceScrape: %w", err)
 		}
 	}
 
-	ssCache, err := createOrUpdateConfigurationSecret(ctx, rclient, cr, prevCR, nil)
+	ssCache, pendingConfigSecret, err := createOrUpdateConfigurationSecret(ctx, rclient, cr, prevCR, nil, recorder)
 	if err != nil {
 		return err
 	}
@@ -162,10 +172,25 @@ func CreateOrUpdateVMAgent(ctx context.Context, cr *vmv1beta1.VMAgent, rclient c
 		return fmt.Errorf("cannot build new deploy for vmagent: %w", err)
 	}
 
-	if cr.Spec.ShardCount != nil && *cr.Spec.ShardCount > 1 {
-		return createOrUpdateShardedDeploy(ctx, rclient, cr, prevCR, newDeploy, prevDeploy)
+	if isSharded(cr) {
+		err = createOrUpdateShardedDeploy(ctx, rclient, cr, prevCR, newDeploy, prevDeploy)
+	} else {
+		cr.Status.ShardTransition = nil
+		err = createOrUpdateDeploy(ctx, rclient, cr, prevCR, newDeploy, prevDeploy)
+	}
+	if err != nil {
+		return err
 	}
-	return createOrUpdateDeploy(ctx, rclient, cr, prevCR, newDeploy, prevDeploy)
+	if pendingConfigSecret != nil {
+		// vmagent pods mount config parts now and could be switched to the split configuration
+		if err := reconcileConfigSecret(ctx, rclient, cr, prevCR, pendingConfigSecret); err != nil {
+			return fmt.Errorf("cannot switch vmagent config secret to config parts: %w", err)
+		}
+	}
+	if err := removeStaleConfigSecretParts(ctx, rclient, cr); err != nil {
+		return fmt.Errorf("cannot remove stale config secret parts: %w", err)
+	}
+	return nil
 }
 
 func createOrUpdateDeploy(ctx context.Context, rclient client.Client, cr, _ *vmv1beta1.VMAgent, newDeploy, prevObjectSpec runtime.Object) error {
@@ -219,6 +244,25 @@ func createOrUpdateDeploy(ctx context.Context, rclient client.Client, cr, _ *vmv
 			return err
 		}
 		stsNames[newDeploy.Name] = struct{}{}
+	case *appsv1.DaemonSet:
+		var prevDS *appsv1.DaemonSet
+		if prevObjectSpec != nil {
+			prevAppObject, ok := prevObjectSpec.(*appsv1.DaemonSet)
+			if ok {
+				prevDS = prevAppObject
+				prevDS, err = k8stools.RenderPlaceholders(prevDS, defaultPlaceholders)
+				if err != nil {
+					return fmt.Errorf("cannot fill placeholders for prev daemonset in vmagent: %w", err)
+				}
+			}
+		}
+		newDeploy, err = k8stools.RenderPlaceholders(newDeploy, defaultPlaceholders)
+		if err != nil {
+			return fmt.Errorf("cannot fill placeholders for daemonset in vmagent: %w", err)
+		}
+		if err := reconcile.DaemonSet(ctx, rclient, newDeploy, prevDS); err != nil {
+			return err
+		}
 	}
 	if err := finalize.RemoveOrphanedDeployments(ctx, rclient, cr, deploymentNames); err != nil {
 		return err
@@ -236,13 +280,18 @@ func createOrUpdateShardedDeploy(ctx context.Context, rclient client.Client, cr,
 	shardsCount := *cr.Spec.ShardCount
 	logger.WithContext(ctx).Info(fmt.Sprintf("using cluster version of VMAgent with shards count=%d", shardsCount))
 
+	// new shards must be created first during upscaling
+	// and old shards must be removed only after update of remaining shards
+	// it allows to redistribute targets without scrape gap
+	hadTransition := cr.Status.ShardTransition != nil
+	transition := newShardTransition(cr, prevCR)
+	cr.Status.ShardTransition = transition
 	isUpscaling := false
-	if prevCR != nil && prevCR.Spec.ShardCount != nil {
-		if *prevCR.Spec.ShardCount < shardsCount {
-			logger.WithContext(ctx).Info(fmt.Sprintf("VMAgent shard upscaling from=%d to=%d", *prevCR.Spec.ShardCount, shardsCount))
-			isUpscaling = true
-		}
+	if transition != nil {
+		isUpscaling = transition.From < transition.To
+		logger.WithContext(ctx).Info(fmt.Sprintf("VMAgent shards count transition from=%d to=%d", transition.From, transition.To))
 	}
+	var shardIdx int
 	for shardNum := range shardNumIter(isUpscaling, shardsCount) {
 		shardedDeploy := newDeploy.DeepCopyObject()
 		var prevShardedObject runtime.Object
@@ -304,6 +353,23 @@ func createOrUpdateShardedDeploy(ctx context.Context, rclient client.Client, cr,
 			}
 			stsNames[shardedDeploy.Name] = struct{}{}
 		}
+		// shards are iterated in the same order on each reconcile
+		// so the first completed shards doesn't need to be checked again
+		if transition != nil && int32(shardIdx) >= transition.CompletedShards {
+			ready, err := isShardTargetsReady(ctx, rclient, cr, shardNum)
+			if err != nil {
+				return err
+			}
+			if !ready {
+				// the rest of shards and orphaned objects will be reconciled on requeue
+				return updateShardTransitionStatus(ctx, rclient, cr)
+			}
+			transition.CompletedShards++
+			if err := updateShardTransitionStatus(ctx, rclient, cr); err != nil {
+				return err
+			}
+		}
+		shardIdx++
 	}
 	if err := finalize.RemoveOrphanedDeployments(ctx, rclient, cr, deploymentNames); err != nil {
 		return err
@@ -311,13 +377,19 @@ func createOrUpdateShardedDeploy(ctx context.Context, rclient client.Client, cr,
 	if err := finalize.RemoveOrphanedSTSs(ctx, rclient, cr, stsNames); err != nil {
 		return err
 	}
+	if transition != nil || hadTransition {
+		cr.Status.ShardTransition = nil
+		if err := updateShardTransitionStatus(ctx, rclient, cr); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
 func shardNumIter(backward bool, shardCount int) iter.Seq[int] {
 	if backward {
 		return func(yield func(int) bool) {
-			for shardCount >= 0 {
+			for shardCount > 0 {
 				shardCount--
 				if !yield(shardCount) {
 					return
@@ -343,6 +415,34 @@ func newDeployForVMAgent(cr *vmv1beta1.VMAgent, ssCache *scrapesSecretsCache) (r
 	}
 	useStrictSecurity := ptr.Deref(cr.Spec.UseStrictSecurity, false)
 
+	if cr.Spec.DaemonSetMode {
+		dsSpec := &appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            cr.PrefixedName(),
+				Namespace:       cr.Namespace,
+				Labels:          cr.AllLabels(),
+				Annotations:     cr.AnnotationsFiltered(),
+				OwnerReferences: cr.AsOwner(),
+				Finalizers:      []string{vmv1beta1.FinalizerName},
+			},
+			Spec: appsv1.DaemonSetSpec{
+				Selector: &metav1.LabelSelector{
+					MatchLabels: cr.SelectorLabels(),
+				},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{
+						Labels:      cr.PodLabels(),
+						Annotations: cr.PodAnnotations(),
+					},
+					Spec: *podSpec,
+				},
+			},
+		}
+		build.DaemonSetAddCommonParams(dsSpec, useStrictSecurity, &cr.Spec.CommonApplicationDeploymentParams)
+		dsSpec.Spec.Template.Spec.Volumes = build.AddServiceAccountTokenVolume(dsSpec.Spec.Template.Spec.Volumes, &cr.Spec.CommonApplicationDeploymentParams)
+		return dsSpec, nil
+	}
+
 	// fast path, use sts
 	if cr.Spec.StatefulMode {
 		stsSpec := &appsv1.StatefulSet{
@@ -448,8 +548,43 @@ func makeSpecForVMAgent(cr *vmv1beta1.VMAgent, ssCache *scrapesSecretsCache) (*c
 	if len(cr.Spec.ExtraEnvs) > 0 {
 		args = append(args, "-envflag.enable=true")
 	}
+	if gl := cr.Spec.GlobalScrapeLimits; gl != nil {
+		if gl.LabelLimit > 0 {
+			args = append(args, fmt.Sprintf("-maxLabelsPerTimeseries=%d", gl.LabelLimit))
+		}
+		if gl.LabelNameLengthLimit > 0 {
+			args = append(args, fmt.Sprintf("-maxLabelNameLen=%d", gl.LabelNameLengthLimit))
+		}
+		if gl.LabelValueLengthLimit > 0 {
+			args = append(args, fmt.Sprintf("-maxLabelValueLen=%d", gl.LabelValueLengthLimit))
+		}
+	}
 
 	var envs []corev1.EnvVar
+	if cr.Spec.DaemonSetMode {
+		// node name is used by scrape config relabeling for filtering node local targets
+		envs = append(envs, corev1.EnvVar{
+			Name: vmAgentNodeNameEnv,
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{FieldPath: "spec.nodeName"},
+			},
+		})
+		args = append(args, "-promscrape.kubernetes.attachNodeMetadataAll=true")
+	}
+	for i, rws := range cr.Spec.RemoteWrite {
+		if rws.ProxyURL == nil || rws.ProxyBasicAuth == nil {
+			continue
+		}
+		envs = append(envs, corev1.EnvVar{
+			Name: remoteWriteProxyAuthEnv(i),
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: cr.PrefixedName()},
+					Key:                  rws.AsSecretKey(i, "proxyBasicAuth"),
+				},
+			},
+		})
+	}
 	envs = append(envs, cr.Spec.ExtraEnvs...)
 
 	var ports []corev1.ContainerPort
@@ -487,6 +622,9 @@ func makeSpecForVMAgent(cr *vmv1beta1.VMAgent, ssCache *scrapesSecretsCache) (*c
 	if !cr.Spec.IngestOnlyMode {
 		args = append(args,
 			fmt.Sprintf("-promscrape.config=%s", path.Join(vmAgentConOfOutDir, configEnvsubstFilename)))
+		if cr.Spec.ConfigCheckInterval != "" {
+			args = append(args, fmt.Sprintf("-promscrape.configCheckInterval=%s", cr.Spec.ConfigCheckInterval))
+		}
 
 		volumes = append(volumes,
 			corev1.Volume{
@@ -532,6 +670,15 @@ func makeSpecForVMAgent(cr *vmv1beta1.VMAgent, ssCache *scrapesSecretsCache) (*c
 				ReadOnly:  true,
 				MountPath: vmAgentConfDir,
 			})
+		if v := buildConfigPartsVolume(cr); v != nil {
+			volumes = append(volumes, *v)
+			agentVolumeMounts = append(agentVolumeMounts,
+				corev1.VolumeMount{
+					Name:      configPartsVolumeName,
+					ReadOnly:  true,
+					MountPath: vmAgentConfigPartsDir,
+				})
+		}
 	}
 	if cr.HasAnyStreamAggrRule() {
 		volumes = append(volumes, corev1.Volume{
@@ -610,8 +757,35 @@ func makeSpecForVMAgent(cr *vmv1beta1.VMAgent, ssCache *scrapesSecretsCache) (*c
 		})
 	}
 
+	if tp := cr.Spec.ServiceScrapeTokenProjection; tp != nil {
+		volumes = append(volumes, corev1.Volume{
+			Name: scrapeTokenVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				Projected: &corev1.ProjectedVolumeSource{
+					Sources: []corev1.VolumeProjection{
+						{
+							ServiceAccountToken: &corev1.ServiceAccountTokenProjection{
+								Audience:          tp.Audience,
+								ExpirationSeconds: tp.ExpirationSeconds,
+								Path:              path.Base(vmv1beta1.VMAgentScrapeTokenPath),
+							},
+						},
+					},
+				},
+			},
+		})
+		agentVolumeMounts = append(agentVolumeMounts, corev1.VolumeMount{
+			Name:      scrapeTokenVolumeName,
+			ReadOnly:  true,
+			MountPath: path.Dir(vmv1beta1.VMAgentScrapeTokenPath),
+		})
+	}
+
 	volumes, agentVolumeMounts = cr.Spec.License.MaybeAddToVolumes(volumes, agentVolumeMounts, vmv1beta1.SecretsDir)
 	args = cr.Spec.License.MaybeAddToArgs(args, vmv1beta1.SecretsDir)
+	if cr.Spec.AcceptEULA && !cr.Spec.License.IsProvided() {
+		args = append(args, "-eula")
+	}
 
 	if cr.Spec.RelabelConfig != nil || len(cr.Spec.InlineRelabelConfig) > 0 {
 		args = append(args, "-remoteWrite.relabelConfig="+path.Join(vmv1beta1.RelabelingConfigDir, globalRelabelingName))
@@ -706,6 +880,37 @@ func makeSpecForVMAgent(cr *vmv1beta1.VMAgent, ssCache *scrapesSecretsCache) (*c
 	}, nil
 }
 
+func isSharded(cr *vmv1beta1.VMAgent) bool {
+	return cr.Spec.ShardCount != nil && *cr.Spec.ShardCount > 1 && !cr.Spec.DaemonSetMode
+}
+
+// addShardRelabelingsToServiceScrape adds shard and replica labels to the self scrape endpoints of sharded vmagent.
+// shard is taken from shard-num pod label, replica is an ordinal of statefulset pod.
+// Deployment pods have no stable ordinal, so replica is only added for statefulMode
+func addShardRelabelingsToServiceScrape(cr *vmv1beta1.VMAgent, vss *vmv1beta1.VMServiceScrape) {
+	if !isSharded(cr) {
+		return
+	}
+	rcs := []*vmv1beta1.RelabelConfig{
+		{
+			SourceLabels: []string{"__meta_kubernetes_pod_label_shard_num"},
+			TargetLabel:  "shard",
+		},
+	}
+	if cr.Spec.StatefulMode {
+		rcs = append(rcs, &vmv1beta1.RelabelConfig{
+			SourceLabels: []string{"__meta_kubernetes_pod_name"},
+			Regex:        vmv1beta1.StringOrArray{`.+-(\d+)`},
+			Replacement:  ptr.To("$1"),
+			TargetLabel:  "replica",
+		})
+	}
+	for i := range vss.Spec.Endpoints {
+		ep := &vss.Spec.Endpoints[i]
+		ep.RelabelConfigs = append(ep.RelabelConfigs, rcs...)
+	}
+}
+
 func addShardSettingsToVMAgent(shardNum, shardsCount int, dep runtime.Object) {
 	var containers []corev1.Container
 	switch dep := dep.(type) {
@@ -844,58 +1049,63 @@ func buildStreamAggrConfig(ctx context.Context, cr *vmv1beta1.VMAgent, rclient c
 	}
 	// global section
 	if cr.Spec.StreamAggrConfig != nil {
-		if len(cr.Spec.StreamAggrConfig.Rules) > 0 {
-			data, err := yaml.Marshal(cr.Spec.StreamAggrConfig.Rules)
-			if err != nil {
-				return nil, fmt.Errorf("cannot serialize relabelConfig as yaml: %w", err)
-			}
-			if len(data) > 0 {
-				cfgCM.Data[globalAggregationConfigName] = string(data)
-			}
+		data, err := buildStreamAggrRules(ctx, rclient, cr.Namespace, cr.Spec.StreamAggrConfig)
+		if err != nil {
+			return nil, fmt.Errorf("incorrect spec.streamAggrConfig: %w", err)
 		}
-		if cr.Spec.StreamAggrConfig.RuleConfigMap != nil {
-			data, err := k8stools.FetchConfigMapContentByKey(ctx, rclient,
-				&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: cr.Spec.StreamAggrConfig.RuleConfigMap.Name, Namespace: cr.Namespace}},
-				cr.Spec.StreamAggrConfig.RuleConfigMap.Key)
-			if err != nil {
-				return nil, fmt.Errorf("cannot fetch configmap: %s, err: %w", cr.Spec.StreamAggrConfig.RuleConfigMap.Name, err)
-			}
-			if len(data) > 0 {
-				cfgCM.Data[globalAggregationConfigName] += data
-			}
+		if len(data) > 0 {
+			cfgCM.Data[globalAggregationConfigName] = data
 		}
 	}
 
 	for i := range cr.Spec.RemoteWrite {
 		rw := cr.Spec.RemoteWrite[i]
 		if rw.StreamAggrConfig != nil {
-			if len(rw.StreamAggrConfig.Rules) > 0 {
-				data, err := yaml.Marshal(rw.StreamAggrConfig.Rules)
-				if err != nil {
-					return nil, fmt.Errorf("cannot serialize relabelConfig as yaml: %w", err)
-				}
-				if len(data) > 0 {
-					cfgCM.Data[rw.AsConfigMapKey(i, "stream-aggr-conf")] = string(data)
-				}
+			data, err := buildStreamAggrRules(ctx, rclient, cr.Namespace, rw.StreamAggrConfig)
+			if err != nil {
+				return nil, fmt.Errorf("incorrect streamAggrConfig at remoteWrite idx=%d: %w", i, err)
 			}
-			if rw.StreamAggrConfig.RuleConfigMap != nil {
-				data, err := k8stools.FetchConfigMapContentByKey(ctx, rclient,
-					&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: rw.StreamAggrConfig.RuleConfigMap.Name, Namespace: cr.Namespace}},
-					rw.StreamAggrConfig.RuleConfigMap.Key)
-				if err != nil {
-					return nil, fmt.Errorf("cannot fetch configmap: %s, err: %w", rw.StreamAggrConfig.RuleConfigMap.Name, err)
-				}
-				if len(data) > 0 {
-					cfgCM.Data[rw.AsConfigMapKey(i, "stream-aggr-conf")] += data
-				}
+			if len(data) > 0 {
+				cfgCM.Data[rw.AsConfigMapKey(i, "stream-aggr-conf")] = data
 			}
-
 		}
-
 	}
 	return cfgCM, nil
 }
 
+// buildStreamAggrRules serializes inline stream aggregation rules and appends rules from the referenced ConfigMap
+// rules from ConfigMap must be a valid yaml list of rules with required fields
+func buildStreamAggrRules(ctx context.Context, rclient client.Client, namespace string, cfg *vmv1beta1.StreamAggrConfig) (string, error) {
+	var content string
+	if len(cfg.Rules) > 0 {
+		if err := cfg.Validate(); err != nil {
+			return "", err
+		}
+		data, err := yaml.Marshal(cfg.Rules)
+		if err != nil {
+			return "", fmt.Errorf("cannot serialize stream aggregation rules as yaml: %w", err)
+		}
+		content = string(data)
+	}
+	if cfg.RuleConfigMap != nil {
+		data, err := k8stools.FetchConfigMapContentByKey(ctx, rclient,
+			&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: cfg.RuleConfigMap.Name, Namespace: namespace}},
+			cfg.RuleConfigMap.Key)
+		if err != nil {
+			return "", fmt.Errorf("cannot fetch configmap: %s, err: %w", cfg.RuleConfigMap.Name, err)
+		}
+		var rules []vmv1beta1.StreamAggrRule
+		if err := yaml.Unmarshal([]byte(data), &rules); err != nil {
+			return "", fmt.Errorf("cannot parse stream aggregation rules from configmap=%s key=%s: %w", cfg.RuleConfigMap.Name, cfg.RuleConfigMap.Key, err)
+		}
+		if err := vmv1beta1.ValidateStreamAggrRules(rules); err != nil {
+			return "", fmt.Errorf("incorrect stream aggregation rules at configmap=%s key=%s: %w", cfg.RuleConfigMap.Name, cfg.RuleConfigMap.Key, err)
+		}
+		content += data
+	}
+	return content, nil
+}
+
 // createOrUpdateStreamAggrConfig builds stream aggregation configs for vmagent at separate configmap, serialized as yaml
 func createOrUpdateStreamAggrConfig(ctx context.Context, rclient client.Client, cr, prevCR *vmv1beta1.VMAgent) error {
 	// fast path
@@ -1072,13 +1282,7 @@ func buildRemoteWriteSettings(cr *vmv1beta1.VMAgent) []string {
 		pqMountPath = *rws.TmpDataPath
 	}
 	args = append(args, fmt.Sprintf("-remoteWrite.tmpDataPath=%s", pqMountPath))
-	var containsMaxDiskUsage bool
-	for arg := range cr.Spec.ExtraArgs {
-		if arg == "remoteWrite.maxDiskUsagePerURL" {
-			containsMaxDiskUsage = true
-		}
-		break
-	}
+	_, containsMaxDiskUsage := cr.Spec.ExtraArgs["remoteWrite.maxDiskUsagePerURL"]
 	if !containsMaxDiskUsage {
 		for i := range cr.Spec.RemoteWrite {
 			rws := cr.Spec.RemoteWrite[i]
@@ -1125,6 +1329,46 @@ func sortMap(m map[string]string) []item {
 	return kv
 }
 
+// expandRemoteWrites adds generated remoteWrite urls to the current and previous VMAgent specs.
+// Both specs must be expanded the same way, otherwise previous deployment never matches the new one
+// and vmagent deployment is updated on each reconcile
+func expandRemoteWrites(ctx context.Context, rclient client.Client, cr, prevCR *vmv1beta1.VMAgent) error {
+	setStructuredRemoteWrites(cr)
+	if err := addTenantRemoteWrites(ctx, rclient, cr); err != nil {
+		return fmt.Errorf("cannot build tenant remote writes for vmagent: %w", err)
+	}
+	if prevCR == nil {
+		return nil
+	}
+	setStructuredRemoteWrites(prevCR)
+	if err := addTenantRemoteWrites(ctx, rclient, prevCR); err != nil {
+		return fmt.Errorf("cannot build tenant remote writes for previous vmagent spec: %w", err)
+	}
+	return nil
+}
+
+// setStructuredRemoteWrites builds remoteWrite urls for kafka and pubsub targets
+// kafka SASL credentials are passed to vmagent as basic auth of the remoteWrite url
+func setStructuredRemoteWrites(cr *vmv1beta1.VMAgent) {
+	for i := range cr.Spec.RemoteWrite {
+		rw := &cr.Spec.RemoteWrite[i]
+		switch {
+		case rw.Kafka != nil:
+			rw.URL = rw.Kafka.BuildURL(rw.TLSConfig != nil)
+			if rw.Kafka.SASLAuth != nil && rw.BasicAuth == nil {
+				rw.BasicAuth = rw.Kafka.SASLAuth
+			}
+		case rw.PubSub != nil:
+			rw.URL = rw.PubSub.BuildURL()
+		}
+	}
+}
+
+// remoteWriteProxyAuthEnv returns name of env variable with escaped proxy credentials for remoteWrite at the given idx
+func remoteWriteProxyAuthEnv(idx int) string {
+	return fmt.Sprintf("VMAGENT_RWS_%d_PROXY_BASIC_AUTH", idx)
+}
+
 func buildRemoteWrites(cr *vmv1beta1.VMAgent, ssCache *scrapesSecretsCache) []string {
 	var finalArgs []string
 	var remoteArgs []remoteFlag
@@ -1154,12 +1398,21 @@ func buildRemoteWrites(cr *vmv1beta1.VMAgent, ssCache *scrapesSecretsCache) []st
 	streamAggrDropInputLabels := remoteFlag{flagSetting: "-remoteWrite.streamAggr.dropInputLabels="}
 	streamAggrIgnoreFirstIntervals := remoteFlag{flagSetting: "-remoteWrite.streamAggr.ignoreFirstIntervals="}
 	streamAggrIgnoreOldSamples := remoteFlag{flagSetting: "-remoteWrite.streamAggr.ignoreOldSamples="}
-	streamAggrEnableWindows := remoteFlag{flagSetting: "-remoteWrite.steamAggr.enableWindows="}
+	streamAggrEnableWindows := remoteFlag{flagSetting: "-remoteWrite.streamAggr.enableWindows="}
 	maxDiskUsagePerURL := remoteFlag{flagSetting: "-remoteWrite.maxDiskUsagePerURL="}
 	forceVMProto := remoteFlag{flagSetting: "-remoteWrite.forceVMProto="}
+	proxyURL := remoteFlag{flagSetting: "-remoteWrite.proxyURL="}
+	pubsubCredentialsFile := remoteFlag{flagSetting: "-gcp.pubsub.publish.credentialsFile="}
 
 	pathPrefix := path.Join(tlsAssetsDir, cr.Namespace)
 
+	// remoteWrite entries without maxDiskUsage must keep value of global setting
+	// in order to not change its buffer size, if maxDiskUsage is set for another entry
+	defaultMaxDiskUsagePerURL := defaultMaxDiskUsage
+	if cr.Spec.RemoteWriteSettings != nil && cr.Spec.RemoteWriteSettings.MaxDiskUsagePerURL != nil {
+		defaultMaxDiskUsagePerURL = fmt.Sprintf("%d", *cr.Spec.RemoteWriteSettings.MaxDiskUsagePerURL)
+	}
+
 	var maxDiskUsageInExtraArgs bool
 	var forceVMProtoInExtraArgs bool
 	for arg := range cr.Spec.ExtraArgs {
@@ -1255,6 +1508,20 @@ func buildRemoteWrites(cr *vmv1beta1.VMAgent, ssCache *scrapesSecretsCache) []st
 		authPasswordFile.flagSetting += fmt.Sprintf("%s,", passFile)
 
 		var value string
+		if rws.ProxyURL != nil {
+			proxyURL.isNotNull = true
+			value = *rws.ProxyURL
+			if rws.ProxyBasicAuth != nil {
+				// vmagent expands env placeholders at flags
+				// it allows to not expose credentials at pod spec
+				if idx := strings.Index(value, "://"); idx > 0 {
+					value = fmt.Sprintf("%s%%{%s}@%s", value[:idx+3], remoteWriteProxyAuthEnv(i), value[idx+3:])
+				}
+			}
+		}
+		proxyURL.flagSetting += fmt.Sprintf("%s,", value)
+
+		value = ""
 		if rws.BearerTokenSecret != nil && rws.BearerTokenSecret.Name != "" {
 			bearerTokenFile.isNotNull = true
 			value = path.Join(vmAgentConfDir, rws.AsSecretKey(i, "bearerToken"))
@@ -1368,19 +1635,26 @@ func buildRemoteWrites(cr *vmv1beta1.VMAgent, ssCache *scrapesSecretsCache) []st
 		streamAggrDedupInterval.flagSetting += fmt.Sprintf("%s,", dedupIntVal)
 		streamAggrIgnoreFirstIntervals.flagSetting += fmt.Sprintf("%d,", ignoreFirstIntervalsVal)
 		streamAggrIgnoreOldSamples.flagSetting += fmt.Sprintf("%v,", ignoreOldSamples)
-		streamAggrEnableWindows.flagSetting += fmt.Sprintf("%v", enableWindows)
+		streamAggrEnableWindows.flagSetting += fmt.Sprintf("%v,", enableWindows)
 
 		if maxDiskUsagePerURL.isNotNull {
 			if rws.MaxDiskUsage != nil {
 				maxDiskUsagePerURL.flagSetting += fmt.Sprintf("%s,", *rws.MaxDiskUsage)
 			} else {
-				maxDiskUsagePerURL.flagSetting += fmt.Sprintf("%s,", defaultMaxDiskUsage)
+				maxDiskUsagePerURL.flagSetting += fmt.Sprintf("%s,", defaultMaxDiskUsagePerURL)
 			}
 		}
 
 		if forceVMProto.isNotNull {
 			forceVMProto.flagSetting += fmt.Sprintf("%t,", rws.ForceVMProto)
 		}
+
+		value = ""
+		if rws.PubSub != nil && rws.PubSub.CredentialsSecret != nil {
+			pubsubCredentialsFile.isNotNull = true
+			value = path.Join(vmAgentConfDir, rws.AsSecretKey(i, "pubsubCredentials"))
+		}
+		pubsubCredentialsFile.flagSetting += fmt.Sprintf("%s,", value)
 	}
 
 	remoteArgs = append(remoteArgs, url, authUser, bearerTokenFile, urlRelabelConfig, tlsInsecure, sendTimeout)
@@ -1388,7 +1662,7 @@ func buildRemoteWrites(cr *vmv1beta1.VMAgent, ssCache *scrapesSecretsCache) []st
 	remoteArgs = append(remoteArgs, oauth2ClientID, oauth2ClientSecretFile, oauth2Scopes, oauth2TokenURL)
 	remoteArgs = append(remoteArgs, headers, authPasswordFile)
 	remoteArgs = append(remoteArgs, streamAggrConfig, streamAggrKeepInput, streamAggrDedupInterval, streamAggrDropInput, streamAggrDropInputLabels, streamAggrIgnoreFirstIntervals, streamAggrIgnoreOldSamples, streamAggrEnableWindows)
-	remoteArgs = append(remoteArgs, maxDiskUsagePerURL, forceVMProto)
+	remoteArgs = append(remoteArgs, maxDiskUsagePerURL, forceVMProto, proxyURL, pubsubCredentialsFile)
 
 	for _, remoteArgType := range remoteArgs {
 		if remoteArgType.isNotNull {
@@ -1415,6 +1689,14 @@ func buildConfigReloaderContainer(cr *vmv1beta1.VMAgent) corev1.Container {
 					MountPath: vmAgentConfDir,
 				})
 		}
+		if cr.Status.ConfigSecretParts > 0 {
+			configReloadVolumeMounts = append(configReloadVolumeMounts,
+				corev1.VolumeMount{
+					Name:      configPartsVolumeName,
+					ReadOnly:  true,
+					MountPath: vmAgentConfigPartsDir,
+				})
+		}
 	}
 	if cr.HasAnyRelabellingConfigs() {
 		configReloadVolumeMounts = append(configReloadVolumeMounts,
@@ -1451,6 +1733,15 @@ func buildConfigReloaderContainer(cr *vmv1beta1.VMAgent) corev1.Container {
 		VolumeMounts: configReloadVolumeMounts,
 		Resources:    cr.Spec.ConfigReloaderResources,
 	}
+	if cr.Spec.DaemonSetMode {
+		// config-reloader may expand env placeholders during envsubst
+		cntr.Env = append(cntr.Env, corev1.EnvVar{
+			Name: vmAgentNodeNameEnv,
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{FieldPath: "spec.nodeName"},
+			},
+		})
+	}
 	if useVMConfigReloader {
 		cntr.Command = nil
 		build.AddServiceAccountTokenVolumeMount(&cntr, &cr.Spec.CommonApplicationDeploymentParams)
@@ -1478,6 +1769,9 @@ func buildConfigReloaderArgs(cr *vmv1beta1.VMAgent) []string {
 		} else {
 			args = append(args, fmt.Sprintf("--config-file=%s", path.Join(vmAgentConfDir, vmagentGzippedFilename)))
 		}
+		if cr.Status.ConfigSecretParts > 0 {
+			args = append(args, fmt.Sprintf("--%s=%s", dirsArg, vmAgentConfigPartsDir))
+		}
 	}
 	if cr.HasAnyStreamAggrRule() {
 		args = append(args, fmt.Sprintf("--%s=%s", dirsArg, vmv1beta1.StreamAggrConfigDir))
@@ -1568,6 +1862,12 @@ func deletePrevStateResources(ctx context.Context, cr *vmv1beta1.VMAgent, rclien
 		}
 	}
 
+	if !cr.Spec.DaemonSetMode && cr.ParsedLastAppliedSpec.DaemonSetMode {
+		if err := finalize.SafeDeleteWithFinalizer(ctx, rclient, &appsv1.DaemonSet{ObjectMeta: objMeta}); err != nil {
+			return fmt.Errorf("cannot remove daemonset from prev state: %w", err)
+		}
+	}
+
 	if ptr.Deref(cr.Spec.DisableSelfServiceScrape, false) && !ptr.Deref(cr.ParsedLastAppliedSpec.DisableSelfServiceScrape, false) {
 		if err := finalize.SafeDeleteWithFinalizer(ctx, rclient, &vmv1beta1.VMServiceScrape{ObjectMeta: objMeta}); err != nil {
 			return fmt.Errorf("cannot remove serviceScrape: %w", err)
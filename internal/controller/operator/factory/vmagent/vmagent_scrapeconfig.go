@@ -6,19 +6,26 @@ import (
 	"context"
 	stderrors "errors"
 	"fmt"
+	"net/url"
 	"path"
 	"reflect"
 	"regexp"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
 
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promrelabel"
 	"github.com/VictoriaMetrics/metricsql"
 	"github.com/prometheus/client_golang/prometheus"
 	"gopkg.in/yaml.v2"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/utils/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/metrics"
@@ -48,6 +55,10 @@ type scrapesSecretsCache struct {
 	nsSecretCache        map[string]*corev1.Secret
 	nsCMCache            map[string]*corev1.ConfigMap
 	tlsAssets            map[string]string
+	// headers contains rendered endpoint headers in the `Name: value` format
+	headers map[string][]string
+	// relabelConfigs contains relabel configs loaded from scrape objects relabelConfigRefs
+	relabelConfigs map[string][]*vmv1beta1.RelabelConfig
 }
 
 type scrapeObjects struct {
@@ -66,51 +77,70 @@ type scrapeObjects struct {
 	totalBrokenCount int
 }
 
+// brokenCount returns number of scrape objects excluded from configuration
+func (sos *scrapeObjects) brokenCount() int {
+	return len(sos.sssBroken) + len(sos.pssBroken) + len(sos.stssBroken) + len(sos.nssBroken) + len(sos.prssBroken) + len(sos.scssBroken)
+}
+
 // CreateOrUpdateConfigurationSecret builds scrape configuration for VMAgent
-func CreateOrUpdateConfigurationSecret(ctx context.Context, rclient client.Client, cr *vmv1beta1.VMAgent, childObject client.Object) error {
+// recorder is optional and used to emit events on rejected scrape objects
+func CreateOrUpdateConfigurationSecret(ctx context.Context, rclient client.Client, cr *vmv1beta1.VMAgent, childObject client.Object, recorder record.EventRecorder) error {
+	setStructuredRemoteWrites(cr)
 	var prevCR *vmv1beta1.VMAgent
 	if cr.ParsedLastAppliedSpec != nil {
 		prevCR = cr.DeepCopy()
-		prevCR.Spec = *cr.ParsedLastAppliedSpec
-	}
-	if _, err := createOrUpdateConfigurationSecret(ctx, rclient, cr, prevCR, childObject); err != nil {
+		// remoteWrite entries are expanded in place and must not share memory with parsed spec
+		prevCR.Spec = *cr.ParsedLastAppliedSpec.DeepCopy()
+		setStructuredRemoteWrites(prevCR)
+	}
+	configSecretParts := cr.Status.ConfigSecretParts
+	conditions := slices.Clone(cr.Status.Conditions)
+	// pending config Secret is applied by VMAgent reconcile, which is triggered by status.configSecretParts change
+	if _, _, err := createOrUpdateConfigurationSecret(ctx, rclient, cr, prevCR, childObject, recorder); err != nil {
 		return err
 	}
+	if cr.Status.ConfigSecretParts != configSecretParts || !equality.Semantic.DeepEqual(cr.Status.Conditions, conditions) {
+		return updateConfigSecretStatus(ctx, rclient, cr)
+	}
 	return nil
 }
 
-func createOrUpdateConfigurationSecret(ctx context.Context, rclient client.Client, cr, prevCR *vmv1beta1.VMAgent, childObject client.Object) (*scrapesSecretsCache, error) {
+// createOrUpdateConfigurationSecret generates scrape configuration and updates config Secrets.
+// It returns pending main config Secret, if configuration refers config parts, which aren't mounted to vmagent pods yet.
+// Pending Secret must be applied after vmagent pods update, otherwise pods reload configuration with missing scrape_config_files
+func createOrUpdateConfigurationSecret(ctx context.Context, rclient client.Client, cr, prevCR *vmv1beta1.VMAgent, childObject client.Object, recorder record.EventRecorder) (*scrapesSecretsCache, *corev1.Secret, error) {
 	if cr.Spec.IngestOnlyMode {
-		return nil, nil
+		cr.Status.ConfigSecretParts = 0
+		return nil, nil, nil
 	}
 	sss, err := selectServiceScrapes(ctx, cr, rclient)
 	if err != nil {
-		return nil, fmt.Errorf("selecting ServiceScrapes failed: %w", err)
+		return nil, nil, fmt.Errorf("selecting ServiceScrapes failed: %w", err)
 	}
 
 	pScrapes, err := selectPodScrapes(ctx, cr, rclient)
 	if err != nil {
-		return nil, fmt.Errorf("selecting PodScrapes failed: %w", err)
+		return nil, nil, fmt.Errorf("selecting PodScrapes failed: %w", err)
 	}
 
 	probes, err := selectVMProbes(ctx, cr, rclient)
 	if err != nil {
-		return nil, fmt.Errorf("selecting VMProbes failed: %w", err)
+		return nil, nil, fmt.Errorf("selecting VMProbes failed: %w", err)
 	}
 
 	nodes, err := selectVMNodeScrapes(ctx, cr, rclient)
 	if err != nil {
-		return nil, fmt.Errorf("selecting VMNodeScrapes failed: %w", err)
+		return nil, nil, fmt.Errorf("selecting VMNodeScrapes failed: %w", err)
 	}
 
 	statics, err := selectStaticScrapes(ctx, cr, rclient)
 	if err != nil {
-		return nil, fmt.Errorf("selecting PodScrapes failed: %w", err)
+		return nil, nil, fmt.Errorf("selecting PodScrapes failed: %w", err)
 	}
 
 	scrapeConfigs, err := selectScrapeConfig(ctx, cr, rclient)
 	if err != nil {
-		return nil, fmt.Errorf("selecting ScrapeConfigs failed: %w", err)
+		return nil, nil, fmt.Errorf("selecting ScrapeConfigs failed: %w", err)
 	}
 	sos := &scrapeObjects{
 		sss:  sss,
@@ -145,19 +175,30 @@ func createOrUpdateConfigurationSecret(ctx context.Context, rclient client.Clien
 
 	ssCache, err := loadScrapeSecrets(ctx, rclient, sos, cr.Namespace, cr.Spec.APIServerConfig, cr.Spec.RemoteWrite)
 	if err != nil {
-		return nil, fmt.Errorf("cannot load scrape target secrets: %w", err)
-	}
-
-	if err := createOrUpdateTLSAssets(ctx, rclient, cr, prevCR, ssCache.tlsAssets); err != nil {
-		return nil, fmt.Errorf("cannot create tls assets secret for vmagent: %w", err)
+		return nil, nil, fmt.Errorf("cannot load scrape target secrets: %w", err)
 	}
 
 	additionalScrapeConfigs, err := loadAdditionalScrapeConfigsSecret(ctx, rclient, cr.Spec.AdditionalScrapeConfigs, cr.Namespace)
 	if err != nil {
-		return nil, fmt.Errorf("loading additional scrape configs from Secret failed: %w", err)
+		return nil, nil, fmt.Errorf("loading additional scrape configs from Secret failed: %w", err)
 	}
 	// TODO: @f41gh7  move it to the separate function
 	sos.sssBroken = append(sos.sssBroken, brokenServiceScrapes...)
+	// scrape classes are applied after secrets loading,
+	// since classes could reference only files
+	applyScrapeClasses(cr, sos)
+	if err := validateManagedProbes(cr, sos); err != nil {
+		return nil, nil, err
+	}
+	if err := createOrUpdateManagedProber(ctx, rclient, cr, prevCR, sos.prss); err != nil {
+		return nil, nil, err
+	}
+
+	if cr.IsOwnsServiceAccount() && config.IsClusterWideAccessAllowed() {
+		if err := ensureVMAgentCRExist(ctx, rclient, cr, prevCR, sos); err != nil {
+			return nil, nil, fmt.Errorf("cannot ensure state of vmagent's cluster role: %w", err)
+		}
+	}
 
 	// Update secret based on the most recent configuration.
 	generatedConfig, err := generateConfig(
@@ -168,33 +209,123 @@ func createOrUpdateConfigurationSecret(ctx context.Context, rclient client.Clien
 		additionalScrapeConfigs,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("generating config for vmagent failed: %w", err)
+		return nil, nil, fmt.Errorf("generating config for vmagent failed: %w", err)
+	}
+	data, err := yaml.Marshal(generatedConfig)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot marshal config for vmagent: %w", err)
+	}
+
+	jobsCount := scrapeJobsCount(generatedConfig)
+	degraded, err := isConfigDegraded(ctx, rclient, cr, jobsCount)
+	if err != nil {
+		return nil, nil, err
+	}
+	if degraded {
+		// assets of the held configuration must be kept as is,
+		// only assets missing at the applied configuration are added, e.g. for the remote write
+		if err := addMissingTLSAssets(ctx, rclient, cr, ssCache.tlsAssets); err != nil {
+			return nil, nil, err
+		}
+		if err := createOrUpdateTLSAssets(ctx, rclient, cr, prevCR, ssCache.tlsAssets); err != nil {
+			return nil, nil, fmt.Errorf("cannot create tls assets secret for vmagent: %w", err)
+		}
+		if err := updateStatusesForScrapeObjects(ctx, rclient, cr, sos, childObject); err != nil {
+			return nil, nil, err
+		}
+		return ssCache, nil, nil
+	}
+	if err := createOrUpdateTLSAssets(ctx, rclient, cr, prevCR, ssCache.tlsAssets); err != nil {
+		return nil, nil, fmt.Errorf("cannot create tls assets secret for vmagent: %w", err)
 	}
 
 	s := makeConfigSecret(cr, ssCache)
 	s.Annotations = map[string]string{
-		"generated": "true",
+		"generated":               "true",
+		scrapeJobsCountAnnotation: strconv.Itoa(jobsCount),
 	}
 
+	renderedData := data
 	// Compress config to avoid 1mb secret limit for a while
 	var buf bytes.Buffer
-	if err = gzipConfig(&buf, generatedConfig); err != nil {
-		return nil, fmt.Errorf("cannot gzip config for vmagent: %w", err)
+	if err = gzipConfig(&buf, data); err != nil {
+		return nil, nil, fmt.Errorf("cannot gzip config for vmagent: %w", err)
+	}
+	// split scrape jobs across multiple secrets, if compressed config still doesn't fit
+	var configParts [][]byte
+	if buf.Len() > maxConfigSecretSize {
+		var mainConfig yaml.MapSlice
+		mainConfig, configParts, err = splitScrapeConfigs(generatedConfig)
+		if err != nil {
+			return nil, nil, fmt.Errorf("cannot split config for vmagent: %w", err)
+		}
+		if data, err = yaml.Marshal(mainConfig); err != nil {
+			return nil, nil, fmt.Errorf("cannot marshal config for vmagent: %w", err)
+		}
+		buf.Reset()
+		if err = gzipConfig(&buf, data); err != nil {
+			return nil, nil, fmt.Errorf("cannot gzip config for vmagent: %w", err)
+		}
 	}
 	s.Data[vmagentGzippedFilename] = buf.Bytes()
-
-	var prevSecretMeta *metav1.ObjectMeta
-	if prevCR != nil {
-		prevSecretMeta = ptr.To(buildConfigMeta(prevCR))
+	// parts must be created before the main config, which refers them
+	if err := reconcileConfigSecretParts(ctx, rclient, cr, prevCR, configParts); err != nil {
+		return nil, nil, err
+	}
+	if partsCount := int32(len(configParts)); partsCount > cr.Status.ConfigSecretParts {
+		// created parts must be tracked at status before any further changes,
+		// since finalizer removes parts referenced by status.configSecretParts
+		cr.Status.ConfigSecretParts = partsCount
+		if err := updateConfigSecretStatus(ctx, rclient, cr); err != nil {
+			return nil, nil, err
+		}
+	}
+	cr.Status.ConfigSecretParts = int32(len(configParts))
+	var pendingConfigSecret *corev1.Secret
+	if len(configParts) > 0 {
+		s.Data[configPartsCountKey] = []byte(strconv.Itoa(len(configParts)))
+		canSwitch, err := canSwitchToConfigParts(ctx, rclient, cr, len(configParts))
+		if err != nil {
+			return nil, nil, err
+		}
+		if !canSwitch {
+			// vmagent pods reload the main config Secret with config-reloader
+			// and must not get references to config parts, which aren't mounted yet
+			pendingConfigSecret = s
+		}
 	}
-	if err := reconcile.Secret(ctx, rclient, s, prevSecretMeta); err != nil {
-		return nil, fmt.Errorf("cannot reconcile vmagent config secret: %w", err)
+	if pendingConfigSecret == nil {
+		if err := reconcileConfigSecret(ctx, rclient, cr, prevCR, s); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := reconcileRenderedConfig(ctx, rclient, cr, prevCR, renderedData, ssCache); err != nil {
+		return nil, nil, err
 	}
+	if isExposeRenderedConfig(cr) {
+		setGeneratedJobsInfo(generatedConfig, sos)
+	}
+	invalidScrapeObjects.WithLabelValues(cr.Namespace, cr.Name).Set(float64(sos.brokenCount()))
+	parentObject := fmt.Sprintf("%s.%s.vmagent", cr.Name, cr.Namespace)
+	events := reconcile.ChildObjectEvents{
+		Object:         "scrape object",
+		Parent:         fmt.Sprintf("vmagent=%s/%s", cr.Namespace, cr.Name),
+		RejectedReason: scrapeObjectRejectedEventReason,
+	}
+	reconcile.ChildObjectsEvents(recorder, parentObject, sos.sssBroken, events)
+	reconcile.ChildObjectsEvents(recorder, parentObject, sos.pssBroken, events)
+	reconcile.ChildObjectsEvents(recorder, parentObject, sos.nssBroken, events)
+	reconcile.ChildObjectsEvents(recorder, parentObject, sos.prssBroken, events)
+	reconcile.ChildObjectsEvents(recorder, parentObject, sos.stssBroken, events)
+	reconcile.ChildObjectsEvents(recorder, parentObject, sos.scssBroken, events)
 	if err := updateStatusesForScrapeObjects(ctx, rclient, cr, sos, childObject); err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	if err := updateScrapeObjectsSelectedBy(ctx, rclient, cr, sos, generatedConfig); err != nil {
+		return nil, nil, err
 	}
 
-	return ssCache, nil
+	return ssCache, pendingConfigSecret, nil
 }
 
 func updateStatusesForScrapeObjects(ctx context.Context, rclient client.Client, cr *vmv1beta1.VMAgent, sos *scrapeObjects, childObject client.Object) error {
@@ -326,16 +457,22 @@ func forEachCollectSkipNotFound[T scrapeObjectWithStatus](src []T, apply func(s
 	for _, o := range src {
 		if err := apply(o); err != nil {
 			var ne *k8stools.KeyNotFoundError
+			var ie *invalidReferenceError
+			var se *invalidScrapeParamsError
+			st := o.GetStatusMetadata()
 			switch {
 			case stderrors.As(err, &ne):
-				notNotFoundLinks = append(notNotFoundLinks, o)
+				st.CurrentSyncError = fmt.Sprintf("cannot find refrenced object: %s", err)
 			case errors.IsNotFound(err):
-				notNotFoundLinks = append(notNotFoundLinks, o)
+				st.CurrentSyncError = fmt.Sprintf("cannot find refrenced object: %s", err)
+			case stderrors.As(err, &ie):
+				st.CurrentSyncError = fmt.Sprintf("invalid content of refrenced object: %s", err)
+			case stderrors.As(err, &se):
+				st.CurrentSyncError = fmt.Sprintf("invalid scrape params: %s", err)
 			default:
 				return nil, nil, err
 			}
-			st := o.GetStatusMetadata()
-			st.CurrentSyncError = fmt.Sprintf("cannot find refrenced object: %s", err)
+			notNotFoundLinks = append(notNotFoundLinks, o)
 			continue
 		}
 		src[cnt] = o
@@ -344,6 +481,107 @@ func forEachCollectSkipNotFound[T scrapeObjectWithStatus](src []T, apply func(s
 	src = src[:cnt]
 	return src, notNotFoundLinks, nil
 }
+
+// invalidReferenceError represents an error of referenced object content
+// it excludes only the scrape object with such reference from configuration
+type invalidReferenceError struct {
+	err error
+}
+
+// Error implements interface
+func (ie *invalidReferenceError) Error() string {
+	return ie.err.Error()
+}
+
+// Unwrap implements interface
+func (ie *invalidReferenceError) Unwrap() error {
+	return ie.err
+}
+
+// invalidScrapeParamsError represents an error of scrape object endpoint params
+// it excludes only the scrape object with such params from configuration
+type invalidScrapeParamsError struct {
+	err error
+}
+
+// Error implements interface
+func (se *invalidScrapeParamsError) Error() string {
+	return se.err.Error()
+}
+
+// Unwrap implements interface
+func (se *invalidScrapeParamsError) Unwrap() error {
+	return se.err
+}
+
+func validateScrapeParams(cs *vmv1beta1.EndpointScrapeParams) error {
+	if err := cs.Validate(); err != nil {
+		return &invalidScrapeParamsError{err: err}
+	}
+	return nil
+}
+
+func relabelConfigsCacheKey(kind string, o client.Object) string {
+	return fmt.Sprintf("%s/%s/%s", kind, o.GetNamespace(), o.GetName())
+}
+
+// loadRelabelConfigRefs fetches relabel configs from the given ConfigMap keys and validates it
+func loadRelabelConfigRefs(ctx context.Context, rclient client.Client, refs []corev1.ConfigMapKeySelector, namespace string, cache map[string]*corev1.ConfigMap) ([]*vmv1beta1.RelabelConfig, error) {
+	var dst []*vmv1beta1.RelabelConfig
+	for _, ref := range refs {
+		data, err := k8stools.GetCredFromConfigMap(ctx, rclient, namespace, ref, buildCacheKey(namespace, ref.Name), cache)
+		if err != nil {
+			return nil, err
+		}
+		rcs, err := parseRelabelConfigs([]byte(data))
+		if err != nil {
+			return nil, &invalidReferenceError{fmt.Errorf("incorrect relabel configs at configmap=%s key=%s: %w", ref.Name, ref.Key, err)}
+		}
+		dst = append(dst, rcs...)
+	}
+	return dst, nil
+}
+
+// parseRelabelConfigs parses relabel configs in yaml format
+// and validates it with the same parser as vmagent uses
+func parseRelabelConfigs(data []byte) ([]*vmv1beta1.RelabelConfig, error) {
+	var src []*vmv1beta1.RelabelConfig
+	if err := yaml.UnmarshalStrict(data, &src); err != nil {
+		return nil, fmt.Errorf("cannot parse yaml: %w", err)
+	}
+	var rcs []*vmv1beta1.RelabelConfig
+	relabelings := make([]yaml.MapSlice, 0, len(src))
+	for _, rc := range src {
+		if rc == nil {
+			continue
+		}
+		if len(rc.SourceLabels) == 0 {
+			rc.SourceLabels = rc.UnderScoreSourceLabels
+		}
+		if rc.TargetLabel == "" {
+			rc.TargetLabel = rc.UnderScoreTargetLabel
+		}
+		rcs = append(rcs, rc)
+		relabelings = append(relabelings, generateRelabelConfig(rc))
+	}
+	generated, err := yaml.Marshal(relabelings)
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal relabel configs: %w", err)
+	}
+	// placeholders cannot be expanded at operator side
+	if bytes.Contains(generated, envTemplatePrefix) {
+		return rcs, nil
+	}
+	var parsed []promrelabel.RelabelConfig
+	if err := yaml.UnmarshalStrict(generated, &parsed); err != nil {
+		return nil, fmt.Errorf("cannot parse relabel configs: %w", err)
+	}
+	if _, err := promrelabel.ParseRelabelConfigs(parsed); err != nil {
+		return nil, err
+	}
+	return rcs, nil
+}
+
 func loadSecretsToCacheFrom(ctx context.Context, rclient client.Client, ep *vmv1beta1.EndpointAuth, cacheKey, namespace string, ss *scrapesSecretsCache) error {
 	if ep.BasicAuth != nil {
 		credentials, err := loadBasicAuthSecretFromAPI(ctx, rclient, ep.BasicAuth, namespace, ss.nsSecretCache)
@@ -382,6 +620,27 @@ func loadSecretsToCacheFrom(ctx context.Context, rclient client.Client, ep *vmv1
 	return nil
 }
 
+// loadHeadersToCache renders endpoint headers with values loaded from secrets
+func loadHeadersToCache(ctx context.Context, rclient client.Client, headers []vmv1beta1.ScrapeHeader, cacheKey, namespace string, ss *scrapesSecretsCache) error {
+	if len(headers) == 0 {
+		return nil
+	}
+	dst := make([]string, 0, len(headers))
+	for _, h := range headers {
+		value := h.Value
+		if h.ValueFrom != nil {
+			secretValue, err := k8stools.GetCredFromSecret(ctx, rclient, namespace, h.ValueFrom, buildCacheKey(namespace, h.ValueFrom.Name), ss.nsSecretCache)
+			if err != nil {
+				return fmt.Errorf("cannot load secret for header=%s for=%s: %w", h.Name, cacheKey, err)
+			}
+			value = secretValue
+		}
+		dst = append(dst, fmt.Sprintf("%s: %s", h.Name, value))
+	}
+	ss.headers[cacheKey] = dst
+	return nil
+}
+
 func loadScrapeSecrets(
 	ctx context.Context,
 	rclient client.Client,
@@ -398,13 +657,28 @@ func loadScrapeSecrets(
 		nsSecretCache:        map[string]*corev1.Secret{},
 		nsCMCache:            map[string]*corev1.ConfigMap{},
 		tlsAssets:            map[string]string{},
+		headers:              map[string][]string{},
+		relabelConfigs:       map[string][]*vmv1beta1.RelabelConfig{},
 	}
 	var err error
 	sos.sss, sos.sssBroken, err = forEachCollectSkipNotFound(sos.sss, func(mon *vmv1beta1.VMServiceScrape) error {
+		for _, ep := range mon.Spec.Endpoints {
+			if err := validateScrapeParams(&ep.EndpointScrapeParams); err != nil {
+				return err
+			}
+		}
+		rcs, err := loadRelabelConfigRefs(ctx, rclient, mon.Spec.RelabelConfigRefs, mon.Namespace, ssCache.nsCMCache)
+		if err != nil {
+			return err
+		}
+		ssCache.relabelConfigs[relabelConfigsCacheKey("serviceScrape", mon)] = rcs
 		for i, ep := range mon.Spec.Endpoints {
 			if err := loadSecretsToCacheFrom(ctx, rclient, &ep.EndpointAuth, mon.AsMapKey(i), mon.Namespace, ssCache); err != nil {
 				return err
 			}
+			if err := loadHeadersToCache(ctx, rclient, ep.Headers, mon.AsMapKey(i), mon.Namespace, ssCache); err != nil {
+				return err
+			}
 			if ep.VMScrapeParams != nil && ep.VMScrapeParams.ProxyClientConfig != nil {
 				ba, token, err := loadProxySecrets(ctx, rclient, ep.VMScrapeParams.ProxyClientConfig, mon.Namespace, ssCache.nsSecretCache)
 				if err != nil {
@@ -427,9 +701,18 @@ func loadScrapeSecrets(
 	}
 
 	sos.nss, sos.nssBroken, err = forEachCollectSkipNotFound(sos.nss, func(node *vmv1beta1.VMNodeScrape) error {
+		if err := validateScrapeParams(&node.Spec.EndpointScrapeParams); err != nil {
+			return err
+		}
+		if err := validateNodeAddressTypes(node.Spec.UseNodeAddressType); err != nil {
+			return err
+		}
 		if err := loadSecretsToCacheFrom(ctx, rclient, &node.Spec.EndpointAuth, node.AsMapKey(), node.Namespace, ssCache); err != nil {
 			return err
 		}
+		if err := loadHeadersToCache(ctx, rclient, node.Spec.Headers, node.AsMapKey(), node.Namespace, ssCache); err != nil {
+			return err
+		}
 		if node.Spec.VMScrapeParams != nil && node.Spec.VMScrapeParams.ProxyClientConfig != nil {
 			ba, token, err := loadProxySecrets(ctx, rclient, node.Spec.VMScrapeParams.ProxyClientConfig, node.Namespace, ssCache.nsSecretCache)
 			if err != nil {
@@ -451,10 +734,23 @@ func loadScrapeSecrets(
 	}
 
 	sos.pss, sos.pssBroken, err = forEachCollectSkipNotFound(sos.pss, func(pod *vmv1beta1.VMPodScrape) error {
+		for _, ep := range pod.Spec.PodMetricsEndpoints {
+			if err := validateScrapeParams(&ep.EndpointScrapeParams); err != nil {
+				return err
+			}
+		}
+		rcs, err := loadRelabelConfigRefs(ctx, rclient, pod.Spec.RelabelConfigRefs, pod.Namespace, ssCache.nsCMCache)
+		if err != nil {
+			return err
+		}
+		ssCache.relabelConfigs[relabelConfigsCacheKey("podScrape", pod)] = rcs
 		for i, ep := range pod.Spec.PodMetricsEndpoints {
 			if err := loadSecretsToCacheFrom(ctx, rclient, &ep.EndpointAuth, pod.AsMapKey(i), pod.Namespace, ssCache); err != nil {
 				return err
 			}
+			if err := loadHeadersToCache(ctx, rclient, ep.Headers, pod.AsMapKey(i), pod.Namespace, ssCache); err != nil {
+				return err
+			}
 			if ep.VMScrapeParams != nil && ep.VMScrapeParams.ProxyClientConfig != nil {
 				ba, token, err := loadProxySecrets(ctx, rclient, ep.VMScrapeParams.ProxyClientConfig, pod.Namespace, ssCache.nsSecretCache)
 				if err != nil {
@@ -477,9 +773,15 @@ func loadScrapeSecrets(
 	}
 
 	sos.prss, sos.prssBroken, err = forEachCollectSkipNotFound(sos.prss, func(probe *vmv1beta1.VMProbe) error {
+		if err := validateScrapeParams(&probe.Spec.EndpointScrapeParams); err != nil {
+			return err
+		}
 		if err := loadSecretsToCacheFrom(ctx, rclient, &probe.Spec.EndpointAuth, probe.AsMapKey(), probe.Namespace, ssCache); err != nil {
 			return err
 		}
+		if err := loadHeadersToCache(ctx, rclient, probe.Spec.Headers, probe.AsMapKey(), probe.Namespace, ssCache); err != nil {
+			return err
+		}
 		if probe.Spec.VMScrapeParams != nil && probe.Spec.VMScrapeParams.ProxyClientConfig != nil {
 			ba, token, err := loadProxySecrets(ctx, rclient, probe.Spec.VMScrapeParams.ProxyClientConfig, probe.Namespace, ssCache.nsSecretCache)
 			if err != nil {
@@ -500,10 +802,21 @@ func loadScrapeSecrets(
 	}
 
 	sos.stss, sos.stssBroken, err = forEachCollectSkipNotFound(sos.stss, func(staticCfg *vmv1beta1.VMStaticScrape) error {
+		for _, ep := range staticCfg.Spec.TargetEndpoints {
+			if err := ep.Validate(); err != nil {
+				return &invalidScrapeParamsError{err: err}
+			}
+			if err := validateScrapeParams(&ep.EndpointScrapeParams); err != nil {
+				return err
+			}
+		}
 		for i, ep := range staticCfg.Spec.TargetEndpoints {
 			if err := loadSecretsToCacheFrom(ctx, rclient, &ep.EndpointAuth, staticCfg.AsMapKey(i), staticCfg.Namespace, ssCache); err != nil {
 				return err
 			}
+			if err := loadHeadersToCache(ctx, rclient, ep.Headers, staticCfg.AsMapKey(i), staticCfg.Namespace, ssCache); err != nil {
+				return err
+			}
 
 			if ep.VMScrapeParams != nil && ep.VMScrapeParams.ProxyClientConfig != nil {
 				ba, token, err := loadProxySecrets(ctx, rclient, ep.VMScrapeParams.ProxyClientConfig, staticCfg.Namespace, ssCache.nsSecretCache)
@@ -526,9 +839,18 @@ func loadScrapeSecrets(
 	}
 
 	sos.scss, sos.scssBroken, err = forEachCollectSkipNotFound(sos.scss, func(scrapeConfig *vmv1beta1.VMScrapeConfig) error {
+		if err := validateScrapeParams(&scrapeConfig.Spec.EndpointScrapeParams); err != nil {
+			return err
+		}
+		if err := scrapeConfig.Spec.ValidateSDConfigs(); err != nil {
+			return &invalidScrapeParamsError{err: err}
+		}
 		if err := loadSecretsToCacheFrom(ctx, rclient, &scrapeConfig.Spec.EndpointAuth, scrapeConfig.AsMapKey("", 0), scrapeConfig.Namespace, ssCache); err != nil {
 			return err
 		}
+		if err := loadHeadersToCache(ctx, rclient, scrapeConfig.Spec.Headers, scrapeConfig.AsMapKey("", 0), scrapeConfig.Namespace, ssCache); err != nil {
+			return err
+		}
 		if scrapeConfig.Spec.VMScrapeParams != nil && scrapeConfig.Spec.VMScrapeParams.ProxyClientConfig != nil {
 			ba, token, err := loadProxySecrets(ctx, rclient, scrapeConfig.Spec.VMScrapeParams.ProxyClientConfig, scrapeConfig.Namespace, ssCache.nsSecretCache)
 			if err != nil {
@@ -645,7 +967,7 @@ func loadScrapeSecrets(
 			if ec.AccessKey != nil {
 				token, err := k8stools.GetCredFromSecret(ctx, rclient, scrapeConfig.Namespace, ec.AccessKey, buildCacheKey(scrapeConfig.Namespace, ec.AccessKey.Name), ssCache.nsSecretCache)
 				if err != nil {
-					return fmt.Errorf("could not generate token for consulSDConfigs %d in VMScrapeConfig %s. %w", i, scrapeConfig.Name, err)
+					return fmt.Errorf("could not generate token for ec2SDConfigs %d in VMScrapeConfig %s. %w", i, scrapeConfig.Name, err)
 				}
 				ssCache.authorizationSecrets[scrapeConfig.AsMapKey("ec2sdAccess", i)] = token
 			}
@@ -749,6 +1071,13 @@ func loadScrapeSecrets(
 			}
 			ssCache.baSecrets[rws.AsMapKey()] = &credentials
 		}
+		if rws.ProxyBasicAuth != nil {
+			credentials, err := k8stools.LoadBasicAuthSecret(ctx, rclient, vmagentCRNamespace, rws.ProxyBasicAuth, ssCache.nsSecretCache)
+			if err != nil {
+				return nil, fmt.Errorf("could not generate proxyBasicAuth for remote write spec %s config. %w", rws.URL, err)
+			}
+			ssCache.baSecrets[rws.AsProxyKey()] = &credentials
+		}
 		if rws.OAuth2 != nil {
 			oauth2, err := k8stools.LoadOAuthSecrets(ctx, rclient, rws.OAuth2, vmagentCRNamespace, ssCache.nsSecretCache, ssCache.nsCMCache)
 			if err != nil {
@@ -763,6 +1092,13 @@ func loadScrapeSecrets(
 			}
 			ssCache.bearerTokens[rws.AsMapKey()] = token
 		}
+		if rws.PubSub != nil && rws.PubSub.CredentialsSecret != nil {
+			creds, err := k8stools.GetCredFromSecret(ctx, rclient, vmagentCRNamespace, rws.PubSub.CredentialsSecret, buildCacheKey(vmagentCRNamespace, rws.PubSub.CredentialsSecret.Name), ssCache.nsSecretCache)
+			if err != nil {
+				return nil, fmt.Errorf("cannot get pubsub credentials for remoteWrite: %w", err)
+			}
+			ssCache.authorizationSecrets[rws.AsPubSubKey()] = creds
+		}
 		if err := addAssetsToCache(ctx, rclient, vmagentCRNamespace, rws.TLSConfig, ssCache); err != nil {
 			return nil, fmt.Errorf("cannot add asset for remote write target: %w", err)
 		}
@@ -914,6 +1250,34 @@ func setScrapeIntervalToWithLimit(ctx context.Context, dst *vmv1beta1.EndpointSc
 	}
 }
 
+// setScrapeLimitsTo sets global sample and series limits for scrape params without own limits.
+// If global limits are enforced, higher limits are reduced and reported as warning at the given scrape object status
+func setScrapeLimitsTo(dst *vmv1beta1.EndpointScrapeParams, vmagentCR *vmv1beta1.VMAgent, st *vmv1beta1.StatusMetadata, jobName string) {
+	gl := vmagentCR.Spec.GlobalScrapeLimits
+	if gl == nil {
+		return
+	}
+	dst.SampleLimit = limitScrapeParam(dst.SampleLimit, gl.SampleLimit, gl.Enforce, st, jobName, "sampleLimit")
+	dst.SeriesLimit = limitScrapeParam(dst.SeriesLimit, gl.SeriesLimit, gl.Enforce, st, jobName, "seriesLimit")
+}
+
+func limitScrapeParam(value, limit uint64, enforce bool, st *vmv1beta1.StatusMetadata, jobName, paramName string) uint64 {
+	switch {
+	case limit == 0:
+		return value
+	case value == 0:
+		return limit
+	case enforce && value > limit:
+		msg := fmt.Sprintf("%s=%d for job=%s is reduced to the global limit=%d", paramName, value, jobName, limit)
+		if st.CurrentSyncWarning != "" {
+			msg = st.CurrentSyncWarning + "; " + msg
+		}
+		st.CurrentSyncWarning = msg
+		return limit
+	}
+	return value
+}
+
 const (
 	defaultScrapeInterval          = "30s"
 	kubernetesSDRoleEndpoint       = "endpoints"
@@ -932,7 +1296,7 @@ func generateConfig(
 	sos *scrapeObjects,
 	secretsCache *scrapesSecretsCache,
 	additionalScrapeConfigs []byte,
-) ([]byte, error) {
+) (yaml.MapSlice, error) {
 	cfg := yaml.MapSlice{}
 	if !config.IsClusterWideAccessAllowed() && cr.IsOwnsServiceAccount() {
 		logger.WithContext(ctx).Info("Setting discovery for the single namespace only." +
@@ -959,11 +1323,16 @@ func generateConfig(
 	cfg = append(cfg, yaml.MapItem{Key: "global", Value: globalItems})
 
 	apiserverConfig := cr.Spec.APIServerConfig
+	if cr.Spec.DaemonSetMode {
+		filterNodeLocalScrapeObjects(ctx, sos)
+	}
 
 	var scrapeConfigs []yaml.MapSlice
-	for _, ss := range sos.sss {
+	var invalidSss []*vmv1beta1.VMServiceScrape
+	scrapeConfigs, sos.sss, invalidSss = appendValidScrapeConfigs(scrapeConfigs, sos.sss, func(ss *vmv1beta1.VMServiceScrape, _ int) []yaml.MapSlice {
+		var scs []yaml.MapSlice
 		for i, ep := range ss.Spec.Endpoints {
-			scrapeConfigs = append(scrapeConfigs,
+			scs = append(scs,
 				generateServiceScrapeConfig(
 					ctx,
 					cr,
@@ -974,10 +1343,15 @@ func generateConfig(
 					cr.Spec.VMAgentSecurityEnforcements,
 				))
 		}
-	}
-	for _, identifier := range sos.pss {
+		return scs
+	})
+	sos.sssBroken = append(sos.sssBroken, invalidSss...)
+
+	var invalidPss []*vmv1beta1.VMPodScrape
+	scrapeConfigs, sos.pss, invalidPss = appendValidScrapeConfigs(scrapeConfigs, sos.pss, func(identifier *vmv1beta1.VMPodScrape, _ int) []yaml.MapSlice {
+		var scs []yaml.MapSlice
 		for i, ep := range identifier.Spec.PodMetricsEndpoints {
-			scrapeConfigs = append(scrapeConfigs,
+			scs = append(scs,
 				generatePodScrapeConfig(
 					ctx,
 					cr,
@@ -987,10 +1361,13 @@ func generateConfig(
 					cr.Spec.VMAgentSecurityEnforcements,
 				))
 		}
-	}
+		return scs
+	})
+	sos.pssBroken = append(sos.pssBroken, invalidPss...)
 
-	for i, identifier := range sos.prss {
-		scrapeConfigs = append(scrapeConfigs,
+	var invalidPrss []*vmv1beta1.VMProbe
+	scrapeConfigs, sos.prss, invalidPrss = appendValidScrapeConfigs(scrapeConfigs, sos.prss, func(identifier *vmv1beta1.VMProbe, i int) []yaml.MapSlice {
+		return []yaml.MapSlice{
 			generateProbeConfig(
 				ctx,
 				cr,
@@ -999,10 +1376,13 @@ func generateConfig(
 				apiserverConfig,
 				secretsCache,
 				cr.Spec.VMAgentSecurityEnforcements,
-			))
-	}
-	for i, identifier := range sos.nss {
-		scrapeConfigs = append(scrapeConfigs,
+			)}
+	})
+	sos.prssBroken = append(sos.prssBroken, invalidPrss...)
+
+	var invalidNss []*vmv1beta1.VMNodeScrape
+	scrapeConfigs, sos.nss, invalidNss = appendValidScrapeConfigs(scrapeConfigs, sos.nss, func(identifier *vmv1beta1.VMNodeScrape, i int) []yaml.MapSlice {
+		return []yaml.MapSlice{
 			generateNodeScrapeConfig(
 				ctx,
 				cr,
@@ -1011,12 +1391,15 @@ func generateConfig(
 				apiserverConfig,
 				secretsCache,
 				cr.Spec.VMAgentSecurityEnforcements,
-			))
-	}
+			)}
+	})
+	sos.nssBroken = append(sos.nssBroken, invalidNss...)
 
-	for _, identifier := range sos.stss {
+	var invalidStss []*vmv1beta1.VMStaticScrape
+	scrapeConfigs, sos.stss, invalidStss = appendValidScrapeConfigs(scrapeConfigs, sos.stss, func(identifier *vmv1beta1.VMStaticScrape, _ int) []yaml.MapSlice {
+		var scs []yaml.MapSlice
 		for i, ep := range identifier.Spec.TargetEndpoints {
-			scrapeConfigs = append(scrapeConfigs,
+			scs = append(scs,
 				generateStaticScrapeConfig(
 					ctx,
 					cr,
@@ -1026,18 +1409,22 @@ func generateConfig(
 					cr.Spec.VMAgentSecurityEnforcements,
 				))
 		}
-	}
+		return scs
+	})
+	sos.stssBroken = append(sos.stssBroken, invalidStss...)
 
-	for _, identifier := range sos.scss {
-		scrapeConfigs = append(scrapeConfigs,
+	var invalidScss []*vmv1beta1.VMScrapeConfig
+	scrapeConfigs, sos.scss, invalidScss = appendValidScrapeConfigs(scrapeConfigs, sos.scss, func(identifier *vmv1beta1.VMScrapeConfig, _ int) []yaml.MapSlice {
+		return []yaml.MapSlice{
 			generateScrapeConfig(
 				ctx,
 				cr,
 				identifier,
 				secretsCache,
 				cr.Spec.VMAgentSecurityEnforcements,
-			))
-	}
+			)}
+	})
+	sos.scssBroken = append(sos.scssBroken, invalidScss...)
 
 	var additionalScrapeConfigsYaml []yaml.MapSlice
 	if err := yaml.Unmarshal(additionalScrapeConfigs, &additionalScrapeConfigsYaml); err != nil {
@@ -1056,7 +1443,7 @@ func generateConfig(
 		Value: append(scrapeConfigs, additionalScrapeConfigsYaml...),
 	})
 
-	return yaml.Marshal(cfg)
+	return cfg, nil
 }
 
 func buildConfigMeta(cr *vmv1beta1.VMAgent) metav1.ObjectMeta {
@@ -1070,6 +1457,18 @@ func buildConfigMeta(cr *vmv1beta1.VMAgent) metav1.ObjectMeta {
 	}
 }
 
+// reconcileConfigSecret creates or updates the main config Secret
+func reconcileConfigSecret(ctx context.Context, rclient client.Client, cr, prevCR *vmv1beta1.VMAgent, s *corev1.Secret) error {
+	var prevSecretMeta *metav1.ObjectMeta
+	if prevCR != nil {
+		prevSecretMeta = ptr.To(buildConfigMeta(prevCR))
+	}
+	if err := reconcile.Secret(ctx, rclient, s, prevSecretMeta); err != nil {
+		return fmt.Errorf("cannot reconcile vmagent config secret: %w", err)
+	}
+	return nil
+}
+
 func makeConfigSecret(cr *vmv1beta1.VMAgent, ssCache *scrapesSecretsCache) *corev1.Secret {
 	s := &corev1.Secret{
 		ObjectMeta: buildConfigMeta(cr),
@@ -1092,6 +1491,19 @@ func makeConfigSecret(cr *vmv1beta1.VMAgent, ssCache *scrapesSecretsCache) *core
 			}
 			s.Data[rw.AsSecretKey(idx, "basicAuthPassword")] = []byte(ba.Password)
 		}
+		if rw.ProxyURL != nil && rw.ProxyBasicAuth != nil {
+			ba, ok := ssCache.baSecrets[rw.AsProxyKey()]
+			if !ok {
+				panic(fmt.Sprintf("bug, remoteWriteSpec proxyBasicAuth is missing: %s", rw.AsProxyKey()))
+			}
+			// credentials are substituted into proxy url by vmagent as is
+			// so it must be escaped in advance
+			userInfo := url.User(ba.Username)
+			if len(ba.Password) > 0 {
+				userInfo = url.UserPassword(ba.Username, ba.Password)
+			}
+			s.Data[rw.AsSecretKey(idx, "proxyBasicAuth")] = []byte(userInfo.String())
+		}
 		if rw.OAuth2 != nil {
 			oauth2, ok := ssCache.oauth2Secrets[rw.AsMapKey()]
 			if !ok {
@@ -1099,6 +1511,13 @@ func makeConfigSecret(cr *vmv1beta1.VMAgent, ssCache *scrapesSecretsCache) *core
 			}
 			s.Data[rw.AsSecretKey(idx, "oauth2Secret")] = []byte(oauth2.ClientSecret)
 		}
+		if rw.PubSub != nil && rw.PubSub.CredentialsSecret != nil {
+			creds, ok := ssCache.authorizationSecrets[rw.AsPubSubKey()]
+			if !ok {
+				panic(fmt.Sprintf("bug, remoteWriteSpec pubsub credentials are missing: %s", rw.AsPubSubKey()))
+			}
+			s.Data[rw.AsSecretKey(idx, "pubsubCredentials")] = []byte(creds)
+		}
 	}
 	return s
 }
@@ -1213,6 +1632,24 @@ func addRelabelConfigs(dst []yaml.MapSlice, rcs []vmv1beta1.RelabelConfig) []yam
 	return dst
 }
 
+// generateJobLabelRelabeling builds relabeling for `job` label with VMAgent jobLabelTemplate.
+// It returns nil if template isn't defined, vmagent uses unique job_name as `job` label in this case.
+func generateJobLabelRelabeling(vmagentCR *vmv1beta1.VMAgent, kind, namespace, name string, idx int) yaml.MapSlice {
+	if vmagentCR.Spec.JobLabelTemplate == "" {
+		return nil
+	}
+	jobLabel := strings.NewReplacer(
+		"{{kind}}", kind,
+		"{{namespace}}", namespace,
+		"{{name}}", name,
+		"{{endpoint}}", strconv.Itoa(idx),
+	).Replace(vmagentCR.Spec.JobLabelTemplate)
+	return yaml.MapSlice{
+		{Key: "target_label", Value: "job"},
+		{Key: "replacement", Value: jobLabel},
+	}
+}
+
 func generateRelabelConfig(rc *vmv1beta1.RelabelConfig) yaml.MapSlice {
 	relabeling := yaml.MapSlice{}
 
@@ -1422,6 +1859,25 @@ func buildVMScrapeParams(namespace, cacheKey string, cfg *vmv1beta1.VMScrapePara
 	return r
 }
 
+// addEndpointHeadersTo appends cached endpoint headers to the headers defined with vm_scrape_params
+func addEndpointHeadersTo(dst yaml.MapSlice, cacheKey string, headersCache map[string][]string) yaml.MapSlice {
+	headers := headersCache[cacheKey]
+	if len(headers) == 0 {
+		// fast path
+		return dst
+	}
+	for i := range dst {
+		if dst[i].Key != "headers" {
+			continue
+		}
+		if prev, ok := dst[i].Value.([]string); ok {
+			dst[i].Value = append(append([]string{}, prev...), headers...)
+			return dst
+		}
+	}
+	return append(dst, yaml.MapItem{Key: "headers", Value: headers})
+}
+
 func addAuthorizationConfigTo(dst yaml.MapSlice, cacheKey string, cfg *vmv1beta1.Authorization, authorizationCache map[string]string) yaml.MapSlice {
 	if cfg == nil {
 		// fast path
@@ -1514,6 +1970,46 @@ func buildProxyAuthConfig(namespace, cacheKey string, proxyAuth *vmv1beta1.Proxy
 	return r
 }
 
+// addNodeFilterToRelabelingFor keeps only targets located at the same node with vmagent pod
+// it's required for daemonSetMode, node name is passed to vmagent with env var
+func addNodeFilterToRelabelingFor(relabelings []yaml.MapSlice, cr *vmv1beta1.VMAgent, sourceLabel string) []yaml.MapSlice {
+	if !cr.Spec.DaemonSetMode {
+		return relabelings
+	}
+	return append(relabelings, yaml.MapSlice{
+		{Key: "action", Value: "keep"},
+		{Key: "source_labels", Value: []string{sourceLabel}},
+		{Key: "regex", Value: fmt.Sprintf("%%{%s}", vmAgentNodeNameEnv)},
+	})
+}
+
+// filterNodeLocalScrapeObjects removes scrape objects, which targets cannot be filtered by node name.
+// Otherwise, at daemonSetMode such targets are scraped by each vmagent pod
+func filterNodeLocalScrapeObjects(ctx context.Context, sos *scrapeObjects) {
+	l := logger.WithContext(ctx)
+	var sss []*vmv1beta1.VMServiceScrape
+	for _, ss := range sos.sss {
+		if ss.Spec.DiscoveryRole == kubernetesSDRoleService {
+			l.Info(fmt.Sprintf("skipping VMServiceScrape=%s/%s with discoveryRole=service at daemonSetMode, its targets have no node metadata", ss.Namespace, ss.Name))
+			continue
+		}
+		sss = append(sss, ss)
+	}
+	sos.sss = sss
+	for _, prs := range sos.prss {
+		l.Info(fmt.Sprintf("skipping VMProbe=%s/%s at daemonSetMode, its targets have no node metadata", prs.Namespace, prs.Name))
+	}
+	sos.prss = nil
+	for _, sts := range sos.stss {
+		l.Info(fmt.Sprintf("skipping VMStaticScrape=%s/%s at daemonSetMode, its targets have no node metadata", sts.Namespace, sts.Name))
+	}
+	sos.stss = nil
+	for _, scs := range sos.scss {
+		l.Info(fmt.Sprintf("skipping VMScrapeConfig=%s/%s at daemonSetMode, its targets have no node metadata", scs.Namespace, scs.Name))
+	}
+	sos.scss = nil
+}
+
 func addSelectorToRelabelingFor(relabelings []yaml.MapSlice, typeName string, selector metav1.LabelSelector) []yaml.MapSlice {
 	// Exact label matches.
 	var labelKeys []string
@@ -1562,7 +2058,7 @@ func addSelectorToRelabelingFor(relabelings []yaml.MapSlice, typeName string, se
 	return relabelings
 }
 
-func addCommonScrapeParamsTo(cfg yaml.MapSlice, cs vmv1beta1.EndpointScrapeParams, se vmv1beta1.VMAgentSecurityEnforcements) yaml.MapSlice {
+func addCommonScrapeParamsTo(cfg yaml.MapSlice, cs vmv1beta1.EndpointScrapeParams, se vmv1beta1.VMAgentSecurityEnforcements, vmagentCR *vmv1beta1.VMAgent) yaml.MapSlice {
 	hl := honorLabels(cs.HonorLabels, se.OverrideHonorLabels)
 	cfg = append(cfg, yaml.MapItem{
 		Key:   "honor_labels",
@@ -1605,9 +2101,12 @@ func addCommonScrapeParamsTo(cfg yaml.MapSlice, cs vmv1beta1.EndpointScrapeParam
 		// vmagent expects lower case format only
 		cfg = append(cfg, yaml.MapItem{Key: "scheme", Value: strings.ToLower(cs.Scheme)})
 	}
-	if cs.MaxScrapeSize != "" {
+	if cs.MaxScrapeSize != "" && isVMAgentVersionAtLeast(vmagentCR, maxScrapeSizeMinVersion) {
 		cfg = append(cfg, yaml.MapItem{Key: "max_scrape_size", Value: cs.MaxScrapeSize})
 	}
+	if len(cs.ScrapeProtocols) > 0 && isVMAgentVersionAtLeast(vmagentCR, scrapeProtocolsMinVersion) {
+		cfg = append(cfg, yaml.MapItem{Key: "scrape_protocols", Value: cs.ScrapeProtocols})
+	}
 	if cs.SampleLimit > 0 {
 		cfg = append(cfg, yaml.MapItem{Key: "sample_limit", Value: cs.SampleLimit})
 	}
@@ -1617,6 +2116,25 @@ func addCommonScrapeParamsTo(cfg yaml.MapSlice, cs vmv1beta1.EndpointScrapeParam
 	return cfg
 }
 
+// withGlobalMetricRelabelConfigs appends global metric relabel configs of VMAgent after the given relabel configs,
+// unless scrape object is matched by exemptSelector
+func withGlobalMetricRelabelConfigs(vmagentCR *vmv1beta1.VMAgent, o client.Object, src []*vmv1beta1.RelabelConfig) []*vmv1beta1.RelabelConfig {
+	gmr := vmagentCR.Spec.GlobalMetricRelabelConfigs
+	if gmr == nil || len(gmr.RelabelConfigs) == 0 {
+		return src
+	}
+	if gmr.ExemptSelector != nil {
+		// invalid selector is rejected by webhook, global configs are applied in this case
+		selector, err := metav1.LabelSelectorAsSelector(gmr.ExemptSelector)
+		if err == nil && !selector.Empty() && selector.Matches(labels.Set(o.GetLabels())) {
+			return src
+		}
+	}
+	dst := make([]*vmv1beta1.RelabelConfig, 0, len(src)+len(gmr.RelabelConfigs))
+	dst = append(dst, src...)
+	return append(dst, gmr.RelabelConfigs...)
+}
+
 func addMetricRelabelingsTo(cfg yaml.MapSlice, src []*vmv1beta1.RelabelConfig, se vmv1beta1.VMAgentSecurityEnforcements) yaml.MapSlice {
 	if len(src) == 0 {
 		return cfg
@@ -421,6 +421,7 @@ func TestCreateOrUpdateConfigurationSecret(t *testing.T) {
 					Spec: vmv1beta1.VMStaticScrapeSpec{
 						TargetEndpoints: []*vmv1beta1.TargetEndpoint{
 							{
+								Targets: []string{"some-host:9100"},
 								EndpointScrapeParams: vmv1beta1.EndpointScrapeParams{
 									Path:     "/metrics-3",
 									Scheme:   "https",
@@ -510,10 +511,6 @@ scrape_configs:
   - source_labels:
     - __meta_kubernetes_service_name
     target_label: service
-  - source_labels:
-    - __meta_kubernetes_service_name
-    target_label: job
-    replacement: ${1}
   - source_labels:
     - __meta_kubernetes_service_label_app
     target_label: job
@@ -561,10 +558,6 @@ scrape_configs:
   - source_labels:
     - __meta_kubernetes_service_name
     target_label: service
-  - source_labels:
-    - __meta_kubernetes_service_name
-    target_label: job
-    replacement: ${1}
   - source_labels:
     - __meta_kubernetes_service_label_app
     target_label: job
@@ -603,8 +596,6 @@ scrape_configs:
   - source_labels:
     - __meta_kubernetes_pod_name
     target_label: pod
-  - target_label: job
-    replacement: default/test-vps
   - source_labels:
     - __meta_kubernetes_pod_label_app
     target_label: job
@@ -649,8 +640,6 @@ scrape_configs:
   - source_labels:
     - __meta_kubernetes_pod_name
     target_label: pod
-  - target_label: job
-    replacement: default/test-vps
   - source_labels:
     - __meta_kubernetes_pod_label_app
     target_label: job
@@ -686,14 +675,13 @@ scrape_configs:
   - source_labels:
     - __meta_kubernetes_node_name
     target_label: node
-  - target_label: job
-    replacement: default/test-vms
   basic_auth:
     username: some-username
     password: some-password
 - job_name: staticScrape/default/test-vmstatic/0
   static_configs:
-  - targets: []
+  - targets:
+    - some-host:9100
   honor_labels: false
   metrics_path: /metrics-3
   proxy_url: https://some-proxy-1
@@ -980,8 +968,6 @@ scrape_configs:
   - source_labels:
     - __meta_kubernetes_pod_name
     target_label: pod
-  - target_label: job
-    replacement: default/test-vps-good
   - source_labels:
     - __meta_kubernetes_pod_label_app
     target_label: job
@@ -997,8 +983,6 @@ scrape_configs:
   - source_labels:
     - __meta_kubernetes_node_name
     target_label: node
-  - target_label: job
-    replacement: default/test-good
 `,
 		},
 		{
@@ -1128,10 +1112,6 @@ scrape_configs:
   - source_labels:
     - __meta_kubernetes_service_name
     target_label: service
-  - source_labels:
-    - __meta_kubernetes_service_name
-    target_label: job
-    replacement: ${1}
   - source_labels:
     - __meta_kubernetes_service_label_app
     target_label: job
@@ -1179,10 +1159,6 @@ scrape_configs:
   - source_labels:
     - __meta_kubernetes_service_name
     target_label: service
-  - source_labels:
-    - __meta_kubernetes_service_name
-    target_label: job
-    replacement: ${1}
   - source_labels:
     - __meta_kubernetes_service_label_app
     target_label: job
@@ -1204,7 +1180,7 @@ scrape_configs:
 				}()
 			}
 			build.AddDefaults(testClient.Scheme())
-			if _, err := createOrUpdateConfigurationSecret(context.TODO(), testClient, tt.args.cr, nil, nil); (err != nil) != tt.wantErr {
+			if _, _, err := createOrUpdateConfigurationSecret(context.TODO(), testClient, tt.args.cr, nil, nil, nil); (err != nil) != tt.wantErr {
 				t.Errorf("CreateOrUpdateConfigurationSecret() error = %v, wantErr %v", err, tt.wantErr)
 			}
 			var expectSecret corev1.Secret
@@ -1227,3 +1203,301 @@ scrape_configs:
 		})
 	}
 }
+
+func Test_setScrapeLimitsTo(t *testing.T) {
+	f := func(limits *vmv1beta1.VMAgentGlobalScrapeLimits, params, want vmv1beta1.EndpointScrapeParams, wantWarning string) {
+		t.Helper()
+		cr := &vmv1beta1.VMAgent{Spec: vmv1beta1.VMAgentSpec{GlobalScrapeLimits: limits}}
+		var st vmv1beta1.StatusMetadata
+		setScrapeLimitsTo(&params, cr, &st, "serviceScrape/default/test/0")
+		assert.Equal(t, want, params)
+		assert.Equal(t, wantWarning, st.CurrentSyncWarning)
+	}
+	// no global limits
+	f(nil, vmv1beta1.EndpointScrapeParams{SampleLimit: 100}, vmv1beta1.EndpointScrapeParams{SampleLimit: 100}, "")
+	// global limits applied to params without limits
+	f(&vmv1beta1.VMAgentGlobalScrapeLimits{SampleLimit: 1000, SeriesLimit: 500},
+		vmv1beta1.EndpointScrapeParams{},
+		vmv1beta1.EndpointScrapeParams{SampleLimit: 1000, SeriesLimit: 500}, "")
+	// own limits have priority without enforce
+	f(&vmv1beta1.VMAgentGlobalScrapeLimits{SampleLimit: 1000, SeriesLimit: 500},
+		vmv1beta1.EndpointScrapeParams{SampleLimit: 5000, SeriesLimit: 100},
+		vmv1beta1.EndpointScrapeParams{SampleLimit: 5000, SeriesLimit: 100}, "")
+	// higher limits are reduced with enforce
+	f(&vmv1beta1.VMAgentGlobalScrapeLimits{SampleLimit: 1000, SeriesLimit: 500, Enforce: true},
+		vmv1beta1.EndpointScrapeParams{SampleLimit: 5000, SeriesLimit: 600},
+		vmv1beta1.EndpointScrapeParams{SampleLimit: 1000, SeriesLimit: 500},
+		"sampleLimit=5000 for job=serviceScrape/default/test/0 is reduced to the global limit=1000; seriesLimit=600 for job=serviceScrape/default/test/0 is reduced to the global limit=500")
+	// lower limits are kept with enforce
+	f(&vmv1beta1.VMAgentGlobalScrapeLimits{SampleLimit: 1000, Enforce: true},
+		vmv1beta1.EndpointScrapeParams{SampleLimit: 10, SeriesLimit: 600},
+		vmv1beta1.EndpointScrapeParams{SampleLimit: 10, SeriesLimit: 600}, "")
+}
+
+func Test_parseRelabelConfigs(t *testing.T) {
+	f := func(data string, want []*vmv1beta1.RelabelConfig, wantErr bool) {
+		t.Helper()
+		got, err := parseRelabelConfigs([]byte(data))
+		if (err != nil) != wantErr {
+			t.Fatalf("unexpected error: %v, wantErr: %v", err, wantErr)
+		}
+		assert.Equal(t, want, got)
+	}
+	f(`
+- source_labels: [__meta_kubernetes_pod_label_team]
+  target_label: team
+- action: drop
+  if: '{__meta_kubernetes_pod_phase="Pending"}'
+`, []*vmv1beta1.RelabelConfig{
+		{
+			UnderScoreSourceLabels: []string{"__meta_kubernetes_pod_label_team"},
+			SourceLabels:           []string{"__meta_kubernetes_pod_label_team"},
+			UnderScoreTargetLabel:  "team",
+			TargetLabel:            "team",
+		},
+		{
+			Action: "drop",
+			If:     vmv1beta1.StringOrArray{`{__meta_kubernetes_pod_phase="Pending"}`},
+		},
+	}, false)
+	// unknown field
+	f(`
+- source_label: [team]
+  target_label: team
+`, nil, true)
+	// unsupported action
+	f(`
+- action: unknown
+`, nil, true)
+	// invalid regex
+	f(`
+- source_labels: [team]
+  regex: "team(.+"
+  action: keep
+`, nil, true)
+}
+
+func TestLoadRelabelConfigRefs(t *testing.T) {
+	newPodScrape := func(name string, refs ...corev1.ConfigMapKeySelector) *vmv1beta1.VMPodScrape {
+		return &vmv1beta1.VMPodScrape{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: vmv1beta1.VMPodScrapeSpec{
+				PodMetricsEndpoints: []vmv1beta1.PodMetricsEndpoint{{Port: ptr.To("http")}},
+				RelabelConfigRefs:   refs,
+			},
+		}
+	}
+	ref := func(name, key string) corev1.ConfigMapKeySelector {
+		return corev1.ConfigMapKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: name}, Key: key}
+	}
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "relabel", Namespace: "default"},
+		Data: map[string]string{
+			"valid": `
+- source_labels: [__meta_kubernetes_pod_label_team]
+  target_label: team
+`,
+			"invalid": `
+- action: unknown
+`,
+		},
+	}
+	fclient := k8stools.GetTestClientWithObjects([]runtime.Object{cm})
+	sos := &scrapeObjects{
+		pss: []*vmv1beta1.VMPodScrape{
+			newPodScrape("valid", ref("relabel", "valid")),
+			newPodScrape("invalid-content", ref("relabel", "invalid")),
+			newPodScrape("missing-key", ref("relabel", "missing")),
+			newPodScrape("missing-configmap", ref("missing", "valid")),
+		},
+	}
+	ssCache, err := loadScrapeSecrets(context.Background(), fclient, sos, "default", nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if assert.Len(t, sos.pss, 1) {
+		assert.Equal(t, "valid", sos.pss[0].Name)
+	}
+	var brokenNames []string
+	for _, o := range sos.pssBroken {
+		brokenNames = append(brokenNames, o.Name)
+		assert.NotEmpty(t, o.Status.CurrentSyncError)
+	}
+	assert.Equal(t, []string{"invalid-content", "missing-key", "missing-configmap"}, brokenNames)
+
+	sc := generatePodScrapeConfig(context.Background(), &vmv1beta1.VMAgent{}, sos.pss[0], sos.pss[0].Spec.PodMetricsEndpoints[0], 0, nil, ssCache, vmv1beta1.VMAgentSecurityEnforcements{})
+	data, err := yaml.Marshal(sc)
+	if err != nil {
+		t.Fatalf("cannot marshal scrape config: %s", err)
+	}
+	assert.Contains(t, string(data), `- source_labels:
+  - __meta_kubernetes_pod_label_team
+  target_label: team
+`)
+}
+
+func TestLoadScrapeHeaders(t *testing.T) {
+	newStaticScrape := func(name string, ep vmv1beta1.TargetEndpoint) *vmv1beta1.VMStaticScrape {
+		ep.Targets = []string{"host:9100"}
+		return &vmv1beta1.VMStaticScrape{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: vmv1beta1.VMStaticScrapeSpec{
+				TargetEndpoints: []*vmv1beta1.TargetEndpoint{&ep},
+			},
+		}
+	}
+	secretHeader := func(name, secret, key string) vmv1beta1.ScrapeHeader {
+		return vmv1beta1.ScrapeHeader{
+			Name: name,
+			ValueFrom: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: secret},
+				Key:                  key,
+			},
+		}
+	}
+	s := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "default"},
+		Data:       map[string][]byte{"key": []byte("secret-value")},
+	}
+	fclient := k8stools.GetTestClientWithObjects([]runtime.Object{s})
+	sos := &scrapeObjects{
+		stss: []*vmv1beta1.VMStaticScrape{
+			newStaticScrape("valid", vmv1beta1.TargetEndpoint{
+				EndpointScrapeParams: vmv1beta1.EndpointScrapeParams{
+					EnableHTTP2: ptr.To(false),
+					Headers: []vmv1beta1.ScrapeHeader{
+						{Name: "X-Tenant", Value: "team-a"},
+						secretHeader("X-Api-Key", "api", "key"),
+					},
+					VMScrapeParams: &vmv1beta1.VMScrapeParams{Headers: []string{"X-Scope: global"}},
+				},
+			}),
+			newStaticScrape("missing-secret", vmv1beta1.TargetEndpoint{
+				EndpointScrapeParams: vmv1beta1.EndpointScrapeParams{
+					Headers: []vmv1beta1.ScrapeHeader{secretHeader("X-Api-Key", "missing", "key")},
+				},
+			}),
+			newStaticScrape("empty-header", vmv1beta1.TargetEndpoint{
+				EndpointScrapeParams: vmv1beta1.EndpointScrapeParams{
+					Headers: []vmv1beta1.ScrapeHeader{{Name: "X-Tenant"}},
+				},
+			}),
+			newStaticScrape("http2", vmv1beta1.TargetEndpoint{
+				EndpointScrapeParams: vmv1beta1.EndpointScrapeParams{
+					EnableHTTP2: ptr.To(true),
+				},
+			}),
+		},
+	}
+	ssCache, err := loadScrapeSecrets(context.Background(), fclient, sos, "default", nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if assert.Len(t, sos.stss, 1) {
+		assert.Equal(t, "valid", sos.stss[0].Name)
+	}
+	brokenErrs := make(map[string]string)
+	for _, o := range sos.stssBroken {
+		brokenErrs[o.Name] = o.Status.CurrentSyncError
+	}
+	assert.Len(t, brokenErrs, 3)
+	assert.Contains(t, brokenErrs["empty-header"], `value or valueFrom must be set for header="X-Tenant"`)
+	assert.Contains(t, brokenErrs["http2"], "enableHTTP2=true is not supported")
+
+	sc := generateStaticScrapeConfig(context.Background(), &vmv1beta1.VMAgent{}, sos.stss[0], sos.stss[0].Spec.TargetEndpoints[0], 0, ssCache, vmv1beta1.VMAgentSecurityEnforcements{})
+	data, err := yaml.Marshal(sc)
+	if err != nil {
+		t.Fatalf("cannot marshal scrape config: %s", err)
+	}
+	assert.Contains(t, string(data), `headers:
+- 'X-Scope: global'
+- 'X-Tenant: team-a'
+- 'X-Api-Key: secret-value'
+`)
+}
+
+func TestMakeConfigSecretRemoteWriteProxy(t *testing.T) {
+	f := func(ba *k8stools.BasicAuthCredentials, want string) {
+		t.Helper()
+		rw := vmv1beta1.VMAgentRemoteWriteSpec{
+			URL:            "http://remote-write",
+			ProxyURL:       ptr.To("http://proxy:3128"),
+			ProxyBasicAuth: &vmv1beta1.BasicAuth{},
+		}
+		cr := &vmv1beta1.VMAgent{
+			ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "default"},
+			Spec:       vmv1beta1.VMAgentSpec{RemoteWrite: []vmv1beta1.VMAgentRemoteWriteSpec{{URL: "http://other"}, rw}},
+		}
+		ssCache := &scrapesSecretsCache{
+			baSecrets: map[string]*k8stools.BasicAuthCredentials{rw.AsProxyKey(): ba},
+		}
+		s := makeConfigSecret(cr, ssCache)
+		assert.Equal(t, want, string(s.Data["RWS_1-SECRET-PROXYBASICAUTH"]))
+	}
+	// username only
+	f(&k8stools.BasicAuthCredentials{Username: "user"}, "user")
+	// credentials with special chars are escaped
+	f(&k8stools.BasicAuthCredentials{Username: "user@corp", Password: "p@ss:w/rd"}, "user%40corp:p%40ss%3Aw%2Frd")
+}
+
+func TestWithGlobalMetricRelabelConfigs(t *testing.T) {
+	own := &vmv1beta1.RelabelConfig{Action: "keep", SourceLabels: []string{"__name__"}, Regex: vmv1beta1.StringOrArray{"node_.*"}}
+	classDrop := &vmv1beta1.RelabelConfig{Action: "drop", SourceLabels: []string{"__name__"}, Regex: vmv1beta1.StringOrArray{"istio_.*"}}
+	globalDrop := &vmv1beta1.RelabelConfig{Action: "drop", SourceLabels: []string{"__name__"}, Regex: vmv1beta1.StringOrArray{"go_.*"}}
+	f := func(gmr *vmv1beta1.VMAgentGlobalMetricRelabelConfigs, objLabels map[string]string, src, want []*vmv1beta1.RelabelConfig) {
+		t.Helper()
+		cr := &vmv1beta1.VMAgent{Spec: vmv1beta1.VMAgentSpec{GlobalMetricRelabelConfigs: gmr}}
+		o := &vmv1beta1.VMPodScrape{ObjectMeta: metav1.ObjectMeta{Name: "pods", Namespace: "default", Labels: objLabels}}
+		assert.Equal(t, want, withGlobalMetricRelabelConfigs(cr, o, src))
+	}
+	// not defined
+	f(nil, nil, []*vmv1beta1.RelabelConfig{own}, []*vmv1beta1.RelabelConfig{own})
+	// global configs are applied after scrape class and own configs
+	f(&vmv1beta1.VMAgentGlobalMetricRelabelConfigs{RelabelConfigs: []*vmv1beta1.RelabelConfig{globalDrop}}, nil,
+		[]*vmv1beta1.RelabelConfig{classDrop, own}, []*vmv1beta1.RelabelConfig{classDrop, own, globalDrop})
+	// without own configs
+	f(&vmv1beta1.VMAgentGlobalMetricRelabelConfigs{RelabelConfigs: []*vmv1beta1.RelabelConfig{globalDrop}}, nil,
+		nil, []*vmv1beta1.RelabelConfig{globalDrop})
+	// empty exempt selector doesn't match any object
+	f(&vmv1beta1.VMAgentGlobalMetricRelabelConfigs{
+		RelabelConfigs: []*vmv1beta1.RelabelConfig{globalDrop},
+		ExemptSelector: &metav1.LabelSelector{},
+	}, map[string]string{"team": "runtime"}, []*vmv1beta1.RelabelConfig{own}, []*vmv1beta1.RelabelConfig{own, globalDrop})
+	// exempted object
+	f(&vmv1beta1.VMAgentGlobalMetricRelabelConfigs{
+		RelabelConfigs: []*vmv1beta1.RelabelConfig{globalDrop},
+		ExemptSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "runtime"}},
+	}, map[string]string{"team": "runtime"}, []*vmv1beta1.RelabelConfig{own}, []*vmv1beta1.RelabelConfig{own})
+	// not exempted object
+	f(&vmv1beta1.VMAgentGlobalMetricRelabelConfigs{
+		RelabelConfigs: []*vmv1beta1.RelabelConfig{globalDrop},
+		ExemptSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "runtime"}},
+	}, map[string]string{"team": "infra"}, []*vmv1beta1.RelabelConfig{own}, []*vmv1beta1.RelabelConfig{own, globalDrop})
+}
+
+func TestFilterNodeLocalScrapeObjects(t *testing.T) {
+	meta := func(name string) metav1.ObjectMeta {
+		return metav1.ObjectMeta{Name: name, Namespace: "default"}
+	}
+	endpoints := &vmv1beta1.VMServiceScrape{ObjectMeta: meta("endpoints")}
+	pods := &vmv1beta1.VMPodScrape{ObjectMeta: meta("pods")}
+	nodes := &vmv1beta1.VMNodeScrape{ObjectMeta: meta("nodes")}
+	sos := &scrapeObjects{
+		sss: []*vmv1beta1.VMServiceScrape{
+			endpoints,
+			{ObjectMeta: meta("services"), Spec: vmv1beta1.VMServiceScrapeSpec{DiscoveryRole: kubernetesSDRoleService}},
+		},
+		pss:  []*vmv1beta1.VMPodScrape{pods},
+		nss:  []*vmv1beta1.VMNodeScrape{nodes},
+		prss: []*vmv1beta1.VMProbe{{ObjectMeta: meta("probe")}},
+		stss: []*vmv1beta1.VMStaticScrape{{ObjectMeta: meta("static")}},
+		scss: []*vmv1beta1.VMScrapeConfig{{ObjectMeta: meta("config")}},
+	}
+	filterNodeLocalScrapeObjects(context.Background(), sos)
+	assert.Equal(t, []*vmv1beta1.VMServiceScrape{endpoints}, sos.sss)
+	assert.Equal(t, []*vmv1beta1.VMPodScrape{pods}, sos.pss)
+	assert.Equal(t, []*vmv1beta1.VMNodeScrape{nodes}, sos.nss)
+	assert.Empty(t, sos.prss)
+	assert.Empty(t, sos.stss)
+	assert.Empty(t, sos.scss)
+}
@@ -618,7 +618,7 @@ func TestCreateOrUpdateVMAgent(t *testing.T) {
 			build.AddDefaults(fclient.Scheme())
 			fclient.Scheme().Default(tt.args.cr)
 			go func() {
-				err := CreateOrUpdateVMAgent(context.TODO(), tt.args.cr, fclient)
+				err := CreateOrUpdateVMAgent(context.TODO(), tt.args.cr, fclient, nil)
 				select {
 				case errC <- err:
 				default:
@@ -1176,6 +1176,32 @@ func TestBuildRemoteWrites(t *testing.T) {
 			},
 			want: []string{"-remoteWrite.url=localhost:8429,localhost:8431,localhost:8432", "-remoteWrite.maxDiskUsagePerURL=1500MB,500MB,1073741824"},
 		},
+		{
+			name: "test maxDiskUsage with global setting",
+			args: args{
+				ssCache: &scrapesSecretsCache{},
+				cr: &vmv1beta1.VMAgent{
+					Spec: vmv1beta1.VMAgentSpec{
+						RemoteWrite: []vmv1beta1.VMAgentRemoteWriteSpec{
+							{
+								URL: "localhost:8429",
+							},
+							{
+								URL:          "localhost:8431",
+								MaxDiskUsage: ptr.To("5GiB"),
+							},
+							{
+								URL: "localhost:8432",
+							},
+						},
+						RemoteWriteSettings: &vmv1beta1.VMAgentRemoteWriteSettings{
+							MaxDiskUsagePerURL: ptr.To(int64(2000)),
+						},
+					},
+				},
+			},
+			want: []string{"-remoteWrite.url=localhost:8429,localhost:8431,localhost:8432", "-remoteWrite.maxDiskUsagePerURL=2000,5GiB,2000"},
+		},
 		{
 			name: "test maxDiskUsage in extraArgs is not overwritten",
 			args: args{
@@ -1396,6 +1422,36 @@ func TestBuildRemoteWrites(t *testing.T) {
 				`-remoteWrite.url=localhost:8431`,
 			},
 		},
+		{
+			name: "test with stream aggr windows",
+			args: args{
+				ssCache: &scrapesSecretsCache{},
+				cr: &vmv1beta1.VMAgent{
+					Spec: vmv1beta1.VMAgentSpec{RemoteWrite: []vmv1beta1.VMAgentRemoteWriteSpec{
+						{
+							URL: "localhost:8429",
+						},
+						{
+							URL: "localhost:8431",
+							StreamAggrConfig: &vmv1beta1.StreamAggrConfig{
+								Rules: []vmv1beta1.StreamAggrRule{
+									{
+										Interval: "1m",
+										Outputs:  []string{"total"},
+									},
+								},
+								EnableWindows: true,
+							},
+						},
+					}},
+				},
+			},
+			want: []string{
+				`-remoteWrite.streamAggr.config=,/etc/vm/stream-aggr/RWS_1-CM-STREAM-AGGR-CONF`,
+				`-remoteWrite.streamAggr.enableWindows=false,true`,
+				`-remoteWrite.url=localhost:8429,localhost:8431`,
+			},
+		},
 		{
 			name: "test with stream aggr (one remote write with defaults)",
 			args: args{
@@ -1469,10 +1525,93 @@ func TestBuildRemoteWrites(t *testing.T) {
 				`-remoteWrite.url=localhost:8428,localhost:8429,localhost:8430,localhost:8431,localhost:8432`,
 			},
 		},
+		{
+			name: "test proxyURL with basicAuth",
+			args: args{
+				ssCache: &scrapesSecretsCache{},
+				cr: &vmv1beta1.VMAgent{
+					Spec: vmv1beta1.VMAgentSpec{RemoteWrite: []vmv1beta1.VMAgentRemoteWriteSpec{
+						{
+							URL:      "localhost:8429",
+							ProxyURL: ptr.To("http://proxy:3128"),
+						},
+						{
+							URL: "localhost:8430",
+						},
+						{
+							URL:      "localhost:8431",
+							ProxyURL: ptr.To("https://proxy-2:3129"),
+							ProxyBasicAuth: &vmv1beta1.BasicAuth{
+								Username: corev1.SecretKeySelector{
+									LocalObjectReference: corev1.LocalObjectReference{Name: "proxy-auth"},
+									Key:                  "username",
+								},
+							},
+						},
+					}},
+				},
+			},
+			want: []string{
+				`-remoteWrite.proxyURL=http://proxy:3128,,https://%{VMAGENT_RWS_2_PROXY_BASIC_AUTH}@proxy-2:3129`,
+				`-remoteWrite.url=localhost:8429,localhost:8430,localhost:8431`,
+			},
+		},
+		{
+			name: "test kafka and pubsub",
+			args: args{
+				ssCache: &scrapesSecretsCache{
+					baSecrets: map[string]*k8stools.BasicAuthCredentials{
+						"remoteWrite-kafka://kafka-0:9092/?bootstrap.servers=kafka-0%3A9092%2Ckafka-1%3A9092&sasl.mechanisms=SCRAM-SHA-512&security.protocol=SASL_SSL&topic=metrics": {
+							Username: "user",
+							Password: "pass",
+						},
+					},
+				},
+				cr: &vmv1beta1.VMAgent{
+					Spec: vmv1beta1.VMAgentSpec{RemoteWrite: []vmv1beta1.VMAgentRemoteWriteSpec{
+						{
+							Kafka: &vmv1beta1.VMAgentRemoteWriteKafka{
+								Brokers:       []string{"kafka-0:9092", "kafka-1:9092"},
+								Topic:         "metrics",
+								SASLMechanism: "SCRAM-SHA-512",
+								SASLAuth: &vmv1beta1.BasicAuth{
+									Username: corev1.SecretKeySelector{
+										LocalObjectReference: corev1.LocalObjectReference{Name: "kafka-auth"},
+										Key:                  "username",
+									},
+								},
+							},
+							TLSConfig: &vmv1beta1.TLSConfig{InsecureSkipVerify: true},
+						},
+						{
+							URL: "localhost:8429",
+						},
+						{
+							PubSub: &vmv1beta1.VMAgentRemoteWritePubSub{
+								Project: "my-project",
+								Topic:   "metrics",
+								CredentialsSecret: &corev1.SecretKeySelector{
+									LocalObjectReference: corev1.LocalObjectReference{Name: "gcp-creds"},
+									Key:                  "credentials.json",
+								},
+							},
+						},
+					}},
+				},
+			},
+			want: []string{
+				`-gcp.pubsub.publish.credentialsFile=,,/etc/vmagent/config/RWS_2-SECRET-PUBSUBCREDENTIALS`,
+				`-remoteWrite.basicAuth.passwordFile=/etc/vmagent/config/RWS_0-SECRET-BASICAUTHPASSWORD,,`,
+				`-remoteWrite.basicAuth.username="user","",""`,
+				`-remoteWrite.tlsInsecureSkipVerify=true,false,false`,
+				`-remoteWrite.url=kafka://kafka-0:9092/?bootstrap.servers=kafka-0%3A9092%2Ckafka-1%3A9092&sasl.mechanisms=SCRAM-SHA-512&security.protocol=SASL_SSL&topic=metrics,localhost:8429,pubsub:projects/my-project/topics/metrics`,
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			sort.Strings(tt.want)
+			setStructuredRemoteWrites(tt.args.cr)
 			got := buildRemoteWrites(tt.args.cr, tt.args.ssCache)
 			sort.Strings(got)
 			assert.Equal(t, tt.want, got)
@@ -1952,6 +2091,98 @@ func TestCreateOrUpdateStreamAggrConfig(t *testing.T) {
 			},
 			predefinedObjects: []runtime.Object{},
 		},
+		{
+			name: "inline and configmap stream aggr rules",
+			args: args{
+				ctx: context.TODO(),
+				cr: &vmv1beta1.VMAgent{
+					ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "default"},
+					Spec: vmv1beta1.VMAgentSpec{
+						StreamAggrConfig: &vmv1beta1.StreamAggrConfig{
+							Rules: []vmv1beta1.StreamAggrRule{{
+								Interval: "1m",
+								Outputs:  []string{"total"},
+							}},
+							RuleConfigMap: &corev1.ConfigMapKeySelector{
+								LocalObjectReference: corev1.LocalObjectReference{Name: "aggr-rules"},
+								Key:                  "rules.yaml",
+							},
+						},
+						RemoteWrite: []vmv1beta1.VMAgentRemoteWriteSpec{{URL: "localhost:8429"}},
+					},
+				},
+			},
+			validate: func(cm *corev1.ConfigMap) error {
+				want := `- interval: 1m
+  outputs:
+  - total
+- interval: 5m
+  outputs: [count_samples]
+`
+				assert.Equal(t, want, cm.Data["global_aggregation.yaml"])
+				return nil
+			},
+			predefinedObjects: []runtime.Object{
+				&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Name: "aggr-rules", Namespace: "default"},
+					Data: map[string]string{"rules.yaml": `- interval: 5m
+  outputs: [count_samples]
+`},
+				},
+			},
+		},
+		{
+			name: "configmap stream aggr rule without outputs",
+			args: args{
+				ctx: context.TODO(),
+				cr: &vmv1beta1.VMAgent{
+					ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "default"},
+					Spec: vmv1beta1.VMAgentSpec{
+						RemoteWrite: []vmv1beta1.VMAgentRemoteWriteSpec{{
+							URL: "localhost:8429",
+							StreamAggrConfig: &vmv1beta1.StreamAggrConfig{
+								RuleConfigMap: &corev1.ConfigMapKeySelector{
+									LocalObjectReference: corev1.LocalObjectReference{Name: "aggr-rules"},
+									Key:                  "rules.yaml",
+								},
+							},
+						}},
+					},
+				},
+			},
+			predefinedObjects: []runtime.Object{
+				&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Name: "aggr-rules", Namespace: "default"},
+					Data:       map[string]string{"rules.yaml": "- interval: 5m\n"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "configmap with invalid stream aggr yaml",
+			args: args{
+				ctx: context.TODO(),
+				cr: &vmv1beta1.VMAgent{
+					ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "default"},
+					Spec: vmv1beta1.VMAgentSpec{
+						StreamAggrConfig: &vmv1beta1.StreamAggrConfig{
+							RuleConfigMap: &corev1.ConfigMapKeySelector{
+								LocalObjectReference: corev1.LocalObjectReference{Name: "aggr-rules"},
+								Key:                  "rules.yaml",
+							},
+						},
+						RemoteWrite: []vmv1beta1.VMAgentRemoteWriteSpec{{URL: "localhost:8429"}},
+					},
+				},
+			},
+			predefinedObjects: []runtime.Object{
+				&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Name: "aggr-rules", Namespace: "default"},
+					Data:       map[string]string{"rules.yaml": "interval: 5m"},
+				},
+			},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -1959,6 +2190,9 @@ func TestCreateOrUpdateStreamAggrConfig(t *testing.T) {
 			if err := createOrUpdateStreamAggrConfig(tt.args.ctx, cl, tt.args.cr, nil); (err != nil) != tt.wantErr {
 				t.Fatalf("CreateOrUpdateVMAgentStreamAggrConfig() error = %v, wantErr %v", err, tt.wantErr)
 			}
+			if tt.wantErr {
+				return
+			}
 			var createdCM corev1.ConfigMap
 			if err := cl.Get(tt.args.ctx, types.NamespacedName{Namespace: tt.args.cr.Namespace, Name: tt.args.cr.StreamAggrConfigName()}, &createdCM); err != nil {
 				t.Fatalf("cannot fetch created cm: %v", err)
@@ -2140,6 +2374,26 @@ func TestBuildRemoteWriteSettings(t *testing.T) {
 			},
 			want: []string{"-remoteWrite.tmpDataPath=/tmp/vmagent-remotewrite-data"},
 		},
+		{
+			name: "maxDiskUsage set in extraArgs",
+			args: args{
+				cr: &vmv1beta1.VMAgent{
+					Spec: vmv1beta1.VMAgentSpec{
+						CommonApplicationDeploymentParams: vmv1beta1.CommonApplicationDeploymentParams{
+							ExtraArgs: map[string]string{
+								"loggerLevel":                    "INFO",
+								"remoteWrite.maxDiskUsagePerURL": "1GB",
+								"remoteWrite.showURL":            "true",
+							},
+						},
+						RemoteWriteSettings: &vmv1beta1.VMAgentRemoteWriteSettings{
+							MaxDiskUsagePerURL: ptr.To(int64(1000)),
+						},
+					},
+				},
+			},
+			want: []string{"-remoteWrite.tmpDataPath=/tmp/vmagent-remotewrite-data"},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -2441,3 +2695,174 @@ containers:
 serviceaccountname: vmagent-agent
 `)
 }
+
+func TestNewDeployForVMAgentDaemonSetMode(t *testing.T) {
+	cr := &vmv1beta1.VMAgent{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "agent",
+			Namespace: "default",
+		},
+		Spec: vmv1beta1.VMAgentSpec{
+			RemoteWrite: []vmv1beta1.VMAgentRemoteWriteSpec{
+				{URL: "http://some-url"},
+			},
+			DaemonSetMode: true,
+		},
+	}
+	scheme := k8stools.GetTestClientWithObjects(nil).Scheme()
+	build.AddDefaults(scheme)
+	scheme.Default(cr)
+	got, err := newDeployForVMAgent(cr, &scrapesSecretsCache{})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	ds, ok := got.(*appsv1.DaemonSet)
+	if !ok {
+		t.Fatalf("unexpected object type, want DaemonSet, got: %T", got)
+	}
+	assert.Equal(t, cr.SelectorLabels(), ds.Spec.Selector.MatchLabels)
+	for _, cnt := range ds.Spec.Template.Spec.Containers {
+		var nodeEnv *corev1.EnvVar
+		for idx := range cnt.Env {
+			if cnt.Env[idx].Name == vmAgentNodeNameEnv {
+				nodeEnv = &cnt.Env[idx]
+			}
+		}
+		if assert.NotNil(t, nodeEnv, "container=%s must have node name env", cnt.Name) {
+			assert.Equal(t, "spec.nodeName", nodeEnv.ValueFrom.FieldRef.FieldPath)
+		}
+		if cnt.Name == "vmagent" {
+			assert.Contains(t, cnt.Args, "-promscrape.kubernetes.attachNodeMetadataAll=true")
+		}
+	}
+}
+
+func TestMakeSpecForAgentScrapeTokenProjection(t *testing.T) {
+	cr := &vmv1beta1.VMAgent{
+		ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "default"},
+		Spec: vmv1beta1.VMAgentSpec{
+			ConfigCheckInterval: "30s",
+			ServiceScrapeTokenProjection: &vmv1beta1.VMAgentTokenProjection{
+				Audience:          "metrics",
+				ExpirationSeconds: ptr.To[int64](3600),
+			},
+		},
+	}
+	scheme := k8stools.GetTestClientWithObjects(nil).Scheme()
+	build.AddDefaults(scheme)
+	scheme.Default(cr)
+	got, err := makeSpecForVMAgent(cr, &scrapesSecretsCache{})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.Contains(t, got.Volumes, corev1.Volume{
+		Name: "scrape-token",
+		VolumeSource: corev1.VolumeSource{
+			Projected: &corev1.ProjectedVolumeSource{
+				Sources: []corev1.VolumeProjection{{
+					ServiceAccountToken: &corev1.ServiceAccountTokenProjection{
+						Audience:          "metrics",
+						ExpirationSeconds: ptr.To[int64](3600),
+						Path:              "token",
+					},
+				}},
+			},
+		},
+	})
+	var agent *corev1.Container
+	for i := range got.Containers {
+		if got.Containers[i].Name == "vmagent" {
+			agent = &got.Containers[i]
+		}
+	}
+	if agent == nil {
+		t.Fatalf("vmagent container is missing")
+	}
+	assert.Contains(t, agent.VolumeMounts, corev1.VolumeMount{
+		Name:      "scrape-token",
+		ReadOnly:  true,
+		MountPath: "/var/run/secrets/operator.victoriametrics.com/scrape-token",
+	})
+	assert.Contains(t, agent.Args, "-promscrape.configCheckInterval=30s")
+}
+
+func Test_addShardRelabelingsToServiceScrape(t *testing.T) {
+	f := func(spec vmv1beta1.VMAgentSpec, want []*vmv1beta1.RelabelConfig) {
+		t.Helper()
+		cr := &vmv1beta1.VMAgent{
+			ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "default"},
+			Spec:       spec,
+		}
+		vss := &vmv1beta1.VMServiceScrape{
+			Spec: vmv1beta1.VMServiceScrapeSpec{
+				Endpoints: []vmv1beta1.Endpoint{{Port: "http"}},
+			},
+		}
+		addShardRelabelingsToServiceScrape(cr, vss)
+		assert.Equal(t, want, vss.Spec.Endpoints[0].RelabelConfigs)
+	}
+	shardRelabeling := &vmv1beta1.RelabelConfig{
+		SourceLabels: []string{"__meta_kubernetes_pod_label_shard_num"},
+		TargetLabel:  "shard",
+	}
+
+	// not sharded
+	f(vmv1beta1.VMAgentSpec{}, nil)
+	f(vmv1beta1.VMAgentSpec{ShardCount: ptr.To(1)}, nil)
+
+	// daemonset ignores shards
+	f(vmv1beta1.VMAgentSpec{ShardCount: ptr.To(2), DaemonSetMode: true}, nil)
+
+	// sharded deployment
+	f(vmv1beta1.VMAgentSpec{ShardCount: ptr.To(2)}, []*vmv1beta1.RelabelConfig{shardRelabeling})
+
+	// sharded statefulset
+	f(vmv1beta1.VMAgentSpec{ShardCount: ptr.To(2), StatefulMode: true}, []*vmv1beta1.RelabelConfig{
+		shardRelabeling,
+		{
+			SourceLabels: []string{"__meta_kubernetes_pod_name"},
+			Regex:        vmv1beta1.StringOrArray{`.+-(\d+)`},
+			Replacement:  ptr.To("$1"),
+			TargetLabel:  "replica",
+		},
+	})
+}
+
+func Test_expandRemoteWrites(t *testing.T) {
+	cr := &vmv1beta1.VMAgent{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+		Spec: vmv1beta1.VMAgentSpec{
+			RemoteWrite: []vmv1beta1.VMAgentRemoteWriteSpec{
+				{PubSub: &vmv1beta1.VMAgentRemoteWritePubSub{Project: "my-project", Topic: "metrics"}},
+			},
+			TenantRouting: &vmv1beta1.VMAgentTenantRouting{
+				URLTemplate:    "http://vminsert:8480/insert/{{tenant}}/prometheus/api/v1/write",
+				NamespaceLabel: "tenant",
+			},
+		},
+	}
+	cr.ParsedLastAppliedSpec = cr.Spec.DeepCopy()
+	prevCR := cr.DeepCopy()
+	prevCR.Spec = *cr.ParsedLastAppliedSpec.DeepCopy()
+	fclient := k8stools.GetTestClientWithObjects([]runtime.Object{
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a", Labels: map[string]string{"tenant": "1"}}},
+	})
+	if err := expandRemoteWrites(context.TODO(), fclient, cr, prevCR); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	// unchanged spec must produce the same remote writes, otherwise deployment is updated on each reconcile
+	assert.Len(t, cr.Spec.RemoteWrite, 2)
+	assert.NotEmpty(t, cr.Spec.RemoteWrite[0].URL)
+	assert.Equal(t, cr.Spec.RemoteWrite, prevCR.Spec.RemoteWrite)
+	newDeploy, err := newDeployForVMAgent(cr, &scrapesSecretsCache{})
+	if err != nil {
+		t.Fatalf("cannot build deployment: %s", err)
+	}
+	prevDeploy, err := newDeployForVMAgent(prevCR, &scrapesSecretsCache{})
+	if err != nil {
+		t.Fatalf("cannot build previous deployment: %s", err)
+	}
+	assert.Equal(t, newDeploy, prevDeploy)
+	// parsed spec must be kept as is
+	assert.Empty(t, cr.ParsedLastAppliedSpec.RemoteWrite[0].URL)
+}
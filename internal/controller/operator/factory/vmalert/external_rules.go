@@ -0,0 +1,195 @@
+package vmalert
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/k8stools"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// externalRulesPrefix is a prefix of directories and volumes for spec.externalRuleSources
+const externalRulesPrefix = "external-"
+
+// externalRuleSourceName returns unique name of the given external rule source
+func externalRuleSourceName(src vmv1beta1.VMAlertExternalRuleSource) string {
+	if src.Secret != nil {
+		return "secret-" + src.Secret.Name
+	}
+	return "configmap-" + src.ConfigMap.Name
+}
+
+// externalRuleSourceDir returns directory for rule files of the given external rule source
+func externalRuleSourceDir(src vmv1beta1.VMAlertExternalRuleSource) string {
+	return path.Join(vmAlertConfigDir, externalRulesPrefix+externalRuleSourceName(src))
+}
+
+// externalRuleSourceVolumeName returns name of volume for the given external rule source
+func externalRuleSourceVolumeName(src vmv1beta1.VMAlertExternalRuleSource) string {
+	return k8stools.SanitizeVolumeName(externalRulesPrefix + externalRuleSourceName(src))
+}
+
+// isExternalRuleFile checks if the given rule file path belongs to spec.externalRuleSources
+func isExternalRuleFile(file string) bool {
+	return strings.HasPrefix(file, path.Join(vmAlertConfigDir, externalRulesPrefix))
+}
+
+// buildExternalRuleSourcesVolumes returns volumes and mounts for spec.externalRuleSources.
+// Volumes are optional, missing objects are reported with ExternalRuleSourcesMissing condition
+// and must not block vmalert pods start
+func buildExternalRuleSourcesVolumes(cr *vmv1beta1.VMAlert) ([]corev1.Volume, []corev1.VolumeMount) {
+	var volumes []corev1.Volume
+	var mounts []corev1.VolumeMount
+	for _, src := range cr.Spec.ExternalRuleSources {
+		var items []corev1.KeyToPath
+		for _, key := range src.Keys {
+			items = append(items, corev1.KeyToPath{Key: key, Path: key})
+		}
+		var vs corev1.VolumeSource
+		if src.Secret != nil {
+			vs.Secret = &corev1.SecretVolumeSource{
+				SecretName: src.Secret.Name,
+				Items:      items,
+				Optional:   ptr.To(true),
+			}
+		} else {
+			vs.ConfigMap = &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: src.ConfigMap.Name},
+				Items:                items,
+				Optional:             ptr.To(true),
+			}
+		}
+		volumes = append(volumes, corev1.Volume{
+			Name:         externalRuleSourceVolumeName(src),
+			VolumeSource: vs,
+		})
+		mounts = append(mounts, corev1.VolumeMount{
+			Name:      externalRuleSourceVolumeName(src),
+			ReadOnly:  true,
+			MountPath: externalRuleSourceDir(src),
+		})
+	}
+	return volumes, mounts
+}
+
+// buildExternalRuleSourcesArgs returns -rule flags for spec.externalRuleSources
+func buildExternalRuleSourcesArgs(cr *vmv1beta1.VMAlert) []string {
+	var args []string
+	for _, src := range cr.Spec.ExternalRuleSources {
+		dir := externalRuleSourceDir(src)
+		if len(src.Keys) == 0 {
+			args = append(args, fmt.Sprintf("-rule=%q", path.Join(dir, "*.yaml")))
+			continue
+		}
+		for _, key := range src.Keys {
+			args = append(args, fmt.Sprintf("-rule=%q", path.Join(dir, key)))
+		}
+	}
+	return args
+}
+
+// externalRuleSourceData returns rule files of the given external rule source.
+// It reports whether object exists
+func externalRuleSourceData(ctx context.Context, rclient client.Client, ns string, src vmv1beta1.VMAlertExternalRuleSource) (map[string]string, bool, error) {
+	data := make(map[string]string)
+	nsn := types.NamespacedName{Namespace: ns}
+	if src.Secret != nil {
+		nsn.Name = src.Secret.Name
+		var s corev1.Secret
+		if err := rclient.Get(ctx, nsn, &s); err != nil {
+			if errors.IsNotFound(err) {
+				return nil, false, nil
+			}
+			return nil, false, fmt.Errorf("cannot get external rules Secret=%s: %w", nsn.Name, err)
+		}
+		for k, v := range s.Data {
+			data[k] = string(v)
+		}
+		return data, true, nil
+	}
+	nsn.Name = src.ConfigMap.Name
+	var cm corev1.ConfigMap
+	if err := rclient.Get(ctx, nsn, &cm); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("cannot get external rules ConfigMap=%s: %w", nsn.Name, err)
+	}
+	for k, v := range cm.Data {
+		data[k] = v
+	}
+	for k, v := range cm.BinaryData {
+		data[k] = string(v)
+	}
+	return data, true, nil
+}
+
+// checkExternalRuleSources verifies that objects and keys referenced by spec.externalRuleSources exist
+// and updates ExternalRuleSourcesMissing condition.
+// It returns checksum of mounted rule files, which changes on any rule file change
+func checkExternalRuleSources(ctx context.Context, rclient client.Client, cr *vmv1beta1.VMAlert) (string, error) {
+	if len(cr.Spec.ExternalRuleSources) == 0 {
+		removeVMAlertCondition(cr, vmv1beta1.VMAlertExternalRuleSourcesMissingCondition)
+		return "", nil
+	}
+	var missing []string
+	h := fnv.New64a()
+	for _, src := range cr.Spec.ExternalRuleSources {
+		name := externalRuleSourceName(src)
+		data, ok, err := externalRuleSourceData(ctx, rclient, cr.Namespace, src)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			missing = append(missing, name)
+			continue
+		}
+		keys := src.Keys
+		if len(keys) == 0 {
+			for k := range data {
+				if strings.HasSuffix(k, ".yaml") {
+					keys = append(keys, k)
+				}
+			}
+			sort.Strings(keys)
+		}
+		for _, k := range keys {
+			v, ok := data[k]
+			if !ok {
+				missing = append(missing, fmt.Sprintf("%s:%s", name, k))
+				continue
+			}
+			h.Write([]byte(name)) //nolint:errcheck
+			h.Write([]byte(k))    //nolint:errcheck
+			h.Write([]byte(v))    //nolint:errcheck
+		}
+	}
+	setExternalRuleSourcesMissingCondition(cr, missing)
+	return strconv.FormatUint(h.Sum64(), 16), nil
+}
+
+// setExternalRuleSourcesMissingCondition updates ExternalRuleSourcesMissing condition at the given VMAlert status
+func setExternalRuleSourcesMissingCondition(cr *vmv1beta1.VMAlert, missing []string) {
+	cond := vmv1beta1.Condition{
+		Type:   vmv1beta1.VMAlertExternalRuleSourcesMissingCondition,
+		Status: metav1.ConditionFalse,
+		Reason: "ExternalRuleSourcesFound",
+	}
+	if len(missing) > 0 {
+		cond.Status = metav1.ConditionTrue
+		cond.Reason = vmv1beta1.VMAlertExternalRuleSourcesMissingCondition
+		cond.Message = fmt.Sprintf("external rule sources are missing: %s", strings.Join(missing, ","))
+	}
+	setVMAlertCondition(cr, cond)
+}
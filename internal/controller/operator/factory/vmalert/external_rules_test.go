@@ -0,0 +1,121 @@
+package vmalert
+
+import (
+	"context"
+	"testing"
+
+	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/k8stools"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestCheckExternalRuleSources(t *testing.T) {
+	f := func(sources []vmv1beta1.VMAlertExternalRuleSource, predefinedObjects []runtime.Object, wantStatus metav1.ConditionStatus, wantMessage string) string {
+		t.Helper()
+		cr := &vmv1beta1.VMAlert{
+			ObjectMeta: metav1.ObjectMeta{Name: "base", Namespace: "default"},
+			Spec:       vmv1beta1.VMAlertSpec{ExternalRuleSources: sources},
+		}
+		fclient := k8stools.GetTestClientWithObjects(predefinedObjects)
+		checksum, err := checkExternalRuleSources(context.TODO(), fclient, cr)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if wantStatus == "" {
+			assert.Empty(t, cr.Status.Conditions)
+			assert.Empty(t, checksum)
+			return checksum
+		}
+		assert.Len(t, cr.Status.Conditions, 1)
+		cond := cr.Status.Conditions[0]
+		assert.Equal(t, vmv1beta1.VMAlertExternalRuleSourcesMissingCondition, cond.Type)
+		assert.Equal(t, wantStatus, cond.Status)
+		assert.Equal(t, wantMessage, cond.Message)
+		return checksum
+	}
+	cm := func(name string, data map[string]string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}, Data: data}
+	}
+	cmSource := func(name string, keys ...string) vmv1beta1.VMAlertExternalRuleSource {
+		return vmv1beta1.VMAlertExternalRuleSource{ConfigMap: &corev1.LocalObjectReference{Name: name}, Keys: keys}
+	}
+
+	// no external sources
+	f(nil, nil, "", "")
+
+	// all sources exist
+	sources := []vmv1beta1.VMAlertExternalRuleSource{
+		cmSource("git-rules"),
+		{Secret: &corev1.LocalObjectReference{Name: "private-rules"}, Keys: []string{"alerts.yml"}},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "private-rules", Namespace: "default"},
+		Data:       map[string][]byte{"alerts.yml": []byte("groups: []")},
+	}
+	checksum := f(sources, []runtime.Object{
+		cm("git-rules", map[string]string{"alerts.yaml": "groups: []", "README.md": "readme"}),
+		secret,
+	}, metav1.ConditionFalse, "")
+
+	// changes of not mounted keys must not change checksum
+	assert.Equal(t, checksum, f(sources, []runtime.Object{
+		cm("git-rules", map[string]string{"alerts.yaml": "groups: []", "README.md": "updated readme"}),
+		secret,
+	}, metav1.ConditionFalse, ""))
+
+	// rule files change
+	assert.NotEqual(t, checksum, f(sources, []runtime.Object{
+		cm("git-rules", map[string]string{"alerts.yaml": "groups: [{name: test, rules: []}]"}),
+		secret,
+	}, metav1.ConditionFalse, ""))
+
+	// missing objects and keys
+	f([]vmv1beta1.VMAlertExternalRuleSource{
+		cmSource("git-rules", "alerts.yaml", "records.yaml"),
+		{Secret: &corev1.LocalObjectReference{Name: "private-rules"}},
+	}, []runtime.Object{
+		cm("git-rules", map[string]string{"alerts.yaml": "groups: []"}),
+	}, metav1.ConditionTrue, "external rule sources are missing: configmap-git-rules:records.yaml,secret-private-rules")
+}
+
+func TestExternalRuleSourcesSpec(t *testing.T) {
+	cr := &vmv1beta1.VMAlert{
+		ObjectMeta: metav1.ObjectMeta{Name: "base", Namespace: "default"},
+		Spec: vmv1beta1.VMAlertSpec{
+			ExternalRuleSources: []vmv1beta1.VMAlertExternalRuleSource{
+				{ConfigMap: &corev1.LocalObjectReference{Name: "git-rules"}},
+				{Secret: &corev1.LocalObjectReference{Name: "private-rules"}, Keys: []string{"alerts.yml", "records.yml"}},
+			},
+		},
+	}
+	assert.Equal(t, []string{
+		`-rule="/etc/vmalert/config/external-configmap-git-rules/*.yaml"`,
+		`-rule="/etc/vmalert/config/external-secret-private-rules/alerts.yml"`,
+		`-rule="/etc/vmalert/config/external-secret-private-rules/records.yml"`,
+	}, buildExternalRuleSourcesArgs(cr))
+
+	volumes, mounts := buildExternalRuleSourcesVolumes(cr)
+	assert.Len(t, volumes, 2)
+	assert.Equal(t, "external-configmap-git-rules", volumes[0].Name)
+	assert.Equal(t, "git-rules", volumes[0].ConfigMap.Name)
+	assert.True(t, *volumes[0].ConfigMap.Optional)
+	assert.Equal(t, "external-secret-private-rules", volumes[1].Name)
+	assert.Equal(t, "private-rules", volumes[1].Secret.SecretName)
+	assert.Equal(t, []corev1.KeyToPath{{Key: "alerts.yml", Path: "alerts.yml"}, {Key: "records.yml", Path: "records.yml"}}, volumes[1].Secret.Items)
+	assert.Equal(t, []corev1.VolumeMount{
+		{Name: "external-configmap-git-rules", ReadOnly: true, MountPath: "/etc/vmalert/config/external-configmap-git-rules"},
+		{Name: "external-secret-private-rules", ReadOnly: true, MountPath: "/etc/vmalert/config/external-secret-private-rules"},
+	}, mounts)
+
+	// external rules must be reloaded for unmanaged vmalert
+	containers := buildConfigReloaderContainer(nil, cr, nil)
+	assert.Len(t, containers, 1)
+	assert.Contains(t, containers[0].Args, "-volume-dir=/etc/vmalert/config/external-configmap-git-rules")
+	assert.Equal(t, mounts, containers[0].VolumeMounts)
+
+	assert.True(t, isExternalRuleFile("/etc/vmalert/config/external-configmap-git-rules/alerts.yaml"))
+	assert.False(t, isExternalRuleFile("/etc/vmalert/config/vm-base-rulefiles-0/default-rule.yaml"))
+}
@@ -0,0 +1,188 @@
+package vmalert
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+
+	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/k8stools"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/logger"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/reconcile"
+	"github.com/ghodss/yaml"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	vmAlertNotifiersDir        = "/etc/vmalert/notifiers"
+	notifiersConfigKey         = "notifiers.yaml"
+	notifiersConfigVolumeName  = "notifiers-config"
+	alertmanagerAlertsEndpoint = "/api/v2/alerts"
+	haModePodNameEnv           = "POD_NAME"
+)
+
+// notifiersConfig is a subset of vmalert -notifier.config file
+type notifiersConfig struct {
+	Scheme              string                   `json:"scheme,omitempty"`
+	PathPrefix          string                   `json:"path_prefix,omitempty"`
+	StaticConfigs       []notifiersStaticConfig  `json:"static_configs"`
+	RelabelConfigs      []notifiersRelabelConfig `json:"relabel_configs,omitempty"`
+	AlertRelabelConfigs []notifiersRelabelConfig `json:"alert_relabel_configs,omitempty"`
+}
+
+type notifiersRelabelConfig struct {
+	Action       string   `json:"action"`
+	SourceLabels []string `json:"source_labels,omitempty"`
+	Regex        string   `json:"regex"`
+	TargetLabel  string   `json:"target_label,omitempty"`
+	Replacement  string   `json:"replacement,omitempty"`
+}
+
+type notifiersStaticConfig struct {
+	Targets   []string            `json:"targets"`
+	TLSConfig *notifiersTLSConfig `json:"tls_config,omitempty"`
+	BasicAuth *notifiersBasicAuth `json:"basic_auth,omitempty"`
+}
+
+type notifiersTLSConfig struct {
+	CA string `json:"ca,omitempty"`
+}
+
+type notifiersBasicAuth struct {
+	Username string `json:"username"`
+	Password string `json:"password,omitempty"`
+}
+
+// selectNotifiers returns VMAlertmanager objects matched by notifier selectors sorted by namespace and name
+func selectNotifiers(ctx context.Context, rclient client.Client, cr *vmv1beta1.VMAlert) ([]*vmv1beta1.VMAlertmanager, error) {
+	var ams []*vmv1beta1.VMAlertmanager
+	if err := k8stools.VisitObjectsForSelectorsAtNs(ctx, rclient, cr.Spec.NotifierNamespaceSelector, cr.Spec.NotifierSelector, cr.Namespace, true,
+		func(list *vmv1beta1.VMAlertmanagerList) {
+			for _, item := range list.Items {
+				if !item.DeletionTimestamp.IsZero() {
+					continue
+				}
+				ams = append(ams, item.DeepCopy())
+			}
+		}); err != nil {
+		return nil, fmt.Errorf("cannot select alertmanagers: %w", err)
+	}
+	sort.Slice(ams, func(i, j int) bool {
+		if ams[i].Namespace != ams[j].Namespace {
+			return ams[i].Namespace < ams[j].Namespace
+		}
+		return ams[i].Name < ams[j].Name
+	})
+	return ams, nil
+}
+
+// buildNotifierStaticConfig returns static config with alertmanager pod addresses
+// and client settings matching alertmanager web config
+func buildNotifierStaticConfig(ctx context.Context, rclient client.Client, am *vmv1beta1.VMAlertmanager) (notifiersStaticConfig, error) {
+	sc := notifiersStaticConfig{Targets: am.PodAddresses()}
+	wc := am.Spec.WebConfig
+	if wc == nil {
+		return sc, nil
+	}
+	secretCache := make(map[string]*corev1.Secret)
+	if wc.TLSServerConfig != nil && wc.TLSServerConfig.CertSecretRef != nil {
+		// alertmanager certificate is used as trusted CA, it allows to verify self-signed certificates
+		sel := wc.TLSServerConfig.CertSecretRef
+		ca, err := k8stools.GetCredFromSecret(ctx, rclient, am.Namespace, sel, fmt.Sprintf("%s/%s", am.Namespace, sel.Name), secretCache)
+		if err != nil {
+			return sc, fmt.Errorf("cannot load tls certificate: %w", err)
+		}
+		sc.TLSConfig = &notifiersTLSConfig{CA: ca}
+	}
+	if wc.ClientBasicAuth != nil {
+		creds, err := k8stools.LoadBasicAuthSecret(ctx, rclient, am.Namespace, wc.ClientBasicAuth, secretCache)
+		if err != nil {
+			return sc, fmt.Errorf("cannot load client basic auth: %w", err)
+		}
+		sc.BasicAuth = &notifiersBasicAuth{Username: creds.Username, Password: creds.Password}
+	}
+	return sc, nil
+}
+
+// buildNotifiersConfig generates vmalert notifier config with static targets for each alertmanager pod.
+// Scheme and path prefix of the first alertmanager are used as defaults,
+// alertmanagers with other settings get relabeling rules for its addresses.
+// replica label is dropped from alerts at haMode, it allows alertmanager to deduplicate alerts of vmalert replicas
+func buildNotifiersConfig(ctx context.Context, rclient client.Client, cr *vmv1beta1.VMAlert, ams []*vmv1beta1.VMAlertmanager) ([]byte, error) {
+	cfg := notifiersConfig{StaticConfigs: make([]notifiersStaticConfig, 0, len(ams))}
+	if cr.Spec.HAMode {
+		cfg.AlertRelabelConfigs = append(cfg.AlertRelabelConfigs, notifiersRelabelConfig{
+			Action: "labeldrop",
+			Regex:  vmv1beta1.VMAlertReplicaLabel,
+		})
+	}
+	for _, am := range ams {
+		sc, err := buildNotifierStaticConfig(ctx, rclient, am)
+		if err != nil {
+			logger.WithContext(ctx).Error(err, fmt.Sprintf("skipping VMAlertmanager=%s/%s at notifiers config", am.Namespace, am.Name))
+			continue
+		}
+		scheme, prefix := am.AccessScheme(), am.Spec.RoutePrefix
+		if len(cfg.StaticConfigs) == 0 {
+			cfg.Scheme, cfg.PathPrefix = scheme, prefix
+		}
+		// pod addresses have format <pod>.<service>.<namespace>.svc:<port>
+		addrRegex := fmt.Sprintf(`[^.]+\.%s:%s`, regexp.QuoteMeta(fmt.Sprintf("%s.%s.svc", am.PrefixedName(), am.Namespace)), am.Port())
+		if scheme != cfg.Scheme {
+			cfg.RelabelConfigs = append(cfg.RelabelConfigs, notifiersRelabelConfig{
+				Action:       "replace",
+				SourceLabels: []string{"__address__"},
+				Regex:        addrRegex,
+				TargetLabel:  "__scheme__",
+				Replacement:  scheme,
+			})
+		}
+		if prefix != cfg.PathPrefix {
+			cfg.RelabelConfigs = append(cfg.RelabelConfigs, notifiersRelabelConfig{
+				Action:       "replace",
+				SourceLabels: []string{"__address__"},
+				Regex:        addrRegex,
+				TargetLabel:  "__alerts_path__",
+				Replacement:  path.Join("/", prefix, alertmanagerAlertsEndpoint),
+			})
+		}
+		cfg.StaticConfigs = append(cfg.StaticConfigs, sc)
+	}
+	return yaml.Marshal(cfg)
+}
+
+// CreateOrUpdateNotifiersConfig discovers VMAlertmanager objects
+// and writes notifiers config into Secret mounted to vmalert pods
+// config changes are applied by config-reloader without pods restart
+func CreateOrUpdateNotifiersConfig(ctx context.Context, rclient client.Client, cr *vmv1beta1.VMAlert) error {
+	if !cr.IsNotifierDiscoveryEnabled() {
+		return nil
+	}
+	ams, err := selectNotifiers(ctx, rclient, cr)
+	if err != nil {
+		return err
+	}
+	data, err := buildNotifiersConfig(ctx, rclient, cr, ams)
+	if err != nil {
+		return fmt.Errorf("cannot build notifiers config: %w", err)
+	}
+	logger.WithContext(ctx).Info(fmt.Sprintf("discovered alertmanagers count=%d for notifiers config", len(ams)))
+	s := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            cr.NotifiersConfigName(),
+			Namespace:       cr.Namespace,
+			Labels:          cr.AllLabels(),
+			OwnerReferences: cr.AsOwner(),
+			Finalizers:      []string{vmv1beta1.FinalizerName},
+		},
+		Data: map[string][]byte{notifiersConfigKey: data},
+	}
+	if err := reconcile.Secret(ctx, rclient, s, nil); err != nil {
+		return fmt.Errorf("cannot reconcile notifiers config: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,254 @@
+package vmalert
+
+import (
+	"context"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmalert/notifier"
+	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/k8stools"
+	"github.com/stretchr/testify/assert"
+	yamlv2 "gopkg.in/yaml.v2"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+)
+
+func TestCreateOrUpdateNotifiersConfig(t *testing.T) {
+	f := func(cr *vmv1beta1.VMAlert, predefinedObjects []runtime.Object, wantConfig string) {
+		t.Helper()
+		fclient := k8stools.GetTestClientWithObjects(predefinedObjects)
+		ctx := context.TODO()
+		if err := CreateOrUpdateNotifiersConfig(ctx, fclient, cr); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		var s corev1.Secret
+		err := fclient.Get(ctx, types.NamespacedName{Namespace: cr.Namespace, Name: cr.NotifiersConfigName()}, &s)
+		if len(wantConfig) == 0 {
+			assert.Error(t, err, "notifiers config must not be created")
+			return
+		}
+		if err != nil {
+			t.Fatalf("cannot get notifiers config: %s", err)
+		}
+		assert.Equal(t, wantConfig, string(s.Data[notifiersConfigKey]))
+		var cfg notifier.Config
+		if err := yamlv2.UnmarshalStrict(s.Data[notifiersConfigKey], &cfg); err != nil {
+			t.Fatalf("cannot parse notifiers config with vmalert parser: %s", err)
+		}
+		assert.Empty(t, cfg.XXX, "notifiers config must not have unknown fields")
+	}
+	am := func(name, ns string, lbls map[string]string, spec vmv1beta1.VMAlertmanagerSpec) *vmv1beta1.VMAlertmanager {
+		return &vmv1beta1.VMAlertmanager{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ns, Labels: lbls},
+			Spec:       spec,
+		}
+	}
+
+	// discovery disabled
+	f(&vmv1beta1.VMAlert{
+		ObjectMeta: metav1.ObjectMeta{Name: "base", Namespace: "default"},
+	}, []runtime.Object{am("main", "default", nil, vmv1beta1.VMAlertmanagerSpec{})}, "")
+
+	// object selector at vmalert namespace
+	f(&vmv1beta1.VMAlert{
+		ObjectMeta: metav1.ObjectMeta{Name: "base", Namespace: "default"},
+		Spec: vmv1beta1.VMAlertSpec{
+			NotifierSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "a"}},
+		},
+	}, []runtime.Object{
+		am("main", "default", map[string]string{"team": "a"}, vmv1beta1.VMAlertmanagerSpec{
+			CommonApplicationDeploymentParams: vmv1beta1.CommonApplicationDeploymentParams{ReplicaCount: ptr.To[int32](2)},
+		}),
+		am("other", "default", map[string]string{"team": "b"}, vmv1beta1.VMAlertmanagerSpec{}),
+		am("main", "monitoring", map[string]string{"team": "a"}, vmv1beta1.VMAlertmanagerSpec{}),
+	}, `scheme: http
+static_configs:
+- targets:
+  - vmalertmanager-main-0.vmalertmanager-main.default.svc:9093
+  - vmalertmanager-main-1.vmalertmanager-main.default.svc:9093
+`)
+
+	// namespace selector with route prefix, tls and basic auth
+	f(&vmv1beta1.VMAlert{
+		ObjectMeta: metav1.ObjectMeta{Name: "base", Namespace: "default"},
+		Spec: vmv1beta1.VMAlertSpec{
+			NotifierNamespaceSelector: &metav1.LabelSelector{},
+		},
+	}, []runtime.Object{
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "monitoring"}},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "am-tls", Namespace: "default"},
+			Data:       map[string][]byte{"cert": []byte("CERT"), "user": []byte("vmalert"), "password": []byte("pass")},
+		},
+		am("main", "monitoring", nil, vmv1beta1.VMAlertmanagerSpec{RoutePrefix: "/am"}),
+		am("secure", "default", nil, vmv1beta1.VMAlertmanagerSpec{
+			WebConfig: &vmv1beta1.AlertmanagerWebConfig{
+				TLSServerConfig: &vmv1beta1.TLSServerConfig{
+					Certs: vmv1beta1.Certs{
+						CertSecretRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "am-tls"}, Key: "cert"},
+					},
+				},
+				ClientBasicAuth: &vmv1beta1.BasicAuth{
+					Username: corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "am-tls"}, Key: "user"},
+					Password: corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "am-tls"}, Key: "password"},
+				},
+			},
+		}),
+	}, `relabel_configs:
+- action: replace
+  regex: '[^.]+\.vmalertmanager-main\.monitoring\.svc:9093'
+  replacement: http
+  source_labels:
+  - __address__
+  target_label: __scheme__
+- action: replace
+  regex: '[^.]+\.vmalertmanager-main\.monitoring\.svc:9093'
+  replacement: /am/api/v2/alerts
+  source_labels:
+  - __address__
+  target_label: __alerts_path__
+scheme: https
+static_configs:
+- basic_auth:
+    password: pass
+    username: vmalert
+  targets:
+  - vmalertmanager-secure-0.vmalertmanager-secure.default.svc:9093
+  tls_config:
+    ca: CERT
+- targets:
+  - vmalertmanager-main-0.vmalertmanager-main.monitoring.svc:9093
+`)
+
+	// missing tls secret skips alertmanager
+	f(&vmv1beta1.VMAlert{
+		ObjectMeta: metav1.ObjectMeta{Name: "base", Namespace: "default"},
+		Spec: vmv1beta1.VMAlertSpec{
+			NotifierSelector: &metav1.LabelSelector{},
+		},
+	}, []runtime.Object{
+		am("main", "default", nil, vmv1beta1.VMAlertmanagerSpec{RoutePrefix: "/am"}),
+		am("secure", "default", nil, vmv1beta1.VMAlertmanagerSpec{
+			WebConfig: &vmv1beta1.AlertmanagerWebConfig{
+				TLSServerConfig: &vmv1beta1.TLSServerConfig{
+					Certs: vmv1beta1.Certs{
+						CertSecretRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "missing"}, Key: "cert"},
+					},
+				},
+			},
+		}),
+	}, `path_prefix: /am
+scheme: http
+static_configs:
+- targets:
+  - vmalertmanager-main-0.vmalertmanager-main.default.svc:9093
+`)
+
+	// no alertmanagers matched
+	f(&vmv1beta1.VMAlert{
+		ObjectMeta: metav1.ObjectMeta{Name: "base", Namespace: "default"},
+		Spec: vmv1beta1.VMAlertSpec{
+			NotifierSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "c"}},
+		},
+	}, []runtime.Object{
+		am("main", "default", map[string]string{"team": "a"}, vmv1beta1.VMAlertmanagerSpec{}),
+	}, `static_configs: []
+`)
+
+	// ha mode drops replica label
+	f(&vmv1beta1.VMAlert{
+		ObjectMeta: metav1.ObjectMeta{Name: "base", Namespace: "default"},
+		Spec: vmv1beta1.VMAlertSpec{
+			NotifierSelector: &metav1.LabelSelector{},
+			HAMode:           true,
+		},
+	}, []runtime.Object{
+		am("main", "default", nil, vmv1beta1.VMAlertmanagerSpec{}),
+	}, `alert_relabel_configs:
+- action: labeldrop
+  regex: vmalert_replica
+scheme: http
+static_configs:
+- targets:
+  - vmalertmanager-main-0.vmalertmanager-main.default.svc:9093
+`)
+}
+
+func TestCreateOrUpdateVMAlertNotifiersDiscovery(t *testing.T) {
+	cr := &vmv1beta1.VMAlert{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "discovery",
+			Namespace: "default",
+		},
+		Spec: vmv1beta1.VMAlertSpec{
+			Datasource: vmv1beta1.VMAlertDatasourceSpec{
+				URL: "http://some-vm-datasource",
+			},
+			NotifierSelector: &metav1.LabelSelector{},
+		},
+	}
+	fclient := k8stools.GetTestClientWithObjects(nil)
+	ctx := context.TODO()
+	if err := CreateOrUpdateVMAlert(ctx, cr, fclient, nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var s corev1.Secret
+	if err := fclient.Get(ctx, types.NamespacedName{Namespace: cr.Namespace, Name: cr.NotifiersConfigName()}, &s); err != nil {
+		t.Fatalf("cannot get notifiers config: %s", err)
+	}
+	var dep appsv1.Deployment
+	if err := fclient.Get(ctx, types.NamespacedName{Namespace: cr.Namespace, Name: cr.PrefixedName()}, &dep); err != nil {
+		t.Fatalf("cannot get deployment: %s", err)
+	}
+	containers := dep.Spec.Template.Spec.Containers
+	assert.Len(t, containers, 2)
+	assert.Contains(t, containers[0].Args, "-notifier.config="+vmAlertNotifiersDir+"/notifiers.yaml")
+	assert.Contains(t, containers[1].VolumeMounts, corev1.VolumeMount{Name: notifiersConfigVolumeName, MountPath: vmAlertNotifiersDir})
+	var found bool
+	for _, v := range dep.Spec.Template.Spec.Volumes {
+		if v.Name == notifiersConfigVolumeName {
+			found = true
+			assert.Equal(t, cr.NotifiersConfigName(), v.Secret.SecretName)
+		}
+	}
+	assert.True(t, found, "notifiers config volume must be present")
+}
+
+func TestCreateOrUpdateVMAlertHAMode(t *testing.T) {
+	cr := &vmv1beta1.VMAlert{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "ha",
+			Namespace: "default",
+		},
+		Spec: vmv1beta1.VMAlertSpec{
+			Datasource: vmv1beta1.VMAlertDatasourceSpec{
+				URL: "http://some-vm-datasource",
+			},
+			NotifierSelector: &metav1.LabelSelector{},
+			HAMode:           true,
+		},
+	}
+	fclient := k8stools.GetTestClientWithObjects(nil)
+	ctx := context.TODO()
+	if err := CreateOrUpdateVMAlert(ctx, cr, fclient, nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var dep appsv1.Deployment
+	if err := fclient.Get(ctx, types.NamespacedName{Namespace: cr.Namespace, Name: cr.PrefixedName()}, &dep); err != nil {
+		t.Fatalf("cannot get deployment: %s", err)
+	}
+	vmalertContainer := dep.Spec.Template.Spec.Containers[0]
+	assert.Contains(t, vmalertContainer.Args, "-external.label=vmalert_replica=%{POD_NAME}")
+	assert.Contains(t, vmalertContainer.Args, "-envflag.enable=true")
+	assert.Contains(t, vmalertContainer.Env, corev1.EnvVar{
+		Name: "POD_NAME",
+		ValueFrom: &corev1.EnvVarSource{
+			FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.name"},
+		},
+	})
+}
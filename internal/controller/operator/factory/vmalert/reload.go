@@ -0,0 +1,252 @@
+package vmalert
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/k8stools"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/logger"
+	"github.com/ghodss/yaml"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const defaultRulesReloadTimeout = 60 * time.Second
+
+const (
+	rulesReloadPendingReason = "ReloadPending"
+	rulesReloadedReason      = "RulesReloaded"
+	rulesReloadTimeoutReason = "ReloadTimeout"
+)
+
+var (
+	// rulesReloadCheckInterval defines requeue interval of VMAlert with pending rules reload check
+	rulesReloadCheckInterval = 5 * time.Second
+	// rulesReloadRequestTimeout defines timeout for a single request to vmalert pod
+	rulesReloadRequestTimeout = 5 * time.Second
+)
+
+// loadedRulesResponse is a subset of vmalert /api/v1/rules response
+type loadedRulesResponse struct {
+	Data struct {
+		Groups []struct {
+			Name  string `json:"name"`
+			File  string `json:"file"`
+			Rules []struct {
+				Name  string `json:"name"`
+				Query string `json:"query"`
+			} `json:"rules"`
+		} `json:"groups"`
+	} `json:"data"`
+}
+
+// ruleFileContent is a subset of vmalert rule file
+type ruleFileContent struct {
+	Groups []struct {
+		Name  string `json:"name"`
+		Rules []struct {
+			Alert  string `json:"alert"`
+			Record string `json:"record"`
+			Expr   string `json:"expr"`
+		} `json:"rules"`
+	} `json:"groups"`
+}
+
+func ruleKey(group, name, expr string) string {
+	return strings.Join([]string{group, name, expr}, "\x00")
+}
+
+// expectedRules returns sorted keys of rules from the generated rule files
+func expectedRules(rulesData map[string]string) ([]string, error) {
+	var keys []string
+	for name, data := range rulesData {
+		var content ruleFileContent
+		if err := yaml.Unmarshal([]byte(data), &content); err != nil {
+			return nil, fmt.Errorf("cannot parse rule file=%q: %w", name, err)
+		}
+		for _, g := range content.Groups {
+			for _, r := range g.Rules {
+				name := r.Alert
+				if name == "" {
+					name = r.Record
+				}
+				keys = append(keys, ruleKey(g.Name, name, r.Expr))
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// loadedRules returns sorted keys of rules loaded by vmalert from operator managed rule files.
+// Rules from spec.rulePath and spec.externalRuleSources are ignored
+func loadedRules(ctx context.Context, hc *http.Client, rulesURL string) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rulesURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot build request: %w", err)
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot request rules: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("unexpected response code=%d for rules request: %s", resp.StatusCode, string(body))
+	}
+	var lr loadedRulesResponse
+	if err := json.NewDecoder(resp.Body).Decode(&lr); err != nil {
+		return nil, fmt.Errorf("cannot parse rules response: %w", err)
+	}
+	var keys []string
+	for _, g := range lr.Data.Groups {
+		if (!strings.HasPrefix(g.File, vmAlertConfigDir) && !strings.HasPrefix(g.File, vmAlertUnpackedRulesDir)) || isExternalRuleFile(g.File) {
+			continue
+		}
+		for _, r := range g.Rules {
+			keys = append(keys, ruleKey(g.Name, r.Name, r.Query))
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// newRulesReloadClient returns http client for requests to vmalert pods
+func newRulesReloadClient(ctx context.Context, rclient client.Client, cr *vmv1beta1.VMAlert) (*http.Client, error) {
+	return k8stools.NewHTTPClient(ctx, rclient, cr.Namespace, cr.Spec.RulesReloadCheck.TLSConfig, rulesReloadRequestTimeout)
+}
+
+// isRulesReloadCheckEnabled checks if operator must wait for rules reload at vmalert pods
+func isRulesReloadCheckEnabled(cr *vmv1beta1.VMAlert) bool {
+	return cr.Spec.RulesReloadCheck != nil && !ptr.Deref(cr.Spec.RolloutOnRuleChange, false)
+}
+
+// rulesReloadTimeout returns deadline for rules reload at vmalert pods
+func rulesReloadTimeout(cr *vmv1beta1.VMAlert) time.Duration {
+	if cr.Spec.RulesReloadCheck == nil || cr.Spec.RulesReloadCheck.Timeout == "" {
+		return defaultRulesReloadTimeout
+	}
+	d, err := time.ParseDuration(cr.Spec.RulesReloadCheck.Timeout)
+	if err != nil {
+		// timeout is validated by webhook
+		return defaultRulesReloadTimeout
+	}
+	return d
+}
+
+// checkRulesReload requests rules API of vmalert pods of the given shard once.
+// It returns names of pods, which didn't load generated rules yet
+func checkRulesReload(ctx context.Context, rclient client.Client, hc *http.Client, cr *vmv1beta1.VMAlert, shardNum int, rulesData map[string]string) ([]string, error) {
+	want, err := expectedRules(rulesData)
+	if err != nil {
+		return nil, err
+	}
+	podLabels := cr.PodLabels()
+	if cr.RuleShardsCount() > 1 {
+		podLabels["shard-num"] = strconv.Itoa(shardNum)
+	}
+	var pods corev1.PodList
+	if err := rclient.List(ctx, &pods, &client.ListOptions{Namespace: cr.Namespace, LabelSelector: labels.SelectorFromSet(podLabels)}); err != nil {
+		return nil, fmt.Errorf("cannot list vmalert pods: %w", err)
+	}
+	var notReloaded []string
+	for _, pod := range pods.Items {
+		if !pod.DeletionTimestamp.IsZero() || pod.Status.Phase != corev1.PodRunning || pod.Status.PodIP == "" {
+			continue
+		}
+		got, err := loadedRules(ctx, hc, cr.PodRulesURL(pod.Status.PodIP))
+		switch {
+		case err != nil:
+		case len(got) != len(want):
+			err = fmt.Errorf("pod has %d loaded rules, want %d", len(got), len(want))
+		case !slices.Equal(got, want):
+			err = fmt.Errorf("pod has outdated rules")
+		}
+		if err != nil {
+			logger.WithContext(ctx).Info(fmt.Sprintf("vmalert pod=%s didn't load new rules yet: %s", pod.Name, err))
+			notReloaded = append(notReloaded, pod.Name)
+		}
+	}
+	return notReloaded, nil
+}
+
+// reconcileRulesReloadCheck checks if vmalert pods loaded generated rules.
+// Check is started on rules update and is tracked with ReloadPending reason of RulesReloadDegraded condition.
+// Reconcile isn't blocked, VMAlert is requeued with RulesReloadRequeueAfter until pods load rules or timeout is reached.
+// Reload timeout must not fail reconcile, since new rules will be eventually loaded by config-reloader
+func reconcileRulesReloadCheck(ctx context.Context, rclient client.Client, cr *vmv1beta1.VMAlert, rulesUpdated bool, rulesDataByShard []map[string]string) error {
+	if !isRulesReloadCheckEnabled(cr) {
+		return nil
+	}
+	if rulesUpdated {
+		// restart check timeout
+		removeVMAlertCondition(cr, vmv1beta1.VMAlertRulesReloadDegradedCondition)
+		setVMAlertCondition(cr, vmv1beta1.Condition{
+			Type:   vmv1beta1.VMAlertRulesReloadDegradedCondition,
+			Status: metav1.ConditionUnknown,
+			Reason: rulesReloadPendingReason,
+		})
+	}
+	pending := findRulesReloadPendingCondition(cr)
+	if pending == nil {
+		return nil
+	}
+	hc, err := newRulesReloadClient(ctx, rclient, cr)
+	if err != nil {
+		return fmt.Errorf("cannot build http client for rules reload check: %w", err)
+	}
+	var notReloaded []string
+	for shardNum, rulesData := range rulesDataByShard {
+		pods, err := checkRulesReload(ctx, rclient, hc, cr, shardNum, rulesData)
+		if err != nil {
+			return err
+		}
+		notReloaded = append(notReloaded, pods...)
+	}
+	timeout := rulesReloadTimeout(cr)
+	switch {
+	case len(notReloaded) == 0:
+		setVMAlertCondition(cr, vmv1beta1.Condition{
+			Type:   vmv1beta1.VMAlertRulesReloadDegradedCondition,
+			Status: metav1.ConditionFalse,
+			Reason: rulesReloadedReason,
+		})
+	case time.Since(pending.LastTransitionTime.Time) > timeout:
+		setVMAlertCondition(cr, vmv1beta1.Condition{
+			Type:    vmv1beta1.VMAlertRulesReloadDegradedCondition,
+			Status:  metav1.ConditionTrue,
+			Reason:  rulesReloadTimeoutReason,
+			Message: fmt.Sprintf("pods didn't load new rules in %s: %s", timeout, strings.Join(notReloaded, ",")),
+		})
+	}
+	return nil
+}
+
+func findRulesReloadPendingCondition(cr *vmv1beta1.VMAlert) *vmv1beta1.Condition {
+	for idx, c := range cr.Status.Conditions {
+		if c.Type == vmv1beta1.VMAlertRulesReloadDegradedCondition && c.Reason == rulesReloadPendingReason {
+			return &cr.Status.Conditions[idx]
+		}
+	}
+	return nil
+}
+
+// RulesReloadRequeueAfter returns requeue interval for VMAlert with pending rules reload check
+func RulesReloadRequeueAfter(cr *vmv1beta1.VMAlert) time.Duration {
+	if !isRulesReloadCheckEnabled(cr) || findRulesReloadPendingCondition(cr) == nil {
+		return 0
+	}
+	return rulesReloadCheckInterval
+}
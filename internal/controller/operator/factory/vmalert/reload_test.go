@@ -0,0 +1,138 @@
+package vmalert
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/k8stools"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const reloadTestRuleFile = `groups:
+- name: group
+  rules:
+  - alert: up
+    expr: up == 0
+  - record: job:up:sum
+    expr: sum(up) by (job)
+`
+
+func TestReconcileRulesReloadCheck(t *testing.T) {
+	f := func(response string, wantNotReloaded bool) {
+		t.Helper()
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/api/v1/rules" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write([]byte(response)) //nolint:errcheck
+		}))
+		defer srv.Close()
+		host, port, err := net.SplitHostPort(srv.Listener.Addr().String())
+		if err != nil {
+			t.Fatalf("cannot parse server address: %s", err)
+		}
+		cr := &vmv1beta1.VMAlert{
+			ObjectMeta: metav1.ObjectMeta{Name: "reload", Namespace: "default"},
+			Spec: vmv1beta1.VMAlertSpec{
+				CommonDefaultableParams: vmv1beta1.CommonDefaultableParams{Port: port},
+				RulesReloadCheck:        &vmv1beta1.VMAlertRulesReloadCheck{Timeout: "1h"},
+			},
+		}
+		fclient := k8stools.GetTestClientWithObjects([]runtime.Object{
+			&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "vmalert-reload-0", Namespace: "default", Labels: cr.PodLabels()},
+				Status:     corev1.PodStatus{Phase: corev1.PodRunning, PodIP: host},
+			},
+			// pod of other vmalert must be ignored
+			&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "vmalert-other-0", Namespace: "default", Labels: map[string]string{"app.kubernetes.io/name": "vmalert"}},
+				Status:     corev1.PodStatus{Phase: corev1.PodRunning, PodIP: "127.0.0.2"},
+			},
+		})
+		ctx := context.TODO()
+		rulesData := []map[string]string{{"default-rule.yaml": reloadTestRuleFile}}
+		if err := reconcileRulesReloadCheck(ctx, fclient, cr, true, rulesData); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		assert.Len(t, cr.Status.Conditions, 1)
+		assert.Equal(t, vmv1beta1.VMAlertRulesReloadDegradedCondition, cr.Status.Conditions[0].Type)
+		if !wantNotReloaded {
+			assert.Equal(t, metav1.ConditionFalse, cr.Status.Conditions[0].Status)
+			assert.Zero(t, RulesReloadRequeueAfter(cr))
+			return
+		}
+		// check is pending and VMAlert must be requeued
+		assert.Equal(t, metav1.ConditionUnknown, cr.Status.Conditions[0].Status)
+		assert.Equal(t, rulesReloadPendingReason, cr.Status.Conditions[0].Reason)
+		assert.Equal(t, rulesReloadCheckInterval, RulesReloadRequeueAfter(cr))
+
+		// timeout is reached at the next reconcile
+		cr.Status.Conditions[0].LastTransitionTime = metav1.NewTime(time.Now().Add(-2 * time.Hour))
+		if err := reconcileRulesReloadCheck(ctx, fclient, cr, false, rulesData); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		assert.Equal(t, metav1.ConditionTrue, cr.Status.Conditions[0].Status)
+		assert.Contains(t, cr.Status.Conditions[0].Message, "vmalert-reload-0")
+		assert.Zero(t, RulesReloadRequeueAfter(cr))
+	}
+	loadedGroup := func(file, expr string) string {
+		return fmt.Sprintf(`{"name":"group","file":%q,"rules":[{"name":"up","query":%q},{"name":"job:up:sum","query":"sum(up) by (job)"}]}`, file, expr)
+	}
+
+	// new rules loaded, groups from spec.rulePath are ignored
+	f(fmt.Sprintf(`{"status":"success","data":{"groups":[%s,{"name":"base","file":"/etc/base/rules.yaml","rules":[{"name":"base","query":"vector(1)"}]}]}}`,
+		loadedGroup(vmAlertConfigDir+"/vm-reload-rulefiles-0/default-rule.yaml", "up == 0")), false)
+
+	// unpacked rules loaded
+	f(fmt.Sprintf(`{"status":"success","data":{"groups":[%s]}}`,
+		loadedGroup(vmAlertUnpackedRulesDir+"/vm-reload-rulefiles-0/default-rule.yaml", "up == 0")), false)
+
+	// outdated rules
+	f(fmt.Sprintf(`{"status":"success","data":{"groups":[%s]}}`,
+		loadedGroup(vmAlertConfigDir+"/vm-reload-rulefiles-0/default-rule.yaml", "up == 1")), true)
+
+	// missing rules
+	f(`{"status":"success","data":{"groups":[]}}`, true)
+
+	// malformed response
+	f(`{"status":`, true)
+}
+
+func TestNewRulesReloadClient(t *testing.T) {
+	cr := &vmv1beta1.VMAlert{
+		ObjectMeta: metav1.ObjectMeta{Name: "reload", Namespace: "default"},
+		Spec: vmv1beta1.VMAlertSpec{
+			RulesReloadCheck: &vmv1beta1.VMAlertRulesReloadCheck{
+				TLSConfig: &vmv1beta1.TLSConfig{CAFile: "/etc/ssl/ca.crt"},
+			},
+		},
+	}
+	// operator filesystem must not be accessed
+	_, err := newRulesReloadClient(context.TODO(), k8stools.GetTestClientWithObjects(nil), cr)
+	assert.Error(t, err)
+}
+
+func TestPodRulesURL(t *testing.T) {
+	f := func(spec vmv1beta1.VMAlertSpec, want string) {
+		t.Helper()
+		cr := &vmv1beta1.VMAlert{Spec: spec}
+		assert.Equal(t, want, cr.PodRulesURL("10.0.0.1"))
+	}
+	f(vmv1beta1.VMAlertSpec{}, "http://10.0.0.1:8080/api/v1/rules")
+	f(vmv1beta1.VMAlertSpec{
+		CommonDefaultableParams: vmv1beta1.CommonDefaultableParams{Port: "8443"},
+		CommonApplicationDeploymentParams: vmv1beta1.CommonApplicationDeploymentParams{
+			ExtraArgs: map[string]string{"tls": "true", "http.pathPrefix": "/vmalert"},
+		},
+	}, "https://10.0.0.1:8443/vmalert/api/v1/rules")
+}
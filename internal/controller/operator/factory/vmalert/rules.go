@@ -1,13 +1,28 @@
 package vmalert
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"encoding/json"
 	"fmt"
+	"hash"
 	"hash/fnv"
+	"maps"
+	"net/http"
+	"net/url"
+	"path"
+	"runtime"
+	"slices"
 	"sort"
 	"strconv"
+	"strings"
+	"sync"
 
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/buildinfo"
+	"github.com/VictoriaMetrics/metricsql"
 	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/config"
 	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/build"
 	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/finalize"
 	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/k8stools"
@@ -15,26 +30,54 @@ import (
 	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/reconcile"
 	"github.com/ghodss/yaml"
 	"github.com/prometheus/client_golang/prometheus"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/metrics"
 )
 
+// badConfigsTotal is deprecated and kept for backward compatibility, use invalidRules instead
 var badConfigsTotal = prometheus.NewCounter(prometheus.CounterOpts{
 	Name: "operator_vmalert_bad_objects_count",
-	Help: "Number of incorrect objects by controller",
+	Help: "Number of incorrect objects by controller. Deprecated, use operator_vmalert_invalid_rules instead",
 	ConstLabels: prometheus.Labels{
 		"controller": "vmrules",
 	},
 })
 
+var (
+	selectedRules = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "operator_vmalert_selected_rules",
+		Help: "Number of VMRules selected by VMAlert",
+	}, []string{"namespace", "name"})
+	invalidRules = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "operator_vmalert_invalid_rules",
+		Help: "Number of selected VMRules with errors, which are fully or partially skipped by VMAlert",
+	}, []string{"namespace", "name"})
+	ruleConfigMaps = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "operator_vmalert_rule_configmaps",
+		Help: "Number of ConfigMaps with rule files generated for VMAlert",
+	}, []string{"namespace", "name"})
+)
+
 func init() {
-	metrics.Registry.MustRegister(badConfigsTotal)
+	metrics.Registry.MustRegister(badConfigsTotal, selectedRules, invalidRules, ruleConfigMaps)
+}
+
+// DeregisterRulesMetrics removes rules metrics of the given VMAlert
+// it must be called on VMAlert delete in order to not expose stale series
+func DeregisterRulesMetrics(cr *vmv1beta1.VMAlert) {
+	selectedRules.DeleteLabelValues(cr.Namespace, cr.Name)
+	invalidRules.DeleteLabelValues(cr.Namespace, cr.Name)
+	ruleConfigMaps.DeleteLabelValues(cr.Namespace, cr.Name)
 }
 
 var (
@@ -45,13 +88,44 @@ var (
 	}
 )
 
+// compressedRuleFileSuffix is added to the rule file name stored at ConfigMap binaryData
+const compressedRuleFileSuffix = ".gz"
+
+// placeholder rule file is written into rules storage if no VMRules were selected
+const (
+	placeholderRuleFileName    = "placeholder-rules.yaml"
+	placeholderRuleFileContent = "groups: []\n"
+)
+
+// ruleFileNamespaceSeparator separates namespace and VMRule name at rule file key for namespace directories
+// and per namespace ConfigMaps layouts.
+// It's not allowed at namespace name, so keys are unique across VMRules
+const ruleFileNamespaceSeparator = "_"
+
+// RuleObject describes ConfigMap or Secret with rule files, which must be mounted into vmalert
+type RuleObject struct {
+	Name string
+	// Items maps object keys to the rule file paths inside the mounted directory.
+	// It's set only for namespace directories layout
+	Items []corev1.KeyToPath
+	// Checksum of rule files stored at object
+	Checksum string
+}
+
+const (
+	ruleRejectedEventReason = "RuleRejected"
+	ruleAcceptedEventReason = "RuleAccepted"
+)
+
 // CreateOrUpdateRuleConfigMaps conditionally selects vmrules and stores content at configmaps
-func CreateOrUpdateRuleConfigMaps(ctx context.Context, rclient client.Client, cr *vmv1beta1.VMAlert, childCR *vmv1beta1.VMRule) ([]string, error) {
+// It returns rule objects per vmalert shard, with rule sharding disabled there is only one shard.
+// recorder is optional and used to emit events on rejected and accepted VMRules
+func CreateOrUpdateRuleConfigMaps(ctx context.Context, rclient client.Client, cr *vmv1beta1.VMAlert, childCR *vmv1beta1.VMRule, recorder record.EventRecorder) ([][]RuleObject, error) {
 	// fast path
 	if cr.IsUnmanaged() {
 		return nil, nil
 	}
-	newRules, err := reconcileVMAlertConfig(ctx, rclient, cr, childCR)
+	newRules, err := reconcileVMAlertConfig(ctx, rclient, cr, childCR, recorder)
 	if err != nil {
 		return nil, err
 	}
@@ -59,8 +133,42 @@ func CreateOrUpdateRuleConfigMaps(ctx context.Context, rclient client.Client, cr
 	return newRules, nil
 }
 
-func reconcileConfigsData(ctx context.Context, rclient client.Client, cr *vmv1beta1.VMAlert, newRules map[string]string) ([]string, error) {
-	newConfigMaps := makeRulesConfigMaps(cr, newRules)
+// reconcileConfigsData stores rule files of the given shard at configmaps or secrets
+// It reports whether rules reload was triggered
+func reconcileConfigsData(ctx context.Context, rclient client.Client, cr *vmv1beta1.VMAlert, shardNum int, newRules map[string]string) ([]RuleObject, bool, error) {
+	prevAssignment, err := getRuleFilesAssignment(ctx, rclient, cr, shardNum)
+	if err != nil {
+		return nil, false, err
+	}
+	newConfigMaps, err := makeRulesConfigMaps(cr, shardNum, newRules, prevAssignment)
+	if err != nil {
+		return nil, false, err
+	}
+	ruleObjects := makeRuleObjects(cr, newConfigMaps)
+	if cr.IsRulesStorageSecret() {
+		hasChanges, err := reconcileRulesSecrets(ctx, rclient, cr, newConfigMaps)
+		if err != nil {
+			return nil, false, err
+		}
+		if hasChanges {
+			if err := reloadRules(ctx, rclient, cr, shardNum, ruleObjects); err != nil {
+				return nil, false, err
+			}
+		}
+		return ruleObjects, hasChanges, nil
+	}
+	if isRulesServerSideApply(cr) {
+		hasChanges, err := applyRulesConfigMaps(ctx, rclient, newConfigMaps)
+		if err != nil {
+			return nil, false, err
+		}
+		if hasChanges {
+			if err := reloadRules(ctx, rclient, cr, shardNum, ruleObjects); err != nil {
+				return nil, false, err
+			}
+		}
+		return ruleObjects, hasChanges, nil
+	}
 	currentCMs := make([]corev1.ConfigMap, len(newConfigMaps))
 	for idx, cm := range newConfigMaps {
 		var existCM corev1.ConfigMap
@@ -68,16 +176,11 @@ func reconcileConfigsData(ctx context.Context, rclient client.Client, cr *vmv1be
 			if errors.IsNotFound(err) {
 				continue
 			}
-			return nil, err
+			return nil, false, err
 		}
 		currentCMs[idx] = existCM
 	}
 
-	newConfigMapNames := make([]string, 0, len(newConfigMaps))
-	for _, cm := range newConfigMaps {
-		newConfigMapNames = append(newConfigMapNames, cm.Name)
-	}
-
 	if len(currentCMs) == 0 {
 		for _, cm := range newConfigMaps {
 			logger.WithContext(ctx).Info(fmt.Sprintf("creating new ConfigMap %s for rules", cm.Name))
@@ -86,14 +189,13 @@ func reconcileConfigsData(ctx context.Context, rclient client.Client, cr *vmv1be
 				if errors.IsAlreadyExists(err) {
 					continue
 				}
-				return nil, fmt.Errorf("failed to create Configmap: %s, err: %w", cm.Name, err)
+				return nil, false, fmt.Errorf("failed to create Configmap: %s, err: %w", cm.Name, err)
 			}
 		}
-		return newConfigMapNames, nil
+		return ruleObjects, false, nil
 	}
 
 	// sort
-	sort.Strings(newConfigMapNames)
 	sort.Slice(currentCMs, func(i, j int) bool {
 		return currentCMs[i].Name < currentCMs[j].Name
 	})
@@ -109,27 +211,311 @@ func reconcileConfigsData(ctx context.Context, rclient client.Client, cr *vmv1be
 			if errors.IsAlreadyExists(err) {
 				continue
 			}
-			return nil, fmt.Errorf("failed to create new rules Configmap: %s, err: %w", cm.Name, err)
+			return nil, false, fmt.Errorf("failed to create new rules Configmap: %s, err: %w", cm.Name, err)
 		}
 	}
 	for _, cm := range toUpdate {
 		if err := finalize.FreeIfNeeded(ctx, rclient, &cm); err != nil {
-			return nil, err
+			return nil, false, err
 		}
 		logger.WithContext(ctx).Info(fmt.Sprintf("updating ConfigMap %s configuration", cm.Name))
 		if err := rclient.Update(ctx, &cm); err != nil {
-			return nil, fmt.Errorf("failed to update rules Configmap: %s, err: %w", cm.Name, err)
+			return nil, false, fmt.Errorf("failed to update rules Configmap: %s, err: %w", cm.Name, err)
+		}
+	}
+
+	hasChanges := len(toCreate) > 0 || len(toUpdate) > 0
+	if hasChanges {
+		if err := reloadRules(ctx, rclient, cr, shardNum, ruleObjects); err != nil {
+			return nil, false, err
 		}
 	}
+	return ruleObjects, hasChanges, nil
+}
 
-	if len(toCreate) > 0 || len(toUpdate) > 0 {
-		// trigger sync for configmap
+// reloadRules triggers vmalert rules reload after rule objects update.
+// By default, pods annotation is changed in order to force kubelet to sync mounted volumes and config-reloader performs in-place reload.
+// With rolloutOnRuleChange, rules checksum is set at deployment or statefulSet pod template and it performs rolling restart of pods
+func reloadRules(ctx context.Context, rclient client.Client, cr *vmv1beta1.VMAlert, shardNum int, ruleObjects []RuleObject) error {
+	if !ptr.Deref(cr.Spec.RolloutOnRuleChange, false) {
+		// trigger sync for rule objects
 		logger.WithContext(ctx).Info("triggering pod config reload by changing annotation")
 		if err := k8stools.UpdatePodAnnotations(ctx, rclient, cr.PodLabels(), cr.Namespace); err != nil {
 			logger.WithContext(ctx).Error(err, "failed to update vmalert pod cm-sync annotation")
 		}
+		return nil
+	}
+	checksum := ruleObjectsChecksum(ruleObjects)
+	patch, err := json.Marshal(map[string]any{
+		"spec": map[string]any{
+			"template": map[string]any{
+				"metadata": map[string]any{
+					"annotations": map[string]string{vmv1beta1.VMAlertRulesChecksumAnnotation: checksum},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("cannot build rules checksum patch: %w", err)
+	}
+	objMeta := metav1.ObjectMeta{Name: vmAlertDeploymentName(cr, shardNum), Namespace: cr.Namespace}
+	var dep client.Object = &appsv1.Deployment{ObjectMeta: objMeta}
+	kind := "deployment"
+	if cr.Spec.StatefulMode {
+		// statefulSet changes are rolled out by the next VMAlert reconcile
+		dep = &appsv1.StatefulSet{ObjectMeta: objMeta}
+		kind = "statefulset"
+	}
+	logger.WithContext(ctx).Info(fmt.Sprintf("triggering rollout of %s=%s with rules checksum=%s", kind, objMeta.Name, checksum))
+	if err := rclient.Patch(ctx, dep, client.RawPatch(types.MergePatchType, patch)); err != nil {
+		// workload will be created with actual checksum
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("cannot patch %s=%s with rules checksum: %w", kind, objMeta.Name, err)
+	}
+	return nil
+}
+
+// ruleObjectsChecksum returns fnv hash of the given rule objects content.
+// It changes only if rule files content changes
+func ruleObjectsChecksum(ruleObjects []RuleObject) string {
+	objects := make([]RuleObject, len(ruleObjects))
+	copy(objects, ruleObjects)
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].Name < objects[j].Name
+	})
+	h := fnv.New64a()
+	for _, obj := range objects {
+		h.Write([]byte(obj.Name))     //nolint:errcheck
+		h.Write([]byte(obj.Checksum)) //nolint:errcheck
+	}
+	return strconv.FormatUint(h.Sum64(), 16)
+}
+
+// ruleObjectVolumeName returns volume name for the given rule object.
+// Volume name is limited to 63 chars, so long names are truncated and suffixed with name hash
+func ruleObjectVolumeName(name string) string {
+	if len(name) <= validation.DNS1123LabelMaxLength {
+		return name
+	}
+	h := fnv.New64a()
+	h.Write([]byte(name)) //nolint:errcheck
+	suffix := "-" + strconv.FormatUint(h.Sum64(), 16)
+	return strings.TrimRight(name[:validation.DNS1123LabelMaxLength-len(suffix)], "-") + suffix
+}
+
+// makeRuleObjects builds rule objects for the given rules configmaps.
+// With namespace directories layout each rule file key is mapped to <namespace>/<name>.yaml path
+func makeRuleObjects(cr *vmv1beta1.VMAlert, cms []corev1.ConfigMap) []RuleObject {
+	useNamespaceDirs := ptr.Deref(cr.Spec.UseNamespaceRuleDirs, false)
+	objects := make([]RuleObject, 0, len(cms))
+	for _, cm := range cms {
+		obj := RuleObject{Name: cm.Name, Checksum: cm.Annotations[vmv1beta1.VMAlertRulesChecksumAnnotation]}
+		if useNamespaceDirs {
+			for key := range cm.Data {
+				obj.Items = append(obj.Items, corev1.KeyToPath{Key: key, Path: ruleFilePath(key)})
+			}
+			sort.Slice(obj.Items, func(i, j int) bool {
+				return obj.Items[i].Key < obj.Items[j].Key
+			})
+		}
+		objects = append(objects, obj)
+	}
+	return objects
+}
+
+// ruleFileKey returns rule file key for the given namespace and name
+// vmalert reads rule files in lexical order, so files with order are prefixed with zero padded order value
+func ruleFileKey(cr *vmv1beta1.VMAlert, namespace, name string, order *int) string {
+	var prefix string
+	if order != nil {
+		prefix = fmt.Sprintf("%04d-", *order)
+	}
+	if useNamespaceRuleFileKeys(cr) {
+		return fmt.Sprintf("%s%s%s%s.yaml", namespace, ruleFileNamespaceSeparator, prefix, name)
 	}
-	return newConfigMapNames, nil
+	return fmt.Sprintf("%s%s-%s.yaml", prefix, namespace, name)
+}
+
+// useNamespaceRuleFileKeys checks if rule file keys must contain namespace separator
+func useNamespaceRuleFileKeys(cr *vmv1beta1.VMAlert) bool {
+	return ptr.Deref(cr.Spec.UseNamespaceRuleDirs, false) || cr.IsRuleConfigMapPerNamespace()
+}
+
+// ruleFileNamespace returns namespace of the given rule file key with namespace separator
+func ruleFileNamespace(cr *vmv1beta1.VMAlert, key string) string {
+	namespace, _, ok := strings.Cut(key, ruleFileNamespaceSeparator)
+	if !ok {
+		return cr.Namespace
+	}
+	return namespace
+}
+
+// ruleFilePath converts rule file key of namespace directories layout into <namespace>/<name>.yaml path
+func ruleFilePath(key string) string {
+	namespace, name, ok := strings.Cut(key, ruleFileNamespaceSeparator)
+	if !ok {
+		return key
+	}
+	return path.Join(namespace, name)
+}
+
+// getRuleFilesAssignment returns index of the existing rules ConfigMap or Secret for each stored rule file of the given shard
+func getRuleFilesAssignment(ctx context.Context, rclient client.Client, cr *vmv1beta1.VMAlert, shardNum int) (map[string]int, error) {
+	namePrefix := ruleConfigMapName(cr, shardNum) + "-"
+	perNamespace := cr.IsRuleConfigMapPerNamespace()
+	bucketIndex := func(name string) int {
+		suffix, ok := strings.CutPrefix(name, namePrefix)
+		if !ok {
+			return -1
+		}
+		// per namespace ConfigMaps have <namespace>-<num> suffix
+		if perNamespace {
+			n := strings.LastIndex(suffix, "-")
+			if n < 0 {
+				return -1
+			}
+			suffix = suffix[n+1:]
+		}
+		idx, err := strconv.Atoi(suffix)
+		if err != nil {
+			return -1
+		}
+		return idx
+	}
+	assignment := make(map[string]int)
+	if cr.IsRulesStorageSecret() {
+		var secretList corev1.SecretList
+		if err := rclient.List(ctx, &secretList, cr.RulesConfigMapSelector()); err != nil {
+			return nil, fmt.Errorf("cannot list rules Secrets: %w", err)
+		}
+		for _, s := range secretList.Items {
+			idx := bucketIndex(s.Name)
+			if idx < 0 {
+				continue
+			}
+			for filename := range s.Data {
+				assignment[filename] = idx
+			}
+		}
+		return assignment, nil
+	}
+	var cmList corev1.ConfigMapList
+	if err := rclient.List(ctx, &cmList, cr.RulesConfigMapSelector()); err != nil {
+		return nil, fmt.Errorf("cannot list rules ConfigMaps: %w", err)
+	}
+	for _, cm := range cmList.Items {
+		idx := bucketIndex(cm.Name)
+		if idx < 0 {
+			continue
+		}
+		for filename := range cm.Data {
+			assignment[filename] = idx
+		}
+		for filename := range cm.BinaryData {
+			assignment[filename] = idx
+		}
+	}
+	return assignment, nil
+}
+
+// reconcileRulesSecrets stores content of the given rules configmaps at secrets with the same names and metadata
+// It reports whether any secret was created or updated
+func reconcileRulesSecrets(ctx context.Context, rclient client.Client, cr *vmv1beta1.VMAlert, newConfigMaps []corev1.ConfigMap) (bool, error) {
+	var hasChanges bool
+	for _, cm := range newConfigMaps {
+		newSecret := makeRulesSecret(&cm)
+		var currentSecret corev1.Secret
+		if err := rclient.Get(ctx, types.NamespacedName{Namespace: newSecret.Namespace, Name: newSecret.Name}, &currentSecret); err != nil {
+			if !errors.IsNotFound(err) {
+				return false, err
+			}
+			logger.WithContext(ctx).Info(fmt.Sprintf("creating new Secret %s for rules", newSecret.Name))
+			if err := rclient.Create(ctx, newSecret); err != nil {
+				return false, fmt.Errorf("failed to create rules Secret: %s, err: %w", newSecret.Name, err)
+			}
+			hasChanges = true
+			continue
+		}
+		if err := finalize.FreeIfNeeded(ctx, rclient, &currentSecret); err != nil {
+			return false, err
+		}
+		newSecret.Annotations = labels.Merge(currentSecret.Annotations, newSecret.Annotations)
+		vmv1beta1.AddFinalizer(newSecret, &currentSecret)
+		if equality.Semantic.DeepEqual(newSecret.Data, currentSecret.Data) &&
+			equality.Semantic.DeepEqual(newSecret.Labels, currentSecret.Labels) &&
+			equalRulesAnnotations(newSecret.Annotations, currentSecret.Annotations) {
+			continue
+		}
+		logger.WithContext(ctx).Info(fmt.Sprintf("updating Secret %s configuration", newSecret.Name))
+		if err := rclient.Update(ctx, newSecret); err != nil {
+			return false, fmt.Errorf("failed to update rules Secret: %s, err: %w", newSecret.Name, err)
+		}
+		hasChanges = true
+	}
+	return hasChanges, nil
+}
+
+// makeRulesSecret converts rules configmap into secret with the same metadata
+func makeRulesSecret(cm *corev1.ConfigMap) *corev1.Secret {
+	s := &corev1.Secret{
+		ObjectMeta: *cm.ObjectMeta.DeepCopy(),
+		Type:       corev1.SecretTypeOpaque,
+		Data:       make(map[string][]byte, len(cm.Data)+len(cm.BinaryData)),
+	}
+	for filename, content := range cm.Data {
+		s.Data[filename] = []byte(content)
+	}
+	for filename, content := range cm.BinaryData {
+		s.Data[filename] = content
+	}
+	return s
+}
+
+// removeStaleRules removes rule files objects, which are not used by VMAlert:
+// objects of the storage kind, which is not configured, and objects missing at ruleObjects.
+// The latter are left after decrease of rule files buckets or shards count.
+// it must be called after vmalert deployment update, since objects could be still mounted to the pods
+func removeStaleRules(ctx context.Context, rclient client.Client, cr *vmv1beta1.VMAlert, ruleObjects [][]RuleObject) error {
+	inUse := make(map[string]struct{})
+	for _, shardObjects := range ruleObjects {
+		for _, obj := range shardObjects {
+			inUse[obj.Name] = struct{}{}
+		}
+	}
+	isSecretStorage := cr.IsRulesStorageSecret()
+	var staleObjects []client.Object
+	var cmList corev1.ConfigMapList
+	if err := rclient.List(ctx, &cmList, cr.RulesConfigMapSelector()); err != nil {
+		return fmt.Errorf("cannot list rules ConfigMaps: %w", err)
+	}
+	for i := range cmList.Items {
+		if _, ok := inUse[cmList.Items[i].Name]; ok && !isSecretStorage {
+			continue
+		}
+		staleObjects = append(staleObjects, &cmList.Items[i])
+	}
+	var secretList corev1.SecretList
+	if err := rclient.List(ctx, &secretList, cr.RulesConfigMapSelector()); err != nil {
+		return fmt.Errorf("cannot list rules Secrets: %w", err)
+	}
+	for i := range secretList.Items {
+		if _, ok := inUse[secretList.Items[i].Name]; ok && isSecretStorage {
+			continue
+		}
+		staleObjects = append(staleObjects, &secretList.Items[i])
+	}
+	for _, obj := range staleObjects {
+		logger.WithContext(ctx).Info(fmt.Sprintf("removing stale rules %T=%s", obj, obj.GetName()))
+		if err := finalize.RemoveFinalizer(ctx, rclient, obj); err != nil {
+			return err
+		}
+		if err := finalize.SafeDelete(ctx, rclient, obj); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // rulesCMDiff - calculates diff between existing at k8s (current) configmaps with rules
@@ -151,8 +537,9 @@ func rulesCMDiff(currentCMs []corev1.ConfigMap, newCMs []corev1.ConfigMap) (toCr
 				newCM.Annotations = labels.Merge(currentCM.Annotations, newCM.Annotations)
 				vmv1beta1.AddFinalizer(&newCM, &currentCM)
 				if equality.Semantic.DeepEqual(newCM.Data, currentCM.Data) &&
+					equality.Semantic.DeepEqual(newCM.BinaryData, currentCM.BinaryData) &&
 					equality.Semantic.DeepEqual(newCM.Labels, currentCM.Labels) &&
-					equality.Semantic.DeepEqual(newCM.Annotations, currentCM.Annotations) {
+					equalRulesAnnotations(newCM.Annotations, currentCM.Annotations) {
 					break
 				}
 				toUpdate = append(toUpdate, newCM)
@@ -166,138 +553,882 @@ func rulesCMDiff(currentCMs []corev1.ConfigMap, newCMs []corev1.ConfigMap) (toCr
 	return toCreate, toUpdate
 }
 
-func reconcileVMAlertConfig(ctx context.Context, rclient client.Client, cr *vmv1beta1.VMAlert, childCR *vmv1beta1.VMRule) ([]string, error) {
-	rulesData, vmRules, err := selectRulesContent(ctx, rclient, cr)
+func reconcileVMAlertConfig(ctx context.Context, rclient client.Client, cr *vmv1beta1.VMAlert, childCR *vmv1beta1.VMRule, recorder record.EventRecorder) ([][]RuleObject, error) {
+	rulesDataByShard, vmRules, stats, err := selectRulesContent(ctx, rclient, cr)
 	if err != nil {
 		return nil, err
 	}
-	// peform config maps content update
-	ruleCMNames, err := reconcileConfigsData(ctx, rclient, cr, rulesData)
+	var ruleFilesCnt int
+	for _, rulesData := range rulesDataByShard {
+		ruleFilesCnt += len(rulesData)
+	}
+	noRulesSelected, err := addPlaceholderRules(ctx, rclient, cr, rulesDataByShard)
 	if err != nil {
 		return nil, err
 	}
+	setNoRulesSelectedCondition(cr, noRulesSelected)
+	// peform config maps content update
+	ruleObjects := make([][]RuleObject, 0, len(rulesDataByShard))
+	var cmsCount int
+	var rulesUpdated bool
+	for shardNum, rulesData := range rulesDataByShard {
+		shardObjects, reloaded, err := reconcileConfigsData(ctx, rclient, cr, shardNum, rulesData)
+		if err != nil {
+			return nil, err
+		}
+		ruleObjects = append(ruleObjects, shardObjects)
+		cmsCount += len(shardObjects)
+		rulesUpdated = rulesUpdated || reloaded
+	}
+	if err := reconcileRulesReloadCheck(ctx, rclient, cr, rulesUpdated, rulesDataByShard); err != nil {
+		return nil, err
+	}
+	ruleConfigMaps.WithLabelValues(cr.Namespace, cr.Name).Set(float64(cmsCount))
+	setRuleSyncStatus(cr, ruleObjects, ruleFilesCnt, stats)
 	parentObject := fmt.Sprintf("%s.%s.vmalert", cr.Name, cr.Namespace)
+	events := reconcile.ChildObjectEvents{
+		Object:         "rule",
+		Parent:         fmt.Sprintf("vmalert=%s/%s", cr.Namespace, cr.Name),
+		RejectedReason: ruleRejectedEventReason,
+		AcceptedReason: ruleAcceptedEventReason,
+	}
 	if childCR != nil {
 		for _, rule := range vmRules {
 			if rule.Name == childCR.Name && rule.Namespace == childCR.Namespace {
 				// fast path update a single object that triggered event
 				// it should be fast path for the most cases
-				if err := reconcile.StatusForChildObjects(ctx, rclient, parentObject, []*vmv1beta1.VMRule{rule}); err != nil {
+				childRules := []*vmv1beta1.VMRule{rule}
+				reconcile.ChildObjectsEvents(recorder, parentObject, childRules, events)
+				if err := reconcile.StatusForChildObjects(ctx, rclient, parentObject, childRules); err != nil {
 					return nil, err
 				}
-				return ruleCMNames, nil
+				return ruleObjects, nil
 			}
 		}
 	}
-	if err := reconcile.StatusForChildObjects(ctx, rclient, parentObject, vmRules); err != nil {
+	reconcile.ChildObjectsEvents(recorder, parentObject, vmRules, events)
+	if err := reconcile.StatusForChildObjectsWithWorkers(ctx, rclient, parentObject, vmRules, rulesProcessingWorkers()); err != nil {
 		return nil, err
 	}
-	return ruleCMNames, nil
+	return ruleObjects, nil
+}
+
+// setRuleSyncStatus updates rule sync status of the given VMAlert
+// sync time is preserved if status is not changed, it prevents VMAlert status updates on each reconcile
+func setRuleSyncStatus(cr *vmv1beta1.VMAlert, ruleObjects [][]RuleObject, ruleFilesCnt int, stats rulesStats) {
+	sync := &vmv1beta1.VMAlertRuleSyncStatus{
+		RuleFiles:     ruleFilesCnt,
+		Groups:        stats.groups,
+		Rules:         stats.rules,
+		RejectedRules: stats.rejected,
+	}
+	// rule files of namespace directories layout are mounted with volume items
+	// and must be tracked in order to update vmalert volumes on change
+	var filesHash hash.Hash64
+	if ptr.Deref(cr.Spec.UseNamespaceRuleDirs, false) {
+		filesHash = fnv.New64a()
+	}
+	for _, shardObjects := range ruleObjects {
+		for _, obj := range shardObjects {
+			sync.ConfigMaps = append(sync.ConfigMaps, obj.Name)
+			if filesHash == nil {
+				continue
+			}
+			for _, item := range obj.Items {
+				filesHash.Write([]byte(obj.Name + "/" + item.Path + "\n")) //nolint:errcheck
+			}
+		}
+	}
+	if filesHash != nil {
+		sync.RuleFilesHash = strconv.FormatUint(filesHash.Sum64(), 16)
+	}
+	if prev := cr.Status.RuleSync; prev != nil {
+		sync.LastSyncTime = prev.LastSyncTime
+		if equality.Semantic.DeepEqual(sync, prev) {
+			return
+		}
+	}
+	sync.LastSyncTime = metav1.Now()
+	cr.Status.RuleSync = sync
 }
 
-func selectRulesContent(ctx context.Context, rclient client.Client, cr *vmv1beta1.VMAlert) (map[string]string, []*vmv1beta1.VMRule, error) {
+// UpdateRuleSyncStatus persists rule sync status and conditions of the given VMAlert.
+// It must be used, if rules were synced outside of VMAlert reconcile, other status fields are not changed
+func UpdateRuleSyncStatus(ctx context.Context, rclient client.Client, cr *vmv1beta1.VMAlert) error {
+	data, err := json.Marshal(map[string]any{"status": map[string]any{"ruleSync": cr.Status.RuleSync, "conditions": cr.Status.Conditions}})
+	if err != nil {
+		return fmt.Errorf("cannot build rule sync status patch: %w", err)
+	}
+	if err := rclient.Status().Patch(ctx, cr, client.RawPatch(types.MergePatchType, data)); err != nil {
+		return fmt.Errorf("cannot update rule sync status: %w", err)
+	}
+	return nil
+}
+
+// addPlaceholderRules adds placeholder rule file to the shards without rules,
+// so vmalert always has a valid rule file to start with.
+// It returns true if no rules were selected for all shards.
+func addPlaceholderRules(ctx context.Context, rclient client.Client, cr *vmv1beta1.VMAlert, rulesDataByShard []map[string]string) (bool, error) {
+	noRulesSelected := true
+	var hasEmptyShards bool
+	for _, rulesData := range rulesDataByShard {
+		if len(rulesData) > 0 {
+			noRulesSelected = false
+			continue
+		}
+		hasEmptyShards = true
+	}
+	if !hasEmptyShards {
+		return noRulesSelected, nil
+	}
+	content := placeholderRuleFileContent
+	if cr.Spec.PlaceholderRulesRef != nil {
+		data, err := k8stools.FetchConfigMapContentByKey(ctx, rclient,
+			&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: cr.Spec.PlaceholderRulesRef.Name, Namespace: cr.Namespace}},
+			cr.Spec.PlaceholderRulesRef.Key)
+		if err != nil {
+			return false, fmt.Errorf("cannot fetch placeholder rules configmap: %s, err: %w", cr.Spec.PlaceholderRulesRef.Name, err)
+		}
+		content = data
+	}
+	fileName := placeholderRuleFileName
+	if useNamespaceRuleFileKeys(cr) {
+		// vmalert reads rule files only from namespace directories
+		fileName = cr.Namespace + ruleFileNamespaceSeparator + placeholderRuleFileName
+	}
+	for _, rulesData := range rulesDataByShard {
+		if len(rulesData) == 0 {
+			rulesData[fileName] = content
+		}
+	}
+	return noRulesSelected, nil
+}
+
+// setNoRulesSelectedCondition updates NoRulesSelected condition at the given VMAlert status
+func setNoRulesSelectedCondition(cr *vmv1beta1.VMAlert, noRulesSelected bool) {
+	cond := vmv1beta1.Condition{
+		Type:   vmv1beta1.VMAlertNoRulesSelectedCondition,
+		Status: metav1.ConditionFalse,
+		Reason: "RulesSelected",
+	}
+	if noRulesSelected {
+		cond.Status = metav1.ConditionTrue
+		cond.Reason = vmv1beta1.VMAlertNoRulesSelectedCondition
+		cond.Message = "no VMRules were selected, placeholder rule file is used"
+	}
+	setVMAlertCondition(cr, cond)
+}
+
+// setRulesLimitReachedCondition updates RulesLimitReached condition at the given VMAlert status
+func setRulesLimitReachedCondition(cr *vmv1beta1.VMAlert, limitReached bool, skippedRules []string) {
+	cond := vmv1beta1.Condition{
+		Type:   vmv1beta1.VMAlertRulesLimitReachedCondition,
+		Status: metav1.ConditionFalse,
+		Reason: "RulesWithinLimit",
+	}
+	if limitReached {
+		cond.Status = metav1.ConditionTrue
+		cond.Reason = vmv1beta1.VMAlertRulesLimitReachedCondition
+		cond.Message = fmt.Sprintf("maxTotalRules=%d limit is reached, skipped VMRules: %s", cr.Spec.MaxTotalRules, strings.Join(skippedRules, ","))
+	}
+	setVMAlertCondition(cr, cond)
+}
+
+// setVMAlertCondition upserts the given condition at VMAlert status
+// transition times are preserved if condition status is not changed
+func setVMAlertCondition(cr *vmv1beta1.VMAlert, cond vmv1beta1.Condition) {
+	ctm := metav1.Now()
+	cond.ObservedGeneration = cr.Generation
+	cond.LastTransitionTime = ctm
+	cond.LastUpdateTime = ctm
+	for idx, c := range cr.Status.Conditions {
+		if c.Type != cond.Type {
+			continue
+		}
+		if c.Status == cond.Status {
+			cond.LastTransitionTime = c.LastTransitionTime
+			cond.LastUpdateTime = c.LastUpdateTime
+		}
+		cr.Status.Conditions[idx] = cond
+		return
+	}
+	cr.Status.Conditions = append(cr.Status.Conditions, cond)
+}
+
+// removeVMAlertCondition removes condition with the given type from VMAlert status
+func removeVMAlertCondition(cr *vmv1beta1.VMAlert, condType string) {
+	var conds []vmv1beta1.Condition
+	for _, c := range cr.Status.Conditions {
+		if c.Type != condType {
+			conds = append(conds, c)
+		}
+	}
+	cr.Status.Conditions = conds
+}
+
+// rulesStats holds numbers of generated and rejected rules
+type rulesStats struct {
+	groups   int
+	rules    int
+	rejected int
+}
+
+// selectRulesContent returns rule files content per vmalert shard
+func selectRulesContent(ctx context.Context, rclient client.Client, cr *vmv1beta1.VMAlert) ([]map[string]string, []*vmv1beta1.VMRule, rulesStats, error) {
+	var stats rulesStats
 	var vmRules []*vmv1beta1.VMRule
 	var namespacedNames []string
+	denySelector := labels.Nothing()
+	if cr.Spec.RuleDenySelector != nil {
+		s, err := metav1.LabelSelectorAsSelector(cr.Spec.RuleDenySelector)
+		if err != nil {
+			return nil, nil, stats, fmt.Errorf("cannot parse ruleDenySelector: %w", err)
+		}
+		denySelector = s
+	}
 	if err := k8stools.VisitObjectsForSelectorsAtNs(ctx, rclient, cr.Spec.RuleNamespaceSelector, cr.Spec.RuleSelector, cr.Namespace, cr.Spec.SelectAllByDefault,
 		func(list *vmv1beta1.VMRuleList) {
 			for _, item := range list.Items {
 				if !item.DeletionTimestamp.IsZero() {
 					continue
 				}
+				// excluded rules must not be reported at status and metrics
+				if item.Annotations[vmv1beta1.VMAlertIgnoreRuleAnnotation] == "true" || denySelector.Matches(labels.Set(item.Labels)) {
+					continue
+				}
 				vmRules = append(vmRules, item.DeepCopy())
 				namespacedNames = append(namespacedNames, fmt.Sprintf("%s/%s", item.Namespace, item.Name))
 			}
 		}); err != nil {
-		return nil, nil, err
+		return nil, nil, stats, err
 	}
 
-	rules := make(map[string]string, len(vmRules))
+	shardsCount := cr.RuleShardsCount()
+	rulesByShard := make([]map[string]string, shardsCount)
+	for shardNum := range rulesByShard {
+		rulesByShard[shardNum] = make(map[string]string, len(vmRules)/shardsCount)
+	}
 
 	if cr.NeedDedupRules() {
 		logger.WithContext(ctx).Info("deduplicating vmalert rules")
 		vmRules = deduplicateRules(ctx, vmRules)
 	}
-	var brokenRulesCnt int
-	for _, pRule := range vmRules {
-		if !build.MustSkipRuntimeValidation {
-			if err := pRule.Validate(); err != nil {
-				pRule.Status.CurrentSyncError = err.Error()
-				brokenRulesCnt++
-				continue
-			}
+	// rules must be processed in the same order on each reconcile
+	// in order to skip the same rules on limits check
+	sort.Slice(vmRules, func(i, j int) bool {
+		if vmRules[i].Namespace != vmRules[j].Namespace {
+			return vmRules[i].Namespace < vmRules[j].Namespace
 		}
-		content, err := generateContent(pRule.Spec, cr.Spec.EnforcedNamespaceLabel, pRule.Namespace)
-		if err != nil {
-			pRule.Status.CurrentSyncError = fmt.Sprintf("cannot generate content for rule: %s, err :%s", pRule.Name, err)
+		return vmRules[i].Name < vmRules[j].Name
+	})
+	// rules are validated and rendered concurrently,
+	// results are collected in rules order in order to keep generated content stable
+	results := processRules(ctx, rclient, cr, vmRules, shardsCount)
+	var brokenRulesCnt, totalRulesCnt int
+	var skippedByLimit []string
+	// identical groups are deduplicated only among accepted VMRules,
+	// so the group isn't lost if VMRule with its first copy is rejected
+	uniqGroups := make(map[uint64]string)
+	for idx, pRule := range vmRules {
+		res := results[idx]
+		if res.broken {
 			brokenRulesCnt++
+		}
+		if res.contentByShard == nil {
 			continue
 		}
-		rules[fmt.Sprintf("%s-%s.yaml", pRule.Namespace, pRule.Name)] = content
+		var groupIDs []uint64
+		if cr.NeedDedupRules() {
+			ids, err := deduplicateRuleGroups(ctx, cr, pRule, &res, uniqGroups, shardsCount)
+			if err != nil {
+				pRule.Status.CurrentSyncError = fmt.Sprintf("cannot deduplicate rule groups: %s", err)
+				if !res.broken {
+					brokenRulesCnt++
+				}
+				continue
+			}
+			groupIDs = ids
+		}
+		if cr.Spec.MaxTotalRules > 0 {
+			if len(skippedByLimit) > 0 || totalRulesCnt+res.rulesCnt > cr.Spec.MaxTotalRules {
+				pRule.Status.CurrentSyncError = fmt.Sprintf("VMRule is skipped, since maxTotalRules=%d limit is reached", cr.Spec.MaxTotalRules)
+				skippedByLimit = append(skippedByLimit, fmt.Sprintf("%s/%s", pRule.Namespace, pRule.Name))
+				if !res.broken {
+					brokenRulesCnt++
+				}
+				continue
+			}
+			totalRulesCnt += res.rulesCnt
+		}
+		for _, groupID := range groupIDs {
+			uniqGroups[groupID] = fmt.Sprintf("%s/%s", pRule.Namespace, pRule.Name)
+		}
+		for shardNum, content := range res.contentByShard {
+			if content == "" {
+				continue
+			}
+			rulesByShard[shardNum][ruleFileKey(cr, pRule.Namespace, pRule.Name, res.order)] = content
+		}
+		stats.groups += len(pRule.Spec.Groups)
+		stats.rules += res.rulesCnt
 	}
+	setRulesLimitReachedCondition(cr, len(skippedByLimit) > 0, skippedByLimit)
 	logger.SelectedObjects(ctx, "VMRules", len(namespacedNames), brokenRulesCnt, namespacedNames)
 	badConfigsTotal.Add(float64(brokenRulesCnt))
-	return rules, vmRules, nil
+	var invalidRulesCnt int
+	for _, pRule := range vmRules {
+		if pRule.Status.CurrentSyncError != "" {
+			invalidRulesCnt++
+		}
+	}
+	stats.rejected = invalidRulesCnt
+	selectedRules.WithLabelValues(cr.Namespace, cr.Name).Set(float64(len(namespacedNames)))
+	invalidRules.WithLabelValues(cr.Namespace, cr.Name).Set(float64(invalidRulesCnt))
+	return rulesByShard, vmRules, stats, nil
 }
 
-func generateContent(promRule vmv1beta1.VMRuleSpec, enforcedNsLabel, ns string) (string, error) {
-	if enforcedNsLabel != "" {
-		for gi, group := range promRule.Groups {
-			for ri := range group.Rules {
-				if len(promRule.Groups[gi].Rules[ri].Labels) == 0 {
-					promRule.Groups[gi].Rules[ri].Labels = map[string]string{}
+// processedRule holds result of VMRule validation and content generation
+type processedRule struct {
+	order          *int
+	contentByShard []string
+	rulesCnt       int
+	// broken is set if any error was found at the rule,
+	// rule could be partially generated with errors
+	broken bool
+}
+
+// rulesProcessingWorkers returns number of concurrent workers for VMRules processing
+func rulesProcessingWorkers() int {
+	if n := config.MustGetBaseConfig().VMAlertRulesProcessingWorkers; n > 0 {
+		return n
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+// processRules validates and generates content for the given rules with bounded number of workers.
+// Results are returned in the same order as the given rules
+func processRules(ctx context.Context, rclient client.Client, cr *vmv1beta1.VMAlert, vmRules []*vmv1beta1.VMRule, shardsCount int) []processedRule {
+	results := make([]processedRule, len(vmRules))
+	workers := min(rulesProcessingWorkers(), len(vmRules))
+	nsTenants := make(map[string]string)
+	var nsTenantsMu sync.Mutex
+	getTenant := func(namespace string) (string, error) {
+		nsTenantsMu.Lock()
+		defer nsTenantsMu.Unlock()
+		return getNamespaceTenant(ctx, rclient, cr.Spec.TenantLabelFromNamespaceAnnotation, namespace, nsTenants)
+	}
+	workCh := make(chan int)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range workCh {
+				// each worker owns the rule by its index, so rule status could be safely modified
+				results[idx] = processRule(cr, vmRules[idx], shardsCount, getTenant)
+			}
+		}()
+	}
+	for idx := range vmRules {
+		workCh <- idx
+	}
+	close(workCh)
+	wg.Wait()
+	return results
+}
+
+// processRule validates the given VMRule and generates rule files content for it.
+// Content is nil if rule must be skipped, sync error is written into rule status
+func processRule(cr *vmv1beta1.VMAlert, pRule *vmv1beta1.VMRule, shardsCount int, getTenant func(namespace string) (string, error)) processedRule {
+	var res processedRule
+	if err := checkRuleLimits(cr, &pRule.Spec); err != nil {
+		pRule.Status.CurrentSyncError = err.Error()
+		res.broken = true
+		return res
+	}
+	order, err := pRule.RuleOrder()
+	if err != nil {
+		pRule.Status.CurrentSyncError = err.Error()
+		res.broken = true
+		return res
+	}
+	res.order = order
+	// expressions are checked per group, so only groups with broken expressions are excluded
+	if !ptr.Deref(cr.Spec.DisableRuleExprValidation, false) {
+		if err := validateRuleExpressions(&pRule.Spec); err != nil {
+			pRule.Status.CurrentSyncError = err.Error()
+			res.broken = true
+			if len(pRule.Spec.Groups) == 0 {
+				return res
+			}
+		}
+	}
+	if !build.MustSkipRuntimeValidation {
+		if err := pRule.Validate(); err != nil {
+			pRule.Status.CurrentSyncError = err.Error()
+			res.broken = true
+			return res
+		}
+	}
+	if err := checkRulePolicies(cr.Spec.RulePolicies, &pRule.Spec); err != nil {
+		pRule.Status.CurrentSyncError = err.Error()
+		res.broken = true
+		return res
+	}
+	if cr.Spec.TenantLabelFromNamespaceAnnotation != "" {
+		tenant, err := getTenant(pRule.Namespace)
+		if err != nil {
+			pRule.Status.CurrentSyncError = err.Error()
+			res.broken = true
+			return res
+		}
+		if err := enforceRuleTenant(&pRule.Spec, tenant); err != nil {
+			pRule.Status.CurrentSyncError = fmt.Sprintf("cannot enforce tenant=%q from namespace=%q annotation: %s", tenant, pRule.Namespace, err)
+			res.broken = true
+			return res
+		}
+	}
+	contentByShard, err := generateShardedContent(pRule, cr.Spec.EnforcedNamespaceLabel, cr.Spec.RuleGroupDefaults, shardsCount)
+	if err != nil {
+		pRule.Status.CurrentSyncError = fmt.Sprintf("cannot generate content for rule: %s, err :%s", pRule.Name, err)
+		res.broken = true
+		return res
+	}
+	res.contentByShard = contentByShard
+	res.rulesCnt = countRules(&pRule.Spec)
+	return res
+}
+
+// checkRuleLimits checks if the given VMRule spec exceeds per object limits of VMAlert
+func checkRuleLimits(cr *vmv1beta1.VMAlert, spec *vmv1beta1.VMRuleSpec) error {
+	if cr.Spec.MaxGroupsPerRule > 0 && len(spec.Groups) > cr.Spec.MaxGroupsPerRule {
+		return fmt.Errorf("VMRule has %d groups, which exceeds maxGroupsPerRule=%d limit", len(spec.Groups), cr.Spec.MaxGroupsPerRule)
+	}
+	if cr.Spec.MaxRulesPerGroup > 0 {
+		for _, group := range spec.Groups {
+			if len(group.Rules) > cr.Spec.MaxRulesPerGroup {
+				return fmt.Errorf("group=%q has %d rules, which exceeds maxRulesPerGroup=%d limit", group.Name, len(group.Rules), cr.Spec.MaxRulesPerGroup)
+			}
+		}
+	}
+	return nil
+}
+
+// checkRulePolicies checks labels and annotations of the given VMRule spec rules against VMAlert rule policies.
+// It returns error with all found violations
+func checkRulePolicies(policies *vmv1beta1.VMAlertRulePolicies, spec *vmv1beta1.VMRuleSpec) error {
+	if policies == nil {
+		return nil
+	}
+	var violations []string
+	for _, group := range spec.Groups {
+		for _, rule := range group.Rules {
+			name := rule.Alert
+			if rule.Record != "" {
+				if !ptr.Deref(policies.ApplyToRecordingRules, false) {
+					continue
+				}
+				name = rule.Record
+			}
+			ruleContext := fmt.Sprintf("group=%q rule=%q", group.Name, name)
+			hasLabel := func(key string) bool {
+				_, inRule := rule.Labels[key]
+				_, inGroup := group.Labels[key]
+				return inRule || inGroup
+			}
+			for _, key := range policies.RequiredLabels {
+				if !hasLabel(key) {
+					violations = append(violations, fmt.Sprintf("%s: missing required label=%q", ruleContext, key))
+				}
+			}
+			for _, key := range policies.ForbiddenLabels {
+				if hasLabel(key) {
+					violations = append(violations, fmt.Sprintf("%s: has forbidden label=%q", ruleContext, key))
+				}
+			}
+			for _, key := range policies.RequiredAnnotations {
+				if _, ok := rule.Annotations[key]; !ok {
+					violations = append(violations, fmt.Sprintf("%s: missing required annotation=%q", ruleContext, key))
+				}
+			}
+			if policies.MaxAnnotationValueLength > 0 {
+				keys := slices.Sorted(maps.Keys(rule.Annotations))
+				for _, key := range keys {
+					if valueLen := len(rule.Annotations[key]); valueLen > policies.MaxAnnotationValueLength {
+						violations = append(violations, fmt.Sprintf("%s: annotation=%q value length=%d exceeds maxAnnotationValueLength=%d", ruleContext, key, valueLen, policies.MaxAnnotationValueLength))
+					}
 				}
-				promRule.Groups[gi].Rules[ri].Labels[enforcedNsLabel] = ns
 			}
 		}
 	}
+	if len(violations) > 0 {
+		return fmt.Errorf("VMRule violates rule policies: %s", strings.Join(violations, "; "))
+	}
+	return nil
+}
+
+// countRules returns total number of rules at the given VMRule spec
+func countRules(spec *vmv1beta1.VMRuleSpec) int {
+	var cnt int
+	for _, group := range spec.Groups {
+		cnt += len(group.Rules)
+	}
+	return cnt
+}
+
+// validateRuleExpressions parses rule expressions with MetricsQL parser
+// and removes groups with unparsable expressions from the given spec.
+// Groups with non-prometheus datasource type are skipped, since it uses different query language.
+func validateRuleExpressions(spec *vmv1beta1.VMRuleSpec) error {
+	var errs []string
+	validGroups := make([]vmv1beta1.RuleGroup, 0, len(spec.Groups))
+	for _, group := range spec.Groups {
+		if group.Type != "" && group.Type != "prometheus" {
+			validGroups = append(validGroups, group)
+			continue
+		}
+		var groupErr error
+		for _, rule := range group.Rules {
+			if _, err := metricsql.Parse(rule.Expr); err != nil {
+				groupErr = fmt.Errorf("cannot parse expr=%q for group=%q: %w", rule.Expr, group.Name, err)
+				break
+			}
+		}
+		if groupErr != nil {
+			errs = append(errs, groupErr.Error())
+			continue
+		}
+		validGroups = append(validGroups, group)
+	}
+	spec.Groups = validGroups
+	if len(errs) > 0 {
+		return fmt.Errorf("rule groups with invalid expressions were skipped: %s", strings.Join(errs, ","))
+	}
+	return nil
+}
+
+// getNamespaceTenant returns tenant id from the given namespace annotation
+// tenants are cached at nsTenants by namespace name
+func getNamespaceTenant(ctx context.Context, rclient client.Client, annotation, namespace string, nsTenants map[string]string) (string, error) {
+	if tenant, ok := nsTenants[namespace]; ok {
+		return tenant, nil
+	}
+	var ns corev1.Namespace
+	if err := rclient.Get(ctx, types.NamespacedName{Name: namespace}, &ns); err != nil {
+		return "", fmt.Errorf("cannot get namespace=%q for tenant discovery: %w", namespace, err)
+	}
+	tenant := ns.Annotations[annotation]
+	if tenant == "" {
+		return "", fmt.Errorf("namespace=%q has no tenant annotation=%q, rule cannot be written to the default tenant", namespace, annotation)
+	}
+	nsTenants[namespace] = tenant
+	return tenant, nil
+}
+
+// enforceRuleTenant adds tenant filter params to each group and tenant labels to each rule of the given spec.
+// tenant must be in form accountID[:projectID]
+func enforceRuleTenant(spec *vmv1beta1.VMRuleSpec, tenant string) error {
+	accountID, projectID, _ := strings.Cut(tenant, ":")
+	if projectID == "" {
+		projectID = "0"
+	}
+	if _, err := strconv.ParseUint(accountID, 10, 32); err != nil {
+		return fmt.Errorf("cannot parse accountID: %w", err)
+	}
+	if _, err := strconv.ParseUint(projectID, 10, 32); err != nil {
+		return fmt.Errorf("cannot parse projectID: %w", err)
+	}
+	tenantLabels := map[string]string{
+		"vm_account_id": accountID,
+		"vm_project_id": projectID,
+	}
+	for gi := range spec.Groups {
+		group := &spec.Groups[gi]
+		params := make(url.Values, len(group.Params)+1)
+		for k, v := range group.Params {
+			params[k] = append([]string{}, v...)
+		}
+		for _, k := range []string{"vm_account_id", "vm_project_id"} {
+			params.Add("extra_label", fmt.Sprintf("%s=%s", k, tenantLabels[k]))
+			// group labels have priority over rule labels
+			delete(group.Labels, k)
+		}
+		group.Params = params
+		for ri := range group.Rules {
+			group.Rules[ri].Labels = labels.Merge(group.Rules[ri].Labels, tenantLabels)
+		}
+	}
+	return nil
+}
+
+// generateShardedContent generates rule file content of the given VMRule per vmalert shard.
+// Rule groups are assigned to shards with consistent hashing of group name,
+// content for shard without groups is empty.
+func generateShardedContent(pRule *vmv1beta1.VMRule, enforcedNsLabel string, groupDefaults *vmv1beta1.VMAlertRuleGroupDefaults, shardsCount int) ([]string, error) {
+	if shardsCount <= 1 {
+		content, err := generateContent(pRule, pRule.Spec, enforcedNsLabel, groupDefaults)
+		if err != nil {
+			return nil, err
+		}
+		return []string{content}, nil
+	}
+	groupsByShard := make([][]vmv1beta1.RuleGroup, shardsCount)
+	for _, group := range pRule.Spec.Groups {
+		// group names are unique only within VMRule
+		shardNum := ruleGroupShardNum(fmt.Sprintf("%s/%s/%s", pRule.Namespace, pRule.Name, group.Name), shardsCount)
+		groupsByShard[shardNum] = append(groupsByShard[shardNum], group)
+	}
+	contentByShard := make([]string, shardsCount)
+	for shardNum, groups := range groupsByShard {
+		if len(groups) == 0 {
+			continue
+		}
+		spec := pRule.Spec
+		spec.Groups = groups
+		content, err := generateContent(pRule, spec, enforcedNsLabel, groupDefaults)
+		if err != nil {
+			return nil, err
+		}
+		contentByShard[shardNum] = content
+	}
+	return contentByShard, nil
+}
+
+// ruleGroupShardNum returns shard number for the given rule group key.
+// It uses jump consistent hash, so only 1/N of groups are moved to another shard
+// on shards count change, see https://arxiv.org/abs/1406.2294
+func ruleGroupShardNum(key string, shardsCount int) int {
+	h := fnv.New64a()
+	h.Write([]byte(key)) //nolint:errcheck
+	k := h.Sum64()
+	var b, j int64 = -1, 0
+	for j < int64(shardsCount) {
+		b = j
+		k = k*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((k>>33)+1)))
+	}
+	return int(b)
+}
+
+// generateContent builds rule file content for the given spec of pRule
+// content is prefixed with comment, which contains information about source VMRule
+// groupDefaults are optional and added to the groups without explicitly set fields
+func generateContent(pRule *vmv1beta1.VMRule, promRule vmv1beta1.VMRuleSpec, enforcedNsLabel string, groupDefaults *vmv1beta1.VMAlertRuleGroupDefaults) (string, error) {
+	promRule.Groups = renderRuleGroups(pRule.Namespace, promRule.Groups, enforcedNsLabel, groupDefaults)
 	content, err := yaml.Marshal(promRule)
 	if err != nil {
 		return "", fmt.Errorf("cannot unmarshal context for cm rule generation: %w", err)
 	}
-	return string(content), nil
+	header := fmt.Sprintf("# source VMRule: namespace=%q name=%q uid=%q generation=%d\n# generated by operator version=%q\n",
+		pRule.Namespace, pRule.Name, pRule.UID, pRule.Generation, buildinfo.Version)
+	return header + string(content), nil
+}
+
+// renderRuleGroups returns the given groups of VMRule from namespace ns in the form written into rule file
+func renderRuleGroups(ns string, groups []vmv1beta1.RuleGroup, enforcedNsLabel string, groupDefaults *vmv1beta1.VMAlertRuleGroupDefaults) []vmv1beta1.RuleGroup {
+	groups = sortRuleGroups(groups)
+	if groupDefaults != nil {
+		withDefaults := make([]vmv1beta1.RuleGroup, 0, len(groups))
+		for _, group := range groups {
+			withDefaults = append(withDefaults, applyRuleGroupDefaults(group, groupDefaults))
+		}
+		groups = withDefaults
+	}
+	if enforcedNsLabel != "" {
+		for gi, group := range groups {
+			for ri := range group.Rules {
+				if len(groups[gi].Rules[ri].Labels) == 0 {
+					groups[gi].Rules[ri].Labels = map[string]string{}
+				}
+				groups[gi].Rules[ri].Labels[enforcedNsLabel] = ns
+			}
+		}
+	}
+	return groups
+}
+
+// sortRuleGroups returns copy of the given groups sorted by order and name, if order is set for any group.
+// Order is removed from the copy, since it's not supported by vmalert
+func sortRuleGroups(groups []vmv1beta1.RuleGroup) []vmv1beta1.RuleGroup {
+	var hasOrder bool
+	for _, group := range groups {
+		if group.Order != 0 {
+			hasOrder = true
+			break
+		}
+	}
+	if !hasOrder {
+		return groups
+	}
+	sorted := make([]vmv1beta1.RuleGroup, len(groups))
+	copy(sorted, groups)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Order != sorted[j].Order {
+			return sorted[i].Order < sorted[j].Order
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+	for i := range sorted {
+		sorted[i].Order = 0
+	}
+	return sorted
+}
+
+// applyRuleGroupDefaults returns copy of the given group with defaults added to unset fields
+// params and headers are merged by name, group values take precedence
+func applyRuleGroupDefaults(group vmv1beta1.RuleGroup, groupDefaults *vmv1beta1.VMAlertRuleGroupDefaults) vmv1beta1.RuleGroup {
+	group = *group.DeepCopy()
+	if group.Interval == "" {
+		group.Interval = groupDefaults.EvalInterval
+	}
+	if group.Concurrency == 0 {
+		group.Concurrency = groupDefaults.Concurrency
+	}
+	for name, values := range groupDefaults.Params {
+		if _, ok := group.Params[name]; ok {
+			continue
+		}
+		if group.Params == nil {
+			group.Params = url.Values{}
+		}
+		group.Params[name] = append([]string{}, values...)
+	}
+	groupHeaders := make(map[string]struct{}, len(group.Headers))
+	for _, header := range group.Headers {
+		groupHeaders[headerName(header)] = struct{}{}
+	}
+	for _, header := range groupDefaults.Headers {
+		if _, ok := groupHeaders[headerName(header)]; ok {
+			continue
+		}
+		group.Headers = append(group.Headers, header)
+	}
+	return group
+}
+
+// headerName returns canonical name of the header in form `name: value`
+func headerName(header string) string {
+	name, _, _ := strings.Cut(header, ":")
+	return http.CanonicalHeaderKey(strings.TrimSpace(name))
 }
 
 // makeRulesConfigMaps takes a VMAlert configuration and rule files and
 // returns a list of Kubernetes ConfigMaps to be later on mounted
 // If the total size of rule files exceeds the Kubernetes ConfigMap limit,
-// they are split up via the simple first-fit [1] bin packing algorithm. In the
-// future this can be replaced by a more sophisticated algorithm, but for now
-// simplicity should be sufficient.
+// they are split up via the simple first-fit [1] bin packing algorithm.
+// Packing is sticky: prevAssignment holds bucket index of files stored at existing ConfigMaps,
+// such files are kept at the same bucket while it fits into the limit.
+// New files and files which no longer fit are appended to the last bucket or to the new one.
+// It prevents content changes of all ConfigMaps after a single rule file update.
+// If compression is enabled, all rule files are gzipped and stored at binaryData,
+// bin packing uses compressed size of rule files.
+// With per namespace layout rule files are grouped by namespace first and packed into dedicated ConfigMaps per namespace.
+// ConfigMaps are returned sorted by namespace and bucket index.
 // [1] https://en.wikipedia.org/wiki/Bin_packing_problem#First-fit_algorithm
-func makeRulesConfigMaps(cr *vmv1beta1.VMAlert, ruleFiles map[string]string) []corev1.ConfigMap {
-	buckets := []map[string]string{
-		{},
+func makeRulesConfigMaps(cr *vmv1beta1.VMAlert, shardNum int, ruleFiles map[string]string, prevAssignment map[string]int) ([]corev1.ConfigMap, error) {
+	isCompressed := ptr.Deref(cr.Spec.CompressRuleConfigMaps, false)
+	storedFiles := make(map[string][]byte, len(ruleFiles))
+	for filename, content := range ruleFiles {
+		if !isCompressed {
+			storedFiles[filename] = []byte(content)
+			continue
+		}
+		var buf bytes.Buffer
+		if err := gzipRuleFile(&buf, content); err != nil {
+			return nil, fmt.Errorf("cannot compress rule file=%q: %w", filename, err)
+		}
+		storedFiles[filename+compressedRuleFileSuffix] = buf.Bytes()
 	}
-	currBucketIndex := 0
-
 	// To make bin packing algorithm deterministic, sort ruleFiles filenames and
 	// iterate over filenames instead of ruleFiles map (not deterministic).
 	fileNames := []string{}
-	for n := range ruleFiles {
+	for n := range storedFiles {
 		fileNames = append(fileNames, n)
 	}
 	sort.Strings(fileNames)
 
+	if !cr.IsRuleConfigMapPerNamespace() {
+		buckets := packRuleFiles(fileNames, storedFiles, prevAssignment)
+		ruleFileConfigMaps := make([]corev1.ConfigMap, 0, len(buckets))
+		for i, bucket := range buckets {
+			cm := makeRulesConfigMap(cr, shardNum, bucket, isCompressed)
+			cm.Name = cm.Name + "-" + strconv.Itoa(i)
+			ruleFileConfigMaps = append(ruleFileConfigMaps, cm)
+		}
+		return ruleFileConfigMaps, nil
+	}
+
+	fileNamesByNamespace := make(map[string][]string)
+	for _, filename := range fileNames {
+		ns := ruleFileNamespace(cr, filename)
+		fileNamesByNamespace[ns] = append(fileNamesByNamespace[ns], filename)
+	}
+	namespaces := make([]string, 0, len(fileNamesByNamespace))
+	for ns := range fileNamesByNamespace {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Strings(namespaces)
+	var ruleFileConfigMaps []corev1.ConfigMap
+	for _, ns := range namespaces {
+		buckets := packRuleFiles(fileNamesByNamespace[ns], storedFiles, prevAssignment)
+		for i, bucket := range buckets {
+			cm := makeRulesConfigMap(cr, shardNum, bucket, isCompressed)
+			cm.Name = fmt.Sprintf("%s-%s-%d", cm.Name, ns, i)
+			ruleFileConfigMaps = append(ruleFileConfigMaps, cm)
+		}
+	}
+	return ruleFileConfigMaps, nil
+}
+
+// packRuleFiles distributes the given sorted rule files across buckets limited by max ConfigMap size
+func packRuleFiles(fileNames []string, storedFiles map[string][]byte, prevAssignment map[string]int) []map[string][]byte {
+	bucketsCount := 1
 	for _, filename := range fileNames {
-		// If rule file doesn't fit into current bucket, create new bucket.
-		if bucketSize(buckets[currBucketIndex])+len(ruleFiles[filename]) > vmv1beta1.MaxConfigMapDataSize {
-			buckets = append(buckets, map[string]string{})
-			currBucketIndex++
+		if idx, ok := prevAssignment[filename]; ok && idx >= bucketsCount {
+			bucketsCount = idx + 1
 		}
-		buckets[currBucketIndex][filename] = ruleFiles[filename]
+	}
+	buckets := make([]map[string][]byte, bucketsCount)
+	for i := range buckets {
+		buckets[i] = map[string][]byte{}
+	}
+	// rule file could be always placed into empty bucket, even if it exceeds size limit
+	fits := func(bucket map[string][]byte, filename string) bool {
+		return len(bucket) == 0 || bucketSize(bucket)+len(storedFiles[filename]) <= vmv1beta1.MaxConfigMapDataSize
 	}
 
-	ruleFileConfigMaps := make([]corev1.ConfigMap, 0, len(buckets))
-	for i, bucket := range buckets {
-		cm := makeRulesConfigMap(cr, bucket)
-		cm.Name = cm.Name + "-" + strconv.Itoa(i)
-		ruleFileConfigMaps = append(ruleFileConfigMaps, cm)
+	var unassigned []string
+	for _, filename := range fileNames {
+		idx, ok := prevAssignment[filename]
+		if !ok || !fits(buckets[idx], filename) {
+			unassigned = append(unassigned, filename)
+			continue
+		}
+		buckets[idx][filename] = storedFiles[filename]
+	}
+	// drop empty buckets at the tail, it allows to place new files into the last non-empty bucket
+	for len(buckets) > 1 && len(buckets[len(buckets)-1]) == 0 {
+		buckets = buckets[:len(buckets)-1]
 	}
+	for _, filename := range unassigned {
+		// If rule file doesn't fit into the last bucket, create new bucket.
+		if !fits(buckets[len(buckets)-1], filename) {
+			buckets = append(buckets, map[string][]byte{})
+		}
+		buckets[len(buckets)-1][filename] = storedFiles[filename]
+	}
+	return buckets
+}
 
-	return ruleFileConfigMaps
+func gzipRuleFile(buf *bytes.Buffer, content string) error {
+	w := gzip.NewWriter(buf)
+	if _, err := w.Write([]byte(content)); err != nil {
+		return err
+	}
+	return w.Close()
 }
 
-func bucketSize(bucket map[string]string) int {
+func bucketSize(bucket map[string][]byte) int {
 	totalSize := 0
 	for _, v := range bucket {
 		totalSize += len(v)
@@ -306,26 +1437,65 @@ func bucketSize(bucket map[string]string) int {
 	return totalSize
 }
 
-func makeRulesConfigMap(cr *vmv1beta1.VMAlert, ruleFiles map[string]string) corev1.ConfigMap {
+// makeRulesConfigMap builds ConfigMap with given rule files
+// mixing of compressed and plain rule files is not allowed
+func makeRulesConfigMap(cr *vmv1beta1.VMAlert, shardNum int, ruleFiles map[string][]byte, isCompressed bool) corev1.ConfigMap {
 	ruleLabels := map[string]string{"vmalert-name": cr.Name}
 	for k, v := range managedByOperatorLabels {
 		ruleLabels[k] = v
 	}
 
-	return corev1.ConfigMap{
+	cm := corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:            ruleConfigMapName(cr.Name),
+			Name:            ruleConfigMapName(cr, shardNum),
 			Namespace:       cr.Namespace,
 			Labels:          ruleLabels,
+			Annotations:     map[string]string{vmv1beta1.VMAlertRulesChecksumAnnotation: rulesChecksum(ruleFiles)},
 			OwnerReferences: cr.AsOwner(),
 			Finalizers:      []string{vmv1beta1.FinalizerName},
 		},
-		Data: ruleFiles,
 	}
+	if isCompressed {
+		cm.BinaryData = ruleFiles
+		return cm
+	}
+	cm.Data = make(map[string]string, len(ruleFiles))
+	for filename, content := range ruleFiles {
+		cm.Data[filename] = string(content)
+	}
+	return cm
+}
+
+// rulesChecksum returns fnv hash of the given rule files
+func rulesChecksum(ruleFiles map[string][]byte) string {
+	fileNames := make([]string, 0, len(ruleFiles))
+	for filename := range ruleFiles {
+		fileNames = append(fileNames, filename)
+	}
+	sort.Strings(fileNames)
+	h := fnv.New64a()
+	for _, filename := range fileNames {
+		h.Write([]byte(filename))    //nolint:errcheck
+		h.Write(ruleFiles[filename]) //nolint:errcheck
+	}
+	return strconv.FormatUint(h.Sum64(), 16)
+}
+
+// equalRulesAnnotations compares annotations of rules objects
+// checksum annotation is ignored, since it's derived from the data
+func equalRulesAnnotations(a, b map[string]string) bool {
+	a = maps.Clone(a)
+	b = maps.Clone(b)
+	delete(a, vmv1beta1.VMAlertRulesChecksumAnnotation)
+	delete(b, vmv1beta1.VMAlertRulesChecksumAnnotation)
+	return equality.Semantic.DeepEqual(a, b)
 }
 
-func ruleConfigMapName(vmName string) string {
-	return "vm-" + vmName + "-rulefiles"
+func ruleConfigMapName(cr *vmv1beta1.VMAlert, shardNum int) string {
+	if cr.RuleShardsCount() > 1 {
+		return fmt.Sprintf("vm-%s-shard-%d-rulefiles", cr.Name, shardNum)
+	}
+	return "vm-" + cr.Name + "-rulefiles"
 }
 
 // deduplicateRules - takes list of vmRules and modifies it
@@ -355,6 +1525,51 @@ func deduplicateRules(ctx context.Context, origin []*vmv1beta1.VMRule) []*vmv1be
 	return origin
 }
 
+// deduplicateRuleGroups removes groups of the given VMRule, which are identical to groups of already accepted VMRules.
+// Groups are compared in the form written into rule file, so groups with different
+// rendered namespace label, tenant or any other field are not treated as duplicates.
+// Rule content is generated again if any group was removed.
+// It returns ids of the kept groups, which must be added to uniqGroups, if VMRule is accepted
+func deduplicateRuleGroups(ctx context.Context, cr *vmv1beta1.VMAlert, pRule *vmv1beta1.VMRule, res *processedRule, uniqGroups map[uint64]string, shardsCount int) ([]uint64, error) {
+	groupIDs := make([]uint64, 0, len(pRule.Spec.Groups))
+	groups := make([]vmv1beta1.RuleGroup, 0, len(pRule.Spec.Groups))
+	for _, grp := range pRule.Spec.Groups {
+		rendered := renderRuleGroups(pRule.Namespace, []vmv1beta1.RuleGroup{grp}, cr.Spec.EnforcedNamespaceLabel, cr.Spec.RuleGroupDefaults)
+		groupID, err := calculateGroupID(rendered[0])
+		if err != nil {
+			return nil, err
+		}
+		if owner, ok := uniqGroups[groupID]; ok {
+			logger.WithContext(ctx).Info(fmt.Sprintf("duplicate group=%q found at vmrule=%s/%s, keeping group from vmrule=%s", grp.Name, pRule.Namespace, pRule.Name, owner))
+			continue
+		}
+		groupIDs = append(groupIDs, groupID)
+		groups = append(groups, grp)
+	}
+	if len(groups) == len(pRule.Spec.Groups) {
+		return groupIDs, nil
+	}
+	pRule.Spec.Groups = groups
+	contentByShard, err := generateShardedContent(pRule, cr.Spec.EnforcedNamespaceLabel, cr.Spec.RuleGroupDefaults, shardsCount)
+	if err != nil {
+		return nil, err
+	}
+	res.contentByShard = contentByShard
+	res.rulesCnt = countRules(&pRule.Spec)
+	return groupIDs, nil
+}
+
+// calculateGroupID returns hash of the given rendered group
+func calculateGroupID(g vmv1beta1.RuleGroup) (uint64, error) {
+	data, err := yaml.Marshal(g)
+	if err != nil {
+		return 0, fmt.Errorf("cannot marshal group=%q: %w", g.Name, err)
+	}
+	h := fnv.New64a()
+	h.Write(data) //nolint:errcheck
+	return h.Sum64(), nil
+}
+
 func calculateRuleID(r vmv1beta1.Rule) uint64 {
 	h := fnv.New64a()
 	h.Write([]byte(r.Expr)) //nolint:errcheck
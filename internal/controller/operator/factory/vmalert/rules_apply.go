@@ -0,0 +1,234 @@
+package vmalert
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/finalize"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/logger"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/util/csaupgrade"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// rulesFieldManager is a field manager used for server-side apply of rule ConfigMaps
+const rulesFieldManager = "vm-operator"
+
+var rulesServerSideApply bool
+
+// SetRulesServerSideApply configures default mode of rule ConfigMaps reconcile.
+// It could be overridden by VMAlert spec.rulesServerSideApply
+func SetRulesServerSideApply(v bool) {
+	rulesServerSideApply = v
+}
+
+// isRulesServerSideApply checks if rule ConfigMaps must be applied with server-side apply
+func isRulesServerSideApply(cr *vmv1beta1.VMAlert) bool {
+	return ptr.Deref(cr.Spec.RulesServerSideApply, rulesServerSideApply)
+}
+
+// ownedFields holds keys of ConfigMap fields owned by field manager
+type ownedFields struct {
+	labels      sets.Set[string]
+	annotations sets.Set[string]
+	data        sets.Set[string]
+	binaryData  sets.Set[string]
+}
+
+// parseOwnedFields returns ConfigMap fields owned by rulesFieldManager with Apply operation.
+// It returns nil if ConfigMap was never applied by operator
+func parseOwnedFields(cm *corev1.ConfigMap) (*ownedFields, error) {
+	for _, entry := range cm.ManagedFields {
+		if entry.Manager != rulesFieldManager || entry.Operation != metav1.ManagedFieldsOperationApply || entry.FieldsV1 == nil {
+			continue
+		}
+		var fields struct {
+			Metadata struct {
+				Labels      json.RawMessage `json:"f:labels"`
+				Annotations json.RawMessage `json:"f:annotations"`
+			} `json:"f:metadata"`
+			Data       json.RawMessage `json:"f:data"`
+			BinaryData json.RawMessage `json:"f:binaryData"`
+		}
+		if err := json.Unmarshal(entry.FieldsV1.Raw, &fields); err != nil {
+			return nil, fmt.Errorf("cannot parse managed fields of ConfigMap=%s: %w", cm.Name, err)
+		}
+		var owned ownedFields
+		for _, f := range []struct {
+			dst *sets.Set[string]
+			raw json.RawMessage
+		}{
+			{&owned.labels, fields.Metadata.Labels},
+			{&owned.annotations, fields.Metadata.Annotations},
+			{&owned.data, fields.Data},
+			{&owned.binaryData, fields.BinaryData},
+		} {
+			keys, err := fieldKeys(f.raw)
+			if err != nil {
+				return nil, fmt.Errorf("cannot parse managed fields of ConfigMap=%s: %w", cm.Name, err)
+			}
+			*f.dst = keys
+		}
+		return &owned, nil
+	}
+	return nil, nil
+}
+
+// fieldKeys returns keys of the given managed fields map
+func fieldKeys(raw json.RawMessage) (sets.Set[string], error) {
+	keys := sets.New[string]()
+	if len(raw) == 0 {
+		return keys, nil
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	for k := range fields {
+		if key, ok := strings.CutPrefix(k, "f:"); ok {
+			keys.Insert(key)
+		}
+	}
+	return keys, nil
+}
+
+// equalOwnedValues checks if current values of owned keys match new values
+func equalOwnedValues[T any](owned sets.Set[string], newValues, currentValues map[string]T) bool {
+	if !owned.Equal(sets.KeySet(newValues)) {
+		return false
+	}
+	for k, v := range newValues {
+		cv, ok := currentValues[k]
+		if !ok || !equality.Semantic.DeepEqual(v, cv) {
+			return false
+		}
+	}
+	return true
+}
+
+// rulesCMApplyDiff returns ConfigMaps, which must be applied.
+// Only fields owned by operator are compared, fields added by third-party controllers are ignored
+func rulesCMApplyDiff(currentCMs []corev1.ConfigMap, newCMs []corev1.ConfigMap) ([]corev1.ConfigMap, error) {
+	currentByName := make(map[string]*corev1.ConfigMap, len(currentCMs))
+	for idx := range currentCMs {
+		currentByName[currentCMs[idx].Name] = &currentCMs[idx]
+	}
+	var toApply []corev1.ConfigMap
+	for _, newCM := range newCMs {
+		currentCM, ok := currentByName[newCM.Name]
+		if !ok {
+			toApply = append(toApply, newCM)
+			continue
+		}
+		owned, err := parseOwnedFields(currentCM)
+		if err != nil {
+			return nil, err
+		}
+		if owned == nil ||
+			!equalOwnedValues(owned.labels, newCM.Labels, currentCM.Labels) ||
+			!equalOwnedValues(owned.annotations, newCM.Annotations, currentCM.Annotations) ||
+			!equalOwnedValues(owned.data, newCM.Data, currentCM.Data) ||
+			!equalOwnedValues(owned.binaryData, newCM.BinaryData, currentCM.BinaryData) ||
+			!sets.New(currentCM.Finalizers...).HasAll(newCM.Finalizers...) {
+			toApply = append(toApply, newCM)
+		}
+	}
+	return toApply, nil
+}
+
+// legacyRulesManagers returns names of field managers, which previously updated rule files of the given ConfigMap
+func legacyRulesManagers(cm *corev1.ConfigMap) sets.Set[string] {
+	managers := sets.New[string]()
+	for _, entry := range cm.ManagedFields {
+		if entry.Operation != metav1.ManagedFieldsOperationUpdate || entry.FieldsV1 == nil || entry.Manager == rulesFieldManager {
+			continue
+		}
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(entry.FieldsV1.Raw, &fields); err != nil {
+			continue
+		}
+		_, hasData := fields["f:data"]
+		_, hasBinaryData := fields["f:binaryData"]
+		if hasData || hasBinaryData {
+			managers.Insert(entry.Manager)
+		}
+	}
+	return managers
+}
+
+// migrateToServerSideApply transfers ownership of fields managed by update requests to rulesFieldManager.
+// Otherwise, rule files removed from ConfigMap are kept, since they are owned by previous field manager
+func migrateToServerSideApply(ctx context.Context, rclient client.Client, cm *corev1.ConfigMap) error {
+	managers := legacyRulesManagers(cm)
+	if managers.Len() == 0 {
+		return nil
+	}
+	patch, err := csaupgrade.UpgradeManagedFieldsPatch(cm, managers, rulesFieldManager)
+	if err != nil {
+		return fmt.Errorf("cannot build managed fields patch for ConfigMap=%s: %w", cm.Name, err)
+	}
+	if patch == nil {
+		return nil
+	}
+	logger.WithContext(ctx).Info(fmt.Sprintf("migrating rules ConfigMap=%s from field managers=%s to server-side apply", cm.Name, strings.Join(sets.List(managers), ",")))
+	if err := rclient.Patch(ctx, cm, client.RawPatch(types.JSONPatchType, patch)); err != nil {
+		return fmt.Errorf("cannot migrate managed fields of ConfigMap=%s: %w", cm.Name, err)
+	}
+	return nil
+}
+
+// applyRulesConfigMaps applies the given rule ConfigMaps with server-side apply.
+// It reports whether any ConfigMap was changed
+func applyRulesConfigMaps(ctx context.Context, rclient client.Client, newCMs []corev1.ConfigMap) (bool, error) {
+	var currentCMs []corev1.ConfigMap
+	for _, cm := range newCMs {
+		var existCM corev1.ConfigMap
+		if err := rclient.Get(ctx, types.NamespacedName{Namespace: cm.Namespace, Name: cm.Name}, &existCM); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return false, err
+		}
+		if err := finalize.FreeIfNeeded(ctx, rclient, &existCM); err != nil {
+			return false, err
+		}
+		currentCMs = append(currentCMs, existCM)
+	}
+	toApply, err := rulesCMApplyDiff(currentCMs, newCMs)
+	if err != nil {
+		return false, err
+	}
+	for idx := range currentCMs {
+		currentCM := &currentCMs[idx]
+		owned, err := parseOwnedFields(currentCM)
+		if err != nil {
+			return false, err
+		}
+		if owned != nil {
+			continue
+		}
+		if err := migrateToServerSideApply(ctx, rclient, currentCM); err != nil {
+			return false, err
+		}
+	}
+	for _, cm := range toApply {
+		cm.TypeMeta = metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"}
+		cm.ResourceVersion = ""
+		cm.ManagedFields = nil
+		logger.WithContext(ctx).Info(fmt.Sprintf("applying rules ConfigMap=%s", cm.Name))
+		// operator must own rule files, it's required for the first apply of ConfigMap created by update requests
+		if err := rclient.Patch(ctx, &cm, client.Apply, client.FieldOwner(rulesFieldManager), client.ForceOwnership); err != nil {
+			return false, fmt.Errorf("failed to apply rules ConfigMap=%s: %w", cm.Name, err)
+		}
+	}
+	return len(toApply) > 0, nil
+}
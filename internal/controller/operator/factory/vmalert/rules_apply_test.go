@@ -0,0 +1,201 @@
+package vmalert
+
+import (
+	"context"
+	"testing"
+
+	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/k8stools"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// applyClient emulates server-side apply, which isn't supported by fake client
+type applyClient struct {
+	client.Client
+	applied []string
+}
+
+func (c *applyClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if patch.Type() != types.ApplyPatchType {
+		return c.Client.Patch(ctx, obj, patch, opts...)
+	}
+	var po client.PatchOptions
+	po.ApplyOptions(opts)
+	if po.FieldManager != rulesFieldManager || !ptr.Deref(po.Force, false) {
+		return errors.NewBadRequest("apply must be performed with forced ownership of operator field manager")
+	}
+	c.applied = append(c.applied, obj.GetName())
+	var existing corev1.ConfigMap
+	if err := c.Get(ctx, client.ObjectKeyFromObject(obj), &existing); err != nil {
+		if errors.IsNotFound(err) {
+			return c.Create(ctx, obj)
+		}
+		return err
+	}
+	obj.SetResourceVersion(existing.ResourceVersion)
+	return c.Update(ctx, obj)
+}
+
+func appliedFields(raw string) []metav1.ManagedFieldsEntry {
+	return []metav1.ManagedFieldsEntry{{
+		Manager:    rulesFieldManager,
+		Operation:  metav1.ManagedFieldsOperationApply,
+		FieldsV1:   &metav1.FieldsV1{Raw: []byte(raw)},
+		APIVersion: "v1",
+	}}
+}
+
+func Test_rulesCMApplyDiff(t *testing.T) {
+	f := func(current corev1.ConfigMap, want bool) {
+		t.Helper()
+		newCM := corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "vm-base-rulefiles",
+				Labels:      map[string]string{"vmalert-name": "base"},
+				Annotations: map[string]string{vmv1beta1.VMAlertRulesChecksumAnnotation: "1"},
+				Finalizers:  []string{vmv1beta1.FinalizerName},
+			},
+			Data: map[string]string{"default-rule.yaml": "groups: []"},
+		}
+		toApply, err := rulesCMApplyDiff([]corev1.ConfigMap{current}, []corev1.ConfigMap{newCM})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		assert.Equal(t, want, len(toApply) > 0)
+	}
+	owned := `{"f:metadata":{"f:labels":{"f:vmalert-name":{}},"f:annotations":{"f:` + vmv1beta1.VMAlertRulesChecksumAnnotation + `":{}},"f:finalizers":{"v:\"` + vmv1beta1.FinalizerName + `\"":{}}},"f:data":{"f:default-rule.yaml":{}}}`
+	current := corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:          "vm-base-rulefiles",
+			Labels:        map[string]string{"vmalert-name": "base"},
+			Annotations:   map[string]string{vmv1beta1.VMAlertRulesChecksumAnnotation: "1"},
+			Finalizers:    []string{vmv1beta1.FinalizerName},
+			ManagedFields: appliedFields(owned),
+		},
+		Data: map[string]string{"default-rule.yaml": "groups: []"},
+	}
+
+	// no changes
+	f(current, false)
+
+	// fields of third-party managers are ignored
+	withExternal := current.DeepCopy()
+	withExternal.Annotations["policies.kyverno.io/owner"] = "team-a"
+	withExternal.Labels["team"] = "a"
+	f(*withExternal, false)
+
+	// changed rule file
+	changed := current.DeepCopy()
+	changed.Data["default-rule.yaml"] = "groups: [{name: a}]"
+	f(*changed, true)
+
+	// stale rule file owned by operator
+	stale := current.DeepCopy()
+	stale.Data["removed-rule.yaml"] = "groups: []"
+	stale.ManagedFields = appliedFields(`{"f:metadata":{"f:labels":{"f:vmalert-name":{}},"f:annotations":{"f:` + vmv1beta1.VMAlertRulesChecksumAnnotation + `":{}}},"f:data":{"f:default-rule.yaml":{},"f:removed-rule.yaml":{}}}`)
+	f(*stale, true)
+
+	// ConfigMap created by update requests must be applied
+	legacy := current.DeepCopy()
+	legacy.ManagedFields = nil
+	f(*legacy, true)
+}
+
+func Test_legacyRulesManagers(t *testing.T) {
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{ManagedFields: []metav1.ManagedFieldsEntry{
+		{Manager: "manager", Operation: metav1.ManagedFieldsOperationUpdate, FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:data":{".":{},"f:default-rule.yaml":{}}}`)}},
+		{Manager: "kyverno", Operation: metav1.ManagedFieldsOperationUpdate, FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:metadata":{"f:annotations":{"f:owner":{}}}}`)}},
+		{Manager: rulesFieldManager, Operation: metav1.ManagedFieldsOperationApply, FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:data":{"f:default-rule.yaml":{}}}`)}},
+	}}}
+	assert.Equal(t, []string{"manager"}, legacyRulesManagers(cm).UnsortedList())
+}
+
+func TestRulesServerSideApply(t *testing.T) {
+	cr := &vmv1beta1.VMAlert{
+		ObjectMeta: metav1.ObjectMeta{Name: "base", Namespace: "default"},
+		Spec: vmv1beta1.VMAlertSpec{
+			SelectAllByDefault:   true,
+			RulesServerSideApply: ptr.To(true),
+		},
+	}
+	rule := &vmv1beta1.VMRule{
+		ObjectMeta: metav1.ObjectMeta{Name: "rule", Namespace: "default"},
+		Spec:       vmv1beta1.VMRuleSpec{Groups: []vmv1beta1.RuleGroup{{Name: "group", Rules: []vmv1beta1.Rule{{Alert: "up", Expr: "up == 0"}}}}},
+	}
+	// ConfigMap created by update requests with annotation of third-party controller
+	legacyCM := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "vm-base-rulefiles-0",
+			Namespace:   "default",
+			Annotations: map[string]string{"policies.kyverno.io/owner": "team-a"},
+		},
+		Data: map[string]string{"default-removed.yaml": "groups: []"},
+	}
+	fclient := &applyClient{Client: k8stools.GetTestClientWithObjects([]runtime.Object{
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+		cr, rule, legacyCM,
+	})}
+	ctx := context.TODO()
+	if _, err := CreateOrUpdateRuleConfigMaps(ctx, fclient, cr, nil, nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.Equal(t, []string{"vm-base-rulefiles-0"}, fclient.applied)
+	var got corev1.ConfigMap
+	if err := fclient.Get(ctx, types.NamespacedName{Namespace: "default", Name: "vm-base-rulefiles-0"}, &got); err != nil {
+		t.Fatalf("cannot get rules ConfigMap: %s", err)
+	}
+	assert.Contains(t, got.Data, "default-rule.yaml")
+
+	// server-side apply isn't used by default
+	fclient.applied = nil
+	cr.Spec.RulesServerSideApply = nil
+	if err := fclient.Get(ctx, types.NamespacedName{Namespace: "default", Name: "rule"}, rule); err != nil {
+		t.Fatalf("cannot get rule: %s", err)
+	}
+	rule.Spec.Groups[0].Rules[0].Expr = "up == 1"
+	if err := fclient.Update(ctx, rule); err != nil {
+		t.Fatalf("cannot update rule: %s", err)
+	}
+	if _, err := CreateOrUpdateRuleConfigMaps(ctx, fclient, cr, nil, nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.Empty(t, fclient.applied)
+}
+
+func Test_migrateToServerSideApply(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "vm-base-rulefiles-0", Namespace: "default", ManagedFields: []metav1.ManagedFieldsEntry{
+			{Manager: "manager", Operation: metav1.ManagedFieldsOperationUpdate, APIVersion: "v1", FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:data":{".":{},"f:default-rule.yaml":{}}}`)}},
+			{Manager: "kyverno", Operation: metav1.ManagedFieldsOperationUpdate, APIVersion: "v1", FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:metadata":{"f:annotations":{"f:owner":{}}}}`)}},
+		}},
+		Data: map[string]string{"default-rule.yaml": "groups: []"},
+	}
+	fclient := k8stools.GetTestClientWithObjects([]runtime.Object{cm})
+	ctx := context.TODO()
+	var current corev1.ConfigMap
+	if err := fclient.Get(ctx, client.ObjectKeyFromObject(cm), &current); err != nil {
+		t.Fatalf("cannot get ConfigMap: %s", err)
+	}
+	if err := migrateToServerSideApply(ctx, fclient, &current); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var got corev1.ConfigMap
+	if err := fclient.Get(ctx, client.ObjectKeyFromObject(cm), &got); err != nil {
+		t.Fatalf("cannot get ConfigMap: %s", err)
+	}
+	managers := make(map[string]metav1.ManagedFieldsOperationType)
+	for _, entry := range got.ManagedFields {
+		managers[entry.Manager] = entry.Operation
+	}
+	assert.Equal(t, map[string]metav1.ManagedFieldsOperationType{
+		rulesFieldManager: metav1.ManagedFieldsOperationApply,
+		"kyverno":         metav1.ManagedFieldsOperationUpdate,
+	}, managers)
+}
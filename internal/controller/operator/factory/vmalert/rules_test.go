@@ -1,21 +1,41 @@
 package vmalert
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"fmt"
+	"io"
+	"maps"
+	"net/url"
+	"os"
+	"path/filepath"
 	"reflect"
+	"slices"
+	"sort"
+	"strings"
 	"testing"
+	"time"
 
+	vmalertconfig "github.com/VictoriaMetrics/VictoriaMetrics/app/vmalert/config"
+	"github.com/ghodss/yaml"
 	"github.com/go-logr/logr"
 	"github.com/go-test/deep"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
+	yamlv2 "gopkg.in/yaml.v2"
+	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 
 	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/config"
 	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/k8stools"
 )
 
@@ -124,7 +144,9 @@ groups:
 				}},
 			},
 			want: map[string]string{
-				"default-error-alert.yaml": `groups:
+				"default-error-alert.yaml": `# source VMRule: namespace="default" name="error-alert" uid="" generation=0
+# generated by operator version=""
+groups:
 - concurrency: 1
   eval_alignment: false
   eval_delay: 40s
@@ -162,7 +184,9 @@ groups:
 					}}},
 				}},
 			},
-			want: map[string]string{"monitoring-error-alert-at-monitoring.yaml": `groups:
+			want: map[string]string{"monitoring-error-alert-at-monitoring.yaml": `# source VMRule: namespace="monitoring" name="error-alert-at-monitoring" uid="" generation=0
+# generated by operator version=""
+groups:
 - interval: 10s
   name: error-alert
   rules:
@@ -204,7 +228,9 @@ groups:
 				},
 			},
 			want: map[string]string{
-				"default-error-alert.yaml": `groups:
+				"default-error-alert.yaml": `# source VMRule: namespace="default" name="error-alert" uid="" generation=0
+# generated by operator version=""
+groups:
 - interval: 10s
   name: error-alert
   rules:
@@ -212,13 +238,164 @@ groups:
     expr: rate(err_metric[1m]) > 10
     for: 10s
 `,
-				"monitoring-error-alert-at-monitoring.yaml": `groups:
+				"monitoring-error-alert-at-monitoring.yaml": `# source VMRule: namespace="monitoring" name="error-alert-at-monitoring" uid="" generation=0
+# generated by operator version=""
+groups:
 - interval: 10s
   name: error-alert
   rules:
   - alert: alerting-2
     expr: "10"
     for: 10s
+`,
+			},
+		},
+		{
+			name: "exclude rules with deny selector and ignore annotation",
+			args: args{
+				p: &vmv1beta1.VMAlert{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-vm-alert", Namespace: "default"},
+					Spec: vmv1beta1.VMAlertSpec{
+						SelectAllByDefault: true,
+						RuleDenySelector:   &metav1.LabelSelector{MatchLabels: map[string]string{"team": "noisy"}},
+					},
+				},
+				l: logf.Log.WithName("unit-test"),
+			},
+			predefinedObjects: []runtime.Object{
+				&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+				&vmv1beta1.VMRule{
+					ObjectMeta: metav1.ObjectMeta{Name: "good-alert", Namespace: "default"},
+					Spec: vmv1beta1.VMRuleSpec{
+						Groups: []vmv1beta1.RuleGroup{{Name: "good", Rules: []vmv1beta1.Rule{
+							{Alert: "up", Expr: "up == 0"},
+						}}},
+					},
+				},
+				&vmv1beta1.VMRule{
+					ObjectMeta: metav1.ObjectMeta{Name: "denied-alert", Namespace: "default", Labels: map[string]string{"team": "noisy"}},
+					Spec: vmv1beta1.VMRuleSpec{
+						Groups: []vmv1beta1.RuleGroup{{Name: "denied", Rules: []vmv1beta1.Rule{
+							{Alert: "up", Expr: "up == 0"},
+						}}},
+					},
+				},
+				&vmv1beta1.VMRule{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        "ignored-alert",
+						Namespace:   "default",
+						Annotations: map[string]string{vmv1beta1.VMAlertIgnoreRuleAnnotation: "true"},
+					},
+					Spec: vmv1beta1.VMRuleSpec{
+						Groups: []vmv1beta1.RuleGroup{{Name: "ignored", Rules: []vmv1beta1.Rule{
+							{Alert: "up", Expr: "up == 0"},
+						}}},
+					},
+				},
+			},
+			want: map[string]string{
+				"default-good-alert.yaml": `# source VMRule: namespace="default" name="good-alert" uid="" generation=0
+# generated by operator version=""
+groups:
+- name: good
+  rules:
+  - alert: up
+    expr: up == 0
+`,
+			},
+		},
+		{
+			name: "enforce tenant from namespace annotation",
+			args: args{
+				p: &vmv1beta1.VMAlert{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-vm-alert", Namespace: "default"},
+					Spec: vmv1beta1.VMAlertSpec{
+						SelectAllByDefault:                 true,
+						TenantLabelFromNamespaceAnnotation: "operator.victoriametrics.com/tenant-id",
+					},
+				},
+				l: logf.Log.WithName("unit-test"),
+			},
+			predefinedObjects: []runtime.Object{
+				&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+				&v1.Namespace{ObjectMeta: metav1.ObjectMeta{
+					Name:        "team-a",
+					Annotations: map[string]string{"operator.victoriametrics.com/tenant-id": "10:5"},
+				}},
+				&vmv1beta1.VMRule{
+					ObjectMeta: metav1.ObjectMeta{Name: "tenant-alert", Namespace: "team-a"},
+					Spec: vmv1beta1.VMRuleSpec{
+						Groups: []vmv1beta1.RuleGroup{{
+							Name:   "tenant",
+							Labels: map[string]string{"vm_account_id": "1"},
+							Rules: []vmv1beta1.Rule{
+								{Alert: "up", Expr: "up == 0"},
+							},
+						}},
+					},
+				},
+				&vmv1beta1.VMRule{
+					ObjectMeta: metav1.ObjectMeta{Name: "no-tenant-alert", Namespace: "default"},
+					Spec: vmv1beta1.VMRuleSpec{
+						Groups: []vmv1beta1.RuleGroup{{Name: "no-tenant", Rules: []vmv1beta1.Rule{
+							{Alert: "up", Expr: "up == 0"},
+						}}},
+					},
+				},
+			},
+			want: map[string]string{
+				"team-a-tenant-alert.yaml": `# source VMRule: namespace="team-a" name="tenant-alert" uid="" generation=0
+# generated by operator version=""
+groups:
+- name: tenant
+  params:
+    extra_label:
+    - vm_account_id=10
+    - vm_project_id=5
+  rules:
+  - alert: up
+    expr: up == 0
+    labels:
+      vm_account_id: "10"
+      vm_project_id: "5"
+`,
+			},
+		},
+		{
+			name: "skip group with invalid expression",
+			args: args{
+				p: &vmv1beta1.VMAlert{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-vm-alert", Namespace: "default"},
+					Spec: vmv1beta1.VMAlertSpec{
+						SelectAllByDefault: true,
+					},
+				},
+				l: logf.Log.WithName("unit-test"),
+			},
+			predefinedObjects: []runtime.Object{
+				&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+				&vmv1beta1.VMRule{
+					ObjectMeta: metav1.ObjectMeta{Name: "mixed-alert", Namespace: "default"},
+					Spec: vmv1beta1.VMRuleSpec{
+						Groups: []vmv1beta1.RuleGroup{
+							{Name: "valid", Rules: []vmv1beta1.Rule{
+								{Alert: "up", Expr: "up == 0"},
+							}},
+							{Name: "broken", Rules: []vmv1beta1.Rule{
+								{Alert: "errors", Expr: "rate(err_metric[1m] > 10"},
+							}},
+						},
+					},
+				},
+			},
+			want: map[string]string{
+				"default-mixed-alert.yaml": `# source VMRule: namespace="default" name="mixed-alert" uid="" generation=0
+# generated by operator version=""
+groups:
+- name: valid
+  rules:
+  - alert: up
+    expr: up == 0
 `,
 			},
 		},
@@ -274,12 +451,12 @@ groups:
 		t.Run(tt.name, func(t *testing.T) {
 			ctx := context.Background()
 			fclient := k8stools.GetTestClientWithObjects(tt.predefinedObjects)
-			got, _, err := selectRulesContent(ctx, fclient, tt.args.p)
+			got, _, _, err := selectRulesContent(ctx, fclient, tt.args.p)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("SelectRules() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
-			for ruleName, content := range got {
+			for ruleName, content := range got[0] {
 				if !assert.Equal(t, tt.want[ruleName], content) {
 					t.Errorf("SelectRules() got = %v, want %v", content, tt.want[ruleName])
 				}
@@ -295,7 +472,7 @@ func TestCreateOrUpdateRuleConfigMaps(t *testing.T) {
 	tests := []struct {
 		name              string
 		args              args
-		want              []string
+		want              [][]RuleObject
 		wantErr           bool
 		predefinedObjects []runtime.Object
 	}{
@@ -318,13 +495,28 @@ func TestCreateOrUpdateRuleConfigMaps(t *testing.T) {
 				},
 				Spec: vmv1beta1.VMAlertSpec{SelectAllByDefault: true},
 			}},
-			want: []string{"vm-base-vmalert-rulefiles-0"},
+			want: [][]RuleObject{{{Name: "vm-base-vmalert-rulefiles-0", Checksum: "9cf4b293237a2aa7"}}},
+		},
+		{
+			name: "base-rules-gen-with-shards",
+			args: args{cr: &vmv1beta1.VMAlert{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "default",
+					Name:      "base-vmalert",
+				},
+				Spec: vmv1beta1.VMAlertSpec{
+					SelectAllByDefault:   true,
+					RuleShardingStrategy: vmv1beta1.VMAlertRuleShardingByGroup,
+					ShardCount:           ptr.To(2),
+				},
+			}},
+			want: [][]RuleObject{{{Name: "vm-base-vmalert-shard-0-rulefiles-0", Checksum: "9cf4b293237a2aa7"}}, {{Name: "vm-base-vmalert-shard-1-rulefiles-0", Checksum: "9cf4b293237a2aa7"}}},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			fclient := k8stools.GetTestClientWithObjects(tt.predefinedObjects)
-			got, err := CreateOrUpdateRuleConfigMaps(context.TODO(), fclient, tt.args.cr, nil)
+			got, err := CreateOrUpdateRuleConfigMaps(context.TODO(), fclient, tt.args.cr, nil, nil)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("CreateOrUpdateRuleConfigMaps() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -463,6 +655,96 @@ func Test_deduplicateRules(t *testing.T) {
 	}
 }
 
+func TestSelectRulesDeduplicateGroups(t *testing.T) {
+	shared := vmv1beta1.RuleGroup{Name: "shared", Interval: "1m", Rules: []vmv1beta1.Rule{{Alert: "down", Expr: "up == 0", For: "5m"}}}
+	f := func(spec vmv1beta1.VMAlertSpec, nsAnnotations map[string]string, teamAExtra []vmv1beta1.RuleGroup, teamB vmv1beta1.RuleGroup, wantGroups map[string][]string) {
+		t.Helper()
+		cr := &vmv1beta1.VMAlert{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "dedup-vmalert",
+				Namespace:   "default",
+				Annotations: map[string]string{vmv1beta1.MetaVMAlertDeduplicateRulesKey: "true"},
+			},
+			Spec: spec,
+		}
+		cr.Spec.SelectAllByDefault = true
+		fclient := k8stools.GetTestClientWithObjects([]runtime.Object{
+			&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a", Annotations: map[string]string{"tenant": nsAnnotations["team-a"]}}},
+			&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-b", Annotations: map[string]string{"tenant": nsAnnotations["team-b"]}}},
+			&vmv1beta1.VMRule{
+				ObjectMeta: metav1.ObjectMeta{Name: "origin", Namespace: "team-a"},
+				Spec:       vmv1beta1.VMRuleSpec{Groups: append([]vmv1beta1.RuleGroup{shared}, teamAExtra...)},
+			},
+			&vmv1beta1.VMRule{
+				ObjectMeta: metav1.ObjectMeta{Name: "copy", Namespace: "team-b"},
+				Spec: vmv1beta1.VMRuleSpec{Groups: []vmv1beta1.RuleGroup{
+					teamB,
+					{Name: "team-b", Rules: []vmv1beta1.Rule{{Alert: "slow", Expr: "up > 1"}}},
+				}},
+			},
+		})
+		_, vmRules, _, err := selectRulesContent(context.TODO(), fclient, cr)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		gotGroups := make(map[string][]string)
+		for _, r := range vmRules {
+			if r.Status.CurrentSyncError != "" {
+				continue
+			}
+			for _, g := range r.Spec.Groups {
+				gotGroups[r.Namespace] = append(gotGroups[r.Namespace], g.Name)
+			}
+		}
+		assert.Equal(t, wantGroups, gotGroups)
+	}
+
+	// identical group is kept only at the first VMRule
+	f(vmv1beta1.VMAlertSpec{}, nil, nil, shared, map[string][]string{
+		"team-a": {"shared"},
+		"team-b": {"team-b"},
+	})
+
+	// groups with different rule or group fields are not duplicates
+	otherFor := *shared.DeepCopy()
+	otherFor.Rules[0].For = "10m"
+	f(vmv1beta1.VMAlertSpec{}, nil, nil, otherFor, map[string][]string{
+		"team-a": {"shared"},
+		"team-b": {"shared", "team-b"},
+	})
+	otherLabels := *shared.DeepCopy()
+	otherLabels.Labels = map[string]string{"team": "b"}
+	f(vmv1beta1.VMAlertSpec{}, nil, nil, otherLabels, map[string][]string{
+		"team-a": {"shared"},
+		"team-b": {"shared", "team-b"},
+	})
+
+	// group is kept, if VMRule with its first copy is rejected
+	teamAExtra := []vmv1beta1.RuleGroup{
+		{Name: "team-a-1", Rules: []vmv1beta1.Rule{{Alert: "down", Expr: "up == 0"}}},
+		{Name: "team-a-2", Rules: []vmv1beta1.Rule{{Alert: "down", Expr: "up == 0"}}},
+	}
+	f(vmv1beta1.VMAlertSpec{MaxGroupsPerRule: 2}, nil, teamAExtra, shared, map[string][]string{
+		"team-b": {"shared", "team-b"},
+	})
+
+	// rendered namespace label makes groups from different namespaces different
+	f(vmv1beta1.VMAlertSpec{EnforcedNamespaceLabel: "namespace"}, nil, nil, shared, map[string][]string{
+		"team-a": {"shared"},
+		"team-b": {"shared", "team-b"},
+	})
+
+	// groups are duplicates only for namespaces with the same tenant
+	f(vmv1beta1.VMAlertSpec{TenantLabelFromNamespaceAnnotation: "tenant"}, map[string]string{"team-a": "1", "team-b": "2"}, nil, shared, map[string][]string{
+		"team-a": {"shared"},
+		"team-b": {"shared", "team-b"},
+	})
+	f(vmv1beta1.VMAlertSpec{TenantLabelFromNamespaceAnnotation: "tenant"}, map[string]string{"team-a": "1", "team-b": "1"}, nil, shared, map[string][]string{
+		"team-a": {"shared"},
+		"team-b": {"team-b"},
+	})
+}
+
 func Test_rulesCMDiff(t *testing.T) {
 	type args struct {
 		currentCMs []v1.ConfigMap
@@ -600,6 +882,30 @@ func Test_rulesCMDiff(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "skip checksum annotation change",
+			args: args{
+				currentCMs: []v1.ConfigMap{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:        "rules-cm-0",
+							Annotations: map[string]string{vmv1beta1.VMAlertRulesChecksumAnnotation: "1"},
+							Finalizers:  []string{vmv1beta1.FinalizerName},
+						},
+						Data: map[string]string{"rule": "content"},
+					},
+				},
+				newCMs: []v1.ConfigMap{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:        "rules-cm-0",
+							Annotations: map[string]string{vmv1beta1.VMAlertRulesChecksumAnnotation: "2"},
+						},
+						Data: map[string]string{"rule": "content"},
+					},
+				},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -609,3 +915,1200 @@ func Test_rulesCMDiff(t *testing.T) {
 		})
 	}
 }
+
+func Test_makeRulesConfigMaps(t *testing.T) {
+	f := func(cr *vmv1beta1.VMAlert, ruleFiles map[string]string, wantCMs int, wantCompressed bool) {
+		t.Helper()
+		got, err := makeRulesConfigMaps(cr, 0, ruleFiles, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		assert.Len(t, got, wantCMs)
+		var gotFiles int
+		for _, cm := range got {
+			if wantCompressed {
+				assert.Empty(t, cm.Data)
+				for name, content := range cm.BinaryData {
+					assert.True(t, strings.HasSuffix(name, compressedRuleFileSuffix))
+					gz, err := gzip.NewReader(bytes.NewReader(content))
+					if err != nil {
+						t.Fatalf("cannot read compressed rule file: %s", err)
+					}
+					data, err := io.ReadAll(gz)
+					if err != nil {
+						t.Fatalf("cannot decompress rule file: %s", err)
+					}
+					assert.Equal(t, ruleFiles[strings.TrimSuffix(name, compressedRuleFileSuffix)], string(data))
+				}
+				gotFiles += len(cm.BinaryData)
+				continue
+			}
+			assert.Empty(t, cm.BinaryData)
+			gotFiles += len(cm.Data)
+		}
+		assert.Equal(t, len(ruleFiles), gotFiles)
+	}
+	// generate rule files, which cannot fit into single ConfigMap without compression
+	largeRuleFiles := map[string]string{}
+	for i := 0; i < 4; i++ {
+		largeRuleFiles[fmt.Sprintf("default-rule-%d.yaml", i)] = strings.Repeat("groups: []\n", vmv1beta1.MaxConfigMapDataSize/(3*11))
+	}
+	cr := &vmv1beta1.VMAlert{ObjectMeta: metav1.ObjectMeta{Name: "base", Namespace: "default"}}
+	f(cr, map[string]string{"default-rule.yaml": "groups: []"}, 1, false)
+	f(cr, largeRuleFiles, 2, false)
+
+	cr = &vmv1beta1.VMAlert{
+		ObjectMeta: metav1.ObjectMeta{Name: "base", Namespace: "default"},
+		Spec: vmv1beta1.VMAlertSpec{
+			CompressRuleConfigMaps: ptr.To(true),
+		},
+	}
+	f(cr, map[string]string{"default-rule.yaml": "groups: []"}, 1, true)
+	f(cr, largeRuleFiles, 1, true)
+}
+
+func Test_makeRulesConfigMapsStickyPacking(t *testing.T) {
+	cr := &vmv1beta1.VMAlert{ObjectMeta: metav1.ObjectMeta{Name: "base", Namespace: "default"}}
+	assignmentOf := func(cms []v1.ConfigMap) map[string]int {
+		assignment := make(map[string]int)
+		for idx, cm := range cms {
+			for filename := range cm.Data {
+				assignment[filename] = idx
+			}
+		}
+		return assignment
+	}
+	changedCMs := func(prev, curr []v1.ConfigMap) int {
+		var changed int
+		for idx := range curr {
+			if idx >= len(prev) || !reflect.DeepEqual(prev[idx].Data, curr[idx].Data) {
+				changed++
+			}
+		}
+		return changed
+	}
+	fileContent := func(size int) string {
+		return strings.Repeat("#", size)
+	}
+	// every ConfigMap fits 3 rule files
+	fileSize := vmv1beta1.MaxConfigMapDataSize/3 - 10*1024
+	ruleFiles := map[string]string{}
+	for i := 0; i < 9; i++ {
+		ruleFiles[fmt.Sprintf("default-rule-%d.yaml", i)] = fileContent(fileSize)
+	}
+	prev, err := makeRulesConfigMaps(cr, 0, ruleFiles, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.Len(t, prev, 3)
+
+	// the same files must be packed in the same way
+	got, err := makeRulesConfigMaps(cr, 0, ruleFiles, assignmentOf(prev))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.Equal(t, 0, changedCMs(prev, got))
+
+	// removal of file must not trigger repacking
+	delete(ruleFiles, "default-rule-1.yaml")
+	got, err = makeRulesConfigMaps(cr, 0, ruleFiles, assignmentOf(prev))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.Len(t, got, 3)
+	assert.Equal(t, 1, changedCMs(prev, got))
+	prev = got
+
+	// grow a file by a few KB, only its ConfigMap must be changed
+	// first-fit packing would move files across all ConfigMaps
+	ruleFiles["default-rule-0.yaml"] = fileContent(fileSize + 5*1024)
+	got, err = makeRulesConfigMaps(cr, 0, ruleFiles, assignmentOf(prev))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.Len(t, got, 3)
+	assert.Equal(t, 1, changedCMs(prev, got))
+	firstFit, err := makeRulesConfigMaps(cr, 0, ruleFiles, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.Greater(t, changedCMs(prev, firstFit), 1)
+	prev = got
+
+	// file, which no longer fits into its ConfigMap, must be moved to the new one
+	ruleFiles["default-rule-3.yaml"] = fileContent(2 * fileSize)
+	got, err = makeRulesConfigMaps(cr, 0, ruleFiles, assignmentOf(prev))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.Len(t, got, 4)
+	assert.Equal(t, 3, assignmentOf(got)["default-rule-5.yaml"])
+	assert.Equal(t, 2, changedCMs(prev, got))
+
+	// new file must be appended to the last ConfigMap
+	ruleFiles["default-rule-10.yaml"] = "groups: []"
+	prev = got
+	got, err = makeRulesConfigMaps(cr, 0, ruleFiles, assignmentOf(prev))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.Len(t, got, 4)
+	assert.Equal(t, 3, assignmentOf(got)["default-rule-10.yaml"])
+	assert.Equal(t, 1, changedCMs(prev, got))
+}
+
+func Test_ruleGroupShardNum(t *testing.T) {
+	const keysCount = 1000
+	f := func(shardsCount int) {
+		t.Helper()
+		shardGroups := make([]int, shardsCount)
+		for i := 0; i < keysCount; i++ {
+			key := fmt.Sprintf("default/rule/group-%d", i)
+			shardNum := ruleGroupShardNum(key, shardsCount)
+			assert.Equal(t, shardNum, ruleGroupShardNum(key, shardsCount), "shard must be deterministic")
+			shardGroups[shardNum]++
+			// on upscale group either stays at the same shard or moves to the new one
+			upscaledShardNum := ruleGroupShardNum(key, shardsCount+1)
+			if upscaledShardNum != shardNum && upscaledShardNum != shardsCount {
+				t.Fatalf("unexpected shard change for key=%q from=%d to=%d", key, shardNum, upscaledShardNum)
+			}
+		}
+		for shardNum, cnt := range shardGroups {
+			assert.Greater(t, cnt, 0, "shard=%d has no groups", shardNum)
+		}
+	}
+	f(1)
+	f(2)
+	f(5)
+	f(16)
+}
+
+func Test_generateShardedContent(t *testing.T) {
+	pRule := &vmv1beta1.VMRule{
+		ObjectMeta: metav1.ObjectMeta{Name: "rule", Namespace: "default"},
+		Spec:       vmv1beta1.VMRuleSpec{},
+	}
+	for i := 0; i < 10; i++ {
+		pRule.Spec.Groups = append(pRule.Spec.Groups, vmv1beta1.RuleGroup{
+			Name:  fmt.Sprintf("group-%d", i),
+			Rules: []vmv1beta1.Rule{{Alert: "up", Expr: "up == 0"}},
+		})
+	}
+	got, err := generateShardedContent(pRule, "", nil, 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.Len(t, got, 3)
+	for _, group := range pRule.Spec.Groups {
+		shardNum := ruleGroupShardNum("default/rule/"+group.Name, 3)
+		for contentShardNum, content := range got {
+			assert.Equal(t, contentShardNum == shardNum, strings.Contains(content, "name: "+group.Name+"\n"), "group=%q", group.Name)
+		}
+	}
+}
+
+func TestRulesMetrics(t *testing.T) {
+	cr := &vmv1beta1.VMAlert{
+		ObjectMeta: metav1.ObjectMeta{Name: "metrics-vmalert", Namespace: "default"},
+		Spec:       vmv1beta1.VMAlertSpec{SelectAllByDefault: true},
+	}
+	fclient := k8stools.GetTestClientWithObjects([]runtime.Object{
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+		&vmv1beta1.VMRule{
+			ObjectMeta: metav1.ObjectMeta{Name: "valid", Namespace: "default"},
+			Spec: vmv1beta1.VMRuleSpec{Groups: []vmv1beta1.RuleGroup{{Name: "valid", Rules: []vmv1beta1.Rule{
+				{Alert: "up", Expr: "up == 0"},
+			}}}},
+		},
+		&vmv1beta1.VMRule{
+			ObjectMeta: metav1.ObjectMeta{Name: "invalid", Namespace: "default"},
+			Spec: vmv1beta1.VMRuleSpec{Groups: []vmv1beta1.RuleGroup{{Name: "invalid", Rules: []vmv1beta1.Rule{
+				{Alert: "up", Expr: "up =="},
+			}}}},
+		},
+	})
+	if _, err := CreateOrUpdateRuleConfigMaps(context.TODO(), fclient, cr, nil, nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.Equal(t, float64(2), testutil.ToFloat64(selectedRules.WithLabelValues(cr.Namespace, cr.Name)))
+	assert.Equal(t, float64(1), testutil.ToFloat64(invalidRules.WithLabelValues(cr.Namespace, cr.Name)))
+	assert.Equal(t, float64(1), testutil.ToFloat64(ruleConfigMaps.WithLabelValues(cr.Namespace, cr.Name)))
+
+	DeregisterRulesMetrics(cr)
+	assert.False(t, selectedRules.DeleteLabelValues(cr.Namespace, cr.Name))
+	assert.False(t, invalidRules.DeleteLabelValues(cr.Namespace, cr.Name))
+	assert.False(t, ruleConfigMaps.DeleteLabelValues(cr.Namespace, cr.Name))
+}
+
+func Test_validateRuleExpressions(t *testing.T) {
+	groups := []vmv1beta1.RuleGroup{
+		{Name: "broken", Rules: []vmv1beta1.Rule{{Alert: "errors", Expr: "rate(err_metric[1m] > 10"}}},
+		{Name: "valid", Rules: []vmv1beta1.Rule{{Alert: "up", Expr: "up == 0"}}},
+		{Name: "logs", Type: "vlogs", Rules: []vmv1beta1.Rule{{Alert: "logs", Expr: "* | stats count()"}}},
+	}
+	origin := append([]vmv1beta1.RuleGroup(nil), groups...)
+	spec := &vmv1beta1.VMRuleSpec{Groups: groups}
+	err := validateRuleExpressions(spec)
+	assert.Error(t, err)
+	assert.Equal(t, []string{"valid", "logs"}, []string{spec.Groups[0].Name, spec.Groups[1].Name})
+	assert.Len(t, spec.Groups, 2)
+	// caller slice must not be modified
+	assert.Equal(t, origin, groups)
+}
+
+func TestSelectRulesBrokenCount(t *testing.T) {
+	cr := &vmv1beta1.VMAlert{
+		ObjectMeta: metav1.ObjectMeta{Name: "broken-vmalert", Namespace: "default"},
+		Spec:       vmv1beta1.VMAlertSpec{SelectAllByDefault: true, MaxTotalRules: 1},
+	}
+	fclient := k8stools.GetTestClientWithObjects([]runtime.Object{
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+		&vmv1beta1.VMRule{
+			ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "default"},
+			Spec: vmv1beta1.VMRuleSpec{Groups: []vmv1beta1.RuleGroup{{Name: "a", Rules: []vmv1beta1.Rule{
+				{Alert: "up", Expr: "up == 0"},
+			}}}},
+		},
+		// partially broken rule, which is also skipped by maxTotalRules limit
+		&vmv1beta1.VMRule{
+			ObjectMeta: metav1.ObjectMeta{Name: "b", Namespace: "default"},
+			Spec: vmv1beta1.VMRuleSpec{Groups: []vmv1beta1.RuleGroup{
+				{Name: "b-valid", Rules: []vmv1beta1.Rule{{Alert: "up", Expr: "up == 0"}}},
+				{Name: "b-broken", Rules: []vmv1beta1.Rule{{Alert: "up", Expr: "up =="}}},
+			}},
+		},
+	})
+	before := testutil.ToFloat64(badConfigsTotal)
+	if _, _, _, err := selectRulesContent(context.TODO(), fclient, cr); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.Equal(t, float64(1), testutil.ToFloat64(badConfigsTotal)-before)
+}
+
+func TestRulesStorageSecret(t *testing.T) {
+	cr := &vmv1beta1.VMAlert{
+		ObjectMeta: metav1.ObjectMeta{Name: "secret-vmalert", Namespace: "default"},
+		Spec: vmv1beta1.VMAlertSpec{
+			SelectAllByDefault: true,
+			RulesStorage:       vmv1beta1.VMAlertRulesStorageSecret,
+		},
+	}
+	staleCM := &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+		Name:      "vm-secret-vmalert-rulefiles-0",
+		Namespace: "default",
+		Labels:    map[string]string{"vmalert-name": cr.Name},
+	}}
+	fclient := k8stools.GetTestClientWithObjects([]runtime.Object{
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+		&vmv1beta1.VMRule{
+			ObjectMeta: metav1.ObjectMeta{Name: "rule", Namespace: "default"},
+			Spec: vmv1beta1.VMRuleSpec{Groups: []vmv1beta1.RuleGroup{{Name: "group", Rules: []vmv1beta1.Rule{
+				{Alert: "up", Expr: "up == 0"},
+			}}}},
+		},
+		staleCM,
+	})
+	ctx := context.TODO()
+	got, err := CreateOrUpdateRuleConfigMaps(ctx, fclient, cr, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.Equal(t, [][]RuleObject{{{Name: "vm-secret-vmalert-rulefiles-0", Checksum: "28ab81d23557535c"}}}, got)
+	var s v1.Secret
+	if err := fclient.Get(ctx, types.NamespacedName{Namespace: "default", Name: "vm-secret-vmalert-rulefiles-0"}, &s); err != nil {
+		t.Fatalf("expected rules secret to be created: %s", err)
+	}
+	assert.Contains(t, s.Data, "default-rule.yaml")
+
+	if err := removeStaleRules(ctx, fclient, cr, got); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var cms v1.ConfigMapList
+	if err := fclient.List(ctx, &cms, cr.RulesConfigMapSelector()); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.Empty(t, cms.Items)
+	var secrets v1.SecretList
+	if err := fclient.List(ctx, &secrets, cr.RulesConfigMapSelector()); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.Len(t, secrets.Items, 1)
+}
+
+func Test_addPlaceholderRules(t *testing.T) {
+	f := func(cr *vmv1beta1.VMAlert, rulesDataByShard []map[string]string, predefinedObjects []runtime.Object, wantNoRules bool, want []map[string]string) {
+		t.Helper()
+		fclient := k8stools.GetTestClientWithObjects(predefinedObjects)
+		noRules, err := addPlaceholderRules(context.TODO(), fclient, cr, rulesDataByShard)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		assert.Equal(t, wantNoRules, noRules)
+		assert.Equal(t, want, rulesDataByShard)
+	}
+	cr := &vmv1beta1.VMAlert{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}}
+
+	// no rules selected
+	f(cr, []map[string]string{{}}, nil, true, []map[string]string{{placeholderRuleFileName: "groups: []\n"}})
+
+	// rules selected
+	f(cr, []map[string]string{{"default-rule.yaml": "groups: []"}}, nil, false, []map[string]string{{"default-rule.yaml": "groups: []"}})
+
+	// empty shard
+	f(cr, []map[string]string{{"default-rule.yaml": "groups: []"}, {}}, nil, false,
+		[]map[string]string{{"default-rule.yaml": "groups: []"}, {placeholderRuleFileName: "groups: []\n"}})
+
+	// placeholder from configmap
+	crWithRef := cr.DeepCopy()
+	crWithRef.Spec.PlaceholderRulesRef = &v1.ConfigMapKeySelector{LocalObjectReference: v1.LocalObjectReference{Name: "placeholder"}, Key: "rules.yaml"}
+	f(crWithRef, []map[string]string{{}}, []runtime.Object{
+		&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "placeholder", Namespace: "default"}, Data: map[string]string{"rules.yaml": "groups:\n- name: custom\n"}},
+	}, true, []map[string]string{{placeholderRuleFileName: "groups:\n- name: custom\n"}})
+}
+
+func Test_setNoRulesSelectedCondition(t *testing.T) {
+	cr := &vmv1beta1.VMAlert{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}}
+	setNoRulesSelectedCondition(cr, true)
+	assert.Len(t, cr.Status.Conditions, 1)
+	cond := cr.Status.Conditions[0]
+	assert.Equal(t, vmv1beta1.VMAlertNoRulesSelectedCondition, cond.Type)
+	assert.Equal(t, metav1.ConditionTrue, cond.Status)
+
+	// transition time must be preserved
+	setNoRulesSelectedCondition(cr, true)
+	assert.Len(t, cr.Status.Conditions, 1)
+	assert.Equal(t, cond.LastTransitionTime, cr.Status.Conditions[0].LastTransitionTime)
+
+	setNoRulesSelectedCondition(cr, false)
+	assert.Len(t, cr.Status.Conditions, 1)
+	assert.Equal(t, metav1.ConditionFalse, cr.Status.Conditions[0].Status)
+}
+
+func Test_removeStaleRules(t *testing.T) {
+	f := func(cr *vmv1beta1.VMAlert, ruleObjects [][]RuleObject, predefinedObjects []runtime.Object, wantCMs, wantSecrets []string) {
+		t.Helper()
+		ctx := context.TODO()
+		fclient := k8stools.GetTestClientWithObjects(predefinedObjects)
+		if err := removeStaleRules(ctx, fclient, cr, ruleObjects); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		var cms v1.ConfigMapList
+		if err := fclient.List(ctx, &cms, cr.RulesConfigMapSelector()); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		var gotCMs []string
+		for _, cm := range cms.Items {
+			gotCMs = append(gotCMs, cm.Name)
+		}
+		assert.Equal(t, wantCMs, gotCMs)
+		var secrets v1.SecretList
+		if err := fclient.List(ctx, &secrets, cr.RulesConfigMapSelector()); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		var gotSecrets []string
+		for _, s := range secrets.Items {
+			gotSecrets = append(gotSecrets, s.Name)
+		}
+		assert.Equal(t, wantSecrets, gotSecrets)
+	}
+	cr := &vmv1beta1.VMAlert{ObjectMeta: metav1.ObjectMeta{Name: "base", Namespace: "default"}}
+	ruleMeta := func(name string) metav1.ObjectMeta {
+		return metav1.ObjectMeta{
+			Name:       name,
+			Namespace:  "default",
+			Labels:     map[string]string{"vmalert-name": "base"},
+			Finalizers: []string{vmv1beta1.FinalizerName},
+		}
+	}
+
+	// buckets count decreased
+	f(cr, [][]RuleObject{{{Name: "vm-base-rulefiles-0"}}}, []runtime.Object{
+		&v1.ConfigMap{ObjectMeta: ruleMeta("vm-base-rulefiles-0")},
+		&v1.ConfigMap{ObjectMeta: ruleMeta("vm-base-rulefiles-1")},
+		&v1.ConfigMap{ObjectMeta: ruleMeta("vm-base-rulefiles-2")},
+	}, []string{"vm-base-rulefiles-0"}, nil)
+
+	// shards count decreased
+	f(cr, [][]RuleObject{{{Name: "vm-base-rulefiles-0"}}}, []runtime.Object{
+		&v1.ConfigMap{ObjectMeta: ruleMeta("vm-base-rulefiles-0")},
+		&v1.ConfigMap{ObjectMeta: ruleMeta("vm-base-shard-0-rulefiles-0")},
+		&v1.ConfigMap{ObjectMeta: ruleMeta("vm-base-shard-1-rulefiles-0")},
+	}, []string{"vm-base-rulefiles-0"}, nil)
+
+	// configmaps of other vmalert must be kept
+	f(cr, [][]RuleObject{{{Name: "vm-base-rulefiles-0"}}}, []runtime.Object{
+		&v1.ConfigMap{ObjectMeta: ruleMeta("vm-base-rulefiles-0")},
+		&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "vm-other-rulefiles-1", Namespace: "default", Labels: map[string]string{"vmalert-name": "other"}}},
+	}, []string{"vm-base-rulefiles-0"}, nil)
+
+	// secret storage
+	crWithSecret := cr.DeepCopy()
+	crWithSecret.Spec.RulesStorage = vmv1beta1.VMAlertRulesStorageSecret
+	f(crWithSecret, [][]RuleObject{{{Name: "vm-base-rulefiles-0"}}}, []runtime.Object{
+		&v1.ConfigMap{ObjectMeta: ruleMeta("vm-base-rulefiles-0")},
+		&v1.Secret{ObjectMeta: ruleMeta("vm-base-rulefiles-0")},
+		&v1.Secret{ObjectMeta: ruleMeta("vm-base-rulefiles-1")},
+	}, nil, []string{"vm-base-rulefiles-0"})
+}
+
+func Test_generateContent(t *testing.T) {
+	pRule := &vmv1beta1.VMRule{
+		ObjectMeta: metav1.ObjectMeta{Name: "rule", Namespace: "default", UID: "8a7e1c0a", Generation: 3},
+		Spec: vmv1beta1.VMRuleSpec{Groups: []vmv1beta1.RuleGroup{{Name: "group", Rules: []vmv1beta1.Rule{
+			{Alert: "up", Expr: "up == 0"},
+		}}}},
+	}
+	got, err := generateContent(pRule, pRule.Spec, "namespace", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.Equal(t, `# source VMRule: namespace="default" name="rule" uid="8a7e1c0a" generation=3
+# generated by operator version=""
+groups:
+- name: group
+  rules:
+  - alert: up
+    expr: up == 0
+    labels:
+      namespace: default
+`, got)
+}
+
+// Test_generateContentGolden checks that rule files received by vmalert
+// preserve group params written at VMRule
+func Test_generateContentGolden(t *testing.T) {
+	f := func(name string) {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join("testdata", "rules", name+".vmrule.yaml"))
+		if err != nil {
+			t.Fatalf("cannot read VMRule: %s", err)
+		}
+		var pRule vmv1beta1.VMRule
+		if err := yaml.Unmarshal(data, &pRule); err != nil {
+			t.Fatalf("cannot parse VMRule: %s", err)
+		}
+		if err := pRule.Validate(); err != nil {
+			t.Fatalf("unexpected validation error: %s", err)
+		}
+		got, err := generateContent(&pRule, pRule.Spec, "", nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		want, err := os.ReadFile(filepath.Join("testdata", "rules", name+".golden.yaml"))
+		if err != nil {
+			t.Fatalf("cannot read golden file: %s", err)
+		}
+		assert.Equal(t, string(want), got)
+
+		// generated content must round-trip into the same spec
+		var gotSpec vmv1beta1.VMRuleSpec
+		if err := yaml.Unmarshal([]byte(got), &gotSpec); err != nil {
+			t.Fatalf("cannot parse generated content: %s", err)
+		}
+		assert.Equal(t, pRule.Spec, gotSpec)
+
+		// vmalert must recognize all group params
+		var vmalertCfg struct {
+			Groups []vmalertconfig.Group `yaml:"groups"`
+		}
+		if err := yamlv2.Unmarshal([]byte(got), &vmalertCfg); err != nil {
+			t.Fatalf("cannot parse generated content by vmalert: %s", err)
+		}
+		assert.Len(t, vmalertCfg.Groups, len(pRule.Spec.Groups))
+		for idx, g := range vmalertCfg.Groups {
+			// tenant is supported by enterprise version of vmalert only
+			delete(g.XXX, "tenant")
+			assert.Empty(t, g.XXX)
+			want := pRule.Spec.Groups[idx]
+			assert.Equal(t, want.Type, g.Type.Get())
+			assert.Len(t, g.Headers, len(want.Headers))
+			assert.Len(t, g.NotifierHeaders, len(want.NotifierHeaders))
+			for _, r := range g.Rules {
+				assert.Empty(t, r.XXX)
+			}
+		}
+	}
+	f("graphite")
+	f("params")
+}
+
+func Test_rulesChecksum(t *testing.T) {
+	files := map[string][]byte{"a.yaml": []byte("groups: []"), "b.yaml": []byte("groups: []")}
+	assert.Equal(t, rulesChecksum(files), rulesChecksum(map[string][]byte{"b.yaml": []byte("groups: []"), "a.yaml": []byte("groups: []")}))
+	assert.NotEqual(t, rulesChecksum(files), rulesChecksum(map[string][]byte{"a.yaml": []byte("groups: []")}))
+
+	cm := makeRulesConfigMap(&vmv1beta1.VMAlert{ObjectMeta: metav1.ObjectMeta{Name: "base", Namespace: "default"}}, 0, files, false)
+	assert.Equal(t, rulesChecksum(files), cm.Annotations[vmv1beta1.VMAlertRulesChecksumAnnotation])
+}
+
+func Test_generateContentWithGroupDefaults(t *testing.T) {
+	pRule := &vmv1beta1.VMRule{
+		ObjectMeta: metav1.ObjectMeta{Name: "rule", Namespace: "default"},
+		Spec: vmv1beta1.VMRuleSpec{Groups: []vmv1beta1.RuleGroup{
+			{
+				Name:  "without-settings",
+				Rules: []vmv1beta1.Rule{{Alert: "up", Expr: "up == 0"}},
+			},
+			{
+				Name:        "with-settings",
+				Interval:    "1m",
+				Concurrency: 2,
+				Params:      url.Values{"extra_label": []string{"env=dev"}},
+				Headers:     []string{"x-scope-orgid: team-a"},
+				Rules:       []vmv1beta1.Rule{{Alert: "up", Expr: "up == 0"}},
+			},
+		}},
+	}
+	groupDefaults := &vmv1beta1.VMAlertRuleGroupDefaults{
+		Params:       url.Values{"extra_label": []string{"env=prod"}, "nocache": []string{"1"}},
+		Headers:      []string{"X-Scope-OrgID: default", "Authorization: Bearer token"},
+		EvalInterval: "30s",
+		Concurrency:  4,
+	}
+	got, err := generateContent(pRule, pRule.Spec, "", groupDefaults)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.Equal(t, `# source VMRule: namespace="default" name="rule" uid="" generation=0
+# generated by operator version=""
+groups:
+- concurrency: 4
+  headers:
+  - 'X-Scope-OrgID: default'
+  - 'Authorization: Bearer token'
+  interval: 30s
+  name: without-settings
+  params:
+    extra_label:
+    - env=prod
+    nocache:
+    - "1"
+  rules:
+  - alert: up
+    expr: up == 0
+- concurrency: 2
+  headers:
+  - 'x-scope-orgid: team-a'
+  - 'Authorization: Bearer token'
+  interval: 1m
+  name: with-settings
+  params:
+    extra_label:
+    - env=dev
+    nocache:
+    - "1"
+  rules:
+  - alert: up
+    expr: up == 0
+`, got)
+	// source VMRule must not be modified
+	assert.Empty(t, pRule.Spec.Groups[0].Interval)
+	assert.Len(t, pRule.Spec.Groups[1].Params, 1)
+}
+
+func TestSelectRulesLimits(t *testing.T) {
+	f := func(spec vmv1beta1.VMAlertSpec, wantFiles []string, wantErrors map[string]string, wantLimitReached metav1.ConditionStatus) {
+		t.Helper()
+		cr := &vmv1beta1.VMAlert{
+			ObjectMeta: metav1.ObjectMeta{Name: "limits-vmalert", Namespace: "default"},
+			Spec:       spec,
+		}
+		cr.Spec.SelectAllByDefault = true
+		rule := func(name string, groups, rules int) *vmv1beta1.VMRule {
+			r := &vmv1beta1.VMRule{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}}
+			for i := 0; i < groups; i++ {
+				g := vmv1beta1.RuleGroup{Name: fmt.Sprintf("%s-%d", name, i)}
+				for j := 0; j < rules; j++ {
+					g.Rules = append(g.Rules, vmv1beta1.Rule{Alert: fmt.Sprintf("alert-%d", j), Expr: "up == 0"})
+				}
+				r.Spec.Groups = append(r.Spec.Groups, g)
+			}
+			return r
+		}
+		fclient := k8stools.GetTestClientWithObjects([]runtime.Object{
+			&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+			rule("a", 1, 2),
+			rule("b", 3, 1),
+			rule("c", 1, 4),
+		})
+		contentByShard, vmRules, _, err := selectRulesContent(context.TODO(), fclient, cr)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		var gotFiles []string
+		for name := range contentByShard[0] {
+			gotFiles = append(gotFiles, name)
+		}
+		sort.Strings(gotFiles)
+		assert.Equal(t, wantFiles, gotFiles)
+		gotErrors := make(map[string]string)
+		for _, r := range vmRules {
+			if r.Status.CurrentSyncError != "" {
+				gotErrors[r.Name] = r.Status.CurrentSyncError
+			}
+		}
+		assert.Equal(t, wantErrors, gotErrors)
+		var gotLimitReached metav1.ConditionStatus
+		for _, c := range cr.Status.Conditions {
+			if c.Type == vmv1beta1.VMAlertRulesLimitReachedCondition {
+				gotLimitReached = c.Status
+			}
+		}
+		assert.Equal(t, wantLimitReached, gotLimitReached)
+	}
+
+	// no limits
+	f(vmv1beta1.VMAlertSpec{}, []string{"default-a.yaml", "default-b.yaml", "default-c.yaml"}, map[string]string{}, metav1.ConditionFalse)
+
+	// per object limits
+	f(vmv1beta1.VMAlertSpec{MaxRulesPerGroup: 3, MaxGroupsPerRule: 2}, []string{"default-a.yaml"}, map[string]string{
+		"b": "VMRule has 3 groups, which exceeds maxGroupsPerRule=2 limit",
+		"c": `group="c-0" has 4 rules, which exceeds maxRulesPerGroup=3 limit`,
+	}, metav1.ConditionFalse)
+
+	// total limit skips all rules after the first exceeding one
+	f(vmv1beta1.VMAlertSpec{MaxTotalRules: 6}, []string{"default-a.yaml", "default-b.yaml"}, map[string]string{
+		"c": "VMRule is skipped, since maxTotalRules=6 limit is reached",
+	}, metav1.ConditionTrue)
+	f(vmv1beta1.VMAlertSpec{MaxTotalRules: 4}, []string{"default-a.yaml"}, map[string]string{
+		"b": "VMRule is skipped, since maxTotalRules=4 limit is reached",
+		"c": "VMRule is skipped, since maxTotalRules=4 limit is reached",
+	}, metav1.ConditionTrue)
+}
+
+func Test_checkRulePolicies(t *testing.T) {
+	f := func(policies *vmv1beta1.VMAlertRulePolicies, groups []vmv1beta1.RuleGroup, wantErr string) {
+		t.Helper()
+		err := checkRulePolicies(policies, &vmv1beta1.VMRuleSpec{Groups: groups})
+		if wantErr == "" {
+			assert.NoError(t, err)
+			return
+		}
+		assert.EqualError(t, err, wantErr)
+	}
+	policies := &vmv1beta1.VMAlertRulePolicies{
+		RequiredLabels:           []string{"severity", "team"},
+		ForbiddenLabels:          []string{"instance"},
+		RequiredAnnotations:      []string{"summary"},
+		MaxAnnotationValueLength: 10,
+	}
+	validAlert := vmv1beta1.Rule{
+		Alert:       "down",
+		Expr:        "up == 0",
+		Labels:      map[string]string{"severity": "critical"},
+		Annotations: map[string]string{"summary": "down"},
+	}
+
+	// no policies
+	f(nil, []vmv1beta1.RuleGroup{{Name: "group", Rules: []vmv1beta1.Rule{{Alert: "down", Expr: "up == 0"}}}}, "")
+
+	// group labels are used as rule labels, recording rules are ignored by default
+	f(policies, []vmv1beta1.RuleGroup{{
+		Name:   "group",
+		Labels: map[string]string{"team": "storage"},
+		Rules:  []vmv1beta1.Rule{validAlert, {Record: "job:up", Expr: "sum(up) by (job)"}},
+	}}, "")
+
+	// all violations are reported
+	f(policies, []vmv1beta1.RuleGroup{{
+		Name: "group",
+		Rules: []vmv1beta1.Rule{validAlert, {
+			Alert:       "slow",
+			Expr:        "latency > 1",
+			Labels:      map[string]string{"instance": "a"},
+			Annotations: map[string]string{"description": "very long description"},
+		}},
+	}}, `VMRule violates rule policies: group="group" rule="down": missing required label="team"; `+
+		`group="group" rule="slow": missing required label="severity"; group="group" rule="slow": missing required label="team"; `+
+		`group="group" rule="slow": has forbidden label="instance"; group="group" rule="slow": missing required annotation="summary"; `+
+		`group="group" rule="slow": annotation="description" value length=21 exceeds maxAnnotationValueLength=10`)
+
+	// recording rules
+	f(&vmv1beta1.VMAlertRulePolicies{RequiredLabels: []string{"team"}, ApplyToRecordingRules: ptr.To(true)}, []vmv1beta1.RuleGroup{{
+		Name:  "group",
+		Rules: []vmv1beta1.Rule{{Record: "job:up", Expr: "sum(up) by (job)"}},
+	}}, `VMRule violates rule policies: group="group" rule="job:up": missing required label="team"`)
+}
+
+func TestCreateOrUpdateRuleConfigMapsNamespaceRuleDirs(t *testing.T) {
+	cr := &vmv1beta1.VMAlert{
+		ObjectMeta: metav1.ObjectMeta{Name: "ns-dirs", Namespace: "default"},
+		Spec: vmv1beta1.VMAlertSpec{
+			SelectAllByDefault:   true,
+			UseNamespaceRuleDirs: ptr.To(true),
+		},
+	}
+	rule := func(namespace, name string) *vmv1beta1.VMRule {
+		return &vmv1beta1.VMRule{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec: vmv1beta1.VMRuleSpec{Groups: []vmv1beta1.RuleGroup{{Name: name, Rules: []vmv1beta1.Rule{
+				{Alert: "up", Expr: "up == 0"},
+			}}}},
+		}
+	}
+	// both rules produce team-a-b-c.yaml file with flat layout
+	fclient := k8stools.GetTestClientWithObjects([]runtime.Object{
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a"}},
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a-b"}},
+		rule("team-a", "b-c"),
+		rule("team-a-b", "c"),
+	})
+	ctx := context.TODO()
+	got, err := CreateOrUpdateRuleConfigMaps(ctx, fclient, cr, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.Equal(t, [][]RuleObject{{{Name: "vm-ns-dirs-rulefiles-0", Items: []v1.KeyToPath{
+		{Key: "team-a-b_c.yaml", Path: "team-a-b/c.yaml"},
+		{Key: "team-a_b-c.yaml", Path: "team-a/b-c.yaml"},
+	}, Checksum: "f55c19e19ac161dc"}}}, got)
+	var cm v1.ConfigMap
+	if err := fclient.Get(ctx, types.NamespacedName{Namespace: "default", Name: "vm-ns-dirs-rulefiles-0"}, &cm); err != nil {
+		t.Fatalf("expected rules configmap to be created: %s", err)
+	}
+	assert.Len(t, cm.Data, 2)
+
+	// placeholder rule file must be placed into vmalert namespace directory
+	emptyCR := cr.DeepCopy()
+	emptyCR.Name = "empty"
+	emptyCR.Spec.RuleSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"missing": "label"}}
+	got, err = CreateOrUpdateRuleConfigMaps(ctx, fclient, emptyCR, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.Equal(t, [][]RuleObject{{{Name: "vm-empty-rulefiles-0", Items: []v1.KeyToPath{
+		{Key: "default_placeholder-rules.yaml", Path: "default/placeholder-rules.yaml"},
+	}, Checksum: "3aa0226986ca1d5"}}}, got)
+}
+
+func TestCreateOrUpdateRuleConfigMapsPerNamespace(t *testing.T) {
+	cr := &vmv1beta1.VMAlert{
+		ObjectMeta: metav1.ObjectMeta{Name: "per-ns", Namespace: "default"},
+		Spec: vmv1beta1.VMAlertSpec{
+			SelectAllByDefault:  true,
+			RuleConfigMapLayout: vmv1beta1.VMAlertRuleConfigMapLayoutPerNamespace,
+		},
+	}
+	rule := func(namespace, name string) *vmv1beta1.VMRule {
+		return &vmv1beta1.VMRule{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec: vmv1beta1.VMRuleSpec{Groups: []vmv1beta1.RuleGroup{{Name: namespace + "-" + name, Rules: []vmv1beta1.Rule{
+				{Alert: "up", Expr: "up == 0"},
+			}}}},
+		}
+	}
+	ctx := context.TODO()
+	f := func(fclient client.Client, wantFiles map[string][]string) {
+		t.Helper()
+		got, err := CreateOrUpdateRuleConfigMaps(ctx, fclient, cr, nil, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if err := removeStaleRules(ctx, fclient, cr, got); err != nil {
+			t.Fatalf("cannot remove stale rules: %s", err)
+		}
+		assert.Len(t, got, 1)
+		var gotNames []string
+		for _, obj := range got[0] {
+			gotNames = append(gotNames, obj.Name)
+		}
+		wantNames := slices.Sorted(maps.Keys(wantFiles))
+		assert.Equal(t, wantNames, gotNames)
+		var cms v1.ConfigMapList
+		if err := fclient.List(ctx, &cms, cr.RulesConfigMapSelector()); err != nil {
+			t.Fatalf("cannot list rules configmaps: %s", err)
+		}
+		gotFiles := make(map[string][]string)
+		for _, cm := range cms.Items {
+			gotFiles[cm.Name] = slices.Sorted(maps.Keys(cm.Data))
+		}
+		assert.Equal(t, wantFiles, gotFiles)
+	}
+	fclient := k8stools.GetTestClientWithObjects([]runtime.Object{
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a"}},
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-b"}},
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-c"}},
+		rule("team-a", "first"),
+		rule("team-a", "second"),
+		rule("team-b", "first"),
+	})
+	f(fclient, map[string][]string{
+		"vm-per-ns-rulefiles-team-a-0": {"team-a_first.yaml", "team-a_second.yaml"},
+		"vm-per-ns-rulefiles-team-b-0": {"team-b_first.yaml"},
+	})
+
+	// namespace without rules must be removed and new namespace must get own ConfigMap
+	if err := fclient.Delete(ctx, rule("team-b", "first")); err != nil {
+		t.Fatalf("cannot delete rule: %s", err)
+	}
+	if err := fclient.Create(ctx, rule("team-c", "first")); err != nil {
+		t.Fatalf("cannot create rule: %s", err)
+	}
+	f(fclient, map[string][]string{
+		"vm-per-ns-rulefiles-team-a-0": {"team-a_first.yaml", "team-a_second.yaml"},
+		"vm-per-ns-rulefiles-team-c-0": {"team-c_first.yaml"},
+	})
+}
+
+func Test_makeRulesConfigMapsPerNamespace(t *testing.T) {
+	cr := &vmv1beta1.VMAlert{
+		ObjectMeta: metav1.ObjectMeta{Name: "base", Namespace: "default"},
+		Spec: vmv1beta1.VMAlertSpec{
+			RuleConfigMapLayout: vmv1beta1.VMAlertRuleConfigMapLayoutPerNamespace,
+		},
+	}
+	// every ConfigMap fits 2 rule files
+	content := strings.Repeat("#", vmv1beta1.MaxConfigMapDataSize/2-10*1024)
+	ruleFiles := map[string]string{
+		"team-b_rule.yaml":               "groups: []",
+		"team-a_first.yaml":              content,
+		"team-a_second.yaml":             content,
+		"team-a_third.yaml":              content,
+		"default_placeholder-rules.yaml": "groups: []",
+	}
+	got, err := makeRulesConfigMaps(cr, 0, ruleFiles, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var gotNames []string
+	for _, cm := range got {
+		gotNames = append(gotNames, cm.Name)
+		for key := range cm.Data {
+			assert.Contains(t, cm.Name, "-"+ruleFileNamespace(cr, key)+"-")
+		}
+	}
+	assert.Equal(t, []string{
+		"vm-base-rulefiles-default-0",
+		"vm-base-rulefiles-team-a-0",
+		"vm-base-rulefiles-team-a-1",
+		"vm-base-rulefiles-team-b-0",
+	}, gotNames)
+
+	// files must be kept at the previous ConfigMaps
+	prevAssignment := map[string]int{"team-a_first.yaml": 1, "team-a_third.yaml": 1, "team-a_second.yaml": 0}
+	got, err = makeRulesConfigMaps(cr, 0, ruleFiles, prevAssignment)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.Equal(t, "vm-base-rulefiles-team-a-1", got[2].Name)
+	assert.Len(t, got[2].Data, 2)
+	assert.Contains(t, got[2].Data, "team-a_first.yaml")
+}
+
+func Test_ruleObjectVolumeName(t *testing.T) {
+	assert.Equal(t, "vm-base-rulefiles-0", ruleObjectVolumeName("vm-base-rulefiles-0"))
+	long := "vm-base-rulefiles-" + strings.Repeat("team", 12) + "-0"
+	got := ruleObjectVolumeName(long)
+	assert.LessOrEqual(t, len(got), 63)
+	assert.NotEqual(t, got, ruleObjectVolumeName(strings.TrimSuffix(long, "0")+"1"))
+}
+
+func Test_generateContentWithGroupsOrder(t *testing.T) {
+	pRule := &vmv1beta1.VMRule{
+		ObjectMeta: metav1.ObjectMeta{Name: "rule", Namespace: "default"},
+		Spec: vmv1beta1.VMRuleSpec{Groups: []vmv1beta1.RuleGroup{
+			{Name: "c", Order: 2, Rules: []vmv1beta1.Rule{{Record: "c", Expr: "b"}}},
+			{Name: "b", Order: 1, Rules: []vmv1beta1.Rule{{Record: "b", Expr: "a"}}},
+			{Name: "a", Order: 1, EvalDelay: "30s", Limit: 10, Rules: []vmv1beta1.Rule{{Record: "a", Expr: "up"}}},
+		}},
+	}
+	got, err := generateContent(pRule, pRule.Spec, "", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.Equal(t, `# source VMRule: namespace="default" name="rule" uid="" generation=0
+# generated by operator version=""
+groups:
+- eval_delay: 30s
+  limit: 10
+  name: a
+  rules:
+  - expr: up
+    record: a
+- name: b
+  rules:
+  - expr: a
+    record: b
+- name: c
+  rules:
+  - expr: b
+    record: c
+`, got)
+	// source object must not be modified
+	assert.Equal(t, "c", pRule.Spec.Groups[0].Name)
+	assert.Equal(t, 2, pRule.Spec.Groups[0].Order)
+}
+
+func Test_ruleFileKey(t *testing.T) {
+	f := func(useNamespaceDirs bool, order *int, want string) {
+		t.Helper()
+		cr := &vmv1beta1.VMAlert{Spec: vmv1beta1.VMAlertSpec{UseNamespaceRuleDirs: ptr.To(useNamespaceDirs)}}
+		assert.Equal(t, want, ruleFileKey(cr, "default", "rule", order))
+	}
+	f(false, nil, "default-rule.yaml")
+	f(false, ptr.To(10), "0010-default-rule.yaml")
+	f(true, nil, "default_rule.yaml")
+	f(true, ptr.To(10), "default_0010-rule.yaml")
+}
+
+func TestRolloutOnRuleChange(t *testing.T) {
+	cr := &vmv1beta1.VMAlert{
+		ObjectMeta: metav1.ObjectMeta{Name: "rollout", Namespace: "default"},
+		Spec: vmv1beta1.VMAlertSpec{
+			SelectAllByDefault:  true,
+			RolloutOnRuleChange: ptr.To(true),
+		},
+	}
+	rule := &vmv1beta1.VMRule{
+		ObjectMeta: metav1.ObjectMeta{Name: "rule", Namespace: "default"},
+		Spec: vmv1beta1.VMRuleSpec{Groups: []vmv1beta1.RuleGroup{{Name: "group", Rules: []vmv1beta1.Rule{
+			{Alert: "up", Expr: "up == 0"},
+		}}}},
+	}
+	fclient := k8stools.GetTestClientWithObjects([]runtime.Object{
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: cr.PrefixedName(), Namespace: cr.Namespace}},
+		rule,
+	})
+	ctx := context.TODO()
+	getChecksum := func() string {
+		t.Helper()
+		var dep appsv1.Deployment
+		if err := fclient.Get(ctx, types.NamespacedName{Namespace: cr.Namespace, Name: cr.PrefixedName()}, &dep); err != nil {
+			t.Fatalf("cannot get deployment: %s", err)
+		}
+		return dep.Spec.Template.Annotations[vmv1beta1.VMAlertRulesChecksumAnnotation]
+	}
+	got, err := CreateOrUpdateRuleConfigMaps(ctx, fclient, cr, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	firstChecksum := getChecksum()
+	assert.NotEmpty(t, firstChecksum)
+	assert.Equal(t, ruleObjectsChecksum(got[0]), firstChecksum)
+
+	// generated deployment must have the same checksum
+	dep, err := newDeployForVMAlert(cr, got[0], nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.Equal(t, firstChecksum, dep.Spec.Template.Annotations[vmv1beta1.VMAlertRulesChecksumAnnotation])
+
+	// checksum must change only with rules content
+	if _, err := CreateOrUpdateRuleConfigMaps(ctx, fclient, cr, nil, nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.Equal(t, firstChecksum, getChecksum())
+	if err := fclient.Get(ctx, types.NamespacedName{Namespace: rule.Namespace, Name: rule.Name}, rule); err != nil {
+		t.Fatalf("cannot get rule: %s", err)
+	}
+	rule.Spec.Groups[0].Rules[0].Expr = "up == 1"
+	if err := fclient.Update(ctx, rule); err != nil {
+		t.Fatalf("cannot update rule: %s", err)
+	}
+	if _, err := CreateOrUpdateRuleConfigMaps(ctx, fclient, cr, nil, nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.NotEqual(t, firstChecksum, getChecksum())
+
+	// in-place reload must not set checksum
+	cr.Spec.RolloutOnRuleChange = nil
+	dep, err = newDeployForVMAlert(cr, got[0], nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.NotContains(t, dep.Spec.Template.Annotations, vmv1beta1.VMAlertRulesChecksumAnnotation)
+}
+
+func TestRuleSyncStatus(t *testing.T) {
+	cr := &vmv1beta1.VMAlert{
+		ObjectMeta: metav1.ObjectMeta{Name: "sync", Namespace: "default"},
+		Spec:       vmv1beta1.VMAlertSpec{SelectAllByDefault: true},
+	}
+	fclient := k8stools.GetTestClientWithObjects([]runtime.Object{
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+		cr.DeepCopy(),
+		&vmv1beta1.VMRule{
+			ObjectMeta: metav1.ObjectMeta{Name: "valid", Namespace: "default"},
+			Spec: vmv1beta1.VMRuleSpec{Groups: []vmv1beta1.RuleGroup{
+				{Name: "first", Rules: []vmv1beta1.Rule{{Alert: "up", Expr: "up == 0"}, {Record: "up:sum", Expr: "sum(up)"}}},
+				{Name: "second", Rules: []vmv1beta1.Rule{{Alert: "up", Expr: "up == 0"}}},
+			}},
+		},
+		&vmv1beta1.VMRule{
+			ObjectMeta: metav1.ObjectMeta{Name: "invalid", Namespace: "default"},
+			Spec: vmv1beta1.VMRuleSpec{Groups: []vmv1beta1.RuleGroup{{Name: "invalid", Rules: []vmv1beta1.Rule{
+				{Alert: "up", Expr: "up =="},
+			}}}},
+		},
+	})
+	ctx := context.TODO()
+	if _, err := CreateOrUpdateRuleConfigMaps(ctx, fclient, cr, nil, nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	got := cr.Status.RuleSync
+	if got == nil {
+		t.Fatalf("expected rule sync status to be set")
+	}
+	assert.Equal(t, []string{"vm-sync-rulefiles-0"}, got.ConfigMaps)
+	assert.Equal(t, 1, got.RuleFiles)
+	assert.Equal(t, 2, got.Groups)
+	assert.Equal(t, 3, got.Rules)
+	assert.Equal(t, 1, got.RejectedRules)
+	assert.False(t, got.LastSyncTime.IsZero())
+
+	// sync time is preserved for unchanged status
+	syncTime := metav1.NewTime(got.LastSyncTime.Add(-time.Hour))
+	cr.Status.RuleSync.LastSyncTime = syncTime
+	if _, err := CreateOrUpdateRuleConfigMaps(ctx, fclient, cr, nil, nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.Equal(t, syncTime, cr.Status.RuleSync.LastSyncTime)
+
+	// only rule sync status and conditions are updated
+	cr.Status.Reason = "must not be stored"
+	if err := UpdateRuleSyncStatus(ctx, fclient, cr); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var stored vmv1beta1.VMAlert
+	if err := fclient.Get(ctx, types.NamespacedName{Namespace: cr.Namespace, Name: cr.Name}, &stored); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.Equal(t, 3, stored.Status.RuleSync.Rules)
+	assert.Len(t, stored.Status.Conditions, len(cr.Status.Conditions))
+	assert.Empty(t, stored.Status.Reason)
+
+	assert.Empty(t, cr.Status.RuleSync.RuleFilesHash)
+}
+
+func TestRuleSyncStatusRuleFilesHash(t *testing.T) {
+	cr := &vmv1beta1.VMAlert{
+		ObjectMeta: metav1.ObjectMeta{Name: "sync", Namespace: "default"},
+		Spec: vmv1beta1.VMAlertSpec{
+			SelectAllByDefault:   true,
+			UseNamespaceRuleDirs: ptr.To(true),
+		},
+	}
+	rule := func(name string) *vmv1beta1.VMRule {
+		return &vmv1beta1.VMRule{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: vmv1beta1.VMRuleSpec{Groups: []vmv1beta1.RuleGroup{
+				{Name: name, Rules: []vmv1beta1.Rule{{Alert: "up", Expr: "up == 0"}}},
+			}},
+		}
+	}
+	fclient := k8stools.GetTestClientWithObjects([]runtime.Object{
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+		cr.DeepCopy(),
+		rule("first"),
+	})
+	ctx := context.TODO()
+	if _, err := CreateOrUpdateRuleConfigMaps(ctx, fclient, cr, nil, nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	prevHash := cr.Status.RuleSync.RuleFilesHash
+	assert.NotEmpty(t, prevHash)
+
+	// new VMRule adds rule file, which must be mounted by VMAlert reconcile
+	second := rule("second")
+	if err := fclient.Create(ctx, second); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := CreateOrUpdateRuleConfigMaps(ctx, fclient, cr, second, nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.NotEqual(t, prevHash, cr.Status.RuleSync.RuleFilesHash)
+}
+
+// genRulesForProcessing returns VMRules with valid and broken rules for rules processing tests
+func genRulesForProcessing(count int) []runtime.Object {
+	objs := []runtime.Object{&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}}}
+	for i := range count {
+		expr := fmt.Sprintf("sum(rate(http_requests_total{job=\"job-%d\"}[5m])) by (instance) > 0", i)
+		if i%10 == 0 {
+			expr = "sum(rate("
+		}
+		objs = append(objs, &vmv1beta1.VMRule{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("rule-%d", i), Namespace: "default"},
+			Spec: vmv1beta1.VMRuleSpec{Groups: []vmv1beta1.RuleGroup{
+				{Name: "alerts", Rules: []vmv1beta1.Rule{{
+					Alert:       fmt.Sprintf("HighRate%d", i),
+					Expr:        expr,
+					Labels:      map[string]string{"severity": "warning"},
+					Annotations: map[string]string{"summary": "{{ $labels.instance }} has high rate"},
+				}}},
+				{Name: "records", Rules: []vmv1beta1.Rule{{
+					Record: fmt.Sprintf("job:http_requests:rate%d", i),
+					Expr:   "sum(rate(http_requests_total[5m])) by (job)",
+				}}},
+			}},
+		})
+	}
+	return objs
+}
+
+func TestSelectRulesContentWorkers(t *testing.T) {
+	cfg := config.MustGetBaseConfig()
+	defaultWorkers := cfg.VMAlertRulesProcessingWorkers
+	defer func() { cfg.VMAlertRulesProcessingWorkers = defaultWorkers }()
+
+	objs := genRulesForProcessing(200)
+	cr := &vmv1beta1.VMAlert{
+		ObjectMeta: metav1.ObjectMeta{Name: "base", Namespace: "default"},
+		Spec: vmv1beta1.VMAlertSpec{
+			SelectAllByDefault:     true,
+			EnforcedNamespaceLabel: "namespace",
+			MaxTotalRules:          300,
+			RuleShardingStrategy:   vmv1beta1.VMAlertRuleShardingByGroup,
+			ShardCount:             ptr.To(2),
+		},
+	}
+	f := func(workers int) ([]map[string]string, []string, rulesStats) {
+		t.Helper()
+		cfg.VMAlertRulesProcessingWorkers = workers
+		fclient := k8stools.GetTestClientWithObjects(objs)
+		contentByShard, vmRules, stats, err := selectRulesContent(context.TODO(), fclient, cr.DeepCopy())
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		syncErrs := make([]string, 0, len(vmRules))
+		for _, r := range vmRules {
+			syncErrs = append(syncErrs, r.Name+": "+r.Status.CurrentSyncError)
+		}
+		return contentByShard, syncErrs, stats
+	}
+	wantContent, wantErrs, wantStats := f(1)
+	assert.Equal(t, rulesStats{groups: 299, rules: 299, rejected: 59}, wantStats)
+	for _, workers := range []int{2, 8, 64} {
+		gotContent, gotErrs, gotStats := f(workers)
+		assert.Equal(t, wantContent, gotContent)
+		assert.Equal(t, wantErrs, gotErrs)
+		assert.Equal(t, wantStats, gotStats)
+	}
+}
+
+func BenchmarkSelectRulesContent(b *testing.B) {
+	cfg := config.MustGetBaseConfig()
+	defaultWorkers := cfg.VMAlertRulesProcessingWorkers
+	defer func() { cfg.VMAlertRulesProcessingWorkers = defaultWorkers }()
+
+	fclient := k8stools.GetTestClientWithObjects(genRulesForProcessing(1000))
+	cr := &vmv1beta1.VMAlert{
+		ObjectMeta: metav1.ObjectMeta{Name: "base", Namespace: "default"},
+		Spec: vmv1beta1.VMAlertSpec{
+			SelectAllByDefault:     true,
+			EnforcedNamespaceLabel: "namespace",
+		},
+	}
+	for _, workers := range []int{1, 4, 16} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			cfg.VMAlertRulesProcessingWorkers = workers
+			b.ReportAllocs()
+			for b.Loop() {
+				if _, _, _, err := selectRulesContent(context.TODO(), fclient, cr); err != nil {
+					b.Fatalf("unexpected error: %s", err)
+				}
+			}
+		})
+	}
+}
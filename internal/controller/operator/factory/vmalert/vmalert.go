@@ -3,8 +3,11 @@ package vmalert
 import (
 	"context"
 	"fmt"
+	"hash/fnv"
+	"maps"
 	"path"
 	"sort"
+	"strconv"
 	"strings"
 
 	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
@@ -25,6 +28,8 @@ import (
 
 const (
 	vmAlertConfigDir        = "/etc/vmalert/config"
+	vmAlertUnpackedRulesDir = "/etc/vmalert/unpacked"
+	unpackedRulesVolumeName = "rules-unpacked"
 	datasourceKey           = "datasource"
 	remoteReadKey           = "remoteRead"
 	remoteWriteKey          = "remoteWrite"
@@ -49,11 +54,20 @@ func createOrUpdateVMAlertService(ctx context.Context, rclient client.Client, cr
 
 	var prevService, prevAdditionalService *corev1.Service
 	if prevCR != nil {
-		prevService = build.Service(prevCR, prevCR.Spec.Port, nil)
+		prevService = build.Service(prevCR, prevCR.Spec.Port, func(svc *corev1.Service) {
+			if prevCR.Spec.StatefulMode {
+				svc.Spec.ClusterIP = "None"
+			}
+		})
 		prevAdditionalService = build.AdditionalServiceFromDefault(prevService, prevCR.Spec.ServiceSpec)
 	}
 
-	newService := build.Service(cr, cr.Spec.Port, nil)
+	newService := build.Service(cr, cr.Spec.Port, func(svc *corev1.Service) {
+		// headless service is required for stable network identities of statefulSet pods
+		if cr.Spec.StatefulMode {
+			svc.Spec.ClusterIP = "None"
+		}
+	})
 
 	if err := cr.Spec.ServiceSpec.IsSomeAndThen(func(s *vmv1beta1.AdditionalServiceSpec) error {
 		additionalSvc := build.AdditionalServiceFromDefault(newService, s)
@@ -74,7 +88,8 @@ func createOrUpdateVMAlertService(ctx context.Context, rclient client.Client, cr
 	return newService, nil
 }
 
-func createOrUpdateVMAlertSecret(ctx context.Context, rclient client.Client, cr, prevCR *vmv1beta1.VMAlert, ssCache map[string]*authSecret) error {
+// createOrUpdateVMAlertSecret stores auth secrets of remote endpoints and returns its data
+func createOrUpdateVMAlertSecret(ctx context.Context, rclient client.Client, cr, prevCR *vmv1beta1.VMAlert, ssCache map[string]*authSecret) (map[string][]byte, error) {
 	s := &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:            cr.PrefixedName(),
@@ -124,11 +139,39 @@ func createOrUpdateVMAlertSecret(ctx context.Context, rclient client.Client, cr,
 		}
 	}
 
-	return reconcile.Secret(ctx, rclient, s, prevSecretMeta)
+	if err := reconcile.Secret(ctx, rclient, s, prevSecretMeta); err != nil {
+		return nil, err
+	}
+	return s.Data, nil
+}
+
+// remoteSecretsChecksum returns checksum of the given secrets data.
+// Flags of remote endpoints with files referred by it cannot be reloaded by vmalert,
+// so checksum is set at pod template annotations in order to trigger rolling restart on secrets change
+func remoteSecretsChecksum(secretsData ...map[string][]byte) string {
+	h := fnv.New64a()
+	var hasData bool
+	for _, data := range secretsData {
+		keys := make([]string, 0, len(data))
+		for k := range data {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			hasData = true
+			h.Write([]byte(k)) //nolint:errcheck
+			h.Write(data[k])   //nolint:errcheck
+		}
+	}
+	if !hasData {
+		return ""
+	}
+	return strconv.FormatUint(h.Sum64(), 16)
 }
 
 // CreateOrUpdateVMAlert creates vmalert deployment for given CRD
-func CreateOrUpdateVMAlert(ctx context.Context, cr *vmv1beta1.VMAlert, rclient client.Client, cmNames []string) error {
+// ruleObjects contains rule configmaps or secrets per vmalert shard
+func CreateOrUpdateVMAlert(ctx context.Context, cr *vmv1beta1.VMAlert, rclient client.Client, ruleObjects [][]RuleObject) error {
 	var prevCR *vmv1beta1.VMAlert
 	if cr.ParsedLastAppliedSpec != nil {
 		prevCR = cr.DeepCopy()
@@ -149,13 +192,17 @@ func CreateOrUpdateVMAlert(ctx context.Context, cr *vmv1beta1.VMAlert, rclient c
 	if err := discoverNotifierIfNeeded(ctx, rclient, cr); err != nil {
 		return fmt.Errorf("cannot discover additional notifiers: %w", err)
 	}
+	if err := CreateOrUpdateNotifiersConfig(ctx, rclient, cr); err != nil {
+		return err
+	}
 
 	remoteSecrets, err := loadVMAlertRemoteSecrets(ctx, rclient, cr)
 	if err != nil {
 		return err
 	}
 	// create secret for remoteSecrets
-	if err := createOrUpdateVMAlertSecret(ctx, rclient, cr, prevCR, remoteSecrets); err != nil {
+	remoteSecretsData, err := createOrUpdateVMAlertSecret(ctx, rclient, cr, prevCR, remoteSecrets)
+	if err != nil {
 		return err
 	}
 
@@ -171,40 +218,148 @@ func CreateOrUpdateVMAlert(ctx context.Context, cr *vmv1beta1.VMAlert, rclient c
 		}
 	}
 
-	if cr.Spec.PodDisruptionBudget != nil {
-		var prevPDB *policyv1.PodDisruptionBudget
-		if prevCR != nil && prevCR.Spec.PodDisruptionBudget != nil {
-			prevPDB = build.PodDisruptionBudget(prevCR, prevCR.Spec.PodDisruptionBudget)
-		}
-		if err := reconcile.PDB(ctx, rclient, build.PodDisruptionBudget(cr, cr.Spec.PodDisruptionBudget), prevPDB); err != nil {
-			return fmt.Errorf("cannot update pod disruption budget for vmalert: %w", err)
-		}
+	tlsAssetsData, err := createOrUpdateTLSAssetsForVMAlert(ctx, rclient, cr, prevCR)
+	if err != nil {
+		return err
 	}
-
-	err = createOrUpdateTLSAssetsForVMAlert(ctx, rclient, cr, prevCR)
+	remoteChecksum := remoteSecretsChecksum(remoteSecretsData, tlsAssetsData)
+	externalRulesChecksum, err := checkExternalRuleSources(ctx, rclient, cr)
 	if err != nil {
 		return err
 	}
-	var prevDeploy *appsv1.Deployment
-	if prevCR != nil {
-		prevDeploy, err = newDeployForVMAlert(prevCR, cmNames, remoteSecrets)
+	shardsCount := cr.RuleShardsCount()
+	if shardsCount > 1 {
+		logger.WithContext(ctx).Info(fmt.Sprintf("using sharded VMAlert with shards count=%d", shardsCount))
+	}
+	deploymentNames := make(map[string]struct{}, shardsCount)
+	stsNames := make(map[string]struct{}, shardsCount)
+	pdbNames := make(map[string]struct{}, shardsCount)
+	for shardNum := 0; shardNum < shardsCount; shardNum++ {
+		var shardRuleObjects []RuleObject
+		if shardNum < len(ruleObjects) {
+			shardRuleObjects = ruleObjects[shardNum]
+		}
+		var prevDeploy *appsv1.Deployment
+		if prevCR != nil {
+			prevDeploy, err = newDeployForVMAlert(prevCR, shardRuleObjects, remoteSecrets)
+			if err != nil {
+				return fmt.Errorf("cannot generate prev deploy spec: %w", err)
+			}
+		}
+
+		newDeploy, err := newDeployForVMAlert(cr, shardRuleObjects, remoteSecrets)
 		if err != nil {
-			return fmt.Errorf("cannot generate prev deploy spec: %w", err)
+			return fmt.Errorf("cannot generate new deploy for vmalert: %w", err)
+		}
+		if shardsCount > 1 {
+			addShardSettingsToVMAlert(shardNum, newDeploy)
+			if prevDeploy != nil {
+				addShardSettingsToVMAlert(shardNum, prevDeploy)
+			}
+		}
+		// each shard gets own PDB, since single PDB would count pods of all shards together
+		if cr.Spec.PodDisruptionBudget != nil {
+			var prevPDB *policyv1.PodDisruptionBudget
+			if prevCR != nil && prevCR.Spec.PodDisruptionBudget != nil {
+				prevPDB = newPDBForVMAlert(prevCR, shardNum, shardsCount)
+			}
+			newPDB := newPDBForVMAlert(cr, shardNum, shardsCount)
+			if err := reconcile.PDB(ctx, rclient, newPDB, prevPDB); err != nil {
+				return fmt.Errorf("cannot update pod disruption budget for vmalert: %w", err)
+			}
+			pdbNames[newPDB.Name] = struct{}{}
+		}
+		if ptr.Deref(cr.Spec.RolloutOnRuleChange, false) && len(cr.Spec.ExternalRuleSources) > 0 {
+			newDeploy.Spec.Template.Annotations[vmv1beta1.VMAlertExternalRulesChecksumAnnotation] = externalRulesChecksum
 		}
+		if remoteChecksum != "" {
+			newDeploy.Spec.Template.Annotations[vmv1beta1.VMAlertRemoteSecretsChecksumAnnotation] = remoteChecksum
+		}
+		if prevDeploy != nil {
+			// checksum is not known for the previous state, but annotation must be tracked as managed by operator
+			// in order to remove it from pod template after all auth secret refs are removed
+			prevDeploy.Spec.Template.Annotations[vmv1beta1.VMAlertRemoteSecretsChecksumAnnotation] = ""
+		}
+		if cr.Spec.StatefulMode {
+			var prevSTS *appsv1.StatefulSet
+			// prev object could be deployment due to switching to statefulMode
+			if prevDeploy != nil && prevCR.Spec.StatefulMode {
+				prevSTS = newSTSForVMAlert(prevCR, prevDeploy)
+			}
+			newSTS := newSTSForVMAlert(cr, newDeploy)
+			stsOpts := reconcile.STSOptions{
+				HasClaim: len(newSTS.Spec.VolumeClaimTemplates) > 0,
+				SelectorLabels: func() map[string]string {
+					return newSTS.Spec.Selector.MatchLabels
+				},
+			}
+			if err := reconcile.HandleSTSUpdate(ctx, rclient, stsOpts, newSTS, prevSTS); err != nil {
+				return err
+			}
+			stsNames[newSTS.Name] = struct{}{}
+			continue
+		}
+		if prevDeploy != nil && prevCR.Spec.StatefulMode {
+			prevDeploy = nil
+		}
+		if err := reconcile.Deployment(ctx, rclient, newDeploy, prevDeploy, false); err != nil {
+			return err
+		}
+		deploymentNames[newDeploy.Name] = struct{}{}
+	}
+	// workloads of the previous mode are removed after switching between deployment and statefulSet
+	if err := finalize.RemoveOrphanedDeployments(ctx, rclient, cr, deploymentNames); err != nil {
+		return err
+	}
+	if err := finalize.RemoveOrphanedSTSs(ctx, rclient, cr, stsNames); err != nil {
+		return err
+	}
+	// PDBs of removed shards or of the previous sharding mode must be removed as well
+	if err := finalize.RemoveOrphanedPDBs(ctx, rclient, cr, pdbNames); err != nil {
+		return err
 	}
+	// rule files objects must be removed only after deployments update
+	// otherwise vmalert pods may reference deleted objects
+	if !cr.IsUnmanaged() {
+		if err := removeStaleRules(ctx, rclient, cr, ruleObjects); err != nil {
+			return fmt.Errorf("cannot remove stale rules: %w", err)
+		}
+	}
+	return nil
+}
 
-	newDeploy, err := newDeployForVMAlert(cr, cmNames, remoteSecrets)
-	if err != nil {
-		return fmt.Errorf("cannot generate new deploy for vmalert: %w", err)
+// vmAlertDeploymentName returns name of vmalert deployment for the given shard
+func vmAlertDeploymentName(cr *vmv1beta1.VMAlert, shardNum int) string {
+	if cr.RuleShardsCount() > 1 {
+		return fmt.Sprintf("%s-shard-%d", cr.PrefixedName(), shardNum)
 	}
+	return cr.PrefixedName()
+}
+
+// addShardSettingsToVMAlert adds shard number suffix to the deployment name and shard-num label to selector
+func addShardSettingsToVMAlert(shardNum int, dep *appsv1.Deployment) {
+	dep.Name = fmt.Sprintf("%s-shard-%d", dep.Name, shardNum)
+	dep.Spec.Selector.MatchLabels["shard-num"] = strconv.Itoa(shardNum)
+	dep.Spec.Template.Labels["shard-num"] = strconv.Itoa(shardNum)
+}
 
-	return reconcile.Deployment(ctx, rclient, newDeploy, prevDeploy, false)
+// newPDBForVMAlert returns PodDisruptionBudget for the given shard,
+// shard-num label is added to selector if vmalert is sharded
+func newPDBForVMAlert(cr *vmv1beta1.VMAlert, shardNum, shardsCount int) *policyv1.PodDisruptionBudget {
+	pdb := build.PodDisruptionBudget(cr, cr.Spec.PodDisruptionBudget)
+	if shardsCount > 1 {
+		pdb.Name = fmt.Sprintf("%s-shard-%d", pdb.Name, shardNum)
+		selector := maps.Clone(pdb.Spec.Selector.MatchLabels)
+		selector["shard-num"] = strconv.Itoa(shardNum)
+		pdb.Spec.Selector.MatchLabels = selector
+	}
+	return pdb
 }
 
 // newDeployForCR returns a busybox pod with the same name/namespace as the cr
-func newDeployForVMAlert(cr *vmv1beta1.VMAlert, ruleConfigMapNames []string, remoteSecrets map[string]*authSecret) (*appsv1.Deployment, error) {
+func newDeployForVMAlert(cr *vmv1beta1.VMAlert, ruleObjects []RuleObject, remoteSecrets map[string]*authSecret) (*appsv1.Deployment, error) {
 
-	generatedSpec, err := vmAlertSpecGen(cr, ruleConfigMapNames, remoteSecrets)
+	generatedSpec, err := vmAlertSpecGen(cr, ruleObjects, remoteSecrets)
 	if err != nil {
 		return nil, fmt.Errorf("cannot generate new spec for vmalert: %w", err)
 	}
@@ -224,12 +379,38 @@ func newDeployForVMAlert(cr *vmv1beta1.VMAlert, ruleConfigMapNames []string, rem
 	return deploy, nil
 }
 
-func vmAlertSpecGen(cr *vmv1beta1.VMAlert, ruleConfigMapNames []string, remoteSecrets map[string]*authSecret) (*appsv1.DeploymentSpec, error) {
+// newSTSForVMAlert converts given vmalert deployment into statefulSet
+func newSTSForVMAlert(cr *vmv1beta1.VMAlert, dep *appsv1.Deployment) *appsv1.StatefulSet {
+	sts := &appsv1.StatefulSet{
+		ObjectMeta: dep.ObjectMeta,
+		Spec: appsv1.StatefulSetSpec{
+			Selector: dep.Spec.Selector,
+			UpdateStrategy: appsv1.StatefulSetUpdateStrategy{
+				Type: cr.Spec.StatefulRollingUpdateStrategy,
+			},
+			ServiceName: cr.PrefixedName(),
+			Template:    dep.Spec.Template,
+		},
+	}
+	build.StatefulSetAddCommonParams(sts, ptr.Deref(cr.Spec.UseStrictSecurity, false), &cr.Spec.CommonApplicationDeploymentParams)
+	sts.Spec.VolumeClaimTemplates = append(sts.Spec.VolumeClaimTemplates, cr.Spec.ClaimTemplates...)
+	return sts
+}
+
+func vmAlertSpecGen(cr *vmv1beta1.VMAlert, ruleObjects []RuleObject, remoteSecrets map[string]*authSecret) (*appsv1.DeploymentSpec, error) {
 
-	args := buildVMAlertArgs(cr, ruleConfigMapNames, remoteSecrets)
+	args := buildVMAlertArgs(cr, ruleObjects, remoteSecrets)
 
 	var envs []corev1.EnvVar
 
+	if cr.Spec.HAMode {
+		envs = append(envs, corev1.EnvVar{
+			Name: haModePodNameEnv,
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.name"},
+			},
+		})
+	}
 	envs = append(envs, cr.Spec.ExtraEnvs...)
 
 	var volumes []corev1.Volume
@@ -253,16 +434,26 @@ func vmAlertSpecGen(cr *vmv1beta1.VMAlert, ruleConfigMapNames []string, remoteSe
 		},
 	)
 
-	for _, name := range ruleConfigMapNames {
-		volumes = append(volumes, corev1.Volume{
-			Name: name,
-			VolumeSource: corev1.VolumeSource{
-				ConfigMap: &corev1.ConfigMapVolumeSource{
-					LocalObjectReference: corev1.LocalObjectReference{
-						Name: name,
-					},
+	for _, obj := range ruleObjects {
+		vs := corev1.VolumeSource{
+			ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{
+					Name: obj.Name,
 				},
+				Items: obj.Items,
 			},
+		}
+		if cr.IsRulesStorageSecret() {
+			vs = corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: obj.Name,
+					Items:      obj.Items,
+				},
+			}
+		}
+		volumes = append(volumes, corev1.Volume{
+			Name:         ruleObjectVolumeName(obj.Name),
+			VolumeSource: vs,
 		})
 	}
 
@@ -282,6 +473,27 @@ func vmAlertSpecGen(cr *vmv1beta1.VMAlert, ruleConfigMapNames []string, remoteSe
 
 	volumes, volumeMounts = cr.Spec.License.MaybeAddToVolumes(volumes, volumeMounts, vmv1beta1.SecretsDir)
 
+	isCompressed := ptr.Deref(cr.Spec.CompressRuleConfigMaps, false)
+	if isCompressed {
+		if !ptr.Deref(cr.Spec.UseVMConfigReloader, false) {
+			return nil, fmt.Errorf("compressRuleConfigMaps requires useVMConfigReloader to be enabled")
+		}
+		if ptr.Deref(cr.Spec.UseNamespaceRuleDirs, false) {
+			return nil, fmt.Errorf("compressRuleConfigMaps cannot be used with useNamespaceRuleDirs")
+		}
+		volumes = append(volumes, corev1.Volume{
+			Name: unpackedRulesVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				EmptyDir: &corev1.EmptyDirVolumeSource{},
+			},
+		})
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{
+			Name:      unpackedRulesVolumeName,
+			ReadOnly:  true,
+			MountPath: vmAlertUnpackedRulesDir,
+		})
+	}
+
 	if cr.Spec.NotifierConfigRef != nil {
 		volumes = append(volumes, corev1.Volume{
 			Name: "vmalert-notifier-config",
@@ -296,6 +508,21 @@ func vmAlertSpecGen(cr *vmv1beta1.VMAlert, ruleConfigMapNames []string, remoteSe
 			MountPath: notifierConfigMountPath,
 		})
 	}
+	if cr.IsNotifierDiscoveryEnabled() {
+		volumes = append(volumes, corev1.Volume{
+			Name: notifiersConfigVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: cr.NotifiersConfigName(),
+				},
+			},
+		})
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{
+			Name:      notifiersConfigVolumeName,
+			ReadOnly:  true,
+			MountPath: vmAlertNotifiersDir,
+		})
+	}
 	for _, s := range cr.Spec.Secrets {
 		volumes = append(volumes, corev1.Volume{
 			Name: k8stools.SanitizeVolumeName("secret-" + s),
@@ -330,11 +557,18 @@ func vmAlertSpecGen(cr *vmv1beta1.VMAlert, ruleConfigMapNames []string, remoteSe
 		})
 	}
 
-	for _, name := range ruleConfigMapNames {
-		volumeMounts = append(volumeMounts, corev1.VolumeMount{
-			Name:      name,
-			MountPath: path.Join(vmAlertConfigDir, name),
-		})
+	externalVolumes, externalMounts := buildExternalRuleSourcesVolumes(cr)
+	volumes = append(volumes, externalVolumes...)
+	volumeMounts = append(volumeMounts, externalMounts...)
+
+	// compressed rule files are mounted only into config-reloader
+	if !isCompressed {
+		for _, obj := range ruleObjects {
+			volumeMounts = append(volumeMounts, corev1.VolumeMount{
+				Name:      ruleObjectVolumeName(obj.Name),
+				MountPath: path.Join(vmAlertConfigDir, obj.Name),
+			})
+		}
 	}
 
 	var ports []corev1.ContainerPort
@@ -363,9 +597,11 @@ func vmAlertSpecGen(cr *vmv1beta1.VMAlert, ruleConfigMapNames []string, remoteSe
 		TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
 	}
 	vmalertContainer = build.Probe(vmalertContainer, cr)
+	// loading of large rule sets may take minutes
+	vmalertContainer = build.AddDefaultStartupProbe(vmalertContainer, cr, build.StartupProbeFailureThreshold(len(ruleObjects)))
 	vmalertContainers = append(vmalertContainers, vmalertContainer)
 
-	vmalertContainers = buildConfigReloaderContainer(vmalertContainers, cr, ruleConfigMapNames)
+	vmalertContainers = buildConfigReloaderContainer(vmalertContainers, cr, ruleObjects)
 
 	useStrictSecurity := ptr.Deref(cr.Spec.UseStrictSecurity, false)
 
@@ -374,6 +610,16 @@ func vmAlertSpecGen(cr *vmv1beta1.VMAlert, ruleConfigMapNames []string, remoteSe
 	if err != nil {
 		return nil, err
 	}
+	var ic []corev1.Container
+	if isCompressed && !cr.IsUnmanaged() {
+		// config-reloader container must be the last one
+		ic = append(ic, buildInitUnpackRulesContainer(vmalertContainers[len(vmalertContainers)-1]))
+		build.AddStrictSecuritySettingsToContainers(cr.Spec.SecurityContext, ic, useStrictSecurity)
+	}
+	ic, err = k8stools.MergePatchContainers(ic, cr.Spec.InitContainers)
+	if err != nil {
+		return nil, fmt.Errorf("cannot apply patch for initContainers: %w", err)
+	}
 
 	strategyType := appsv1.RollingUpdateDeploymentStrategyType
 	if cr.Spec.UpdateStrategy != nil {
@@ -388,6 +634,11 @@ func vmAlertSpecGen(cr *vmv1beta1.VMAlert, ruleConfigMapNames []string, remoteSe
 		}
 	}
 
+	podAnnotations := cr.PodAnnotations()
+	if ptr.Deref(cr.Spec.RolloutOnRuleChange, false) && !cr.IsUnmanaged() {
+		podAnnotations[vmv1beta1.VMAlertRulesChecksumAnnotation] = ruleObjectsChecksum(ruleObjects)
+	}
+
 	spec := &appsv1.DeploymentSpec{
 
 		Selector: &metav1.LabelSelector{
@@ -401,11 +652,11 @@ func vmAlertSpecGen(cr *vmv1beta1.VMAlert, ruleConfigMapNames []string, remoteSe
 		Template: corev1.PodTemplateSpec{
 			ObjectMeta: metav1.ObjectMeta{
 				Labels:      cr.PodLabels(),
-				Annotations: cr.PodAnnotations(),
+				Annotations: podAnnotations,
 			},
 			Spec: corev1.PodSpec{
 				ServiceAccountName: cr.GetServiceAccountName(),
-				InitContainers:     cr.Spec.InitContainers,
+				InitContainers:     ic,
 				Containers:         containers,
 				Volumes:            volumes,
 			},
@@ -461,7 +712,7 @@ func buildVMAlertAuthArgs(args []string, flagPrefix string, ha vmv1beta1.HTTPAut
 	return args
 }
 
-func buildVMAlertArgs(cr *vmv1beta1.VMAlert, ruleConfigMapNames []string, remoteSecrets map[string]*authSecret) []string {
+func buildVMAlertArgs(cr *vmv1beta1.VMAlert, ruleObjects []RuleObject, remoteSecrets map[string]*authSecret) []string {
 	pathPrefix := path.Join(tlsAssetsDir, cr.Namespace)
 	args := []string{
 		fmt.Sprintf("-datasource.url=%s", cr.Spec.Datasource.URL),
@@ -500,6 +751,11 @@ func buildVMAlertArgs(cr *vmv1beta1.VMAlert, ruleConfigMapNames []string, remote
 	for k, v := range cr.Spec.ExternalLabels {
 		args = append(args, fmt.Sprintf("-external.label=%s=%s", k, v))
 	}
+	if cr.Spec.HAMode {
+		// external labels are applied to alerts and remote write results
+		// vmalert expands placeholder from pod env variable
+		args = append(args, fmt.Sprintf("-external.label=%s=%%{%s}", vmv1beta1.VMAlertReplicaLabel, haModePodNameEnv))
+	}
 
 	if cr.Spec.RemoteRead != nil {
 		args = append(args, fmt.Sprintf("-remoteRead.url=%s", cr.Spec.RemoteRead.URL))
@@ -524,16 +780,26 @@ func buildVMAlertArgs(cr *vmv1beta1.VMAlert, ruleConfigMapNames []string, remote
 		args = append(args, fmt.Sprintf("-loggerFormat=%s", cr.Spec.LogFormat))
 	}
 
-	for _, cm := range ruleConfigMapNames {
-		args = append(args, fmt.Sprintf("-rule=%q", path.Join(vmAlertConfigDir, cm, "*.yaml")))
+	rulesDir := vmAlertConfigDir
+	if ptr.Deref(cr.Spec.CompressRuleConfigMaps, false) {
+		rulesDir = vmAlertUnpackedRulesDir
+	}
+	rulesGlob := "*.yaml"
+	if ptr.Deref(cr.Spec.UseNamespaceRuleDirs, false) {
+		rulesGlob = "*/*.yaml"
 	}
+	for _, obj := range ruleObjects {
+		args = append(args, fmt.Sprintf("-rule=%q", path.Join(rulesDir, obj.Name, rulesGlob)))
+	}
+
+	args = append(args, buildExternalRuleSourcesArgs(cr)...)
 
 	args = append(args, fmt.Sprintf("-httpListenAddr=:%s", cr.Spec.Port))
 
 	for _, rulePath := range cr.Spec.RulePath {
 		args = append(args, fmt.Sprintf("-rule=%q", rulePath))
 	}
-	if len(cr.Spec.ExtraEnvs) > 0 {
+	if len(cr.Spec.ExtraEnvs) > 0 || cr.Spec.HAMode {
 		args = append(args, "-envflag.enable=true")
 	}
 
@@ -621,10 +887,11 @@ func loadVMAlertRemoteSecrets(
 	return authSecretsBySource, nil
 }
 
-func createOrUpdateTLSAssetsForVMAlert(ctx context.Context, rclient client.Client, cr, prevCR *vmv1beta1.VMAlert) error {
+// createOrUpdateTLSAssetsForVMAlert stores tls assets of remote endpoints and returns its data
+func createOrUpdateTLSAssetsForVMAlert(ctx context.Context, rclient client.Client, cr, prevCR *vmv1beta1.VMAlert) (map[string][]byte, error) {
 	assets, err := loadTLSAssetsForVMAlert(ctx, rclient, cr)
 	if err != nil {
-		return fmt.Errorf("cannot load tls assets: %w", err)
+		return nil, fmt.Errorf("cannot load tls assets: %w", err)
 	}
 
 	tlsAssetsSecret := &corev1.Secret{
@@ -651,7 +918,10 @@ func createOrUpdateTLSAssetsForVMAlert(ctx context.Context, rclient client.Clien
 			Namespace:   prevCR.Namespace,
 		}
 	}
-	return reconcile.Secret(ctx, rclient, tlsAssetsSecret, prevSecretMeta)
+	if err := reconcile.Secret(ctx, rclient, tlsAssetsSecret, prevSecretMeta); err != nil {
+		return nil, err
+	}
+	return tlsAssetsSecret.Data, nil
 }
 
 func FetchTLSAssets(ctx context.Context, rclient client.Client, namespace string, tc *vmv1beta1.TLSConfig, assetPathDst map[string]string) error {
@@ -768,6 +1038,10 @@ func buildNotifiersArgs(cr *vmv1beta1.VMAlert, ntBasicAuth map[string]*authSecre
 		return finalArgs
 	}
 
+	if cr.IsNotifierDiscoveryEnabled() {
+		return append(finalArgs, fmt.Sprintf("-notifier.config=%s", path.Join(vmAlertNotifiersDir, notifiersConfigKey)))
+	}
+
 	if len(notifierTargets) == 0 && cr.Spec.NotifierConfigRef != nil {
 		return append(finalArgs, fmt.Sprintf("-notifier.config=%s/%s", notifierConfigMountPath, cr.Spec.NotifierConfigRef.Key))
 	}
@@ -919,8 +1193,9 @@ func buildCacheKey(ns, keyName string) string {
 	return fmt.Sprintf("%s/%s", ns, keyName)
 }
 
-func buildConfigReloaderContainer(dst []corev1.Container, cr *vmv1beta1.VMAlert, ruleConfigMapNames []string) []corev1.Container {
-	if cr.IsUnmanaged() {
+func buildConfigReloaderContainer(dst []corev1.Container, cr *vmv1beta1.VMAlert, ruleObjects []RuleObject) []corev1.Container {
+	// discovered notifiers config and external rules must be reloaded even without managed rules
+	if cr.IsUnmanaged() && !cr.IsNotifierDiscoveryEnabled() && len(cr.Spec.ExternalRuleSources) == 0 {
 		return dst
 	}
 	volumeWatchArg := "-volume-dir"
@@ -933,8 +1208,23 @@ func buildConfigReloaderContainer(dst []corev1.Container, cr *vmv1beta1.VMAlert,
 	confReloadArgs := []string{
 		fmt.Sprintf("%s=%s", reloadURLArg, vmv1beta1.BuildReloadPathWithPort(cr.Spec.ExtraArgs, cr.Spec.Port)),
 	}
-	for _, cm := range ruleConfigMapNames {
-		confReloadArgs = append(confReloadArgs, fmt.Sprintf("%s=%s", volumeWatchArg, path.Join(vmAlertConfigDir, cm)))
+	for _, obj := range ruleObjects {
+		confReloadArgs = append(confReloadArgs, fmt.Sprintf("%s=%s", volumeWatchArg, path.Join(vmAlertConfigDir, obj.Name)))
+	}
+	if cr.IsNotifierDiscoveryEnabled() {
+		confReloadArgs = append(confReloadArgs, fmt.Sprintf("%s=%s", volumeWatchArg, vmAlertNotifiersDir))
+	}
+	isCompressed := ptr.Deref(cr.Spec.CompressRuleConfigMaps, false)
+	if isCompressed {
+		confReloadArgs = append(confReloadArgs, fmt.Sprintf("--unpack-dir=%s", vmAlertUnpackedRulesDir))
+	}
+	// external rule files are not compressed and must not be unpacked by config-reloader
+	_, externalMounts := buildExternalRuleSourcesVolumes(cr)
+	if isCompressed {
+		externalMounts = nil
+	}
+	for _, m := range externalMounts {
+		confReloadArgs = append(confReloadArgs, fmt.Sprintf("%s=%s", volumeWatchArg, m.MountPath))
 	}
 	if len(cr.Spec.ConfigReloaderExtraArgs) > 0 {
 		for idx, arg := range confReloadArgs {
@@ -950,12 +1240,25 @@ func buildConfigReloaderContainer(dst []corev1.Container, cr *vmv1beta1.VMAlert,
 		sort.Strings(confReloadArgs)
 	}
 	var reloaderVolumes []corev1.VolumeMount
-	for _, name := range ruleConfigMapNames {
+	for _, obj := range ruleObjects {
+		reloaderVolumes = append(reloaderVolumes, corev1.VolumeMount{
+			Name:      ruleObjectVolumeName(obj.Name),
+			MountPath: path.Join(vmAlertConfigDir, obj.Name),
+		})
+	}
+	if cr.IsNotifierDiscoveryEnabled() {
+		reloaderVolumes = append(reloaderVolumes, corev1.VolumeMount{
+			Name:      notifiersConfigVolumeName,
+			MountPath: vmAlertNotifiersDir,
+		})
+	}
+	if isCompressed {
 		reloaderVolumes = append(reloaderVolumes, corev1.VolumeMount{
-			Name:      name,
-			MountPath: path.Join(vmAlertConfigDir, name),
+			Name:      unpackedRulesVolumeName,
+			MountPath: vmAlertUnpackedRulesDir,
 		})
 	}
+	reloaderVolumes = append(reloaderVolumes, externalMounts...)
 	sort.Slice(reloaderVolumes, func(i, j int) bool {
 		return reloaderVolumes[i].Name < reloaderVolumes[j].Name
 	})
@@ -976,6 +1279,22 @@ func buildConfigReloaderContainer(dst []corev1.Container, cr *vmv1beta1.VMAlert,
 	return dst
 }
 
+// buildInitUnpackRulesContainer builds init container, which unpacks compressed rule files
+// before vmalert start. It uses the same args and mounts as config-reloader container
+func buildInitUnpackRulesContainer(configReloader corev1.Container) corev1.Container {
+	args := make([]string, 0, len(configReloader.Args)+1)
+	args = append(args, configReloader.Args...)
+	args = append(args, "--only-init-config")
+	return corev1.Container{
+		Name:                     "config-init",
+		Image:                    configReloader.Image,
+		Args:                     args,
+		Resources:                configReloader.Resources,
+		TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
+		VolumeMounts:             configReloader.VolumeMounts,
+	}
+}
+
 func discoverNotifierIfNeeded(ctx context.Context, rclient client.Client, cr *vmv1beta1.VMAlert) error {
 	var additionalNotifiers []vmv1beta1.VMAlertNotifierSpec
 
@@ -1046,5 +1365,12 @@ func deletePrevStateResources(ctx context.Context, cr *vmv1beta1.VMAlert, rclien
 		}
 	}
 
+	if !cr.IsNotifierDiscoveryEnabled() && (cr.ParsedLastAppliedSpec.NotifierSelector != nil || cr.ParsedLastAppliedSpec.NotifierNamespaceSelector != nil) {
+		s := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: cr.NotifiersConfigName(), Namespace: cr.Namespace}}
+		if err := finalize.SafeDeleteWithFinalizer(ctx, rclient, s); err != nil {
+			return fmt.Errorf("cannot remove notifiers config: %w", err)
+		}
+	}
+
 	return nil
 }
@@ -13,9 +13,13 @@ import (
 	"github.com/stretchr/testify/assert"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
@@ -136,9 +140,9 @@ func Test_loadTLSAssetsForVMAlert(t *testing.T) {
 
 func TestCreateOrUpdateVMAlert(t *testing.T) {
 	type args struct {
-		cr      *vmv1beta1.VMAlert
-		c       *config.BaseOperatorConf
-		cmNames []string
+		cr          *vmv1beta1.VMAlert
+		c           *config.BaseOperatorConf
+		ruleObjects [][]RuleObject
 	}
 	tests := []struct {
 		name              string
@@ -561,7 +565,7 @@ func TestCreateOrUpdateVMAlert(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			fclient := k8stools.GetTestClientWithObjects(tt.predefinedObjects)
-			err := CreateOrUpdateVMAlert(context.TODO(), tt.args.cr, fclient, tt.args.cmNames)
+			err := CreateOrUpdateVMAlert(context.TODO(), tt.args.cr, fclient, tt.args.ruleObjects)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("CreateOrUpdateVMAlert() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -580,6 +584,139 @@ func TestCreateOrUpdateVMAlert(t *testing.T) {
 	}
 }
 
+func TestCreateOrUpdateVMAlertWithShards(t *testing.T) {
+	cr := &vmv1beta1.VMAlert{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "sharded-vmalert",
+			Namespace: "default",
+		},
+		Spec: vmv1beta1.VMAlertSpec{
+			Notifier: &vmv1beta1.VMAlertNotifierSpec{
+				URL: "http://some-alertmanager",
+			},
+			Datasource: vmv1beta1.VMAlertDatasourceSpec{
+				URL: "http://some-vm-datasource",
+			},
+			RuleShardingStrategy: vmv1beta1.VMAlertRuleShardingByGroup,
+			ShardCount:           ptr.To(2),
+			PodDisruptionBudget: &vmv1beta1.EmbeddedPodDisruptionBudgetSpec{
+				MaxUnavailable: ptr.To(intstr.FromInt(1)),
+			},
+		},
+	}
+	// deployment and PDB from non-sharded mode must be removed
+	fclient := k8stools.GetTestClientWithObjects([]runtime.Object{
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      cr.PrefixedName(),
+				Namespace: cr.Namespace,
+				Labels:    cr.SelectorLabels(),
+			},
+		},
+		&policyv1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      cr.PrefixedName(),
+				Namespace: cr.Namespace,
+				Labels:    cr.SelectorLabels(),
+			},
+		},
+	})
+	ruleObjects := [][]RuleObject{{{Name: "vm-sharded-vmalert-shard-0-rulefiles-0"}}, {{Name: "vm-sharded-vmalert-shard-1-rulefiles-0"}}}
+	if err := CreateOrUpdateVMAlert(context.TODO(), cr, fclient, ruleObjects); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var deploys appsv1.DeploymentList
+	if err := fclient.List(context.TODO(), &deploys); err != nil {
+		t.Fatalf("cannot list deployments: %s", err)
+	}
+	assert.Len(t, deploys.Items, 2)
+	for shardNum, dep := range deploys.Items {
+		assert.Equal(t, fmt.Sprintf("%s-shard-%d", cr.PrefixedName(), shardNum), dep.Name)
+		assert.Equal(t, fmt.Sprintf("%d", shardNum), dep.Spec.Selector.MatchLabels["shard-num"])
+		vmalertContainer := dep.Spec.Template.Spec.Containers[0]
+		assert.Contains(t, vmalertContainer.Args, fmt.Sprintf(`-rule="%s/%s/*.yaml"`, vmAlertConfigDir, ruleObjects[shardNum][0].Name))
+		assert.NotContains(t, vmalertContainer.Args, fmt.Sprintf(`-rule="%s/%s/*.yaml"`, vmAlertConfigDir, ruleObjects[(shardNum+1)%2][0].Name))
+	}
+	var pdbs policyv1.PodDisruptionBudgetList
+	if err := fclient.List(context.TODO(), &pdbs); err != nil {
+		t.Fatalf("cannot list pdbs: %s", err)
+	}
+	assert.Len(t, pdbs.Items, 2)
+	for shardNum, pdb := range pdbs.Items {
+		assert.Equal(t, fmt.Sprintf("%s-shard-%d", cr.PrefixedName(), shardNum), pdb.Name)
+		assert.Equal(t, fmt.Sprintf("%d", shardNum), pdb.Spec.Selector.MatchLabels["shard-num"])
+	}
+}
+
+func TestCreateOrUpdateVMAlertNamespaceRuleDirs(t *testing.T) {
+	cr := &vmv1beta1.VMAlert{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "ns-dirs",
+			Namespace: "default",
+		},
+		Spec: vmv1beta1.VMAlertSpec{
+			Notifier: &vmv1beta1.VMAlertNotifierSpec{
+				URL: "http://some-alertmanager",
+			},
+			Datasource: vmv1beta1.VMAlertDatasourceSpec{
+				URL: "http://some-vm-datasource",
+			},
+			UseNamespaceRuleDirs: ptr.To(true),
+		},
+	}
+	fclient := k8stools.GetTestClientWithObjects(nil)
+	items := []corev1.KeyToPath{{Key: "team-a_b-c.yaml", Path: "team-a/b-c.yaml"}}
+	ruleObjects := [][]RuleObject{{{Name: "vm-ns-dirs-rulefiles-0", Items: items}}}
+	if err := CreateOrUpdateVMAlert(context.TODO(), cr, fclient, ruleObjects); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var dep appsv1.Deployment
+	if err := fclient.Get(context.TODO(), types.NamespacedName{Namespace: cr.Namespace, Name: cr.PrefixedName()}, &dep); err != nil {
+		t.Fatalf("cannot get deployment: %s", err)
+	}
+	vmalertContainer := dep.Spec.Template.Spec.Containers[0]
+	assert.Contains(t, vmalertContainer.Args, fmt.Sprintf(`-rule="%s/vm-ns-dirs-rulefiles-0/*/*.yaml"`, vmAlertConfigDir))
+	var found bool
+	for _, v := range dep.Spec.Template.Spec.Volumes {
+		if v.Name == "vm-ns-dirs-rulefiles-0" {
+			found = true
+			assert.Equal(t, items, v.ConfigMap.Items)
+		}
+	}
+	assert.True(t, found, "rules volume must be present")
+}
+
+func TestCreateOrUpdateVMAlertStartupProbe(t *testing.T) {
+	cr := &vmv1beta1.VMAlert{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "startup",
+			Namespace: "default",
+		},
+		Spec: vmv1beta1.VMAlertSpec{
+			Notifier: &vmv1beta1.VMAlertNotifierSpec{
+				URL: "http://some-alertmanager",
+			},
+			Datasource: vmv1beta1.VMAlertDatasourceSpec{
+				URL: "http://some-vm-datasource",
+			},
+		},
+	}
+	fclient := k8stools.GetTestClientWithObjects(nil)
+	ruleObjects := [][]RuleObject{{{Name: "vm-startup-rulefiles-0"}, {Name: "vm-startup-rulefiles-1"}, {Name: "vm-startup-rulefiles-2"}}}
+	if err := CreateOrUpdateVMAlert(context.TODO(), cr, fclient, ruleObjects); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var dep appsv1.Deployment
+	if err := fclient.Get(context.TODO(), types.NamespacedName{Namespace: cr.Namespace, Name: cr.PrefixedName()}, &dep); err != nil {
+		t.Fatalf("cannot get deployment: %s", err)
+	}
+	sp := dep.Spec.Template.Spec.Containers[0].StartupProbe
+	if assert.NotNil(t, sp) {
+		assert.Equal(t, int32(28), sp.FailureThreshold)
+		assert.Equal(t, "/health", sp.HTTPGet.Path)
+	}
+}
+
 func TestBuildNotifiers(t *testing.T) {
 	type args struct {
 		cr          *vmv1beta1.VMAlert
@@ -630,6 +767,17 @@ func TestBuildNotifiers(t *testing.T) {
 			},
 			want: []string{"-notifier.config=" + notifierConfigMountPath + "/cfg.yaml"},
 		},
+		{
+			name: "with notifier selector",
+			args: args{
+				cr: &vmv1beta1.VMAlert{
+					Spec: vmv1beta1.VMAlertSpec{
+						NotifierSelector: &metav1.LabelSelector{},
+					},
+				},
+			},
+			want: []string{"-notifier.config=" + vmAlertNotifiersDir + "/notifiers.yaml"},
+		},
 		{
 			name: "with headers and oauth2",
 			args: args{
@@ -722,9 +870,9 @@ func TestCreateOrUpdateVMAlertService(t *testing.T) {
 
 func Test_buildVMAlertArgs(t *testing.T) {
 	type args struct {
-		cr                 *vmv1beta1.VMAlert
-		ruleConfigMapNames []string
-		remoteSecrets      map[string]*authSecret
+		cr            *vmv1beta1.VMAlert
+		ruleObjects   []RuleObject
+		remoteSecrets map[string]*authSecret
 	}
 	tests := []struct {
 		name string
@@ -741,8 +889,8 @@ func Test_buildVMAlertArgs(t *testing.T) {
 						},
 					},
 				},
-				ruleConfigMapNames: []string{"first-rule-cm.yaml"},
-				remoteSecrets:      map[string]*authSecret{},
+				ruleObjects:   []RuleObject{{Name: "first-rule-cm.yaml"}},
+				remoteSecrets: map[string]*authSecret{},
 			},
 			want: []string{"-datasource.url=http://vmsingle-url", "-httpListenAddr=:", "-notifier.url=", "-rule=\"/etc/vmalert/config/first-rule-cm.yaml/*.yaml\""},
 		},
@@ -764,18 +912,202 @@ func Test_buildVMAlertArgs(t *testing.T) {
 						},
 					},
 				},
-				ruleConfigMapNames: []string{"first-rule-cm.yaml"},
-				remoteSecrets:      map[string]*authSecret{},
+				ruleObjects:   []RuleObject{{Name: "first-rule-cm.yaml"}},
+				remoteSecrets: map[string]*authSecret{},
 			},
 			want: []string{"--datasource.headers=x-org-id:one^^x-org-tenant:5", "-datasource.tlsCAFile=/path/to/sa", "-datasource.tlsInsecureSkipVerify=true", "-datasource.tlsKeyFile=/path/to/key", "-datasource.url=http://vmsingle-url", "-httpListenAddr=:", "-notifier.url=", "-rule=\"/etc/vmalert/config/first-rule-cm.yaml/*.yaml\""},
 		},
+		{
+			name: "with compressed rules",
+			args: args{
+				cr: &vmv1beta1.VMAlert{
+					Spec: vmv1beta1.VMAlertSpec{
+						Datasource: vmv1beta1.VMAlertDatasourceSpec{
+							URL: "http://vmsingle-url",
+						},
+						CompressRuleConfigMaps: ptr.To(true),
+					},
+				},
+				ruleObjects:   []RuleObject{{Name: "first-rule-cm"}},
+				remoteSecrets: map[string]*authSecret{},
+			},
+			want: []string{"-datasource.url=http://vmsingle-url", "-httpListenAddr=:", "-notifier.url=", "-rule=\"/etc/vmalert/unpacked/first-rule-cm/*.yaml\""},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := buildVMAlertArgs(tt.args.cr, tt.args.ruleConfigMapNames, tt.args.remoteSecrets); !reflect.DeepEqual(got, tt.want) {
+			if got := buildVMAlertArgs(tt.args.cr, tt.args.ruleObjects, tt.args.remoteSecrets); !reflect.DeepEqual(got, tt.want) {
 				assert.Equal(t, tt.want, got)
 				t.Errorf("buildVMAlertArgs() got = \n%v\n, want \n%v\n", got, tt.want)
 			}
 		})
 	}
 }
+
+func TestCreateOrUpdateVMAlertRemoteSecretsChecksum(t *testing.T) {
+	cr := &vmv1beta1.VMAlert{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "remote-auth",
+			Namespace: "default",
+		},
+		Spec: vmv1beta1.VMAlertSpec{
+			Notifier: &vmv1beta1.VMAlertNotifierSpec{
+				URL: "http://some-alertmanager",
+			},
+			Datasource: vmv1beta1.VMAlertDatasourceSpec{
+				URL: "http://some-vm-datasource",
+			},
+			RemoteRead: &vmv1beta1.VMAlertRemoteReadSpec{
+				URL: "https://remote-read",
+				HTTPAuth: vmv1beta1.HTTPAuth{
+					OAuth2: &vmv1beta1.OAuth2{
+						ClientID: vmv1beta1.SecretOrConfigMap{
+							Secret: &corev1.SecretKeySelector{
+								LocalObjectReference: corev1.LocalObjectReference{Name: "oauth2"},
+								Key:                  "client-id",
+							},
+						},
+						ClientSecret: &corev1.SecretKeySelector{
+							LocalObjectReference: corev1.LocalObjectReference{Name: "oauth2"},
+							Key:                  "client-secret",
+						},
+						TokenURL: "https://oauth2/token",
+					},
+					TLSConfig: &vmv1beta1.TLSConfig{
+						CA: vmv1beta1.SecretOrConfigMap{
+							Secret: &corev1.SecretKeySelector{
+								LocalObjectReference: corev1.LocalObjectReference{Name: "remote-tls"},
+								Key:                  "ca",
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	oauth2Secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "oauth2", Namespace: "default"},
+		Data: map[string][]byte{
+			"client-id":     []byte("vmalert"),
+			"client-secret": []byte("secret-1"),
+		},
+	}
+	fclient := k8stools.GetTestClientWithObjects([]runtime.Object{
+		oauth2Secret,
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "remote-tls", Namespace: "default"},
+			Data:       map[string][]byte{"ca": []byte("ca-data")},
+		},
+	})
+	ctx := context.TODO()
+	getChecksum := func() string {
+		t.Helper()
+		if err := CreateOrUpdateVMAlert(ctx, cr, fclient, nil); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		var dep appsv1.Deployment
+		if err := fclient.Get(ctx, types.NamespacedName{Namespace: cr.Namespace, Name: cr.PrefixedName()}, &dep); err != nil {
+			t.Fatalf("cannot get deployment: %s", err)
+		}
+		vmalertContainer := dep.Spec.Template.Spec.Containers[0]
+		assert.Contains(t, vmalertContainer.Args, "-remoteRead.oauth2.clientSecretFile=/etc/vmalert/remote_secrets/REMOTEREAD_OAUTH2SECRETKEY")
+		assert.Contains(t, vmalertContainer.Args, "-remoteRead.tlsCAFile=/etc/vmalert-tls/certs/default_remote-tls_ca")
+		return dep.Spec.Template.Annotations[vmv1beta1.VMAlertRemoteSecretsChecksumAnnotation]
+	}
+	checksum := getChecksum()
+	assert.NotEmpty(t, checksum)
+	assert.Equal(t, checksum, getChecksum())
+
+	// secret rotation triggers rollout
+	oauth2Secret.Data["client-secret"] = []byte("secret-2")
+	if err := fclient.Update(ctx, oauth2Secret); err != nil {
+		t.Fatalf("cannot update secret: %s", err)
+	}
+	assert.NotEqual(t, checksum, getChecksum())
+
+	// checksum is removed with auth secret refs
+	cr.ParsedLastAppliedSpec = cr.Spec.DeepCopy()
+	cr.Spec.RemoteRead = nil
+	if err := CreateOrUpdateVMAlert(ctx, cr, fclient, nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var dep appsv1.Deployment
+	if err := fclient.Get(ctx, types.NamespacedName{Namespace: cr.Namespace, Name: cr.PrefixedName()}, &dep); err != nil {
+		t.Fatalf("cannot get deployment: %s", err)
+	}
+	assert.NotContains(t, dep.Spec.Template.Annotations, vmv1beta1.VMAlertRemoteSecretsChecksumAnnotation)
+}
+
+func TestCreateOrUpdateVMAlertStatefulMode(t *testing.T) {
+	cr := &vmv1beta1.VMAlert{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "stateful",
+			Namespace: "default",
+		},
+		Spec: vmv1beta1.VMAlertSpec{
+			Notifier: &vmv1beta1.VMAlertNotifierSpec{
+				URL: "http://some-alertmanager",
+			},
+			Datasource: vmv1beta1.VMAlertDatasourceSpec{
+				URL: "http://some-vm-datasource",
+			},
+		},
+	}
+	fclient := k8stools.GetTestClientWithObjects(nil)
+	ctx := context.TODO()
+	nsn := types.NamespacedName{Namespace: cr.Namespace, Name: cr.PrefixedName()}
+	reconcileWithMode := func(statefulMode bool) {
+		t.Helper()
+		prevSpec := cr.Spec.DeepCopy()
+		cr.Spec.StatefulMode = statefulMode
+		if statefulMode {
+			cr.Spec.ClaimTemplates = []corev1.PersistentVolumeClaim{{
+				ObjectMeta: metav1.ObjectMeta{Name: "data"},
+			}}
+		} else {
+			cr.Spec.ClaimTemplates = nil
+		}
+		cr.ParsedLastAppliedSpec = prevSpec
+		if err := CreateOrUpdateVMAlert(ctx, cr, fclient, nil); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	assertMode := func(statefulMode bool) {
+		t.Helper()
+		var dep appsv1.Deployment
+		depErr := fclient.Get(ctx, nsn, &dep)
+		var sts appsv1.StatefulSet
+		stsErr := fclient.Get(ctx, nsn, &sts)
+		var svc corev1.Service
+		if err := fclient.Get(ctx, nsn, &svc); err != nil {
+			t.Fatalf("cannot get service: %s", err)
+		}
+		if statefulMode {
+			assert.True(t, k8serrors.IsNotFound(depErr), "deployment must be removed, got err: %v", depErr)
+			if assert.NoError(t, stsErr) {
+				assert.Equal(t, cr.PrefixedName(), sts.Spec.ServiceName)
+				if assert.Len(t, sts.Spec.VolumeClaimTemplates, 1) {
+					assert.Equal(t, "data", sts.Spec.VolumeClaimTemplates[0].Name)
+				}
+			}
+			assert.Equal(t, corev1.ClusterIPNone, svc.Spec.ClusterIP)
+			return
+		}
+		assert.NoError(t, depErr)
+		assert.True(t, k8serrors.IsNotFound(stsErr), "statefulset must be removed, got err: %v", stsErr)
+		assert.NotEqual(t, corev1.ClusterIPNone, svc.Spec.ClusterIP)
+	}
+
+	if err := CreateOrUpdateVMAlert(ctx, cr, fclient, nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assertMode(false)
+
+	// deployment to statefulSet
+	reconcileWithMode(true)
+	assertMode(true)
+
+	// statefulSet to deployment
+	reconcileWithMode(false)
+	assertMode(false)
+}
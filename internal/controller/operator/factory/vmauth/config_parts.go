@@ -0,0 +1,261 @@
+package vmauth
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/config"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/build"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/finalize"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/logger"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/reconcile"
+)
+
+const (
+	vmAuthConfigPartsDir     = "/opt/vmauth-config-parts"
+	configPartsVolumeName    = "config-parts"
+	configPartFilenameFormat = "part-%d.yaml.gz"
+	configPartVersionFormat  = "part-%d.version"
+	// configPartsVersionKey holds version of the split configuration at the main config Secret and at each part Secret.
+	// Parts are merged only if all versions are equal, since kubelet updates mounted Secrets independently
+	configPartsVersionKey = "parts.version"
+	// configPartsMergeInterval defines how often config-merge container checks config parts for changes
+	configPartsMergeInterval = "10s"
+)
+
+// maxConfigSecretSize defines size limit for compressed config at the main Secret
+// and for uncompressed users config at each config part
+var maxConfigSecretSize = vmv1beta1.MaxConfigMapDataSize
+
+// splitVMAuthConfig splits users of the given config into gzipped parts.
+// Users are packed sequentially in the order of the given config, so the split is deterministic.
+// Each part contains uncompressed users config limited by maxConfigSecretSize.
+// The first part holds the rest of configuration and the users section header,
+// next parts hold plain yaml lists of users.
+// Concatenation of gzip members is a valid gzip stream, so parts could be merged
+// with cat and gunzip into the origin configuration.
+func splitVMAuthConfig(data []byte) ([][]byte, error) {
+	var cfg yaml.MapSlice
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("cannot parse vmauth config: %w", err)
+	}
+	var users []any
+	mainCfg := make(yaml.MapSlice, 0, len(cfg))
+	for _, item := range cfg {
+		if item.Key == "users" {
+			users, _ = item.Value.([]any)
+			continue
+		}
+		mainCfg = append(mainCfg, item)
+	}
+	var mainPart []byte
+	if len(mainCfg) > 0 {
+		var err error
+		if mainPart, err = yaml.Marshal(mainCfg); err != nil {
+			return nil, fmt.Errorf("cannot marshal vmauth config: %w", err)
+		}
+	}
+	if len(users) > 0 {
+		mainPart = append(mainPart, "users:\n"...)
+	}
+	plainParts := [][]byte{mainPart}
+	for idx, u := range users {
+		ud, err := yaml.Marshal([]any{u})
+		if err != nil {
+			return nil, fmt.Errorf("cannot marshal user at idx=%d: %w", idx, err)
+		}
+		// user could be always placed into empty part, even if it exceeds size limit
+		last := plainParts[len(plainParts)-1]
+		if len(last) > 0 && len(last)+len(ud) > maxConfigSecretSize {
+			plainParts = append(plainParts, nil)
+		}
+		plainParts[len(plainParts)-1] = append(plainParts[len(plainParts)-1], ud...)
+	}
+	parts := make([][]byte, 0, len(plainParts))
+	for idx, p := range plainParts {
+		var buf bytes.Buffer
+		if err := gzipConfig(&buf, p); err != nil {
+			return nil, fmt.Errorf("cannot gzip config part=%d: %w", idx, err)
+		}
+		parts = append(parts, buf.Bytes())
+	}
+	return parts, nil
+}
+
+func configPartsChecksum(parts [][]byte) string {
+	h := sha256.New()
+	for _, p := range parts {
+		h.Write(p)
+	}
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+// configPartsVersion returns version of the given config parts.
+// It contains number of additional parts, so pods with outdated parts volume never merge config
+func configPartsVersion(parts [][]byte) string {
+	return fmt.Sprintf("%d-%s", len(parts)-1, configPartsChecksum(parts))
+}
+
+func buildConfigPartMeta(cr *vmv1beta1.VMAuth, idx int) metav1.ObjectMeta {
+	meta := buildConfigSecretMeta(cr)
+	meta.Name = cr.ConfigSecretPartName(idx)
+	return meta
+}
+
+// reconcileConfigSecretParts creates or updates Secrets with the given config parts
+// the first part is stored at the main config Secret and isn't reconciled by this function.
+// Parts must be updated before the main config Secret, which switches vmauth pods to the new parts version.
+// Stale parts must be removed with removeStaleConfigSecretParts after vmauth pods update
+func reconcileConfigSecretParts(ctx context.Context, rclient client.Client, cr, prevCR *vmv1beta1.VMAuth, parts [][]byte) error {
+	version := configPartsVersion(parts)
+	for idx := 1; idx < len(parts); idx++ {
+		s := &corev1.Secret{
+			ObjectMeta: buildConfigPartMeta(cr, idx),
+			Data: map[string][]byte{
+				vmAuthConfigNameGz:    parts[idx],
+				configPartsVersionKey: []byte(version),
+			},
+		}
+		var prevMeta *metav1.ObjectMeta
+		if prevCR != nil {
+			prevMeta = ptr.To(buildConfigPartMeta(prevCR, idx))
+		}
+		if err := reconcile.Secret(ctx, rclient, s, prevMeta); err != nil {
+			return fmt.Errorf("cannot reconcile vmauth config secret part=%d: %w", idx, err)
+		}
+	}
+	return nil
+}
+
+// removeStaleConfigSecretParts removes config part Secrets, which are not referenced by status.configSecretParts
+// it must be called after vmauth pods update, since Secrets could be still mounted to the pods
+func removeStaleConfigSecretParts(ctx context.Context, rclient client.Client, cr *vmv1beta1.VMAuth) error {
+	var secretList corev1.SecretList
+	if err := rclient.List(ctx, &secretList, client.InNamespace(cr.Namespace), client.MatchingLabels(cr.SelectorLabels())); err != nil {
+		return fmt.Errorf("cannot list vmauth config secret parts: %w", err)
+	}
+	prefix := cr.ConfigSecretName() + "-"
+	for i := range secretList.Items {
+		s := &secretList.Items[i]
+		idx, err := strconv.Atoi(strings.TrimPrefix(s.Name, prefix))
+		if !strings.HasPrefix(s.Name, prefix) || err != nil || idx <= int(cr.Status.ConfigSecretParts) {
+			continue
+		}
+		logger.WithContext(ctx).Info(fmt.Sprintf("removing stale vmauth config secret part=%s", s.Name))
+		if err := finalize.RemoveFinalizer(ctx, rclient, s); err != nil {
+			return err
+		}
+		if err := finalize.SafeDelete(ctx, rclient, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// buildConfigPartsVolume returns projected volume with all additional config parts
+func buildConfigPartsVolume(cr *vmv1beta1.VMAuth) corev1.Volume {
+	sources := make([]corev1.VolumeProjection, 0, cr.Status.ConfigSecretParts)
+	for idx := 1; idx <= int(cr.Status.ConfigSecretParts); idx++ {
+		sources = append(sources, corev1.VolumeProjection{
+			Secret: &corev1.SecretProjection{
+				LocalObjectReference: corev1.LocalObjectReference{Name: cr.ConfigSecretPartName(idx)},
+				Items: []corev1.KeyToPath{
+					{Key: vmAuthConfigNameGz, Path: fmt.Sprintf(configPartFilenameFormat, idx)},
+					{Key: configPartsVersionKey, Path: fmt.Sprintf(configPartVersionFormat, idx)},
+				},
+			},
+		})
+	}
+	return corev1.Volume{
+		Name: configPartsVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			Projected: &corev1.ProjectedVolumeSource{Sources: sources},
+		},
+	}
+}
+
+// buildMergeConfigPartsScript returns shell script, which merges config parts into vmauth config.
+// Parts are merged only if versions of the main config and all mounted parts are equal.
+// Main config without version is used as is, it's the case of switching pods to the split configuration.
+//
+// Init container waits for consistent parts, while sidecar container keeps the previous config
+// on versions mismatch and checks parts for changes periodically. vmauth reloads config with -configCheckInterval
+func buildMergeConfigPartsScript(cr *vmv1beta1.VMAuth, isSidecar bool) string {
+	mainFile := path.Join(vmAuthConfigMountGz, vmAuthConfigNameGz)
+	files := []string{mainFile}
+	var versionChecks []string
+	for idx := 1; idx <= int(cr.Status.ConfigSecretParts); idx++ {
+		files = append(files, path.Join(vmAuthConfigPartsDir, fmt.Sprintf(configPartFilenameFormat, idx)))
+		versionChecks = append(versionChecks,
+			fmt.Sprintf(`[ "$(cat %s)" = "$v" ] || return 1`, path.Join(vmAuthConfigPartsDir, fmt.Sprintf(configPartVersionFormat, idx))))
+	}
+	out := path.Join(vmAuthConfigFolder, vmAuthConfigName)
+	var script strings.Builder
+	fmt.Fprintf(&script, `merge() {
+v=$(cat %s 2>/dev/null) || return 2
+case "$v" in %d-*) ;; *) return 1 ;; esac
+%s
+cat %s | gunzip > %s.tmp || { echo "cannot merge config parts" >&2; return 3; }
+cmp -s %s.tmp %s || mv %s.tmp %s
+}
+`, path.Join(vmAuthConfigMountGz, configPartsVersionKey), cr.Status.ConfigSecretParts, strings.Join(versionChecks, "\n"), strings.Join(files, " "), out, out, out, out, out)
+	if isSidecar {
+		fmt.Fprintf(&script, "while true; do merge; sleep %s; done", configPartsMergeInterval)
+	} else {
+		fmt.Fprintf(&script, `merge; rc=$?
+while [ $rc -eq 1 ]; do sleep 1; merge; rc=$?; done
+case $rc in 0) ;; 2) gunzip -c %s > %s ;; *) exit 1 ;; esac`, mainFile, out)
+	}
+	return script.String()
+}
+
+// buildMergeConfigPartsContainer returns container, which merges config parts into vmauth config.
+// It's used as init container and as sidecar, which applies config changes without pods restart.
+// It uses operator default vmauth image instead of the image from spec, since it requires shell
+// and config-reloader or custom vmauth images may not contain it
+func buildMergeConfigPartsContainer(cr *vmv1beta1.VMAuth, isSidecar bool) corev1.Container {
+	name := "config-init"
+	if isSidecar {
+		name = "config-merge"
+	}
+	return corev1.Container{
+		Name:            name,
+		Image:           mergeConfigPartsImage(),
+		ImagePullPolicy: cr.Spec.Image.PullPolicy,
+		Command:         []string{"/bin/sh"},
+		Args:            []string{"-c", buildMergeConfigPartsScript(cr, isSidecar)},
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      vmAuthVolumeName,
+				MountPath: vmAuthConfigMountGz,
+			},
+			{
+				Name:      configPartsVolumeName,
+				MountPath: vmAuthConfigPartsDir,
+			},
+			{
+				Name:      "config-out",
+				MountPath: vmAuthConfigFolder,
+			},
+		},
+		Resources: cr.Spec.ConfigReloaderResources,
+	}
+}
+
+// mergeConfigPartsImage returns operator default vmauth image
+func mergeConfigPartsImage() string {
+	c := config.MustGetBaseConfig()
+	return build.FormatContainerImage(fmt.Sprintf("%s:%s", c.VMAuthDefault.Image, c.VMAuthDefault.Version))
+}
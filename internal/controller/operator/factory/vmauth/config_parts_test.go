@@ -0,0 +1,299 @@
+package vmauth
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+
+	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/k8stools"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/reconcile"
+)
+
+func gunzipParts(t *testing.T, parts ...[]byte) string {
+	t.Helper()
+	gr, err := gzip.NewReader(bytes.NewReader(bytes.Join(parts, nil)))
+	if err != nil {
+		t.Fatalf("cannot read gzipped config: %s", err)
+	}
+	data, err := io.ReadAll(gr)
+	if err != nil {
+		t.Fatalf("cannot read config: %s", err)
+	}
+	return string(data)
+}
+
+func Test_splitVMAuthConfig(t *testing.T) {
+	defer func(v int) { maxConfigSecretSize = v }(maxConfigSecretSize)
+	maxConfigSecretSize = 140
+
+	data := []byte(`users:
+- url_prefix:
+  - http://vmselect
+  username: user-a
+  password: pass-a
+- url_prefix:
+  - http://vmselect
+  username: user-b
+  password: pass-b
+unauthorized_user:
+  url_prefix:
+  - http://vmselect
+`)
+	parts, err := splitVMAuthConfig(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.Len(t, parts, 2)
+	assert.Equal(t, `unauthorized_user:
+  url_prefix:
+  - http://vmselect
+users:
+- url_prefix:
+  - http://vmselect
+  username: user-a
+  password: pass-a
+`, gunzipParts(t, parts[0]))
+	assert.Equal(t, `- url_prefix:
+  - http://vmselect
+  username: user-b
+  password: pass-b
+`, gunzipParts(t, parts[1]))
+
+	// merged parts contain the same configuration
+	var want, got map[string]any
+	if err := yaml.Unmarshal(data, &want); err != nil {
+		t.Fatalf("cannot parse config: %s", err)
+	}
+	if err := yaml.Unmarshal([]byte(gunzipParts(t, parts...)), &got); err != nil {
+		t.Fatalf("cannot parse merged config: %s", err)
+	}
+	assert.Equal(t, want, got)
+}
+
+func TestCreateOrUpdateVMAuthConfigParts(t *testing.T) {
+	defer func(v int) { maxConfigSecretSize = v }(maxConfigSecretSize)
+
+	cr := &vmv1beta1.VMAuth{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+		Spec: vmv1beta1.VMAuthSpec{
+			SelectAllByDefault: true,
+		},
+	}
+	objects := []runtime.Object{cr.DeepCopy()}
+	for i := range 20 {
+		objects = append(objects, &vmv1beta1.VMUser{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("user-%d", i), Namespace: "default"},
+			Spec: vmv1beta1.VMUserSpec{
+				Password: ptr.To(fmt.Sprintf("password-%d", i)),
+				TargetRefs: []vmv1beta1.TargetRef{{
+					Static: &vmv1beta1.StaticRef{URL: fmt.Sprintf("http://vmselect-%d:8481", i)},
+				}},
+			},
+		})
+	}
+	fclient := k8stools.GetTestClientWithObjects(objects)
+	ctx := context.TODO()
+
+	getSecret := func(name string) *corev1.Secret {
+		t.Helper()
+		var s corev1.Secret
+		if err := fclient.Get(ctx, types.NamespacedName{Namespace: cr.Namespace, Name: name}, &s); err != nil {
+			t.Fatalf("cannot get vmauth config secret=%s: %s", name, err)
+		}
+		return &s
+	}
+	getSecretConfig := func(name string) []byte {
+		t.Helper()
+		return getSecret(name).Data[vmAuthConfigNameGz]
+	}
+
+	// config fits into the main secret
+	if err := CreateOrUpdateVMAuthConfig(ctx, fclient, cr, nil, nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.Equal(t, int32(0), cr.Status.ConfigSecretParts)
+	assert.Contains(t, gunzipParts(t, getSecretConfig(cr.ConfigSecretName())), "user-19")
+
+	// config exceeds size limit
+	// main config must not be switched to the first part until pods mount config parts
+	maxConfigSecretSize = 100
+	if err := CreateOrUpdateVMAuthConfig(ctx, fclient, cr, nil, nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if cr.Status.ConfigSecretParts < 2 {
+		t.Fatalf("expected config to be split, got parts: %d", cr.Status.ConfigSecretParts)
+	}
+	assert.NotEmpty(t, cr.Status.ConfigSecretPartsChecksum)
+	assert.Contains(t, gunzipParts(t, getSecretConfig(cr.ConfigSecretName())), "user-19")
+	var persisted vmv1beta1.VMAuth
+	if err := fclient.Get(ctx, types.NamespacedName{Namespace: cr.Namespace, Name: cr.Name}, &persisted); err != nil {
+		t.Fatalf("cannot get vmauth: %s", err)
+	}
+	assert.Equal(t, cr.Status.ConfigSecretParts, persisted.Status.ConfigSecretParts)
+
+	// VMAuth reconcile returns pending config, which is applied after pods update
+	pending, err := createOrUpdateVMAuthConfig(ctx, fclient, cr, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if pending == nil {
+		t.Fatalf("expected pending config secret")
+	}
+	if err := reconcile.Secret(ctx, fclient, pending, nil); err != nil {
+		t.Fatalf("cannot apply pending config secret: %s", err)
+	}
+	version := getSecret(cr.ConfigSecretName()).Data[configPartsVersionKey]
+	assert.Equal(t, fmt.Sprintf("%d-%s", cr.Status.ConfigSecretParts, cr.Status.ConfigSecretPartsChecksum), string(version))
+	parts := [][]byte{getSecretConfig(cr.ConfigSecretName())}
+	for idx := 1; idx <= int(cr.Status.ConfigSecretParts); idx++ {
+		assert.Equal(t, version, getSecret(cr.ConfigSecretPartName(idx)).Data[configPartsVersionKey])
+		parts = append(parts, getSecretConfig(cr.ConfigSecretPartName(idx)))
+	}
+	merged := gunzipParts(t, parts...)
+	for i := range 20 {
+		assert.Contains(t, merged, fmt.Sprintf("password: password-%d\n", i))
+	}
+
+	// split config is switched at once, pods merge parts of the same version only
+	pending, err = createOrUpdateVMAuthConfig(ctx, fclient, cr, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.Nil(t, pending)
+
+	// pods merge config parts with init and sidecar containers and aren't restarted on config change
+	dep, err := newDeployForVMAuth(cr)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	podSpec := dep.Spec.Template.Spec
+	assert.Len(t, dep.Spec.Template.Annotations, 0)
+	if assert.Len(t, podSpec.Containers, 2) {
+		assert.Contains(t, podSpec.Containers[0].Args, "-configCheckInterval=10s")
+		assert.Equal(t, "config-merge", podSpec.Containers[1].Name)
+		assert.Equal(t, mergeConfigPartsImage(), podSpec.Containers[1].Image)
+	}
+	if assert.Len(t, podSpec.InitContainers, 1) {
+		assert.Equal(t, mergeConfigPartsImage(), podSpec.InitContainers[0].Image)
+	}
+
+	// stale parts are removed after switch back to the main secret
+	maxConfigSecretSize = vmv1beta1.MaxConfigMapDataSize
+	if err := CreateOrUpdateVMAuthConfig(ctx, fclient, cr, nil, nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.Equal(t, int32(0), cr.Status.ConfigSecretParts)
+	if err := removeStaleConfigSecretParts(ctx, fclient, cr); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var secrets corev1.SecretList
+	if err := fclient.List(ctx, &secrets); err != nil {
+		t.Fatalf("cannot list secrets: %s", err)
+	}
+	for _, s := range secrets.Items {
+		assert.NotContains(t, s.Name, cr.ConfigSecretName()+"-")
+	}
+}
+
+func Test_buildMergeConfigPartsScript(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("shell is not available")
+	}
+	cr := &vmv1beta1.VMAuth{
+		Status: vmv1beta1.VMAuthStatus{ConfigSecretParts: 2},
+	}
+	gz := func(data string) []byte {
+		var buf bytes.Buffer
+		if err := gzipConfig(&buf, []byte(data)); err != nil {
+			t.Fatalf("cannot gzip: %s", err)
+		}
+		return buf.Bytes()
+	}
+	f := func(mainVersion string, partVersions []string, corruptPart bool, isSidecar bool, prevConfig, wantConfig string) {
+		t.Helper()
+		dir := t.TempDir()
+		write := func(name string, data []byte) {
+			t.Helper()
+			name = filepath.Join(dir, name)
+			if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+				t.Fatalf("cannot create dir: %s", err)
+			}
+			if err := os.WriteFile(name, data, 0644); err != nil {
+				t.Fatalf("cannot write file: %s", err)
+			}
+		}
+		write("opt/vmauth-config-gz/config.yaml.gz", gz("users:\n"))
+		if mainVersion != "" {
+			write("opt/vmauth-config-gz/parts.version", []byte(mainVersion))
+		}
+		for idx, v := range partVersions {
+			part := gz(fmt.Sprintf("- username: user-%d\n", idx+1))
+			if corruptPart {
+				part = []byte("corrupted")
+			}
+			write(fmt.Sprintf("opt/vmauth-config-parts/part-%d.yaml.gz", idx+1), part)
+			write(fmt.Sprintf("opt/vmauth-config-parts/part-%d.version", idx+1), []byte(v))
+		}
+		write("opt/vmauth/config.yaml", []byte(prevConfig))
+		script := strings.ReplaceAll(buildMergeConfigPartsScript(cr, isSidecar), "/opt/", dir+"/opt/")
+		if isSidecar {
+			// run merge function only
+			script = strings.Split(script, "while true")[0] + "merge"
+		}
+		out, err := exec.Command("sh", "-c", script).CombinedOutput()
+		if corruptPart {
+			if err == nil {
+				t.Fatalf("expected script to fail on corrupted part")
+			}
+			assert.Contains(t, string(out), "cannot merge config parts")
+		} else if err != nil && len(out) > 0 {
+			t.Fatalf("unexpected script output: %s", out)
+		}
+		got, err := os.ReadFile(filepath.Join(dir, "opt/vmauth/config.yaml"))
+		if err != nil {
+			t.Fatalf("cannot read merged config: %s", err)
+		}
+		assert.Equal(t, wantConfig, string(got))
+	}
+	merged := "users:\n- username: user-1\n- username: user-2\n"
+
+	// consistent parts
+	f("2-abc", []string{"2-abc", "2-abc"}, false, true, "prev", merged)
+
+	// part isn't synced yet
+	f("2-abc", []string{"2-abc", "2-old"}, false, true, "prev", "prev")
+
+	// main config refers more parts than mounted
+	f("3-abc", []string{"3-abc", "3-abc"}, false, true, "prev", "prev")
+
+	// main config isn't switched to config parts yet
+	f("", []string{"2-abc", "2-abc"}, false, true, "prev", "prev")
+
+	// corrupted part keeps previous config
+	f("2-abc", []string{"2-abc", "2-abc"}, true, true, "prev", "prev")
+
+	// init container merges consistent parts
+	f("2-abc", []string{"2-abc", "2-abc"}, false, false, "", merged)
+
+	// init container falls back to the main config
+	f("", []string{"2-abc", "2-abc"}, false, false, "", "users:\n")
+
+	// init container fails on corrupted part instead of waiting forever
+	f("2-abc", []string{"2-abc", "2-abc"}, true, false, "", "")
+}
@@ -0,0 +1,169 @@
+package vmauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/logger"
+)
+
+const (
+	// vmUserPreviousPasswordKey holds rotated password at vmuser secret during overlap window
+	vmUserPreviousPasswordKey = "previousPassword"
+	// vmUserPasswordRotatedAtAnnotation persists time of the last password rotation at vmuser secret
+	vmUserPasswordRotatedAtAnnotation = "operator.victoriametrics.com/password-rotated-at"
+	// vmUserPasswordRotateNowAnnotation persists rotateNow value, which triggered the last rotation
+	vmUserPasswordRotateNowAnnotation = "operator.victoriametrics.com/password-rotate-now"
+)
+
+func isPasswordRotationEnabled(user *vmv1beta1.VMUser) bool {
+	return user.Spec.PasswordRotation != nil && user.Spec.GeneratePassword && user.Spec.Password == nil
+}
+
+// passwordRotatedAt returns time of the last password rotation persisted at vmuser secret
+// or secret creation time, if password was never rotated
+func passwordRotatedAt(secret *corev1.Secret) time.Time {
+	if v, ok := secret.Annotations[vmUserPasswordRotatedAtAnnotation]; ok {
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			return t
+		}
+	}
+	return secret.CreationTimestamp.Time
+}
+
+// rotatePassword generates new password for the given user, if rotation period elapsed or rotateNow is requested.
+// Previous password is kept at secret until overlap window ends.
+// It returns previous password, which must be kept at vmauth config and true if secret must be updated
+func rotatePassword(secret *corev1.Secret, user *vmv1beta1.VMUser, now time.Time) (string, bool, error) {
+	if !isPasswordRotationEnabled(user) || len(secret.Data["password"]) == 0 {
+		return "", false, nil
+	}
+	pr := user.Spec.PasswordRotation
+	rotatedAt := passwordRotatedAt(secret)
+	var needRotate bool
+	if pr.RotateNow != "" && secret.Annotations[vmUserPasswordRotateNowAnnotation] != pr.RotateNow {
+		needRotate = true
+	}
+	if interval := pr.GetInterval(); interval > 0 && !now.Before(rotatedAt.Add(interval)) {
+		needRotate = true
+	}
+	if needRotate {
+		pwd, err := genPassword()
+		if err != nil {
+			return "", false, fmt.Errorf("cannot generate password for user=%q: %w", user.Name, err)
+		}
+		if secret.Annotations == nil {
+			secret.Annotations = make(map[string]string)
+		}
+		secret.Data[vmUserPreviousPasswordKey] = secret.Data["password"]
+		secret.Data["password"] = []byte(pwd)
+		secret.Annotations[vmUserPasswordRotatedAtAnnotation] = now.UTC().Format(time.RFC3339)
+		if pr.RotateNow != "" {
+			secret.Annotations[vmUserPasswordRotateNowAnnotation] = pr.RotateNow
+		}
+		return string(secret.Data[vmUserPreviousPasswordKey]), true, nil
+	}
+	prevPassword, ok := secret.Data[vmUserPreviousPasswordKey]
+	if !ok {
+		return "", false, nil
+	}
+	if now.After(rotatedAt.Add(pr.GetOverlapWindow())) {
+		delete(secret.Data, vmUserPreviousPasswordKey)
+		return "", true, nil
+	}
+	return string(prevPassword), false, nil
+}
+
+// PasswordRotationRequeueAfter returns duration, after which the given VMUser must be reconciled again
+// in order to rotate generated password or to remove previous password from vmauth config.
+// It returns 0, if password rotation isn't configured
+func PasswordRotationRequeueAfter(user *vmv1beta1.VMUser, now time.Time) time.Duration {
+	if !isPasswordRotationEnabled(user) {
+		return 0
+	}
+	rotatedAt := user.CreationTimestamp.Time
+	if user.Status.LastPasswordRotationTime != nil {
+		rotatedAt = user.Status.LastPasswordRotationTime.Time
+	}
+	var requeueAfter time.Duration
+	pr := user.Spec.PasswordRotation
+	if d := rotatedAt.Add(pr.GetOverlapWindow()).Sub(now); d > 0 {
+		requeueAfter = d
+	}
+	if interval := pr.GetInterval(); interval > 0 {
+		d := rotatedAt.Add(interval).Sub(now)
+		if d <= 0 {
+			d = time.Minute
+		}
+		if requeueAfter == 0 || d < requeueAfter {
+			requeueAfter = d
+		}
+	}
+	return requeueAfter
+}
+
+// updateRotatedPasswordSecrets writes vmuser secrets with rotated passwords
+// and persists status.lastPasswordRotationTime of the rotated users.
+// It must be called after vmauth config with the rotated passwords was applied,
+// otherwise clients could get password, which is not accepted by vmauth yet
+func updateRotatedPasswordSecrets(ctx context.Context, rclient client.Client, sus *skipableVMUsers) error {
+	for _, secret := range sus.rotatedPasswordSecrets {
+		logger.WithContext(ctx).Info(fmt.Sprintf("updating vmuser secret %s with rotated password", secret.Name))
+		if err := rclient.Update(ctx, secret); err != nil {
+			return fmt.Errorf("cannot update vmuser secret=%s/%s with rotated password: %w", secret.Namespace, secret.Name, err)
+		}
+	}
+	if len(sus.passwordRotatedUsers) == 0 {
+		return nil
+	}
+	var rotatedUsers []*vmv1beta1.VMUser
+	for _, u := range sus.users {
+		if _, ok := sus.passwordRotatedUsers[fmt.Sprintf("%s/%s", u.Namespace, u.Name)]; ok {
+			rotatedUsers = append(rotatedUsers, u)
+		}
+	}
+	return updatePasswordRotationStatus(ctx, rclient, rotatedUsers)
+}
+
+// updatePasswordRotationStatus persists status.lastPasswordRotationTime of the given users.
+// It must be called after vmauth config with the rotated passwords was applied
+func updatePasswordRotationStatus(ctx context.Context, rclient client.Client, users []*vmv1beta1.VMUser) error {
+	for _, user := range users {
+		patch := map[string]any{
+			"status": map[string]any{
+				"lastPasswordRotationTime": user.Status.LastPasswordRotationTime,
+			},
+		}
+		data, err := json.Marshal(patch)
+		if err != nil {
+			return fmt.Errorf("cannot marshal password rotation status patch: %w", err)
+		}
+		if err := rclient.Status().Patch(ctx, user, client.RawPatch(types.MergePatchType, data)); err != nil {
+			return fmt.Errorf("cannot update password rotation status for vmuser=%s/%s: %w", user.Namespace, user.Name, err)
+		}
+		logger.WithContext(ctx).Info(fmt.Sprintf("rotated password of vmuser=%s/%s at %s", user.Namespace, user.Name, user.Status.LastPasswordRotationTime.Format(time.RFC3339)))
+	}
+	return nil
+}
+
+// setPasswordRotationTime updates status.lastPasswordRotationTime of the given user
+// and returns true, if status must be persisted
+func setPasswordRotationTime(secret *corev1.Secret, user *vmv1beta1.VMUser) bool {
+	if _, ok := secret.Annotations[vmUserPasswordRotatedAtAnnotation]; !ok {
+		return false
+	}
+	rotatedAt := metav1.NewTime(passwordRotatedAt(secret))
+	if user.Status.LastPasswordRotationTime != nil && user.Status.LastPasswordRotationTime.Equal(&rotatedAt) {
+		return false
+	}
+	user.Status.LastPasswordRotationTime = &rotatedAt
+	return true
+}
@@ -0,0 +1,220 @@
+package vmauth
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+
+	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/k8stools"
+)
+
+func Test_rotatePassword(t *testing.T) {
+	now := time.Date(2024, 10, 1, 12, 0, 0, 0, time.UTC)
+	type opts struct {
+		pr               *vmv1beta1.VMUserPasswordRotation
+		annotations      map[string]string
+		data             map[string][]byte
+		wantPrevPassword string
+		wantUpdate       bool
+		wantRotated      bool
+	}
+	f := func(o opts) {
+		t.Helper()
+		user := &vmv1beta1.VMUser{
+			ObjectMeta: metav1.ObjectMeta{Name: "user", Namespace: "default"},
+			Spec: vmv1beta1.VMUserSpec{
+				GeneratePassword: true,
+				PasswordRotation: o.pr,
+			},
+		}
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Annotations: o.annotations},
+			Data:       o.data,
+		}
+		prevPassword := string(o.data["password"])
+		gotPrevPassword, gotUpdate, err := rotatePassword(secret, user, now)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		assert.Equal(t, o.wantUpdate, gotUpdate)
+		if o.wantRotated {
+			assert.Equal(t, prevPassword, gotPrevPassword)
+			assert.NotEqual(t, prevPassword, string(secret.Data["password"]))
+			assert.Equal(t, now.Format(time.RFC3339), secret.Annotations[vmUserPasswordRotatedAtAnnotation])
+			return
+		}
+		assert.Equal(t, o.wantPrevPassword, gotPrevPassword)
+		assert.Equal(t, prevPassword, string(secret.Data["password"]))
+	}
+	rotatedAt := func(d time.Duration) map[string]string {
+		return map[string]string{vmUserPasswordRotatedAtAnnotation: now.Add(-d).Format(time.RFC3339)}
+	}
+
+	// rotation is not configured
+	f(opts{
+		data: map[string][]byte{"password": []byte("pass")},
+	})
+
+	// interval is not elapsed
+	f(opts{
+		pr:          &vmv1beta1.VMUserPasswordRotation{Interval: "24h"},
+		annotations: rotatedAt(time.Hour),
+		data:        map[string][]byte{"password": []byte("pass")},
+	})
+
+	// interval elapsed
+	f(opts{
+		pr:          &vmv1beta1.VMUserPasswordRotation{Interval: "24h"},
+		annotations: rotatedAt(25 * time.Hour),
+		data:        map[string][]byte{"password": []byte("pass")},
+		wantUpdate:  true,
+		wantRotated: true,
+	})
+
+	// previous password is valid during overlap window
+	f(opts{
+		pr:               &vmv1beta1.VMUserPasswordRotation{Interval: "24h", OverlapWindow: "10m"},
+		annotations:      rotatedAt(5 * time.Minute),
+		data:             map[string][]byte{"password": []byte("pass"), vmUserPreviousPasswordKey: []byte("prev-pass")},
+		wantPrevPassword: "prev-pass",
+	})
+
+	// overlap window ended
+	f(opts{
+		pr:          &vmv1beta1.VMUserPasswordRotation{Interval: "24h"},
+		annotations: rotatedAt(10 * time.Minute),
+		data:        map[string][]byte{"password": []byte("pass"), vmUserPreviousPasswordKey: []byte("prev-pass")},
+		wantUpdate:  true,
+	})
+
+	// rotate now
+	f(opts{
+		pr:          &vmv1beta1.VMUserPasswordRotation{RotateNow: "2"},
+		annotations: map[string]string{vmUserPasswordRotateNowAnnotation: "1"},
+		data:        map[string][]byte{"password": []byte("pass")},
+		wantUpdate:  true,
+		wantRotated: true,
+	})
+
+	// rotate now was already performed for the current value
+	f(opts{
+		pr:          &vmv1beta1.VMUserPasswordRotation{RotateNow: "2"},
+		annotations: map[string]string{vmUserPasswordRotateNowAnnotation: "2"},
+		data:        map[string][]byte{"password": []byte("pass")},
+	})
+}
+
+func TestPasswordRotationRequeueAfter(t *testing.T) {
+	now := time.Date(2024, 10, 1, 12, 0, 0, 0, time.UTC)
+	f := func(pr *vmv1beta1.VMUserPasswordRotation, lastRotation time.Time, want time.Duration) {
+		t.Helper()
+		user := &vmv1beta1.VMUser{
+			ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.NewTime(now.Add(-48 * time.Hour))},
+			Spec: vmv1beta1.VMUserSpec{
+				GeneratePassword: true,
+				PasswordRotation: pr,
+			},
+		}
+		if !lastRotation.IsZero() {
+			user.Status.LastPasswordRotationTime = ptr.To(metav1.NewTime(lastRotation))
+		}
+		assert.Equal(t, want, PasswordRotationRequeueAfter(user, now))
+	}
+
+	f(nil, time.Time{}, 0)
+	f(&vmv1beta1.VMUserPasswordRotation{RotateNow: "1"}, time.Time{}, 0)
+	f(&vmv1beta1.VMUserPasswordRotation{Interval: "72h"}, time.Time{}, 24*time.Hour)
+	f(&vmv1beta1.VMUserPasswordRotation{Interval: "24h"}, time.Time{}, time.Minute)
+	f(&vmv1beta1.VMUserPasswordRotation{Interval: "24h", OverlapWindow: "10m"}, now.Add(-time.Minute), 9*time.Minute)
+}
+
+func TestBuildVMAuthConfigPasswordRotation(t *testing.T) {
+	vmauth := &vmv1beta1.VMAuth{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+		Spec: vmv1beta1.VMAuthSpec{
+			SelectAllByDefault: true,
+		},
+	}
+	user := &vmv1beta1.VMUser{
+		ObjectMeta: metav1.ObjectMeta{Name: "user", Namespace: "default"},
+		Spec: vmv1beta1.VMUserSpec{
+			GeneratePassword: true,
+			PasswordRotation: &vmv1beta1.VMUserPasswordRotation{Interval: "24h", OverlapWindow: "10m"},
+			TargetRefs: []vmv1beta1.TargetRef{{
+				Static: &vmv1beta1.StaticRef{URL: "http://some-static"},
+			}},
+		},
+	}
+	fclient := k8stools.GetTestClientWithObjects([]runtime.Object{
+		user,
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        user.SecretName(),
+				Namespace:   user.Namespace,
+				Annotations: map[string]string{vmUserPasswordRotatedAtAnnotation: time.Now().Add(-25 * time.Hour).UTC().Format(time.RFC3339)},
+			},
+			Data: map[string][]byte{"username": []byte("user"), "password": []byte("old-password")},
+		},
+	})
+	ctx := context.TODO()
+	sus, err := selectVMUsers(ctx, fclient, vmauth)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	got, err := buildVMAuthConfig(ctx, fclient, vmauth, sus, map[string]string{})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	getSecret := func() *corev1.Secret {
+		t.Helper()
+		var secret corev1.Secret
+		if err := fclient.Get(ctx, types.NamespacedName{Namespace: user.Namespace, Name: user.SecretName()}, &secret); err != nil {
+			t.Fatalf("cannot get user secret: %s", err)
+		}
+		return &secret
+	}
+	// rotated password must not be written before vmauth config
+	assert.Equal(t, "old-password", string(getSecret().Data["password"]))
+	if !assert.Len(t, sus.rotatedPasswordSecrets, 1) {
+		return
+	}
+	newPassword := string(sus.rotatedPasswordSecrets[0].Data["password"])
+	assert.NotEqual(t, "old-password", newPassword)
+
+	var cfg struct {
+		Users []struct {
+			Username string `yaml:"username"`
+			Password string `yaml:"password"`
+		} `yaml:"users"`
+	}
+	if err := yaml.Unmarshal(got, &cfg); err != nil {
+		t.Fatalf("cannot parse config: %s", err)
+	}
+	if assert.Len(t, cfg.Users, 2, strings.TrimSpace(string(got))) {
+		assert.Equal(t, "user", cfg.Users[0].Username)
+		assert.Equal(t, newPassword, cfg.Users[0].Password)
+		assert.Equal(t, "user", cfg.Users[1].Username)
+		assert.Equal(t, "old-password", cfg.Users[1].Password)
+	}
+	if assert.Contains(t, sus.passwordRotatedUsers, "default/user") {
+		assert.NoError(t, updateRotatedPasswordSecrets(ctx, fclient, sus))
+		secret := getSecret()
+		assert.Equal(t, newPassword, string(secret.Data["password"]))
+		assert.Equal(t, "old-password", string(secret.Data[vmUserPreviousPasswordKey]))
+		var gotUser vmv1beta1.VMUser
+		if err := fclient.Get(ctx, types.NamespacedName{Namespace: user.Namespace, Name: user.Name}, &gotUser); err != nil {
+			t.Fatalf("cannot get user: %s", err)
+		}
+		assert.NotNil(t, gotUser.Status.LastPasswordRotationTime)
+	}
+}
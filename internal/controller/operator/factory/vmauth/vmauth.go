@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"compress/gzip"
 	"context"
+	"crypto/sha256"
+	"encoding/json"
 	"fmt"
 	"path"
 	"sort"
@@ -18,8 +20,10 @@ import (
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/utils/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	gwapiv1 "sigs.k8s.io/gateway-api/apis/v1"
 
 	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
 	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/build"
@@ -39,7 +43,8 @@ const (
 )
 
 // CreateOrUpdateVMAuth - handles VMAuth deployment reconciliation.
-func CreateOrUpdateVMAuth(ctx context.Context, cr *vmv1beta1.VMAuth, rclient client.Client) error {
+// recorder is optional and used to emit events on rejected and accepted VMUsers
+func CreateOrUpdateVMAuth(ctx context.Context, cr *vmv1beta1.VMAuth, rclient client.Client, recorder record.EventRecorder) error {
 
 	var prevCR *vmv1beta1.VMAuth
 	if cr.ParsedLastAppliedSpec != nil {
@@ -67,13 +72,17 @@ func CreateOrUpdateVMAuth(ctx context.Context, cr *vmv1beta1.VMAuth, rclient cli
 	if err := createOrUpdateVMAuthIngress(ctx, rclient, cr); err != nil {
 		return fmt.Errorf("cannot create or update ingress for vmauth: %w", err)
 	}
+	if err := createOrUpdateVMAuthHTTPRoute(ctx, rclient, cr); err != nil {
+		return fmt.Errorf("cannot create or update httpRoute for vmauth: %w", err)
+	}
 	if !ptr.Deref(cr.Spec.DisableSelfServiceScrape, false) {
 		if err := reconcile.VMServiceScrapeForCRD(ctx, rclient, build.VMServiceScrapeForServiceWithSpec(svc, cr)); err != nil {
 			return err
 		}
 	}
 
-	if err := CreateOrUpdateVMAuthConfig(ctx, rclient, cr, nil); err != nil {
+	pendingConfigSecret, err := createOrUpdateVMAuthConfig(ctx, rclient, cr, nil, recorder)
+	if err != nil {
 		return err
 	}
 
@@ -101,6 +110,19 @@ func CreateOrUpdateVMAuth(ctx context.Context, cr *vmv1beta1.VMAuth, rclient cli
 	if err := reconcile.Deployment(ctx, rclient, newDeploy, prevDeploy, false); err != nil {
 		return fmt.Errorf("cannot reconcile vmauth deployment: %w", err)
 	}
+	if pendingConfigSecret != nil {
+		// vmauth pods mount config parts now and could be switched to the split configuration
+		var prevSecretMeta *metav1.ObjectMeta
+		if prevCR != nil {
+			prevSecretMeta = ptr.To(buildConfigSecretMeta(prevCR))
+		}
+		if err := reconcile.Secret(ctx, rclient, pendingConfigSecret, prevSecretMeta); err != nil {
+			return fmt.Errorf("cannot switch vmauth config secret to config parts: %w", err)
+		}
+	}
+	if err := removeStaleConfigSecretParts(ctx, rclient, cr); err != nil {
+		return err
+	}
 	if err := deletePrevStateResources(ctx, rclient, cr, prevCR); err != nil {
 		return err
 	}
@@ -239,6 +261,40 @@ func makeSpecForVMAuth(cr *vmv1beta1.VMAuth) (*corev1.PodTemplateSpec, error) {
 		// no-op external managed configuration
 		// add check interval
 		args = append(args, "-configCheckInterval=1m")
+	case cr.Status.ConfigSecretParts > 0:
+		// config-reloader cannot merge config parts
+		// config is merged by init and sidecar containers, vmauth reloads it on change
+		args = append(args, "-configCheckInterval="+configPartsMergeInterval)
+		volumes = append(volumes,
+			corev1.Volume{
+				Name: "config-out",
+				VolumeSource: corev1.VolumeSource{
+					EmptyDir: &corev1.EmptyDirVolumeSource{},
+				},
+			},
+			corev1.Volume{
+				Name: vmAuthVolumeName,
+				VolumeSource: corev1.VolumeSource{
+					Secret: &corev1.SecretVolumeSource{
+						SecretName: cr.ConfigSecretName(),
+					},
+				},
+			},
+			buildConfigPartsVolume(cr),
+		)
+		volumeMounts = append(volumeMounts,
+			corev1.VolumeMount{
+				Name:      vmAuthVolumeName,
+				MountPath: vmAuthConfigRawFolder,
+			},
+			corev1.VolumeMount{
+				Name:      "config-out",
+				MountPath: vmAuthConfigFolder,
+			},
+		)
+		initContainers = append(initContainers, buildMergeConfigPartsContainer(cr, false))
+		operatorContainers = append(operatorContainers, buildMergeConfigPartsContainer(cr, true))
+		build.AddStrictSecuritySettingsToContainers(cr.Spec.SecurityContext, initContainers, useStrictSecurity)
 	default:
 		volumes = append(volumes, corev1.Volume{
 			Name: "config-out",
@@ -320,10 +376,27 @@ func makeSpecForVMAuth(cr *vmv1beta1.VMAuth) (*corev1.PodTemplateSpec, error) {
 }
 
 // CreateOrUpdateVMAuthConfig configuration secret for vmauth.
-func CreateOrUpdateVMAuthConfig(ctx context.Context, rclient client.Client, cr *vmv1beta1.VMAuth, childObject *vmv1beta1.VMUser) error {
+// It persists config status changes, status.configSecretParts change triggers VMAuth reconcile
+// recorder is optional and used to emit events on rejected and accepted VMUsers
+func CreateOrUpdateVMAuthConfig(ctx context.Context, rclient client.Client, cr *vmv1beta1.VMAuth, childObject *vmv1beta1.VMUser, recorder record.EventRecorder) error {
+	prevStatus := cr.Status.DeepCopy()
+	// pending config Secret is applied by VMAuth reconcile, which is triggered by status.configSecretParts change
+	if _, err := createOrUpdateVMAuthConfig(ctx, rclient, cr, childObject, recorder); err != nil {
+		return err
+	}
+	if isConfigStatusChanged(prevStatus, &cr.Status) {
+		return updateConfigStatus(ctx, rclient, cr)
+	}
+	return nil
+}
+
+// createOrUpdateVMAuthConfig generates vmauth configuration and updates config Secrets.
+// It returns pending main config Secret, if configuration was split and vmauth pods don't mount config parts yet.
+// Pending Secret must be applied after vmauth pods update, otherwise pods reload truncated configuration
+func createOrUpdateVMAuthConfig(ctx context.Context, rclient client.Client, cr *vmv1beta1.VMAuth, childObject *vmv1beta1.VMUser, recorder record.EventRecorder) (*corev1.Secret, error) {
 	// fast path
 	if cr.Spec.ExternalConfig.SecretRef != nil || cr.Spec.ExternalConfig.LocalPath != "" {
-		return nil
+		return nil, nil
 	}
 	var prevCR *vmv1beta1.VMAuth
 	if cr.ParsedLastAppliedSpec != nil {
@@ -339,55 +412,158 @@ func CreateOrUpdateVMAuthConfig(ctx context.Context, rclient client.Client, cr *
 	// fetch exist users for vmauth.
 	sus, err := selectVMUsers(ctx, rclient, cr)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	tlsAssets := make(map[string]string)
 	generatedConfig, err := buildVMAuthConfig(ctx, rclient, cr, sus, tlsAssets)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	for assetKey, assetValue := range tlsAssets {
 		s.Data[assetKey] = []byte(assetValue)
 	}
+	cr.Status.UsersTotal = int32(len(sus.namespacedNames))
+	cr.Status.UsersFailed = int32(len(sus.brokenVMUsers))
+	cr.Status.LastConfigHash = fmt.Sprintf("%x", sha256.Sum256(generatedConfig))
 
 	var buf bytes.Buffer
 	if err := gzipConfig(&buf, generatedConfig); err != nil {
-		return fmt.Errorf("cannot gzip config for vmagent: %w", err)
+		return nil, fmt.Errorf("cannot gzip config for vmagent: %w", err)
+	}
+	var configParts [][]byte
+	if buf.Len() > maxConfigSecretSize {
+		configParts, err = splitVMAuthConfig(generatedConfig)
+		if err != nil {
+			return nil, fmt.Errorf("cannot split config for vmauth: %w", err)
+		}
+		buf.Reset()
+		buf.Write(configParts[0])
 	}
 	s.Data[vmAuthConfigNameGz] = buf.Bytes()
-	var prevSecretMeta *metav1.ObjectMeta
-	if prevCR != nil {
-		prevSecretMeta = ptr.To(buildConfigSecretMeta(prevCR))
+	// parts must be created before the main config, which refers them
+	if err := reconcileConfigSecretParts(ctx, rclient, cr, prevCR, configParts); err != nil {
+		return nil, err
 	}
-	if err := reconcile.Secret(ctx, rclient, s, prevSecretMeta); err != nil {
-		return err
+	cr.Status.ConfigSecretParts = 0
+	cr.Status.ConfigSecretPartsChecksum = ""
+	var pendingConfigSecret *corev1.Secret
+	if len(configParts) > 1 {
+		cr.Status.ConfigSecretParts = int32(len(configParts) - 1)
+		cr.Status.ConfigSecretPartsChecksum = configPartsChecksum(configParts)
+		s.Data[configPartsVersionKey] = []byte(configPartsVersion(configParts))
+		canSwitch, err := canSwitchToConfigParts(ctx, rclient, cr)
+		if err != nil {
+			return nil, err
+		}
+		if !canSwitch {
+			// pods of not split configuration reload the main config Secret with config-reloader
+			// and must not get the first config part only
+			pendingConfigSecret = s
+		}
+	}
+	if pendingConfigSecret == nil {
+		var prevSecretMeta *metav1.ObjectMeta
+		if prevCR != nil {
+			prevSecretMeta = ptr.To(buildConfigSecretMeta(prevCR))
+		}
+		if err := reconcile.Secret(ctx, rclient, s, prevSecretMeta); err != nil {
+			return nil, err
+		}
+		// rotated passwords are not persisted for pending config Secret
+		// and will be rotated again on the next reconcile
+		if err := updateRotatedPasswordSecrets(ctx, rclient, sus); err != nil {
+			return nil, err
+		}
 	}
 	logger.SelectedObjects(ctx, "VMUsers", len(sus.namespacedNames), len(sus.brokenVMUsers), sus.namespacedNames)
 
 	parentObject := fmt.Sprintf("%s.%s.vmauth", cr.GetName(), cr.GetNamespace())
+	events := reconcile.ChildObjectEvents{
+		Object:         "user",
+		Parent:         fmt.Sprintf("vmauth=%s/%s", cr.Namespace, cr.Name),
+		RejectedReason: vmUserRejectedEventReason,
+		AcceptedReason: vmUserAcceptedEventReason,
+	}
 	if childObject != nil {
 		// fast path
 		for _, u := range sus.users {
 			if u.Name == childObject.Name && u.Namespace == childObject.Namespace {
-				return reconcile.StatusForChildObjects(ctx, rclient, parentObject, []*vmv1beta1.VMUser{u})
+				childUsers := []*vmv1beta1.VMUser{u}
+				reconcile.ChildObjectsEvents(recorder, parentObject, childUsers, events)
+				return pendingConfigSecret, reconcile.StatusForChildObjects(ctx, rclient, parentObject, childUsers)
 			}
 		}
 		for _, u := range sus.brokenVMUsers {
 			if u.Name == childObject.Name && u.Namespace == childObject.Namespace {
-				return reconcile.StatusForChildObjects(ctx, rclient, parentObject, []*vmv1beta1.VMUser{u})
+				childUsers := []*vmv1beta1.VMUser{u}
+				reconcile.ChildObjectsEvents(recorder, parentObject, childUsers, events)
+				return pendingConfigSecret, reconcile.StatusForChildObjects(ctx, rclient, parentObject, childUsers)
 			}
 		}
 	}
+	reconcile.ChildObjectsEvents(recorder, parentObject, sus.users, events)
 	if err := reconcile.StatusForChildObjects(ctx, rclient, parentObject, sus.users); err != nil {
-		return fmt.Errorf("cannot update statuses for vmusers: %w", err)
+		return nil, fmt.Errorf("cannot update statuses for vmusers: %w", err)
 	}
+	reconcile.ChildObjectsEvents(recorder, parentObject, sus.brokenVMUsers, events)
 	if err := reconcile.StatusForChildObjects(ctx, rclient, parentObject, sus.brokenVMUsers); err != nil {
-		return fmt.Errorf("cannot update statuses for broken vmusers: %w", err)
+		return nil, fmt.Errorf("cannot update statuses for broken vmusers: %w", err)
 	}
 
+	return pendingConfigSecret, nil
+}
+
+const (
+	vmUserRejectedEventReason = "VMUserRejected"
+	vmUserAcceptedEventReason = "VMUserAccepted"
+)
+
+// isConfigStatusChanged checks if status fields, which are set during config generation, were changed
+func isConfigStatusChanged(prev, curr *vmv1beta1.VMAuthStatus) bool {
+	return prev.ConfigSecretParts != curr.ConfigSecretParts ||
+		prev.ConfigSecretPartsChecksum != curr.ConfigSecretPartsChecksum ||
+		prev.UsersTotal != curr.UsersTotal ||
+		prev.UsersFailed != curr.UsersFailed ||
+		prev.LastConfigHash != curr.LastConfigHash
+}
+
+// updateConfigStatus persists status fields of the given VMAuth, which are set during config generation.
+// It must be used, if configuration was updated outside of VMAuth reconcile,
+// status.configSecretParts change triggers VMAuth reconcile, which updates vmauth pods with new config parts
+func updateConfigStatus(ctx context.Context, rclient client.Client, cr *vmv1beta1.VMAuth) error {
+	patch := map[string]any{
+		"status": map[string]any{
+			"configSecretParts":         cr.Status.ConfigSecretParts,
+			"configSecretPartsChecksum": cr.Status.ConfigSecretPartsChecksum,
+			"usersTotal":                cr.Status.UsersTotal,
+			"usersFailed":               cr.Status.UsersFailed,
+			"lastConfigHash":            cr.Status.LastConfigHash,
+		},
+	}
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return fmt.Errorf("cannot marshal config status patch: %w", err)
+	}
+	if err := rclient.Status().Patch(ctx, cr, client.RawPatch(types.MergePatchType, data)); err != nil {
+		return fmt.Errorf("cannot update config status: %w", err)
+	}
 	return nil
 }
 
+// canSwitchToConfigParts checks if the main config Secret could be switched to split configuration before vmauth pods update.
+// It's safe only if Secret is missing or already holds split configuration
+func canSwitchToConfigParts(ctx context.Context, rclient client.Client, cr *vmv1beta1.VMAuth) (bool, error) {
+	var s corev1.Secret
+	if err := rclient.Get(ctx, types.NamespacedName{Namespace: cr.Namespace, Name: cr.ConfigSecretName()}, &s); err != nil {
+		if errors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, fmt.Errorf("cannot get vmauth config secret: %w", err)
+	}
+	_, ok := s.Data[configPartsVersionKey]
+	return ok, nil
+}
+
 func buildConfigSecretMeta(cr *vmv1beta1.VMAuth) metav1.ObjectMeta {
 	return metav1.ObjectMeta{
 		Name:   cr.ConfigSecretName(),
@@ -434,7 +610,7 @@ func buildIngressConfig(cr *vmv1beta1.VMAuth) *networkingv1.Ingress {
 			HTTP: &networkingv1.HTTPIngressRuleValue{
 				Paths: []networkingv1.HTTPIngressPath{
 					{
-						Path: "/",
+						Path: cr.Spec.Ingress.PathPrefixOrDefault(),
 						Backend: networkingv1.IngressBackend{
 							Service: &networkingv1.IngressServiceBackend{
 								Name: cr.PrefixedName(),
@@ -483,6 +659,71 @@ func buildIngressConfig(cr *vmv1beta1.VMAuth) *networkingv1.Ingress {
 	}
 }
 
+// createOrUpdateVMAuthHTTPRoute handles Gateway API HTTPRoute for vmauth.
+func createOrUpdateVMAuthHTTPRoute(ctx context.Context, rclient client.Client, cr *vmv1beta1.VMAuth) error {
+	if cr.Spec.HTTPRoute == nil {
+		return nil
+	}
+	newRoute := buildHTTPRoute(cr)
+	var existRoute gwapiv1.HTTPRoute
+	if err := rclient.Get(ctx, types.NamespacedName{Namespace: newRoute.Namespace, Name: newRoute.Name}, &existRoute); err != nil {
+		if errors.IsNotFound(err) {
+			return rclient.Create(ctx, newRoute)
+		}
+		return err
+	}
+	if err := finalize.FreeIfNeeded(ctx, rclient, &existRoute); err != nil {
+		return err
+	}
+	newRoute.Annotations = labels.Merge(existRoute.Annotations, newRoute.Annotations)
+	newRoute.ResourceVersion = existRoute.ResourceVersion
+	vmv1beta1.AddFinalizer(newRoute, &existRoute)
+	return rclient.Update(ctx, newRoute)
+}
+
+func buildHTTPRoute(cr *vmv1beta1.VMAuth) *gwapiv1.HTTPRoute {
+	hr := cr.Spec.HTTPRoute
+	rule := gwapiv1.HTTPRouteRule{
+		Matches: []gwapiv1.HTTPRouteMatch{
+			{
+				Path: &gwapiv1.HTTPPathMatch{
+					Type:  ptr.To(gwapiv1.PathMatchPathPrefix),
+					Value: ptr.To(hr.PathPrefixOrDefault()),
+				},
+			},
+		},
+		BackendRefs: []gwapiv1.HTTPBackendRef{
+			{
+				BackendRef: gwapiv1.BackendRef{
+					BackendObjectReference: gwapiv1.BackendObjectReference{
+						Name: gwapiv1.ObjectName(cr.PrefixedName()),
+						Port: ptr.To(gwapiv1.PortNumber(intstr.Parse(cr.Spec.Port).IntVal)),
+					},
+				},
+			},
+		},
+	}
+	return &gwapiv1.HTTPRoute{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            cr.PrefixedName(),
+			Namespace:       cr.Namespace,
+			Labels:          labels.Merge(hr.Labels, cr.SelectorLabels()),
+			Annotations:     hr.Annotations,
+			OwnerReferences: cr.AsOwner(),
+			Finalizers: []string{
+				vmv1beta1.FinalizerName,
+			},
+		},
+		Spec: gwapiv1.HTTPRouteSpec{
+			CommonRouteSpec: gwapiv1.CommonRouteSpec{
+				ParentRefs: hr.ParentRefs,
+			},
+			Hostnames: hr.Hostnames,
+			Rules:     []gwapiv1.HTTPRouteRule{rule},
+		},
+	}
+}
+
 func buildVMAuthConfigReloaderContainer(cr *vmv1beta1.VMAuth) corev1.Container {
 	configReloaderArgs := []string{
 		fmt.Sprintf("--reload-url=%s", vmv1beta1.BuildReloadPathWithPort(cr.Spec.ExtraArgs, cr.Spec.Port)),
@@ -653,6 +894,11 @@ func deletePrevStateResources(ctx context.Context, rclient client.Client, cr, pr
 			return fmt.Errorf("cannot delete ingress from prev state: %w", err)
 		}
 	}
+	if cr.Spec.HTTPRoute == nil && prevCR.Spec.HTTPRoute != nil {
+		if err := finalize.SafeDeleteWithFinalizer(ctx, rclient, &gwapiv1.HTTPRoute{ObjectMeta: objMeta}); err != nil {
+			return fmt.Errorf("cannot delete httpRoute from prev state: %w", err)
+		}
+	}
 	if ptr.Deref(cr.Spec.DisableSelfServiceScrape, false) && !ptr.Deref(prevCR.Spec.DisableSelfServiceScrape, false) {
 		if err := finalize.SafeDeleteWithFinalizer(ctx, rclient, &vmv1beta1.VMServiceScrape{ObjectMeta: objMeta}); err != nil {
 			return fmt.Errorf("cannot remove serviceScrape: %w", err)
@@ -2,6 +2,8 @@ package vmauth
 
 import (
 	"context"
+	"crypto/sha256"
+	"fmt"
 	"testing"
 
 	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
@@ -11,9 +13,13 @@ import (
 	"github.com/stretchr/testify/assert"
 	"gopkg.in/yaml.v2"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/utils/ptr"
+	gwapiv1 "sigs.k8s.io/gateway-api/apis/v1"
 )
 
 func TestCreateOrUpdateVMAuth(t *testing.T) {
@@ -158,7 +164,7 @@ func TestCreateOrUpdateVMAuth(t *testing.T) {
 			ctx := context.Background()
 			tc := k8stools.GetTestClientWithObjects(tt.predefinedObjects)
 			// TODO fix
-			if err := CreateOrUpdateVMAuth(ctx, tt.args.cr, tc); (err != nil) != tt.wantErr {
+			if err := CreateOrUpdateVMAuth(ctx, tt.args.cr, tc, nil); (err != nil) != tt.wantErr {
 				t.Errorf("CreateOrUpdateVMAuth() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
@@ -447,3 +453,143 @@ serviceaccountname: vmauth-auth
 `)
 	})
 }
+
+func TestCreateOrUpdateVMAuthConfigStatus(t *testing.T) {
+	cr := &vmv1beta1.VMAuth{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+		Spec: vmv1beta1.VMAuthSpec{
+			SelectAllByDefault: true,
+		},
+	}
+	staticRef := []vmv1beta1.TargetRef{{Static: &vmv1beta1.StaticRef{URL: "http://some-static"}}}
+	fclient := k8stools.GetTestClientWithObjects([]runtime.Object{
+		cr.DeepCopy(),
+		&vmv1beta1.VMUser{
+			ObjectMeta: metav1.ObjectMeta{Name: "valid", Namespace: "default"},
+			Spec: vmv1beta1.VMUserSpec{
+				BearerToken: ptr.To("token"),
+				TargetRefs:  staticRef,
+			},
+		},
+		&vmv1beta1.VMUser{
+			ObjectMeta: metav1.ObjectMeta{Name: "missing-crd", Namespace: "default"},
+			Spec: vmv1beta1.VMUserSpec{
+				BearerToken: ptr.To("token-2"),
+				TargetRefs: []vmv1beta1.TargetRef{{
+					CRD: &vmv1beta1.CRDRef{Kind: "VMAgent", Name: "missing", Namespace: "default"},
+				}},
+			},
+		},
+		&vmv1beta1.VMUser{
+			ObjectMeta: metav1.ObjectMeta{Name: "missing-secret", Namespace: "default"},
+			Spec: vmv1beta1.VMUserSpec{
+				PasswordRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "missing"},
+					Key:                  "password",
+				},
+				TargetRefs: staticRef,
+			},
+		},
+	})
+	ctx := context.TODO()
+	recorder := record.NewFakeRecorder(10)
+	if err := CreateOrUpdateVMAuthConfig(ctx, fclient, cr, nil, recorder); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.Equal(t, int32(3), cr.Status.UsersTotal)
+	assert.Equal(t, int32(2), cr.Status.UsersFailed)
+	assert.Equal(t, fmt.Sprintf("%x", sha256.Sum256([]byte(`users:
+- url_prefix:
+  - http://some-static
+  bearer_token: token
+`))), cr.Status.LastConfigHash)
+
+	var persisted vmv1beta1.VMAuth
+	if err := fclient.Get(ctx, types.NamespacedName{Namespace: cr.Namespace, Name: cr.Name}, &persisted); err != nil {
+		t.Fatalf("cannot get vmauth: %s", err)
+	}
+	assert.Equal(t, cr.Status.UsersTotal, persisted.Status.UsersTotal)
+	assert.Equal(t, cr.Status.UsersFailed, persisted.Status.UsersFailed)
+	assert.Equal(t, cr.Status.LastConfigHash, persisted.Status.LastConfigHash)
+
+	for _, name := range []string{"missing-crd", "missing-secret"} {
+		var user vmv1beta1.VMUser
+		if err := fclient.Get(ctx, types.NamespacedName{Namespace: "default", Name: name}, &user); err != nil {
+			t.Fatalf("cannot get vmuser: %s", err)
+		}
+		if assert.Len(t, user.Status.Conditions, 1, "vmuser=%s", name) {
+			cond := user.Status.Conditions[0]
+			assert.Equal(t, "test.default.vmauth"+vmv1beta1.ConditionDomainTypeAppliedSuffix, cond.Type)
+			assert.Equal(t, metav1.ConditionFalse, cond.Status)
+			assert.NotEmpty(t, cond.Message)
+		}
+	}
+	close(recorder.Events)
+	var gotEvents []string
+	for e := range recorder.Events {
+		gotEvents = append(gotEvents, e)
+	}
+	if assert.Len(t, gotEvents, 2) {
+		for _, e := range gotEvents {
+			assert.Contains(t, e, "Warning VMUserRejected user was rejected by vmauth=default/test: ")
+		}
+	}
+}
+
+func TestCreateOrUpdateVMAuthHTTPRoute(t *testing.T) {
+	cr := &vmv1beta1.VMAuth{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+		Spec: vmv1beta1.VMAuthSpec{
+			CommonDefaultableParams: vmv1beta1.CommonDefaultableParams{Port: "8427"},
+			HTTPRoute: &vmv1beta1.EmbeddedHTTPRoute{
+				EmbeddedObjectMetadata: vmv1beta1.EmbeddedObjectMetadata{
+					Labels:      map[string]string{"team": "ops"},
+					Annotations: map[string]string{"cert-manager.io/cluster-issuer": "letsencrypt"},
+				},
+				ParentRefs: []gwapiv1.ParentReference{{Name: "gateway", Namespace: ptr.To(gwapiv1.Namespace("gateway-system"))}},
+				Hostnames:  []gwapiv1.Hostname{"vmauth.example.com"},
+				PathPrefix: "/api",
+			},
+		},
+	}
+	fclient := k8stools.GetTestClientWithObjects([]runtime.Object{cr.DeepCopy()})
+	ctx := context.TODO()
+	nsn := types.NamespacedName{Namespace: cr.Namespace, Name: cr.PrefixedName()}
+
+	if err := createOrUpdateVMAuthHTTPRoute(ctx, fclient, cr); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var got gwapiv1.HTTPRoute
+	if err := fclient.Get(ctx, nsn, &got); err != nil {
+		t.Fatalf("cannot get httpRoute: %s", err)
+	}
+	assert.Equal(t, "ops", got.Labels["team"])
+	assert.Equal(t, "letsencrypt", got.Annotations["cert-manager.io/cluster-issuer"])
+	assert.Equal(t, cr.Spec.HTTPRoute.ParentRefs, got.Spec.ParentRefs)
+	assert.Equal(t, cr.Spec.HTTPRoute.Hostnames, got.Spec.Hostnames)
+	if assert.Len(t, got.Spec.Rules, 1) {
+		rule := got.Spec.Rules[0]
+		assert.Equal(t, "/api", *rule.Matches[0].Path.Value)
+		assert.Equal(t, gwapiv1.ObjectName(cr.PrefixedName()), rule.BackendRefs[0].Name)
+		assert.Equal(t, gwapiv1.PortNumber(8427), *rule.BackendRefs[0].Port)
+	}
+
+	// update keeps object in sync
+	cr.Spec.HTTPRoute.Hostnames = []gwapiv1.Hostname{"auth.example.com"}
+	if err := createOrUpdateVMAuthHTTPRoute(ctx, fclient, cr); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := fclient.Get(ctx, nsn, &got); err != nil {
+		t.Fatalf("cannot get httpRoute: %s", err)
+	}
+	assert.Equal(t, []gwapiv1.Hostname{"auth.example.com"}, got.Spec.Hostnames)
+
+	// httpRoute is removed with spec block
+	prevCR := cr.DeepCopy()
+	cr.Spec.HTTPRoute = nil
+	if err := deletePrevStateResources(ctx, fclient, cr, prevCR); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	err := fclient.Get(ctx, nsn, &got)
+	assert.True(t, errors.IsNotFound(err), "expected httpRoute to be deleted, got: %v", err)
+}
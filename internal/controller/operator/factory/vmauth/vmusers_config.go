@@ -1,13 +1,16 @@
 package vmauth
 
 import (
+	"bytes"
 	"context"
 	"crypto/rand"
 	"encoding/base64"
+	goerrors "errors"
 	"fmt"
 	"math/big"
 	"net/url"
 	"path"
+	"slices"
 	"sort"
 	"strings"
 	"time"
@@ -33,6 +36,14 @@ type skipableVMUsers struct {
 	users           []*vmv1beta1.VMUser
 	brokenVMUsers   []*vmv1beta1.VMUser
 	namespacedNames []string
+	// previous passwords of users with rotated password, which are still valid
+	previousPasswords map[string]string
+	// users with status.lastPasswordRotationTime changed
+	passwordRotatedUsers map[string]struct{}
+	// vmuser secrets with rotated password, which must be updated after vmauth config
+	rotatedPasswordSecrets []*corev1.Secret
+	// urls of objects selected by crdSelector targetRefs, by user namespace/name and ref idx
+	crdSelectorURLs map[string]map[int][]string
 }
 
 // visitAll visits all users objects
@@ -222,8 +233,30 @@ func addAuthCredentialsBuildSecrets(ctx context.Context, rclient client.Client,
 				needToCreateSecrets = append(needToCreateSecrets, userSecret)
 
 			} else {
+				prevPassword, isRotated, err := rotatePassword(&vmus, user, time.Now())
+				if err != nil {
+					user.Status.CurrentSyncError = fmt.Sprintf("cannot rotate user password: %q", err)
+					return false
+				}
+				userKey := fmt.Sprintf("%s/%s", user.Namespace, user.Name)
+				if prevPassword != "" {
+					if sus.previousPasswords == nil {
+						sus.previousPasswords = make(map[string]string)
+					}
+					sus.previousPasswords[userKey] = prevPassword
+				}
+				if setPasswordRotationTime(&vmus, user) {
+					if sus.passwordRotatedUsers == nil {
+						sus.passwordRotatedUsers = make(map[string]struct{})
+					}
+					sus.passwordRotatedUsers[userKey] = struct{}{}
+				}
 				// secret exists, check it's state
-				if injectAuthSettings(&vmus, user) {
+				needUpdate := injectAuthSettings(&vmus, user)
+				switch {
+				case isRotated:
+					sus.rotatedPasswordSecrets = append(sus.rotatedPasswordSecrets, &vmus)
+				case needUpdate:
 					needToUpdateSecrets = append(needToUpdateSecrets, &vmus)
 				}
 			}
@@ -358,13 +391,144 @@ func (c *clusterWithURL) AsURL() string {
 	}
 }
 
+// errNoSelectedTargets is returned for user, which has no routes, since crdSelector refs matched no objects
+var errNoSelectedTargets = goerrors.New("crdSelector targetRefs matched no objects")
+
+// crdSelectorTemplateData holds fields of matched object available at target_path_suffix template of crdSelector ref
+type crdSelectorTemplateData struct {
+	Name      string
+	Namespace string
+	Labels    map[string]string
+}
+
+// selectCRDTargets returns objects of the given kind matched by crdSelector
+// objects are sorted by namespace and name for consistency
+func selectCRDTargets(ctx context.Context, rclient client.Client, sel *vmv1beta1.CRDSelectorRef, userNamespace string) ([]objectWithURL, error) {
+	var objs []objectWithURL
+	objSelector := sel.Selector
+	if objSelector == nil {
+		// select all objects of the given kind
+		objSelector = &metav1.LabelSelector{}
+	}
+	var err error
+	switch sel.Kind {
+	case "VMAgent":
+		err = k8stools.VisitObjectsForSelectorsAtNs(ctx, rclient, sel.NamespaceSelector, objSelector, userNamespace, false, func(l *vmv1beta1.VMAgentList) {
+			for i := range l.Items {
+				objs = append(objs, &l.Items[i])
+			}
+		})
+	case "VMAlert":
+		err = k8stools.VisitObjectsForSelectorsAtNs(ctx, rclient, sel.NamespaceSelector, objSelector, userNamespace, false, func(l *vmv1beta1.VMAlertList) {
+			for i := range l.Items {
+				objs = append(objs, &l.Items[i])
+			}
+		})
+	case "VMSingle":
+		err = k8stools.VisitObjectsForSelectorsAtNs(ctx, rclient, sel.NamespaceSelector, objSelector, userNamespace, false, func(l *vmv1beta1.VMSingleList) {
+			for i := range l.Items {
+				objs = append(objs, &l.Items[i])
+			}
+		})
+	case "VLogs":
+		err = k8stools.VisitObjectsForSelectorsAtNs(ctx, rclient, sel.NamespaceSelector, objSelector, userNamespace, false, func(l *vmv1beta1.VLogsList) {
+			for i := range l.Items {
+				objs = append(objs, &l.Items[i])
+			}
+		})
+	case "VMAlertmanager", "VMAlertManager":
+		err = k8stools.VisitObjectsForSelectorsAtNs(ctx, rclient, sel.NamespaceSelector, objSelector, userNamespace, false, func(l *vmv1beta1.VMAlertmanagerList) {
+			for i := range l.Items {
+				objs = append(objs, &l.Items[i])
+			}
+		})
+	case "VMCluster/vmselect", "VMCluster/vminsert", "VMCluster/vmstorage":
+		component := strings.TrimPrefix(sel.Kind, "VMCluster/")
+		err = k8stools.VisitObjectsForSelectorsAtNs(ctx, rclient, sel.NamespaceSelector, objSelector, userNamespace, false, func(l *vmv1beta1.VMClusterList) {
+			for i := range l.Items {
+				objs = append(objs, &clusterWithURL{&l.Items[i], &l.Items[i], component})
+			}
+		})
+	default:
+		return nil, fmt.Errorf("unsupported kind for crdSelector: %q", sel.Kind)
+	}
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(objs, func(i, j int) bool {
+		if objs[i].GetNamespace() != objs[j].GetNamespace() {
+			return objs[i].GetNamespace() < objs[j].GetNamespace()
+		}
+		return objs[i].GetName() < objs[j].GetName()
+	})
+	return objs, nil
+}
+
+// buildCRDSelectorURLs returns url for each object matched by crdSelector of the given ref
+// target_path_suffix is executed as a template for each object
+func buildCRDSelectorURLs(ref *vmv1beta1.TargetRef, objs []objectWithURL) ([]string, error) {
+	tpl, err := ref.ParseTargetPathSuffixTemplate()
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse target_path_suffix template: %w", err)
+	}
+	urls := make([]string, 0, len(objs))
+	var buf bytes.Buffer
+	for _, obj := range objs {
+		urlPrefix := obj.AsURL()
+		if ref.TargetPathSuffix != "" {
+			buf.Reset()
+			data := crdSelectorTemplateData{
+				Name:      obj.GetName(),
+				Namespace: obj.GetNamespace(),
+				Labels:    obj.GetLabels(),
+			}
+			if err := tpl.Execute(&buf, data); err != nil {
+				return nil, fmt.Errorf("cannot execute target_path_suffix template for object=%s/%s: %w", obj.GetNamespace(), obj.GetName(), err)
+			}
+			if urlPrefix, err = addTargetPathSuffix(urlPrefix, buf.String()); err != nil {
+				return nil, err
+			}
+		}
+		urls = append(urls, urlPrefix)
+	}
+	return urls, nil
+}
+
 // fetchCRDRefURLs performs a fetch for CRD objects for vmauth users and returns an url by crd ref key name
+// urls of objects selected by crdSelector refs are stored at sus.crdSelectorURLs
 func fetchCRDRefURLs(ctx context.Context, rclient client.Client, sus *skipableVMUsers) (map[string]string, error) {
 	crdCacheURLCache := make(map[string]string)
 	var resultErr error
+	sus.crdSelectorURLs = make(map[string]map[int][]string)
 	sus.visitAll(func(user *vmv1beta1.VMUser) bool {
 		for j := range user.Spec.TargetRefs {
 			ref := user.Spec.TargetRefs[j]
+			if ref.CRDSelector != nil {
+				objs, err := selectCRDTargets(ctx, rclient, ref.CRDSelector, user.Namespace)
+				if err != nil {
+					resultErr = fmt.Errorf("cannot select objects for crdSelector: %w", err)
+					sus.stopIter = true
+					return true
+				}
+				urls, err := buildCRDSelectorURLs(&ref, objs)
+				if err != nil {
+					user.Status.CurrentSyncError = fmt.Sprintf("cannot build urls for crdSelector at ref idx=%d: %s", j, err)
+					return false
+				}
+				if len(urls) == 0 {
+					msg := fmt.Sprintf("crdSelector for kind=%q at ref idx=%d matched no objects", ref.CRDSelector.Kind, j)
+					if user.Status.CurrentSyncWarning != "" {
+						msg = user.Status.CurrentSyncWarning + "; " + msg
+					}
+					user.Status.CurrentSyncWarning = msg
+				}
+				userKey := fmt.Sprintf("%s/%s", user.Namespace, user.Name)
+				if sus.crdSelectorURLs[userKey] == nil {
+					sus.crdSelectorURLs[userKey] = make(map[int][]string)
+				}
+				sus.crdSelectorURLs[userKey][j] = urls
+				continue
+			}
 			if ref.CRD == nil {
 				continue
 			}
@@ -384,7 +548,7 @@ func fetchCRDRefURLs(ctx context.Context, rclient client.Client, sus *skipableVM
 					sus.stopIter = true
 					return true
 				}
-				user.Status.CurrentSyncError = fmt.Sprintf("cannot fined CRD link for kind=%q at ref idx=%d: %q", ref.CRD.Kind, j, err)
+				user.Status.CurrentSyncError = fmt.Sprintf("cannot find CRD link for kind=%q at ref idx=%d: %q", ref.CRD.Kind, j, err)
 				return false
 			}
 			crdCacheURLCache[ref.CRD.AsKey()] = url
@@ -410,14 +574,34 @@ func generateVMAuthConfig(cr *vmv1beta1.VMAuth, sus *skipableVMUsers, crdCache m
 		TLSAssets:          tlsAssets,
 	}
 	var cfgUsers []yaml.MapSlice
+	if cr.Spec.DefaultUserPolicies != nil {
+		if err := cr.Spec.DefaultUserPolicies.Validate(); err != nil {
+			return nil, fmt.Errorf("incorrect spec.defaultUserPolicies: %w", err)
+		}
+	}
 
 	sus.visitAll(func(user *vmv1beta1.VMUser) bool {
-		userCfg, err := genUserCfg(user, crdCache, cb)
+		userCfg, err := genUserCfg(user, cr.Spec.DefaultUserPolicies, crdCache, sus.crdSelectorURLs[fmt.Sprintf("%s/%s", user.Namespace, user.Name)], cb)
 		if err != nil {
+			if goerrors.Is(err, errNoSelectedTargets) {
+				// user has no routes, status warning is already set during crdSelector targets fetch
+				return true
+			}
 			user.Status.CurrentSyncError = err.Error()
 			return false
 		}
 		cfgUsers = append(cfgUsers, userCfg)
+		// keep previous password valid during overlap window of password rotation
+		if prevPassword, ok := sus.previousPasswords[fmt.Sprintf("%s/%s", user.Namespace, user.Name)]; ok {
+			prevUserCfg := make(yaml.MapSlice, 0, len(userCfg))
+			for _, item := range userCfg {
+				if item.Key == "password" {
+					item.Value = prevPassword
+				}
+				prevUserCfg = append(prevUserCfg, item)
+			}
+			cfgUsers = append(cfgUsers, prevUserCfg)
+		}
 		return true
 	})
 
@@ -458,6 +642,9 @@ func appendIfNotEmpty(src []string, key string, origin yaml.MapSlice) yaml.MapSl
 func buildUnauthorizedConfig(cr *vmv1beta1.VMAuth, cb *build.TLSConfigBuilder) ([]yaml.MapItem, error) {
 	var result []yaml.MapItem
 
+	if cr.Spec.UnauthorizedUserAccessSpec != nil && len(cr.Spec.UnauthorizedAccessConfig) > 0 {
+		return nil, fmt.Errorf("at most one option can be used `spec.unauthorizedAccessConfig` or `spec.unauthorizedUserAccessSpec`, got both")
+	}
 	switch {
 	case cr.Spec.UnauthorizedUserAccessSpec != nil:
 		uua := cr.Spec.UnauthorizedUserAccessSpec
@@ -493,7 +680,10 @@ func buildUnauthorizedConfig(cr *vmv1beta1.VMAuth, cb *build.TLSConfigBuilder) (
 	case len(cr.Spec.UnauthorizedAccessConfig) > 0:
 		// Deprecated and will be removed at v1.0
 		var urlMapYAML []yaml.MapSlice
-		for _, uc := range cr.Spec.UnauthorizedAccessConfig {
+		for idx, uc := range cr.Spec.UnauthorizedAccessConfig {
+			if err := uc.Validate(); err != nil {
+				return nil, fmt.Errorf("incorrect spec.unauthorizedAccessConfig at idx=%d: %w", idx, err)
+			}
 			urlMap := appendIfNotEmpty(uc.SrcPaths, "src_paths", yaml.MapSlice{})
 			urlMap = appendIfNotEmpty(uc.SrcHosts, "src_hosts", urlMap)
 			urlMap = appendIfNotEmpty(uc.URLPrefix, "url_prefix", urlMap)
@@ -503,6 +693,9 @@ func buildUnauthorizedConfig(cr *vmv1beta1.VMAuth, cb *build.TLSConfigBuilder) (
 		}
 		result = append(result, yaml.MapItem{Key: "url_map", Value: urlMapYAML})
 
+		if err := cr.Spec.VMUserConfigOptions.Validate(); err != nil {
+			return nil, fmt.Errorf("incorrect spec.unauthorizedAccessConfig options: %w", err)
+		}
 		var err error
 		result, err = addUserConfigOptionToYaml(result, cr.Spec.VMUserConfigOptions, cb)
 		if err != nil {
@@ -623,6 +816,42 @@ func addUserConfigOptionToYaml(dst yaml.MapSlice, opt vmv1beta1.VMUserConfigOpti
 	return dst, nil
 }
 
+// applyDefaultUserPolicies merges VMAuth default user policies into the given user options.
+// Options defined at VMUser take precedence over defaults, except deny_list, which is merged with default one
+func applyDefaultUserPolicies(opts vmv1beta1.VMUserConfigOptions, dp *vmv1beta1.VMAuthDefaultUserPolicies) (vmv1beta1.VMUserConfigOptions, error) {
+	if dp == nil {
+		return opts, nil
+	}
+	if dp.Enforce {
+		if err := dp.ValidateUserIPFilters(opts.IPFilters); err != nil {
+			return opts, fmt.Errorf("user violates VMAuth default user policies: %w", err)
+		}
+	}
+	if len(opts.IPFilters.AllowList) == 0 {
+		opts.IPFilters.AllowList = dp.IPFilters.AllowList
+	}
+	// deny_list only narrows access, so default entries are always kept
+	if len(dp.IPFilters.DenyList) > 0 {
+		denyList := slices.Clone(dp.IPFilters.DenyList)
+		for _, v := range opts.IPFilters.DenyList {
+			if !slices.Contains(denyList, v) {
+				denyList = append(denyList, v)
+			}
+		}
+		opts.IPFilters.DenyList = denyList
+	}
+	if opts.MaxConcurrentRequests == nil {
+		opts.MaxConcurrentRequests = dp.MaxConcurrentRequests
+	}
+	if opts.DiscoverBackendIPs == nil {
+		opts.DiscoverBackendIPs = dp.DiscoverBackendIPs
+	}
+	if len(opts.RetryStatusCodes) == 0 {
+		opts.RetryStatusCodes = dp.RetryStatusCodes
+	}
+	return opts, nil
+}
+
 // AddToYaml conditionally adds ip filters to dst yaml
 func addIPFiltersToYaml(dst yaml.MapSlice, ipf vmv1beta1.VMUserIPFilters) yaml.MapSlice {
 	ipFilters := yaml.MapSlice{}
@@ -644,12 +873,50 @@ func addIPFiltersToYaml(dst yaml.MapSlice, ipf vmv1beta1.VMUserIPFilters) yaml.M
 	return dst
 }
 
+// addTargetPathSuffix joins path of the given suffix with urlPrefix path and merges query params
+func addTargetPathSuffix(urlPrefix, suffix string) (string, error) {
+	parsedSuffix, err := url.Parse(suffix)
+	if err != nil {
+		return "", fmt.Errorf("cannot parse targetPath: %q, err: %w", suffix, err)
+	}
+	parsedURLPrefix, err := url.Parse(urlPrefix)
+	if err != nil {
+		return "", fmt.Errorf("cannot parse urlPrefix: %q,err: %w", urlPrefix, err)
+	}
+	parsedURLPrefix.Path = path.Join(parsedURLPrefix.Path, parsedSuffix.Path)
+	suffixQuery := parsedSuffix.Query()
+	// update query params if needed.
+	if len(suffixQuery) > 0 {
+		urlQ := parsedURLPrefix.Query()
+		for k, v := range suffixQuery {
+			urlQ[k] = v
+		}
+		parsedURLPrefix.RawQuery = urlQ.Encode()
+	}
+	return parsedURLPrefix.String(), nil
+}
+
 // generates routing config for given target refs
-func genURLMaps(userName string, refs []vmv1beta1.TargetRef, result yaml.MapSlice, crdURLCache map[string]string) (yaml.MapSlice, error) {
+// crdSelectorURLs holds urls of objects selected by crdSelector refs by ref idx
+func genURLMaps(userName string, refs []vmv1beta1.TargetRef, result yaml.MapSlice, crdURLCache map[string]string, crdSelectorURLs map[int][]string) (yaml.MapSlice, error) {
 	var urlMaps []yaml.MapSlice
-	handleRef := func(ref vmv1beta1.TargetRef) ([]string, error) {
+	handleRef := func(idx int) ([]string, error) {
+		ref := refs[idx]
+		if err := ref.URLMapCommon.Validate(); err != nil {
+			return nil, fmt.Errorf("incorrect targetRef options for user: %s: %w", userName, err)
+		}
 		var urlPrefixes []string
 		switch {
+		case ref.CRDSelector != nil:
+			selectedURLs, ok := crdSelectorURLs[idx]
+			if !ok {
+				return nil, fmt.Errorf("cannot find crdSelector targets at ref idx=%d, for user: %s", idx, userName)
+			}
+			if len(selectedURLs) == 0 {
+				return nil, errNoSelectedTargets
+			}
+			// target_path_suffix is already applied to each selected object url
+			return selectedURLs, nil
 		case ref.CRD != nil:
 			urlPrefix := crdURLCache[ref.CRD.AsKey()]
 			if urlPrefix == "" {
@@ -665,26 +932,12 @@ func genURLMaps(userName string, refs []vmv1beta1.TargetRef, result yaml.MapSlic
 		}
 
 		if ref.TargetPathSuffix != "" {
-			parsedSuffix, err := url.Parse(ref.TargetPathSuffix)
-			if err != nil {
-				return nil, fmt.Errorf("cannot parse targetPath: %q, err: %w", ref.TargetPathSuffix, err)
-			}
 			for idx, urlPrefix := range urlPrefixes {
-				parsedURLPrefix, err := url.Parse(urlPrefix)
+				u, err := addTargetPathSuffix(urlPrefix, ref.TargetPathSuffix)
 				if err != nil {
-					return nil, fmt.Errorf("cannot parse urlPrefix: %q,err: %w", urlPrefix, err)
+					return nil, err
 				}
-				parsedURLPrefix.Path = path.Join(parsedURLPrefix.Path, parsedSuffix.Path)
-				suffixQuery := parsedSuffix.Query()
-				// update query params if needed.
-				if len(suffixQuery) > 0 {
-					urlQ := parsedURLPrefix.Query()
-					for k, v := range suffixQuery {
-						urlQ[k] = v
-					}
-					parsedURLPrefix.RawQuery = urlQ.Encode()
-				}
-				urlPrefixes[idx] = parsedURLPrefix.String()
+				urlPrefixes[idx] = u
 			}
 		}
 		return urlPrefixes, nil
@@ -707,7 +960,7 @@ func genURLMaps(userName string, refs []vmv1beta1.TargetRef, result yaml.MapSlic
 		// special case, use different config syntax.
 		if isDefaultRoute {
 			ref := refs[0]
-			urlPrefix, err := handleRef(ref)
+			urlPrefix, err := handleRef(0)
 			if err != nil {
 				return result, fmt.Errorf("cannot build urlPrefix for one ref, err: %w", err)
 			}
@@ -718,16 +971,29 @@ func genURLMaps(userName string, refs []vmv1beta1.TargetRef, result yaml.MapSlic
 
 	}
 
+	var hasEmptySelectors bool
 	for i := range refs {
 		var urlMap yaml.MapSlice
 		ref := refs[i]
-		if ref.Static == nil && ref.CRD == nil {
+		if ref.Static == nil && ref.CRD == nil && ref.CRDSelector == nil {
 			continue
 		}
-		urlPrefix, err := handleRef(ref)
+		urlPrefix, err := handleRef(i)
 		if err != nil {
+			if goerrors.Is(err, errNoSelectedTargets) {
+				// skip route without targets
+				hasEmptySelectors = true
+				continue
+			}
 			return result, err
 		}
+		var kind string
+		switch {
+		case ref.CRD != nil:
+			kind = ref.CRD.Kind
+		case ref.CRDSelector != nil:
+			kind = ref.CRDSelector.Kind
+		}
 
 		paths := ref.Paths
 		switch len(paths) {
@@ -735,9 +1001,9 @@ func genURLMaps(userName string, refs []vmv1beta1.TargetRef, result yaml.MapSlic
 			// special case for
 			// https://github.com/VictoriaMetrics/operator/issues/379
 			switch {
-			case len(refs) > 1 && ref.CRD != nil && ref.CRD.Kind == "VMCluster/vminsert":
+			case len(refs) > 1 && kind == "VMCluster/vminsert":
 				paths = addVMInsertPaths(paths)
-			case len(refs) > 1 && ref.CRD != nil && ref.CRD.Kind == "VMCluster/vmselect":
+			case len(refs) > 1 && kind == "VMCluster/vmselect":
 				paths = addVMSelectPaths(paths)
 			default:
 				paths = append(paths, "/.*")
@@ -795,6 +1061,9 @@ func genURLMaps(userName string, refs []vmv1beta1.TargetRef, result yaml.MapSlic
 		urlMaps = append(urlMaps, urlMap)
 	}
 	if len(urlMaps) == 0 {
+		if hasEmptySelectors {
+			return nil, errNoSelectedTargets
+		}
 		return nil, fmt.Errorf("user must has at least 1 url target")
 	}
 	result = append(result, yaml.MapItem{Key: "url_map", Value: urlMaps})
@@ -803,10 +1072,10 @@ func genURLMaps(userName string, refs []vmv1beta1.TargetRef, result yaml.MapSlic
 
 // this function mutates user and fills missing fields,
 // such password or username.
-func genUserCfg(user *vmv1beta1.VMUser, crdURLCache map[string]string, cb *build.TLSConfigBuilder) (yaml.MapSlice, error) {
+func genUserCfg(user *vmv1beta1.VMUser, defaultPolicies *vmv1beta1.VMAuthDefaultUserPolicies, crdURLCache map[string]string, crdSelectorURLs map[int][]string, cb *build.TLSConfigBuilder) (yaml.MapSlice, error) {
 	var r yaml.MapSlice
 
-	r, err := genURLMaps(user.Name, user.Spec.TargetRefs, r, crdURLCache)
+	r, err := genURLMaps(user.Name, user.Spec.TargetRefs, r, crdURLCache, crdSelectorURLs)
 	if err != nil {
 		return nil, fmt.Errorf("cannot generate urlMaps for user: %w", err)
 	}
@@ -833,7 +1102,11 @@ func genUserCfg(user *vmv1beta1.VMUser, crdURLCache map[string]string, cb *build
 	if user.Spec.BearerToken != nil {
 		token = *user.Spec.BearerToken
 	}
-	r, err = addUserConfigOptionToYaml(r, user.Spec.VMUserConfigOptions, cb)
+	opts, err := applyDefaultUserPolicies(user.Spec.VMUserConfigOptions, defaultPolicies)
+	if err != nil {
+		return nil, err
+	}
+	r, err = addUserConfigOptionToYaml(r, opts, cb)
 	if err != nil {
 		return nil, err
 	}
@@ -935,6 +1208,13 @@ func buildVMUserSecret(src *vmv1beta1.VMUser) (*corev1.Secret, error) {
 			return nil, fmt.Errorf("cannot generate password for user=%q: %w", src.Name, err)
 		}
 		src.Spec.Password = ptr.To(pwd)
+		// newly generated password must not be rotated by the current rotateNow trigger
+		if src.Spec.PasswordRotation != nil && src.Spec.PasswordRotation.RotateNow != "" {
+			if s.Annotations == nil {
+				s.Annotations = make(map[string]string)
+			}
+			s.Annotations[vmUserPasswordRotateNowAnnotation] = src.Spec.PasswordRotation.RotateNow
+		}
 	}
 	if src.Spec.Name != nil {
 		s.Data["name"] = []byte(*src.Spec.Name)
@@ -487,14 +487,106 @@ username: basic
 password: pass
 `,
 		},
+		{
+			name: "with crd and static targets options",
+			args: args{
+				user: &vmv1beta1.VMUser{
+					Spec: vmv1beta1.VMUserSpec{
+						UserName: ptr.To("basic"),
+						Password: ptr.To("pass"),
+						TargetRefs: []vmv1beta1.TargetRef{
+							{
+								CRD: &vmv1beta1.CRDRef{
+									Kind:      "VMCluster/vmselect",
+									Name:      "main",
+									Namespace: "monitoring",
+								},
+								Paths: []string{"/tenant-a/select/.*"},
+								URLMapCommon: vmv1beta1.URLMapCommon{
+									RequestHeaders:         []string{"X-Scope-OrgID: tenant-a"},
+									ResponseHeaders:        []string{"X-Backend: vmselect"},
+									RetryStatusCodes:       []int{502, 503},
+									LoadBalancingPolicy:    ptr.To("first_available"),
+									DropSrcPathPrefixParts: ptr.To(1),
+								},
+							},
+							{
+								Static: &vmv1beta1.StaticRef{URLs: []string{"http://vminsert-1:8480", "http://vminsert-2:8480"}},
+								Paths:  []string{"/tenant-b/insert/.*"},
+								URLMapCommon: vmv1beta1.URLMapCommon{
+									RequestHeaders:         []string{"X-Scope-OrgID: tenant-b"},
+									RetryStatusCodes:       []int{429},
+									LoadBalancingPolicy:    ptr.To("least_loaded"),
+									DropSrcPathPrefixParts: ptr.To(2),
+								},
+							},
+						},
+					},
+				},
+				crdURLCache: map[string]string{
+					"VMCluster/vmselect/monitoring/main": "http://vmselect-main.monitoring.svc:8481",
+				},
+			},
+			want: `url_map:
+- url_prefix:
+  - http://vmselect-main.monitoring.svc:8481
+  src_paths:
+  - /tenant-a/select/.*
+  headers:
+  - 'X-Scope-OrgID: tenant-a'
+  response_headers:
+  - 'X-Backend: vmselect'
+  retry_status_codes:
+  - 502
+  - 503
+  drop_src_path_prefix_parts: 1
+  load_balancing_policy: first_available
+- url_prefix:
+  - http://vminsert-1:8480
+  - http://vminsert-2:8480
+  src_paths:
+  - /tenant-b/insert/.*
+  headers:
+  - 'X-Scope-OrgID: tenant-b'
+  retry_status_codes:
+  - 429
+  drop_src_path_prefix_parts: 2
+  load_balancing_policy: least_loaded
+username: basic
+password: pass
+`,
+		},
+		{
+			name: "incorrect target options",
+			args: args{
+				user: &vmv1beta1.VMUser{
+					Spec: vmv1beta1.VMUserSpec{
+						UserName: ptr.To("basic"),
+						TargetRefs: []vmv1beta1.TargetRef{
+							{
+								Static: &vmv1beta1.StaticRef{URL: "http://vmselect"},
+								Paths:  []string{"/select/.*"},
+								URLMapCommon: vmv1beta1.URLMapCommon{
+									RequestHeaders: []string{"X-Scope-OrgID"},
+								},
+							},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := genUserCfg(tt.args.user, tt.args.crdURLCache, &build.TLSConfigBuilder{})
+			got, err := genUserCfg(tt.args.user, nil, tt.args.crdURLCache, nil, &build.TLSConfigBuilder{})
 			if (err != nil) != tt.wantErr {
 				t.Errorf("genUserCfg() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
+			if tt.wantErr {
+				return
+			}
 			szd, err := yaml.Marshal(got)
 			if err != nil {
 				t.Fatalf("cannot serialize resutl: %v", err)
@@ -745,6 +837,49 @@ func Test_buildVMAuthConfig(t *testing.T) {
 		wantErr           bool
 		predefinedObjects []runtime.Object
 	}{
+		{
+			name: "both unauthorized access configs",
+			args: args{
+				vmauth: &vmv1beta1.VMAuth{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-vmauth",
+						Namespace: "default",
+					},
+					Spec: vmv1beta1.VMAuthSpec{
+						UnauthorizedAccessConfig: []vmv1beta1.UnauthorizedAccessConfigURLMap{
+							{
+								SrcPaths:  []string{"/"},
+								URLPrefix: []string{"http://some-url"},
+							},
+						},
+						UnauthorizedUserAccessSpec: &vmv1beta1.VMAuthUnauthorizedUserAccessSpec{
+							URLPrefix: []string{"http://some-url"},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "incorrect unauthorized access ip filters",
+			args: args{
+				vmauth: &vmv1beta1.VMAuth{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-vmauth",
+						Namespace: "default",
+					},
+					Spec: vmv1beta1.VMAuthSpec{
+						UnauthorizedUserAccessSpec: &vmv1beta1.VMAuthUnauthorizedUserAccessSpec{
+							URLPrefix: []string{"http://some-url"},
+							VMUserConfigOptions: vmv1beta1.VMUserConfigOptions{
+								IPFilters: vmv1beta1.VMUserIPFilters{AllowList: []string{"10.0.0.1/33"}},
+							},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
 		{
 			name: "simple cfg",
 			args: args{
@@ -809,6 +944,111 @@ func Test_buildVMAuthConfig(t *testing.T) {
 - url_prefix:
   - http://vmagent-test.default.svc:8429
   bearer_token: bearer-token-2
+`,
+		},
+		{
+			name: "default user policies",
+			args: args{
+				vmauth: &vmv1beta1.VMAuth{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-vmauth",
+						Namespace: "default",
+					},
+					Spec: vmv1beta1.VMAuthSpec{
+						SelectAllByDefault: true,
+						DefaultUserPolicies: &vmv1beta1.VMAuthDefaultUserPolicies{
+							IPFilters: vmv1beta1.VMUserIPFilters{
+								AllowList: []string{"10.0.0.0/16"},
+								DenyList:  []string{"10.0.0.1"},
+							},
+							MaxConcurrentRequests: ptr.To(10),
+							DiscoverBackendIPs:    ptr.To(true),
+							RetryStatusCodes:      []int{502, 503},
+							Enforce:               true,
+						},
+					},
+				},
+			},
+			predefinedObjects: []runtime.Object{
+				&vmv1beta1.VMUser{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "user-1",
+						Namespace: "default",
+					},
+					Spec: vmv1beta1.VMUserSpec{
+						BearerToken: ptr.To("bearer-1"),
+						TargetRefs: []vmv1beta1.TargetRef{
+							{
+								Static: &vmv1beta1.StaticRef{URL: "http://some-static"},
+							},
+						},
+					},
+				},
+				&vmv1beta1.VMUser{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "user-2",
+						Namespace: "default",
+					},
+					Spec: vmv1beta1.VMUserSpec{
+						BearerToken: ptr.To("bearer-2"),
+						TargetRefs: []vmv1beta1.TargetRef{
+							{
+								Static: &vmv1beta1.StaticRef{URL: "http://some-static"},
+							},
+						},
+						VMUserConfigOptions: vmv1beta1.VMUserConfigOptions{
+							IPFilters:             vmv1beta1.VMUserIPFilters{AllowList: []string{"10.0.1.0/24", "10.0.2.5"}, DenyList: []string{"10.0.1.1", "10.0.0.1"}},
+							MaxConcurrentRequests: ptr.To(50),
+						},
+					},
+				},
+				&vmv1beta1.VMUser{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "user-3",
+						Namespace: "default",
+					},
+					Spec: vmv1beta1.VMUserSpec{
+						BearerToken: ptr.To("bearer-3"),
+						TargetRefs: []vmv1beta1.TargetRef{
+							{
+								Static: &vmv1beta1.StaticRef{URL: "http://some-static"},
+							},
+						},
+						VMUserConfigOptions: vmv1beta1.VMUserConfigOptions{
+							IPFilters: vmv1beta1.VMUserIPFilters{AllowList: []string{"10.0.0.0/8"}},
+						},
+					},
+				},
+			},
+			want: `users:
+- url_prefix:
+  - http://some-static
+  ip_filters:
+    allow_list:
+    - 10.0.0.0/16
+    deny_list:
+    - 10.0.0.1
+  discover_backend_ips: true
+  retry_status_codes:
+  - 502
+  - 503
+  max_concurrent_requests: 10
+  bearer_token: bearer-1
+- url_prefix:
+  - http://some-static
+  ip_filters:
+    allow_list:
+    - 10.0.1.0/24
+    - 10.0.2.5
+    deny_list:
+    - 10.0.0.1
+    - 10.0.1.1
+  discover_backend_ips: true
+  retry_status_codes:
+  - 502
+  - 503
+  max_concurrent_requests: 50
+  bearer_token: bearer-2
 `,
 		},
 		{
@@ -2206,3 +2446,78 @@ unauthorized_user:
 		})
 	}
 }
+
+func TestBuildVMAuthConfigCRDSelector(t *testing.T) {
+	vmauth := &vmv1beta1.VMAuth{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+		Spec: vmv1beta1.VMAuthSpec{
+			SelectAllByDefault: true,
+		},
+	}
+	newVMSingle := func(name, namespace string, labels map[string]string) *vmv1beta1.VMSingle {
+		return &vmv1beta1.VMSingle{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: labels}}
+	}
+	fclient := k8stools.GetTestClientWithObjects([]runtime.Object{
+		newVMSingle("b", "default", map[string]string{"team": "infra", "tenant": "2"}),
+		newVMSingle("a", "default", map[string]string{"team": "infra", "tenant": "1"}),
+		newVMSingle("c", "default", map[string]string{"team": "dev", "tenant": "3"}),
+		newVMSingle("d", "other", map[string]string{"team": "infra", "tenant": "4"}),
+		&vmv1beta1.VMUser{
+			ObjectMeta: metav1.ObjectMeta{Name: "selected", Namespace: "default"},
+			Spec: vmv1beta1.VMUserSpec{
+				Password: ptr.To("selected-password"),
+				TargetRefs: []vmv1beta1.TargetRef{{
+					CRDSelector: &vmv1beta1.CRDSelectorRef{
+						Kind:     "VMSingle",
+						Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "infra"}},
+					},
+					TargetPathSuffix: "/tenant/{{ .Labels.tenant }}",
+				}},
+			},
+		},
+		&vmv1beta1.VMUser{
+			ObjectMeta: metav1.ObjectMeta{Name: "empty", Namespace: "default"},
+			Spec: vmv1beta1.VMUserSpec{
+				Password: ptr.To("empty-password"),
+				TargetRefs: []vmv1beta1.TargetRef{{
+					CRDSelector: &vmv1beta1.CRDSelectorRef{Kind: "VMAgent"},
+				}},
+			},
+		},
+	})
+	ctx := context.TODO()
+	sus, err := selectVMUsers(ctx, fclient, vmauth)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	got, err := buildVMAuthConfig(ctx, fclient, vmauth, sus, map[string]string{})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var cfg struct {
+		Users []struct {
+			Username  string   `yaml:"username"`
+			URLPrefix []string `yaml:"url_prefix"`
+		} `yaml:"users"`
+	}
+	if err := yaml.Unmarshal(got, &cfg); err != nil {
+		t.Fatalf("cannot parse config: %s", err)
+	}
+	if assert.Len(t, cfg.Users, 1, string(got)) {
+		assert.Equal(t, "selected", cfg.Users[0].Username)
+		assert.Equal(t, []string{
+			"http://vmsingle-a.default.svc:8429/tenant/1",
+			"http://vmsingle-b.default.svc:8429/tenant/2",
+		}, cfg.Users[0].URLPrefix)
+	}
+	// user without matched objects isn't broken, but has a warning
+	for _, u := range sus.brokenVMUsers {
+		t.Errorf("unexpected broken user=%s: %s", u.Name, u.Status.CurrentSyncError)
+	}
+	for _, u := range sus.users {
+		if u.Name == "empty" {
+			assert.Equal(t, `crdSelector for kind="VMAgent" at ref idx=0 matched no objects`, u.Status.CurrentSyncWarning)
+			assert.Empty(t, u.Status.CurrentSyncError)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package vmcluster
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"path"
+	"slices"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/reconcile"
+)
+
+const internalTLSVolumeName = "internal-tls"
+
+// internalTLSSecretKeys defines keys of the Secret with internal TLS certificate
+// it matches keys of the Secret generated by cert-manager
+var internalTLSSecretKeys = []string{"ca.crt", "tls.crt", "tls.key"}
+
+// addInternalTLSToPodSpec mounts internal TLS certificate of the given component
+// and adds -cluster.tls* flags
+func addInternalTLSToPodSpec(cr *vmv1beta1.VMCluster, component string, args []string, volumes []corev1.Volume, mounts []corev1.VolumeMount) ([]string, []corev1.Volume, []corev1.VolumeMount) {
+	if cr.Spec.InternalTLS == nil {
+		return args, volumes, mounts
+	}
+	secretName := cr.GetInternalTLSSecretName(component)
+	mountPath := path.Join(vmv1beta1.SecretsDir, secretName)
+	volumes = append(volumes, corev1.Volume{
+		Name: internalTLSVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{
+				SecretName: secretName,
+			},
+		},
+	})
+	mounts = append(mounts, corev1.VolumeMount{
+		Name:      internalTLSVolumeName,
+		ReadOnly:  true,
+		MountPath: mountPath,
+	})
+	args = append(args,
+		"-cluster.tls=true",
+		fmt.Sprintf("-cluster.tlsCAFile=%s", path.Join(mountPath, "ca.crt")),
+		fmt.Sprintf("-cluster.tlsCertFile=%s", path.Join(mountPath, "tls.crt")),
+		fmt.Sprintf("-cluster.tlsKeyFile=%s", path.Join(mountPath, "tls.key")),
+	)
+	return args, volumes, mounts
+}
+
+// internalTLSChecksum returns checksum of the internal TLS certificate for the given component
+// it's set at pod template annotations in order to trigger rolling restart on certificate rotation
+func internalTLSChecksum(ctx context.Context, rclient client.Client, cr *vmv1beta1.VMCluster, component string) (string, error) {
+	if cr.Spec.InternalTLS == nil {
+		return "", nil
+	}
+	var secret corev1.Secret
+	nsn := types.NamespacedName{Namespace: cr.Namespace, Name: cr.GetInternalTLSSecretName(component)}
+	if err := rclient.Get(ctx, nsn, &secret); err != nil {
+		if errors.IsNotFound(err) {
+			return "", fmt.Errorf("internal TLS Secret=%s for %s doesn't exist yet", nsn.Name, component)
+		}
+		return "", fmt.Errorf("cannot get internal TLS Secret=%s: %w", nsn.Name, err)
+	}
+	h := fnv.New64a()
+	for _, k := range internalTLSSecretKeys {
+		v, ok := secret.Data[k]
+		if !ok {
+			return "", fmt.Errorf("internal TLS Secret=%s must contain key=%q", nsn.Name, k)
+		}
+		h.Write([]byte(k)) //nolint:errcheck
+		h.Write(v)         //nolint:errcheck
+	}
+	return strconv.FormatUint(h.Sum64(), 16), nil
+}
+
+// setInternalTLSChecksum sets internal TLS certificate checksum at the given pod template
+func setInternalTLSChecksum(tmpl *corev1.PodTemplateSpec, checksum string) {
+	if checksum == "" {
+		return
+	}
+	if tmpl.Annotations == nil {
+		tmpl.Annotations = make(map[string]string)
+	}
+	tmpl.Annotations[vmv1beta1.VMClusterInternalTLSChecksumAnnotation] = checksum
+}
+
+// internalTLSDNSNames returns dns names of the given service pods
+func internalTLSDNSNames(cr *vmv1beta1.VMCluster, serviceName string) []string {
+	names := []string{
+		serviceName,
+		fmt.Sprintf("%s.%s", serviceName, cr.Namespace),
+		fmt.Sprintf("%s.%s.svc", serviceName, cr.Namespace),
+		fmt.Sprintf("*.%s.%s", serviceName, cr.Namespace),
+		fmt.Sprintf("*.%s.%s.svc", serviceName, cr.Namespace),
+	}
+	if cr.Spec.ClusterDomainName != "" {
+		names = append(names,
+			fmt.Sprintf("%s.%s.svc.%s", serviceName, cr.Namespace, cr.Spec.ClusterDomainName),
+			fmt.Sprintf("*.%s.%s.svc.%s", serviceName, cr.Namespace, cr.Spec.ClusterDomainName),
+		)
+	}
+	return names
+}
+
+// buildInternalTLSCertificate builds cert-manager Certificate for the given cluster component
+// certificate is valid for pods of all the given services
+func buildInternalTLSCertificate(cr *vmv1beta1.VMCluster, component string, serviceNames []string, selectorLabels map[string]string) *unstructured.Unstructured {
+	tls := cr.Spec.InternalTLS
+	issuerRef := map[string]any{
+		"name": tls.IssuerRef.Name,
+	}
+	if tls.IssuerRef.Kind != "" {
+		issuerRef["kind"] = tls.IssuerRef.Kind
+	}
+	if tls.IssuerRef.Group != "" {
+		issuerRef["group"] = tls.IssuerRef.Group
+	}
+	var dnsNames []any
+	for _, serviceName := range serviceNames {
+		for _, name := range internalTLSDNSNames(cr, serviceName) {
+			dnsNames = append(dnsNames, name)
+		}
+	}
+	spec := map[string]any{
+		"secretName": cr.GetInternalTLSSecretName(component),
+		"issuerRef":  issuerRef,
+		"commonName": serviceNames[0],
+		"dnsNames":   dnsNames,
+		"usages":     []any{"digital signature", "key encipherment", "server auth", "client auth"},
+	}
+	if tls.Duration != "" {
+		spec["duration"] = tls.Duration
+	}
+	if tls.RenewBefore != "" {
+		spec["renewBefore"] = tls.RenewBefore
+	}
+	cert := &unstructured.Unstructured{Object: map[string]any{"spec": spec}}
+	cert.SetGroupVersionKind(reconcile.CertificateGVK)
+	cert.SetName(cr.GetInternalTLSSecretName(component))
+	cert.SetNamespace(cr.Namespace)
+	cert.SetLabels(cr.FinalLabels(selectorLabels))
+	cert.SetOwnerReferences(cr.AsOwner())
+	return cert
+}
+
+// buildInternalTLSCertificates builds cert-manager Certificates for all cluster components
+func buildInternalTLSCertificates(cr *vmv1beta1.VMCluster) []*unstructured.Unstructured {
+	if cr.Spec.InternalTLS == nil || cr.Spec.InternalTLS.IssuerRef == nil {
+		return nil
+	}
+	var certs []*unstructured.Unstructured
+	if cr.Spec.VMStorage != nil {
+		certs = append(certs, buildInternalTLSCertificate(cr, vmv1beta1.VMClusterComponentVMStorage, []string{cr.GetVMStorageName()}, cr.VMStorageSelectorLabels()))
+	}
+	if cr.Spec.VMSelect != nil {
+		certs = append(certs, buildInternalTLSCertificate(cr, vmv1beta1.VMClusterComponentVMSelect, cr.VMSelectStatefulSetNames(), cr.VMSelectSelectorLabels()))
+	}
+	if cr.Spec.VMInsert != nil {
+		certs = append(certs, buildInternalTLSCertificate(cr, vmv1beta1.VMClusterComponentVMInsert, []string{cr.GetVMInsertName()}, cr.VMInsertSelectorLabels()))
+	}
+	return certs
+}
+
+// createOrUpdateInternalTLSCertificates reconciles cert-manager Certificates for cluster components
+// and removes Certificates, which are no longer needed
+func createOrUpdateInternalTLSCertificates(ctx context.Context, rclient client.Client, cr, prevCR *vmv1beta1.VMCluster) error {
+	newCerts := buildInternalTLSCertificates(cr)
+	newNames := make(map[string]struct{}, len(newCerts))
+	for _, newCert := range newCerts {
+		newNames[newCert.GetName()] = struct{}{}
+		if err := reconcile.Certificate(ctx, rclient, newCert); err != nil {
+			return err
+		}
+	}
+	if prevCR == nil {
+		return nil
+	}
+	for _, prevCert := range buildInternalTLSCertificates(prevCR) {
+		if _, ok := newNames[prevCert.GetName()]; ok {
+			continue
+		}
+		if err := reconcile.RemoveCertificate(ctx, rclient, prevCert.GetNamespace(), prevCert.GetName()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// internalTLSSecretNames returns names of the Secrets with internal TLS certificates used by cluster components
+func internalTLSSecretNames(cr *vmv1beta1.VMCluster) []string {
+	if cr.Spec.InternalTLS == nil {
+		return nil
+	}
+	if cr.Spec.InternalTLS.SecretName != "" {
+		return []string{cr.Spec.InternalTLS.SecretName}
+	}
+	var names []string
+	for _, component := range cr.UpdateOrder() {
+		names = append(names, cr.GetInternalTLSSecretName(component))
+	}
+	return names
+}
+
+// IsInternalTLSSecret checks if the given Secret holds internal TLS certificate of the cluster
+func IsInternalTLSSecret(cr *vmv1beta1.VMCluster, secretName string) bool {
+	return slices.Contains(internalTLSSecretNames(cr), secretName)
+}
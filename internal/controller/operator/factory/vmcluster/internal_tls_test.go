@@ -0,0 +1,159 @@
+package vmcluster
+
+import (
+	"context"
+	"testing"
+
+	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/k8stools"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/reconcile"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestAddInternalTLSToPodSpec(t *testing.T) {
+	f := func(tls *vmv1beta1.VMClusterInternalTLS, wantSecretName string) {
+		t.Helper()
+		cr := &vmv1beta1.VMCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster", Namespace: "default"},
+			Spec: vmv1beta1.VMClusterSpec{
+				VMStorage:   &vmv1beta1.VMStorage{},
+				VMInsert:    &vmv1beta1.VMInsert{},
+				InternalTLS: tls,
+			},
+		}
+		storagePod, err := makePodSpecForVMStorage(context.TODO(), cr)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		insertPod, err := makePodSpecForVMInsert(cr)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		for _, pod := range []*corev1.PodTemplateSpec{storagePod, insertPod} {
+			secretName := wantSecretName
+			if secretName == "" {
+				secretName = pod.Spec.Containers[0].Name + "-cluster-internal-tls"
+			}
+			args := pod.Spec.Containers[0].Args
+			assert.Contains(t, args, "-cluster.tls=true")
+			assert.Contains(t, args, "-cluster.tlsCAFile=/etc/vm/secrets/"+secretName+"/ca.crt")
+			assert.Contains(t, args, "-cluster.tlsCertFile=/etc/vm/secrets/"+secretName+"/tls.crt")
+			assert.Contains(t, args, "-cluster.tlsKeyFile=/etc/vm/secrets/"+secretName+"/tls.key")
+			var found bool
+			for _, v := range pod.Spec.Volumes {
+				if v.Name == internalTLSVolumeName {
+					found = true
+					assert.Equal(t, secretName, v.Secret.SecretName)
+				}
+			}
+			assert.True(t, found, "internal tls volume must be added")
+		}
+	}
+
+	// pre-provisioned secret
+	f(&vmv1beta1.VMClusterInternalTLS{SecretName: "mtls"}, "mtls")
+
+	// cert-manager secret per component
+	f(&vmv1beta1.VMClusterInternalTLS{IssuerRef: &vmv1beta1.VMClusterCertManagerIssuerRef{Name: "ca-issuer"}}, "")
+}
+
+func TestInternalTLSChecksum(t *testing.T) {
+	cr := &vmv1beta1.VMCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster", Namespace: "default"},
+		Spec: vmv1beta1.VMClusterSpec{
+			VMStorage:   &vmv1beta1.VMStorage{},
+			InternalTLS: &vmv1beta1.VMClusterInternalTLS{SecretName: "mtls"},
+		},
+	}
+	ctx := context.TODO()
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "mtls", Namespace: "default"},
+		Data:       map[string][]byte{"ca.crt": []byte("ca"), "tls.crt": []byte("cert"), "tls.key": []byte("key")},
+	}
+	fclient := k8stools.GetTestClientWithObjects(nil)
+	if _, err := internalTLSChecksum(ctx, fclient, cr, vmv1beta1.VMClusterComponentVMStorage); err == nil {
+		t.Fatalf("expected error for missing secret")
+	}
+	if err := fclient.Create(ctx, secret); err != nil {
+		t.Fatalf("cannot create secret: %s", err)
+	}
+	checksum, err := internalTLSChecksum(ctx, fclient, cr, vmv1beta1.VMClusterComponentVMStorage)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.NotEmpty(t, checksum)
+
+	// rotated certificate must change checksum
+	secret.Data["tls.crt"] = []byte("rotated-cert")
+	if err := fclient.Update(ctx, secret); err != nil {
+		t.Fatalf("cannot update secret: %s", err)
+	}
+	rotatedChecksum, err := internalTLSChecksum(ctx, fclient, cr, vmv1beta1.VMClusterComponentVMStorage)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.NotEqual(t, checksum, rotatedChecksum)
+
+	assert.True(t, IsInternalTLSSecret(cr, "mtls"))
+	assert.False(t, IsInternalTLSSecret(cr, "other"))
+}
+
+func TestCreateOrUpdateInternalTLSCertificates(t *testing.T) {
+	cr := &vmv1beta1.VMCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster", Namespace: "default"},
+		Spec: vmv1beta1.VMClusterSpec{
+			VMStorage: &vmv1beta1.VMStorage{},
+			VMSelect:  &vmv1beta1.VMSelect{},
+			InternalTLS: &vmv1beta1.VMClusterInternalTLS{
+				IssuerRef: &vmv1beta1.VMClusterCertManagerIssuerRef{Name: "ca-issuer", Kind: "ClusterIssuer"},
+				Duration:  "2160h",
+			},
+		},
+	}
+	ctx := context.TODO()
+	fclient := k8stools.GetTestClientWithObjects([]runtime.Object{})
+	getCert := func(name string) (*unstructured.Unstructured, error) {
+		cert := &unstructured.Unstructured{}
+		cert.SetGroupVersionKind(reconcile.CertificateGVK)
+		err := fclient.Get(ctx, types.NamespacedName{Namespace: "default", Name: name}, cert)
+		return cert, err
+	}
+	if err := createOrUpdateInternalTLSCertificates(ctx, fclient, cr, nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	cert, err := getCert("vmstorage-cluster-internal-tls")
+	if err != nil {
+		t.Fatalf("cannot get vmstorage certificate: %s", err)
+	}
+	dnsNames, _, _ := unstructured.NestedStringSlice(cert.Object, "spec", "dnsNames")
+	assert.Contains(t, dnsNames, "*.vmstorage-cluster.default.svc")
+	secretName, _, _ := unstructured.NestedString(cert.Object, "spec", "secretName")
+	assert.Equal(t, "vmstorage-cluster-internal-tls", secretName)
+	issuerKind, _, _ := unstructured.NestedString(cert.Object, "spec", "issuerRef", "kind")
+	assert.Equal(t, "ClusterIssuer", issuerKind)
+	if _, err := getCert("vmselect-cluster-internal-tls"); err != nil {
+		t.Fatalf("cannot get vmselect certificate: %s", err)
+	}
+
+	// vmselect certificate must be removed with component
+	prevCR := cr.DeepCopy()
+	cr.Spec.VMSelect = nil
+	cr.Spec.InternalTLS.Duration = "720h"
+	if err := createOrUpdateInternalTLSCertificates(ctx, fclient, cr, prevCR); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := getCert("vmselect-cluster-internal-tls"); err == nil {
+		t.Fatalf("vmselect certificate must be removed")
+	}
+	cert, err = getCert("vmstorage-cluster-internal-tls")
+	if err != nil {
+		t.Fatalf("cannot get vmstorage certificate: %s", err)
+	}
+	duration, _, _ := unstructured.NestedString(cert.Object, "spec", "duration")
+	assert.Equal(t, "720h", duration)
+}
@@ -0,0 +1,133 @@
+package vmcluster
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
+)
+
+// setComponentStalledReason sets reason of the failed component update at the given VMCluster status
+// reason is cleared on successful update
+func setComponentStalledReason(cr *vmv1beta1.VMCluster, component string, updateErr error) {
+	var reason string
+	if updateErr != nil {
+		reason = updateErr.Error()
+	}
+	cs, ok := cr.Status.Components[component]
+	if !ok {
+		if reason == "" {
+			return
+		}
+		cs = &vmv1beta1.VMClusterComponentStatus{}
+		if cr.Status.Components == nil {
+			cr.Status.Components = make(map[string]*vmv1beta1.VMClusterComponentStatus)
+		}
+		cr.Status.Components[component] = cs
+	}
+	cs.Reason = reason
+}
+
+// UpdateComponentsStatus populates status of cluster components
+// with replicas state of the component StatefulSets and Deployments.
+// Last rollout time is changed only if component was rolled out since previous update
+// it allows to skip status update requests for not changed cluster
+func UpdateComponentsStatus(ctx context.Context, rclient client.Client, cr *vmv1beta1.VMCluster) error {
+	for _, component := range cr.UpdateOrder() {
+		var cs *vmv1beta1.VMClusterComponentStatus
+		var err error
+		switch component {
+		case vmv1beta1.VMClusterComponentVMStorage:
+			if cr.Spec.VMStorage == nil {
+				break
+			}
+			cs, err = statefulSetsComponentStatus(ctx, rclient, cr.Namespace, cr.VMStorageStatefulSetNames())
+		case vmv1beta1.VMClusterComponentVMSelect:
+			if cr.Spec.VMSelect == nil {
+				break
+			}
+			cs, err = statefulSetsComponentStatus(ctx, rclient, cr.Namespace, cr.VMSelectStatefulSetNames())
+		case vmv1beta1.VMClusterComponentVMInsert:
+			if cr.Spec.VMInsert == nil {
+				break
+			}
+			cs, err = deploymentComponentStatus(ctx, rclient, cr.Namespace, cr.GetVMInsertName())
+		}
+		if err != nil {
+			return fmt.Errorf("cannot get status of component=%s: %w", component, err)
+		}
+		if cs == nil {
+			delete(cr.Status.Components, component)
+			continue
+		}
+		prev := cr.Status.Components[component]
+		if prev != nil {
+			cs.Reason = prev.Reason
+			cs.LastRolloutTime = prev.LastRolloutTime
+		}
+		if isComponentRolledOut(cs) && (prev == nil || prev.LastRolloutTime == nil || !isComponentRolledOut(prev) || prev.ObservedGeneration != cs.ObservedGeneration) {
+			cs.LastRolloutTime = ptr.To(metav1.Now())
+		}
+		if cr.Status.Components == nil {
+			cr.Status.Components = make(map[string]*vmv1beta1.VMClusterComponentStatus)
+		}
+		cr.Status.Components[component] = cs
+	}
+	if len(cr.Status.Components) == 0 {
+		cr.Status.Components = nil
+	}
+	return nil
+}
+
+// isComponentRolledOut checks if all replicas of the component are updated and ready
+func isComponentRolledOut(cs *vmv1beta1.VMClusterComponentStatus) bool {
+	return cs.ObservedGeneration > 0 && cs.ReadyReplicas == cs.Replicas && cs.UpdatedReplicas == cs.Replicas
+}
+
+// statefulSetsComponentStatus builds status of the component from the given StatefulSets
+// replicas are summed and the lowest observed generation is reported
+func statefulSetsComponentStatus(ctx context.Context, rclient client.Client, namespace string, names []string) (*vmv1beta1.VMClusterComponentStatus, error) {
+	cs := &vmv1beta1.VMClusterComponentStatus{}
+	var found bool
+	for _, name := range names {
+		var sts appsv1.StatefulSet
+		if err := rclient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, &sts); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		cs.Replicas += ptr.Deref(sts.Spec.Replicas, 1)
+		cs.ReadyReplicas += sts.Status.ReadyReplicas
+		cs.UpdatedReplicas += sts.Status.UpdatedReplicas
+		if !found || sts.Status.ObservedGeneration < cs.ObservedGeneration {
+			cs.ObservedGeneration = sts.Status.ObservedGeneration
+		}
+		found = true
+	}
+	return cs, nil
+}
+
+// deploymentComponentStatus builds status of the component from the given Deployment
+func deploymentComponentStatus(ctx context.Context, rclient client.Client, namespace, name string) (*vmv1beta1.VMClusterComponentStatus, error) {
+	cs := &vmv1beta1.VMClusterComponentStatus{}
+	var dep appsv1.Deployment
+	if err := rclient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, &dep); err != nil {
+		if errors.IsNotFound(err) {
+			return cs, nil
+		}
+		return nil, err
+	}
+	cs.Replicas = ptr.Deref(dep.Spec.Replicas, 1)
+	cs.ReadyReplicas = dep.Status.ReadyReplicas
+	cs.UpdatedReplicas = dep.Status.UpdatedReplicas
+	cs.ObservedGeneration = dep.Status.ObservedGeneration
+	return cs, nil
+}
@@ -0,0 +1,82 @@
+package vmcluster
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/k8stools"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+)
+
+func TestUpdateComponentsStatus(t *testing.T) {
+	cr := &vmv1beta1.VMCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster", Namespace: "default"},
+		Spec: vmv1beta1.VMClusterSpec{
+			VMStorage: &vmv1beta1.VMStorage{},
+			VMInsert:  &vmv1beta1.VMInsert{},
+		},
+	}
+	storageSts := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: cr.GetVMStorageName(), Namespace: cr.Namespace},
+		Spec:       appsv1.StatefulSetSpec{Replicas: ptr.To[int32](3)},
+		Status:     appsv1.StatefulSetStatus{ObservedGeneration: 2, ReadyReplicas: 2, UpdatedReplicas: 3},
+	}
+	insertDep := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: cr.GetVMInsertName(), Namespace: cr.Namespace},
+		Spec:       appsv1.DeploymentSpec{Replicas: ptr.To[int32](2)},
+		Status:     appsv1.DeploymentStatus{ObservedGeneration: 1, ReadyReplicas: 2, UpdatedReplicas: 2},
+	}
+	ctx := context.TODO()
+	fclient := k8stools.GetTestClientWithObjects([]runtime.Object{storageSts, insertDep})
+
+	setComponentStalledReason(cr, vmv1beta1.VMClusterComponentVMStorage, fmt.Errorf("pod is not ready"))
+	if err := UpdateComponentsStatus(ctx, fclient, cr); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	storageStatus := cr.Status.Components[vmv1beta1.VMClusterComponentVMStorage]
+	assert.Equal(t, &vmv1beta1.VMClusterComponentStatus{
+		Replicas:           3,
+		ReadyReplicas:      2,
+		UpdatedReplicas:    3,
+		ObservedGeneration: 2,
+		Reason:             "pod is not ready",
+	}, storageStatus)
+	insertStatus := cr.Status.Components[vmv1beta1.VMClusterComponentVMInsert]
+	assert.Equal(t, int32(2), insertStatus.ReadyReplicas)
+	assert.NotNil(t, insertStatus.LastRolloutTime)
+	assert.NotContains(t, cr.Status.Components, vmv1beta1.VMClusterComponentVMSelect)
+
+	// not changed components must keep status as is
+	prevStatus := cr.Status.DeepCopy()
+	if err := UpdateComponentsStatus(ctx, fclient, cr); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.Equal(t, prevStatus.Components, cr.Status.Components)
+
+	// rolled out component must update last rollout time
+	storageSts.Status.ReadyReplicas = 3
+	if err := fclient.Status().Update(ctx, storageSts); err != nil {
+		t.Fatalf("cannot update statefulset: %s", err)
+	}
+	setComponentStalledReason(cr, vmv1beta1.VMClusterComponentVMStorage, nil)
+	if err := UpdateComponentsStatus(ctx, fclient, cr); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	storageStatus = cr.Status.Components[vmv1beta1.VMClusterComponentVMStorage]
+	assert.Empty(t, storageStatus.Reason)
+	assert.NotNil(t, storageStatus.LastRolloutTime)
+	assert.Equal(t, prevStatus.Components[vmv1beta1.VMClusterComponentVMInsert], cr.Status.Components[vmv1beta1.VMClusterComponentVMInsert])
+
+	// removed component must be removed from status
+	cr.Spec.VMInsert = nil
+	if err := UpdateComponentsStatus(ctx, fclient, cr); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.NotContains(t, cr.Status.Components, vmv1beta1.VMClusterComponentVMInsert)
+}
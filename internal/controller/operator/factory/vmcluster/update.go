@@ -0,0 +1,427 @@
+package vmcluster
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/finalize"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/k8stools"
+)
+
+// vmStorageHealthCheckInterval defines requeue interval for VMCluster with not healthy vmstorage pods
+var vmStorageHealthCheckInterval = 10 * time.Second
+
+// storageExpansionCheckInterval defines requeue interval for VMCluster with pending vmstorage volumes resize
+var storageExpansionCheckInterval = 30 * time.Second
+
+const (
+	vmStorageRequestTimeout   = 5 * time.Second
+	componentNotHealthyReason = "ComponentNotHealthy"
+)
+
+// componentNotHealthyError means that component was updated, but its pods are not healthy yet.
+// Update of the next components must be postponed until the next reconcile
+type componentNotHealthyError struct {
+	err error
+}
+
+func (e *componentNotHealthyError) Error() string {
+	return e.err.Error()
+}
+
+func (e *componentNotHealthyError) Unwrap() error {
+	return e.err
+}
+
+// UpdateRequeueAfter returns interval for the next VMCluster reconcile
+// if update of cluster components is postponed until component pods become healthy
+// or if vmstorage volumes resize is pending. It returns 0 otherwise
+func UpdateRequeueAfter(cr *vmv1beta1.VMCluster) time.Duration {
+	for _, c := range cr.Status.Conditions {
+		if c.Type == vmv1beta1.VMClusterUpdateStalledCondition && c.Status == metav1.ConditionTrue && c.Reason == componentNotHealthyReason {
+			return vmStorageHealthCheckInterval
+		}
+	}
+	// status.storageExpansionInProgress must be cleared after volumes resize without spec changes
+	if cr.Status.StorageExpansionInProgress {
+		return storageExpansionCheckInterval
+	}
+	return 0
+}
+
+// isUpdateOrderConfigured checks if cluster components must be updated in order defined at spec.updateStrategy
+func isUpdateOrderConfigured(cr *vmv1beta1.VMCluster) bool {
+	return cr.Spec.UpdateStrategy != nil && len(cr.Spec.UpdateStrategy.Order) > 0
+}
+
+// updateOrder returns order of cluster components update
+// vmstorage is updated first if new storage StatefulSets were added,
+// since vminsert and vmselect must not be rolled with new storage nodes before they become ready
+func updateOrder(cr, prevCR *vmv1beta1.VMCluster) []string {
+	order := cr.UpdateOrder()
+	if prevCR == nil || prevCR.Spec.VMStorage == nil || cr.Spec.VMStorage == nil {
+		return order
+	}
+	prevNames := prevCR.VMStorageStatefulSetNames()
+	for _, name := range cr.VMStorageStatefulSetNames() {
+		if !slices.Contains(prevNames, name) {
+			order = slices.DeleteFunc(order, func(component string) bool {
+				return component == vmv1beta1.VMClusterComponentVMStorage
+			})
+			return append([]string{vmv1beta1.VMClusterComponentVMStorage}, order...)
+		}
+	}
+	return order
+}
+
+// storageScaleDown describes vmstorage StatefulSet, which replicas must be decreased
+type storageScaleDown struct {
+	name            string
+	currentReplicas int32
+	desiredReplicas int32
+	claimNames      []string
+}
+
+// prepareStorageScaleDown checks if number of vmstorage nodes was decreased and if it's allowed.
+// Removed nodes are excluded from vminsert and vmselect storageNode lists first,
+// StatefulSets are scaled down only after vminsert and vmselect rollout
+func prepareStorageScaleDown(ctx context.Context, rclient client.Client, cr, prevCR *vmv1beta1.VMCluster) ([]storageScaleDown, error) {
+	cr.Status.StorageScaleDownPhase = ""
+	if cr.Spec.VMStorage == nil || cr.IsComponentPaused(vmv1beta1.VMClusterComponentVMStorage) {
+		return nil, nil
+	}
+	desired := cr.VMStorageReplicas()
+	names := cr.VMStorageStatefulSetNames()
+	if prevCR != nil && prevCR.Spec.VMStorage != nil {
+		for _, name := range prevCR.VMStorageStatefulSetNames() {
+			if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	var scaleDowns []storageScaleDown
+	for _, name := range names {
+		var sts appsv1.StatefulSet
+		if err := rclient.Get(ctx, types.NamespacedName{Namespace: cr.Namespace, Name: name}, &sts); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return nil, fmt.Errorf("cannot get vmstorage sts=%s: %w", name, err)
+		}
+		currentReplicas := ptr.Deref(sts.Spec.Replicas, 1)
+		if currentReplicas <= desired[name] {
+			continue
+		}
+		sd := storageScaleDown{
+			name:            name,
+			currentReplicas: currentReplicas,
+			desiredReplicas: desired[name],
+		}
+		for _, claim := range sts.Spec.VolumeClaimTemplates {
+			sd.claimNames = append(sd.claimNames, claim.Name)
+		}
+		scaleDowns = append(scaleDowns, sd)
+	}
+	var scaleDownErr error
+	switch {
+	case len(scaleDowns) == 0:
+	case isStorageLayoutMigration(cr, prevCR) && !cr.Spec.VMStorage.AllowDataLossOnScaleDown:
+		// replicationFactor doesn't protect from data loss, since all previous nodes are removed
+		scaleDownErr = fmt.Errorf("migration between vmstorage storageNodeGroups and single StatefulSet removes all previous vmstorage nodes and their data. " +
+			"Set vmstorage.allowDataLossOnScaleDown to allow it")
+	case !cr.IsStorageScaleDownAllowed():
+		sd := scaleDowns[0]
+		scaleDownErr = fmt.Errorf("cannot decrease replicas of vmstorage=%s from=%d to=%d, it may lead to data loss. "+
+			"Set vmstorage.allowDataLossOnScaleDown or replicationFactor>=2 to allow it", sd.name, sd.currentReplicas, sd.desiredReplicas)
+	}
+	setStorageScaleDownRejectedCondition(cr, scaleDownErr)
+	if scaleDownErr != nil {
+		return nil, scaleDownErr
+	}
+	if len(scaleDowns) > 0 {
+		if err := updateStorageScaleDownPhase(ctx, rclient, cr, vmv1beta1.VMClusterStorageScaleDownExcludingNodes); err != nil {
+			return nil, err
+		}
+	}
+	return scaleDowns, nil
+}
+
+// isStorageLayoutMigration checks if vmstorage is moved into storageNodeGroups or back to the single StatefulSet
+func isStorageLayoutMigration(cr, prevCR *vmv1beta1.VMCluster) bool {
+	if prevCR == nil || prevCR.Spec.VMStorage == nil {
+		return false
+	}
+	return (len(prevCR.Spec.VMStorage.StorageNodeGroups) == 0) != (len(cr.Spec.VMStorage.StorageNodeGroups) == 0)
+}
+
+// finishStorageScaleDown scales down vmstorage StatefulSets after vminsert and vmselect stopped using removed nodes
+// and deletes PVCs of removed nodes if reclaimPolicy is Delete
+func finishStorageScaleDown(ctx context.Context, rclient client.Client, cr, prevCR *vmv1beta1.VMCluster, scaleDowns []storageScaleDown) error {
+	if len(scaleDowns) == 0 {
+		return nil
+	}
+	if err := updateStorageScaleDownPhase(ctx, rclient, cr, vmv1beta1.VMClusterStorageScaleDownScalingDown); err != nil {
+		return err
+	}
+	if err := createOrUpdateVMStorage(ctx, rclient, cr, prevCR, nil); err != nil {
+		return err
+	}
+	if cr.Spec.VMStorage.ReclaimPolicy == vmv1beta1.VMStorageReclaimPolicyDelete {
+		if err := updateStorageScaleDownPhase(ctx, rclient, cr, vmv1beta1.VMClusterStorageScaleDownDeletingVolumes); err != nil {
+			return err
+		}
+		for _, sd := range scaleDowns {
+			for ordinal := sd.desiredReplicas; ordinal < sd.currentReplicas; ordinal++ {
+				for _, claimName := range sd.claimNames {
+					pvc := &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{
+						Namespace: cr.Namespace,
+						Name:      fmt.Sprintf("%s-%s-%d", claimName, sd.name, ordinal),
+					}}
+					if err := finalize.SafeDelete(ctx, rclient, pvc); err != nil {
+						return fmt.Errorf("cannot delete pvc=%s: %w", pvc.Name, err)
+					}
+				}
+			}
+		}
+	}
+	cr.Status.StorageScaleDownPhase = ""
+	return nil
+}
+
+// updateStorageScaleDownPhase persists the given phase of vmstorage scale down.
+// Scale down may take a few reconciles and the phase must be visible during it,
+// while the final status update at the end of reconcile clears it
+func updateStorageScaleDownPhase(ctx context.Context, rclient client.Client, cr *vmv1beta1.VMCluster, phase string) error {
+	cr.Status.StorageScaleDownPhase = phase
+	patch := map[string]any{
+		"status": map[string]any{
+			"storageScaleDownPhase": phase,
+		},
+	}
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return fmt.Errorf("cannot marshal storage scale down status patch: %w", err)
+	}
+	// make a deep copy, patch response must not override in-memory object
+	objToUpdate := cr.DeepCopy()
+	if err := rclient.Status().Patch(ctx, objToUpdate, client.RawPatch(types.MergePatchType, data)); err != nil {
+		return fmt.Errorf("cannot update storage scale down phase: %w", err)
+	}
+	cr.SetResourceVersion(objToUpdate.GetResourceVersion())
+	return nil
+}
+
+// setStorageScaleDownRejectedCondition updates StorageScaleDownRejected condition at the given VMCluster status
+func setStorageScaleDownRejectedCondition(cr *vmv1beta1.VMCluster, scaleDownErr error) {
+	cond := vmv1beta1.Condition{
+		Type:   vmv1beta1.VMClusterStorageScaleDownRejectedCondition,
+		Status: metav1.ConditionFalse,
+		Reason: "StorageReplicasApplied",
+	}
+	if scaleDownErr != nil {
+		cond.Status = metav1.ConditionTrue
+		cond.Reason = vmv1beta1.VMClusterStorageScaleDownRejectedCondition
+		cond.Message = scaleDownErr.Error()
+	}
+	setVMClusterCondition(cr, cond)
+}
+
+// checkVMStorageHealthy checks health endpoint of running vmstorage pods.
+// It's required to make sure, that vmstorage accepts rpc connections before update of the next cluster component.
+// componentNotHealthyError is returned if any pod is not healthy yet
+func checkVMStorageHealthy(ctx context.Context, rclient client.Client, cr *vmv1beta1.VMCluster) error {
+	var pods corev1.PodList
+	opts := &client.ListOptions{
+		Namespace:     cr.Namespace,
+		LabelSelector: labels.SelectorFromSet(cr.VMStorageSelectorLabels()),
+	}
+	if err := rclient.List(ctx, &pods, opts); err != nil {
+		return fmt.Errorf("cannot list vmstorage pods: %w", err)
+	}
+	hc, err := newVMStorageHTTPClient(ctx, rclient, cr)
+	if err != nil {
+		return err
+	}
+	for _, pod := range pods.Items {
+		if !pod.DeletionTimestamp.IsZero() || pod.Status.Phase != corev1.PodRunning || pod.Status.PodIP == "" {
+			continue
+		}
+		if err := checkHealth(ctx, hc, cr.VMStoragePodHealthURL(pod.Status.PodIP)); err != nil {
+			return &componentNotHealthyError{err: fmt.Errorf("vmstorage pod=%s is not healthy: %w", pod.Name, err)}
+		}
+	}
+	return nil
+}
+
+// newVMStorageHTTPClient returns client for requests to vmstorage pods
+// it uses tlsConfig of vmstorage serviceScrapeSpec http endpoint
+func newVMStorageHTTPClient(ctx context.Context, rclient client.Client, cr *vmv1beta1.VMCluster) (*http.Client, error) {
+	var tc *vmv1beta1.TLSConfig
+	if ss := cr.Spec.VMStorage.ServiceScrapeSpec; ss != nil {
+		for _, ep := range ss.Endpoints {
+			if ep.Port == "http" && ep.TLSConfig != nil {
+				tc = ep.TLSConfig
+				break
+			}
+		}
+	}
+	if tc == nil && cr.Spec.VMStorage.ExtraArgs["tls"] == "true" {
+		// certificates are not verified without tlsConfig, the same way as kubelet does for https probes
+		tc = &vmv1beta1.TLSConfig{InsecureSkipVerify: true}
+	}
+	return k8stools.NewHTTPClient(ctx, rclient, cr.Namespace, tc, vmStorageRequestTimeout)
+}
+
+// checkHealth performs request to the given health endpoint
+func checkHealth(ctx context.Context, hc *http.Client, healthURL string) error {
+	_, err := getURL(ctx, hc, healthURL)
+	return err
+}
+
+// getURL performs GET request to the given url and returns response body
+func getURL(ctx context.Context, hc *http.Client, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code=%d, response=%q", resp.StatusCode, string(body))
+	}
+	return body, nil
+}
+
+const (
+	vmStorageReadyMetric             = "vm_indexdb_items_added_total"
+	defaultVMStorageReadyChecksCount = 3
+	defaultVMStorageReadyInterval    = 5 * time.Second
+	defaultVMStorageReadyTimeout     = 5 * time.Minute
+)
+
+// buildVMStorageReadyCheck returns check of updated vmstorage pod, which ensures that pod loaded index caches and serves metrics.
+// It returns nil if check is not enabled
+func buildVMStorageReadyCheck(rclient client.Client, cr *vmv1beta1.VMCluster) func(ctx context.Context, pod *corev1.Pod) error {
+	behavior := cr.Spec.VMStorage.RollingUpdateStrategyBehavior
+	if behavior == nil || !behavior.WaitForVMStorageReadyMetrics {
+		return nil
+	}
+	checksCount := ptr.Deref(behavior.ReadyChecksCount, defaultVMStorageReadyChecksCount)
+	interval := defaultVMStorageReadyInterval
+	if d, err := time.ParseDuration(behavior.ReadyCheckInterval); err == nil {
+		interval = d
+	}
+	timeout := defaultVMStorageReadyTimeout
+	if d, err := time.ParseDuration(behavior.ReadyCheckTimeout); err == nil {
+		timeout = d
+	}
+	return func(ctx context.Context, pod *corev1.Pod) error {
+		hc, err := newVMStorageHTTPClient(ctx, rclient, cr)
+		if err != nil {
+			return err
+		}
+		var successChecks int32
+		var lastErr error
+		err = wait.PollUntilContextTimeout(ctx, interval, timeout, true, func(ctx context.Context) (bool, error) {
+			if lastErr = checkVMStorageReadyMetrics(ctx, hc, cr, pod); lastErr != nil {
+				successChecks = 0
+				return false, nil
+			}
+			successChecks++
+			return successChecks >= checksCount, nil
+		})
+		if err != nil {
+			if lastErr != nil {
+				err = lastErr
+			}
+			return fmt.Errorf("vmstorage pod=%s didn't report ready metrics: %w", pod.Name, err)
+		}
+		return nil
+	}
+}
+
+// checkVMStorageReadyMetrics checks that vmstorage pod is healthy and exposes index metrics
+func checkVMStorageReadyMetrics(ctx context.Context, hc *http.Client, cr *vmv1beta1.VMCluster, pod *corev1.Pod) error {
+	if pod.Status.PodIP == "" {
+		return fmt.Errorf("pod doesn't have IP address yet")
+	}
+	if err := checkHealth(ctx, hc, cr.VMStoragePodURL(pod.Status.PodIP, "/-/healthy")); err != nil {
+		return err
+	}
+	metrics, err := getURL(ctx, hc, cr.VMStoragePodURL(pod.Status.PodIP, "/metrics"))
+	if err != nil {
+		return err
+	}
+	for _, line := range strings.Split(string(metrics), "\n") {
+		if strings.HasPrefix(line, vmStorageReadyMetric) {
+			return nil
+		}
+	}
+	return fmt.Errorf("metric %s is not exposed yet", vmStorageReadyMetric)
+}
+
+// setClusterUpdateStalledCondition updates ClusterUpdateStalled condition at the given VMCluster status
+func setClusterUpdateStalledCondition(cr *vmv1beta1.VMCluster, component string, updateErr error) {
+	cond := vmv1beta1.Condition{
+		Type:   vmv1beta1.VMClusterUpdateStalledCondition,
+		Status: metav1.ConditionFalse,
+		Reason: "ClusterUpdated",
+	}
+	if updateErr != nil {
+		cond.Status = metav1.ConditionTrue
+		cond.Reason = vmv1beta1.VMClusterUpdateStalledCondition
+		cond.Message = fmt.Sprintf("update of component=%s failed: %s", component, updateErr)
+		var nhe *componentNotHealthyError
+		if stderrors.As(updateErr, &nhe) {
+			cond.Reason = componentNotHealthyReason
+			cond.Message = fmt.Sprintf("update is postponed until component=%s is healthy: %s", component, updateErr)
+		}
+	}
+	setVMClusterCondition(cr, cond)
+}
+
+// setVMClusterCondition adds or updates condition at the given VMCluster status
+// transition times are preserved if condition status is not changed
+func setVMClusterCondition(cr *vmv1beta1.VMCluster, cond vmv1beta1.Condition) {
+	ctm := metav1.Now()
+	cond.ObservedGeneration = cr.Generation
+	cond.LastTransitionTime = ctm
+	cond.LastUpdateTime = ctm
+	for idx, c := range cr.Status.Conditions {
+		if c.Type != cond.Type {
+			continue
+		}
+		if c.Status == cond.Status {
+			cond.LastTransitionTime = c.LastTransitionTime
+			cond.LastUpdateTime = c.LastUpdateTime
+		}
+		cr.Status.Conditions[idx] = cond
+		return
+	}
+	cr.Status.Conditions = append(cr.Status.Conditions, cond)
+}
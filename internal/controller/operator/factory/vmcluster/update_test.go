@@ -0,0 +1,306 @@
+package vmcluster
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+
+	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/k8stools"
+)
+
+func TestCheckVMStorageHealthy(t *testing.T) {
+	f := func(statusCode int, wantErr bool) {
+		t.Helper()
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/health" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.WriteHeader(statusCode)
+		}))
+		defer srv.Close()
+		host, port, err := net.SplitHostPort(srv.Listener.Addr().String())
+		if err != nil {
+			t.Fatalf("cannot parse server address: %s", err)
+		}
+		cr := &vmv1beta1.VMCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster", Namespace: "default"},
+			Spec: vmv1beta1.VMClusterSpec{
+				VMStorage: &vmv1beta1.VMStorage{
+					CommonDefaultableParams: vmv1beta1.CommonDefaultableParams{Port: port},
+				},
+			},
+		}
+		fclient := k8stools.GetTestClientWithObjects([]runtime.Object{
+			&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "vmstorage-cluster-0", Namespace: "default", Labels: cr.VMStorageSelectorLabels()},
+				Status:     corev1.PodStatus{Phase: corev1.PodRunning, PodIP: host},
+			},
+			// pending pod must be ignored
+			&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "vmstorage-cluster-1", Namespace: "default", Labels: cr.VMStorageSelectorLabels()},
+				Status:     corev1.PodStatus{Phase: corev1.PodPending},
+			},
+		})
+		err = checkVMStorageHealthy(context.TODO(), fclient, cr)
+		if (err != nil) != wantErr {
+			t.Fatalf("checkVMStorageHealthy() error = %v, wantErr %v", err, wantErr)
+		}
+		var nhe *componentNotHealthyError
+		if wantErr && !errors.As(err, &nhe) {
+			t.Fatalf("unexpected error type: %T", err)
+		}
+	}
+
+	// healthy
+	f(http.StatusOK, false)
+
+	// not ready
+	f(http.StatusServiceUnavailable, true)
+}
+
+func TestSetClusterUpdateStalledCondition(t *testing.T) {
+	cr := &vmv1beta1.VMCluster{}
+	setClusterUpdateStalledCondition(cr, vmv1beta1.VMClusterComponentVMSelect, errors.New("cannot wait for statefulset to become ready"))
+	assert.Len(t, cr.Status.Conditions, 1)
+	cond := cr.Status.Conditions[0]
+	assert.Equal(t, vmv1beta1.VMClusterUpdateStalledCondition, cond.Type)
+	assert.Equal(t, metav1.ConditionTrue, cond.Status)
+	assert.Equal(t, "update of component=vmselect failed: cannot wait for statefulset to become ready", cond.Message)
+
+	assert.Zero(t, UpdateRequeueAfter(cr))
+
+	// not healthy component requeues reconcile
+	setClusterUpdateStalledCondition(cr, vmv1beta1.VMClusterComponentVMStorage, &componentNotHealthyError{err: errors.New("vmstorage pod=vmstorage-0 is not healthy")})
+	assert.Len(t, cr.Status.Conditions, 1)
+	assert.Equal(t, componentNotHealthyReason, cr.Status.Conditions[0].Reason)
+	assert.Equal(t, vmStorageHealthCheckInterval, UpdateRequeueAfter(cr))
+
+	setClusterUpdateStalledCondition(cr, "", nil)
+	assert.Len(t, cr.Status.Conditions, 1)
+	assert.Equal(t, metav1.ConditionFalse, cr.Status.Conditions[0].Status)
+	assert.Empty(t, cr.Status.Conditions[0].Message)
+	assert.Zero(t, UpdateRequeueAfter(cr))
+
+	// pending volumes resize requeues reconcile
+	cr.Status.StorageExpansionInProgress = true
+	assert.Equal(t, storageExpansionCheckInterval, UpdateRequeueAfter(cr))
+}
+
+func TestUpdateOrder(t *testing.T) {
+	f := func(cr, prevCR *vmv1beta1.VMCluster, want []string) {
+		t.Helper()
+		assert.Equal(t, want, updateOrder(cr, prevCR))
+	}
+	withGroups := func(order []string, groups ...string) *vmv1beta1.VMCluster {
+		cr := &vmv1beta1.VMCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster", Namespace: "default"},
+			Spec: vmv1beta1.VMClusterSpec{
+				VMStorage:      &vmv1beta1.VMStorage{},
+				UpdateStrategy: &vmv1beta1.VMClusterUpdateStrategy{Order: order},
+			},
+		}
+		for _, g := range groups {
+			cr.Spec.VMStorage.StorageNodeGroups = append(cr.Spec.VMStorage.StorageNodeGroups, vmv1beta1.VMStorageNodeGroup{Name: g})
+		}
+		return cr
+	}
+	order := []string{"vminsert", "vmselect", "vmstorage"}
+
+	// new cluster
+	f(withGroups(order, "a"), nil, order)
+
+	// groups are not changed
+	f(withGroups(order, "a", "b"), withGroups(order, "a", "b"), order)
+
+	// group removed
+	f(withGroups(order, "a"), withGroups(order, "a", "b"), order)
+
+	// group added, vmstorage must be updated first
+	f(withGroups(order, "a", "b"), withGroups(order, "a"), []string{"vmstorage", "vminsert", "vmselect"})
+
+	// migration to groups
+	f(withGroups(order, "a"), withGroups(order), []string{"vmstorage", "vminsert", "vmselect"})
+}
+
+func TestPrepareStorageScaleDown(t *testing.T) {
+	f := func(cr *vmv1beta1.VMCluster, currentReplicas int32, wantScaleDowns []storageScaleDown, wantErr bool) {
+		t.Helper()
+		fclient := k8stools.GetTestClientWithObjects([]runtime.Object{
+			cr.DeepCopy(),
+			&appsv1.StatefulSet{
+				ObjectMeta: metav1.ObjectMeta{Name: cr.GetVMStorageName(), Namespace: cr.Namespace},
+				Spec: appsv1.StatefulSetSpec{
+					Replicas: ptr.To(currentReplicas),
+					VolumeClaimTemplates: []corev1.PersistentVolumeClaim{
+						{ObjectMeta: metav1.ObjectMeta{Name: "vmstorage-db"}},
+					},
+				},
+			},
+		})
+		scaleDowns, err := prepareStorageScaleDown(context.TODO(), fclient, cr, nil)
+		if (err != nil) != wantErr {
+			t.Fatalf("prepareStorageScaleDown() error = %v, wantErr %v", err, wantErr)
+		}
+		assert.Equal(t, wantScaleDowns, scaleDowns)
+		assert.Len(t, cr.Status.Conditions, 1)
+		cond := cr.Status.Conditions[0]
+		assert.Equal(t, vmv1beta1.VMClusterStorageScaleDownRejectedCondition, cond.Type)
+		if wantErr {
+			assert.Equal(t, metav1.ConditionTrue, cond.Status)
+		} else {
+			assert.Equal(t, metav1.ConditionFalse, cond.Status)
+		}
+		if len(wantScaleDowns) > 0 {
+			assert.Equal(t, vmv1beta1.VMClusterStorageScaleDownExcludingNodes, cr.Status.StorageScaleDownPhase)
+			// phase must be persisted before scale down of vmstorage
+			var got vmv1beta1.VMCluster
+			assert.NoError(t, fclient.Get(context.TODO(), types.NamespacedName{Namespace: cr.Namespace, Name: cr.Name}, &got))
+			assert.Equal(t, vmv1beta1.VMClusterStorageScaleDownExcludingNodes, got.Status.StorageScaleDownPhase)
+		} else {
+			assert.Empty(t, cr.Status.StorageScaleDownPhase)
+		}
+	}
+	newCR := func(replicas, replicationFactor int32, allowDataLoss bool) *vmv1beta1.VMCluster {
+		return &vmv1beta1.VMCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster", Namespace: "default"},
+			Spec: vmv1beta1.VMClusterSpec{
+				ReplicationFactor: ptr.To(replicationFactor),
+				VMStorage: &vmv1beta1.VMStorage{
+					AllowDataLossOnScaleDown: allowDataLoss,
+					CommonApplicationDeploymentParams: vmv1beta1.CommonApplicationDeploymentParams{
+						ReplicaCount: ptr.To(replicas),
+					},
+				},
+			},
+		}
+	}
+
+	// scale up
+	f(newCR(3, 1, false), 2, nil, false)
+
+	// scale down without replication
+	f(newCR(2, 1, false), 3, nil, true)
+
+	// scale down with replication
+	f(newCR(2, 2, false), 3, []storageScaleDown{{
+		name:            "vmstorage-cluster",
+		currentReplicas: 3,
+		desiredReplicas: 2,
+		claimNames:      []string{"vmstorage-db"},
+	}}, false)
+
+	// scale down with explicit data loss permission
+	f(newCR(1, 1, true), 3, []storageScaleDown{{
+		name:            "vmstorage-cluster",
+		currentReplicas: 3,
+		desiredReplicas: 1,
+		claimNames:      []string{"vmstorage-db"},
+	}}, false)
+}
+
+func TestIsStorageLayoutMigration(t *testing.T) {
+	f := func(prevGroups, groups []vmv1beta1.VMStorageNodeGroup, want bool) {
+		t.Helper()
+		prevCR := &vmv1beta1.VMCluster{Spec: vmv1beta1.VMClusterSpec{VMStorage: &vmv1beta1.VMStorage{StorageNodeGroups: prevGroups}}}
+		cr := &vmv1beta1.VMCluster{Spec: vmv1beta1.VMClusterSpec{VMStorage: &vmv1beta1.VMStorage{StorageNodeGroups: groups}}}
+		assert.Equal(t, want, isStorageLayoutMigration(cr, prevCR))
+	}
+	groupA := []vmv1beta1.VMStorageNodeGroup{{Name: "a", ReplicaCount: ptr.To[int32](1)}}
+	groupB := []vmv1beta1.VMStorageNodeGroup{{Name: "b", ReplicaCount: ptr.To[int32](1)}}
+	f(nil, nil, false)
+	f(groupA, groupB, false)
+	f(nil, groupA, true)
+	f(groupA, nil, true)
+}
+
+func TestBuildVMStorageReadyCheck(t *testing.T) {
+	f := func(metrics string, wantErr bool) {
+		t.Helper()
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/-/healthy":
+				w.WriteHeader(http.StatusOK)
+			case "/metrics":
+				w.Write([]byte(metrics)) //nolint:errcheck
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		defer srv.Close()
+		host, port, err := net.SplitHostPort(srv.Listener.Addr().String())
+		if err != nil {
+			t.Fatalf("cannot parse server address: %s", err)
+		}
+		cr := &vmv1beta1.VMCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster", Namespace: "default"},
+			Spec: vmv1beta1.VMClusterSpec{
+				VMStorage: &vmv1beta1.VMStorage{
+					RollingUpdateStrategyBehavior: &vmv1beta1.VMStorageRollingUpdateStrategyBehavior{
+						WaitForVMStorageReadyMetrics: true,
+						ReadyChecksCount:             ptr.To[int32](2),
+						ReadyCheckInterval:           "10ms",
+						ReadyCheckTimeout:            "100ms",
+					},
+					CommonDefaultableParams: vmv1beta1.CommonDefaultableParams{Port: port},
+				},
+			},
+		}
+		check := buildVMStorageReadyCheck(k8stools.GetTestClientWithObjects(nil), cr)
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "vmstorage-cluster-0", Namespace: "default"},
+			Status:     corev1.PodStatus{PodIP: host},
+		}
+		err = check(context.TODO(), pod)
+		if (err != nil) != wantErr {
+			t.Fatalf("vmstorage ready check error = %v, wantErr %v", err, wantErr)
+		}
+	}
+
+	// index metrics are exposed
+	f("vm_indexdb_items_added_total 10\n", false)
+
+	// index metrics are not exposed yet
+	f("vm_app_uptime_seconds 1\n", true)
+
+	// check is disabled
+	cr := &vmv1beta1.VMCluster{Spec: vmv1beta1.VMClusterSpec{VMStorage: &vmv1beta1.VMStorage{}}}
+	assert.Nil(t, buildVMStorageReadyCheck(k8stools.GetTestClientWithObjects(nil), cr))
+}
+
+func TestCreateOrUpdateVMClusterPausedComponent(t *testing.T) {
+	cr := &vmv1beta1.VMCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "cluster",
+			Namespace:   "default",
+			Annotations: map[string]string{vmv1beta1.VMClusterSkipReconcileAnnotation: "vmstorage"},
+		},
+		Spec: vmv1beta1.VMClusterSpec{
+			VMStorage: &vmv1beta1.VMStorage{
+				CommonApplicationDeploymentParams: vmv1beta1.CommonApplicationDeploymentParams{ReplicaCount: ptr.To[int32](2)},
+			},
+		},
+	}
+	ctx := context.TODO()
+	fclient := k8stools.GetTestClientWithObjects(nil)
+	if err := CreateOrUpdateVMCluster(ctx, cr, fclient); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.Equal(t, []string{vmv1beta1.VMClusterComponentVMStorage}, cr.Status.PausedComponents)
+	var sts appsv1.StatefulSet
+	err := fclient.Get(ctx, types.NamespacedName{Namespace: cr.Namespace, Name: cr.GetVMStorageName()}, &sts)
+	assert.True(t, k8serrors.IsNotFound(err), "unexpected error: %v", err)
+}
@@ -2,8 +2,10 @@ package vmcluster
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"path"
+	"slices"
 	"sort"
 	"strings"
 
@@ -21,6 +23,7 @@ import (
 	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/build"
 	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/finalize"
 	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/k8stools"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/logger"
 	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/reconcile"
 )
 
@@ -30,9 +33,11 @@ const (
 )
 
 // CreateOrUpdateVMCluster reconciled cluster object with order
+// defined by spec.updateStrategy.order, by default
 // first we check status of vmStorage and waiting for its readiness
 // then vmSelect and wait for it readiness as well
 // and last one is vmInsert
+// failed component update halts update of the next components
 // we manually handle statefulsets rolling updates
 // needed in update checked by revesion status
 // its controlled by k8s controller-manager
@@ -61,92 +66,158 @@ func CreateOrUpdateVMCluster(ctx context.Context, cr *vmv1beta1.VMCluster, rclie
 		}
 	}
 
-	if cr.Spec.VMStorage != nil {
-		if cr.Spec.VMStorage.PodDisruptionBudget != nil {
-			err := createOrUpdatePodDisruptionBudgetForVMStorage(ctx, rclient, cr, prevCR)
-			if err != nil {
-				return err
-			}
-		}
-		if err := createOrUpdateVMStorage(ctx, rclient, cr, prevCR); err != nil {
-			return err
-		}
+	if err := createOrUpdateInternalTLSCertificates(ctx, rclient, cr, prevCR); err != nil {
+		return err
+	}
 
-		storageSvc, err := createOrUpdateVMStorageService(ctx, rclient, cr, prevCR)
-		if err != nil {
-			return err
+	scaleDowns, err := prepareStorageScaleDown(ctx, rclient, cr, prevCR)
+	if err != nil {
+		return err
+	}
+	cr.Status.PausedComponents = nil
+	for _, component := range updateOrder(cr, prevCR) {
+		if cr.IsComponentPaused(component) {
+			logger.WithContext(ctx).Info(fmt.Sprintf("skipping reconcile of paused component=%s", component))
+			cr.Status.PausedComponents = append(cr.Status.PausedComponents, component)
+			continue
 		}
-		if !ptr.Deref(cr.Spec.VMStorage.DisableSelfServiceScrape, false) {
-			err := reconcile.VMServiceScrapeForCRD(ctx, rclient, build.VMServiceScrapeForServiceWithSpec(storageSvc, cr.Spec.VMStorage, "vmbackupmanager"))
-			if err != nil {
-				return fmt.Errorf("cannot create VMServiceScrape for vmStorage: %w", err)
+		if err := createOrUpdateComponent(ctx, rclient, cr, prevCR, component, scaleDowns); err != nil {
+			setClusterUpdateStalledCondition(cr, component, err)
+			setComponentStalledReason(cr, component, err)
+			var nhe *componentNotHealthyError
+			if errors.As(err, &nhe) {
+				// reconcile is requeued with UpdateRequeueAfter, next components are updated after component becomes healthy
+				logger.WithContext(ctx).Info(fmt.Sprintf("postponing update of cluster components: %s", err))
+				return nil
 			}
+			return err
 		}
+		setComponentStalledReason(cr, component, nil)
 	}
+	setClusterUpdateStalledCondition(cr, "", nil)
 
-	if cr.Spec.VMSelect != nil {
-		if cr.Spec.VMSelect.PodDisruptionBudget != nil {
-			if err := createOrUpdatePodDisruptionBudgetForVMSelect(ctx, rclient, cr, prevCR); err != nil {
-				return err
-			}
+	if err := deletePrevStateResources(ctx, rclient, cr, prevCR); err != nil {
+		return fmt.Errorf("failed to remove objects from previous cluster state: %w", err)
+	}
+	if err := finishStorageScaleDown(ctx, rclient, cr, prevCR, scaleDowns); err != nil {
+		return fmt.Errorf("failed to scale down vmstorage: %w", err)
+	}
+	return nil
+}
+
+// createOrUpdateComponent reconciles objects of the given cluster component and waits until it's ready
+func createOrUpdateComponent(ctx context.Context, rclient client.Client, cr, prevCR *vmv1beta1.VMCluster, component string, scaleDowns []storageScaleDown) error {
+	switch component {
+	case vmv1beta1.VMClusterComponentVMStorage:
+		if cr.Spec.VMStorage == nil {
+			cr.Status.StorageExpansionInProgress = false
+			return nil
 		}
-		if err := createOrUpdateVMSelect(ctx, rclient, cr, prevCR); err != nil {
-			return err
+		return createOrUpdateVMStorageComponent(ctx, rclient, cr, prevCR, scaleDowns)
+	case vmv1beta1.VMClusterComponentVMSelect:
+		if cr.Spec.VMSelect == nil {
+			return nil
 		}
-
-		if err := createOrUpdateVMSelectHPA(ctx, rclient, cr, prevCR); err != nil {
-			return err
+		return createOrUpdateVMSelectComponent(ctx, rclient, cr, prevCR)
+	case vmv1beta1.VMClusterComponentVMInsert:
+		if cr.Spec.VMInsert == nil {
+			return nil
 		}
-		// create vmselect service
-		selectSvc, err := createOrUpdateVMSelectService(ctx, rclient, cr, prevCR)
+		return createOrUpdateVMInsertComponent(ctx, rclient, cr, prevCR)
+	default:
+		return fmt.Errorf("BUG: unsupported cluster component=%q", component)
+	}
+}
+
+func createOrUpdateVMStorageComponent(ctx context.Context, rclient client.Client, cr, prevCR *vmv1beta1.VMCluster, scaleDowns []storageScaleDown) error {
+	if cr.Spec.VMStorage.PodDisruptionBudget != nil {
+		err := createOrUpdatePodDisruptionBudgetForVMStorage(ctx, rclient, cr, prevCR)
 		if err != nil {
 			return err
 		}
-		if !ptr.Deref(cr.Spec.VMSelect.DisableSelfServiceScrape, false) {
+	}
+	if err := createOrUpdateVMStorage(ctx, rclient, cr, prevCR, scaleDowns); err != nil {
+		return err
+	}
 
-			svs := build.VMServiceScrapeForServiceWithSpec(selectSvc, cr.Spec.VMSelect)
-			if cr.Spec.RequestsLoadBalancer.Enabled && !cr.Spec.RequestsLoadBalancer.DisableSelectBalancing {
-				// for backward compatibility we must keep job label value
-				svs.Spec.JobLabel = vmauthLBServiceProxyJobNameLabel
-			}
-			err := reconcile.VMServiceScrapeForCRD(ctx, rclient, svs)
-			if err != nil {
-				return fmt.Errorf("cannot create VMServiceScrape for vmSelect: %w", err)
-			}
+	storageSvc, err := createOrUpdateVMStorageService(ctx, rclient, cr, prevCR)
+	if err != nil {
+		return err
+	}
+	if !ptr.Deref(cr.Spec.VMStorage.DisableSelfServiceScrape, false) {
+		err := reconcile.VMServiceScrapeForCRD(ctx, rclient, build.VMServiceScrapeForServiceWithSpec(storageSvc, cr.Spec.VMStorage, "vmbackupmanager"))
+		if err != nil {
+			return fmt.Errorf("cannot create VMServiceScrape for vmStorage: %w", err)
 		}
 	}
+	if !isUpdateOrderConfigured(cr) {
+		return nil
+	}
+	return checkVMStorageHealthy(ctx, rclient, cr)
+}
 
-	if cr.Spec.VMInsert != nil {
-		if cr.Spec.VMInsert.PodDisruptionBudget != nil {
-			if err := createOrUpdatePodDisruptionBudgetForVMInsert(ctx, rclient, cr, prevCR); err != nil {
-				return err
-			}
-		}
-		if err := createOrUpdateVMInsert(ctx, rclient, cr, prevCR); err != nil {
+func createOrUpdateVMSelectComponent(ctx context.Context, rclient client.Client, cr, prevCR *vmv1beta1.VMCluster) error {
+	if cr.Spec.VMSelect.PodDisruptionBudget != nil {
+		if err := createOrUpdatePodDisruptionBudgetForVMSelect(ctx, rclient, cr, prevCR); err != nil {
 			return err
 		}
-		insertSvc, err := createOrUpdateVMInsertService(ctx, rclient, cr, prevCR)
+	}
+	if err := createOrUpdateVMSelect(ctx, rclient, cr, prevCR); err != nil {
+		return err
+	}
+
+	if err := createOrUpdateVMSelectHPA(ctx, rclient, cr, prevCR); err != nil {
+		return err
+	}
+	// create vmselect service
+	selectSvc, err := createOrUpdateVMSelectService(ctx, rclient, cr, prevCR)
+	if err != nil {
+		return err
+	}
+	if err := createOrUpdateVMSelectReplicaServices(ctx, rclient, cr, prevCR); err != nil {
+		return err
+	}
+	if !ptr.Deref(cr.Spec.VMSelect.DisableSelfServiceScrape, false) {
+
+		svs := build.VMServiceScrapeForServiceWithSpec(selectSvc, cr.Spec.VMSelect)
+		if cr.Spec.RequestsLoadBalancer.Enabled && !cr.Spec.RequestsLoadBalancer.DisableSelectBalancing {
+			// for backward compatibility we must keep job label value
+			svs.Spec.JobLabel = vmauthLBServiceProxyJobNameLabel
+		}
+		err := reconcile.VMServiceScrapeForCRD(ctx, rclient, svs)
 		if err != nil {
-			return err
+			return fmt.Errorf("cannot create VMServiceScrape for vmSelect: %w", err)
 		}
-		if err := createOrUpdateVMInsertHPA(ctx, rclient, cr, prevCR); err != nil {
+	}
+	return createOrUpdateVMSelectPools(ctx, rclient, cr, prevCR)
+}
+
+func createOrUpdateVMInsertComponent(ctx context.Context, rclient client.Client, cr, prevCR *vmv1beta1.VMCluster) error {
+	if cr.Spec.VMInsert.PodDisruptionBudget != nil {
+		if err := createOrUpdatePodDisruptionBudgetForVMInsert(ctx, rclient, cr, prevCR); err != nil {
 			return err
 		}
-		if !ptr.Deref(cr.Spec.VMInsert.DisableSelfServiceScrape, false) {
-			svs := build.VMServiceScrapeForServiceWithSpec(insertSvc, cr.Spec.VMInsert)
-			if cr.Spec.RequestsLoadBalancer.Enabled && !cr.Spec.RequestsLoadBalancer.DisableInsertBalancing {
-				// for backward compatibility we must keep job label value
-				svs.Spec.JobLabel = vmauthLBServiceProxyJobNameLabel
-			}
-			err := reconcile.VMServiceScrapeForCRD(ctx, rclient, svs)
-			if err != nil {
-				return fmt.Errorf("cannot create VMServiceScrape for vmInsert: %w", err)
-			}
-		}
 	}
-
-	if err := deletePrevStateResources(ctx, rclient, cr, prevCR); err != nil {
-		return fmt.Errorf("failed to remove objects from previous cluster state: %w", err)
+	if err := createOrUpdateVMInsert(ctx, rclient, cr, prevCR); err != nil {
+		return err
+	}
+	insertSvc, err := createOrUpdateVMInsertService(ctx, rclient, cr, prevCR)
+	if err != nil {
+		return err
+	}
+	if err := createOrUpdateVMInsertHPA(ctx, rclient, cr, prevCR); err != nil {
+		return err
+	}
+	if !ptr.Deref(cr.Spec.VMInsert.DisableSelfServiceScrape, false) {
+		svs := build.VMServiceScrapeForServiceWithSpec(insertSvc, cr.Spec.VMInsert)
+		if cr.Spec.RequestsLoadBalancer.Enabled && !cr.Spec.RequestsLoadBalancer.DisableInsertBalancing {
+			// for backward compatibility we must keep job label value
+			svs.Spec.JobLabel = vmauthLBServiceProxyJobNameLabel
+		}
+		err := reconcile.VMServiceScrapeForCRD(ctx, rclient, svs)
+		if err != nil {
+			return fmt.Errorf("cannot create VMServiceScrape for vmInsert: %w", err)
+		}
 	}
 	return nil
 }
@@ -156,15 +227,20 @@ func createOrUpdateVMSelect(ctx context.Context, rclient client.Client, cr, prev
 	var prevSts *appsv1.StatefulSet
 	if prevCR != nil && prevCR.Spec.VMSelect != nil {
 		var err error
-		prevSts, err = genVMSelectSpec(prevCR)
+		prevSts, err = genVMSelectSpec(prevCR, "")
 		if err != nil {
 			return fmt.Errorf("cannot build prev storage spec: %w", err)
 		}
 	}
-	newSts, err := genVMSelectSpec(cr)
+	newSts, err := genVMSelectSpec(cr, "")
+	if err != nil {
+		return err
+	}
+	checksum, err := internalTLSChecksum(ctx, rclient, cr, vmv1beta1.VMClusterComponentVMSelect)
 	if err != nil {
 		return err
 	}
+	setInternalTLSChecksum(&newSts.Spec.Template, checksum)
 
 	stsOpts := reconcile.STSOptions{
 		HasClaim:       len(newSts.Spec.VolumeClaimTemplates) > 0,
@@ -179,15 +255,17 @@ func createOrUpdateVMSelect(ctx context.Context, rclient client.Client, cr, prev
 	return reconcile.HandleSTSUpdate(ctx, rclient, stsOpts, newSts, prevSts)
 }
 
-func buildVMSelectService(cr *vmv1beta1.VMCluster) *corev1.Service {
+// buildVMSelectPoolService builds headless service for the given vmselect pool
+// empty pool refers to the default vmselect pool
+func buildVMSelectPoolService(cr *vmv1beta1.VMCluster, pool string) *corev1.Service {
 	b := &optsBuilder{
 		cr,
-		cr.GetVMSelectName(),
-		cr.FinalLabels(cr.VMSelectSelectorLabels()),
-		cr.VMSelectSelectorLabels(),
+		cr.GetVMSelectPoolName(pool),
+		cr.FinalLabels(cr.VMSelectPoolSelectorLabels(pool)),
+		cr.VMSelectPoolSelectorLabels(pool),
 		cr.Spec.VMSelect.ServiceSpec,
 	}
-	svc := build.Service(b, cr.Spec.VMSelect.Port, func(svc *corev1.Service) {
+	return build.Service(b, cr.Spec.VMSelect.Port, func(svc *corev1.Service) {
 		svc.Spec.ClusterIP = "None"
 		if cr.Spec.VMSelect.ClusterNativePort != "" {
 			svc.Spec.Ports = append(svc.Spec.Ports, corev1.ServicePort{
@@ -198,6 +276,10 @@ func buildVMSelectService(cr *vmv1beta1.VMCluster) *corev1.Service {
 			})
 		}
 	})
+}
+
+func buildVMSelectService(cr *vmv1beta1.VMCluster) *corev1.Service {
+	svc := buildVMSelectPoolService(cr, "")
 	if cr.Spec.RequestsLoadBalancer.Enabled && !cr.Spec.RequestsLoadBalancer.DisableSelectBalancing {
 		svc.Name = cr.GetVMSelectLBName()
 		svc.Spec.ClusterIP = corev1.ClusterIPNone
@@ -245,6 +327,65 @@ func createOrUpdateVMSelectService(ctx context.Context, rclient client.Client, c
 	return svc, nil
 }
 
+// vmSelectReplicaServiceLabelValue marks dedicated vmselect pod services
+// such services are excluded from VMServiceScrape selector in order to prevent duplicate scraping
+const vmSelectReplicaServiceLabelValue = "per-replica"
+
+// buildVMSelectReplicaServices builds dedicated service for each vmselect pod
+func buildVMSelectReplicaServices(cr *vmv1beta1.VMCluster) []*corev1.Service {
+	if !cr.Spec.VMSelect.PerReplicaService {
+		return nil
+	}
+	baseSvc := buildVMSelectService(cr)
+	replicas := ptr.Deref(cr.Spec.VMSelect.ReplicaCount, 1)
+	svcs := make([]*corev1.Service, 0, replicas)
+	for i := int32(0); i < replicas; i++ {
+		svc := baseSvc.DeepCopy()
+		svc.Name = cr.GetVMSelectReplicaServiceName(i)
+		svc.Labels = labels.Merge(svc.Labels, map[string]string{vmv1beta1.AdditionalServiceLabel: vmSelectReplicaServiceLabelValue})
+		// pod and its dedicated service share the same name
+		svc.Spec.Selector = labels.Merge(svc.Spec.Selector, map[string]string{appsv1.StatefulSetPodNameLabel: svc.Name})
+		svcs = append(svcs, svc)
+	}
+	return svcs
+}
+
+// createOrUpdateVMSelectReplicaServices reconciles dedicated services for vmselect pods
+// and removes services of scaled down pods
+func createOrUpdateVMSelectReplicaServices(ctx context.Context, rclient client.Client, cr, prevCR *vmv1beta1.VMCluster) error {
+	prevSvcs := make(map[string]*corev1.Service)
+	if prevCR != nil && prevCR.Spec.VMSelect != nil {
+		for _, svc := range buildVMSelectReplicaServices(prevCR) {
+			prevSvcs[svc.Name] = svc
+		}
+	}
+	svcNames := make(map[string]struct{})
+	for _, svc := range buildVMSelectReplicaServices(cr) {
+		if err := reconcile.Service(ctx, rclient, svc, prevSvcs[svc.Name]); err != nil {
+			return fmt.Errorf("cannot reconcile vmselect replica service: %w", err)
+		}
+		svcNames[svc.Name] = struct{}{}
+	}
+	var existingSvcs corev1.ServiceList
+	opts := &client.ListOptions{
+		Namespace:     cr.Namespace,
+		LabelSelector: labels.SelectorFromSet(labels.Merge(cr.VMSelectSelectorLabels(), map[string]string{vmv1beta1.AdditionalServiceLabel: vmSelectReplicaServiceLabelValue})),
+	}
+	if err := rclient.List(ctx, &existingSvcs, opts); err != nil {
+		return fmt.Errorf("cannot list vmselect replica services: %w", err)
+	}
+	for i := range existingSvcs.Items {
+		svc := &existingSvcs.Items[i]
+		if _, ok := svcNames[svc.Name]; ok {
+			continue
+		}
+		if err := finalize.SafeDeleteWithFinalizer(ctx, rclient, svc); err != nil {
+			return fmt.Errorf("cannot remove vmselect replica service=%s: %w", svc.Name, err)
+		}
+	}
+	return nil
+}
+
 // createOrUpdateLBProxyService builds vminsert and vmselect external services to expose vmcluster components for access by vmauth
 func createOrUpdateLBProxyService(ctx context.Context, rclient client.Client, cr, prevCR *vmv1beta1.VMCluster, svcName, port, prevPort, targetName string, svcSelectorLabels map[string]string) error {
 
@@ -299,6 +440,11 @@ func createOrUpdateVMInsert(ctx context.Context, rclient client.Client, cr, prev
 	if err != nil {
 		return err
 	}
+	checksum, err := internalTLSChecksum(ctx, rclient, cr, vmv1beta1.VMClusterComponentVMInsert)
+	if err != nil {
+		return err
+	}
+	setInternalTLSChecksum(&newDeployment.Spec.Template, checksum)
 	return reconcile.Deployment(ctx, rclient, newDeployment, prevDeploy, cr.Spec.VMInsert.HPA != nil)
 }
 
@@ -371,26 +517,77 @@ func createOrUpdateVMInsertService(ctx context.Context, rclient client.Client, c
 	return newService, nil
 }
 
-func createOrUpdateVMStorage(ctx context.Context, rclient client.Client, cr, prevCR *vmv1beta1.VMCluster) error {
-	var prevSts *appsv1.StatefulSet
-
+// createOrUpdateVMStorage reconciles vmstorage StatefulSets
+// replicas of StatefulSets from scaleDowns are kept until vminsert and vmselect exclude removed nodes
+func createOrUpdateVMStorage(ctx context.Context, rclient client.Client, cr, prevCR *vmv1beta1.VMCluster, scaleDowns []storageScaleDown) error {
+	prevStsByName := make(map[string]*appsv1.StatefulSet)
 	if prevCR != nil && prevCR.Spec.VMStorage != nil {
-		var err error
-		prevSts, err = buildVMStorageSpec(ctx, prevCR)
+		prevSpecs, err := buildVMStorageSpecs(ctx, prevCR)
 		if err != nil {
 			return fmt.Errorf("cannot build prev storage spec: %w", err)
 		}
+		for _, prevSts := range prevSpecs {
+			prevStsByName[prevSts.Name] = prevSts
+		}
 	}
-	newSts, err := buildVMStorageSpec(ctx, cr)
+	newSpecs, err := buildVMStorageSpecs(ctx, cr)
+	if err != nil {
+		return err
+	}
+	checksum, err := internalTLSChecksum(ctx, rclient, cr, vmv1beta1.VMClusterComponentVMStorage)
 	if err != nil {
 		return err
 	}
 
-	stsOpts := reconcile.STSOptions{
-		HasClaim:       len(newSts.Spec.VolumeClaimTemplates) > 0,
-		SelectorLabels: cr.VMStorageSelectorLabels,
+	for _, newSts := range newSpecs {
+		setInternalTLSChecksum(&newSts.Spec.Template, checksum)
+		if err := reconcile.CheckSTSPVCShrink(ctx, rclient, newSts); err != nil {
+			setStorageShrinkRejectedCondition(cr, err)
+			return err
+		}
 	}
-	return reconcile.HandleSTSUpdate(ctx, rclient, stsOpts, newSts, prevSts)
+	setStorageShrinkRejectedCondition(cr, nil)
+
+	var inProgress bool
+	for _, newSts := range newSpecs {
+		for _, sd := range scaleDowns {
+			if sd.name == newSts.Name {
+				newSts.Spec.Replicas = ptr.To(sd.currentReplicas)
+			}
+		}
+		selectorLabels := newSts.Spec.Selector.MatchLabels
+		stsOpts := reconcile.STSOptions{
+			HasClaim:       len(newSts.Spec.VolumeClaimTemplates) > 0,
+			SelectorLabels: func() map[string]string { return selectorLabels },
+			PodReadyCheck:  buildVMStorageReadyCheck(rclient, cr),
+		}
+		if err := reconcile.HandleSTSUpdate(ctx, rclient, stsOpts, newSts, prevStsByName[newSts.Name]); err != nil {
+			return err
+		}
+		// PVCs are expanded by HandleSTSUpdate, but storage provider may resize volumes asynchronously
+		stsInProgress, err := reconcile.IsSTSPVCExpansionInProgress(ctx, rclient, newSts)
+		if err != nil {
+			return err
+		}
+		inProgress = inProgress || stsInProgress
+	}
+	cr.Status.StorageExpansionInProgress = inProgress
+	return nil
+}
+
+// setStorageShrinkRejectedCondition updates StorageShrinkRejected condition at the given VMCluster status
+func setStorageShrinkRejectedCondition(cr *vmv1beta1.VMCluster, shrinkErr error) {
+	cond := vmv1beta1.Condition{
+		Type:   vmv1beta1.VMClusterStorageShrinkRejectedCondition,
+		Status: metav1.ConditionFalse,
+		Reason: "StorageSizeApplied",
+	}
+	if shrinkErr != nil {
+		cond.Status = metav1.ConditionTrue
+		cond.Reason = vmv1beta1.VMClusterStorageShrinkRejectedCondition
+		cond.Message = shrinkErr.Error()
+	}
+	setVMClusterCondition(cr, cond)
 }
 
 func createOrUpdateVMStorageService(ctx context.Context, rclient client.Client, cr, prevCR *vmv1beta1.VMCluster) (*corev1.Service, error) {
@@ -486,30 +683,32 @@ func createOrUpdateVMStorageService(ctx context.Context, rclient client.Client,
 	return newHeadless, nil
 }
 
-func genVMSelectSpec(cr *vmv1beta1.VMCluster) (*appsv1.StatefulSet, error) {
-	podSpec, err := makePodSpecForVMSelect(cr)
+// genVMSelectSpec builds vmselect StatefulSet for the given pool
+// empty pool refers to the default vmselect pool
+func genVMSelectSpec(cr *vmv1beta1.VMCluster, pool string) (*appsv1.StatefulSet, error) {
+	podSpec, err := makePodSpecForVMSelect(cr, pool)
 	if err != nil {
 		return nil, err
 	}
 
 	stsSpec := &appsv1.StatefulSet{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:            cr.GetVMSelectName(),
+			Name:            cr.GetVMSelectPoolName(pool),
 			Namespace:       cr.Namespace,
-			Labels:          cr.FinalLabels(cr.VMSelectSelectorLabels()),
+			Labels:          cr.FinalLabels(cr.VMSelectPoolSelectorLabels(pool)),
 			Annotations:     cr.AnnotationsFiltered(),
 			OwnerReferences: cr.AsOwner(),
 			Finalizers:      []string{vmv1beta1.FinalizerName},
 		},
 		Spec: appsv1.StatefulSetSpec{
 			Selector: &metav1.LabelSelector{
-				MatchLabels: cr.VMSelectSelectorLabels(),
+				MatchLabels: cr.VMSelectPoolSelectorLabels(pool),
 			},
 			UpdateStrategy: appsv1.StatefulSetUpdateStrategy{
 				Type: cr.Spec.VMSelect.RollingUpdateStrategy,
 			},
 			Template:    *podSpec,
-			ServiceName: cr.GetVMSelectName(),
+			ServiceName: cr.GetVMSelectPoolName(pool),
 		},
 	}
 	build.StatefulSetAddCommonParams(stsSpec, ptr.Deref(cr.Spec.VMSelect.UseStrictSecurity, false), &cr.Spec.VMSelect.CommonApplicationDeploymentParams)
@@ -525,7 +724,17 @@ func genVMSelectSpec(cr *vmv1beta1.VMCluster) (*appsv1.StatefulSet, error) {
 	return stsSpec, nil
 }
 
-func makePodSpecForVMSelect(cr *vmv1beta1.VMCluster) (*corev1.PodTemplateSpec, error) {
+// buildDownsamplingArg builds -downsampling.period flag
+// it must be the same for vmselect and vmstorage
+func buildDownsamplingArg(cr *vmv1beta1.VMCluster) string {
+	periods := make([]string, 0, len(cr.Spec.Downsampling))
+	for _, dp := range cr.Spec.Downsampling {
+		periods = append(periods, dp.String())
+	}
+	return fmt.Sprintf("-downsampling.period=%s", strings.Join(periods, ","))
+}
+
+func makePodSpecForVMSelect(cr *vmv1beta1.VMCluster, pool string) (*corev1.PodTemplateSpec, error) {
 	args := []string{
 		fmt.Sprintf("-httpListenAddr=:%s", cr.Spec.VMSelect.Port),
 	}
@@ -538,6 +747,9 @@ func makePodSpecForVMSelect(cr *vmv1beta1.VMCluster) (*corev1.PodTemplateSpec, e
 	if cr.Spec.VMSelect.LogFormat != "" {
 		args = append(args, fmt.Sprintf("-loggerFormat=%s", cr.Spec.VMSelect.LogFormat))
 	}
+	if len(cr.Spec.Downsampling) > 0 {
+		args = append(args, buildDownsamplingArg(cr))
+	}
 	if cr.Spec.ReplicationFactor != nil && *cr.Spec.ReplicationFactor > 1 {
 		var replicationFactorIsSet bool
 		var dedupIsSet bool
@@ -557,15 +769,13 @@ func makePodSpecForVMSelect(cr *vmv1beta1.VMCluster) (*corev1.PodTemplateSpec, e
 		}
 	}
 
-	if cr.Spec.VMStorage != nil && cr.Spec.VMStorage.ReplicaCount != nil {
-
+	if storagePods := cr.AvailableStorageNodePods("select"); len(storagePods) > 0 {
 		storageArg := "-storageNode="
-		for _, i := range cr.AvailableStorageNodeIDs("select") {
-			storageArg += build.PodDNSAddress(cr.GetVMStorageName(), i, cr.Namespace, cr.Spec.VMStorage.VMSelectPort, cr.Spec.ClusterDomainName)
+		for _, pod := range storagePods {
+			storageArg += build.ServicePodDNSAddress(pod, cr.GetVMStorageName(), cr.Namespace, cr.Spec.VMStorage.VMSelectPort, cr.Spec.ClusterDomainName)
 		}
 		storageArg = strings.TrimSuffix(storageArg, ",")
 		args = append(args, storageArg)
-
 	}
 	// selectNode arg add for deployments without HPA
 	// HPA leads to rolling restart for vmselect statefulset in case of replicas count changes
@@ -573,7 +783,7 @@ func makePodSpecForVMSelect(cr *vmv1beta1.VMCluster) (*corev1.PodTemplateSpec, e
 		selectArg := "-selectNode="
 		vmselectCount := *cr.Spec.VMSelect.ReplicaCount
 		for i := int32(0); i < vmselectCount; i++ {
-			selectArg += build.PodDNSAddress(cr.GetVMSelectName(), i, cr.Namespace, cr.Spec.VMSelect.Port, cr.Spec.ClusterDomainName)
+			selectArg += build.PodDNSAddress(cr.GetVMSelectPoolName(pool), i, cr.Namespace, cr.Spec.VMSelect.Port, cr.Spec.ClusterDomainName)
 		}
 		selectArg = strings.TrimSuffix(selectArg, ",")
 		args = append(args, selectArg)
@@ -643,6 +853,7 @@ func makePodSpecForVMSelect(cr *vmv1beta1.VMCluster) (*corev1.PodTemplateSpec, e
 
 	volumes, vmMounts = cr.Spec.License.MaybeAddToVolumes(volumes, vmMounts, vmv1beta1.SecretsDir)
 	args = cr.Spec.License.MaybeAddToArgs(args, vmv1beta1.SecretsDir)
+	args, volumes, vmMounts = addInternalTLSToPodSpec(cr, vmv1beta1.VMClusterComponentVMSelect, args, volumes, vmMounts)
 
 	args = build.AddExtraArgsOverrideDefaults(args, cr.Spec.VMSelect.ExtraArgs, "-")
 	sort.Strings(args)
@@ -671,14 +882,14 @@ func makePodSpecForVMSelect(cr *vmv1beta1.VMCluster) (*corev1.PodTemplateSpec, e
 	for i := range cr.Spec.VMSelect.TopologySpreadConstraints {
 		if cr.Spec.VMSelect.TopologySpreadConstraints[i].LabelSelector == nil {
 			cr.Spec.VMSelect.TopologySpreadConstraints[i].LabelSelector = &metav1.LabelSelector{
-				MatchLabels: cr.VMSelectSelectorLabels(),
+				MatchLabels: cr.VMSelectPoolSelectorLabels(pool),
 			}
 		}
 	}
 
 	vmSelectPodSpec := &corev1.PodTemplateSpec{
 		ObjectMeta: metav1.ObjectMeta{
-			Labels:      cr.VMSelectPodLabels(),
+			Labels:      cr.VMSelectPoolPodLabels(pool),
 			Annotations: cr.VMSelectPodAnnotations(),
 		},
 		Spec: corev1.PodSpec{
@@ -693,13 +904,24 @@ func makePodSpecForVMSelect(cr *vmv1beta1.VMCluster) (*corev1.PodTemplateSpec, e
 	return vmSelectPodSpec, nil
 }
 
+// componentPDBSpec returns PodDisruptionBudget spec for cluster component.
+// It allows a single unavailable pod if neither minAvailable nor maxUnavailable is set
+func componentPDBSpec(spec *vmv1beta1.EmbeddedPodDisruptionBudgetSpec) *vmv1beta1.EmbeddedPodDisruptionBudgetSpec {
+	if spec.MinAvailable != nil || spec.MaxUnavailable != nil {
+		return spec
+	}
+	spec = spec.DeepCopy()
+	spec.MaxUnavailable = ptr.To(intstr.FromInt32(1))
+	return spec
+}
+
 func createOrUpdatePodDisruptionBudgetForVMSelect(ctx context.Context, rclient client.Client, cr, prevCR *vmv1beta1.VMCluster) error {
 	t := newOptsBuilder(cr, cr.GetVMSelectName(), cr.VMSelectSelectorLabels())
-	pdb := build.PodDisruptionBudget(t, cr.Spec.VMSelect.PodDisruptionBudget)
+	pdb := build.PodDisruptionBudget(t, componentPDBSpec(cr.Spec.VMSelect.PodDisruptionBudget))
 	var prevPDB *policyv1.PodDisruptionBudget
-	if prevCR != nil && prevCR.Spec.VMSelect.PodDisruptionBudget != nil {
+	if prevCR != nil && prevCR.Spec.VMSelect != nil && prevCR.Spec.VMSelect.PodDisruptionBudget != nil {
 		t = newOptsBuilder(prevCR, prevCR.GetVMSelectName(), prevCR.VMSelectSelectorLabels())
-		prevPDB = build.PodDisruptionBudget(t, prevCR.Spec.VMSelect.PodDisruptionBudget)
+		prevPDB = build.PodDisruptionBudget(t, componentPDBSpec(prevCR.Spec.VMSelect.PodDisruptionBudget))
 	}
 	return reconcile.PDB(ctx, rclient, pdb, prevPDB)
 }
@@ -758,10 +980,10 @@ func makePodSpecForVMInsert(cr *vmv1beta1.VMCluster) (*corev1.PodTemplateSpec, e
 		args = append(args, fmt.Sprintf("--clusternativeListenAddr=:%s", cr.Spec.VMInsert.ClusterNativePort))
 	}
 
-	if cr.Spec.VMStorage != nil && cr.Spec.VMStorage.ReplicaCount != nil {
+	if storagePods := cr.AvailableStorageNodePods("insert"); len(storagePods) > 0 {
 		storageArg := "-storageNode="
-		for _, i := range cr.AvailableStorageNodeIDs("insert") {
-			storageArg += build.PodDNSAddress(cr.GetVMStorageName(), i, cr.Namespace, cr.Spec.VMStorage.VMInsertPort, cr.Spec.ClusterDomainName)
+		for _, pod := range storagePods {
+			storageArg += build.ServicePodDNSAddress(pod, cr.GetVMStorageName(), cr.Namespace, cr.Spec.VMStorage.VMInsertPort, cr.Spec.ClusterDomainName)
 		}
 		storageArg = strings.TrimSuffix(storageArg, ",")
 
@@ -840,6 +1062,7 @@ func makePodSpecForVMInsert(cr *vmv1beta1.VMCluster) (*corev1.PodTemplateSpec, e
 	}
 	volumes, vmMounts = cr.Spec.License.MaybeAddToVolumes(volumes, vmMounts, vmv1beta1.SecretsDir)
 	args = cr.Spec.License.MaybeAddToArgs(args, vmv1beta1.SecretsDir)
+	args, volumes, vmMounts = addInternalTLSToPodSpec(cr, vmv1beta1.VMClusterComponentVMInsert, args, volumes, vmMounts)
 
 	args = build.AddExtraArgsOverrideDefaults(args, cr.Spec.VMInsert.ExtraArgs, "-")
 	sort.Strings(args)
@@ -891,11 +1114,11 @@ func makePodSpecForVMInsert(cr *vmv1beta1.VMCluster) (*corev1.PodTemplateSpec, e
 
 func createOrUpdatePodDisruptionBudgetForVMInsert(ctx context.Context, rclient client.Client, cr, prevCR *vmv1beta1.VMCluster) error {
 	t := newOptsBuilder(cr, cr.GetVMInsertName(), cr.VMInsertSelectorLabels())
-	pdb := build.PodDisruptionBudget(t, cr.Spec.VMInsert.PodDisruptionBudget)
+	pdb := build.PodDisruptionBudget(t, componentPDBSpec(cr.Spec.VMInsert.PodDisruptionBudget))
 	var prevPDB *policyv1.PodDisruptionBudget
-	if prevCR != nil && prevCR.Spec.VMInsert.PodDisruptionBudget != nil {
+	if prevCR != nil && prevCR.Spec.VMInsert != nil && prevCR.Spec.VMInsert.PodDisruptionBudget != nil {
 		t = newOptsBuilder(prevCR, prevCR.GetVMInsertName(), prevCR.VMInsertSelectorLabels())
-		prevPDB = build.PodDisruptionBudget(t, prevCR.Spec.VMInsert.PodDisruptionBudget)
+		prevPDB = build.PodDisruptionBudget(t, componentPDBSpec(prevCR.Spec.VMInsert.PodDisruptionBudget))
 	}
 	return reconcile.PDB(ctx, rclient, pdb, prevPDB)
 }
@@ -935,6 +1158,40 @@ func buildVMStorageSpec(ctx context.Context, cr *vmv1beta1.VMCluster) (*appsv1.S
 	return stsSpec, nil
 }
 
+// buildVMStorageSpecs builds vmstorage StatefulSets
+// a dedicated StatefulSet is built for each storage node group
+func buildVMStorageSpecs(ctx context.Context, cr *vmv1beta1.VMCluster) ([]*appsv1.StatefulSet, error) {
+	stsSpec, err := buildVMStorageSpec(ctx, cr)
+	if err != nil {
+		return nil, err
+	}
+	groups := cr.Spec.VMStorage.StorageNodeGroups
+	if len(groups) == 0 {
+		return []*appsv1.StatefulSet{stsSpec}, nil
+	}
+	result := make([]*appsv1.StatefulSet, 0, len(groups))
+	for _, g := range groups {
+		groupSts := stsSpec.DeepCopy()
+		selectorLabels := cr.VMStorageGroupSelectorLabels(g.Name)
+		groupSts.Name = cr.GetVMStorageGroupName(g.Name)
+		groupSts.Labels = cr.FinalLabels(selectorLabels)
+		groupSts.Spec.Selector = &metav1.LabelSelector{MatchLabels: selectorLabels}
+		groupSts.Spec.Template.Labels[vmv1beta1.VMStorageGroupLabel] = g.Name
+		groupSts.Spec.Replicas = g.ReplicaCount
+		if g.NodeSelector != nil {
+			groupSts.Spec.Template.Spec.NodeSelector = g.NodeSelector
+		}
+		if g.Affinity != nil {
+			groupSts.Spec.Template.Spec.Affinity = g.Affinity
+		}
+		if g.Tolerations != nil {
+			groupSts.Spec.Template.Spec.Tolerations = g.Tolerations
+		}
+		result = append(result, groupSts)
+	}
+	return result, nil
+}
+
 func makePodSpecForVMStorage(ctx context.Context, cr *vmv1beta1.VMCluster) (*corev1.PodTemplateSpec, error) {
 	args := []string{
 		fmt.Sprintf("-vminsertAddr=:%s", cr.Spec.VMStorage.VMInsertPort),
@@ -942,6 +1199,16 @@ func makePodSpecForVMStorage(ctx context.Context, cr *vmv1beta1.VMCluster) (*cor
 		fmt.Sprintf("-httpListenAddr=:%s", cr.Spec.VMStorage.Port),
 		fmt.Sprintf("-retentionPeriod=%s", cr.Spec.RetentionPeriod),
 	}
+	if len(cr.Spec.RetentionFilters) > 0 {
+		filters := make([]string, 0, len(cr.Spec.RetentionFilters))
+		for _, rf := range cr.Spec.RetentionFilters {
+			filters = append(filters, rf.String())
+		}
+		args = append(args, fmt.Sprintf("-retentionFilter=%s", strings.Join(filters, ",")))
+	}
+	if len(cr.Spec.Downsampling) > 0 {
+		args = append(args, buildDownsamplingArg(cr))
+	}
 	if cr.Spec.VMStorage.LogLevel != "" {
 		args = append(args, fmt.Sprintf("-loggerLevel=%s", cr.Spec.VMStorage.LogLevel))
 	}
@@ -1047,6 +1314,7 @@ func makePodSpecForVMStorage(ctx context.Context, cr *vmv1beta1.VMCluster) (*cor
 
 	volumes, vmMounts = cr.Spec.License.MaybeAddToVolumes(volumes, vmMounts, vmv1beta1.SecretsDir)
 	args = cr.Spec.License.MaybeAddToArgs(args, vmv1beta1.SecretsDir)
+	args, volumes, vmMounts = addInternalTLSToPodSpec(cr, vmv1beta1.VMClusterComponentVMStorage, args, volumes, vmMounts)
 
 	args = build.AddExtraArgsOverrideDefaults(args, cr.Spec.VMStorage.ExtraArgs, "-")
 	sort.Strings(args)
@@ -1079,7 +1347,7 @@ func makePodSpecForVMStorage(ctx context.Context, cr *vmv1beta1.VMCluster) (*cor
 		if cr.Spec.VMStorage.VMBackup.Restore != nil &&
 			cr.Spec.VMStorage.VMBackup.Restore.OnStart != nil &&
 			cr.Spec.VMStorage.VMBackup.Restore.OnStart.Enabled {
-			vmRestore, err := build.VMRestore(cr.Spec.VMStorage.VMBackup, cr.Spec.VMStorage.StorageDataPath, cr.Spec.VMStorage.GetStorageVolumeName())
+			vmRestore, err := build.VMRestore(cr.Spec.VMStorage.VMBackup, cr.Spec.VMStorage.StorageDataPath, cr.Spec.VMStorage.GetStorageVolumeName(), cr.Spec.License)
 			if err != nil {
 				return nil, err
 			}
@@ -1127,11 +1395,11 @@ func makePodSpecForVMStorage(ctx context.Context, cr *vmv1beta1.VMCluster) (*cor
 
 func createOrUpdatePodDisruptionBudgetForVMStorage(ctx context.Context, rclient client.Client, cr, prevCR *vmv1beta1.VMCluster) error {
 	t := newOptsBuilder(cr, cr.GetVMStorageName(), cr.VMStorageSelectorLabels())
-	pdb := build.PodDisruptionBudget(t, cr.Spec.VMStorage.PodDisruptionBudget)
+	pdb := build.PodDisruptionBudget(t, componentPDBSpec(cr.Spec.VMStorage.PodDisruptionBudget))
 	var prevPDB *policyv1.PodDisruptionBudget
-	if prevCR != nil && prevCR.Spec.VMStorage.PodDisruptionBudget != nil {
+	if prevCR != nil && prevCR.Spec.VMStorage != nil && prevCR.Spec.VMStorage.PodDisruptionBudget != nil {
 		t = newOptsBuilder(prevCR, prevCR.GetVMStorageName(), prevCR.VMStorageSelectorLabels())
-		prevPDB = build.PodDisruptionBudget(t, prevCR.Spec.VMStorage.PodDisruptionBudget)
+		prevPDB = build.PodDisruptionBudget(t, componentPDBSpec(prevCR.Spec.VMStorage.PodDisruptionBudget))
 	}
 	return reconcile.PDB(ctx, rclient, pdb, prevPDB)
 }
@@ -1215,7 +1483,7 @@ func deletePrevStateResources(ctx context.Context, rclient client.Client, cr, pr
 	prevSt := prevSpec.VMStorage
 	prevSe := prevSpec.VMSelect
 	prevIs := prevSpec.VMInsert
-	if prevSt != nil {
+	if prevSt != nil && !cr.IsComponentPaused(vmv1beta1.VMClusterComponentVMStorage) {
 		if vmst == nil {
 			if err := finalize.OnVMStorageDelete(ctx, rclient, cr, prevSt); err != nil {
 				return fmt.Errorf("cannot remove storage from prev state: %w", err)
@@ -1236,10 +1504,21 @@ func deletePrevStateResources(ctx context.Context, rclient client.Client, cr, pr
 			if err := reconcile.AdditionalServices(ctx, rclient, cr.GetVMStorageName(), cr.Namespace, prevSvc, currSvc); err != nil {
 				return fmt.Errorf("cannot remove vmstorage additional service: %w", err)
 			}
+			// storage node group StatefulSets are removed only after vminsert and vmselect update
+			// PersistentVolumeClaims of removed StatefulSets are kept
+			currStsNames := cr.VMStorageStatefulSetNames()
+			for _, name := range prevCR.VMStorageStatefulSetNames() {
+				if slices.Contains(currStsNames, name) {
+					continue
+				}
+				if err := finalize.SafeDeleteWithFinalizer(ctx, rclient, &appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Namespace: cr.Namespace, Name: name}}); err != nil {
+					return fmt.Errorf("cannot remove vmstorage statefulset=%s from prev state: %w", name, err)
+				}
+			}
 		}
 	}
 
-	if prevSe != nil {
+	if prevSe != nil && !cr.IsComponentPaused(vmv1beta1.VMClusterComponentVMSelect) {
 		if vmse == nil {
 			if err := finalize.OnVMSelectDelete(ctx, rclient, cr, prevSe); err != nil {
 				return fmt.Errorf("cannot remove select from prev state: %w", err)
@@ -1267,6 +1546,9 @@ func deletePrevStateResources(ctx context.Context, rclient client.Client, cr, pr
 				return fmt.Errorf("cannot remove vmselect additional service: %w", err)
 			}
 		}
+		if err := deletePrevVMSelectPools(ctx, rclient, cr, prevCR); err != nil {
+			return err
+		}
 		// transition to load-balancer state
 		// have to remove prev service scrape
 		if (!prevSpec.RequestsLoadBalancer.Enabled &&
@@ -1305,7 +1587,7 @@ func deletePrevStateResources(ctx context.Context, rclient client.Client, cr, pr
 		}
 	}
 
-	if prevIs != nil {
+	if prevIs != nil && !cr.IsComponentPaused(vmv1beta1.VMClusterComponentVMInsert) {
 		if vmis == nil {
 			if err := finalize.OnVMInsertDelete(ctx, rclient, cr, prevIs); err != nil {
 				return fmt.Errorf("cannot remove insert from prev state: %w", err)
@@ -1427,10 +1709,12 @@ unauthorized_user:
     - "/insert/.*"
     url_prefix: "http://srv+%s.%s:%s"
     discover_backend_ips: true
+    load_balancing_policy: least_loaded
   - src_paths:
     - "/.*"
     url_prefix: "http://srv+%s.%s:%s"
     discover_backend_ips: true
+    load_balancing_policy: least_loaded
       `, cr.GetVMInsertLBName(), targetHostSuffix, insertPort,
 			cr.GetVMSelectLBName(), targetHostSuffix, selectPort,
 		)},
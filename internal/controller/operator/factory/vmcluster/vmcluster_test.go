@@ -2,6 +2,7 @@ package vmcluster
 
 import (
 	"context"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -13,8 +14,10 @@ import (
 	"gopkg.in/yaml.v3"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
@@ -1011,3 +1014,286 @@ spec:
 		},
 	})
 }
+
+func TestCreateOrUpdatePodDisruptionBudgetForVMSelect(t *testing.T) {
+	f := func(spec *vmv1beta1.EmbeddedPodDisruptionBudgetSpec, prevCR *vmv1beta1.VMCluster, want policyv1.PodDisruptionBudgetSpec) {
+		t.Helper()
+		cr := &vmv1beta1.VMCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster", Namespace: "default"},
+			Spec: vmv1beta1.VMClusterSpec{
+				VMSelect: &vmv1beta1.VMSelect{PodDisruptionBudget: spec},
+			},
+		}
+		fclient := k8stools.GetTestClientWithObjects(nil)
+		ctx := context.TODO()
+		if err := createOrUpdatePodDisruptionBudgetForVMSelect(ctx, fclient, cr, prevCR); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		var pdb policyv1.PodDisruptionBudget
+		if err := fclient.Get(ctx, types.NamespacedName{Namespace: cr.Namespace, Name: cr.GetVMSelectName()}, &pdb); err != nil {
+			t.Fatalf("cannot get pdb: %s", err)
+		}
+		assert.Equal(t, want, pdb.Spec)
+		assert.Equal(t, cr.AsOwner(), pdb.OwnerReferences)
+	}
+	selector := &metav1.LabelSelector{MatchLabels: map[string]string{
+		"app.kubernetes.io/component": "monitoring",
+		"app.kubernetes.io/instance":  "cluster",
+		"app.kubernetes.io/name":      "vmselect",
+		"managed-by":                  "vm-operator",
+	}}
+
+	// default budget
+	f(&vmv1beta1.EmbeddedPodDisruptionBudgetSpec{}, nil, policyv1.PodDisruptionBudgetSpec{
+		MaxUnavailable: ptr.To(intstr.FromInt32(1)),
+		Selector:       selector,
+	})
+
+	// vmselect added to existing cluster
+	prevCR := &vmv1beta1.VMCluster{ObjectMeta: metav1.ObjectMeta{Name: "cluster", Namespace: "default"}}
+	f(&vmv1beta1.EmbeddedPodDisruptionBudgetSpec{MinAvailable: ptr.To(intstr.FromString("50%"))}, prevCR, policyv1.PodDisruptionBudgetSpec{
+		MinAvailable: ptr.To(intstr.FromString("50%")),
+		Selector:     selector,
+	})
+}
+
+func TestCreateOrUpdateVMSelectReplicaServices(t *testing.T) {
+	f := func(perReplicaService bool, replicas int32, wantSvcNames []string) {
+		t.Helper()
+		cr := &vmv1beta1.VMCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster", Namespace: "default"},
+			Spec: vmv1beta1.VMClusterSpec{
+				VMSelect: &vmv1beta1.VMSelect{
+					PerReplicaService: perReplicaService,
+					CommonApplicationDeploymentParams: vmv1beta1.CommonApplicationDeploymentParams{
+						ReplicaCount: ptr.To(replicas),
+					},
+				},
+			},
+		}
+		ctx := context.TODO()
+		fclient := k8stools.GetTestClientWithObjects([]runtime.Object{
+			// common vmselect service must be kept
+			&corev1.Service{ObjectMeta: metav1.ObjectMeta{
+				Name:      cr.GetVMSelectName(),
+				Namespace: cr.Namespace,
+				Labels:    cr.VMSelectSelectorLabels(),
+			}},
+			// service of scaled down replica must be removed
+			&corev1.Service{ObjectMeta: metav1.ObjectMeta{
+				Name:      cr.GetVMSelectReplicaServiceName(3),
+				Namespace: cr.Namespace,
+				Labels:    labels.Merge(cr.VMSelectSelectorLabels(), map[string]string{vmv1beta1.AdditionalServiceLabel: vmSelectReplicaServiceLabelValue}),
+			}},
+		})
+		if err := createOrUpdateVMSelectReplicaServices(ctx, fclient, cr, nil); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		var svcs corev1.ServiceList
+		if err := fclient.List(ctx, &svcs); err != nil {
+			t.Fatalf("cannot list services: %s", err)
+		}
+		var gotSvcNames []string
+		for _, svc := range svcs.Items {
+			gotSvcNames = append(gotSvcNames, svc.Name)
+			if svc.Name == cr.GetVMSelectName() {
+				continue
+			}
+			assert.Equal(t, svc.Name, svc.Spec.Selector[appsv1.StatefulSetPodNameLabel])
+			assert.Equal(t, vmSelectReplicaServiceLabelValue, svc.Labels[vmv1beta1.AdditionalServiceLabel])
+		}
+		assert.ElementsMatch(t, wantSvcNames, gotSvcNames)
+	}
+
+	// disabled
+	f(false, 2, []string{"vmselect-cluster"})
+
+	// enabled
+	f(true, 2, []string{"vmselect-cluster", "vmselect-cluster-0", "vmselect-cluster-1"})
+}
+
+func TestRetentionFiltersAndDownsamplingArgs(t *testing.T) {
+	f := func(spec vmv1beta1.VMClusterSpec, wantStorageArgs, wantSelectArgs []string) {
+		t.Helper()
+		spec.VMStorage = &vmv1beta1.VMStorage{}
+		spec.VMSelect = &vmv1beta1.VMSelect{}
+		cr := &vmv1beta1.VMCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster", Namespace: "default"},
+			Spec:       spec,
+		}
+		storagePod, err := makePodSpecForVMStorage(context.TODO(), cr)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		selectPod, err := makePodSpecForVMSelect(cr, "")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		hasArgs := func(args, want []string) {
+			t.Helper()
+			for _, arg := range want {
+				assert.Contains(t, args, arg)
+			}
+		}
+		hasArgs(storagePod.Spec.Containers[0].Args, wantStorageArgs)
+		hasArgs(selectPod.Spec.Containers[0].Args, wantSelectArgs)
+	}
+
+	f(vmv1beta1.VMClusterSpec{
+		RetentionFilters: []vmv1beta1.VMClusterRetentionFilter{
+			{Filter: `{vm_account_id="5",env="dev"}`, Retention: "5d"},
+			{Filter: `{vm_account_id="5",env="prod"}`, Retention: "5y"},
+		},
+	}, []string{`-retentionFilter={vm_account_id="5",env="dev"}:5d,{vm_account_id="5",env="prod"}:5y`}, nil)
+
+	f(vmv1beta1.VMClusterSpec{
+		Downsampling: []vmv1beta1.VMClusterDownsamplingPeriod{
+			{Offset: "30d", Interval: "5m"},
+			{Filter: `{__name__=~"node_.*"}`, Offset: "180d", Interval: "1h"},
+		},
+	}, []string{`-downsampling.period=30d:5m,{__name__=~"node_.*"}:180d:1h`}, []string{`-downsampling.period=30d:5m,{__name__=~"node_.*"}:180d:1h`})
+}
+
+func TestBuildVMStorageSpecsWithGroups(t *testing.T) {
+	cr := &vmv1beta1.VMCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster", Namespace: "default"},
+		Spec: vmv1beta1.VMClusterSpec{
+			VMStorage: &vmv1beta1.VMStorage{
+				VMInsertPort: "8400",
+				VMSelectPort: "8401",
+				StorageNodeGroups: []vmv1beta1.VMStorageNodeGroup{
+					{Name: "zone-a", ReplicaCount: ptr.To[int32](2), NodeSelector: map[string]string{"topology.kubernetes.io/zone": "a"}},
+					{Name: "zone-b", ReplicaCount: ptr.To[int32](1), NodeSelector: map[string]string{"topology.kubernetes.io/zone": "b"}},
+				},
+				CommonApplicationDeploymentParams: vmv1beta1.CommonApplicationDeploymentParams{
+					ReplicaCount: ptr.To[int32](5),
+					NodeSelector: map[string]string{"disktype": "ssd"},
+				},
+			},
+			VMInsert: &vmv1beta1.VMInsert{},
+		},
+	}
+	specs, err := buildVMStorageSpecs(context.TODO(), cr)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.Len(t, specs, 2)
+	for i, want := range []struct {
+		name     string
+		group    string
+		replicas int32
+		zone     string
+	}{
+		{name: "vmstorage-cluster-zone-a", group: "zone-a", replicas: 2, zone: "a"},
+		{name: "vmstorage-cluster-zone-b", group: "zone-b", replicas: 1, zone: "b"},
+	} {
+		sts := specs[i]
+		assert.Equal(t, want.name, sts.Name)
+		assert.Equal(t, "vmstorage-cluster", sts.Spec.ServiceName)
+		assert.Equal(t, want.replicas, *sts.Spec.Replicas)
+		assert.Equal(t, cr.VMStorageGroupSelectorLabels(want.group), sts.Spec.Selector.MatchLabels)
+		assert.Equal(t, want.group, sts.Spec.Template.Labels[vmv1beta1.VMStorageGroupLabel])
+		assert.Equal(t, map[string]string{"topology.kubernetes.io/zone": want.zone}, sts.Spec.Template.Spec.NodeSelector)
+	}
+
+	insertPod, err := makePodSpecForVMInsert(cr)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.Contains(t, insertPod.Spec.Containers[0].Args, "-storageNode="+
+		"vmstorage-cluster-zone-a-0.vmstorage-cluster.default:8400,"+
+		"vmstorage-cluster-zone-a-1.vmstorage-cluster.default:8400,"+
+		"vmstorage-cluster-zone-b-0.vmstorage-cluster.default:8400")
+}
+
+func TestBuildVMStorageSpecWithBackup(t *testing.T) {
+	f := func(license *vmv1beta1.License, acceptEULA bool, wantLicenseArg string) {
+		t.Helper()
+		cr := &vmv1beta1.VMCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster", Namespace: "default"},
+			Spec: vmv1beta1.VMClusterSpec{
+				License: license,
+				VMStorage: &vmv1beta1.VMStorage{
+					StorageDataPath: "/vm-data",
+					VMBackup: &vmv1beta1.VMBackup{
+						AcceptEULA:  acceptEULA,
+						Destination: "s3://bucket/folder",
+						Port:        "8300",
+						Restore:     &vmv1beta1.VMRestore{OnStart: &vmv1beta1.VMRestoreOnStartConfig{Enabled: true}},
+					},
+				},
+			},
+		}
+		sts, err := buildVMStorageSpec(context.TODO(), cr)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		var backupContainer, restoreContainer *corev1.Container
+		for i := range sts.Spec.Template.Spec.Containers {
+			if sts.Spec.Template.Spec.Containers[i].Name == "vmbackuper" {
+				backupContainer = &sts.Spec.Template.Spec.Containers[i]
+			}
+		}
+		for i := range sts.Spec.Template.Spec.InitContainers {
+			if sts.Spec.Template.Spec.InitContainers[i].Name == "vmbackuper-restore" {
+				restoreContainer = &sts.Spec.Template.Spec.InitContainers[i]
+			}
+		}
+		if !acceptEULA && !license.IsProvided() {
+			assert.Nil(t, backupContainer)
+			assert.Nil(t, restoreContainer)
+			return
+		}
+		if assert.NotNil(t, backupContainer) {
+			assert.Contains(t, backupContainer.Args, "-dst=s3://bucket/folder/$(POD_NAME)/")
+			if wantLicenseArg != "" {
+				assert.Contains(t, backupContainer.Args, wantLicenseArg)
+			}
+		}
+		if assert.NotNil(t, restoreContainer) {
+			assert.Contains(t, restoreContainer.Args, "-storageDataPath=/vm-data")
+			if wantLicenseArg != "" {
+				assert.Contains(t, restoreContainer.Args, wantLicenseArg)
+			}
+		}
+	}
+
+	// no license and eula
+	f(nil, false, "")
+
+	// eula accepted
+	f(nil, true, "")
+
+	// license secret
+	f(&vmv1beta1.License{KeyRef: &corev1.SecretKeySelector{
+		LocalObjectReference: corev1.LocalObjectReference{Name: "license"},
+		Key:                  "key",
+	}}, false, "-licenseFile=/etc/vm/secrets/license/key")
+}
+
+func TestBuildVMauthLBSecret(t *testing.T) {
+	cr := &vmv1beta1.VMCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster", Namespace: "default"},
+		Spec: vmv1beta1.VMClusterSpec{
+			VMInsert: &vmv1beta1.VMInsert{CommonDefaultableParams: vmv1beta1.CommonDefaultableParams{Port: "8480"}},
+			VMSelect: &vmv1beta1.VMSelect{CommonDefaultableParams: vmv1beta1.CommonDefaultableParams{Port: "8481"}},
+		},
+	}
+	cr.Spec.RequestsLoadBalancer.Enabled = true
+	secret := buildVMauthLBSecret(cr)
+	wantCfg := `
+unauthorized_user:
+  url_map:
+  - src_paths:
+    - "/insert/.*"
+    url_prefix: "http://srv+vminsertinternal-cluster.default.svc:8480"
+    discover_backend_ips: true
+    load_balancing_policy: least_loaded
+  - src_paths:
+    - "/.*"
+    url_prefix: "http://srv+vmselectinternal-cluster.default.svc:8481"
+    discover_backend_ips: true
+    load_balancing_policy: least_loaded
+`
+	assert.Equal(t, strings.TrimSpace(wantCfg), strings.TrimSpace(secret.StringData["config.yaml"]))
+}
@@ -0,0 +1,163 @@
+package vmcluster
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/build"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/finalize"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/reconcile"
+)
+
+// vmSelectPoolCluster returns copy of the given cluster with vmselect spec of the additional pool
+// pool inherits vmselect configuration and overrides it with the defined pool fields
+func vmSelectPoolCluster(cr *vmv1beta1.VMCluster, pool *vmv1beta1.VMSelectPool) *vmv1beta1.VMCluster {
+	poolCR := cr.DeepCopy()
+	vmse := poolCR.Spec.VMSelect
+	vmse.AdditionalPools = nil
+	// autoscaling, disruption budget and additional services are managed only for the default pool
+	vmse.HPA = nil
+	vmse.PodDisruptionBudget = nil
+	vmse.ServiceSpec = nil
+	vmse.PerReplicaService = false
+	if pool.ReplicaCount != nil {
+		vmse.ReplicaCount = pool.ReplicaCount
+	}
+	if pool.Resources != nil {
+		vmse.Resources = *pool.Resources
+	}
+	if len(pool.ExtraArgs) > 0 {
+		vmse.ExtraArgs = labels.Merge(vmse.ExtraArgs, pool.ExtraArgs)
+	}
+	if pool.CacheMountPath != "" {
+		vmse.CacheMountPath = pool.CacheMountPath
+	}
+	return poolCR
+}
+
+// vmSelectPools returns additional vmselect pools of the given cluster mapped by name
+func vmSelectPools(cr *vmv1beta1.VMCluster) map[string]*vmv1beta1.VMSelectPool {
+	if cr == nil || cr.Spec.VMSelect == nil {
+		return nil
+	}
+	pools := make(map[string]*vmv1beta1.VMSelectPool, len(cr.Spec.VMSelect.AdditionalPools))
+	for i := range cr.Spec.VMSelect.AdditionalPools {
+		pool := &cr.Spec.VMSelect.AdditionalPools[i]
+		pools[pool.Name] = pool
+	}
+	return pools
+}
+
+// createOrUpdateVMSelectPools reconciles StatefulSets, Services and VMServiceScrapes of additional vmselect pools
+func createOrUpdateVMSelectPools(ctx context.Context, rclient client.Client, cr, prevCR *vmv1beta1.VMCluster) error {
+	prevPools := vmSelectPools(prevCR)
+	for i := range cr.Spec.VMSelect.AdditionalPools {
+		pool := &cr.Spec.VMSelect.AdditionalPools[i]
+		var prevPoolCR *vmv1beta1.VMCluster
+		if prevPool, ok := prevPools[pool.Name]; ok {
+			prevPoolCR = vmSelectPoolCluster(prevCR, prevPool)
+		}
+		if err := createOrUpdateVMSelectPool(ctx, rclient, vmSelectPoolCluster(cr, pool), prevPoolCR, pool.Name); err != nil {
+			return fmt.Errorf("cannot reconcile vmselect pool=%s: %w", pool.Name, err)
+		}
+	}
+	return nil
+}
+
+func createOrUpdateVMSelectPool(ctx context.Context, rclient client.Client, cr, prevCR *vmv1beta1.VMCluster, pool string) error {
+	var prevSts *appsv1.StatefulSet
+	var prevSvc *corev1.Service
+	if prevCR != nil {
+		var err error
+		prevSts, err = genVMSelectSpec(prevCR, pool)
+		if err != nil {
+			return fmt.Errorf("cannot build prev vmselect pool spec: %w", err)
+		}
+		prevSvc = buildVMSelectPoolService(prevCR, pool)
+	}
+	newSts, err := genVMSelectSpec(cr, pool)
+	if err != nil {
+		return err
+	}
+	checksum, err := internalTLSChecksum(ctx, rclient, cr, vmv1beta1.VMClusterComponentVMSelect)
+	if err != nil {
+		return err
+	}
+	setInternalTLSChecksum(&newSts.Spec.Template, checksum)
+	stsOpts := reconcile.STSOptions{
+		HasClaim: len(newSts.Spec.VolumeClaimTemplates) > 0,
+		SelectorLabels: func() map[string]string {
+			return cr.VMSelectPoolSelectorLabels(pool)
+		},
+	}
+	if err := reconcile.HandleSTSUpdate(ctx, rclient, stsOpts, newSts, prevSts); err != nil {
+		return err
+	}
+
+	svc := buildVMSelectPoolService(cr, pool)
+	if err := reconcile.Service(ctx, rclient, svc, prevSvc); err != nil {
+		return fmt.Errorf("cannot reconcile vmselect pool service: %w", err)
+	}
+	if !ptr.Deref(cr.Spec.VMSelect.DisableSelfServiceScrape, false) {
+		if err := reconcile.VMServiceScrapeForCRD(ctx, rclient, build.VMServiceScrapeForServiceWithSpec(svc, cr.Spec.VMSelect)); err != nil {
+			return fmt.Errorf("cannot create VMServiceScrape for vmselect pool: %w", err)
+		}
+	}
+	return nil
+}
+
+// deletePrevVMSelectPools removes objects of vmselect pools, which are no longer defined at the cluster spec
+// PersistentVolumeClaims of the removed pool caches are deleted unless retainCaches is set
+func deletePrevVMSelectPools(ctx context.Context, rclient client.Client, cr, prevCR *vmv1beta1.VMCluster) error {
+	currPools := vmSelectPools(cr)
+	for name, prevPool := range vmSelectPools(prevCR) {
+		poolName := cr.GetVMSelectPoolName(name)
+		objMeta := metav1.ObjectMeta{Namespace: cr.Namespace, Name: poolName}
+		if _, ok := currPools[name]; ok {
+			if ptr.Deref(cr.Spec.VMSelect.DisableSelfServiceScrape, false) && !ptr.Deref(prevCR.Spec.VMSelect.DisableSelfServiceScrape, false) {
+				if err := finalize.SafeDeleteWithFinalizer(ctx, rclient, &vmv1beta1.VMServiceScrape{ObjectMeta: objMeta}); err != nil {
+					return fmt.Errorf("cannot remove serviceScrape from prev vmselect pool=%s: %w", name, err)
+				}
+			}
+			continue
+		}
+		prevPoolCR := vmSelectPoolCluster(prevCR, prevPool)
+		prevSts, err := genVMSelectSpec(prevPoolCR, name)
+		if err != nil {
+			return fmt.Errorf("cannot build prev vmselect pool=%s spec: %w", name, err)
+		}
+		objsToRemove := []client.Object{
+			&appsv1.StatefulSet{ObjectMeta: objMeta},
+			&corev1.Service{ObjectMeta: objMeta},
+			&vmv1beta1.VMServiceScrape{ObjectMeta: objMeta},
+		}
+		for _, obj := range objsToRemove {
+			if err := finalize.SafeDeleteWithFinalizer(ctx, rclient, obj); err != nil {
+				return fmt.Errorf("cannot remove %T=%s of prev vmselect pool: %w", obj, poolName, err)
+			}
+		}
+		if prevPool.RetainCaches {
+			continue
+		}
+		for ordinal := int32(0); ordinal < ptr.Deref(prevSts.Spec.Replicas, 1); ordinal++ {
+			for _, claim := range prevSts.Spec.VolumeClaimTemplates {
+				pvc := &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{
+					Namespace: cr.Namespace,
+					Name:      fmt.Sprintf("%s-%s-%d", claim.Name, poolName, ordinal),
+				}}
+				if err := finalize.SafeDelete(ctx, rclient, pvc); err != nil {
+					return fmt.Errorf("cannot delete pvc=%s: %w", pvc.Name, err)
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package vmcluster
+
+import (
+	"context"
+	"testing"
+
+	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/k8stools"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+)
+
+func TestGenVMSelectPoolSpec(t *testing.T) {
+	cr := &vmv1beta1.VMCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster", Namespace: "default"},
+		Spec: vmv1beta1.VMClusterSpec{
+			VMSelect: &vmv1beta1.VMSelect{
+				CommonApplicationDeploymentParams: vmv1beta1.CommonApplicationDeploymentParams{
+					ReplicaCount: ptr.To[int32](2),
+					ExtraArgs:    map[string]string{"search.maxQueryDuration": "30s", "search.maxUniqueTimeseries": "100000"},
+				},
+				CommonDefaultableParams: vmv1beta1.CommonDefaultableParams{
+					Port: "8481",
+				},
+				PerReplicaService: true,
+				HPA:               &vmv1beta1.EmbeddedHPA{MaxReplicas: 5},
+				AdditionalPools: []vmv1beta1.VMSelectPool{{
+					Name:         "alerting",
+					ReplicaCount: ptr.To[int32](3),
+					Resources: &corev1.ResourceRequirements{
+						Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+					},
+					ExtraArgs:      map[string]string{"search.maxQueryDuration": "10s"},
+					CacheMountPath: "/alerting-cache",
+				}},
+			},
+		},
+	}
+	pool := &cr.Spec.VMSelect.AdditionalPools[0]
+	poolCR := vmSelectPoolCluster(cr, pool)
+	assert.Nil(t, poolCR.Spec.VMSelect.HPA)
+	assert.False(t, poolCR.Spec.VMSelect.PerReplicaService)
+	sts, err := genVMSelectSpec(poolCR, pool.Name)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.Equal(t, "vmselect-cluster-alerting", sts.Name)
+	assert.Equal(t, "vmselect-cluster-alerting", sts.Spec.ServiceName)
+	assert.Equal(t, int32(3), *sts.Spec.Replicas)
+	assert.Equal(t, "alerting", sts.Spec.Selector.MatchLabels[vmv1beta1.VMSelectPoolLabel])
+	assert.NotEqual(t, cr.VMSelectSelectorLabels()["app.kubernetes.io/name"], sts.Spec.Selector.MatchLabels["app.kubernetes.io/name"])
+	assert.Equal(t, sts.Spec.Selector.MatchLabels, sts.Spec.Template.Labels)
+	container := sts.Spec.Template.Spec.Containers[0]
+	assert.Equal(t, resource.MustParse("1Gi"), container.Resources.Limits[corev1.ResourceMemory])
+	assert.Contains(t, container.Args, "-search.maxQueryDuration=10s")
+	assert.Contains(t, container.Args, "-search.maxUniqueTimeseries=100000")
+	assert.Contains(t, container.Args, "-cacheDataPath=/alerting-cache")
+	assert.Contains(t, container.Args, "-selectNode=vmselect-cluster-alerting-0.vmselect-cluster-alerting.default:8481,"+
+		"vmselect-cluster-alerting-1.vmselect-cluster-alerting.default:8481,vmselect-cluster-alerting-2.vmselect-cluster-alerting.default:8481")
+
+	svc := buildVMSelectPoolService(poolCR, pool.Name)
+	assert.Equal(t, "vmselect-cluster-alerting", svc.Name)
+	assert.Equal(t, sts.Spec.Selector.MatchLabels, svc.Spec.Selector)
+
+	// default pool must not be changed
+	defaultSts, err := genVMSelectSpec(cr, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.Equal(t, "vmselect-cluster", defaultSts.Name)
+	assert.Equal(t, cr.VMSelectSelectorLabels(), defaultSts.Spec.Selector.MatchLabels)
+	assert.Contains(t, defaultSts.Spec.Template.Spec.Containers[0].Args, "-search.maxQueryDuration=30s")
+}
+
+func TestDeletePrevVMSelectPools(t *testing.T) {
+	f := func(retainCaches bool) {
+		t.Helper()
+		cr := &vmv1beta1.VMCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster", Namespace: "default"},
+			Spec: vmv1beta1.VMClusterSpec{
+				VMSelect: &vmv1beta1.VMSelect{
+					CacheMountPath: "/cache",
+					StorageSpec:    &vmv1beta1.StorageSpec{},
+					AdditionalPools: []vmv1beta1.VMSelectPool{
+						{Name: "alerting", ReplicaCount: ptr.To[int32](2), RetainCaches: retainCaches},
+						{Name: "dashboards"},
+					},
+				},
+			},
+		}
+		var objs []runtime.Object
+		for _, name := range []string{"vmselect-cluster-alerting", "vmselect-cluster-dashboards"} {
+			objMeta := metav1.ObjectMeta{Name: name, Namespace: cr.Namespace}
+			objs = append(objs, &appsv1.StatefulSet{ObjectMeta: objMeta}, &corev1.Service{ObjectMeta: objMeta})
+		}
+		for _, name := range []string{"vmselect-cachedir-vmselect-cluster-alerting-0", "vmselect-cachedir-vmselect-cluster-alerting-1"} {
+			objs = append(objs, &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: cr.Namespace}})
+		}
+		ctx := context.TODO()
+		fclient := k8stools.GetTestClientWithObjects(objs)
+		prevCR := cr.DeepCopy()
+		cr.Spec.VMSelect.AdditionalPools = cr.Spec.VMSelect.AdditionalPools[1:]
+		if err := deletePrevVMSelectPools(ctx, fclient, cr, prevCR); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		nsn := types.NamespacedName{Namespace: cr.Namespace, Name: "vmselect-cluster-alerting"}
+		assert.Error(t, fclient.Get(ctx, nsn, &appsv1.StatefulSet{}), "removed pool statefulset must be deleted")
+		assert.Error(t, fclient.Get(ctx, nsn, &corev1.Service{}), "removed pool service must be deleted")
+		nsn.Name = "vmselect-cluster-dashboards"
+		assert.NoError(t, fclient.Get(ctx, nsn, &appsv1.StatefulSet{}), "existing pool statefulset must be kept")
+		var pvcs corev1.PersistentVolumeClaimList
+		if err := fclient.List(ctx, &pvcs); err != nil {
+			t.Fatalf("cannot list pvcs: %s", err)
+		}
+		if retainCaches {
+			assert.Len(t, pvcs.Items, 2)
+		} else {
+			assert.Empty(t, pvcs.Items)
+		}
+	}
+
+	f(false)
+	f(true)
+}
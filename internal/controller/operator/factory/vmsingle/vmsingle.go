@@ -309,7 +309,7 @@ func makeSpecForVMSingle(ctx context.Context, cr *vmv1beta1.VMSingle) (*corev1.P
 		if cr.Spec.VMBackup.Restore != nil &&
 			cr.Spec.VMBackup.Restore.OnStart != nil &&
 			cr.Spec.VMBackup.Restore.OnStart.Enabled {
-			vmRestore, err := build.VMRestore(cr.Spec.VMBackup, storagePath, vmDataVolumeName)
+			vmRestore, err := build.VMRestore(cr.Spec.VMBackup, storagePath, vmDataVolumeName, cr.Spec.License)
 			if err != nil {
 				return nil, err
 			}
@@ -31,8 +31,12 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
 var (
@@ -45,6 +49,7 @@ type VMAgentReconciler struct {
 	client.Client
 	Log          logr.Logger
 	OriginScheme *runtime.Scheme
+	Recorder     record.EventRecorder
 	BaseConf     *config.BaseOperatorConf
 }
 
@@ -61,6 +66,7 @@ func (r *VMAgentReconciler) Init(rclient client.Client, l logr.Logger, sc *runti
 // +kubebuilder:rbac:groups=operator.victoriametrics.com,resources=vmagents/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=operator.victoriametrics.com,resources=vmagents/finalizers,verbs=*
 // +kubebuilder:rbac:groups="",resources=pods,verbs=*
+// +kubebuilder:rbac:groups=apps,resources=daemonsets,verbs=*
 // +kubebuilder:rbac:groups="",resources=nodes,verbs=get;watch;list
 // +kubebuilder:rbac:groups="",resources=nodes/proxy,verbs=get;watch;list
 // +kubebuilder:rbac:groups="networking.k8s.io",resources=ingresses,verbs=get;watch;list
@@ -93,9 +99,13 @@ func (r *VMAgentReconciler) Reconcile(ctx context.Context, req ctrl.Request) (re
 
 	RegisterObjectStat(instance, "vmagent")
 	if !instance.DeletionTimestamp.IsZero() {
+		if err := vmagent.RemoveScrapeObjectsSelectedBy(ctx, r.Client, instance); err != nil {
+			return result, err
+		}
 		if err := finalize.OnVMAgentDelete(ctx, r.Client, instance); err != nil {
 			return result, err
 		}
+		vmagent.DeregisterScrapeMetrics(instance)
 		return
 	}
 
@@ -108,8 +118,14 @@ func (r *VMAgentReconciler) Reconcile(ctx context.Context, req ctrl.Request) (re
 	}
 	r.Client.Scheme().Default(instance)
 
-	result, err = reconcileAndTrackStatus(ctx, r.Client, instance.DeepCopy(), func() (ctrl.Result, error) {
-		if err = vmagent.CreateOrUpdateVMAgent(ctx, instance, r); err != nil {
+	statusInstance := instance.DeepCopy()
+	result, err = reconcileAndTrackStatus(ctx, r.Client, statusInstance, func() (ctrl.Result, error) {
+		err = vmagent.CreateOrUpdateVMAgent(ctx, instance, r, r.Recorder)
+		// shards transition progress, config parts and tenant routing condition must be persisted with status update
+		statusInstance.Status.ShardTransition = instance.Status.ShardTransition
+		statusInstance.Status.ConfigSecretParts = instance.Status.ConfigSecretParts
+		statusInstance.Status.Conditions = instance.Status.Conditions
+		if err != nil {
 			return result, err
 		}
 
@@ -119,6 +135,9 @@ func (r *VMAgentReconciler) Reconcile(ctx context.Context, req ctrl.Request) (re
 		return
 	}
 	result.RequeueAfter = r.BaseConf.ResyncAfterDuration()
+	if d := vmagent.ShardTransitionRequeueAfter(instance); d > 0 {
+		result.RequeueAfter = d
+	}
 
 	return
 }
@@ -130,11 +149,34 @@ func (r *VMAgentReconciler) Scheme() *runtime.Scheme {
 
 // SetupWithManager general setup method
 func (r *VMAgentReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	r.Recorder = mgr.GetEventRecorderFor("vmagent-controller")
+	b := ctrl.NewControllerManagedBy(mgr).
 		For(&vmv1beta1.VMAgent{}).
 		Owns(&appsv1.Deployment{}).
 		Owns(&appsv1.StatefulSet{}).
-		Owns(&v1.ServiceAccount{}).
-		WithOptions(getDefaultOptions()).
+		Owns(&appsv1.DaemonSet{}).
+		Owns(&v1.ServiceAccount{})
+	if config.IsClusterWideAccessAllowed() {
+		b = b.WatchesMetadata(&v1.Namespace{}, handler.EnqueueRequestsFromMapFunc(r.agentsForNamespace))
+	}
+	return b.WithOptions(getDefaultOptions()).
 		Complete(r)
 }
+
+// agentsForNamespace returns VMAgents with tenantRouting enabled
+// it allows to update per-tenant remote write urls on namespace changes
+func (r *VMAgentReconciler) agentsForNamespace(ctx context.Context, _ client.Object) []reconcile.Request {
+	var agents vmv1beta1.VMAgentList
+	if err := r.Client.List(ctx, &agents); err != nil {
+		r.Log.Error(err, "cannot list VMAgents for namespace change")
+		return nil
+	}
+	var requests []reconcile.Request
+	for i := range agents.Items {
+		cr := &agents.Items[i]
+		if cr.Spec.TenantRouting != nil {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: cr.Namespace, Name: cr.Name}})
+		}
+	}
+	return requests
+}
@@ -23,6 +23,7 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -41,6 +42,7 @@ type VMAlertReconciler struct {
 	client.Client
 	Log          logr.Logger
 	OriginScheme *runtime.Scheme
+	Recorder     record.EventRecorder
 	BaseConf     *config.BaseOperatorConf
 }
 
@@ -61,6 +63,7 @@ func (r *VMAlertReconciler) Scheme() *runtime.Scheme {
 // +kubebuilder:rbac:groups=operator.victoriametrics.com,resources=vmalerts,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=operator.victoriametrics.com,resources=vmalerts/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=operator.victoriametrics.com,resources=vmalerts/finalizers,verbs=*
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;watch;list
 func (r *VMAlertReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, resultErr error) {
 	reqLogger := r.Log.WithValues("vmalert", req.Name, "namespace", req.Namespace)
 	ctx = logger.AddToContext(ctx, reqLogger)
@@ -80,6 +83,7 @@ func (r *VMAlertReconciler) Reconcile(ctx context.Context, req ctrl.Request) (re
 		if err := finalize.OnVMAlertDelete(ctx, r.Client, instance); err != nil {
 			return result, err
 		}
+		vmalert.DeregisterRulesMetrics(instance)
 		return result, nil
 	}
 
@@ -92,12 +96,19 @@ func (r *VMAlertReconciler) Reconcile(ctx context.Context, req ctrl.Request) (re
 	}
 	r.Client.Scheme().Default(instance)
 
-	result, resultErr = reconcileAndTrackStatus(ctx, r.Client, instance.DeepCopy(), func() (ctrl.Result, error) {
-		maps, err := vmalert.CreateOrUpdateRuleConfigMaps(ctx, r, instance, nil)
+	statusInstance := instance.DeepCopy()
+	result, resultErr = reconcileAndTrackStatus(ctx, r.Client, statusInstance, func() (ctrl.Result, error) {
+		maps, err := vmalert.CreateOrUpdateRuleConfigMaps(ctx, r, instance, nil, r.Recorder)
 		if err != nil {
 			return result, err
 		}
-		if err := vmalert.CreateOrUpdateVMAlert(ctx, instance, r, maps); err != nil {
+		// rules selection conditions and sync status must be persisted with status update
+		statusInstance.Status.Conditions = instance.Status.Conditions
+		statusInstance.Status.RuleSync = instance.Status.RuleSync
+		err = vmalert.CreateOrUpdateVMAlert(ctx, instance, r, maps)
+		// external rule sources condition is set by vmalert reconcile
+		statusInstance.Status.Conditions = instance.Status.Conditions
+		if err != nil {
 			return result, err
 		}
 
@@ -107,14 +118,19 @@ func (r *VMAlertReconciler) Reconcile(ctx context.Context, req ctrl.Request) (re
 		return
 	}
 	result.RequeueAfter = r.BaseConf.ResyncAfterDuration()
+	if d := vmalert.RulesReloadRequeueAfter(instance); d > 0 {
+		result.RequeueAfter = d
+	}
 	return
 }
 
 // SetupWithManager general setup method
 func (r *VMAlertReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.Recorder = mgr.GetEventRecorderFor("vmalert-controller")
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&vmv1beta1.VMAlert{}).
 		Owns(&appsv1.Deployment{}).
+		Owns(&appsv1.StatefulSet{}).
 		Owns(&v1.ServiceAccount{}).
 		WithOptions(getDefaultOptions()).
 		Complete(r)
@@ -18,19 +18,26 @@ package operator
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/go-logr/logr"
 	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
 	"github.com/VictoriaMetrics/operator/internal/config"
 	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/alertmanager"
 	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/finalize"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/k8stools"
 	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/logger"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/vmalert"
 )
 
 // VMAlertmanagerReconciler reconciles a VMAlertmanager object
@@ -39,6 +46,7 @@ type VMAlertmanagerReconciler struct {
 	Log          logr.Logger
 	OriginScheme *runtime.Scheme
 	BaseConf     *config.BaseOperatorConf
+	Recorder     record.EventRecorder
 }
 
 // Init implements crdController interface
@@ -61,6 +69,7 @@ func (r *VMAlertmanagerReconciler) Scheme() *runtime.Scheme {
 // +kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=*
 // +kubebuilder:rbac:groups="",resources=configmaps,verbs=*
 // +kubebuilder:rbac:groups="",resources=secrets,verbs=*
+// +kubebuilder:rbac:groups=cert-manager.io,resources=certificates,verbs=get;create;update;delete
 func (r *VMAlertmanagerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
 	reqLogger := r.Log.WithValues("vmalertmanager", req.Name, "namespace", req.Namespace)
 	ctx = logger.AddToContext(ctx, reqLogger)
@@ -78,7 +87,7 @@ func (r *VMAlertmanagerReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		if err := finalize.OnVMAlertManagerDelete(ctx, r.Client, instance); err != nil {
 			return result, err
 		}
-		return
+		return result, r.updateVMAlertNotifiers(ctx)
 	}
 	if instance.Spec.ParsingError != "" {
 		return result, &parsingError{instance.Spec.ParsingError, "vmalertmanager"}
@@ -89,8 +98,14 @@ func (r *VMAlertmanagerReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 	}
 	r.Client.Scheme().Default(instance)
 
-	result, err = reconcileAndTrackStatus(ctx, r.Client, instance.DeepCopy(), func() (ctrl.Result, error) {
-		if err := alertmanager.CreateOrUpdateConfig(ctx, r.Client, instance, nil); err != nil {
+	statusInstance := instance.DeepCopy()
+	result, err = reconcileAndTrackStatus(ctx, r.Client, statusInstance, func() (ctrl.Result, error) {
+		err := alertmanager.CreateOrUpdateConfig(ctx, r.Client, instance, nil, r.Recorder)
+		// config status is persisted by CreateOrUpdateConfig,
+		// it must not be overwritten by the final status update
+		statusInstance.Status.Conditions = instance.Status.Conditions
+		statusInstance.Status.ConfigSummary = instance.Status.ConfigSummary
+		if err != nil {
 			return result, err
 		}
 
@@ -103,17 +118,80 @@ func (r *VMAlertmanagerReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 	if err != nil {
 		return
 	}
+	if err = r.updateVMAlertNotifiers(ctx); err != nil {
+		return
+	}
 
 	result.RequeueAfter = r.BaseConf.ResyncAfterDuration()
 	return
 }
 
+// updateVMAlertNotifiers refreshes notifiers config of VMAlerts with enabled alertmanagers discovery
+func (r *VMAlertmanagerReconciler) updateVMAlertNotifiers(ctx context.Context) error {
+	var objects vmv1beta1.VMAlertList
+	if err := k8stools.ListObjectsByNamespace(ctx, r.Client, config.MustGetWatchNamespaces(), func(dst *vmv1beta1.VMAlertList) {
+		objects.Items = append(objects.Items, dst.Items...)
+	}); err != nil {
+		return fmt.Errorf("cannot list vmalerts for vmalertmanager: %w", err)
+	}
+	for i := range objects.Items {
+		item := &objects.Items[i]
+		if item.DeletionTimestamp != nil || item.Spec.ParsingError != "" || !item.IsNotifierDiscoveryEnabled() {
+			continue
+		}
+		ctx := logger.AddToContext(ctx, logger.WithContext(ctx).WithValues("vmalert", item.Name, "parent_namespace", item.Namespace))
+		if err := vmalert.CreateOrUpdateNotifiersConfig(ctx, r.Client, item); err != nil {
+			return fmt.Errorf("cannot update notifiers config for vmalert=%s/%s: %w", item.Namespace, item.Name, err)
+		}
+	}
+	return nil
+}
+
 // SetupWithManager general setup method
 func (r *VMAlertmanagerReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.Recorder = mgr.GetEventRecorderFor("vmalertmanager-controller")
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&vmv1beta1.VMAlertmanager{}).
 		Owns(&appsv1.StatefulSet{}).
 		Owns(&v1.ServiceAccount{}).
+		WatchesMetadata(&v1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.alertmanagersForSecret)).
+		WatchesMetadata(&v1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.alertmanagersForTemplatesConfigMap)).
 		WithOptions(getDefaultOptions()).
 		Complete(r)
 }
+
+// alertmanagersForSecret returns VMAlertmanagers, which mount the given Secret as gossip TLS certificate or templates
+// it triggers rolling restart of alertmanager pods on certificate rotation and updates TemplatesDegraded condition
+func (r *VMAlertmanagerReconciler) alertmanagersForSecret(ctx context.Context, secret client.Object) []reconcile.Request {
+	var ams vmv1beta1.VMAlertmanagerList
+	if err := r.Client.List(ctx, &ams, client.InNamespace(secret.GetNamespace())); err != nil {
+		r.Log.Error(err, "cannot list VMAlertmanagers for secret", "secret", secret.GetName(), "namespace", secret.GetNamespace())
+		return nil
+	}
+	var requests []reconcile.Request
+	for i := range ams.Items {
+		cr := &ams.Items[i]
+		if alertmanager.IsClusterTLSSecret(cr, secret.GetName()) || alertmanager.IsTemplatesSecret(cr, secret.GetName()) {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: cr.Namespace, Name: cr.Name}})
+		}
+	}
+	return requests
+}
+
+// alertmanagersForTemplatesConfigMap returns VMAlertmanagers, which reference the given ConfigMap with spec.templates
+// it updates TemplatesDegraded condition on ConfigMap change
+func (r *VMAlertmanagerReconciler) alertmanagersForTemplatesConfigMap(ctx context.Context, cm client.Object) []reconcile.Request {
+	var ams vmv1beta1.VMAlertmanagerList
+	if err := r.Client.List(ctx, &ams, client.InNamespace(cm.GetNamespace())); err != nil {
+		r.Log.Error(err, "cannot list VMAlertmanagers for templates configmap", "configmap", cm.GetName(), "namespace", cm.GetNamespace())
+		return nil
+	}
+	var requests []reconcile.Request
+	for i := range ams.Items {
+		cr := &ams.Items[i]
+		if alertmanager.IsTemplatesConfigMap(cr, cm.GetName()) {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: cr.Namespace, Name: cr.Name}})
+		}
+	}
+	return requests
+}
@@ -22,6 +22,7 @@ import (
 
 	"github.com/go-logr/logr"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
@@ -42,6 +43,7 @@ type VMAlertmanagerConfigReconciler struct {
 	Log          logr.Logger
 	OriginScheme *runtime.Scheme
 	BaseConf     *config.BaseOperatorConf
+	Recorder     record.EventRecorder
 }
 
 // Init implements crdController interface
@@ -74,6 +76,12 @@ func (r *VMAlertmanagerConfigReconciler) Reconcile(ctx context.Context, req ctrl
 
 	RegisterObjectStat(&instance, "vmalertmanagerconfig")
 
+	if hasTestReceiverAnnotation(&instance) && instance.DeletionTimestamp.IsZero() && !r.BaseConf.DisableAlertmanagerReceiverTest {
+		if err := r.testReceiver(ctx, &instance); err != nil {
+			return result, err
+		}
+	}
+
 	if vmaConfigRateLimiter.MustThrottleReconcile() {
 		return
 	}
@@ -106,18 +114,50 @@ func (r *VMAlertmanagerConfigReconciler) Reconcile(ctx context.Context, req ctrl
 				continue
 			}
 		}
-		if err := alertmanager.CreateOrUpdateConfig(ctx, r.Client, am, &instance); err != nil {
+		if err := alertmanager.CreateOrUpdateConfig(ctx, r.Client, am, &instance, r.Recorder); err != nil {
 			continue
 		}
 	}
 	return
 }
 
+// testReceiver sends synthetic alert for the receiver defined with test-receiver annotation
+// into VMAlertmanagers, which select given VMAlertmanagerConfig
+func (r *VMAlertmanagerConfigReconciler) testReceiver(ctx context.Context, instance *vmv1beta1.VMAlertmanagerConfig) error {
+	var objects vmv1beta1.VMAlertmanagerList
+	if err := k8stools.ListObjectsByNamespace(ctx, r.Client, config.MustGetWatchNamespaces(), func(dst *vmv1beta1.VMAlertmanagerList) {
+		objects.Items = append(objects.Items, dst.Items...)
+	}); err != nil {
+		return fmt.Errorf("cannot list vmalertmanagers for receiver test: %w", err)
+	}
+	var parents []*vmv1beta1.VMAlertmanager
+	for i := range objects.Items {
+		am := &objects.Items[i]
+		if !am.DeletionTimestamp.IsZero() || am.Spec.ParsingError != "" || am.IsUnmanaged() {
+			continue
+		}
+		match, err := isSelectorsMatchesTargetCRD(ctx, r.Client, instance, am, am.Spec.ConfigSelector, am.Spec.ConfigNamespaceSelector, am.Spec.SelectAllByDefault)
+		if err != nil {
+			return fmt.Errorf("cannot match alertmanager=%s/%s against selector: %w", am.Namespace, am.Name, err)
+		}
+		if match {
+			parents = append(parents, am)
+		}
+	}
+	return alertmanager.TestReceiver(ctx, r.Client, instance, parents)
+}
+
+func hasTestReceiverAnnotation(obj client.Object) bool {
+	return obj.GetAnnotations()[vmv1beta1.VMAlertmanagerConfigTestReceiverAnnotation] != ""
+}
+
 // SetupWithManager configures reconcile
 func (r *VMAlertmanagerConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.Recorder = mgr.GetEventRecorderFor("vmalertmanagerconfig-controller")
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&vmv1beta1.VMAlertmanagerConfig{}).
-		WithEventFilter(predicate.TypedGenerationChangedPredicate[client.Object]{}).
+		// annotation changes don't update generation, so receiver test must be triggered explicitly
+		WithEventFilter(predicate.Or[client.Object](predicate.TypedGenerationChangedPredicate[client.Object]{}, predicate.NewPredicateFuncs(hasTestReceiverAnnotation))).
 		WithOptions(getDefaultOptions()).
 		Complete(r)
 }
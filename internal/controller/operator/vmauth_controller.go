@@ -29,9 +29,14 @@ import (
 	"github.com/go-logr/logr"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	gwapiv1 "sigs.k8s.io/gateway-api/apis/v1"
 )
 
 // VMAuthReconciler reconciles a VMAuth object
@@ -40,6 +45,7 @@ type VMAuthReconciler struct {
 	BaseConf     *config.BaseOperatorConf
 	Log          logr.Logger
 	OriginScheme *runtime.Scheme
+	Recorder     record.EventRecorder
 }
 
 // Init implements crdController interface
@@ -88,8 +94,16 @@ func (r *VMAuthReconciler) Reconcile(ctx context.Context, req ctrl.Request) (res
 	}
 	r.Client.Scheme().Default(instance)
 
-	result, err = reconcileAndTrackStatus(ctx, r.Client, instance.DeepCopy(), func() (ctrl.Result, error) {
-		if err := vmauth.CreateOrUpdateVMAuth(ctx, instance, r); err != nil {
+	statusInstance := instance.DeepCopy()
+	result, err = reconcileAndTrackStatus(ctx, r.Client, statusInstance, func() (ctrl.Result, error) {
+		err := vmauth.CreateOrUpdateVMAuth(ctx, instance, r, r.Recorder)
+		// config status must be persisted with status update
+		statusInstance.Status.ConfigSecretParts = instance.Status.ConfigSecretParts
+		statusInstance.Status.ConfigSecretPartsChecksum = instance.Status.ConfigSecretPartsChecksum
+		statusInstance.Status.UsersTotal = instance.Status.UsersTotal
+		statusInstance.Status.UsersFailed = instance.Status.UsersFailed
+		statusInstance.Status.LastConfigHash = instance.Status.LastConfigHash
+		if err != nil {
 			return result, fmt.Errorf("cannot create or update vmauth deploy: %w", err)
 		}
 
@@ -105,10 +119,33 @@ func (r *VMAuthReconciler) Reconcile(ctx context.Context, req ctrl.Request) (res
 
 // SetupWithManager inits object.
 func (r *VMAuthReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	r.Recorder = mgr.GetEventRecorderFor("vmauth-controller")
+	b := ctrl.NewControllerManagedBy(mgr).
 		For(&vmv1beta1.VMAuth{}).
 		Owns(&appsv1.Deployment{}).
 		Owns(&corev1.ServiceAccount{}).
-		WithOptions(getDefaultOptions()).
-		Complete(r)
+		Owns(&networkingv1.Ingress{}).
+		WithOptions(getDefaultOptions())
+	hasHTTPRoute, err := isGatewayAPIInstalled(mgr)
+	if err != nil {
+		return err
+	}
+	if hasHTTPRoute {
+		b = b.Owns(&gwapiv1.HTTPRoute{})
+	} else {
+		r.Log.Info("Gateway API HTTPRoute CRD is not installed, skipping HTTPRoute watch")
+	}
+	return b.Complete(r)
+}
+
+// isGatewayAPIInstalled checks if Gateway API HTTPRoute CRD is served by kubernetes API server
+func isGatewayAPIInstalled(mgr ctrl.Manager) (bool, error) {
+	gk := schema.GroupKind{Group: gwapiv1.GroupName, Kind: "HTTPRoute"}
+	if _, err := mgr.GetRESTMapper().RESTMapping(gk, gwapiv1.SchemeGroupVersion.Version); err != nil {
+		if meta.IsNoMatchError(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("cannot check Gateway API HTTPRoute CRD: %w", err)
+	}
+	return true, nil
 }
@@ -11,9 +11,14 @@ import (
 	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/vmcluster"
 	"github.com/go-logr/logr"
 	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
 // VMClusterReconciler reconciles a VMCluster object
@@ -42,6 +47,7 @@ func (r *VMClusterReconciler) Scheme() *runtime.Scheme {
 // +kubebuilder:rbac:groups=operator.victoriametrics.com,resources=vmclusters/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=operator.victoriametrics.com,resources=vmclusters/finalizers,verbs=*
 // +kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=*
+// +kubebuilder:rbac:groups=cert-manager.io,resources=certificates,verbs=get;create;update;delete
 func (r *VMClusterReconciler) Reconcile(ctx context.Context, request ctrl.Request) (result ctrl.Result, err error) {
 	reqLogger := r.Log.WithValues("vmcluster", request.Name, "namespace", request.Namespace)
 	ctx = logger.AddToContext(ctx, reqLogger)
@@ -71,8 +77,18 @@ func (r *VMClusterReconciler) Reconcile(ctx context.Context, request ctrl.Reques
 	}
 	r.Client.Scheme().Default(instance)
 
-	result, err = reconcileAndTrackStatus(ctx, r.Client, instance.DeepCopy(), func() (ctrl.Result, error) {
+	statusInstance := instance.DeepCopy()
+	result, err = reconcileAndTrackStatus(ctx, r.Client, statusInstance, func() (ctrl.Result, error) {
 		err = vmcluster.CreateOrUpdateVMCluster(ctx, instance, r.Client)
+		if statusErr := vmcluster.UpdateComponentsStatus(ctx, r.Client, instance); statusErr != nil && err == nil {
+			err = statusErr
+		}
+		// conditions and cluster components state must be persisted with status update
+		statusInstance.Status.Conditions = instance.Status.Conditions
+		statusInstance.Status.StorageExpansionInProgress = instance.Status.StorageExpansionInProgress
+		statusInstance.Status.StorageScaleDownPhase = instance.Status.StorageScaleDownPhase
+		statusInstance.Status.PausedComponents = instance.Status.PausedComponents
+		statusInstance.Status.Components = instance.Status.Components
 		if err != nil {
 			return result, fmt.Errorf("failed create or update vmcluster: %w", err)
 		}
@@ -83,6 +99,9 @@ func (r *VMClusterReconciler) Reconcile(ctx context.Context, request ctrl.Reques
 	}
 
 	result.RequeueAfter = r.BaseConf.ResyncAfterDuration()
+	if d := vmcluster.UpdateRequeueAfter(instance); d > 0 {
+		result.RequeueAfter = d
+	}
 	return
 }
 
@@ -92,6 +111,26 @@ func (r *VMClusterReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		For(&vmv1beta1.VMCluster{}).
 		Owns(&appsv1.Deployment{}).
 		Owns(&appsv1.StatefulSet{}).
+		Owns(&policyv1.PodDisruptionBudget{}).
+		WatchesMetadata(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.clustersForInternalTLSSecret)).
 		WithOptions(getDefaultOptions()).
 		Complete(r)
 }
+
+// clustersForInternalTLSSecret returns VMClusters, which mount the given Secret as internal TLS certificate
+// it triggers rolling restart of cluster components on certificate rotation
+func (r *VMClusterReconciler) clustersForInternalTLSSecret(ctx context.Context, secret client.Object) []reconcile.Request {
+	var clusters vmv1beta1.VMClusterList
+	if err := r.Client.List(ctx, &clusters, client.InNamespace(secret.GetNamespace())); err != nil {
+		r.Log.Error(err, "cannot list VMClusters for internal TLS secret", "secret", secret.GetName(), "namespace", secret.GetNamespace())
+		return nil
+	}
+	var requests []reconcile.Request
+	for i := range clusters.Items {
+		cr := &clusters.Items[i]
+		if vmcluster.IsInternalTLSSecret(cr, secret.GetName()) {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: cr.Namespace, Name: cr.Name}})
+		}
+	}
+	return requests
+}
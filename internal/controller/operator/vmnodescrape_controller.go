@@ -22,6 +22,7 @@ import (
 
 	"github.com/go-logr/logr"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
@@ -38,6 +39,7 @@ type VMNodeScrapeReconciler struct {
 	client.Client
 	Log          logr.Logger
 	OriginScheme *runtime.Scheme
+	Recorder     record.EventRecorder
 }
 
 // Init implements crdController interface
@@ -107,7 +109,7 @@ func (r *VMNodeScrapeReconciler) Reconcile(ctx context.Context, req ctrl.Request
 			}
 		}
 
-		if err := vmagent.CreateOrUpdateConfigurationSecret(ctx, r, currentVMagent, instance); err != nil {
+		if err := vmagent.CreateOrUpdateConfigurationSecret(ctx, r, currentVMagent, instance, r.Recorder); err != nil {
 			continue
 		}
 	}
@@ -117,6 +119,7 @@ func (r *VMNodeScrapeReconciler) Reconcile(ctx context.Context, req ctrl.Request
 
 // SetupWithManager - setups manager for VMNodeScrape
 func (r *VMNodeScrapeReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.Recorder = mgr.GetEventRecorderFor("vmnodescrape-controller")
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&vmv1beta1.VMNodeScrape{}).
 		WithEventFilter(predicate.TypedGenerationChangedPredicate[client.Object]{}).
@@ -19,11 +19,17 @@ package operator
 import (
 	"context"
 	"fmt"
+	"slices"
 
 	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 
 	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
@@ -38,6 +44,7 @@ type VMPodScrapeReconciler struct {
 	client.Client
 	Log          logr.Logger
 	OriginScheme *runtime.Scheme
+	Recorder     record.EventRecorder
 }
 
 // Init implements crdController interface
@@ -105,7 +112,7 @@ func (r *VMPodScrapeReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 			}
 		}
 
-		if err := vmagent.CreateOrUpdateConfigurationSecret(ctx, r, currentVMagent, instance); err != nil {
+		if err := vmagent.CreateOrUpdateConfigurationSecret(ctx, r, currentVMagent, instance, r.Recorder); err != nil {
 			continue
 		}
 	}
@@ -115,9 +122,28 @@ func (r *VMPodScrapeReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 
 // SetupWithManager general setup method
 func (r *VMPodScrapeReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.Recorder = mgr.GetEventRecorderFor("vmpodscrape-controller")
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&vmv1beta1.VMPodScrape{}).
-		WithEventFilter(predicate.TypedGenerationChangedPredicate[client.Object]{}).
+		For(&vmv1beta1.VMPodScrape{}, builder.WithPredicates(predicate.TypedGenerationChangedPredicate[client.Object]{})).
+		WatchesMetadata(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.podScrapesForConfigMap)).
 		WithOptions(getDefaultOptions()).
 		Complete(r)
 }
+
+// podScrapesForConfigMap returns VMPodScrapes, which reference the given ConfigMap with relabelConfigRefs
+// it triggers scrape configuration update on ConfigMap change
+func (r *VMPodScrapeReconciler) podScrapesForConfigMap(ctx context.Context, cm client.Object) []ctrl.Request {
+	var objects vmv1beta1.VMPodScrapeList
+	if err := r.List(ctx, &objects, client.InNamespace(cm.GetNamespace())); err != nil {
+		r.Log.Error(err, "cannot list VMPodScrapes for configmap", "configmap", cm.GetName(), "namespace", cm.GetNamespace())
+		return nil
+	}
+	var requests []ctrl.Request
+	for i := range objects.Items {
+		o := &objects.Items[i]
+		if slices.ContainsFunc(o.Spec.RelabelConfigRefs, func(ref corev1.ConfigMapKeySelector) bool { return ref.Name == cm.GetName() }) {
+			requests = append(requests, ctrl.Request{NamespacedName: types.NamespacedName{Namespace: o.Namespace, Name: o.Name}})
+		}
+	}
+	return requests
+}
@@ -21,7 +21,9 @@ import (
 	"fmt"
 
 	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
@@ -38,6 +40,7 @@ type VMRuleReconciler struct {
 	client.Client
 	Log          logr.Logger
 	OriginScheme *runtime.Scheme
+	Recorder     record.EventRecorder
 }
 
 // Init implements crdController interface
@@ -104,16 +107,24 @@ func (r *VMRuleReconciler) Reconcile(ctx context.Context, req ctrl.Request) (res
 			}
 		}
 
-		_, err := vmalert.CreateOrUpdateRuleConfigMaps(ctx, r, currVMAlert, instance)
+		prevStatus := currVMAlert.Status.DeepCopy()
+		_, err := vmalert.CreateOrUpdateRuleConfigMaps(ctx, r, currVMAlert, instance, r.Recorder)
 		if err != nil {
 			return ctrl.Result{}, fmt.Errorf("cannot update rules configmaps: %w", err)
 		}
+		if !equality.Semantic.DeepEqual(prevStatus.RuleSync, currVMAlert.Status.RuleSync) ||
+			!equality.Semantic.DeepEqual(prevStatus.Conditions, currVMAlert.Status.Conditions) {
+			if err := vmalert.UpdateRuleSyncStatus(ctx, r, currVMAlert); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
 	}
 	return
 }
 
 // SetupWithManager general setup method
 func (r *VMRuleReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.Recorder = mgr.GetEventRecorderFor("vmrule-controller")
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&vmv1beta1.VMRule{}).
 		WithEventFilter(predicate.TypedGenerationChangedPredicate[client.Object]{}).
@@ -19,13 +19,18 @@ package operator
 import (
 	"context"
 	"fmt"
+	"slices"
+	"time"
 
 	"github.com/go-logr/logr"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 
 	vmv1beta1 "github.com/VictoriaMetrics/operator/api/operator/v1beta1"
@@ -42,6 +47,7 @@ type VMUserReconciler struct {
 	client.Client
 	Log          logr.Logger
 	OriginScheme *runtime.Scheme
+	Recorder     record.EventRecorder
 }
 
 // Init implements crdController interface
@@ -73,6 +79,7 @@ func (r *VMUserReconciler) Reconcile(ctx context.Context, req ctrl.Request) (res
 		return result, &getError{err, "vmuser", req}
 	}
 	RegisterObjectStat(&instance, "vmuser")
+	result.RequeueAfter = vmauth.PasswordRotationRequeueAfter(&instance, time.Now())
 
 	if !instance.DeletionTimestamp.IsZero() {
 		// need to remove finalizer and delete related resources.
@@ -116,7 +123,7 @@ func (r *VMUserReconciler) Reconcile(ctx context.Context, req ctrl.Request) (res
 				continue
 			}
 		}
-		if err := vmauth.CreateOrUpdateVMAuthConfig(ctx, r, currentVMAuth, &instance); err != nil {
+		if err := vmauth.CreateOrUpdateVMAuthConfig(ctx, r, currentVMAuth, &instance, r.Recorder); err != nil {
 			return ctrl.Result{}, fmt.Errorf("cannot create or update vmauth deploy for vmuser: %w", err)
 		}
 	}
@@ -125,10 +132,46 @@ func (r *VMUserReconciler) Reconcile(ctx context.Context, req ctrl.Request) (res
 
 // SetupWithManager inits object
 func (r *VMUserReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&vmv1beta1.VMUser{}).
-		Owns(&v1.Secret{}, builder.OnlyMetadata).
-		WithEventFilter(predicate.TypedGenerationChangedPredicate[client.Object]{}).
-		WithOptions(getDefaultOptions()).
+	r.Recorder = mgr.GetEventRecorderFor("vmuser-controller")
+	b := ctrl.NewControllerManagedBy(mgr).
+		For(&vmv1beta1.VMUser{}, builder.WithPredicates(predicate.TypedGenerationChangedPredicate[client.Object]{})).
+		Owns(&v1.Secret{}, builder.OnlyMetadata, builder.WithPredicates(predicate.TypedGenerationChangedPredicate[client.Object]{}))
+	// watch for objects, which could be selected by crdSelector targetRefs
+	for _, t := range []struct {
+		obj   client.Object
+		kinds []string
+	}{
+		{&vmv1beta1.VMAgent{}, []string{"VMAgent"}},
+		{&vmv1beta1.VMAlert{}, []string{"VMAlert"}},
+		{&vmv1beta1.VMSingle{}, []string{"VMSingle"}},
+		{&vmv1beta1.VLogs{}, []string{"VLogs"}},
+		{&vmv1beta1.VMAlertmanager{}, []string{"VMAlertmanager", "VMAlertManager"}},
+		{&vmv1beta1.VMCluster{}, []string{"VMCluster/vmselect", "VMCluster/vminsert", "VMCluster/vmstorage"}},
+	} {
+		b = b.WatchesMetadata(t.obj, handler.EnqueueRequestsFromMapFunc(r.usersForSelectedKinds(t.kinds)), builder.WithPredicates(predicate.LabelChangedPredicate{}))
+	}
+	return b.WithOptions(getDefaultOptions()).
 		Complete(r)
 }
+
+// usersForSelectedKinds returns VMUsers, which have crdSelector targetRefs for one of the given kinds
+// it triggers vmauth configuration update on creation, deletion or labels change of the selected objects
+func (r *VMUserReconciler) usersForSelectedKinds(kinds []string) handler.MapFunc {
+	return func(ctx context.Context, obj client.Object) []ctrl.Request {
+		var requests []ctrl.Request
+		if err := k8stools.ListObjectsByNamespace(ctx, r.Client, config.MustGetWatchNamespaces(), func(dst *vmv1beta1.VMUserList) {
+			for i := range dst.Items {
+				u := &dst.Items[i]
+				if slices.ContainsFunc(u.Spec.TargetRefs, func(ref vmv1beta1.TargetRef) bool {
+					return ref.CRDSelector != nil && slices.Contains(kinds, ref.CRDSelector.Kind)
+				}) {
+					requests = append(requests, ctrl.Request{NamespacedName: types.NamespacedName{Namespace: u.Namespace, Name: u.Name}})
+				}
+			}
+		}); err != nil {
+			r.Log.Error(err, "cannot list VMUsers for selected object", "name", obj.GetName(), "namespace", obj.GetNamespace())
+			return nil
+		}
+		return requests
+	}
+}
@@ -23,6 +23,8 @@ import (
 	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/k8stools"
 	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/logger"
 	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/reconcile"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/vmagent"
+	"github.com/VictoriaMetrics/operator/internal/controller/operator/factory/vmalert"
 	"github.com/go-logr/logr"
 	promv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
 	"github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1alpha1"
@@ -48,6 +50,7 @@ import (
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	gwapiv1 "sigs.k8s.io/gateway-api/apis/v1"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -107,6 +110,10 @@ var (
 	loggerJSONFields = managerFlags.String("loggerJSONFields", "", "Allows renaming fields in JSON formatted logs"+
 		`Example: "ts:timestamp,msg:message" renames "ts" to "timestamp" and "msg" to "message".`+
 		"Supported fields: ts, level, caller, msg")
+	vmalertRulesServerSideApply = managerFlags.Bool("controller.vmalert.rulesServerSideApply", false, "Enables server-side apply for VMAlert rule ConfigMaps with vm-operator field manager. "+
+		"It preserves ConfigMap fields added by third-party controllers. Could be overridden by VMAlert spec.rulesServerSideApply")
+	vmagentScrapeObjectSelectedByStatus = managerFlags.Bool("controller.vmagent.scrapeObjectSelectedByStatus", false, "Enables status.selectedBy of scrape objects with VMAgents, which selected the object, and names of generated scrape jobs. "+
+		"It increases number of status update requests to kubernetes API server for clusters with many VMAgents and scrape objects")
 	statusUpdateTTL = managerFlags.Duration("controller.statusLastUpdateTimeTTL", time.Hour, "Configures TTL for LastUpdateTime status.condtions fields. "+
 		"It's used to detect stale parent objects on child objects. Like VMAlert->VMRule .status.Conditions.Type")
 )
@@ -118,6 +125,7 @@ func init() {
 	utilruntime.Must(metav1.AddToScheme(scheme))
 	utilruntime.Must(v1alpha1.AddToScheme(scheme))
 	utilruntime.Must(promv1.AddToScheme(scheme))
+	utilruntime.Must(gwapiv1.AddToScheme(scheme))
 	build.AddDefaults(scheme)
 	// +kubebuilder:scaffold:scheme
 }
@@ -206,6 +214,8 @@ func RunManager(ctx context.Context) error {
 
 	reconcile.InitDeadlines(baseConfig.PodWaitReadyIntervalCheck, baseConfig.AppReadyTimeout, baseConfig.PodWaitReadyTimeout)
 	reconcile.SetStatusUpdateTTL(*statusUpdateTTL)
+	vmalert.SetRulesServerSideApply(*vmalertRulesServerSideApply)
+	vmagent.SetScrapeObjectSelectedByStatus(*vmagentScrapeObjectSelectedByStatus)
 	config := ctrl.GetConfigOrDie()
 	config.RateLimiter = flowcontrol.NewTokenBucketRateLimiter(float32(*clientQPS), *clientBurst)
 